@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/rzmn/governi/internal/db/migrations"
+	"github.com/rzmn/governi/internal/services/logging"
 
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	"github.com/rzmn/governi/internal/services/pathProvider"
@@ -17,31 +24,140 @@ func main() {
 	logger := standartOutputLoggingService.New()
 	pathProvider := envBasedPathProvider.New(logger)
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == commandNameMigrate {
+		migrate(args[1:], pathProvider, logger)
+		return
+	}
+	if len(args) > 0 && args[0] == commandNameBalances {
+		balances(args[1:], pathProvider, logger)
+		return
+	}
 	command, err := valueForArg(argNameCommandType, args)
 	if err != nil {
 		logger.LogFatal("failed to get command type: %v", err)
 	}
 	switch command {
 	case commandNameCreateTables:
-		configData, err := getConfigData(args, pathProvider)
+		engine := getMigrationEngine(args, pathProvider, logger)
+		migrateUp(engine, logger)
+	case commandNameDropTables:
+		engine := getMigrationEngine(args, pathProvider, logger)
+		migrateDown(engine, math.MaxInt, logger)
+	}
+}
+
+func migrate(args []string, pathProvider pathProvider.Service, logger logging.Service) {
+	if len(args) == 0 {
+		logger.LogFatal("migrate: expected one of %s, %s, %s, %s", migrateCommandUp, migrateCommandDown, migrateCommandStatus, migrateCommandCreate)
+	}
+	switch args[0] {
+	case migrateCommandUp:
+		engine := getMigrationEngine(args[1:], pathProvider, logger)
+		migrateUp(engine, logger)
+	case migrateCommandDown:
+		if len(args) < 2 {
+			logger.LogFatal("migrate %s: expected number of migrations to revert", migrateCommandDown)
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count <= 0 {
+			logger.LogFatal("migrate %s: bad number of migrations %s", migrateCommandDown, args[1])
+		}
+		engine := getMigrationEngine(args[2:], pathProvider, logger)
+		migrateDown(engine, count, logger)
+	case migrateCommandStatus:
+		engine := getMigrationEngine(args[1:], pathProvider, logger)
+		statuses, err := engine.Status(context.Background())
 		if err != nil {
-			logger.LogFatal("failed to get config data: %v", err)
+			logger.LogFatal("failed to get migrations status err: %v", err)
 		}
-		actions, err := createDatabaseActions(configData, logger)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = fmt.Sprintf("applied at %s", time.Unix(*status.AppliedAt, 0).UTC().Format(time.RFC3339))
+			}
+			if !status.Known {
+				state += ", unknown to this binary"
+			}
+			fmt.Printf("%04d_%s: %s\n", status.Version, status.Name, state)
+		}
+	case migrateCommandCreate:
+		if len(args) < 2 {
+			logger.LogFatal("migrate %s: expected migration name", migrateCommandCreate)
+		}
+		existing, err := migrations.Embedded()
 		if err != nil {
-			logger.LogFatal("failed to create database actions err: %v", err)
+			logger.LogFatal("failed to load migrations err: %v", err)
 		}
-		actions.setup()
-	case commandNameDropTables:
-		configData, err := getConfigData(args, pathProvider)
+		up, down, err := migrations.Create(pathProvider.AbsolutePath("./"+migrations.EmbeddedDirectory), args[1], existing)
 		if err != nil {
-			logger.LogFatal("failed to get config data: %v", err)
+			logger.LogFatal("failed to create migration err: %v", err)
 		}
-		actions, err := createDatabaseActions(configData, logger)
+		logger.LogInfo("created %s and %s", up, down)
+	default:
+		logger.LogFatal("migrate: unknown command %s", args[0])
+	}
+}
+
+func balances(args []string, pathProvider pathProvider.Service, logger logging.Service) {
+	if len(args) == 0 {
+		logger.LogFatal("balances: expected one of %s, %s", balancesCommandRebuild, balancesCommandVerify)
+	}
+	configData, err := getConfigData(args[1:], pathProvider)
+	if err != nil {
+		logger.LogFatal("failed to get config data: %v", err)
+	}
+	switch args[0] {
+	case balancesCommandRebuild:
+		if err := createSpendingsRepository(configData, logger).RebuildBalances(context.Background()); err != nil {
+			logger.LogFatal("failed to rebuild balances err: %v", err)
+		}
+		logger.LogInfo("balances rebuilt")
+	case balancesCommandVerify:
+		mismatches, err := createSpendingsRepository(configData, logger).VerifyBalances(context.Background())
 		if err != nil {
-			logger.LogFatal("failed to create database actions err: %v", err)
+			logger.LogFatal("failed to verify balances err: %v", err)
+		}
+		for _, mismatch := range mismatches {
+			fmt.Printf("%s -> %s [%s]: materialized %d, ledger %d\n", mismatch.Owner, mismatch.Counterparty, mismatch.Currency, mismatch.Materialized, mismatch.Ledger)
 		}
-		actions.drop()
+		if len(mismatches) > 0 {
+			logger.LogFatal("found %d balance mismatches, run `utilities balances %s` to fix them", len(mismatches), balancesCommandRebuild)
+		}
+		logger.LogInfo("balances match the ledger")
+	default:
+		logger.LogFatal("balances: unknown command %s", args[0])
+	}
+}
+
+func getMigrationEngine(args []string, pathProvider pathProvider.Service, logger logging.Service) migrations.Engine {
+	configData, err := getConfigData(args, pathProvider)
+	if err != nil {
+		logger.LogFatal("failed to get config data: %v", err)
+	}
+	engine, err := createMigrationEngine(configData, logger)
+	if err != nil {
+		logger.LogFatal("failed to create migration engine err: %v", err)
+	}
+	return engine
+}
+
+func migrateUp(engine migrations.Engine, logger logging.Service) {
+	applied, err := engine.Up(context.Background())
+	for _, migration := range applied {
+		logger.LogInfo("applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		logger.LogFatal("failed to apply migrations err: %v", err)
+	}
+}
+
+func migrateDown(engine migrations.Engine, count int, logger logging.Service) {
+	reverted, err := engine.Down(context.Background(), count)
+	for _, migration := range reverted {
+		logger.LogInfo("reverted migration %04d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		logger.LogFatal("failed to revert migrations err: %v", err)
 	}
 }
 
@@ -54,6 +170,20 @@ const (
 const (
 	commandNameCreateTables = "create-tables"
 	commandNameDropTables   = "drop-tables"
+	commandNameMigrate      = "migrate"
+	commandNameBalances     = "balances"
+)
+
+const (
+	migrateCommandUp     = "up"
+	migrateCommandDown   = "down"
+	migrateCommandStatus = "status"
+	migrateCommandCreate = "create"
+)
+
+const (
+	balancesCommandRebuild = "rebuild"
+	balancesCommandVerify  = "verify"
 )
 
 const (
@@ -61,7 +191,7 @@ const (
 )
 
 func valueForArg(argName string, args []string) (string, error) {
-	for i := 0; i < len(args); i += 2 {
+	for i := 0; i+1 < len(args); i += 2 {
 		if argName != args[i] {
 			continue
 		}
@@ -1,33 +1,64 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/db/migrations"
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
 	authRepository "github.com/rzmn/governi/internal/repositories/auth"
 	defaultAuthRepository "github.com/rzmn/governi/internal/repositories/auth/default"
+	memoryAuthRepository "github.com/rzmn/governi/internal/repositories/auth/memory"
+	dataExportsRepository "github.com/rzmn/governi/internal/repositories/dataExports"
+	defaultDataExportsRepository "github.com/rzmn/governi/internal/repositories/dataExports/default"
+	memoryDataExportsRepository "github.com/rzmn/governi/internal/repositories/dataExports/memory"
 	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
 	defaultFriendsRepository "github.com/rzmn/governi/internal/repositories/friends/default"
+	memoryFriendsRepository "github.com/rzmn/governi/internal/repositories/friends/memory"
+	idempotencyKeysRepository "github.com/rzmn/governi/internal/repositories/idempotencyKeys"
+	defaultIdempotencyKeysRepository "github.com/rzmn/governi/internal/repositories/idempotencyKeys/default"
+	memoryIdempotencyKeysRepository "github.com/rzmn/governi/internal/repositories/idempotencyKeys/memory"
+	identitiesRepository "github.com/rzmn/governi/internal/repositories/identities"
+	defaultIdentitiesRepository "github.com/rzmn/governi/internal/repositories/identities/default"
+	memoryIdentitiesRepository "github.com/rzmn/governi/internal/repositories/identities/memory"
 	imagesRepository "github.com/rzmn/governi/internal/repositories/images"
 	defaultImagesRepository "github.com/rzmn/governi/internal/repositories/images/default"
+	memoryImagesRepository "github.com/rzmn/governi/internal/repositories/images/memory"
+	loginAttemptsRepository "github.com/rzmn/governi/internal/repositories/loginAttempts"
+	defaultLoginAttemptsRepository "github.com/rzmn/governi/internal/repositories/loginAttempts/default"
+	memoryLoginAttemptsRepository "github.com/rzmn/governi/internal/repositories/loginAttempts/memory"
+	outboxRepository "github.com/rzmn/governi/internal/repositories/outbox"
+	defaultOutboxRepository "github.com/rzmn/governi/internal/repositories/outbox/default"
+	memoryOutboxRepository "github.com/rzmn/governi/internal/repositories/outbox/memory"
 	pushRegistryRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
 	defaultPushRegistryRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/default"
+	memoryPushRegistryRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/memory"
 	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
 	defaultSpendingsRepository "github.com/rzmn/governi/internal/repositories/spendings/default"
+	memorySpendingsRepository "github.com/rzmn/governi/internal/repositories/spendings/memory"
+	twoFactorRepository "github.com/rzmn/governi/internal/repositories/twoFactor"
+	defaultTwoFactorRepository "github.com/rzmn/governi/internal/repositories/twoFactor/default"
+	memoryTwoFactorRepository "github.com/rzmn/governi/internal/repositories/twoFactor/memory"
 	usersRepository "github.com/rzmn/governi/internal/repositories/users"
 	defaultUsersRepository "github.com/rzmn/governi/internal/repositories/users/default"
+	memoryUsersRepository "github.com/rzmn/governi/internal/repositories/users/memory"
 	verificationRepository "github.com/rzmn/governi/internal/repositories/verification"
 	defaultVerificationRepository "github.com/rzmn/governi/internal/repositories/verification/default"
+	memoryVerificationRepository "github.com/rzmn/governi/internal/repositories/verification/memory"
 	ginServer "github.com/rzmn/governi/internal/server/gin"
 
 	defaultAccessTokenHandler "github.com/rzmn/governi/internal/requestHandlers/accessToken/default"
 	defaultAuthHandler "github.com/rzmn/governi/internal/requestHandlers/auth/default"
 	defaultAvatarsHandler "github.com/rzmn/governi/internal/requestHandlers/avatars/default"
+	defaultDataExportsHandler "github.com/rzmn/governi/internal/requestHandlers/dataExports/default"
 	defaultFriendsHandler "github.com/rzmn/governi/internal/requestHandlers/friends/default"
 	defaultProfileHandler "github.com/rzmn/governi/internal/requestHandlers/profile/default"
 	defaultSpendingsHandler "github.com/rzmn/governi/internal/requestHandlers/spendings/default"
@@ -42,13 +73,26 @@ import (
 	defaultFormatValidation "github.com/rzmn/governi/internal/services/formatValidation/default"
 	"github.com/rzmn/governi/internal/services/jwt"
 	defaultJwtService "github.com/rzmn/governi/internal/services/jwt/default"
+	"github.com/rzmn/governi/internal/services/localization"
+	defaultLocalization "github.com/rzmn/governi/internal/services/localization/default"
 	"github.com/rzmn/governi/internal/services/logging"
 	prodLoggingService "github.com/rzmn/governi/internal/services/logging/prod"
+	defaultMetricsService "github.com/rzmn/governi/internal/services/metrics/default"
+	"github.com/rzmn/governi/internal/services/oidc"
+	jwksOidcService "github.com/rzmn/governi/internal/services/oidc/jwks"
 	"github.com/rzmn/governi/internal/services/pathProvider"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 	"github.com/rzmn/governi/internal/services/pushNotifications"
 	applePushNotifications "github.com/rzmn/governi/internal/services/pushNotifications/apns"
+	firebasePushNotifications "github.com/rzmn/governi/internal/services/pushNotifications/fcm"
+	localizedPushContent "github.com/rzmn/governi/internal/services/pushNotifications/localized"
+	multiplatformPushNotifications "github.com/rzmn/governi/internal/services/pushNotifications/multiplatform"
 	"github.com/rzmn/governi/internal/services/realtimeEvents"
+	localRealtimeBroker "github.com/rzmn/governi/internal/services/realtimeEvents/broker/local"
+	postgresRealtimeBroker "github.com/rzmn/governi/internal/services/realtimeEvents/broker/postgres"
+	defaultRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/default"
+	"github.com/rzmn/governi/internal/services/totp"
+	defaultTotpService "github.com/rzmn/governi/internal/services/totp/default"
 	"github.com/rzmn/governi/internal/services/watchdog"
 	telegramWatchdog "github.com/rzmn/governi/internal/services/watchdog/telegram"
 
@@ -56,8 +100,12 @@ import (
 	defaultAuthController "github.com/rzmn/governi/internal/controllers/auth/default"
 	avatarsController "github.com/rzmn/governi/internal/controllers/avatars"
 	defaultAvatarsController "github.com/rzmn/governi/internal/controllers/avatars/default"
+	dataExportsController "github.com/rzmn/governi/internal/controllers/dataExports"
+	defaultDataExportsController "github.com/rzmn/governi/internal/controllers/dataExports/default"
 	friendsController "github.com/rzmn/governi/internal/controllers/friends"
 	defaultFriendsController "github.com/rzmn/governi/internal/controllers/friends/default"
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
+	defaultOutboxController "github.com/rzmn/governi/internal/controllers/outbox/default"
 	profileController "github.com/rzmn/governi/internal/controllers/profile"
 	defaultProfileController "github.com/rzmn/governi/internal/controllers/profile/default"
 	spendingsController "github.com/rzmn/governi/internal/controllers/spendings"
@@ -68,27 +116,42 @@ import (
 	defaultVerificationController "github.com/rzmn/governi/internal/controllers/verification/default"
 )
 
+const outboxDispatchInterval = 200 * time.Millisecond
+const expiredDataExportsRemovalInterval = time.Hour
+
 type Repositories struct {
-	auth         authRepository.Repository
-	friends      friendsRepository.Repository
-	images       imagesRepository.Repository
-	pushRegistry pushRegistryRepository.Repository
-	spendings    spendingsRepository.Repository
-	users        usersRepository.Repository
-	verification verificationRepository.Repository
+	auth          authRepository.Repository
+	dataExports   dataExportsRepository.Repository
+	friends       friendsRepository.Repository
+	identities    identitiesRepository.Repository
+	idempotency   idempotencyKeysRepository.Repository
+	images        imagesRepository.Repository
+	loginAttempts loginAttemptsRepository.Repository
+	outbox        outboxRepository.Repository
+	pushRegistry  pushRegistryRepository.Repository
+	spendings     spendingsRepository.Repository
+	twoFactor     twoFactorRepository.Repository
+	users         usersRepository.Repository
+	verification  verificationRepository.Repository
 }
 
 type Services struct {
 	push                    pushNotifications.Service
+	realtimeEvents          realtimeEvents.Service
 	jwt                     jwt.Service
+	totp                    totp.Service
+	oidc                    oidc.Service
 	emailSender             emailSender.Service
 	formatValidationService formatValidation.Service
+	localization            localization.Service
 }
 
 type Controllers struct {
 	auth         authController.Controller
 	avatars      avatarsController.Controller
+	dataExports  dataExportsController.Controller
 	friends      friendsController.Controller
+	outbox       outboxController.Controller
 	profile      profileController.Controller
 	spendings    spendingsController.Controller
 	users        usersController.Controller
@@ -105,6 +168,8 @@ func main() {
 		PushNotifications Module `json:"pushNotifications"`
 		EmailSender       Module `json:"emailSender"`
 		Jwt               Module `json:"jwt"`
+		Totp              Module `json:"totp"`
+		IdentityProviders Module `json:"identityProviders"`
 		Server            Module `json:"server"`
 		Watchdog          Module `json:"watchdog"`
 	}
@@ -168,7 +233,8 @@ func main() {
 	}()
 	logger.LogInfo("initializing with config %v", config)
 
-	database := func() db.DB {
+	metricsService := defaultMetricsService.New()
+	repositories, unitOfWork, realtimeBroker, closeStorage := func() (Repositories, db.UnitOfWork, realtimeEvents.Broker, func() error) {
 		switch config.Storage.Type {
 		case "postgres":
 			data, err := json.Marshal(config.Storage.Config)
@@ -178,49 +244,155 @@ func main() {
 			var postgresConfig postgresDb.PostgresConfig
 			json.Unmarshal(data, &postgresConfig)
 			logger.LogInfo("creating postgres with config %v", postgresConfig)
-			db, err := postgresDb.Postgres(postgresConfig, logger)
+			database, err := postgresDb.Postgres(postgresConfig, logger)
 			if err != nil {
 				logger.LogFatal("failed to initialize postgres err: %v", err)
 			}
 			logger.LogInfo("initialized postgres")
-			return db
+			embedded, err := migrations.Embedded()
+			if err != nil {
+				logger.LogFatal("failed to load migrations err: %v", err)
+			}
+			pending, err := migrations.New(database, embedded, logger, func() time.Time {
+				return time.Now()
+			}).Pending(context.Background())
+			if err != nil {
+				logger.LogFatal("failed to check pending migrations err: %v", err)
+			}
+			if len(pending) > 0 {
+				logger.LogFatal("database has %d pending migrations starting from %04d_%s, run `utilities migrate up` first", len(pending), pending[0].Version, pending[0].Name)
+			}
+			metricsService.Register(postgresDb.StatsCollector(database))
+			replicas := []db.DB{}
+			for i, replicaConfig := range postgresConfig.Replicas {
+				replica, err := postgresDb.Postgres(replicaConfig, logger)
+				if err != nil {
+					logger.LogFatal("failed to initialize postgres replica %d err: %v", i, err)
+				}
+				replicas = append(replicas, replica)
+			}
+			logger.LogInfo("initialized %d postgres replicas", len(replicas))
+			storage := db.NewReplicated(database, replicas)
+			realtimeBroker, err := postgresRealtimeBroker.New(storage, postgresDb.ConnectionString(postgresConfig), logger)
+			if err != nil {
+				logger.LogFatal("failed to initialize postgres realtime broker err: %v", err)
+			}
+			return Repositories{
+				auth:          defaultAuthRepository.New(storage, logger),
+				dataExports:   defaultDataExportsRepository.New(storage, logger),
+				friends:       defaultFriendsRepository.New(storage, logger),
+				identities:    defaultIdentitiesRepository.New(storage, logger),
+				idempotency:   defaultIdempotencyKeysRepository.New(storage, logger),
+				images:        defaultImagesRepository.New(storage, logger),
+				loginAttempts: defaultLoginAttemptsRepository.New(storage, logger),
+				outbox:        defaultOutboxRepository.New(storage, logger),
+				pushRegistry:  defaultPushRegistryRepository.New(storage, logger),
+				spendings:     defaultSpendingsRepository.New(storage, logger),
+				twoFactor:     defaultTwoFactorRepository.New(storage, logger),
+				users:         defaultUsersRepository.New(storage, logger),
+				verification:  defaultVerificationRepository.New(storage, logger),
+			}, db.NewUnitOfWork(storage), realtimeBroker, func() error {
+				return errors.Join(realtimeBroker.Close(), storage.Close())
+			}
+		case "memory":
+			logger.LogInfo("creating in-memory storage, data will be lost on restart")
+			return Repositories{
+				auth:          memoryAuthRepository.New(logger),
+				dataExports:   memoryDataExportsRepository.New(logger),
+				friends:       memoryFriendsRepository.New(logger),
+				identities:    memoryIdentitiesRepository.New(logger),
+				idempotency:   memoryIdempotencyKeysRepository.New(logger),
+				images:        memoryImagesRepository.New(logger),
+				loginAttempts: memoryLoginAttemptsRepository.New(logger),
+				outbox:        memoryOutboxRepository.New(logger),
+				pushRegistry:  memoryPushRegistryRepository.New(logger),
+				spendings:     memorySpendingsRepository.New(logger),
+				twoFactor:     memoryTwoFactorRepository.New(logger),
+				users:         memoryUsersRepository.New(logger),
+				verification:  memoryVerificationRepository.New(logger),
+			}, memoryDb.NewUnitOfWork(), localRealtimeBroker.New(), func() error {
+				return nil
+			}
 		default:
 			logger.LogFatal("unknown storage type %s", config.Storage.Type)
-			return nil
+			return Repositories{}, nil, nil, nil
 		}
 	}()
-	defer database.Close()
-	repositories := Repositories{
-		auth:         defaultAuthRepository.New(database, logger),
-		friends:      defaultFriendsRepository.New(database, logger),
-		images:       defaultImagesRepository.New(database, logger),
-		pushRegistry: defaultPushRegistryRepository.New(database, logger),
-		spendings:    defaultSpendingsRepository.New(database, logger),
-		users:        defaultUsersRepository.New(database, logger),
-		verification: defaultVerificationRepository.New(database, logger),
+	defer closeStorage()
+	localizationService, err := defaultLocalization.New(logger)
+	if err != nil {
+		logger.LogFatal("failed to load localization catalogs err: %v", err)
 	}
+	pushContent := localizedPushContent.New(repositories.users, localizationService, logger)
 	services := Services{
 		push: func() pushNotifications.Service {
-			switch config.PushNotifications.Type {
-			case "apns":
-				data, err := json.Marshal(config.PushNotifications.Config)
+			apns := func(config map[string]interface{}) pushNotifications.Service {
+				data, err := json.Marshal(config)
 				if err != nil {
 					logger.LogFatal("failed to serialize apple apns config err: %v", err)
 				}
 				var apnsConfig applePushNotifications.ApnsConfig
 				json.Unmarshal(data, &apnsConfig)
 				logger.LogInfo("creating apple apns service with config %v", apnsConfig)
-				service, err := applePushNotifications.New(apnsConfig, logger, pathProvider, repositories.pushRegistry)
+				service, err := applePushNotifications.New(apnsConfig, logger, pathProvider, repositories.pushRegistry, pushContent)
 				if err != nil {
 					logger.LogFatal("failed to initialize apple apns service err: %v", err)
 				}
 				logger.LogInfo("initialized apple apns service")
 				return service
+			}
+			fcm := func(config map[string]interface{}) pushNotifications.Service {
+				data, err := json.Marshal(config)
+				if err != nil {
+					logger.LogFatal("failed to serialize firebase fcm config err: %v", err)
+				}
+				var fcmConfig firebasePushNotifications.FcmConfig
+				json.Unmarshal(data, &fcmConfig)
+				logger.LogInfo("creating firebase fcm service with config %v", fcmConfig)
+				service, err := firebasePushNotifications.New(
+					fcmConfig,
+					&http.Client{
+						Timeout: time.Second * 10,
+					},
+					logger,
+					pathProvider,
+					repositories.pushRegistry,
+					pushContent,
+					func() time.Time {
+						return time.Now()
+					},
+				)
+				if err != nil {
+					logger.LogFatal("failed to initialize firebase fcm service err: %v", err)
+				}
+				logger.LogInfo("initialized firebase fcm service")
+				return service
+			}
+			switch config.PushNotifications.Type {
+			case "apns":
+				return apns(config.PushNotifications.Config)
+			case "fcm":
+				return fcm(config.PushNotifications.Config)
+			case "multiplatform":
+				data, err := json.Marshal(config.PushNotifications.Config)
+				if err != nil {
+					logger.LogFatal("failed to serialize multiplatform push config err: %v", err)
+				}
+				var multiplatformConfig struct {
+					Apns map[string]interface{} `json:"apns"`
+					Fcm  map[string]interface{} `json:"fcm"`
+				}
+				json.Unmarshal(data, &multiplatformConfig)
+				return multiplatformPushNotifications.New([]pushNotifications.Service{
+					apns(multiplatformConfig.Apns),
+					fcm(multiplatformConfig.Fcm),
+				})
 			default:
-				logger.LogFatal("unknown apns type %s", config.PushNotifications.Type)
+				logger.LogFatal("unknown push notifications type %s", config.PushNotifications.Type)
 				return nil
 			}
 		}(),
+		realtimeEvents: defaultRealtimeEvents.New([]realtimeEvents.Publisher{realtimeBroker}, logger),
 		jwt: func() jwt.Service {
 			switch config.Jwt.Type {
 			case "default":
@@ -243,6 +415,53 @@ func main() {
 				return nil
 			}
 		}(),
+		totp: func() totp.Service {
+			switch config.Totp.Type {
+			case "default":
+				data, err := json.Marshal(config.Totp.Config)
+				if err != nil {
+					logger.LogFatal("failed to serialize totp config err: %v", err)
+				}
+				var defaultConfig defaultTotpService.DefaultConfig
+				json.Unmarshal(data, &defaultConfig)
+				logger.LogInfo("creating totp service with config %v", defaultConfig)
+				return defaultTotpService.New(
+					defaultConfig,
+					logger,
+					func() time.Time {
+						return time.Now()
+					},
+				)
+			default:
+				logger.LogFatal("unknown totp service type %s", config.Totp.Type)
+				return nil
+			}
+		}(),
+		oidc: func() oidc.Service {
+			switch config.IdentityProviders.Type {
+			case "jwks":
+				data, err := json.Marshal(config.IdentityProviders.Config)
+				if err != nil {
+					logger.LogFatal("failed to serialize identity providers config err: %v", err)
+				}
+				var jwksConfig jwksOidcService.JwksConfig
+				json.Unmarshal(data, &jwksConfig)
+				logger.LogInfo("creating jwks oidc service with config %v", jwksConfig)
+				return jwksOidcService.New(
+					jwksConfig,
+					&http.Client{
+						Timeout: time.Second * 10,
+					},
+					logger,
+					func() time.Time {
+						return time.Now()
+					},
+				)
+			default:
+				logger.LogFatal("unknown identity providers service type %s", config.IdentityProviders.Type)
+				return nil
+			}
+		}(),
 		emailSender: func() emailSender.Service {
 			switch config.EmailSender.Type {
 			case "yandex":
@@ -262,15 +481,25 @@ func main() {
 		formatValidationService: func() formatValidation.Service {
 			return defaultFormatValidation.New(logger)
 		}(),
+		localization: localizationService,
 	}
 	controllers := Controllers{
 		auth: defaultAuthController.New(
 			repositories.auth,
 			repositories.pushRegistry,
 			repositories.users,
+			repositories.twoFactor,
+			repositories.identities,
+			repositories.loginAttempts,
+			unitOfWork,
 			services.jwt,
+			services.totp,
+			services.oidc,
 			services.formatValidationService,
 			logger,
+			func() time.Time {
+				return time.Now()
+			},
 		),
 		avatars: defaultAvatarsController.New(
 			repositories.images,
@@ -278,18 +507,41 @@ func main() {
 		),
 		friends: defaultFriendsController.New(
 			repositories.friends,
+			repositories.outbox,
+			unitOfWork,
 			logger,
 		),
+		outbox: defaultOutboxController.New(
+			repositories.outbox,
+			repositories.spendings,
+			services.push,
+			services.realtimeEvents,
+			logger,
+			func() time.Time {
+				return time.Now()
+			},
+		),
 		profile: defaultProfileController.New(
 			repositories.auth,
 			repositories.images,
 			repositories.users,
 			repositories.friends,
+			repositories.pushRegistry,
+			repositories.identities,
+			repositories.twoFactor,
+			repositories.verification,
+			repositories.dataExports,
+			repositories.loginAttempts,
+			repositories.outbox,
+			unitOfWork,
 			services.formatValidationService,
+			services.localization,
 			logger,
 		),
 		spendings: defaultSpendingsController.New(
 			repositories.spendings,
+			repositories.outbox,
+			unitOfWork,
 			logger,
 		),
 		users: defaultUsersController.New(
@@ -300,10 +552,27 @@ func main() {
 		verification: defaultVerificationController.New(
 			repositories.verification,
 			repositories.auth,
+			repositories.users,
+			unitOfWork,
 			services.emailSender,
+			services.localization,
 			logger,
 		),
 	}
+	controllers.dataExports = defaultDataExportsController.New(
+		repositories.dataExports,
+		repositories.friends,
+		repositories.spendings,
+		repositories.images,
+		controllers.profile,
+		logger,
+		func() time.Time {
+			return time.Now()
+		},
+		func(task func()) {
+			go task()
+		},
+	)
 	server := func() server.Server {
 		switch config.Server.Type {
 		case "gin":
@@ -321,42 +590,43 @@ func main() {
 					services.jwt,
 					logger,
 				),
-				func(realtimeEvents realtimeEvents.Service) ginServer.RequestHandlers {
-					return ginServer.RequestHandlers{
-						Auth: defaultAuthHandler.New(
-							controllers.auth,
-							logger,
-						),
-						Spendings: defaultSpendingsHandler.New(
-							controllers.spendings,
-							services.push,
-							realtimeEvents,
-							logger,
-						),
-						Friends: defaultFriendsHandler.New(
-							controllers.friends,
-							services.push,
-							realtimeEvents,
-							logger,
-						),
-						Profile: defaultProfileHandler.New(
-							controllers.profile,
-							logger,
-						),
-						Verification: defaultVerificationHandler.New(
-							controllers.verification,
-							logger,
-						),
-						Users: defaultUsersHandler.New(
-							controllers.users,
-							logger,
-						),
-						Avatars: defaultAvatarsHandler.New(
-							controllers.avatars,
-							logger,
-						),
-					}
+				ginServer.RequestHandlers{
+					Auth: defaultAuthHandler.New(
+						controllers.auth,
+						logger,
+					),
+					Spendings: defaultSpendingsHandler.New(
+						controllers.spendings,
+						logger,
+					),
+					Friends: defaultFriendsHandler.New(
+						controllers.friends,
+						logger,
+					),
+					Profile: defaultProfileHandler.New(
+						controllers.profile,
+						logger,
+					),
+					Verification: defaultVerificationHandler.New(
+						controllers.verification,
+						logger,
+					),
+					Users: defaultUsersHandler.New(
+						controllers.users,
+						logger,
+					),
+					Avatars: defaultAvatarsHandler.New(
+						controllers.avatars,
+						logger,
+					),
+					DataExports: defaultDataExportsHandler.New(
+						controllers.dataExports,
+						logger,
+					),
 				},
+				realtimeBroker,
+				repositories.idempotency,
+				metricsService,
 				logger,
 			)
 		default:
@@ -364,5 +634,27 @@ func main() {
 			return nil
 		}
 	}()
+	go dispatchOutbox(controllers.outbox, logger)
+	go removeExpiredDataExports(controllers.dataExports, logger)
 	server.ListenAndServe()
 }
+
+func dispatchOutbox(controller outboxController.Controller, logger logging.Service) {
+	ticker := time.NewTicker(outboxDispatchInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := controller.Dispatch(context.Background()); err != nil {
+			logger.LogError("failed to dispatch outbox err: %v", err)
+		}
+	}
+}
+
+func removeExpiredDataExports(controller dataExportsController.Controller, logger logging.Service) {
+	ticker := time.NewTicker(expiredDataExportsRemovalInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := controller.RemoveExpiredExports(context.Background()); err != nil {
+			logger.LogError("failed to remove expired data exports err: %v", err)
+		}
+	}
+}
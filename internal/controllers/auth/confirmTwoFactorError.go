@@ -0,0 +1,29 @@
+package auth
+
+type ConfirmTwoFactorErrorCode int
+
+const (
+	_ ConfirmTwoFactorErrorCode = iota
+	ConfirmTwoFactorErrorNotEnrolled
+	ConfirmTwoFactorErrorAlreadyConfirmed
+	ConfirmTwoFactorErrorWrongCode
+	ConfirmTwoFactorErrorTooManyAttempts
+	ConfirmTwoFactorErrorInternal
+)
+
+func (c ConfirmTwoFactorErrorCode) Message() string {
+	switch c {
+	case ConfirmTwoFactorErrorNotEnrolled:
+		return "two factor authentication enrollment has not been started"
+	case ConfirmTwoFactorErrorAlreadyConfirmed:
+		return "two factor authentication is already enabled"
+	case ConfirmTwoFactorErrorWrongCode:
+		return "wrong code"
+	case ConfirmTwoFactorErrorTooManyAttempts:
+		return "too many failed attempts, retry later"
+	case ConfirmTwoFactorErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -1,10 +1,24 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 )
 
 type UserId string
+type PushPlatform string
+
+const (
+	PushPlatformIos     PushPlatform = "ios"
+	PushPlatformAndroid PushPlatform = "android"
+)
+
+type PushToken struct {
+	Token      string
+	Platform   PushPlatform
+	AppVersion *string
+}
 
 type Session struct {
 	Id           UserId
@@ -12,14 +26,40 @@ type Session struct {
 	RefreshToken string
 }
 
+type LoginResult struct {
+	Session        *Session
+	TwoFactorToken *string
+	RetryAfterSec  int
+}
+
+type VerifyTwoFactorResult struct {
+	Session       Session
+	RetryAfterSec int
+}
+
+type ConfirmTwoFactorResult struct {
+	RecoveryCodes []string
+	RetryAfterSec int
+}
+
+type TwoFactorEnrollment struct {
+	Secret string
+	Uri    string
+}
+
 type Controller interface {
-	Signup(email string, password string) (Session, *common.CodeBasedError[SignupErrorCode])
-	Login(email string, password string) (Session, *common.CodeBasedError[LoginErrorCode])
-	Refresh(refreshToken string) (Session, *common.CodeBasedError[RefreshErrorCode])
-	Logout(id UserId) *common.CodeBasedError[LogoutErrorCode]
+	Signup(ctx context.Context, email string, password string) (Session, *common.CodeBasedError[SignupErrorCode])
+	Login(ctx context.Context, email string, password string, ip string) (LoginResult, *common.CodeBasedError[LoginErrorCode])
+	LoginWithIdentityProvider(ctx context.Context, provider string, idToken string) (LoginResult, *common.CodeBasedError[LoginWithIdentityProviderErrorCode])
+	Refresh(ctx context.Context, refreshToken string) (Session, *common.CodeBasedError[RefreshErrorCode])
+	Logout(ctx context.Context, id UserId) *common.CodeBasedError[LogoutErrorCode]
+
+	UpdateEmail(ctx context.Context, email string, id UserId) (Session, *common.CodeBasedError[UpdateEmailErrorCode])
+	UpdatePassword(ctx context.Context, oldPassword string, newPassword string, id UserId) (Session, *common.CodeBasedError[UpdatePasswordErrorCode])
 
-	UpdateEmail(email string, id UserId) (Session, *common.CodeBasedError[UpdateEmailErrorCode])
-	UpdatePassword(oldPassword string, newPassword string, id UserId) (Session, *common.CodeBasedError[UpdatePasswordErrorCode])
+	RegisterForPushNotifications(ctx context.Context, pushToken PushToken, id UserId) *common.CodeBasedError[RegisterForPushNotificationsErrorCode]
 
-	RegisterForPushNotifications(pushToken string, id UserId) *common.CodeBasedError[RegisterForPushNotificationsErrorCode]
+	EnrollTwoFactor(ctx context.Context, id UserId) (TwoFactorEnrollment, *common.CodeBasedError[EnrollTwoFactorErrorCode])
+	ConfirmTwoFactor(ctx context.Context, code string, id UserId) (ConfirmTwoFactorResult, *common.CodeBasedError[ConfirmTwoFactorErrorCode])
+	VerifyTwoFactor(ctx context.Context, twoFactorToken string, code string) (VerifyTwoFactorResult, *common.CodeBasedError[VerifyTwoFactorErrorCode])
 }
@@ -1,18 +1,32 @@
 package defaultController
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rzmn/governi/internal/common"
+	"github.com/rzmn/governi/internal/db"
 
 	"github.com/rzmn/governi/internal/services/formatValidation"
 	"github.com/rzmn/governi/internal/services/jwt"
 	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/oidc"
+	"github.com/rzmn/governi/internal/services/totp"
 
 	"github.com/rzmn/governi/internal/controllers/auth"
+	"github.com/rzmn/governi/internal/repositories"
 
 	authRepository "github.com/rzmn/governi/internal/repositories/auth"
+	identitiesRepository "github.com/rzmn/governi/internal/repositories/identities"
+	loginAttemptsRepository "github.com/rzmn/governi/internal/repositories/loginAttempts"
 	pushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
+	twoFactorRepository "github.com/rzmn/governi/internal/repositories/twoFactor"
 	usersRepository "github.com/rzmn/governi/internal/repositories/users"
 
 	"github.com/google/uuid"
@@ -21,35 +35,101 @@ import (
 type AuthRepository authRepository.Repository
 type UsersRepository usersRepository.Repository
 type PushTokensRepository pushNotificationsRepository.Repository
+type TwoFactorRepository twoFactorRepository.Repository
+type IdentitiesRepository identitiesRepository.Repository
+type LoginAttemptsRepository loginAttemptsRepository.Repository
 
 func New(
 	authRepository AuthRepository,
 	pushTokensRepository PushTokensRepository,
 	usersRepository UsersRepository,
+	twoFactorRepository TwoFactorRepository,
+	identitiesRepository IdentitiesRepository,
+	loginAttemptsRepository LoginAttemptsRepository,
+	unitOfWork db.UnitOfWork,
 	jwtService jwt.Service,
+	totpService totp.Service,
+	oidcService oidc.Service,
 	formatValidationService formatValidation.Service,
 	logger logging.Service,
+	currentTime func() time.Time,
 ) auth.Controller {
 	return &defaultController{
 		authRepository:          authRepository,
 		pushTokensRepository:    pushTokensRepository,
 		usersRepository:         usersRepository,
+		twoFactorRepository:     twoFactorRepository,
+		identitiesRepository:    identitiesRepository,
+		loginAttemptsRepository: loginAttemptsRepository,
+		unitOfWork:              unitOfWork,
 		jwtService:              jwtService,
+		totpService:             totpService,
+		oidcService:             oidcService,
 		formatValidationService: formatValidationService,
 		logger:                  logger,
+		currentTime:             currentTime,
 	}
 }
 
+const (
+	recoveryCodesCount                = 10
+	recoveryCodeSizeBytes             = 5
+	identityProviderPasswordSizeBytes = 32
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type throttlingPolicy struct {
+	freeAttempts    int
+	lockoutAttempts int
+	baseDelay       time.Duration
+	lockout         time.Duration
+	forgetAfter     time.Duration
+}
+
+var (
+	emailThrottlingPolicy = throttlingPolicy{
+		freeAttempts:    5,
+		lockoutAttempts: 10,
+		baseDelay:       time.Second,
+		lockout:         time.Minute * 15,
+		forgetAfter:     time.Hour,
+	}
+	ipThrottlingPolicy = throttlingPolicy{
+		freeAttempts:    20,
+		lockoutAttempts: 100,
+		baseDelay:       time.Second,
+		lockout:         time.Minute * 15,
+		forgetAfter:     time.Hour,
+	}
+	twoFactorThrottlingPolicy = throttlingPolicy{
+		freeAttempts:    5,
+		lockoutAttempts: 10,
+		baseDelay:       time.Second,
+		lockout:         time.Minute * 15,
+		forgetAfter:     time.Hour,
+	}
+)
+
+var errTwoFactorCodeReused = errors.New("two factor code has already been used")
+
 type defaultController struct {
 	authRepository          AuthRepository
 	pushTokensRepository    PushTokensRepository
 	usersRepository         UsersRepository
+	twoFactorRepository     TwoFactorRepository
+	identitiesRepository    IdentitiesRepository
+	loginAttemptsRepository LoginAttemptsRepository
+	unitOfWork              db.UnitOfWork
 	jwtService              jwt.Service
+	totpService             totp.Service
+	oidcService             oidc.Service
 	formatValidationService formatValidation.Service
 	logger                  logging.Service
+	currentTime             func() time.Time
 }
 
-func (c *defaultController) Signup(email string, password string) (auth.Session, *common.CodeBasedError[auth.SignupErrorCode]) {
+func (c *defaultController) Signup(ctx context.Context, email string, password string) (auth.Session, *common.CodeBasedError[auth.SignupErrorCode]) {
 	const op = "auth.defaultController.Signup"
 	c.logger.LogInfo("%s: start", op)
 	if err := c.formatValidationService.ValidateEmailFormat(email); err != nil {
@@ -60,7 +140,7 @@ func (c *defaultController) Signup(email string, password string) (auth.Session,
 		c.logger.LogInfo("%s: wrong password format err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.SignupErrorWrongFormat, err.Error())
 	}
-	uidAccosiatedWithEmail, err := c.authRepository.GetUserIdByEmail(email)
+	uidAccosiatedWithEmail, err := c.authRepository.GetUserIdByEmail(ctx, email)
 	if err != nil {
 		c.logger.LogInfo("%s: getting uid by credentials from db failed err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.SignupErrorInternal, err.Error())
@@ -80,19 +160,17 @@ func (c *defaultController) Signup(email string, password string) (auth.Session,
 		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, jwtErr)
 		return auth.Session{}, common.NewErrorWithDescription(auth.SignupErrorInternal, jwtErr.Error())
 	}
-	createUserTransaction := c.usersRepository.StoreUser(usersRepository.User{
-		Id:          usersRepository.UserId(uid),
-		DisplayName: strings.Split(email, "@")[0],
-		AvatarId:    nil,
-	})
-	if err := createUserTransaction.Perform(); err != nil {
-		c.logger.LogInfo("storing user meta to db failed err: %v", err)
-		return auth.Session{}, common.NewErrorWithDescription(auth.SignupErrorInternal, err.Error())
-	}
-	transaction := c.authRepository.CreateUser(authRepository.UserId(uid), email, password, string(refreshToken))
-	if err := transaction.Perform(); err != nil {
-		createUserTransaction.Rollback()
-		c.logger.LogInfo("storing credentials to db failed err: %v", err)
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		if err := c.usersRepository.WithTx(tx).StoreUser(ctx, usersRepository.User{
+			Id:          usersRepository.UserId(uid),
+			DisplayName: strings.Split(email, "@")[0],
+			AvatarId:    nil,
+		}).Perform(); err != nil {
+			return err
+		}
+		return c.authRepository.WithTx(tx).CreateUser(ctx, authRepository.UserId(uid), email, password, string(refreshToken)).Perform()
+	}); err != nil {
+		c.logger.LogInfo("%s: storing user to db failed err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.SignupErrorInternal, err.Error())
 	}
 	c.logger.LogInfo("%s: success", op)
@@ -103,51 +181,327 @@ func (c *defaultController) Signup(email string, password string) (auth.Session,
 	}, nil
 }
 
-func (c *defaultController) Login(email string, password string) (auth.Session, *common.CodeBasedError[auth.LoginErrorCode]) {
+func (c *defaultController) Login(ctx context.Context, email string, password string, ip string) (auth.LoginResult, *common.CodeBasedError[auth.LoginErrorCode]) {
 	const op = "auth.defaultController.Login"
 	c.logger.LogInfo("%s: start", op)
-	valid, err := c.authRepository.CheckCredentials(email, password)
+	currentTime := c.currentTime()
+	emailKey := loginAttemptsRepository.Key(fmt.Sprintf("email:%s", strings.ToLower(email)))
+	ipKey := loginAttemptsRepository.Key(fmt.Sprintf("ip:%s", ip))
+	emailFailures, err := c.loginAttemptsRepository.GetFailures(ctx, emailKey)
+	if err != nil {
+		c.logger.LogInfo("%s: getting email login failures from db failed err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+	}
+	ipFailures, err := c.loginAttemptsRepository.GetFailures(ctx, ipKey)
+	if err != nil {
+		c.logger.LogInfo("%s: getting ip login failures from db failed err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+	}
+	retryAfter := max(
+		emailThrottlingPolicy.retryAfter(emailFailures, currentTime),
+		ipThrottlingPolicy.retryAfter(ipFailures, currentTime),
+	)
+	if retryAfter > 0 {
+		retryAfterSec := retryAfterSeconds(retryAfter)
+		c.logger.LogInfo("%s: login is throttled for %d seconds", op, retryAfterSec)
+		return auth.LoginResult{
+			RetryAfterSec: retryAfterSec,
+		}, common.NewErrorWithDescription(auth.LoginErrorTooManyAttempts, fmt.Sprintf("retry after %d seconds", retryAfterSec))
+	}
+	valid, err := c.authRepository.CheckCredentials(ctx, email, password)
 	if err != nil {
 		c.logger.LogInfo("%s: credentials check failed err: %v", op, err)
-		return auth.Session{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
 	}
 	if !valid {
 		c.logger.LogInfo("%s: credentials are wrong", op)
-		return auth.Session{}, common.NewError(auth.LoginErrorWrongCredentials)
+		failures, err := c.recordLoginFailure(ctx, email, ip, map[loginAttemptsRepository.Key]throttlingPolicy{
+			emailKey: emailThrottlingPolicy,
+			ipKey:    ipThrottlingPolicy,
+		}, currentTime)
+		if err != nil {
+			c.logger.LogInfo("%s: recording login failure failed err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+		}
+		retryAfter := max(
+			emailThrottlingPolicy.retryAfter(failures[emailKey], currentTime),
+			ipThrottlingPolicy.retryAfter(failures[ipKey], currentTime),
+		)
+		if retryAfter > 0 {
+			retryAfterSec := retryAfterSeconds(retryAfter)
+			c.logger.LogInfo("%s: login is throttled for %d seconds", op, retryAfterSec)
+			return auth.LoginResult{
+				RetryAfterSec: retryAfterSec,
+			}, common.NewErrorWithDescription(auth.LoginErrorTooManyAttempts, fmt.Sprintf("retry after %d seconds", retryAfterSec))
+		}
+		return auth.LoginResult{}, common.NewError(auth.LoginErrorWrongCredentials)
+	}
+	if emailFailures.Count > 0 {
+		if err := c.loginAttemptsRepository.ResetFailures(ctx, emailKey).Perform(); err != nil {
+			c.logger.LogInfo("%s: resetting email login failures failed err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+		}
 	}
-	uid, err := c.authRepository.GetUserIdByEmail(email)
+	uid, err := c.authRepository.GetUserIdByEmail(ctx, email)
 	if err != nil {
 		c.logger.LogInfo("%s: getting uid by credentials in db failed err: %v", op, err)
-		return auth.Session{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
 	}
 	if uid == nil {
 		c.logger.LogInfo("%s: no uid accosiated with credentials", op)
-		return auth.Session{}, common.NewErrorWithDescription(auth.LoginErrorInternal, "no uid accosiated with credentials")
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, "no uid accosiated with credentials")
+	}
+	twoFactorToken, err := c.issueTwoFactorToken(ctx, string(*uid))
+	if err != nil {
+		c.logger.LogInfo("%s: checking two factor authentication failed err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+	}
+	if twoFactorToken != nil {
+		c.logger.LogInfo("%s: success, two factor verification required", op)
+		return auth.LoginResult{
+			TwoFactorToken: twoFactorToken,
+		}, nil
 	}
 	accessToken, jwtErr := c.jwtService.IssueAccessToken(jwt.Subject(*uid))
 	if jwtErr != nil {
 		c.logger.LogInfo("%s: issuing access token failed err: %v", op, jwtErr)
-		return auth.Session{}, common.NewErrorWithDescription(auth.LoginErrorInternal, jwtErr.Error())
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, jwtErr.Error())
 	}
 	refreshToken, jwtErr := c.jwtService.IssueRefreshToken(jwt.Subject(*uid))
 	if jwtErr != nil {
 		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, jwtErr)
-		return auth.Session{}, common.NewErrorWithDescription(auth.LoginErrorInternal, jwtErr.Error())
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, jwtErr.Error())
 	}
-	transaction := c.authRepository.UpdateRefreshToken(*uid, string(refreshToken))
+	transaction := c.authRepository.UpdateRefreshToken(ctx, *uid, string(refreshToken))
 	if err := transaction.Perform(); err != nil {
 		c.logger.LogInfo("%s: storing refresh token to db failed err: %v", op, err)
-		return auth.Session{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginErrorInternal, err.Error())
 	}
 	c.logger.LogInfo("%s: success", op)
+	return auth.LoginResult{
+		Session: &auth.Session{
+			Id:           auth.UserId(*uid),
+			AccessToken:  string(accessToken),
+			RefreshToken: string(refreshToken),
+		},
+	}, nil
+}
+
+// recordLoginFailure counts the failed attempt against every key in a single unit of work and
+// returns the counters as the database sees them, so concurrent attempts cannot lose increments.
+func (c *defaultController) recordLoginFailure(
+	ctx context.Context,
+	email string,
+	ip string,
+	policies map[loginAttemptsRepository.Key]throttlingPolicy,
+	currentTime time.Time,
+) (map[loginAttemptsRepository.Key]loginAttemptsRepository.Failures, error) {
+	uid, err := c.authRepository.GetUserIdByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	failures := map[loginAttemptsRepository.Key]loginAttemptsRepository.Failures{}
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		loginAttempts := c.loginAttemptsRepository.WithTx(tx)
+		if err := loginAttempts.RecordFailedLogin(ctx, loginAttemptsRepository.FailedLogin{
+			UserId:    (*loginAttemptsRepository.UserId)(uid),
+			Email:     email,
+			Ip:        ip,
+			Timestamp: currentTime.Unix(),
+		}).Perform(); err != nil {
+			return err
+		}
+		for key, policy := range policies {
+			value, err := policy.addFailure(ctx, loginAttempts, key, currentTime)
+			if err != nil {
+				return err
+			}
+			failures[key] = value
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// issueTwoFactorToken returns a token to finish the login with when uid has two factor
+// authentication enabled, nil otherwise.
+func (c *defaultController) issueTwoFactorToken(ctx context.Context, uid string) (*string, error) {
+	secret, err := c.twoFactorRepository.GetSecret(ctx, twoFactorRepository.UserId(uid))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || !secret.Confirmed {
+		return nil, nil
+	}
+	twoFactorToken, jwtErr := c.jwtService.IssueTwoFactorToken(jwt.Subject(uid))
+	if jwtErr != nil {
+		return nil, jwtErr
+	}
+	token := string(twoFactorToken)
+	return &token, nil
+}
+
+func (c *defaultController) LoginWithIdentityProvider(ctx context.Context, provider string, idToken string) (auth.LoginResult, *common.CodeBasedError[auth.LoginWithIdentityProviderErrorCode]) {
+	const op = "auth.defaultController.LoginWithIdentityProvider"
+	c.logger.LogInfo("%s: start[provider=%s]", op, provider)
+	identity, oidcErr := c.oidcService.Verify(oidc.Provider(provider), oidc.IdToken(idToken))
+	if oidcErr != nil {
+		c.logger.LogInfo("%s: id token verification failed err: %v", op, oidcErr)
+		switch oidcErr.Code {
+		case oidc.CodeUnknownProvider:
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorUnknownProvider, oidcErr.Error())
+		case oidc.CodeTokenExpired:
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorTokenExpired, oidcErr.Error())
+		case oidc.CodeTokenInvalid:
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorTokenIsWrong, oidcErr.Error())
+		default:
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, oidcErr.Error())
+		}
+	}
+	linkedIdentity := identitiesRepository.Identity{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+	}
+	linkedUid, err := c.identitiesRepository.GetUserIdByIdentity(ctx, linkedIdentity)
+	if err != nil {
+		c.logger.LogInfo("%s: getting uid by identity from db failed err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+	}
+	if linkedUid != nil {
+		twoFactorToken, err := c.issueTwoFactorToken(ctx, string(*linkedUid))
+		if err != nil {
+			c.logger.LogInfo("%s: checking two factor authentication failed err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+		}
+		if twoFactorToken != nil {
+			c.logger.LogInfo("%s: success, two factor verification required[provider=%s]", op, provider)
+			return auth.LoginResult{
+				TwoFactorToken: twoFactorToken,
+			}, nil
+		}
+		session, err := c.startSession(ctx, string(*linkedUid), func(tx db.DB) []repositories.MutationWorkItem {
+			return []repositories.MutationWorkItem{}
+		})
+		if err != nil {
+			c.logger.LogInfo("%s: cannot start session err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+		}
+		c.logger.LogInfo("%s: success[provider=%s]", op, provider)
+		return auth.LoginResult{
+			Session: &session,
+		}, nil
+	}
+	if identity.Email == nil {
+		c.logger.LogInfo("%s: identity has no email to link with", op)
+		return auth.LoginResult{}, common.NewError(auth.LoginWithIdentityProviderErrorEmailMissing)
+	}
+	uidAccosiatedWithEmail, err := c.authRepository.GetUserIdByEmail(ctx, *identity.Email)
+	if err != nil {
+		c.logger.LogInfo("%s: getting uid by email from db failed err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+	}
+	if uidAccosiatedWithEmail != nil {
+		if !identity.EmailVerified {
+			c.logger.LogInfo("%s: email is taken and is not verified by identity provider", op)
+			return auth.LoginResult{}, common.NewError(auth.LoginWithIdentityProviderErrorEmailAlreadyTaken)
+		}
+		account, err := c.authRepository.GetUserInfo(ctx, *uidAccosiatedWithEmail)
+		if err != nil {
+			c.logger.LogInfo("%s: getting credentials for uid from db failed err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+		}
+		if !account.EmailVerified {
+			// whoever registered the email has never proven owning it, linking would hand the
+			// identity over to an account that might have been created in advance by someone else
+			c.logger.LogInfo("%s: email is taken by an account that has not verified it", op)
+			return auth.LoginResult{}, common.NewError(auth.LoginWithIdentityProviderErrorEmailAlreadyTaken)
+		}
+		// the identity is linked only once two factor verification passes, until then
+		// the next login with it goes through this branch again
+		twoFactorToken, err := c.issueTwoFactorToken(ctx, string(*uidAccosiatedWithEmail))
+		if err != nil {
+			c.logger.LogInfo("%s: checking two factor authentication failed err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+		}
+		if twoFactorToken != nil {
+			c.logger.LogInfo("%s: success, two factor verification required[provider=%s]", op, provider)
+			return auth.LoginResult{
+				TwoFactorToken: twoFactorToken,
+			}, nil
+		}
+		session, err := c.startSession(ctx, string(*uidAccosiatedWithEmail), func(tx db.DB) []repositories.MutationWorkItem {
+			return []repositories.MutationWorkItem{
+				c.identitiesRepository.WithTx(tx).LinkIdentity(ctx, identitiesRepository.UserId(*uidAccosiatedWithEmail), linkedIdentity),
+			}
+		})
+		if err != nil {
+			c.logger.LogInfo("%s: cannot start session err: %v", op, err)
+			return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+		}
+		c.logger.LogInfo("%s: success[provider=%s]", op, provider)
+		return auth.LoginResult{
+			Session: &session,
+		}, nil
+	}
+	uid := uuid.New().String()
+	password, err := generateIdentityProviderPassword()
+	if err != nil {
+		c.logger.LogInfo("%s: cannot generate password err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+	}
+	session, err := c.startSession(ctx, uid, func(tx db.DB) []repositories.MutationWorkItem {
+		credentials := c.authRepository.WithTx(tx)
+		transactions := []repositories.MutationWorkItem{
+			c.usersRepository.WithTx(tx).StoreUser(ctx, usersRepository.User{
+				Id:          usersRepository.UserId(uid),
+				DisplayName: strings.Split(*identity.Email, "@")[0],
+				AvatarId:    nil,
+			}),
+			credentials.CreateUser(ctx, authRepository.UserId(uid), *identity.Email, password, ""),
+		}
+		if identity.EmailVerified {
+			transactions = append(transactions, credentials.MarkUserEmailValidated(ctx, authRepository.UserId(uid)))
+		}
+		return append(transactions, c.identitiesRepository.WithTx(tx).LinkIdentity(ctx, identitiesRepository.UserId(uid), linkedIdentity))
+	})
+	if err != nil {
+		c.logger.LogInfo("%s: cannot start session err: %v", op, err)
+		return auth.LoginResult{}, common.NewErrorWithDescription(auth.LoginWithIdentityProviderErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success[provider=%s]", op, provider)
+	return auth.LoginResult{
+		Session: &session,
+	}, nil
+}
+
+func (c *defaultController) startSession(ctx context.Context, uid string, prepare func(tx db.DB) []repositories.MutationWorkItem) (auth.Session, error) {
+	accessToken, jwtErr := c.jwtService.IssueAccessToken(jwt.Subject(uid))
+	if jwtErr != nil {
+		return auth.Session{}, jwtErr
+	}
+	refreshToken, jwtErr := c.jwtService.IssueRefreshToken(jwt.Subject(uid))
+	if jwtErr != nil {
+		return auth.Session{}, jwtErr
+	}
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		transactions := append(
+			prepare(tx),
+			c.authRepository.WithTx(tx).UpdateRefreshToken(ctx, authRepository.UserId(uid), string(refreshToken)),
+		)
+		return repositories.PerformAll(transactions...)
+	}); err != nil {
+		return auth.Session{}, err
+	}
 	return auth.Session{
-		Id:           auth.UserId(*uid),
+		Id:           auth.UserId(uid),
 		AccessToken:  string(accessToken),
 		RefreshToken: string(refreshToken),
 	}, nil
 }
 
-func (c *defaultController) Refresh(refreshToken string) (auth.Session, *common.CodeBasedError[auth.RefreshErrorCode]) {
+func (c *defaultController) Refresh(ctx context.Context, refreshToken string) (auth.Session, *common.CodeBasedError[auth.RefreshErrorCode]) {
 	const op = "auth.defaultController.Refresh"
 	c.logger.LogInfo("%s: start", op)
 	if err := c.jwtService.ValidateRefreshToken(jwt.RefreshToken(refreshToken)); err != nil {
@@ -166,7 +520,7 @@ func (c *defaultController) Refresh(refreshToken string) (auth.Session, *common.
 		c.logger.LogInfo("%s: cannot get refresh token subject err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.RefreshErrorInternal, err.Error())
 	}
-	user, errGetFromDb := c.authRepository.GetUserInfo(authRepository.UserId(uid))
+	user, errGetFromDb := c.authRepository.GetUserInfo(ctx, authRepository.UserId(uid))
 	if errGetFromDb != nil {
 		c.logger.LogInfo("%s: cannot get user data from db err: %v", op, errGetFromDb)
 		return auth.Session{}, common.NewErrorWithDescription(auth.RefreshErrorInternal, errGetFromDb.Error())
@@ -185,7 +539,7 @@ func (c *defaultController) Refresh(refreshToken string) (auth.Session, *common.
 		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.RefreshErrorInternal, err.Error())
 	}
-	transaction := c.authRepository.UpdateRefreshToken(authRepository.UserId(uid), string(newRefreshToken))
+	transaction := c.authRepository.UpdateRefreshToken(ctx, authRepository.UserId(uid), string(newRefreshToken))
 	if err := transaction.Perform(); err != nil {
 		c.logger.LogInfo("%s: storing refresh token to db failed err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.RefreshErrorInternal, err.Error())
@@ -198,7 +552,7 @@ func (c *defaultController) Refresh(refreshToken string) (auth.Session, *common.
 	}, nil
 }
 
-func (c *defaultController) Logout(id auth.UserId) *common.CodeBasedError[auth.LogoutErrorCode] {
+func (c *defaultController) Logout(ctx context.Context, id auth.UserId) *common.CodeBasedError[auth.LogoutErrorCode] {
 	const op = "auth.defaultController.Logout"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 	refreshToken, jwtErr := c.jwtService.IssueRefreshToken(jwt.Subject(id))
@@ -206,7 +560,7 @@ func (c *defaultController) Logout(id auth.UserId) *common.CodeBasedError[auth.L
 		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, jwtErr)
 		return common.NewErrorWithDescription(auth.LogoutErrorInternal, jwtErr.Error())
 	}
-	updateTokenTransaction := c.authRepository.UpdateRefreshToken(authRepository.UserId(id), string(refreshToken))
+	updateTokenTransaction := c.authRepository.UpdateRefreshToken(ctx, authRepository.UserId(id), string(refreshToken))
 	if err := updateTokenTransaction.Perform(); err != nil {
 		c.logger.LogInfo("%s: storing refresh token to db failed err: %v", op, err)
 		return common.NewErrorWithDescription(auth.LogoutErrorInternal, err.Error())
@@ -215,14 +569,14 @@ func (c *defaultController) Logout(id auth.UserId) *common.CodeBasedError[auth.L
 	return nil
 }
 
-func (c *defaultController) UpdateEmail(email string, id auth.UserId) (auth.Session, *common.CodeBasedError[auth.UpdateEmailErrorCode]) {
+func (c *defaultController) UpdateEmail(ctx context.Context, email string, id auth.UserId) (auth.Session, *common.CodeBasedError[auth.UpdateEmailErrorCode]) {
 	const op = "auth.defaultController.UpdateEmail"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 	if err := c.formatValidationService.ValidateEmailFormat(email); err != nil {
 		c.logger.LogInfo("%s: wrong email format err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdateEmailErrorWrongFormat, err.Error())
 	}
-	uidForNewEmail, err := c.authRepository.GetUserIdByEmail(email)
+	uidForNewEmail, err := c.authRepository.GetUserIdByEmail(ctx, email)
 	if err != nil {
 		c.logger.LogInfo("%s: cannot check email existence in db err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdateEmailErrorInternal, err.Error())
@@ -241,17 +595,16 @@ func (c *defaultController) UpdateEmail(email string, id auth.UserId) (auth.Sess
 		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, jwtErr)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdateEmailErrorInternal, jwtErr.Error())
 	}
-	updateEmailTransaction := c.authRepository.UpdateEmail(authRepository.UserId(id), email)
-	if err := updateEmailTransaction.Perform(); err != nil {
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		credentials := c.authRepository.WithTx(tx)
+		if err := credentials.UpdateEmail(ctx, authRepository.UserId(id), email).Perform(); err != nil {
+			return err
+		}
+		return credentials.UpdateRefreshToken(ctx, authRepository.UserId(id), string(refreshToken)).Perform()
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot update email in db err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdateEmailErrorInternal, err.Error())
 	}
-	updateTokenTransaction := c.authRepository.UpdateRefreshToken(authRepository.UserId(id), string(refreshToken))
-	if err := updateTokenTransaction.Perform(); err != nil {
-		c.logger.LogInfo("%s: storing refresh token to db failed err: %v", op, err)
-		updateEmailTransaction.Rollback()
-		return auth.Session{}, common.NewErrorWithDescription(auth.UpdateEmailErrorInternal, err.Error())
-	}
 	c.logger.LogInfo("%s: success[id=%s]", op, id)
 	return auth.Session{
 		Id:           id,
@@ -260,19 +613,19 @@ func (c *defaultController) UpdateEmail(email string, id auth.UserId) (auth.Sess
 	}, nil
 }
 
-func (c *defaultController) UpdatePassword(oldPassword string, newPassword string, id auth.UserId) (auth.Session, *common.CodeBasedError[auth.UpdatePasswordErrorCode]) {
+func (c *defaultController) UpdatePassword(ctx context.Context, oldPassword string, newPassword string, id auth.UserId) (auth.Session, *common.CodeBasedError[auth.UpdatePasswordErrorCode]) {
 	const op = "auth.defaultController.UpdatePassword"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 	if err := c.formatValidationService.ValidatePasswordFormat(newPassword); err != nil {
 		c.logger.LogInfo("%s: wrong password format err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdatePasswordErrorWrongFormat, err.Error())
 	}
-	account, err := c.authRepository.GetUserInfo(authRepository.UserId(id))
+	account, err := c.authRepository.GetUserInfo(ctx, authRepository.UserId(id))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get credentials for id in db err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdatePasswordErrorInternal, err.Error())
 	}
-	passed, err := c.authRepository.CheckCredentials(account.Email, oldPassword)
+	passed, err := c.authRepository.CheckCredentials(ctx, account.Email, oldPassword)
 	if err != nil {
 		c.logger.LogInfo("%s: cannot check password for id in db err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdatePasswordErrorInternal, err.Error())
@@ -291,17 +644,16 @@ func (c *defaultController) UpdatePassword(oldPassword string, newPassword strin
 		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, jwtErr)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdatePasswordErrorInternal, jwtErr.Error())
 	}
-	updatePasswordTransaction := c.authRepository.UpdatePassword(authRepository.UserId(id), newPassword)
-	if err := updatePasswordTransaction.Perform(); err != nil {
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		credentials := c.authRepository.WithTx(tx)
+		if err := credentials.UpdatePassword(ctx, authRepository.UserId(id), newPassword).Perform(); err != nil {
+			return err
+		}
+		return credentials.UpdateRefreshToken(ctx, authRepository.UserId(id), string(refreshToken)).Perform()
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot update password in db err: %v", op, err)
 		return auth.Session{}, common.NewErrorWithDescription(auth.UpdatePasswordErrorInternal, err.Error())
 	}
-	updateTokenTransaction := c.authRepository.UpdateRefreshToken(authRepository.UserId(id), string(refreshToken))
-	if err := updateTokenTransaction.Perform(); err != nil {
-		c.logger.LogInfo("%s: storing refresh token to db failed err: %v", op, err)
-		updatePasswordTransaction.Rollback()
-		return auth.Session{}, common.NewErrorWithDescription(auth.UpdatePasswordErrorInternal, err.Error())
-	}
 	c.logger.LogInfo("%s: success[id=%s]", op, id)
 	return auth.Session{
 		Id:           id,
@@ -310,10 +662,18 @@ func (c *defaultController) UpdatePassword(oldPassword string, newPassword strin
 	}, nil
 }
 
-func (c *defaultController) RegisterForPushNotifications(pushToken string, id auth.UserId) *common.CodeBasedError[auth.RegisterForPushNotificationsErrorCode] {
-	const op = "auth.defaultController.ConfirmEmail"
-	c.logger.LogInfo("%s: start[id=%s]", op, id)
-	storeTransaction := c.pushTokensRepository.StorePushToken(pushNotificationsRepository.UserId(id), pushToken)
+func (c *defaultController) RegisterForPushNotifications(ctx context.Context, pushToken auth.PushToken, id auth.UserId) *common.CodeBasedError[auth.RegisterForPushNotificationsErrorCode] {
+	const op = "auth.defaultController.RegisterForPushNotifications"
+	c.logger.LogInfo("%s: start[id=%s platform=%s]", op, id, pushToken.Platform)
+	if pushToken.Platform != auth.PushPlatformIos && pushToken.Platform != auth.PushPlatformAndroid {
+		c.logger.LogInfo("%s: unsupported platform %s", op, pushToken.Platform)
+		return common.NewError(auth.RegisterForPushNotificationsErrorUnsupportedPlatform)
+	}
+	storeTransaction := c.pushTokensRepository.StorePushToken(ctx, pushNotificationsRepository.UserId(id), pushNotificationsRepository.PushToken{
+		Platform:   pushNotificationsRepository.Platform(pushToken.Platform),
+		Token:      pushToken.Token,
+		AppVersion: pushToken.AppVersion,
+	})
 	if err := storeTransaction.Perform(); err != nil {
 		c.logger.LogInfo("%s: cannot store push token in db err: %v", op, err)
 		return common.NewErrorWithDescription(auth.RegisterForPushNotificationsErrorInternal, err.Error())
@@ -321,3 +681,311 @@ func (c *defaultController) RegisterForPushNotifications(pushToken string, id au
 	c.logger.LogInfo("%s: success[id=%s]", op, id)
 	return nil
 }
+
+func (c *defaultController) EnrollTwoFactor(ctx context.Context, id auth.UserId) (auth.TwoFactorEnrollment, *common.CodeBasedError[auth.EnrollTwoFactorErrorCode]) {
+	const op = "auth.defaultController.EnrollTwoFactor"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	existed, err := c.twoFactorRepository.GetSecret(ctx, twoFactorRepository.UserId(id))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get two factor secret from db err: %v", op, err)
+		return auth.TwoFactorEnrollment{}, common.NewErrorWithDescription(auth.EnrollTwoFactorErrorInternal, err.Error())
+	}
+	if existed != nil && existed.Confirmed {
+		c.logger.LogInfo("%s: two factor authentication is already enabled", op)
+		return auth.TwoFactorEnrollment{}, common.NewError(auth.EnrollTwoFactorErrorAlreadyEnrolled)
+	}
+	account, err := c.authRepository.GetUserInfo(ctx, authRepository.UserId(id))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get credentials for id in db err: %v", op, err)
+		return auth.TwoFactorEnrollment{}, common.NewErrorWithDescription(auth.EnrollTwoFactorErrorInternal, err.Error())
+	}
+	secret, err := c.totpService.GenerateSecret()
+	if err != nil {
+		c.logger.LogInfo("%s: cannot generate two factor secret err: %v", op, err)
+		return auth.TwoFactorEnrollment{}, common.NewErrorWithDescription(auth.EnrollTwoFactorErrorInternal, err.Error())
+	}
+	storeTransaction := c.twoFactorRepository.StoreSecret(ctx, twoFactorRepository.UserId(id), string(secret))
+	if err := storeTransaction.Perform(); err != nil {
+		c.logger.LogInfo("%s: cannot store two factor secret in db err: %v", op, err)
+		return auth.TwoFactorEnrollment{}, common.NewErrorWithDescription(auth.EnrollTwoFactorErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return auth.TwoFactorEnrollment{
+		Secret: string(secret),
+		Uri:    c.totpService.ProvisioningUri(secret, account.Email),
+	}, nil
+}
+
+func (c *defaultController) ConfirmTwoFactor(ctx context.Context, code string, id auth.UserId) (auth.ConfirmTwoFactorResult, *common.CodeBasedError[auth.ConfirmTwoFactorErrorCode]) {
+	const op = "auth.defaultController.ConfirmTwoFactor"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	currentTime := c.currentTime()
+	failuresKey := twoFactorFailuresKey(string(id))
+	failures, err := c.loginAttemptsRepository.GetFailures(ctx, failuresKey)
+	if err != nil {
+		c.logger.LogInfo("%s: getting two factor failures from db failed err: %v", op, err)
+		return auth.ConfirmTwoFactorResult{}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorInternal, err.Error())
+	}
+	if retryAfter := twoFactorThrottlingPolicy.retryAfter(failures, currentTime); retryAfter > 0 {
+		retryAfterSec := retryAfterSeconds(retryAfter)
+		c.logger.LogInfo("%s: confirmation is throttled for %d seconds", op, retryAfterSec)
+		return auth.ConfirmTwoFactorResult{
+			RetryAfterSec: retryAfterSec,
+		}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorTooManyAttempts, fmt.Sprintf("retry after %d seconds", retryAfterSec))
+	}
+	secret, err := c.twoFactorRepository.GetSecret(ctx, twoFactorRepository.UserId(id))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get two factor secret from db err: %v", op, err)
+		return auth.ConfirmTwoFactorResult{}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorInternal, err.Error())
+	}
+	if secret == nil {
+		c.logger.LogInfo("%s: two factor enrollment not found", op)
+		return auth.ConfirmTwoFactorResult{}, common.NewError(auth.ConfirmTwoFactorErrorNotEnrolled)
+	}
+	if secret.Confirmed {
+		c.logger.LogInfo("%s: two factor authentication is already enabled", op)
+		return auth.ConfirmTwoFactorResult{}, common.NewError(auth.ConfirmTwoFactorErrorAlreadyConfirmed)
+	}
+	step, valid := c.totpService.Validate(totp.Secret(secret.Value), code)
+	if !valid {
+		c.logger.LogInfo("%s: wrong code", op)
+		retryAfter, err := c.recordTwoFactorFailure(ctx, failuresKey, currentTime)
+		if err != nil {
+			c.logger.LogInfo("%s: recording two factor failure failed err: %v", op, err)
+			return auth.ConfirmTwoFactorResult{}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorInternal, err.Error())
+		}
+		if retryAfter > 0 {
+			retryAfterSec := retryAfterSeconds(retryAfter)
+			c.logger.LogInfo("%s: confirmation is throttled for %d seconds", op, retryAfterSec)
+			return auth.ConfirmTwoFactorResult{
+				RetryAfterSec: retryAfterSec,
+			}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorTooManyAttempts, fmt.Sprintf("retry after %d seconds", retryAfterSec))
+		}
+		return auth.ConfirmTwoFactorResult{}, common.NewError(auth.ConfirmTwoFactorErrorWrongCode)
+	}
+	recoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		c.logger.LogInfo("%s: cannot generate recovery codes err: %v", op, err)
+		return auth.ConfirmTwoFactorResult{}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorInternal, err.Error())
+	}
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		twoFactor := c.twoFactorRepository.WithTx(tx)
+		if err := twoFactor.StoreRecoveryCodes(ctx, twoFactorRepository.UserId(id), recoveryCodes).Perform(); err != nil {
+			return err
+		}
+		if err := twoFactor.ConfirmSecret(ctx, twoFactorRepository.UserId(id)).Perform(); err != nil {
+			return err
+		}
+		if _, err := twoFactor.AcceptStep(ctx, twoFactorRepository.UserId(id), int64(step)).Perform(); err != nil {
+			return err
+		}
+		if failures.Count > 0 {
+			return c.loginAttemptsRepository.WithTx(tx).ResetFailures(ctx, failuresKey).Perform()
+		}
+		return nil
+	}); err != nil {
+		c.logger.LogInfo("%s: cannot confirm two factor secret in db err: %v", op, err)
+		return auth.ConfirmTwoFactorResult{}, common.NewErrorWithDescription(auth.ConfirmTwoFactorErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return auth.ConfirmTwoFactorResult{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (c *defaultController) VerifyTwoFactor(ctx context.Context, twoFactorToken string, code string) (auth.VerifyTwoFactorResult, *common.CodeBasedError[auth.VerifyTwoFactorErrorCode]) {
+	const op = "auth.defaultController.VerifyTwoFactor"
+	c.logger.LogInfo("%s: start", op)
+	currentTime := c.currentTime()
+	if err := c.jwtService.ValidateTwoFactorToken(jwt.TwoFactorToken(twoFactorToken)); err != nil {
+		c.logger.LogInfo("%s: token validation failed err: %v", op, err)
+		switch err.Code {
+		case jwt.CodeTokenExpired:
+			return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorTokenExpired, err.Error())
+		case jwt.CodeTokenInvalid:
+			return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorTokenIsWrong, err.Error())
+		default:
+			return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, err.Error())
+		}
+	}
+	uid, jwtErr := c.jwtService.GetTwoFactorTokenSubject(jwt.TwoFactorToken(twoFactorToken))
+	if jwtErr != nil {
+		c.logger.LogInfo("%s: cannot get two factor token subject err: %v", op, jwtErr)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, jwtErr.Error())
+	}
+	failuresKey := twoFactorFailuresKey(string(uid))
+	failures, err := c.loginAttemptsRepository.GetFailures(ctx, failuresKey)
+	if err != nil {
+		c.logger.LogInfo("%s: getting two factor failures from db failed err: %v", op, err)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, err.Error())
+	}
+	if retryAfter := twoFactorThrottlingPolicy.retryAfter(failures, currentTime); retryAfter > 0 {
+		retryAfterSec := retryAfterSeconds(retryAfter)
+		c.logger.LogInfo("%s: verification is throttled for %d seconds", op, retryAfterSec)
+		return auth.VerifyTwoFactorResult{
+			RetryAfterSec: retryAfterSec,
+		}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorTooManyAttempts, fmt.Sprintf("retry after %d seconds", retryAfterSec))
+	}
+	secret, err := c.twoFactorRepository.GetSecret(ctx, twoFactorRepository.UserId(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get two factor secret from db err: %v", op, err)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, err.Error())
+	}
+	if secret == nil || !secret.Confirmed {
+		c.logger.LogInfo("%s: two factor authentication is not enabled for token subject", op)
+		return auth.VerifyTwoFactorResult{}, common.NewError(auth.VerifyTwoFactorErrorTokenIsWrong)
+	}
+	step, isTotpCode := c.totpService.Validate(totp.Secret(secret.Value), code)
+	if !isTotpCode {
+		isRecoveryCode, err := c.twoFactorRepository.HasRecoveryCode(ctx, twoFactorRepository.UserId(uid), code)
+		if err != nil {
+			c.logger.LogInfo("%s: cannot check recovery code in db err: %v", op, err)
+			return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, err.Error())
+		}
+		if !isRecoveryCode {
+			c.logger.LogInfo("%s: wrong code", op)
+			return c.failTwoFactorVerification(ctx, failuresKey, currentTime)
+		}
+	}
+	accessToken, jwtErr := c.jwtService.IssueAccessToken(jwt.Subject(uid))
+	if jwtErr != nil {
+		c.logger.LogInfo("%s: issuing access token failed err: %v", op, jwtErr)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, jwtErr.Error())
+	}
+	refreshToken, jwtErr := c.jwtService.IssueRefreshToken(jwt.Subject(uid))
+	if jwtErr != nil {
+		c.logger.LogInfo("%s: issuing refresh token failed err: %v", op, jwtErr)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, jwtErr.Error())
+	}
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		twoFactor := c.twoFactorRepository.WithTx(tx)
+		transactions := []repositories.MutationWorkItem{}
+		if isTotpCode {
+			accepted, err := twoFactor.AcceptStep(ctx, twoFactorRepository.UserId(uid), int64(step)).Perform()
+			if err != nil {
+				return err
+			}
+			if !accepted {
+				return errTwoFactorCodeReused
+			}
+		} else {
+			transactions = append(transactions, twoFactor.RemoveRecoveryCode(ctx, twoFactorRepository.UserId(uid), code))
+		}
+		if failures.Count > 0 {
+			transactions = append(transactions, c.loginAttemptsRepository.WithTx(tx).ResetFailures(ctx, failuresKey))
+		}
+		transactions = append(transactions, c.authRepository.WithTx(tx).UpdateRefreshToken(ctx, authRepository.UserId(uid), string(refreshToken)))
+		return repositories.PerformAll(transactions...)
+	}); err != nil {
+		if errors.Is(err, errTwoFactorCodeReused) || errors.Is(err, twoFactorRepository.ErrRecoveryCodeNotFound) {
+			c.logger.LogInfo("%s: code has already been used", op)
+			return c.failTwoFactorVerification(ctx, failuresKey, currentTime)
+		}
+		c.logger.LogInfo("%s: storing session to db failed err: %v", op, err)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success", op)
+	return auth.VerifyTwoFactorResult{
+		Session: auth.Session{
+			Id:           auth.UserId(uid),
+			AccessToken:  string(accessToken),
+			RefreshToken: string(refreshToken),
+		},
+	}, nil
+}
+
+// twoFactorFailuresKey is shared by confirmation and verification, both guess the same kind of code.
+func twoFactorFailuresKey(uid string) loginAttemptsRepository.Key {
+	return loginAttemptsRepository.Key(fmt.Sprintf("2fa:%s", uid))
+}
+
+// recordTwoFactorFailure counts the failed attempt and returns how long codes are not accepted after it.
+func (c *defaultController) recordTwoFactorFailure(
+	ctx context.Context,
+	key loginAttemptsRepository.Key,
+	currentTime time.Time,
+) (time.Duration, error) {
+	failures, err := twoFactorThrottlingPolicy.addFailure(ctx, c.loginAttemptsRepository, key, currentTime)
+	if err != nil {
+		return 0, err
+	}
+	return twoFactorThrottlingPolicy.retryAfter(failures, currentTime), nil
+}
+
+func (c *defaultController) failTwoFactorVerification(
+	ctx context.Context,
+	key loginAttemptsRepository.Key,
+	currentTime time.Time,
+) (auth.VerifyTwoFactorResult, *common.CodeBasedError[auth.VerifyTwoFactorErrorCode]) {
+	const op = "auth.defaultController.failTwoFactorVerification"
+	retryAfter, err := c.recordTwoFactorFailure(ctx, key, currentTime)
+	if err != nil {
+		c.logger.LogInfo("%s: recording two factor failure failed err: %v", op, err)
+		return auth.VerifyTwoFactorResult{}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorInternal, err.Error())
+	}
+	if retryAfter > 0 {
+		retryAfterSec := retryAfterSeconds(retryAfter)
+		c.logger.LogInfo("%s: verification is throttled for %d seconds", op, retryAfterSec)
+		return auth.VerifyTwoFactorResult{
+			RetryAfterSec: retryAfterSec,
+		}, common.NewErrorWithDescription(auth.VerifyTwoFactorErrorTooManyAttempts, fmt.Sprintf("retry after %d seconds", retryAfterSec))
+	}
+	return auth.VerifyTwoFactorResult{}, common.NewError(auth.VerifyTwoFactorErrorWrongCode)
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeSizeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return []string{}, err
+		}
+		codes[i] = strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+	}
+	return codes, nil
+}
+
+func generateIdentityProviderPassword() (string, error) {
+	raw := make([]byte, identityProviderPasswordSizeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func (p throttlingPolicy) isForgotten(failures loginAttemptsRepository.Failures, currentTime time.Time) bool {
+	return currentTime.Sub(time.Unix(failures.LastFailureTimestamp, 0)) >= p.forgetAfter
+}
+
+func (p throttlingPolicy) retryAfter(failures loginAttemptsRepository.Failures, currentTime time.Time) time.Duration {
+	if failures.Count < p.freeAttempts || p.isForgotten(failures, currentTime) {
+		return 0
+	}
+	delay := p.lockout
+	if failures.Count < p.lockoutAttempts {
+		delay = p.baseDelay
+		for i := p.freeAttempts; i < failures.Count && delay < p.lockout; i++ {
+			delay *= 2
+		}
+		delay = min(delay, p.lockout)
+	}
+	unlockTime := time.Unix(failures.LastFailureTimestamp, 0).Add(delay)
+	if !currentTime.Before(unlockTime) {
+		return 0
+	}
+	return unlockTime.Sub(currentTime)
+}
+
+func retryAfterSeconds(retryAfter time.Duration) int {
+	return int((retryAfter + time.Second - 1) / time.Second)
+}
+
+func (p throttlingPolicy) addFailure(
+	ctx context.Context,
+	repository loginAttemptsRepository.Repository,
+	key loginAttemptsRepository.Key,
+	currentTime time.Time,
+) (loginAttemptsRepository.Failures, error) {
+	forgetBefore := currentTime.Add(-p.forgetAfter).Unix() + 1
+	return repository.AddFailure(ctx, key, currentTime.Unix(), forgetBefore).Perform()
+}
@@ -1,22 +1,37 @@
 package defaultController_test
 
 import (
+	"context"
 	"errors"
+	"github.com/rzmn/governi/internal/db"
+	db_mock "github.com/rzmn/governi/internal/db/mock"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rzmn/governi/internal/controllers/auth"
 	defaultController "github.com/rzmn/governi/internal/controllers/auth/default"
 	"github.com/rzmn/governi/internal/repositories"
 	authRepository "github.com/rzmn/governi/internal/repositories/auth"
 	auth_mock "github.com/rzmn/governi/internal/repositories/auth/mock"
+	"github.com/rzmn/governi/internal/repositories/identities"
+	identities_mock "github.com/rzmn/governi/internal/repositories/identities/mock"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+	loginAttempts_mock "github.com/rzmn/governi/internal/repositories/loginAttempts/mock"
 	"github.com/rzmn/governi/internal/repositories/pushNotifications"
 	pushNotifications_mock "github.com/rzmn/governi/internal/repositories/pushNotifications/mock"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
+	twoFactor_mock "github.com/rzmn/governi/internal/repositories/twoFactor/mock"
 	"github.com/rzmn/governi/internal/repositories/users"
 	users_mock "github.com/rzmn/governi/internal/repositories/users/mock"
 	formatValidation_mock "github.com/rzmn/governi/internal/services/formatValidation/mock"
 	"github.com/rzmn/governi/internal/services/jwt"
 	jwt_mock "github.com/rzmn/governi/internal/services/jwt/mock"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/oidc"
+	oidc_mock "github.com/rzmn/governi/internal/services/oidc/mock"
+	"github.com/rzmn/governi/internal/services/totp"
+	totp_mock "github.com/rzmn/governi/internal/services/totp/mock"
 
 	"github.com/google/uuid"
 )
@@ -34,15 +49,27 @@ func TestSignupInvalidEmailFormat(t *testing.T) {
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -64,15 +91,27 @@ func TestSignupInvalidPasswordFormat(t *testing.T) {
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -91,22 +130,34 @@ func TestSignupFailedToCheckIfEmailIsTaken(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, errors.New("some error")
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -125,7 +176,7 @@ func TestSignupFailedEmailIsTaken(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			id := uuid.New().String()
 			return (*authRepository.UserId)(&id), nil
 		},
@@ -133,15 +184,27 @@ func TestSignupFailedEmailIsTaken(t *testing.T) {
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -160,7 +223,7 @@ func TestSignupIssueAccessTokenFailed(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, nil
 		},
 	}
@@ -174,15 +237,27 @@ func TestSignupIssueAccessTokenFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -201,7 +276,7 @@ func TestSignupIssueRefreshTokenFailed(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, nil
 		},
 	}
@@ -215,15 +290,27 @@ func TestSignupIssueRefreshTokenFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -242,13 +329,13 @@ func TestSignupCreateUserMetaFailed(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{
-		StoreUserImpl: func(user users.User) repositories.MutationWorkItem {
+		StoreUserImpl: func(ctx context.Context, user users.User) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -264,15 +351,27 @@ func TestSignupCreateUserMetaFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -291,10 +390,10 @@ func TestSignupCreateUserFailed(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, nil
 		},
-		CreateUserImpl: func(uid authRepository.UserId, email, password, refreshToken string) repositories.MutationWorkItem {
+		CreateUserImpl: func(ctx context.Context, uid authRepository.UserId, email, password, refreshToken string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -306,7 +405,7 @@ func TestSignupCreateUserFailed(t *testing.T) {
 	storeUserCalls := 0
 	storeUserRollbacks := 0
 	usersRepositoryMock := users_mock.RepositoryMock{
-		StoreUserImpl: func(user users.User) repositories.MutationWorkItem {
+		StoreUserImpl: func(ctx context.Context, user users.User) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeUserCalls += 1
@@ -327,23 +426,48 @@ func TestSignupCreateUserFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		&unitOfWork,
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
 	if err.Code != auth.SignupErrorInternal {
 		t.Fatalf("err code should be `internal`, found %v", err)
 	}
-	if storeUserCalls != 1 || storeUserRollbacks != 1 {
-		t.Fatalf("store and rollback should be called once, found %d %d", storeUserCalls, storeUserRollbacks)
+	if storeUserCalls != 1 || storeUserRollbacks != 0 {
+		t.Fatalf("store should be called once without manual rollback, found %d %d", storeUserCalls, storeUserRollbacks)
+	}
+	if abortedUnitsOfWork != 1 {
+		t.Fatalf("unit of work should be aborted once, found %d", abortedUnitsOfWork)
 	}
 }
 
@@ -359,10 +483,10 @@ func TestSignupOk(t *testing.T) {
 		},
 	}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, nil
 		},
-		CreateUserImpl: func(uid authRepository.UserId, email, password, refreshToken string) repositories.MutationWorkItem {
+		CreateUserImpl: func(ctx context.Context, uid authRepository.UserId, email, password, refreshToken string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					createUserCalls += 1
@@ -372,7 +496,7 @@ func TestSignupOk(t *testing.T) {
 		},
 	}
 	usersRepositoryMock := users_mock.RepositoryMock{
-		StoreUserImpl: func(user users.User) repositories.MutationWorkItem {
+		StoreUserImpl: func(ctx context.Context, user users.User) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeUserCalls += 1
@@ -390,15 +514,27 @@ func TestSignupOk(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Signup(uuid.New().String(), uuid.New().String())
+	_, err := controller.Signup(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Fatalf("err should be nil, found %v", err)
 	}
@@ -410,22 +546,45 @@ func TestSignupOk(t *testing.T) {
 func TestLoginUnableToCheckCredentials(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
 			return false, errors.New("some error")
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
@@ -435,606 +594,1028 @@ func TestLoginUnableToCheckCredentials(t *testing.T) {
 }
 
 func TestLoginWrongCredentials(t *testing.T) {
+	storedFailures := map[loginAttempts.Key]loginAttempts.Failures{}
+	recordedLogins := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
 			return false, nil
 		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{
+				Count:                2,
+				LastFailureTimestamp: time.Now().Unix(),
+			}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					failures := loginAttempts.Failures{
+						Count:                3,
+						LastFailureTimestamp: timestamp,
+					}
+					storedFailures[key] = failures
+					return failures, nil
+				},
+			}
+		},
+		RecordFailedLoginImpl: func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					recordedLogins += 1
+					return nil
+				},
+			}
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
 	if err.Code != auth.LoginErrorWrongCredentials {
 		t.Fatalf("err code should be `wrong credentials`, found %v", err)
 	}
+	if recordedLogins != 1 {
+		t.Fatalf("should record failed login once")
+	}
+	if len(storedFailures) != 2 {
+		t.Fatalf("should add failures for both email and ip, found %v", storedFailures)
+	}
 }
 
-func TestLoginGetUserFailed(t *testing.T) {
+func TestLoginThrottledByEmail(t *testing.T) {
+	currentTime := time.Now()
+	checkCredentialsCalls := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			checkCredentialsCalls += 1
+			return false, nil
 		},
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			return nil, errors.New("some error")
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "email:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                7,
+				LastFailureTimestamp: currentTime.Add(-time.Second).Unix(),
+			}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+		RecordFailedLoginImpl: func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	result, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LoginErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
+	}
+	if result.RetryAfterSec != 3 {
+		t.Fatalf("should retry after 3 seconds, found %d", result.RetryAfterSec)
+	}
+	if checkCredentialsCalls != 0 {
+		t.Fatalf("credentials should not be checked while throttled")
 	}
 }
 
-func TestLoginGetUserNotFound(t *testing.T) {
+func TestLoginLockedOutByEmail(t *testing.T) {
+	currentTime := time.Now()
+	checkCredentialsCalls := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			checkCredentialsCalls += 1
+			return false, nil
 		},
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
 			return nil, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "email:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                10,
+				LastFailureTimestamp: currentTime.Add(-time.Minute).Unix(),
+			}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+		RecordFailedLoginImpl: func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	result, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LoginErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
+	}
+	if result.RetryAfterSec != 840 {
+		t.Fatalf("should retry after 840 seconds, found %d", result.RetryAfterSec)
+	}
+	if checkCredentialsCalls != 0 {
+		t.Fatalf("credentials should not be checked while throttled")
 	}
 }
 
-func TestLoginIssueAccessTokenFailed(t *testing.T) {
+func TestLoginThrottledByIp(t *testing.T) {
+	currentTime := time.Now()
+	checkCredentialsCalls := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			checkCredentialsCalls += 1
+			return false, nil
 		},
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			uid := uuid.New().String()
-			return (*authRepository.UserId)(&uid), nil
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "ip:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                21,
+				LastFailureTimestamp: currentTime.Add(-time.Second).Unix(),
+			}, nil
 		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+		RecordFailedLoginImpl: func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	result, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LoginErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
+	}
+	if result.RetryAfterSec != 1 {
+		t.Fatalf("should retry after 1 seconds, found %d", result.RetryAfterSec)
+	}
+	if checkCredentialsCalls != 0 {
+		t.Fatalf("credentials should not be checked while throttled")
 	}
 }
 
-func TestLoginIssueRefreshTokenFailed(t *testing.T) {
+func TestLoginBackoffElapsed(t *testing.T) {
+	currentTime := time.Now()
+	checkCredentialsCalls := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			checkCredentialsCalls += 1
+			return false, nil
 		},
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			uid := uuid.New().String()
-			return (*authRepository.UserId)(&uid), nil
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "email:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                7,
+				LastFailureTimestamp: currentTime.Add(-time.Second * 4).Unix(),
+			}, nil
 		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                8,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+		RecordFailedLoginImpl: func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	result, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LoginErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
+	}
+	if result.RetryAfterSec != 8 {
+		t.Fatalf("should retry after 8 seconds, found %d", result.RetryAfterSec)
+	}
+	if checkCredentialsCalls != 1 {
+		t.Fatalf("credentials should be checked once")
 	}
 }
 
-func TestLoginUpdateTokenFailed(t *testing.T) {
+func TestLoginFailuresForgotten(t *testing.T) {
+	currentTime := time.Now()
+	checkCredentialsCalls := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
-		},
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			uid := uuid.New().String()
-			return (*authRepository.UserId)(&uid), nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			checkCredentialsCalls += 1
+			return false, nil
 		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					return errors.New("some error")
-				},
-			}
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "email:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                10,
+				LastFailureTimestamp: currentTime.Add(-time.Hour * 2).Unix(),
+			}, nil
 		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+		RecordFailedLoginImpl: func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LoginErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginErrorWrongCredentials {
+		t.Fatalf("err code should be `wrong credentials`, found %v", err)
+	}
+	if checkCredentialsCalls != 1 {
+		t.Fatalf("credentials should be checked once")
 	}
 }
 
-func TestLoginOk(t *testing.T) {
-	updateTokenCalls := 0
+func TestLoginGetUserFailed(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
 			return true, nil
 		},
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			uid := uuid.New().String()
-			return (*authRepository.UserId)(&uid), nil
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, errors.New("some error")
 		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					updateTokenCalls += 1
 					return nil
 				},
 			}
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
-		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
-		},
-	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Login(uuid.New().String(), uuid.New().String())
-	if err != nil {
-		t.Fatalf("err should be nil, found %v", err)
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
+	if err == nil {
+		t.Fatalf("err should not be nil")
 	}
-	if updateTokenCalls != 1 {
-		t.Fatalf("should update token once")
+	if err.Code != auth.LoginErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestRefreshTokenExpired(t *testing.T) {
+func TestLoginGetUserNotFound(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return &jwt.Error{Code: jwt.CodeTokenExpired}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.RefreshErrorTokenExpired {
-		t.Fatalf("err code should be `token expired`, found %v", err)
+	if err.Code != auth.LoginErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestRefreshTokenWrong(t *testing.T) {
+func TestLoginIssueAccessTokenFailed(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
+		},
+	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return &jwt.Error{Code: jwt.CodeTokenInvalid}
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.RefreshErrorTokenIsWrong {
-		t.Fatalf("err code should be `token wrong`, found %v", err)
+	if err.Code != auth.LoginErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestRefreshUnableToValidateToken(t *testing.T) {
+func TestLoginIssueRefreshTokenFailed(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
+		},
+	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return &jwt.Error{Code: jwt.CodeInternal}
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
 		},
 	}
-	controller := defaultController.New(
-		&authRepositoryMock,
-		&pushTokensRepositoryMock,
-		&usersRepositoryMock,
-		&jwtServiceMock,
-		&formatValidatorMock,
-		standartOutputLoggingService.New(),
-	)
-	_, err := controller.Refresh(uuid.New().String())
-	if err == nil {
-		t.Fatalf("err should not be nil")
-	}
-	if err.Code != auth.RefreshErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
 	}
-}
-
-func TestRefreshUnableToGetSubject(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
 		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), &jwt.Error{}
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.RefreshErrorInternal {
+	if err.Code != auth.LoginErrorInternal {
 		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestRefreshUnableToGetCurrentToken(t *testing.T) {
+func TestLoginUpdateTokenFailed(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, errors.New("some error")
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
 		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), nil
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(uuid.New().String())
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.RefreshErrorInternal {
+	if err.Code != auth.LoginErrorInternal {
 		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestRefreshTokensDidNotMatch(t *testing.T) {
-	currentToken := uuid.New().String()
+func TestLoginOk(t *testing.T) {
+	updateTokenCalls := 0
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{
-				RefreshToken: currentToken,
-			}, nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateTokenCalls += 1
+					return nil
+				},
+			}
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
 		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), nil
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(uuid.New().String())
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	_, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
 	}
-	if err.Code != auth.RefreshErrorTokenIsWrong {
-		t.Fatalf("err code should be `token wrong`, found %v", err)
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once")
 	}
 }
 
-func TestRefreshIssueAccessTokenFailed(t *testing.T) {
-	currentToken := uuid.New().String()
+func TestLoginTwoFactorRequired(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{
-				RefreshToken: currentToken,
-			}, nil
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
+		IssueTwoFactorTokenImpl: func(subject jwt.Subject) (jwt.TwoFactorToken, *jwt.Error) {
+			return jwt.TwoFactorToken(uuid.New().String()), nil
 		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), nil
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
 		},
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
 		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(currentToken)
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	result, err := controller.Login(context.Background(), uuid.New().String(), uuid.New().String(), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
 	}
-	if err.Code != auth.RefreshErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if result.Session != nil {
+		t.Fatalf("session should not be issued before two factor verification")
+	}
+	if result.TwoFactorToken == nil {
+		t.Fatalf("two factor token should be issued")
 	}
 }
 
-func TestRefreshIssueRefreshTokenFailed(t *testing.T) {
-	currentToken := uuid.New().String()
+func TestLoginWithIdentityProviderUnknownProvider(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{
-				RefreshToken: currentToken,
-			}, nil
-		},
-	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
-		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), nil
-		},
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
-		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			return oidc.Identity{}, &oidc.Error{Code: oidc.CodeUnknownProvider}
 		},
 	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(currentToken)
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.RefreshErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginWithIdentityProviderErrorUnknownProvider {
+		t.Fatalf("err code should be `unknown provider`, found %v", err)
 	}
 }
 
-func TestRefreshUpdateRefreshTokenFailed(t *testing.T) {
-	currentToken := uuid.New().String()
+func TestLoginWithIdentityProviderTokenExpired(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{
-				RefreshToken: currentToken,
-			}, nil
-		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					return errors.New("some error")
-				},
-			}
-		},
-	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
-		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), nil
-		},
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
-		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			return oidc.Identity{}, &oidc.Error{Code: oidc.CodeTokenExpired}
 		},
 	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(currentToken)
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.RefreshErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginWithIdentityProviderErrorTokenExpired {
+		t.Fatalf("err code should be `token expired`, found %v", err)
 	}
 }
 
-func TestRefreshOk(t *testing.T) {
-	updateRefreshTokenCalls := 0
-	currentToken := uuid.New().String()
+func TestLoginWithIdentityProviderLinkedIdentityOk(t *testing.T) {
+	updateTokenCalls := 0
+	linkedUid := uuid.New().String()
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{
-				RefreshToken: currentToken,
-			}, nil
-		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					updateRefreshTokenCalls += 1
+					updateTokenCalls += 1
 					return nil
 				},
 			}
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
-			return nil
-		},
-		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
-			return jwt.Subject(uuid.New().String()), nil
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+			return (*identities.UserId)(&linkedUid), nil
 		},
+	}
+	jwtServiceMock := jwt_mock.ServiceMock{
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
 		},
@@ -1042,299 +1623,532 @@ func TestRefreshOk(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         nil,
+				EmailVerified: false,
+			}, nil
+		},
+	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.Refresh(currentToken)
+	result, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Fatalf("err should be nil, found %v", err)
 	}
-	if updateRefreshTokenCalls != 1 {
-		t.Fatalf("token should be updated once, found %d", updateRefreshTokenCalls)
+	if result.Session == nil || result.Session.Id != auth.UserId(linkedUid) {
+		t.Fatalf("session should belong to linked user %s, found %v", linkedUid, result.Session)
+	}
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once")
 	}
 }
 
-func TestLogoutIssueNewRefreshTokenFailed(t *testing.T) {
+func TestLoginWithIdentityProviderEmailMissing(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+			return nil, nil
+		},
+	}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         nil,
+				EmailVerified: false,
+			}, nil
 		},
 	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	err := controller.Logout(auth.UserId(uuid.New().String()))
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LogoutErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginWithIdentityProviderErrorEmailMissing {
+		t.Fatalf("err code should be `email missing`, found %v", err)
 	}
 }
 
-func TestLogoutUpdateRefreshTokenFailed(t *testing.T) {
+func TestLoginWithIdentityProviderUnverifiedEmailAlreadyTaken(t *testing.T) {
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					return errors.New("some error")
-				},
-			}
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+			return nil, nil
 		},
 	}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			email := uuid.New().String()
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         &email,
+				EmailVerified: false,
+			}, nil
+		},
+	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	err := controller.Logout(auth.UserId(uuid.New().String()))
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.LogoutErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginWithIdentityProviderErrorEmailAlreadyTaken {
+		t.Fatalf("err code should be `email already taken`, found %v", err)
 	}
 }
 
-func TestLogoutOk(t *testing.T) {
-	updateRefreshTokenCalls := 0
+func TestLoginWithIdentityProviderLinksExistingAccount(t *testing.T) {
+	updateTokenCalls := 0
+	linkCalls := 0
+	linkRollbacks := 0
+	existingUid := uuid.New().String()
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return (*authRepository.UserId)(&existingUid), nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				UserId:        uid,
+				EmailVerified: true,
+			}, nil
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					updateRefreshTokenCalls += 1
+					updateTokenCalls += 1
 					return nil
 				},
 			}
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+			return nil, nil
+		},
+		LinkIdentityImpl: func(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
+			if uid != identities.UserId(existingUid) {
+				t.Fatalf("identity should be linked with existing user %s, found %s", existingUid, uid)
+			}
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					linkCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					linkRollbacks += 1
+					return nil
+				},
+			}
+		},
+	}
 	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
 		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			email := uuid.New().String()
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         &email,
+				EmailVerified: true,
+			}, nil
+		},
+	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	err := controller.Logout(auth.UserId(uuid.New().String()))
+	result, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Fatalf("err should be nil, found %v", err)
 	}
-	if updateRefreshTokenCalls != 1 {
-		t.Fatalf("token should be updated once, found %d", updateRefreshTokenCalls)
-	}
-}
-
-func TestUpdateEmailWrongFormat(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return errors.New("some error")
-		},
+	if result.Session == nil || result.Session.Id != auth.UserId(existingUid) {
+		t.Fatalf("session should belong to existing user %s, found %v", existingUid, result.Session)
 	}
-	authRepositoryMock := auth_mock.RepositoryMock{}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
-	controller := defaultController.New(
-		&authRepositoryMock,
-		&pushTokensRepositoryMock,
-		&usersRepositoryMock,
-		&jwtServiceMock,
-		&formatValidatorMock,
-		standartOutputLoggingService.New(),
-	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	if linkCalls != 1 || linkRollbacks != 0 {
+		t.Fatalf("should link identity once, found %d links %d rollbacks", linkCalls, linkRollbacks)
 	}
-	if err.Code != auth.UpdateEmailErrorWrongFormat {
-		t.Fatalf("err code should be `wrong format`, found %v", err)
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once")
 	}
 }
 
-func TestUpdateEmailGetUserFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
-		},
-	}
+func TestLoginWithIdentityProviderLinkedIdentityTwoFactorRequired(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			return nil, errors.New("some error")
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			t.Fatalf("session should not be started before two factor verification")
+			return repositories.MutationWorkItem{}
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
-	controller := defaultController.New(
-		&authRepositoryMock,
-		&pushTokensRepositoryMock,
-		&usersRepositoryMock,
-		&jwtServiceMock,
-		&formatValidatorMock,
-		standartOutputLoggingService.New(),
-	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+			uid := uuid.New().String()
+			return (*identities.UserId)(&uid), nil
+		},
 	}
-	if err.Code != auth.UpdateEmailErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueTwoFactorTokenImpl: func(subject jwt.Subject) (jwt.TwoFactorToken, *jwt.Error) {
+			return jwt.TwoFactorToken(uuid.New().String()), nil
+		},
 	}
-}
-
-func TestUpdateEmailAlreadyTaken(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
 		},
 	}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			id := uuid.New().String()
-			return (*authRepository.UserId)(&id), nil
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			return oidc.Identity{
+				Issuer:  "https://appleid.apple.com",
+				Subject: uuid.New().String(),
+			}, nil
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	result, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
 	}
-	if err.Code != auth.UpdateEmailErrorAlreadyTaken {
-		t.Fatalf("err code should be `already taken`, found %v", err)
+	if result.Session != nil {
+		t.Fatalf("session should not be issued before two factor verification")
+	}
+	if result.TwoFactorToken == nil {
+		t.Fatalf("two factor token should be issued")
 	}
 }
 
-func TestUpdateEmailIssueAccessTokenFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
+func TestLoginWithIdentityProviderExistingAccountEmailNotVerified(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				UserId:        uid,
+				EmailVerified: false,
+			}, nil
 		},
 	}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
 			return nil, nil
 		},
+		LinkIdentityImpl: func(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
+			t.Fatalf("identity should not be linked with an account that has not verified its email")
+			return repositories.MutationWorkItem{}
+		},
 	}
+	jwtServiceMock := jwt_mock.ServiceMock{}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
-		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			email := uuid.New().String()
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         &email,
+				EmailVerified: true,
+			}, nil
 		},
 	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdateEmailErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.LoginWithIdentityProviderErrorEmailAlreadyTaken {
+		t.Fatalf("err code should be `email already taken`, found %v", err)
 	}
 }
 
-func TestUpdateEmailIssueRefreshTokenFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
+func TestLoginWithIdentityProviderExistingAccountTwoFactorRequired(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			uid := uuid.New().String()
+			return (*authRepository.UserId)(&uid), nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				UserId:        uid,
+				EmailVerified: true,
+			}, nil
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			t.Fatalf("session should not be started before two factor verification")
+			return repositories.MutationWorkItem{}
 		},
 	}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
 			return nil, nil
 		},
+		LinkIdentityImpl: func(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
+			t.Fatalf("identity should not be linked before two factor verification")
+			return repositories.MutationWorkItem{}
+		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
+		IssueTwoFactorTokenImpl: func(subject jwt.Subject) (jwt.TwoFactorToken, *jwt.Error) {
+			return jwt.TwoFactorToken(uuid.New().String()), nil
 		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			email := uuid.New().String()
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         &email,
+				EmailVerified: true,
+			}, nil
 		},
 	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	result, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
 	}
-	if err.Code != auth.UpdateEmailErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if result.Session != nil {
+		t.Fatalf("session should not be issued before two factor verification")
+	}
+	if result.TwoFactorToken == nil {
+		t.Fatalf("two factor token should be issued")
 	}
 }
 
-func TestUpdateEmailEmailUpdateFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
+func TestLoginWithIdentityProviderNewUserLinkFailed(t *testing.T) {
+	storeUserCalls := 0
+	storeUserRollbacks := 0
+	createUserCalls := 0
+	createUserRollbacks := 0
+	markValidatedCalls := 0
+	markValidatedRollbacks := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+		CreateUserImpl: func(ctx context.Context, uid authRepository.UserId, email, password, refreshToken string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					createUserCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					createUserRollbacks += 1
+					return nil
+				},
+			}
+		},
+		MarkUserEmailValidatedImpl: func(ctx context.Context, uid authRepository.UserId) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					markValidatedCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					markValidatedRollbacks += 1
+					return nil
+				},
+			}
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					t.Fatalf("refresh token should not be stored after failure")
+					return nil
+				},
+			}
 		},
 	}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
+	usersRepositoryMock := users_mock.RepositoryMock{
+		StoreUserImpl: func(ctx context.Context, user users.User) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					storeUserCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					storeUserRollbacks += 1
+					return nil
+				},
+			}
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
 			return nil, nil
 		},
-		UpdateEmailImpl: func(uid authRepository.UserId, newEmail string) repositories.MutationWorkItem {
+		LinkIdentityImpl: func(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -1342,8 +2156,6 @@ func TestUpdateEmailEmailUpdateFailed(t *testing.T) {
 			}
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
@@ -1352,57 +2164,136 @@ func TestUpdateEmailEmailUpdateFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			email := uuid.New().String()
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         &email,
+				EmailVerified: true,
+			}, nil
+		},
+	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		&unitOfWork,
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdateEmailErrorInternal {
+	if err.Code != auth.LoginWithIdentityProviderErrorInternal {
 		t.Fatalf("err code should be `internal`, found %v", err)
 	}
+	if storeUserCalls != 1 || createUserCalls != 1 || markValidatedCalls != 1 {
+		t.Fatalf("should perform each step once, found %d %d %d", storeUserCalls, createUserCalls, markValidatedCalls)
+	}
+	if storeUserRollbacks != 0 || createUserRollbacks != 0 || markValidatedRollbacks != 0 {
+		t.Fatalf("should not rollback steps manually, found %d %d %d", storeUserRollbacks, createUserRollbacks, markValidatedRollbacks)
+	}
+	if abortedUnitsOfWork != 1 {
+		t.Fatalf("unit of work should be aborted once, found %d", abortedUnitsOfWork)
+	}
 }
 
-func TestUpdateEmailTokenUpdateFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
+func TestLoginWithIdentityProviderNewUserOk(t *testing.T) {
+	updateTokenCalls := 0
+	storeUserCalls := 0
+	storeUserRollbacks := 0
+	createUserCalls := 0
+	createUserRollbacks := 0
+	linkCalls := 0
+	linkRollbacks := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
 		},
-	}
-	updateEmailCalls := 0
-	updateEmailRollbacks := 0
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			return nil, nil
+		CreateUserImpl: func(ctx context.Context, uid authRepository.UserId, email, password, refreshToken string) repositories.MutationWorkItem {
+			if password == "" {
+				t.Fatalf("password should not be empty")
+			}
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					createUserCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					createUserRollbacks += 1
+					return nil
+				},
+			}
+		},
+		MarkUserEmailValidatedImpl: func(ctx context.Context, uid authRepository.UserId) repositories.MutationWorkItem {
+			t.Fatalf("unverified email should not be marked as validated")
+			return repositories.MutationWorkItem{}
 		},
-		UpdateEmailImpl: func(uid authRepository.UserId, newEmail string) repositories.MutationWorkItem {
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					updateEmailCalls += 1
+					updateTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	usersRepositoryMock := users_mock.RepositoryMock{
+		StoreUserImpl: func(ctx context.Context, user users.User) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					storeUserCalls += 1
 					return nil
 				},
 				Rollback: func() error {
-					updateEmailRollbacks += 1
+					storeUserRollbacks += 1
 					return nil
 				},
 			}
 		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{
+		GetUserIdByIdentityImpl: func(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+			return nil, nil
+		},
+		LinkIdentityImpl: func(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					return errors.New("some error")
+					linkCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					linkRollbacks += 1
+					return nil
 				},
 			}
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
@@ -1411,320 +2302,2795 @@ func TestUpdateEmailTokenUpdateFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			email := uuid.New().String()
+			return oidc.Identity{
+				Issuer:        "https://appleid.apple.com",
+				Subject:       uuid.New().String(),
+				Email:         &email,
+				EmailVerified: false,
+			}, nil
+		},
+	}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	_, err := controller.LoginWithIdentityProvider(context.Background(), uuid.New().String(), uuid.New().String())
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
 	}
-	if err.Code != auth.UpdateEmailErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if storeUserCalls != 1 || createUserCalls != 1 || linkCalls != 1 {
+		t.Fatalf("should perform each step once, found %d %d %d", storeUserCalls, createUserCalls, linkCalls)
 	}
-	if updateEmailCalls != 1 {
-		t.Fatalf("should update email once, found %d", updateEmailCalls)
+	if storeUserRollbacks != 0 || createUserRollbacks != 0 || linkRollbacks != 0 {
+		t.Fatalf("should not rollback, found %d %d %d", storeUserRollbacks, createUserRollbacks, linkRollbacks)
 	}
-	if updateEmailRollbacks != 1 {
-		t.Fatalf("should update email rollback once, found %d", updateEmailRollbacks)
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once")
 	}
 }
 
-func TestUpdateEmailOk(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidateEmailFormatImpl: func(email string) error {
-			return nil
+func TestRefreshTokenExpired(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return &jwt.Error{Code: jwt.CodeTokenExpired}
 		},
 	}
-	updateEmailCalls := 0
-	updateTokenCalls := 0
-	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserIdByEmailImpl: func(email string) (*authRepository.UserId, error) {
-			return nil, nil
-		},
-		UpdateEmailImpl: func(uid authRepository.UserId, newEmail string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					updateEmailCalls += 1
-					return nil
-				},
-			}
-		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					updateTokenCalls += 1
-					return nil
-				},
-			}
-		},
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.Refresh(context.Background(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.RefreshErrorTokenExpired {
+		t.Fatalf("err code should be `token expired`, found %v", err)
 	}
+}
+
+func TestRefreshTokenWrong(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), nil
-		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return &jwt.Error{Code: jwt.CodeTokenInvalid}
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdateEmail(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err != nil {
-		t.Fatalf("err should be nil, found %v", err)
-	}
-	if updateEmailCalls != 1 {
-		t.Fatalf("should update email once, found %d", updateEmailCalls)
+	_, err := controller.Refresh(context.Background(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
 	}
-	if updateTokenCalls != 1 {
-		t.Fatalf("should update token once, found %d", updateTokenCalls)
+	if err.Code != auth.RefreshErrorTokenIsWrong {
+		t.Fatalf("err code should be `token wrong`, found %v", err)
 	}
 }
 
-func TestUpdatePasswordNewPasswordHasWrongFormat(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return errors.New("some error")
-		},
-	}
+func TestRefreshUnableToValidateToken(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return &jwt.Error{Code: jwt.CodeInternal}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.Refresh(context.Background(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorWrongFormat {
-		t.Fatalf("err code should be `wrong format`, found %v", err)
+	if err.Code != auth.RefreshErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestUpdatePasswordGetUserFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
+func TestRefreshUnableToGetSubject(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
 			return nil
 		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), &jwt.Error{}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.Refresh(context.Background(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.RefreshErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
 	}
+}
+
+func TestRefreshUnableToGetCurrentToken(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
 			return authRepository.UserInfo{}, errors.New("some error")
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return nil
+		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.Refresh(context.Background(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorInternal {
+	if err.Code != auth.RefreshErrorInternal {
 		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestUpdatePasswordCredentialsCheckFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
-		},
-	}
+func TestRefreshTokensDidNotMatch(t *testing.T) {
+	currentToken := uuid.New().String()
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return false, errors.New("some error")
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				RefreshToken: currentToken,
+			}, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return nil
+		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.Refresh(context.Background(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.RefreshErrorTokenIsWrong {
+		t.Fatalf("err code should be `token wrong`, found %v", err)
 	}
 }
 
-func TestUpdatePasswordOldPasswordIsWrong(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
-		},
-	}
+func TestRefreshIssueAccessTokenFailed(t *testing.T) {
+	currentToken := uuid.New().String()
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return false, nil
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				RefreshToken: currentToken,
+			}, nil
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return nil
+		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.Refresh(context.Background(), currentToken)
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorOldPasswordIsWrong {
-		t.Fatalf("err code should be `old password is wrong`, found %v", err)
+	if err.Code != auth.RefreshErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
 	}
 }
 
-func TestUpdatePasswordIssueAccessTokenFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
+func TestRefreshIssueRefreshTokenFailed(t *testing.T) {
+	currentToken := uuid.New().String()
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				RefreshToken: currentToken,
+			}, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return nil
+		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.Refresh(context.Background(), currentToken)
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.RefreshErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestRefreshUpdateRefreshTokenFailed(t *testing.T) {
+	currentToken := uuid.New().String()
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				RefreshToken: currentToken,
+			}, nil
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return nil
+		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.Refresh(context.Background(), currentToken)
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.RefreshErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestRefreshOk(t *testing.T) {
+	updateRefreshTokenCalls := 0
+	currentToken := uuid.New().String()
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				RefreshToken: currentToken,
+			}, nil
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateRefreshTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateRefreshTokenImpl: func(token jwt.RefreshToken) *jwt.Error {
+			return nil
+		},
+		GetRefreshTokenSubjectImpl: func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.Refresh(context.Background(), currentToken)
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if updateRefreshTokenCalls != 1 {
+		t.Fatalf("token should be updated once, found %d", updateRefreshTokenCalls)
+	}
+}
+
+func TestLogoutIssueNewRefreshTokenFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	err := controller.Logout(context.Background(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.LogoutErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestLogoutUpdateRefreshTokenFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	err := controller.Logout(context.Background(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.LogoutErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestLogoutOk(t *testing.T) {
+	updateRefreshTokenCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateRefreshTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	err := controller.Logout(context.Background(), auth.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if updateRefreshTokenCalls != 1 {
+		t.Fatalf("token should be updated once, found %d", updateRefreshTokenCalls)
+	}
+}
+
+func TestUpdateEmailWrongFormat(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return errors.New("some error")
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorWrongFormat {
+		t.Fatalf("err code should be `wrong format`, found %v", err)
+	}
+}
+
+func TestUpdateEmailGetUserFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, errors.New("some error")
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdateEmailAlreadyTaken(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			id := uuid.New().String()
+			return (*authRepository.UserId)(&id), nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorAlreadyTaken {
+		t.Fatalf("err code should be `already taken`, found %v", err)
+	}
+}
+
+func TestUpdateEmailIssueAccessTokenFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdateEmailIssueRefreshTokenFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdateEmailEmailUpdateFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+		UpdateEmailImpl: func(ctx context.Context, uid authRepository.UserId, newEmail string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdateEmailTokenUpdateFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	updateEmailCalls := 0
+	updateEmailRollbacks := 0
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+		UpdateEmailImpl: func(ctx context.Context, uid authRepository.UserId, newEmail string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateEmailCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					updateEmailRollbacks += 1
+					return nil
+				},
+			}
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		&unitOfWork,
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdateEmailErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+	if updateEmailCalls != 1 {
+		t.Fatalf("should update email once, found %d", updateEmailCalls)
+	}
+	if updateEmailRollbacks != 0 || abortedUnitsOfWork != 1 {
+		t.Fatalf("should abort unit of work instead of manual rollback, found %d rollbacks %d aborts", updateEmailRollbacks, abortedUnitsOfWork)
+	}
+}
+
+func TestUpdateEmailOk(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+	}
+	updateEmailCalls := 0
+	updateTokenCalls := 0
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserIdByEmailImpl: func(ctx context.Context, email string) (*authRepository.UserId, error) {
+			return nil, nil
+		},
+		UpdateEmailImpl: func(ctx context.Context, uid authRepository.UserId, newEmail string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateEmailCalls += 1
+					return nil
+				},
+			}
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdateEmail(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if updateEmailCalls != 1 {
+		t.Fatalf("should update email once, found %d", updateEmailCalls)
+	}
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once, found %d", updateTokenCalls)
+	}
+}
+
+func TestUpdatePasswordNewPasswordHasWrongFormat(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return errors.New("some error")
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorWrongFormat {
+		t.Fatalf("err code should be `wrong format`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordGetUserFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, errors.New("some error")
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordCredentialsCheckFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return false, errors.New("some error")
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordOldPasswordIsWrong(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return false, nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorOldPasswordIsWrong {
+		t.Fatalf("err code should be `old password is wrong`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordIssueAccessTokenFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordIssueRefreshTokenFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordPasswordUpdateFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+		UpdatePasswordImpl: func(ctx context.Context, uid authRepository.UserId, newPassword string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestUpdatePasswordTokenUpdateFailed(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	updatePasswordCalls := 0
+	updatePasswordRollbacks := 0
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+		UpdatePasswordImpl: func(ctx context.Context, uid authRepository.UserId, newPassword string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updatePasswordCalls += 1
+					return nil
+				},
+				Rollback: func() error {
+					updatePasswordRollbacks += 1
+					return nil
+				},
+			}
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		&unitOfWork,
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.UpdatePasswordErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+	if updatePasswordCalls != 1 {
+		t.Fatalf("password should be updated once, found %d", updatePasswordCalls)
+	}
+	if updatePasswordRollbacks != 0 || abortedUnitsOfWork != 1 {
+		t.Fatalf("should abort unit of work instead of manual rollback, found %d rollbacks %d aborts", updatePasswordRollbacks, abortedUnitsOfWork)
+	}
+}
+
+func TestUpdatePasswordOk(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	updatePasswordCalls := 0
+	updateTokenCalls := 0
+	authRepositoryMock := auth_mock.RepositoryMock{
+		CheckCredentialsImpl: func(ctx context.Context, email, password string) (bool, error) {
+			return true, nil
+		},
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{}, nil
+		},
+		UpdatePasswordImpl: func(ctx context.Context, uid authRepository.UserId, newPassword string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updatePasswordCalls += 1
+					return nil
+				},
+			}
+		},
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.UpdatePassword(context.Background(), uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if updatePasswordCalls != 1 {
+		t.Fatalf("password should be updated once, found %d", updatePasswordCalls)
+	}
+	if updateTokenCalls != 1 {
+		t.Fatalf("token update should be once, found %d", updateTokenCalls)
+	}
+}
+
+func TestRegisterForPushNotificationsFailedToStoreToken(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{
+		StorePushTokenImpl: func(ctx context.Context, uid pushNotifications.UserId, token pushNotifications.PushToken) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	err := controller.RegisterForPushNotifications(context.Background(), auth.PushToken{Token: uuid.New().String(), Platform: auth.PushPlatformIos}, auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.RegisterForPushNotificationsErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+}
+
+func TestRegisterForPushNotificationsUnsupportedPlatform(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	err := controller.RegisterForPushNotifications(context.Background(), auth.PushToken{Token: uuid.New().String(), Platform: auth.PushPlatform("windows")}, auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.RegisterForPushNotificationsErrorUnsupportedPlatform {
+		t.Fatalf("err code should be `unsupported platform`, found %v", err)
+	}
+}
+
+func TestRegisterForPushNotificationsOk(t *testing.T) {
+	appVersion := "1.0.0"
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	storeTokenCalls := 0
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{
+		StorePushTokenImpl: func(ctx context.Context, uid pushNotifications.UserId, token pushNotifications.PushToken) repositories.MutationWorkItem {
+			if token.Platform != pushNotifications.PlatformAndroid || token.AppVersion == nil || *token.AppVersion != appVersion {
+				t.Fatalf("token should be stored with platform and app version, found %v", token)
+			}
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					storeTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	err := controller.RegisterForPushNotifications(context.Background(), auth.PushToken{Token: uuid.New().String(), Platform: auth.PushPlatformAndroid, AppVersion: &appVersion}, auth.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if storeTokenCalls != 1 {
+		t.Fatalf("store should be called once, found %d", storeTokenCalls)
+	}
+}
+
+func TestEnrollTwoFactorAlreadyEnrolled(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.EnrollTwoFactor(context.Background(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.EnrollTwoFactorErrorAlreadyEnrolled {
+		t.Fatalf("err code should be `already enrolled`, found %v", err)
+	}
+}
+
+func TestEnrollTwoFactorOk(t *testing.T) {
+	storeSecretCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid authRepository.UserId) (authRepository.UserInfo, error) {
+			return authRepository.UserInfo{
+				UserId: uid,
+				Email:  uuid.New().String(),
+			}, nil
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+		StoreSecretImpl: func(ctx context.Context, uid twoFactor.UserId, secret string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					storeSecretCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	secret := totp.Secret(uuid.New().String())
+	uri := uuid.New().String()
+	totpServiceMock := totp_mock.ServiceMock{
+		GenerateSecretImpl: func() (totp.Secret, error) {
+			return secret, nil
+		},
+		ProvisioningUriImpl: func(secret totp.Secret, account string) string {
+			return uri
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	enrollment, err := controller.EnrollTwoFactor(context.Background(), auth.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if storeSecretCalls != 1 {
+		t.Fatalf("should store secret once")
+	}
+	if enrollment.Secret != string(secret) || enrollment.Uri != uri {
+		t.Fatalf("unexpected enrollment %v", enrollment)
+	}
+}
+
+func TestConfirmTwoFactorNotEnrolled(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return nil, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.ConfirmTwoFactor(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.ConfirmTwoFactorErrorNotEnrolled {
+		t.Fatalf("err code should be `not enrolled`, found %v", err)
+	}
+}
+
+func TestConfirmTwoFactorWrongCode(t *testing.T) {
+	addedFailures := []loginAttempts.Key{}
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: false,
+			}, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			addedFailures = append(addedFailures, key)
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.ConfirmTwoFactor(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.ConfirmTwoFactorErrorWrongCode {
+		t.Fatalf("err code should be `wrong code`, found %v", err)
+	}
+	if len(addedFailures) != 1 || !strings.HasPrefix(string(addedFailures[0]), "2fa:") {
+		t.Fatalf("should record two factor failure once, found %v", addedFailures)
+	}
+}
+
+func TestConfirmTwoFactorConfirmFailed(t *testing.T) {
+	storeCodesRollbacks := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: false,
+			}, nil
+		},
+		StoreRecoveryCodesImpl: func(ctx context.Context, uid twoFactor.UserId, codes []string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+				Rollback: func() error {
+					storeCodesRollbacks += 1
+					return nil
+				},
+			}
+		},
+		ConfirmSecretImpl: func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+	}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		&unitOfWork,
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.ConfirmTwoFactor(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.ConfirmTwoFactorErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+	if storeCodesRollbacks != 0 || abortedUnitsOfWork != 1 {
+		t.Fatalf("should abort unit of work instead of manual rollback, found %d rollbacks %d aborts", storeCodesRollbacks, abortedUnitsOfWork)
+	}
+}
+
+func TestConfirmTwoFactorOk(t *testing.T) {
+	confirmCalls := 0
+	acceptedSteps := []int64{}
+	var storedCodes []string
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: false,
+			}, nil
+		},
+		StoreRecoveryCodesImpl: func(ctx context.Context, uid twoFactor.UserId, codes []string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					storedCodes = codes
+					return nil
+				},
+			}
+		},
+		ConfirmSecretImpl: func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					confirmCalls += 1
+					return nil
+				},
+			}
+		},
+		AcceptStepImpl: func(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+			return repositories.MutationWorkItemWithReturnValue[bool]{
+				Perform: func() (bool, error) {
+					acceptedSteps = append(acceptedSteps, step)
+					return true, nil
+				},
+			}
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	result, err := controller.ConfirmTwoFactor(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if confirmCalls != 1 {
+		t.Fatalf("should confirm secret once")
+	}
+	if len(acceptedSteps) != 1 || acceptedSteps[0] != 1 {
+		t.Fatalf("should accept confirmation code step once, found %v", acceptedSteps)
+	}
+	recoveryCodes := result.RecoveryCodes
+	if len(recoveryCodes) == 0 || len(recoveryCodes) != len(storedCodes) {
+		t.Fatalf("returned recovery codes %v should match stored %v", recoveryCodes, storedCodes)
+	}
+	for i := range recoveryCodes {
+		if recoveryCodes[i] != storedCodes[i] {
+			t.Fatalf("returned recovery codes %v should match stored %v", recoveryCodes, storedCodes)
+		}
+	}
+}
+
+func TestConfirmTwoFactorThrottled(t *testing.T) {
+	currentTime := time.Now()
+	validateCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: false,
+			}, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			validateCalls += 1
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "2fa:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                10,
+				LastFailureTimestamp: currentTime.Add(-time.Minute).Unix(),
+			}, nil
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
+	)
+	result, err := controller.ConfirmTwoFactor(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.ConfirmTwoFactorErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
+	}
+	if result.RetryAfterSec != 840 {
+		t.Fatalf("should retry after 840 seconds, found %d", result.RetryAfterSec)
+	}
+	if validateCalls != 0 {
+		t.Fatalf("code should not be checked while throttled")
+	}
+}
+
+func TestConfirmTwoFactorWrongCodeThrottles(t *testing.T) {
+	currentTime := time.Now()
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: false,
+			}, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{
+				Count:                4,
+				LastFailureTimestamp: currentTime.Add(-time.Minute).Unix(),
+			}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                5,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
+	)
+	result, err := controller.ConfirmTwoFactor(context.Background(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.ConfirmTwoFactorErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
+	}
+	if result.RetryAfterSec != 1 {
+		t.Fatalf("should retry after 1 second, found %d", result.RetryAfterSec)
+	}
+}
+
+func TestVerifyTwoFactorTokenExpired(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return &jwt.Error{Code: jwt.CodeTokenExpired}
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{}
+	totpServiceMock := totp_mock.ServiceMock{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.VerifyTwoFactorErrorTokenExpired {
+		t.Fatalf("err code should be `token expired`, found %v", err)
+	}
+}
+
+func TestVerifyTwoFactorWrongCode(t *testing.T) {
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		HasRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+			return false, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.VerifyTwoFactorErrorWrongCode {
+		t.Fatalf("err code should be `wrong code`, found %v", err)
+	}
+}
+
+func TestVerifyTwoFactorRecoveryCodeUpdateTokenFailed(t *testing.T) {
+	removeCodeRollbacks := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		HasRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+			return true, nil
+		},
+		RemoveRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+				Rollback: func() error {
+					removeCodeRollbacks += 1
+					return nil
+				},
+			}
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		&unitOfWork,
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err.Code != auth.VerifyTwoFactorErrorInternal {
+		t.Fatalf("err code should be `internal`, found %v", err)
+	}
+	if removeCodeRollbacks != 0 || abortedUnitsOfWork != 1 {
+		t.Fatalf("should abort unit of work instead of manual rollback, found %d rollbacks %d aborts", removeCodeRollbacks, abortedUnitsOfWork)
+	}
+}
+
+func TestVerifyTwoFactorRecoveryCodeOk(t *testing.T) {
+	removeCodeCalls := 0
+	updateTokenCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateTokenCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		HasRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+			return true, nil
+		},
+		RemoveRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					removeCodeCalls += 1
+					return nil
+				},
+			}
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
+	controller := defaultController.New(
+		&authRepositoryMock,
+		&pushTokensRepositoryMock,
+		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
+		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
+		&formatValidatorMock,
+		standartOutputLoggingService.New(),
+		time.Now,
+	)
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if removeCodeCalls != 1 {
+		t.Fatalf("should consume recovery code once")
+	}
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once")
+	}
+}
+
+func TestVerifyTwoFactorRecoveryCodeAlreadyRedeemed(t *testing.T) {
+	addFailureCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return nil
+				},
+			}
+		},
+	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
+	usersRepositoryMock := users_mock.RepositoryMock{}
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		HasRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+			return true, nil
+		},
+		RemoveRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					// redeemed by a concurrent request after `HasRecoveryCode`
+					return twoFactor.ErrRecoveryCodeNotFound
+				},
+			}
 		},
 	}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
 		},
 	}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{
-		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
-			return jwt.AccessToken(uuid.New().String()), &jwt.Error{}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
 		},
-		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), nil
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					addFailureCalls += 1
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.VerifyTwoFactorErrorWrongCode {
+		t.Fatalf("err code should be `wrong code`, found %v", err)
+	}
+	if addFailureCalls != 1 {
+		t.Fatalf("should record failure once, found %d", addFailureCalls)
 	}
 }
 
-func TestUpdatePasswordIssueRefreshTokenFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
-		},
-	}
+func TestVerifyTwoFactorOk(t *testing.T) {
+	updateTokenCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					updateTokenCalls += 1
+					return nil
+				},
+			}
 		},
 	}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
 		},
 		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
-			return jwt.RefreshToken(uuid.New().String()), &jwt.Error{}
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		AcceptStepImpl: func(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+			return repositories.MutationWorkItemWithReturnValue[bool]{
+				Perform: func() (bool, error) {
+					return true, nil
+				},
+			}
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
 		},
 	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		time.Now,
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
 	}
-	if err.Code != auth.UpdatePasswordErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if updateTokenCalls != 1 {
+		t.Fatalf("should update token once")
 	}
 }
 
-func TestUpdatePasswordPasswordUpdateFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
-		},
-	}
-	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
-		},
-		UpdatePasswordImpl: func(uid authRepository.UserId, newPassword string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					return errors.New("")
-				},
-			}
-		},
-	}
+func TestVerifyTwoFactorThrottled(t *testing.T) {
+	currentTime := time.Now()
+	validateCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
 		},
@@ -1732,61 +5098,79 @@ func TestUpdatePasswordPasswordUpdateFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			validateCalls += 1
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			if !strings.HasPrefix(string(key), "2fa:") {
+				return loginAttempts.Failures{}, nil
+			}
+			return loginAttempts.Failures{
+				Count:                10,
+				LastFailureTimestamp: currentTime.Add(-time.Minute).Unix(),
+			}, nil
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	result, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.VerifyTwoFactorErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
 	}
-}
-
-func TestUpdatePasswordTokenUpdateFailed(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
-		},
+	if result.RetryAfterSec != 840 {
+		t.Fatalf("should retry after 840 seconds, found %d", result.RetryAfterSec)
 	}
-	updatePasswordCalls := 0
-	updatePasswordRollbacks := 0
-	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
-		},
-		UpdatePasswordImpl: func(uid authRepository.UserId, newPassword string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					updatePasswordCalls += 1
-					return nil
-				},
-				Rollback: func() error {
-					updatePasswordRollbacks += 1
-					return nil
-				},
-			}
-		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					return errors.New("some error")
-				},
-			}
-		},
+	if validateCalls != 0 {
+		t.Fatalf("code should not be checked while throttled")
 	}
+}
+
+func TestVerifyTwoFactorWrongCodeThrottles(t *testing.T) {
+	currentTime := time.Now()
+	addedFailures := []loginAttempts.Key{}
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
+	authRepositoryMock := auth_mock.RepositoryMock{}
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
 		},
@@ -1794,53 +5178,82 @@ func TestUpdatePasswordTokenUpdateFailed(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		HasRecoveryCodeImpl: func(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+			return false, nil
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 0, false
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{
+				Count:                4,
+				LastFailureTimestamp: currentTime.Add(-time.Minute).Unix(),
+			}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					addedFailures = append(addedFailures, key)
+					return loginAttempts.Failures{
+						Count:                5,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
+	result, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
 	if err == nil {
 		t.Fatalf("err should not be nil")
 	}
-	if err.Code != auth.UpdatePasswordErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	if err.Code != auth.VerifyTwoFactorErrorTooManyAttempts {
+		t.Fatalf("err code should be `too many attempts`, found %v", err)
 	}
-	if updatePasswordCalls != 1 {
-		t.Fatalf("password should be updated once, found %d", updatePasswordCalls)
+	if result.RetryAfterSec != 1 {
+		t.Fatalf("should retry after 1 seconds, found %d", result.RetryAfterSec)
 	}
-	if updatePasswordRollbacks != 1 {
-		t.Fatalf("password update should be rolled back once, found %d", updatePasswordRollbacks)
+	if len(addedFailures) != 1 || !strings.HasPrefix(string(addedFailures[0]), "2fa:") {
+		t.Fatalf("should count failure for the token subject once, found %v", addedFailures)
 	}
 }
 
-func TestUpdatePasswordOk(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{
-		ValidatePasswordFormatImpl: func(password string) error {
-			return nil
-		},
-	}
-	updatePasswordCalls := 0
+func TestVerifyTwoFactorReusedCode(t *testing.T) {
+	currentTime := time.Now()
+	addedFailures := 0
 	updateTokenCalls := 0
+	formatValidatorMock := formatValidation_mock.ServiceMock{}
 	authRepositoryMock := auth_mock.RepositoryMock{
-		CheckCredentialsImpl: func(email, password string) (bool, error) {
-			return true, nil
-		},
-		GetUserInfoImpl: func(uid authRepository.UserId) (authRepository.UserInfo, error) {
-			return authRepository.UserInfo{}, nil
-		},
-		UpdatePasswordImpl: func(uid authRepository.UserId, newPassword string) repositories.MutationWorkItem {
-			return repositories.MutationWorkItem{
-				Perform: func() error {
-					updatePasswordCalls += 1
-					return nil
-				},
-			}
-		},
-		UpdateRefreshTokenImpl: func(uid authRepository.UserId, token string) repositories.MutationWorkItem {
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					updateTokenCalls += 1
@@ -1852,6 +5265,12 @@ func TestUpdatePasswordOk(t *testing.T) {
 	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
 	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
 		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
 			return jwt.AccessToken(uuid.New().String()), nil
 		},
@@ -1859,86 +5278,174 @@ func TestUpdatePasswordOk(t *testing.T) {
 			return jwt.RefreshToken(uuid.New().String()), nil
 		},
 	}
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:            uuid.New().String(),
+				Confirmed:        true,
+				LastAcceptedStep: 1,
+			}, nil
+		},
+		AcceptStepImpl: func(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+			return repositories.MutationWorkItemWithReturnValue[bool]{
+				Perform: func() (bool, error) {
+					return false, nil
+				},
+			}
+		},
+	}
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{}, nil
+		},
+		AddFailureImpl: func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+			return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+				Perform: func() (loginAttempts.Failures, error) {
+					addedFailures += 1
+					return loginAttempts.Failures{
+						Count:                1,
+						LastFailureTimestamp: timestamp,
+					}, nil
+				},
+			}
+		},
+	}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	_, err := controller.UpdatePassword(uuid.New().String(), uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err != nil {
-		t.Fatalf("err should be nil, found %v", err)
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
+	if err == nil {
+		t.Fatalf("err should not be nil")
 	}
-	if updatePasswordCalls != 1 {
-		t.Fatalf("password should be updated once, found %d", updatePasswordCalls)
+	if err.Code != auth.VerifyTwoFactorErrorWrongCode {
+		t.Fatalf("err code should be `wrong code`, found %v", err)
 	}
-	if updateTokenCalls != 1 {
-		t.Fatalf("token update should be once, found %d", updateTokenCalls)
+	if addedFailures != 1 {
+		t.Fatalf("reused code should count as a failure")
+	}
+	if updateTokenCalls != 0 {
+		t.Fatalf("session should not be started with a reused code")
 	}
 }
 
-func TestRegisterForPushNotificationsFailedToStoreToken(t *testing.T) {
+func TestVerifyTwoFactorOkResetsFailures(t *testing.T) {
+	currentTime := time.Now()
+	resetKeys := []loginAttempts.Key{}
 	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{}
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{
-		StorePushTokenImpl: func(uid pushNotifications.UserId, token string) repositories.MutationWorkItem {
+	authRepositoryMock := auth_mock.RepositoryMock{
+		UpdateRefreshTokenImpl: func(ctx context.Context, uid authRepository.UserId, token string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					return errors.New("some error")
+					return nil
 				},
 			}
 		},
 	}
+	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{}
 	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
-	controller := defaultController.New(
-		&authRepositoryMock,
-		&pushTokensRepositoryMock,
-		&usersRepositoryMock,
-		&jwtServiceMock,
-		&formatValidatorMock,
-		standartOutputLoggingService.New(),
-	)
-	err := controller.RegisterForPushNotifications(uuid.New().String(), auth.UserId(uuid.New().String()))
-	if err == nil {
-		t.Fatalf("err should not be nil")
+	jwtServiceMock := jwt_mock.ServiceMock{
+		ValidateTwoFactorTokenImpl: func(token jwt.TwoFactorToken) *jwt.Error {
+			return nil
+		},
+		GetTwoFactorTokenSubjectImpl: func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+			return jwt.Subject(uuid.New().String()), nil
+		},
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
 	}
-	if err.Code != auth.RegisterForPushNotificationsErrorInternal {
-		t.Fatalf("err code should be `internal`, found %v", err)
+	twoFactorRepositoryMock := twoFactor_mock.RepositoryMock{
+		GetSecretImpl: func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+			return &twoFactor.Secret{
+				Value:     uuid.New().String(),
+				Confirmed: true,
+			}, nil
+		},
+		AcceptStepImpl: func(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+			return repositories.MutationWorkItemWithReturnValue[bool]{
+				Perform: func() (bool, error) {
+					return true, nil
+				},
+			}
+		},
 	}
-}
-
-func TestRegisterForPushNotificationsOk(t *testing.T) {
-	formatValidatorMock := formatValidation_mock.ServiceMock{}
-	authRepositoryMock := auth_mock.RepositoryMock{}
-	storeTokenCalls := 0
-	pushTokensRepositoryMock := pushNotifications_mock.RepositoryMock{
-		StorePushTokenImpl: func(uid pushNotifications.UserId, token string) repositories.MutationWorkItem {
+	totpServiceMock := totp_mock.ServiceMock{
+		ValidateImpl: func(secret totp.Secret, code string) (totp.Step, bool) {
+			return 1, true
+		},
+	}
+	identitiesRepositoryMock := identities_mock.RepositoryMock{}
+	oidcServiceMock := oidc_mock.ServiceMock{}
+	loginAttemptsRepositoryMock := loginAttempts_mock.RepositoryMock{
+		GetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+			return loginAttempts.Failures{
+				Count:                2,
+				LastFailureTimestamp: currentTime.Add(-time.Minute).Unix(),
+			}, nil
+		},
+		ResetFailuresImpl: func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					storeTokenCalls += 1
+					resetKeys = append(resetKeys, key)
 					return nil
 				},
 			}
 		},
 	}
-	usersRepositoryMock := users_mock.RepositoryMock{}
-	jwtServiceMock := jwt_mock.ServiceMock{}
 	controller := defaultController.New(
 		&authRepositoryMock,
 		&pushTokensRepositoryMock,
 		&usersRepositoryMock,
+		&twoFactorRepositoryMock,
+		&identitiesRepositoryMock,
+		&loginAttemptsRepositoryMock,
+		unitOfWorkMock(),
 		&jwtServiceMock,
+		&totpServiceMock,
+		&oidcServiceMock,
 		&formatValidatorMock,
 		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
 	)
-	err := controller.RegisterForPushNotifications(uuid.New().String(), auth.UserId(uuid.New().String()))
+	_, err := controller.VerifyTwoFactor(context.Background(), uuid.New().String(), uuid.New().String())
 	if err != nil {
 		t.Fatalf("err should be nil, found %v", err)
 	}
-	if storeTokenCalls != 1 {
-		t.Fatalf("store should be called once, found %d", storeTokenCalls)
+	if len(resetKeys) != 1 || !strings.HasPrefix(string(resetKeys[0]), "2fa:") {
+		t.Fatalf("should reset failures for the token subject once, found %v", resetKeys)
+	}
+}
+
+func unitOfWorkMock() *db_mock.UnitOfWorkMock {
+	return &db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			return work(nil)
+		},
 	}
 }
@@ -0,0 +1,20 @@
+package auth
+
+type EnrollTwoFactorErrorCode int
+
+const (
+	_ EnrollTwoFactorErrorCode = iota
+	EnrollTwoFactorErrorAlreadyEnrolled
+	EnrollTwoFactorErrorInternal
+)
+
+func (c EnrollTwoFactorErrorCode) Message() string {
+	switch c {
+	case EnrollTwoFactorErrorAlreadyEnrolled:
+		return "two factor authentication is already enabled"
+	case EnrollTwoFactorErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -5,6 +5,7 @@ type LoginErrorCode int
 const (
 	_ LoginErrorCode = iota
 	LoginErrorWrongCredentials
+	LoginErrorTooManyAttempts
 	LoginErrorInternal
 )
 
@@ -12,6 +13,8 @@ func (c LoginErrorCode) Message() string {
 	switch c {
 	case LoginErrorWrongCredentials:
 		return "wrong credentials"
+	case LoginErrorTooManyAttempts:
+		return "too many failed attempts, retry later"
 	case LoginErrorInternal:
 		return "internal error"
 	default:
@@ -0,0 +1,32 @@
+package auth
+
+type LoginWithIdentityProviderErrorCode int
+
+const (
+	_ LoginWithIdentityProviderErrorCode = iota
+	LoginWithIdentityProviderErrorUnknownProvider
+	LoginWithIdentityProviderErrorTokenExpired
+	LoginWithIdentityProviderErrorTokenIsWrong
+	LoginWithIdentityProviderErrorEmailMissing
+	LoginWithIdentityProviderErrorEmailAlreadyTaken
+	LoginWithIdentityProviderErrorInternal
+)
+
+func (c LoginWithIdentityProviderErrorCode) Message() string {
+	switch c {
+	case LoginWithIdentityProviderErrorUnknownProvider:
+		return "unknown identity provider"
+	case LoginWithIdentityProviderErrorTokenExpired:
+		return "token expired"
+	case LoginWithIdentityProviderErrorTokenIsWrong:
+		return "wrong token"
+	case LoginWithIdentityProviderErrorEmailMissing:
+		return "identity provider did not share an email"
+	case LoginWithIdentityProviderErrorEmailAlreadyTaken:
+		return "email is already taken by an account that is not linked with identity provider"
+	case LoginWithIdentityProviderErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -5,12 +5,15 @@ type RegisterForPushNotificationsErrorCode int
 const (
 	_ RegisterForPushNotificationsErrorCode = iota
 	RegisterForPushNotificationsErrorInternal
+	RegisterForPushNotificationsErrorUnsupportedPlatform
 )
 
 func (c RegisterForPushNotificationsErrorCode) Message() string {
 	switch c {
 	case RegisterForPushNotificationsErrorInternal:
 		return "internal error"
+	case RegisterForPushNotificationsErrorUnsupportedPlatform:
+		return "unsupported platform"
 	default:
 		return "unknown error"
 	}
@@ -0,0 +1,29 @@
+package auth
+
+type VerifyTwoFactorErrorCode int
+
+const (
+	_ VerifyTwoFactorErrorCode = iota
+	VerifyTwoFactorErrorTokenExpired
+	VerifyTwoFactorErrorTokenIsWrong
+	VerifyTwoFactorErrorWrongCode
+	VerifyTwoFactorErrorTooManyAttempts
+	VerifyTwoFactorErrorInternal
+)
+
+func (c VerifyTwoFactorErrorCode) Message() string {
+	switch c {
+	case VerifyTwoFactorErrorTokenExpired:
+		return "token expired"
+	case VerifyTwoFactorErrorTokenIsWrong:
+		return "wrong token"
+	case VerifyTwoFactorErrorWrongCode:
+		return "wrong code"
+	case VerifyTwoFactorErrorTooManyAttempts:
+		return "too many failed attempts, retry later"
+	case VerifyTwoFactorErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
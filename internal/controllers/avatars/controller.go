@@ -1,6 +1,8 @@
 package avatars
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 	imagesRepository "github.com/rzmn/governi/internal/repositories/images"
 )
@@ -9,5 +11,5 @@ type AvatarId string
 type Avatar imagesRepository.Image
 
 type Controller interface {
-	GetAvatars(ids []AvatarId) ([]Avatar, *common.CodeBasedError[GetAvatarsErrorCode])
+	GetAvatars(ctx context.Context, ids []AvatarId) ([]Avatar, *common.CodeBasedError[GetAvatarsErrorCode])
 }
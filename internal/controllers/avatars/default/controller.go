@@ -1,6 +1,8 @@
 package defaultController
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 	"github.com/rzmn/governi/internal/controllers/avatars"
 	imagesRepository "github.com/rzmn/governi/internal/repositories/images"
@@ -21,10 +23,10 @@ type defaultController struct {
 	logger     logging.Service
 }
 
-func (c *defaultController) GetAvatars(ids []avatars.AvatarId) ([]avatars.Avatar, *common.CodeBasedError[avatars.GetAvatarsErrorCode]) {
+func (c *defaultController) GetAvatars(ctx context.Context, ids []avatars.AvatarId) ([]avatars.Avatar, *common.CodeBasedError[avatars.GetAvatarsErrorCode]) {
 	const op = "avatars.defaultController.GetAvatars"
 	c.logger.LogInfo("%s: start[ids=%s]", op, ids)
-	result, err := c.repository.GetImagesBase64(common.Map(ids, func(id avatars.AvatarId) imagesRepository.ImageId {
+	result, err := c.repository.GetImagesBase64(ctx, common.Map(ids, func(id avatars.AvatarId) imagesRepository.ImageId {
 		return imagesRepository.ImageId(id)
 	}))
 	if err != nil {
@@ -1,6 +1,7 @@
 package defaultController_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -13,12 +14,12 @@ import (
 
 func TestGetAvatarsCannotGetFromRepository(t *testing.T) {
 	repository := images_mock.RepositoryMock{
-		GetImagesBase64Impl: func(ids []images.ImageId) ([]images.Image, error) {
+		GetImagesBase64Impl: func(ctx context.Context, ids []images.ImageId) ([]images.Image, error) {
 			return []images.Image{}, errors.New("some error")
 		},
 	}
 	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetAvatars([]avatars.AvatarId{})
+	_, err := controller.GetAvatars(context.Background(), []avatars.AvatarId{})
 	if err == nil {
 		t.Fatalf("`GetAvatars` should fail with err, found nil")
 	}
@@ -29,12 +30,12 @@ func TestGetAvatarsCannotGetFromRepository(t *testing.T) {
 
 func TestGetAvatarsOk(t *testing.T) {
 	repository := images_mock.RepositoryMock{
-		GetImagesBase64Impl: func(ids []images.ImageId) ([]images.Image, error) {
+		GetImagesBase64Impl: func(ctx context.Context, ids []images.ImageId) ([]images.Image, error) {
 			return []images.Image{}, nil
 		},
 	}
 	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetAvatars([]avatars.AvatarId{})
+	_, err := controller.GetAvatars(context.Background(), []avatars.AvatarId{})
 	if err != nil {
 		t.Fatalf("`GetAvatars` should not fail with err, found %v", err)
 	}
@@ -0,0 +1,37 @@
+package dataExports
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/common"
+)
+
+type UserId string
+type ExportId string
+type Archive []byte
+
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusReady
+	StatusFailed
+	StatusExpired
+)
+
+type Export struct {
+	Id        ExportId
+	Status    Status
+	CreatedAt int64
+	// ExpiresAt is when the archive of a ready export stops being available, zero for exports
+	// that are not ready.
+	ExpiresAt int64
+}
+
+type Controller interface {
+	RequestExport(ctx context.Context, id UserId) (Export, *common.CodeBasedError[RequestExportErrorCode])
+	GetExport(ctx context.Context, exportId ExportId, id UserId) (Export, *common.CodeBasedError[GetExportErrorCode])
+	DownloadExport(ctx context.Context, exportId ExportId, id UserId) (Archive, *common.CodeBasedError[DownloadExportErrorCode])
+	// RemoveExpiredExports removes exports whose archives are no longer available.
+	RemoveExpiredExports(ctx context.Context) *common.CodeBasedError[RemoveExpiredExportsErrorCode]
+}
@@ -0,0 +1,329 @@
+package defaultController
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rzmn/governi/internal/common"
+	"github.com/rzmn/governi/internal/controllers/dataExports"
+	"github.com/rzmn/governi/internal/controllers/profile"
+	"github.com/rzmn/governi/internal/repositories/friends"
+	"github.com/rzmn/governi/internal/repositories/images"
+	"github.com/rzmn/governi/internal/repositories/spendings"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	dataExportsRepository "github.com/rzmn/governi/internal/repositories/dataExports"
+	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
+	imagesRepository "github.com/rzmn/governi/internal/repositories/images"
+	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
+
+	"github.com/google/uuid"
+)
+
+type DataExportsRepository dataExportsRepository.Repository
+type FriendsRepository friendsRepository.Repository
+type SpendingsRepository spendingsRepository.Repository
+type ImagesRepository imagesRepository.Repository
+type ProfileController profile.Controller
+
+func New(
+	exports DataExportsRepository,
+	friends FriendsRepository,
+	spendings SpendingsRepository,
+	images ImagesRepository,
+	profile ProfileController,
+	logger logging.Service,
+	currentTime func() time.Time,
+	runInBackground func(task func()),
+) dataExports.Controller {
+	return &defaultController{
+		exports:         exports,
+		friends:         friends,
+		spendings:       spendings,
+		images:          images,
+		profile:         profile,
+		logger:          logger,
+		currentTime:     currentTime,
+		runInBackground: runInBackground,
+	}
+}
+
+// exportLease is how long a pending export may stay unfinished before it is considered
+// abandoned and reported as failed, so the user can request a new one.
+const exportLease = time.Minute * 15
+
+// exportRetention is how long the archive of a ready export stays available for download.
+const exportRetention = time.Hour * 24 * 7
+
+type defaultController struct {
+	exports         DataExportsRepository
+	friends         FriendsRepository
+	spendings       SpendingsRepository
+	images          ImagesRepository
+	profile         ProfileController
+	logger          logging.Service
+	currentTime     func() time.Time
+	runInBackground func(task func())
+}
+
+func (c *defaultController) RequestExport(ctx context.Context, id dataExports.UserId) (dataExports.Export, *common.CodeBasedError[dataExports.RequestExportErrorCode]) {
+	const op = "dataExports.defaultController.RequestExport"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	now := c.currentTime().Unix()
+	export := dataExportsRepository.Export{
+		Id:        dataExportsRepository.ExportId(uuid.New().String()),
+		UserId:    dataExportsRepository.UserId(id),
+		Status:    dataExportsRepository.StatusPending,
+		CreatedAt: now,
+		StartedAt: now,
+	}
+	if err := c.exports.StoreExport(ctx, export).Perform(); err != nil {
+		c.logger.LogInfo("%s: cannot store export err: %v", op, err)
+		return dataExports.Export{}, common.NewErrorWithDescription(dataExports.RequestExportErrorInternal, err.Error())
+	}
+	backgroundCtx := context.WithoutCancel(ctx)
+	c.runInBackground(func() {
+		c.buildExport(backgroundCtx, export)
+	})
+	c.logger.LogInfo("%s: success[id=%s export=%s]", op, id, export.Id)
+	return mapExport(export), nil
+}
+
+func (c *defaultController) GetExport(ctx context.Context, exportId dataExports.ExportId, id dataExports.UserId) (dataExports.Export, *common.CodeBasedError[dataExports.GetExportErrorCode]) {
+	const op = "dataExports.defaultController.GetExport"
+	c.logger.LogInfo("%s: start[id=%s export=%s]", op, id, exportId)
+	export, err := c.exports.GetExport(ctx, dataExportsRepository.ExportId(exportId))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get export err: %v", op, err)
+		return dataExports.Export{}, common.NewErrorWithDescription(dataExports.GetExportErrorInternal, err.Error())
+	}
+	if export == nil || export.UserId != dataExportsRepository.UserId(id) {
+		c.logger.LogInfo("%s: export not found", op)
+		return dataExports.Export{}, common.NewError(dataExports.GetExportErrorNotFound)
+	}
+	if export.Status == dataExportsRepository.StatusPending {
+		staleBefore := c.currentTime().Add(-exportLease).Unix()
+		failed, err := c.exports.FailStaleExport(ctx, export.Id, staleBefore).Perform()
+		if err != nil {
+			c.logger.LogInfo("%s: cannot fail stale export err: %v", op, err)
+			return dataExports.Export{}, common.NewErrorWithDescription(dataExports.GetExportErrorInternal, err.Error())
+		}
+		if failed {
+			c.logger.LogInfo("%s: export %s lease expired, marked as failed", op, export.Id)
+			export.Status = dataExportsRepository.StatusFailed
+		}
+	}
+	result := mapExport(*export)
+	if c.expired(*export) {
+		result.Status = dataExports.StatusExpired
+	}
+	c.logger.LogInfo("%s: success[id=%s export=%s]", op, id, exportId)
+	return result, nil
+}
+
+func (c *defaultController) DownloadExport(ctx context.Context, exportId dataExports.ExportId, id dataExports.UserId) (dataExports.Archive, *common.CodeBasedError[dataExports.DownloadExportErrorCode]) {
+	const op = "dataExports.defaultController.DownloadExport"
+	c.logger.LogInfo("%s: start[id=%s export=%s]", op, id, exportId)
+	export, err := c.exports.GetExport(ctx, dataExportsRepository.ExportId(exportId))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get export err: %v", op, err)
+		return nil, common.NewErrorWithDescription(dataExports.DownloadExportErrorInternal, err.Error())
+	}
+	if export == nil || export.UserId != dataExportsRepository.UserId(id) {
+		c.logger.LogInfo("%s: export not found", op)
+		return nil, common.NewError(dataExports.DownloadExportErrorNotFound)
+	}
+	if export.Status != dataExportsRepository.StatusReady {
+		c.logger.LogInfo("%s: export is not ready, status %d", op, export.Status)
+		return nil, common.NewError(dataExports.DownloadExportErrorNotReady)
+	}
+	if c.expired(*export) {
+		c.logger.LogInfo("%s: export expired at %d", op, export.ExpiresAt)
+		return nil, common.NewError(dataExports.DownloadExportErrorExpired)
+	}
+	archive, err := c.exports.GetArchive(ctx, export.Id)
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get archive err: %v", op, err)
+		return nil, common.NewErrorWithDescription(dataExports.DownloadExportErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success[id=%s export=%s]", op, id, exportId)
+	return dataExports.Archive(archive), nil
+}
+
+func (c *defaultController) RemoveExpiredExports(ctx context.Context) *common.CodeBasedError[dataExports.RemoveExpiredExportsErrorCode] {
+	const op = "dataExports.defaultController.RemoveExpiredExports"
+	c.logger.LogInfo("%s: start", op)
+	if err := c.exports.RemoveExpiredExports(ctx, c.currentTime().Unix()); err != nil {
+		c.logger.LogInfo("%s: cannot remove expired exports err: %v", op, err)
+		return common.NewErrorWithDescription(dataExports.RemoveExpiredExportsErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success", op)
+	return nil
+}
+
+func (c *defaultController) expired(export dataExportsRepository.Export) bool {
+	return export.Status == dataExportsRepository.StatusReady && export.ExpiresAt <= c.currentTime().Unix()
+}
+
+func (c *defaultController) buildExport(ctx context.Context, export dataExportsRepository.Export) {
+	const op = "dataExports.defaultController.buildExport"
+	c.logger.LogInfo("%s: start[export=%s]", op, export.Id)
+	archive, err := c.buildArchive(ctx, dataExports.UserId(export.UserId))
+	if err == nil {
+		expiresAt := c.currentTime().Add(exportRetention).Unix()
+		err = c.exports.CompleteExport(ctx, export.Id, archive, expiresAt).Perform()
+	}
+	if err != nil {
+		c.logger.LogInfo("%s: cannot build archive err: %v", op, err)
+		if err := c.exports.FailExport(ctx, export.Id).Perform(); err != nil {
+			c.logger.LogError("%s: cannot mark export %s as failed err: %v", op, export.Id, err)
+		}
+		return
+	}
+	c.logger.LogInfo("%s: success[export=%s]", op, export.Id)
+}
+
+type archivedProfile struct {
+	Id            string  `json:"id"`
+	DisplayName   string  `json:"displayName"`
+	AvatarId      *string `json:"avatarId,omitempty"`
+	Email         string  `json:"email"`
+	EmailVerified bool    `json:"emailVerified"`
+}
+
+type archivedRelations struct {
+	Friends       []string `json:"friends"`
+	Subscribers   []string `json:"subscribers"`
+	Subscriptions []string `json:"subscriptions"`
+}
+
+type archivedShare struct {
+	Counterparty string `json:"counterparty"`
+	Cost         int64  `json:"cost"`
+}
+
+type archivedExpense struct {
+	Id        string          `json:"id"`
+	Timestamp int64           `json:"timestamp"`
+	Details   string          `json:"details"`
+	Total     int64           `json:"total"`
+	Currency  string          `json:"currency"`
+	Shares    []archivedShare `json:"shares"`
+}
+
+func (c *defaultController) buildArchive(ctx context.Context, id dataExports.UserId) ([]byte, error) {
+	info, profileErr := c.profile.GetProfileInfo(ctx, profile.UserId(id))
+	if profileErr != nil {
+		return nil, profileErr
+	}
+	friendsList, err := c.friends.GetFriends(ctx, friends.UserId(id))
+	if err != nil {
+		return nil, err
+	}
+	subscribers, err := c.friends.GetSubscribers(ctx, friends.UserId(id))
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := c.friends.GetSubscriptions(ctx, friends.UserId(id))
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := c.spendings.GetExpensesOf(ctx, spendings.CounterpartyId(id))
+	if err != nil {
+		return nil, err
+	}
+	var avatars []images.Image
+	if info.AvatarId != nil {
+		avatars, err = c.images.GetImagesBase64(ctx, []images.ImageId{images.ImageId(*info.AvatarId)})
+		if err != nil {
+			return nil, err
+		}
+	}
+	documents := []struct {
+		name    string
+		content any
+	}{
+		{
+			name: "profile.json",
+			content: archivedProfile{
+				Id:            string(info.Id),
+				DisplayName:   info.DisplayName,
+				AvatarId:      (*string)(info.AvatarId),
+				Email:         info.Email,
+				EmailVerified: info.EmailVerified,
+			},
+		},
+		{
+			name: "relations.json",
+			content: archivedRelations{
+				Friends:       mapUserIds(friendsList),
+				Subscribers:   mapUserIds(subscribers),
+				Subscriptions: mapUserIds(subscriptions),
+			},
+		},
+		{
+			name:    "expenses.json",
+			content: common.Map(expenses, mapExpense),
+		},
+	}
+	buffer := bytes.Buffer{}
+	writer := zip.NewWriter(&buffer)
+	for _, document := range documents {
+		file, err := writer.Create(document.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(document.content); err != nil {
+			return nil, err
+		}
+	}
+	for _, avatar := range avatars {
+		file, err := writer.Create("avatar.base64")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write([]byte(avatar.Base64)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func mapUserIds(ids []friends.UserId) []string {
+	return common.Map(ids, func(id friends.UserId) string {
+		return string(id)
+	})
+}
+
+func mapExpense(expense spendings.IdentifiableExpense) archivedExpense {
+	return archivedExpense{
+		Id:        string(expense.Id),
+		Timestamp: expense.Timestamp,
+		Details:   expense.Details,
+		Total:     int64(expense.Total),
+		Currency:  string(expense.Currency),
+		Shares: common.Map(expense.Shares, func(share spendings.ShareOfExpense) archivedShare {
+			return archivedShare{
+				Counterparty: string(share.Counterparty),
+				Cost:         int64(share.Cost),
+			}
+		}),
+	}
+}
+
+func mapExport(export dataExportsRepository.Export) dataExports.Export {
+	return dataExports.Export{
+		Id:        dataExports.ExportId(export.Id),
+		Status:    dataExports.Status(export.Status),
+		CreatedAt: export.CreatedAt,
+		ExpiresAt: export.ExpiresAt,
+	}
+}
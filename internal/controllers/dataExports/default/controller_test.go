@@ -0,0 +1,449 @@
+package defaultController_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rzmn/governi/internal/common"
+	"github.com/rzmn/governi/internal/controllers/dataExports"
+	defaultController "github.com/rzmn/governi/internal/controllers/dataExports/default"
+	"github.com/rzmn/governi/internal/controllers/profile"
+	"github.com/rzmn/governi/internal/repositories"
+	dataExportsRepository "github.com/rzmn/governi/internal/repositories/dataExports"
+	dataExports_mock "github.com/rzmn/governi/internal/repositories/dataExports/mock"
+	"github.com/rzmn/governi/internal/repositories/friends"
+	friends_mock "github.com/rzmn/governi/internal/repositories/friends/mock"
+	"github.com/rzmn/governi/internal/repositories/images"
+	images_mock "github.com/rzmn/governi/internal/repositories/images/mock"
+	"github.com/rzmn/governi/internal/repositories/spendings"
+	spendings_mock "github.com/rzmn/governi/internal/repositories/spendings/mock"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+
+	"github.com/google/uuid"
+)
+
+type profileControllerMock struct {
+	info profile.ProfileInfo
+}
+
+func (c *profileControllerMock) GetProfileInfo(ctx context.Context, id profile.UserId) (profile.ProfileInfo, *common.CodeBasedError[profile.GetInfoErrorCode]) {
+	return c.info, nil
+}
+
+func (c *profileControllerMock) UpdateDisplayName(ctx context.Context, name string, id profile.UserId) *common.CodeBasedError[profile.UpdateDisplayNameErrorCode] {
+	return nil
+}
+
+func (c *profileControllerMock) UpdateAvatar(ctx context.Context, base64 string, id profile.UserId) (profile.AvatarId, *common.CodeBasedError[profile.UpdateAvatarErrorCode]) {
+	return "", nil
+}
+
+func (c *profileControllerMock) UpdateLanguage(ctx context.Context, language profile.Language, id profile.UserId) *common.CodeBasedError[profile.UpdateLanguageErrorCode] {
+	return nil
+}
+
+func (c *profileControllerMock) DeleteAccount(ctx context.Context, password string, id profile.UserId) *common.CodeBasedError[profile.DeleteAccountErrorCode] {
+	return nil
+}
+
+func okTransaction() repositories.MutationWorkItem {
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			return nil
+		},
+	}
+}
+
+func createController(
+	exportsRepository *dataExports_mock.RepositoryMock,
+	friendsRepository *friends_mock.RepositoryMock,
+	uid dataExports.UserId,
+) dataExports.Controller {
+	avatarId := profile.AvatarId(uuid.New().String())
+	profileController := profileControllerMock{
+		info: profile.ProfileInfo{
+			Id:            profile.UserId(uid),
+			DisplayName:   "display name",
+			AvatarId:      &avatarId,
+			Email:         "a@b.com",
+			EmailVerified: true,
+		},
+	}
+	spendingsRepository := spendings_mock.RepositoryMock{
+		GetExpensesOfImpl: func(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+			return []spendings.IdentifiableExpense{
+				{
+					Id: spendings.ExpenseId(uuid.New().String()),
+					Expense: spendings.Expense{
+						Timestamp: 123,
+						Details:   "details",
+						Total:     100,
+						Currency:  "RUB",
+						Shares: []spendings.ShareOfExpense{
+							{
+								Counterparty: counterparty,
+								Cost:         100,
+							},
+							{
+								Counterparty: spendings.CounterpartyId(uuid.New().String()),
+								Cost:         -100,
+							},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+	imagesRepository := images_mock.RepositoryMock{
+		GetImagesBase64Impl: func(ctx context.Context, ids []images.ImageId) ([]images.Image, error) {
+			return []images.Image{
+				{
+					Id:     ids[0],
+					Base64: "base64",
+				},
+			}, nil
+		},
+	}
+	return defaultController.New(
+		exportsRepository,
+		friendsRepository,
+		&spendingsRepository,
+		&imagesRepository,
+		&profileController,
+		standartOutputLoggingService.New(),
+		time.Now,
+		func(task func()) {
+			task()
+		},
+	)
+}
+
+func createFriendsRepository() friends_mock.RepositoryMock {
+	return friends_mock.RepositoryMock{
+		GetFriendsImpl: func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+			return []friends.UserId{friends.UserId(uuid.New().String())}, nil
+		},
+		GetSubscribersImpl: func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+			return []friends.UserId{}, nil
+		},
+		GetSubscriptionsImpl: func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+			return []friends.UserId{}, nil
+		},
+	}
+}
+
+func TestRequestExportStoreFailed(t *testing.T) {
+	exportsRepository := dataExports_mock.RepositoryMock{
+		StoreExportImpl: func(ctx context.Context, export dataExportsRepository.Export) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	uid := dataExports.UserId(uuid.New().String())
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	_, err := controller.RequestExport(context.Background(), uid)
+	if err == nil {
+		t.Fatalf("`RequestExport` should be failed, found no err")
+	}
+	if err.Code != dataExports.RequestExportErrorInternal {
+		t.Fatalf("`RequestExport` should be failed with `internal`, found %v", err)
+	}
+}
+
+func TestRequestExportBuildFailed(t *testing.T) {
+	failCalls := 0
+	exportsRepository := dataExports_mock.RepositoryMock{
+		StoreExportImpl: func(ctx context.Context, export dataExportsRepository.Export) repositories.MutationWorkItem {
+			return okTransaction()
+		},
+		FailExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) repositories.MutationWorkItem {
+			failCalls += 1
+			return okTransaction()
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	friendsRepository.GetFriendsImpl = func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+		return nil, errors.New("some error")
+	}
+	uid := dataExports.UserId(uuid.New().String())
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	export, err := controller.RequestExport(context.Background(), uid)
+	if err != nil {
+		t.Fatalf("`RequestExport` should not be failed, found err %v", err)
+	}
+	if export.Status != dataExports.StatusPending {
+		t.Fatalf("export should be pending, found %v", export)
+	}
+	if failCalls != 1 {
+		t.Fatalf("export should be marked as failed once, found %d", failCalls)
+	}
+}
+
+func TestRequestExportOk(t *testing.T) {
+	var archive []byte
+	var archiveExpiresAt int64
+	exportsRepository := dataExports_mock.RepositoryMock{
+		StoreExportImpl: func(ctx context.Context, export dataExportsRepository.Export) repositories.MutationWorkItem {
+			return okTransaction()
+		},
+		CompleteExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId, data []byte, expiresAt int64) repositories.MutationWorkItem {
+			archive = data
+			archiveExpiresAt = expiresAt
+			return okTransaction()
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	uid := dataExports.UserId(uuid.New().String())
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	if _, err := controller.RequestExport(context.Background(), uid); err != nil {
+		t.Fatalf("`RequestExport` should not be failed, found err %v", err)
+	}
+	if archiveExpiresAt <= time.Now().Add(time.Hour*24*6).Unix() {
+		t.Fatalf("archive should be available for a week, found expiration at %d", archiveExpiresAt)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("archive should be a valid zip, found err %v", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		opened, err := file.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s err: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(opened)
+		opened.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s err: %v", file.Name, err)
+		}
+		files[file.Name] = data
+	}
+	for _, name := range []string{"profile.json", "relations.json", "expenses.json", "avatar.base64"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("archive should contain %s, found %v", name, reader.File)
+		}
+	}
+	var profileInfo map[string]any
+	if err := json.Unmarshal(files["profile.json"], &profileInfo); err != nil {
+		t.Fatalf("failed to decode profile err: %v", err)
+	}
+	if profileInfo["id"] != string(uid) || profileInfo["email"] != "a@b.com" || profileInfo["emailVerified"] != true {
+		t.Fatalf("unexpected profile %v", profileInfo)
+	}
+	var expenses []map[string]any
+	if err := json.Unmarshal(files["expenses.json"], &expenses); err != nil {
+		t.Fatalf("failed to decode expenses err: %v", err)
+	}
+	if len(expenses) != 1 {
+		t.Fatalf("archive should contain one expense, found %v", expenses)
+	}
+}
+
+func TestGetExportOfOtherUser(t *testing.T) {
+	exportsRepository := dataExports_mock.RepositoryMock{
+		GetExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) (*dataExportsRepository.Export, error) {
+			return &dataExportsRepository.Export{
+				Id:     id,
+				UserId: dataExportsRepository.UserId(uuid.New().String()),
+				Status: dataExportsRepository.StatusReady,
+			}, nil
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	uid := dataExports.UserId(uuid.New().String())
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	_, err := controller.GetExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if err == nil {
+		t.Fatalf("`GetExport` should be failed, found no err")
+	}
+	if err.Code != dataExports.GetExportErrorNotFound {
+		t.Fatalf("`GetExport` should be failed with `not found`, found %v", err)
+	}
+	_, downloadErr := controller.DownloadExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if downloadErr == nil {
+		t.Fatalf("`DownloadExport` should be failed, found no err")
+	}
+	if downloadErr.Code != dataExports.DownloadExportErrorNotFound {
+		t.Fatalf("`DownloadExport` should be failed with `not found`, found %v", downloadErr)
+	}
+}
+
+func TestDownloadExportNotReady(t *testing.T) {
+	uid := dataExports.UserId(uuid.New().String())
+	exportsRepository := dataExports_mock.RepositoryMock{
+		GetExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) (*dataExportsRepository.Export, error) {
+			return &dataExportsRepository.Export{
+				Id:     id,
+				UserId: dataExportsRepository.UserId(uid),
+				Status: dataExportsRepository.StatusPending,
+			}, nil
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	_, err := controller.DownloadExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if err == nil {
+		t.Fatalf("`DownloadExport` should be failed, found no err")
+	}
+	if err.Code != dataExports.DownloadExportErrorNotReady {
+		t.Fatalf("`DownloadExport` should be failed with `not ready`, found %v", err)
+	}
+}
+
+func TestDownloadExportOk(t *testing.T) {
+	uid := dataExports.UserId(uuid.New().String())
+	archive := []byte(uuid.New().String())
+	exportsRepository := dataExports_mock.RepositoryMock{
+		GetExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) (*dataExportsRepository.Export, error) {
+			return &dataExportsRepository.Export{
+				Id:        id,
+				UserId:    dataExportsRepository.UserId(uid),
+				Status:    dataExportsRepository.StatusReady,
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			}, nil
+		},
+		GetArchiveImpl: func(ctx context.Context, id dataExportsRepository.ExportId) ([]byte, error) {
+			return archive, nil
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	downloaded, err := controller.DownloadExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if err != nil {
+		t.Fatalf("`DownloadExport` should not be failed, found err %v", err)
+	}
+	if string(downloaded) != string(archive) {
+		t.Fatalf("downloaded archive should be equal to %s, found %s", archive, downloaded)
+	}
+}
+
+func TestDownloadExportExpired(t *testing.T) {
+	uid := dataExports.UserId(uuid.New().String())
+	exportsRepository := dataExports_mock.RepositoryMock{
+		GetExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) (*dataExportsRepository.Export, error) {
+			return &dataExportsRepository.Export{
+				Id:        id,
+				UserId:    dataExportsRepository.UserId(uid),
+				Status:    dataExportsRepository.StatusReady,
+				ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+			}, nil
+		},
+		GetArchiveImpl: func(ctx context.Context, id dataExportsRepository.ExportId) ([]byte, error) {
+			panic("expired archive should not be read")
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	_, err := controller.DownloadExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if err == nil {
+		t.Fatalf("`DownloadExport` should be failed, found no err")
+	}
+	if err.Code != dataExports.DownloadExportErrorExpired {
+		t.Fatalf("`DownloadExport` should be failed with `expired`, found %v", err)
+	}
+	export, getErr := controller.GetExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if getErr != nil {
+		t.Fatalf("`GetExport` should not be failed, found err %v", getErr)
+	}
+	if export.Status != dataExports.StatusExpired {
+		t.Fatalf("export should be expired, found %v", export)
+	}
+}
+
+func TestRemoveExpiredExports(t *testing.T) {
+	uid := dataExports.UserId(uuid.New().String())
+	var removedBefore int64
+	exportsRepository := dataExports_mock.RepositoryMock{
+		RemoveExpiredExportsImpl: func(ctx context.Context, expiredBefore int64) error {
+			removedBefore = expiredBefore
+			return nil
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	if err := controller.RemoveExpiredExports(context.Background()); err != nil {
+		t.Fatalf("`RemoveExpiredExports` should not be failed, found err %v", err)
+	}
+	if now := time.Now().Unix(); removedBefore < now-1 || removedBefore > now {
+		t.Fatalf("exports expired before now should be removed, found %d", removedBefore)
+	}
+}
+
+func TestGetExportLeaseExpired(t *testing.T) {
+	uid := dataExports.UserId(uuid.New().String())
+	startedAt := time.Now().Add(-time.Hour).Unix()
+	var staleBefore int64
+	exportsRepository := dataExports_mock.RepositoryMock{
+		GetExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) (*dataExportsRepository.Export, error) {
+			return &dataExportsRepository.Export{
+				Id:        id,
+				UserId:    dataExportsRepository.UserId(uid),
+				Status:    dataExportsRepository.StatusPending,
+				CreatedAt: startedAt,
+				StartedAt: startedAt,
+			}, nil
+		},
+		FailStaleExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+			staleBefore = startedBefore
+			return repositories.MutationWorkItemWithReturnValue[bool]{
+				Perform: func() (bool, error) {
+					return startedAt < startedBefore, nil
+				},
+			}
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	export, err := controller.GetExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if err != nil {
+		t.Fatalf("`GetExport` should not be failed, found err %v", err)
+	}
+	if export.Status != dataExports.StatusFailed {
+		t.Fatalf("abandoned export should be reported as failed, found %v", export)
+	}
+	if staleBefore <= startedAt || staleBefore >= time.Now().Unix() {
+		t.Fatalf("lease should expire between %d and now, found %d", startedAt, staleBefore)
+	}
+}
+
+func TestGetExportLeaseActive(t *testing.T) {
+	uid := dataExports.UserId(uuid.New().String())
+	startedAt := time.Now().Unix()
+	exportsRepository := dataExports_mock.RepositoryMock{
+		GetExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId) (*dataExportsRepository.Export, error) {
+			return &dataExportsRepository.Export{
+				Id:        id,
+				UserId:    dataExportsRepository.UserId(uid),
+				Status:    dataExportsRepository.StatusPending,
+				CreatedAt: startedAt,
+				StartedAt: startedAt,
+			}, nil
+		},
+		FailStaleExportImpl: func(ctx context.Context, id dataExportsRepository.ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+			return repositories.MutationWorkItemWithReturnValue[bool]{
+				Perform: func() (bool, error) {
+					return startedAt < startedBefore, nil
+				},
+			}
+		},
+	}
+	friendsRepository := createFriendsRepository()
+	controller := createController(&exportsRepository, &friendsRepository, uid)
+	export, err := controller.GetExport(context.Background(), dataExports.ExportId(uuid.New().String()), uid)
+	if err != nil {
+		t.Fatalf("`GetExport` should not be failed, found err %v", err)
+	}
+	if export.Status != dataExports.StatusPending {
+		t.Fatalf("export being built should stay pending, found %v", export)
+	}
+}
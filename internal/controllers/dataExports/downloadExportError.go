@@ -0,0 +1,26 @@
+package dataExports
+
+type DownloadExportErrorCode int
+
+const (
+	_ DownloadExportErrorCode = iota
+	DownloadExportErrorNotFound
+	DownloadExportErrorNotReady
+	DownloadExportErrorExpired
+	DownloadExportErrorInternal
+)
+
+func (c DownloadExportErrorCode) Message() string {
+	switch c {
+	case DownloadExportErrorNotFound:
+		return "export not found"
+	case DownloadExportErrorNotReady:
+		return "export is not ready yet"
+	case DownloadExportErrorExpired:
+		return "export has expired"
+	case DownloadExportErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -0,0 +1,20 @@
+package dataExports
+
+type GetExportErrorCode int
+
+const (
+	_ GetExportErrorCode = iota
+	GetExportErrorNotFound
+	GetExportErrorInternal
+)
+
+func (c GetExportErrorCode) Message() string {
+	switch c {
+	case GetExportErrorNotFound:
+		return "export not found"
+	case GetExportErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
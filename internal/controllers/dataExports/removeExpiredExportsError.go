@@ -0,0 +1,17 @@
+package dataExports
+
+type RemoveExpiredExportsErrorCode int
+
+const (
+	_ RemoveExpiredExportsErrorCode = iota
+	RemoveExpiredExportsErrorInternal
+)
+
+func (c RemoveExpiredExportsErrorCode) Message() string {
+	switch c {
+	case RemoveExpiredExportsErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -0,0 +1,17 @@
+package dataExports
+
+type RequestExportErrorCode int
+
+const (
+	_ RequestExportErrorCode = iota
+	RequestExportErrorInternal
+)
+
+func (c RequestExportErrorCode) Message() string {
+	switch c {
+	case RequestExportErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
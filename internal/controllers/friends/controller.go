@@ -1,6 +1,8 @@
 package friends
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 )
 
@@ -15,9 +17,9 @@ const (
 )
 
 type Controller interface {
-	AcceptFriendRequest(sender UserId, target UserId) *common.CodeBasedError[AcceptFriendRequestErrorCode]
-	GetFriends(statuses []FriendStatus, userId UserId) (map[FriendStatus][]UserId, *common.CodeBasedError[GetFriendsErrorCode])
-	RollbackFriendRequest(sender UserId, target UserId) *common.CodeBasedError[RollbackFriendRequestErrorCode]
-	SendFriendRequest(sender UserId, target UserId) *common.CodeBasedError[SendFriendRequestErrorCode]
-	Unfriend(sender UserId, target UserId) *common.CodeBasedError[UnfriendErrorCode]
+	AcceptFriendRequest(ctx context.Context, sender UserId, target UserId) *common.CodeBasedError[AcceptFriendRequestErrorCode]
+	GetFriends(ctx context.Context, statuses []FriendStatus, userId UserId) (map[FriendStatus][]UserId, *common.CodeBasedError[GetFriendsErrorCode])
+	RollbackFriendRequest(ctx context.Context, sender UserId, target UserId) *common.CodeBasedError[RollbackFriendRequestErrorCode]
+	SendFriendRequest(ctx context.Context, sender UserId, target UserId) *common.CodeBasedError[SendFriendRequestErrorCode]
+	Unfriend(ctx context.Context, sender UserId, target UserId) *common.CodeBasedError[UnfriendErrorCode]
 }
@@ -1,32 +1,42 @@
 package defaultController
 
 import (
+	"context"
 	"slices"
 
 	"github.com/rzmn/governi/internal/common"
 	"github.com/rzmn/governi/internal/controllers/friends"
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
 	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
+	outboxRepository "github.com/rzmn/governi/internal/repositories/outbox"
 	"github.com/rzmn/governi/internal/services/logging"
 )
 
 type Repository friendsRepository.Repository
+type OutboxRepository outboxRepository.Repository
 
-func New(repository Repository, logger logging.Service) friends.Controller {
+func New(repository Repository, outbox OutboxRepository, unitOfWork db.UnitOfWork, logger logging.Service) friends.Controller {
 	return &defaultController{
 		repository: repository,
+		outbox:     outbox,
+		unitOfWork: unitOfWork,
 		logger:     logger,
 	}
 }
 
 type defaultController struct {
 	repository Repository
+	outbox     OutboxRepository
+	unitOfWork db.UnitOfWork
 	logger     logging.Service
 }
 
-func (c *defaultController) AcceptFriendRequest(sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.AcceptFriendRequestErrorCode] {
+func (c *defaultController) AcceptFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.AcceptFriendRequestErrorCode] {
 	const op = "friends.defaultController.AcceptFriendRequest"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
-	hasRequest, err := c.repository.HasFriendRequest(friendsRepository.UserId(sender), friendsRepository.UserId(target))
+	hasRequest, err := c.repository.HasFriendRequest(ctx, friendsRepository.UserId(sender), friendsRepository.UserId(target))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot check friend request existence in db err: %v", op, err)
 		return common.NewErrorWithDescription(friends.AcceptFriendRequestErrorInternal, err.Error())
@@ -35,8 +45,13 @@ func (c *defaultController) AcceptFriendRequest(sender friends.UserId, target fr
 		c.logger.LogInfo("%s: does not have a friend request", op)
 		return common.NewError(friends.AcceptFriendRequestErrorNoSuchRequest)
 	}
-	transaction := c.repository.StoreFriendRequest(friendsRepository.UserId(target), friendsRepository.UserId(sender))
-	if err := transaction.Perform(); err != nil {
+	if err := c.performWithMessages(ctx, func(repository Repository) repositories.MutationWorkItem {
+		return repository.StoreFriendRequest(ctx, friendsRepository.UserId(target), friendsRepository.UserId(sender))
+	}, outboxController.KindFriendStatusChanged, outboxController.FriendStatusNotification{
+		Receiver: outboxController.UserId(sender),
+		UserId:   outboxController.UserId(target),
+		Status:   friendsRepository.FriendStatusFriend,
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot store friendship to db err: %v", op, err)
 		return common.NewErrorWithDescription(friends.AcceptFriendRequestErrorInternal, err.Error())
 	}
@@ -44,7 +59,7 @@ func (c *defaultController) AcceptFriendRequest(sender friends.UserId, target fr
 	return nil
 }
 
-func (c *defaultController) GetFriends(statuses []friends.FriendStatus, userId friends.UserId) (map[friends.FriendStatus][]friends.UserId, *common.CodeBasedError[friends.GetFriendsErrorCode]) {
+func (c *defaultController) GetFriends(ctx context.Context, statuses []friends.FriendStatus, userId friends.UserId) (map[friends.FriendStatus][]friends.UserId, *common.CodeBasedError[friends.GetFriendsErrorCode]) {
 	const op = "friends.defaultController.GetFriends"
 	c.logger.LogInfo("%s: start[statuses=%v uid=%s]", op, statuses, userId)
 	result := map[friends.FriendStatus][]friends.UserId{}
@@ -52,7 +67,7 @@ func (c *defaultController) GetFriends(statuses []friends.FriendStatus, userId f
 		result[statuses[i]] = []friends.UserId{}
 	}
 	if slices.Contains(statuses, friends.FriendStatusFriends) {
-		ids, err := c.repository.GetFriends(friendsRepository.UserId(userId))
+		ids, err := c.repository.GetFriends(ctx, friendsRepository.UserId(userId))
 		if err != nil {
 			c.logger.LogInfo("%s: cannot get friends from db err: %v", op, err)
 			return map[friends.FriendStatus][]friends.UserId{}, common.NewErrorWithDescription(friends.GetFriendsErrorInternal, err.Error())
@@ -62,7 +77,7 @@ func (c *defaultController) GetFriends(statuses []friends.FriendStatus, userId f
 		}
 	}
 	if slices.Contains(statuses, friends.FriendStatusSubscriber) {
-		ids, err := c.repository.GetSubscribers(friendsRepository.UserId(userId))
+		ids, err := c.repository.GetSubscribers(ctx, friendsRepository.UserId(userId))
 		if err != nil {
 			c.logger.LogInfo("%s: cannot get subscribers from db err: %v", op, err)
 			return map[friends.FriendStatus][]friends.UserId{}, common.NewErrorWithDescription(friends.GetFriendsErrorInternal, err.Error())
@@ -72,7 +87,7 @@ func (c *defaultController) GetFriends(statuses []friends.FriendStatus, userId f
 		}
 	}
 	if slices.Contains(statuses, friends.FriendStatusSubscription) {
-		ids, err := c.repository.GetSubscriptions(friendsRepository.UserId(userId))
+		ids, err := c.repository.GetSubscriptions(ctx, friendsRepository.UserId(userId))
 		if err != nil {
 			c.logger.LogInfo("%s: cannot get subscriptions from db err: %v", op, err)
 			return map[friends.FriendStatus][]friends.UserId{}, common.NewErrorWithDescription(friends.GetFriendsErrorInternal, err.Error())
@@ -85,10 +100,10 @@ func (c *defaultController) GetFriends(statuses []friends.FriendStatus, userId f
 	return result, nil
 }
 
-func (c *defaultController) RollbackFriendRequest(sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.RollbackFriendRequestErrorCode] {
+func (c *defaultController) RollbackFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.RollbackFriendRequestErrorCode] {
 	const op = "friends.defaultController.RollbackFriendRequest"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
-	hasRequest, err := c.repository.HasFriendRequest(friendsRepository.UserId(sender), friendsRepository.UserId(target))
+	hasRequest, err := c.repository.HasFriendRequest(ctx, friendsRepository.UserId(sender), friendsRepository.UserId(target))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot check has friend request in db err: %v", op, err)
 		return common.NewErrorWithDescription(friends.RollbackFriendRequestErrorInternal, err.Error())
@@ -97,8 +112,17 @@ func (c *defaultController) RollbackFriendRequest(sender friends.UserId, target
 		c.logger.LogInfo("%s: no friend request from %s to %s", op, sender, target)
 		return common.NewError(friends.RollbackFriendRequestErrorNoSuchRequest)
 	}
-	transaction := c.repository.RemoveFriendRequest(friendsRepository.UserId(sender), friendsRepository.UserId(target))
-	if err := transaction.Perform(); err != nil {
+	if err := c.performWithMessages(ctx, func(repository Repository) repositories.MutationWorkItem {
+		return repository.RemoveFriendRequest(ctx, friendsRepository.UserId(sender), friendsRepository.UserId(target))
+	}, outboxController.KindFriendStatusChanged, outboxController.FriendStatusNotification{
+		Receiver: outboxController.UserId(sender),
+		UserId:   outboxController.UserId(target),
+		Status:   friendsRepository.FriendStatusNo,
+	}, outboxController.FriendStatusNotification{
+		Receiver: outboxController.UserId(target),
+		UserId:   outboxController.UserId(sender),
+		Status:   friendsRepository.FriendStatusNo,
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot remove friend request from %s to %s from db err: %v", op, sender, target, err)
 		return common.NewErrorWithDescription(friends.RollbackFriendRequestErrorInternal, err.Error())
 	}
@@ -106,10 +130,10 @@ func (c *defaultController) RollbackFriendRequest(sender friends.UserId, target
 	return nil
 }
 
-func (c *defaultController) SendFriendRequest(sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.SendFriendRequestErrorCode] {
+func (c *defaultController) SendFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.SendFriendRequestErrorCode] {
 	const op = "friends.defaultController.SendFriendRequest"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
-	statuses, err := c.repository.GetStatuses(friendsRepository.UserId(sender), []friendsRepository.UserId{friendsRepository.UserId(target)})
+	statuses, err := c.repository.GetStatuses(ctx, friendsRepository.UserId(sender), []friendsRepository.UserId{friendsRepository.UserId(target)})
 	if err != nil {
 		c.logger.LogInfo("%s: cannot check target status of %s in db err: %v", op, target, err)
 		return common.NewErrorWithDescription(friends.SendFriendRequestErrorInternal, err.Error())
@@ -132,8 +156,12 @@ func (c *defaultController) SendFriendRequest(sender friends.UserId, target frie
 			return common.NewError(friends.SendFriendRequestErrorIncorrectUserStatus)
 		}
 	}
-	transaction := c.repository.StoreFriendRequest(friendsRepository.UserId(sender), friendsRepository.UserId(target))
-	if err := transaction.Perform(); err != nil {
+	if err := c.performWithMessages(ctx, func(repository Repository) repositories.MutationWorkItem {
+		return repository.StoreFriendRequest(ctx, friendsRepository.UserId(sender), friendsRepository.UserId(target))
+	}, outboxController.KindFriendRequestReceived, outboxController.FriendRequestNotification{
+		Receiver: outboxController.UserId(target),
+		Sender:   outboxController.UserId(sender),
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot store friend request from %s to %s in db err: %v", op, sender, target, err)
 		return common.NewErrorWithDescription(friends.SendFriendRequestErrorInternal, err.Error())
 	}
@@ -141,10 +169,10 @@ func (c *defaultController) SendFriendRequest(sender friends.UserId, target frie
 	return nil
 }
 
-func (c *defaultController) Unfriend(sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.UnfriendErrorCode] {
+func (c *defaultController) Unfriend(ctx context.Context, sender friends.UserId, target friends.UserId) *common.CodeBasedError[friends.UnfriendErrorCode] {
 	const op = "friends.defaultController.Unfriend"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
-	statuses, err := c.repository.GetStatuses(friendsRepository.UserId(sender), []friendsRepository.UserId{friendsRepository.UserId(target)})
+	statuses, err := c.repository.GetStatuses(ctx, friendsRepository.UserId(sender), []friendsRepository.UserId{friendsRepository.UserId(target)})
 	if err != nil {
 		c.logger.LogInfo("%s: cannot check target status of %s in db err: %v", op, target, err)
 		return common.NewErrorWithDescription(friends.UnfriendErrorInternal, err.Error())
@@ -158,11 +186,34 @@ func (c *defaultController) Unfriend(sender friends.UserId, target friends.UserI
 		c.logger.LogInfo("%s: status of %s is %d, expected %d", op, target, targetStatus, friendsRepository.FriendStatusFriend)
 		return common.NewError(friends.UnfriendErrorNotAFriend)
 	}
-	transaction := c.repository.RemoveFriendRequest(friendsRepository.UserId(sender), friendsRepository.UserId(target))
-	if err := transaction.Perform(); err != nil {
+	if err := c.performWithMessages(ctx, func(repository Repository) repositories.MutationWorkItem {
+		return repository.RemoveFriendRequest(ctx, friendsRepository.UserId(sender), friendsRepository.UserId(target))
+	}, outboxController.KindFriendStatusChanged, outboxController.FriendStatusNotification{
+		Receiver: outboxController.UserId(target),
+		UserId:   outboxController.UserId(sender),
+		Status:   friendsRepository.FriendStatusSubscription,
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot remove friend request from %s to %s from db err: %v", op, sender, target, err)
 		return common.NewErrorWithDescription(friends.UnfriendErrorInternal, err.Error())
 	}
 	c.logger.LogInfo("%s: success[sender=%s target=%s]", op, sender, target)
 	return nil
 }
+
+// performWithMessages performs mutation and stores notifications with payloads in a single transaction.
+func (c *defaultController) performWithMessages(ctx context.Context, mutation func(repository Repository) repositories.MutationWorkItem, kind outboxController.Kind, payloads ...any) error {
+	messages := make([]outboxRepository.Message, len(payloads))
+	for i, payload := range payloads {
+		message, err := outboxController.NewMessage(kind, payload)
+		if err != nil {
+			return err
+		}
+		messages[i] = message
+	}
+	return c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		if err := mutation(c.repository.WithTx(tx)).Perform(); err != nil {
+			return err
+		}
+		return c.outbox.WithTx(tx).AddMessages(ctx, messages).Perform()
+	})
+}
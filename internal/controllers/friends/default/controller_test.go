@@ -1,14 +1,21 @@
 package defaultController_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
 	"github.com/rzmn/governi/internal/controllers/friends"
 	defaultController "github.com/rzmn/governi/internal/controllers/friends/default"
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
+	"github.com/rzmn/governi/internal/db"
+	db_mock "github.com/rzmn/governi/internal/db/mock"
 	"github.com/rzmn/governi/internal/repositories"
 	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
 	friends_mock "github.com/rzmn/governi/internal/repositories/friends/mock"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	outbox_mock "github.com/rzmn/governi/internal/repositories/outbox/mock"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 
 	"github.com/google/uuid"
@@ -16,12 +23,12 @@ import (
 
 func TestAcceptRequestFailedToCheckIfRequestExists(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return false, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.AcceptFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.AcceptFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`AcceptFriendRequest` should fail, found nil err")
 	}
@@ -32,12 +39,12 @@ func TestAcceptRequestFailedToCheckIfRequestExists(t *testing.T) {
 
 func TestAcceptRequestFailedNoSuchRequest(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return false, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.AcceptFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.AcceptFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`AcceptFriendRequest` should fail, found nil err")
 	}
@@ -48,10 +55,10 @@ func TestAcceptRequestFailedNoSuchRequest(t *testing.T) {
 
 func TestAcceptRequestFailedToAccept(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return true, nil
 		},
-		StoreFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		StoreFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -59,8 +66,8 @@ func TestAcceptRequestFailedToAccept(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.AcceptFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.AcceptFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`AcceptFriendRequest` should fail, found nil err")
 	}
@@ -72,10 +79,10 @@ func TestAcceptRequestFailedToAccept(t *testing.T) {
 func TestAcceptRequestOk(t *testing.T) {
 	storeCalls := 0
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return true, nil
 		},
-		StoreFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		StoreFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeCalls += 1
@@ -84,8 +91,8 @@ func TestAcceptRequestOk(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.AcceptFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.AcceptFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`AcceptFriendRequest` should not fail, found err %v", err)
 	}
@@ -110,7 +117,7 @@ func testGetFriendsGetOnlyRequestedStatus(t *testing.T, statuses []friends.Frien
 	getSubscriptionsCalls := 0
 	getFriendsCalls := 0
 	repository := friends_mock.RepositoryMock{
-		GetSubscribersImpl: func(userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
+		GetSubscribersImpl: func(ctx context.Context, userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
 			if getSubscribersCalls >= 1 {
 				t.Fatalf("get subscribers should be called at most once")
 			}
@@ -124,7 +131,7 @@ func testGetFriendsGetOnlyRequestedStatus(t *testing.T, statuses []friends.Frien
 			}
 			return []friendsRepository.UserId{}, errors.New("some error")
 		},
-		GetSubscriptionsImpl: func(userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
+		GetSubscriptionsImpl: func(ctx context.Context, userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
 			if getSubscriptionsCalls >= 1 {
 				t.Fatalf("get subscriptions should be called at most once")
 			}
@@ -138,7 +145,7 @@ func testGetFriendsGetOnlyRequestedStatus(t *testing.T, statuses []friends.Frien
 			}
 			return []friendsRepository.UserId{}, errors.New("some error")
 		},
-		GetFriendsImpl: func(userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
+		GetFriendsImpl: func(ctx context.Context, userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
 			if getFriendsCalls >= 1 {
 				t.Fatalf("get friends should be called at most once")
 			}
@@ -153,8 +160,8 @@ func testGetFriendsGetOnlyRequestedStatus(t *testing.T, statuses []friends.Frien
 			return []friendsRepository.UserId{}, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	friendsMap, err := controller.GetFriends(statuses, friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	friendsMap, err := controller.GetFriends(context.Background(), statuses, friends.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`GetFriends` should not be failed, found err: %v", err)
 	}
@@ -174,24 +181,24 @@ func testGetFriendsGetOnlyRequestedStatus(t *testing.T, statuses []friends.Frien
 
 func TestGetFriendsGetFailedEachStatus(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		GetSubscribersImpl: func(userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
+		GetSubscribersImpl: func(ctx context.Context, userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
 			return []friendsRepository.UserId{}, errors.New("some error")
 		},
-		GetSubscriptionsImpl: func(userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
+		GetSubscriptionsImpl: func(ctx context.Context, userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
 			return []friendsRepository.UserId{}, errors.New("some error")
 		},
-		GetFriendsImpl: func(userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
+		GetFriendsImpl: func(ctx context.Context, userId friendsRepository.UserId) ([]friendsRepository.UserId, error) {
 			return []friendsRepository.UserId{}, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
 	testGetFriendsGetFailed(t, controller, friends.FriendStatusSubscriber)
 	testGetFriendsGetFailed(t, controller, friends.FriendStatusSubscription)
 	testGetFriendsGetFailed(t, controller, friends.FriendStatusFriends)
 }
 
 func testGetFriendsGetFailed(t *testing.T, controller friends.Controller, status friends.FriendStatus) {
-	_, err := controller.GetFriends([]friends.FriendStatus{status}, friends.UserId(uuid.New().String()))
+	_, err := controller.GetFriends(context.Background(), []friends.FriendStatus{status}, friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetFriends` should fail, found success")
 	}
@@ -202,12 +209,12 @@ func testGetFriendsGetFailed(t *testing.T, controller friends.Controller, status
 
 func TestRollbackFailedToCheckIfRequestExists(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return false, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.RollbackFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.RollbackFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`RollbackFriendRequest` should fail, found nil err")
 	}
@@ -218,12 +225,12 @@ func TestRollbackFailedToCheckIfRequestExists(t *testing.T) {
 
 func TestRollbackFailedNoSuchRequest(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return false, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.RollbackFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.RollbackFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`RollbackFriendRequest` should fail, found nil err")
 	}
@@ -234,10 +241,10 @@ func TestRollbackFailedNoSuchRequest(t *testing.T) {
 
 func TestRollbackFailedToRemove(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return true, nil
 		},
-		RemoveFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		RemoveFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -245,8 +252,8 @@ func TestRollbackFailedToRemove(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.RollbackFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.RollbackFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`RollbackFriendRequest` should fail, found nil err")
 	}
@@ -258,10 +265,10 @@ func TestRollbackFailedToRemove(t *testing.T) {
 func TestRollbackOk(t *testing.T) {
 	storeCalls := 0
 	repository := friends_mock.RepositoryMock{
-		HasFriendRequestImpl: func(sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
+		HasFriendRequestImpl: func(ctx context.Context, sender friendsRepository.UserId, target friendsRepository.UserId) (bool, error) {
 			return true, nil
 		},
-		RemoveFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		RemoveFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeCalls += 1
@@ -270,8 +277,8 @@ func TestRollbackOk(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.RollbackFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.RollbackFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`RollbackFriendRequest` should not fail, found err %v", err)
 	}
@@ -282,12 +289,12 @@ func TestRollbackOk(t *testing.T) {
 
 func TestSendRequestFailedToCheckStatus(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{}, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.SendFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`SendFriendRequest` should fail, found nil err")
 	}
@@ -298,12 +305,12 @@ func TestSendRequestFailedToCheckStatus(t *testing.T) {
 
 func TestSendRequestFailedUnknownStatus(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.SendFriendRequest(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`SendFriendRequest` should fail, found nil err")
 	}
@@ -315,14 +322,14 @@ func TestSendRequestFailedUnknownStatus(t *testing.T) {
 func TestSendRequestFailedTargetIsMe(t *testing.T) {
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusMe,
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.SendFriendRequest(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err == nil {
 		t.Fatalf("`SendFriendRequest` should fail, found nil err")
 	}
@@ -334,14 +341,14 @@ func TestSendRequestFailedTargetIsMe(t *testing.T) {
 func TestSendRequestAlreadySent(t *testing.T) {
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusSubscription,
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.SendFriendRequest(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err == nil {
 		t.Fatalf("`SendFriendRequest` should fail, found nil err")
 	}
@@ -353,14 +360,14 @@ func TestSendRequestAlreadySent(t *testing.T) {
 func TestSendRequestHaveIncoming(t *testing.T) {
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusSubscriber,
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.SendFriendRequest(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err == nil {
 		t.Fatalf("`SendFriendRequest` should fail, found nil err")
 	}
@@ -372,12 +379,12 @@ func TestSendRequestHaveIncoming(t *testing.T) {
 func TestSendRequestFailedToStoreRequest(t *testing.T) {
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusNo,
 			}, nil
 		},
-		StoreFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		StoreFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -385,8 +392,8 @@ func TestSendRequestFailedToStoreRequest(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.SendFriendRequest(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err == nil {
 		t.Fatalf("`SendFriendRequest` should fail, found nil err")
 	}
@@ -399,12 +406,12 @@ func TestSendRequestOk(t *testing.T) {
 	storeCalls := 0
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusNo,
 			}, nil
 		},
-		StoreFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		StoreFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeCalls += 1
@@ -413,24 +420,36 @@ func TestSendRequestOk(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.SendFriendRequest(friends.UserId(uuid.New().String()), target)
+	stored := []outbox.Message{}
+	controller := defaultController.New(&repository, outboxMock(&stored), unitOfWorkMock(), standartOutputLoggingService.New())
+	sender := friends.UserId(uuid.New().String())
+	err := controller.SendFriendRequest(context.Background(), sender, target)
 	if err != nil {
 		t.Fatalf("`SendFriendRequest` should not fail, found err %v", err)
 	}
 	if storeCalls != 1 {
 		t.Fatalf("`store should be called once, found %d", storeCalls)
 	}
+	if len(stored) != 1 || stored[0].Kind != string(outboxController.KindFriendRequestReceived) {
+		t.Fatalf("target should be notified about friend request, found %v", stored)
+	}
+	var notification outboxController.FriendRequestNotification
+	if err := json.Unmarshal(stored[0].Payload, &notification); err != nil {
+		t.Fatalf("failed to decode notification err: %v", err)
+	}
+	if notification.Receiver != outboxController.UserId(target) || notification.Sender != outboxController.UserId(sender) {
+		t.Fatalf("unexpected notification %v", notification)
+	}
 }
 
 func TestUnfriendFailedToCheckStatus(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{}, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.Unfriend(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.Unfriend(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`Unfriend` should fail, found nil err")
 	}
@@ -441,12 +460,12 @@ func TestUnfriendFailedToCheckStatus(t *testing.T) {
 
 func TestUnfriendUnknownStatus(t *testing.T) {
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.Unfriend(friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.Unfriend(context.Background(), friends.UserId(uuid.New().String()), friends.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`Unfriend` should fail, found nil err")
 	}
@@ -465,14 +484,14 @@ func TestUnfriendNotAFriend(t *testing.T) {
 func testUnfriendNotAFriend(t *testing.T, status friendsRepository.FriendStatus) {
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): status,
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.Unfriend(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.Unfriend(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err == nil {
 		t.Fatalf("`Unfriend` should fail, found nil err")
 	}
@@ -484,12 +503,12 @@ func testUnfriendNotAFriend(t *testing.T, status friendsRepository.FriendStatus)
 func TestUnfriendFailedToRemove(t *testing.T) {
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusFriend,
 			}, nil
 		},
-		RemoveFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		RemoveFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -497,8 +516,8 @@ func TestUnfriendFailedToRemove(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.Unfriend(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.Unfriend(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err == nil {
 		t.Fatalf("`Unfriend` should fail, found nil err")
 	}
@@ -511,12 +530,12 @@ func TestUnfriendOk(t *testing.T) {
 	removeCalls := 0
 	target := friends.UserId(uuid.New().String())
 	repository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(target): friendsRepository.FriendStatusFriend,
 			}, nil
 		},
-		RemoveFriendRequestImpl: func(sender, target friendsRepository.UserId) repositories.MutationWorkItem {
+		RemoveFriendRequestImpl: func(ctx context.Context, sender, target friendsRepository.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					removeCalls += 1
@@ -525,8 +544,8 @@ func TestUnfriendOk(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	err := controller.Unfriend(friends.UserId(uuid.New().String()), target)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	err := controller.Unfriend(context.Background(), friends.UserId(uuid.New().String()), target)
 	if err != nil {
 		t.Fatalf("`Unfriend` should not fail, found err %v", err)
 	}
@@ -534,3 +553,26 @@ func TestUnfriendOk(t *testing.T) {
 		t.Fatalf("`remove should be called once, found %d", removeCalls)
 	}
 }
+
+func outboxMock(stored *[]outbox.Message) *outbox_mock.RepositoryMock {
+	return &outbox_mock.RepositoryMock{
+		AddMessagesImpl: func(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					if stored != nil {
+						*stored = append(*stored, messages...)
+					}
+					return nil
+				},
+			}
+		},
+	}
+}
+
+func unitOfWorkMock() *db_mock.UnitOfWorkMock {
+	return &db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			return work(nil)
+		},
+	}
+}
@@ -0,0 +1,13 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/common"
+)
+
+type Controller interface {
+	// Dispatch sends notifications that are due, retrying failed ones later, and returns
+	// the number of processed messages.
+	Dispatch(ctx context.Context) (int, *common.CodeBasedError[DispatchErrorCode])
+}
@@ -0,0 +1,238 @@
+package defaultController
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rzmn/governi/internal/common"
+	"github.com/rzmn/governi/internal/controllers/outbox"
+	"github.com/rzmn/governi/internal/db"
+	outboxRepository "github.com/rzmn/governi/internal/repositories/outbox"
+	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+)
+
+const (
+	batchSize      = 100
+	leaseDuration  = time.Minute
+	maxAttempts    = 10
+	baseRetryDelay = 2 * time.Second
+	maxRetryDelay  = 10 * time.Minute
+)
+
+var errMalformedMessage = errors.New("malformed message")
+
+type Repository outboxRepository.Repository
+type SpendingsRepository spendingsRepository.Repository
+
+func New(
+	repository Repository,
+	spendings SpendingsRepository,
+	pushService pushNotifications.Service,
+	realtimeEvents realtimeEvents.Service,
+	logger logging.Service,
+	currentTime func() time.Time,
+) outbox.Controller {
+	return &defaultController{
+		repository:     repository,
+		spendings:      spendings,
+		pushService:    pushService,
+		realtimeEvents: realtimeEvents,
+		logger:         logger,
+		currentTime:    currentTime,
+	}
+}
+
+type defaultController struct {
+	repository     Repository
+	spendings      SpendingsRepository
+	pushService    pushNotifications.Service
+	realtimeEvents realtimeEvents.Service
+	logger         logging.Service
+	currentTime    func() time.Time
+}
+
+func (c *defaultController) Dispatch(ctx context.Context) (int, *common.CodeBasedError[outbox.DispatchErrorCode]) {
+	const op = "outbox.defaultController.Dispatch"
+	now := c.currentTime()
+	leasedUntil := now.Add(leaseDuration)
+	messages, err := c.repository.LeaseMessages(ctx, now.Unix(), leasedUntil.Unix(), batchSize)
+	if err != nil {
+		c.logger.LogInfo("%s: cannot lease messages from db err: %v", op, err)
+		return 0, common.NewErrorWithDescription(outbox.DispatchErrorInternal, err.Error())
+	}
+	processed := 0
+	for len(messages) > 0 {
+		// sends are sequential, leases of the rest of the batch are renewed before they run out
+		// so that no other dispatcher picks the messages up while they are still being sent
+		if now := c.currentTime(); now.After(leasedUntil.Add(-leaseDuration / 2)) {
+			renewedUntil := now.Add(leaseDuration)
+			renewed, err := c.repository.RenewLeases(ctx, common.Map(messages, func(message outboxRepository.Message) outboxRepository.MessageId {
+				return message.Id
+			}), leasedUntil.Unix(), renewedUntil.Unix())
+			if err != nil {
+				c.logger.LogError("%s: cannot renew leases of %d messages, leaving them until the lease runs out err: %v", op, len(messages), err)
+				break
+			}
+			stillLeased := []outboxRepository.Message{}
+			for _, message := range messages {
+				if slices.Contains(renewed, message.Id) {
+					stillLeased = append(stillLeased, message)
+				}
+			}
+			messages = stillLeased
+			leasedUntil = renewedUntil
+			continue
+		}
+		c.dispatch(ctx, messages[0])
+		messages = messages[1:]
+		processed += 1
+	}
+	return processed, nil
+}
+
+func (c *defaultController) dispatch(ctx context.Context, message outboxRepository.Message) {
+	const op = "outbox.defaultController.dispatch"
+	sendErr := c.send(ctx, message)
+	now := c.currentTime()
+	if sendErr == nil {
+		if err := c.repository.RemoveMessage(ctx, message.Id); err != nil {
+			c.logger.LogError("%s: cannot remove sent message %s err: %v", op, message.Id, err)
+		}
+		return
+	}
+	if message.Attempts >= maxAttempts || errors.Is(sendErr, errMalformedMessage) {
+		c.logger.LogError("%s: dead lettering message %s of kind %s after %d attempts err: %v", op, message.Id, message.Kind, message.Attempts, sendErr)
+		if err := c.repository.DeadLetterMessage(ctx, message.Id, now.Unix(), sendErr.Error()); err != nil {
+			c.logger.LogError("%s: cannot dead letter message %s err: %v", op, message.Id, err)
+		}
+		return
+	}
+	// a push delivered to some of the tokens is sent again only to the rest of them
+	tokens := message.Tokens
+	var undelivered *pushNotifications.UndeliveredError
+	if errors.As(sendErr, &undelivered) {
+		tokens = undelivered.Tokens
+	}
+	nextAttemptAt := now.Add(retryDelay(message.Attempts))
+	c.logger.LogInfo("%s: message %s of kind %s failed, retrying at %d err: %v", op, message.Id, message.Kind, nextAttemptAt.Unix(), sendErr)
+	if err := c.repository.RetryMessage(ctx, message.Id, nextAttemptAt.Unix(), sendErr.Error(), tokens); err != nil {
+		c.logger.LogError("%s: cannot schedule retry of message %s err: %v", op, message.Id, err)
+	}
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+func (c *defaultController) send(ctx context.Context, message outboxRepository.Message) error {
+	switch outbox.Kind(message.Kind) {
+	case outbox.KindNewExpenseReceived:
+		return withPayload(message, func(notification outbox.ExpenseNotification) error {
+			pushService := c.pushService
+			if len(message.Tokens) > 0 {
+				pushService = pushService.WithTokens(message.Tokens)
+			}
+			return pushService.NewExpenseReceived(
+				pushNotifications.UserId(notification.Receiver),
+				pushNotifications.Expense(mapIdentifiableExpense(notification.Expense)),
+				pushNotifications.UserId(notification.Author),
+			)
+		})
+	case outbox.KindExpenseAdded:
+		return withPayload(message, func(notification outbox.ExpenseNotification) error {
+			return c.realtimeEvents.ExpenseAdded(realtimeEvents.UserId(notification.Receiver), schema.ExpenseAddedEvent{
+				Counterparty: schema.UserId(notification.Author),
+				Expense:      mapIdentifiableExpense(notification.Expense),
+			})
+		})
+	case outbox.KindExpenseRemoved:
+		return withPayload(message, func(notification outbox.ExpenseNotification) error {
+			return c.realtimeEvents.ExpenseRemoved(realtimeEvents.UserId(notification.Receiver), schema.ExpenseRemovedEvent{
+				Counterparty: schema.UserId(notification.Author),
+				Expense:      mapIdentifiableExpense(notification.Expense),
+			})
+		})
+	case outbox.KindBalanceChanged:
+		return withPayload(message, func(notification outbox.BalanceNotification) error {
+			balance, err := c.spendings.GetBalance(db.ForcePrimary(ctx), spendingsRepository.CounterpartyId(notification.Receiver))
+			if err != nil {
+				return fmt.Errorf("getting balance of %s: %w", notification.Receiver, err)
+			}
+			return c.realtimeEvents.BalanceChanged(realtimeEvents.UserId(notification.Receiver), schema.BalanceChangedEvent{
+				Balance: common.Map(balance, mapBalance),
+			})
+		})
+	case outbox.KindFriendRequestReceived:
+		return withPayload(message, func(notification outbox.FriendRequestNotification) error {
+			return c.realtimeEvents.FriendRequestReceived(realtimeEvents.UserId(notification.Receiver), schema.FriendRequestReceivedEvent{
+				Sender: schema.UserId(notification.Sender),
+			})
+		})
+	case outbox.KindFriendStatusChanged:
+		return withPayload(message, func(notification outbox.FriendStatusNotification) error {
+			return c.realtimeEvents.FriendStatusChanged(realtimeEvents.UserId(notification.Receiver), schema.FriendStatusChangedEvent{
+				UserId: schema.UserId(notification.UserId),
+				Status: schema.FriendStatus(notification.Status),
+			})
+		})
+	case outbox.KindProfileChanged:
+		return withPayload(message, func(notification outbox.ProfileNotification) error {
+			return c.realtimeEvents.ProfileChanged(realtimeEvents.UserId(notification.Receiver), schema.ProfileChangedEvent{
+				DisplayName: notification.DisplayName,
+				AvatarId:    (*schema.ImageId)(notification.AvatarId),
+			})
+		})
+	default:
+		return fmt.Errorf("%w: unknown kind %s", errMalformedMessage, message.Kind)
+	}
+}
+
+func withPayload[T any](message outboxRepository.Message, send func(T) error) error {
+	var payload T
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: %w", errMalformedMessage, err)
+	}
+	return send(payload)
+}
+
+func mapIdentifiableExpense(expense spendingsRepository.IdentifiableExpense) schema.IdentifiableExpense {
+	return schema.IdentifiableExpense{
+		Id: schema.ExpenseId(expense.Id),
+		Expense: schema.Expense{
+			Timestamp:   expense.Timestamp,
+			Details:     expense.Details,
+			Total:       schema.Cost(expense.Total),
+			Attachments: []schema.ExpenseAttachment{},
+			Currency:    schema.Currency(expense.Currency),
+			Shares: common.Map(expense.Shares, func(share spendingsRepository.ShareOfExpense) schema.ShareOfExpense {
+				return schema.ShareOfExpense{
+					UserId: schema.UserId(share.Counterparty),
+					Cost:   schema.Cost(share.Cost),
+				}
+			}),
+		},
+	}
+}
+
+func mapBalance(balance spendingsRepository.Balance) schema.Balance {
+	currencies := map[schema.Currency]schema.Cost{}
+	for currency, cost := range balance.Currencies {
+		currencies[schema.Currency(currency)] = schema.Cost(cost)
+	}
+	return schema.Balance{
+		Counterparty: string(balance.Counterparty),
+		Currencies:   currencies,
+	}
+}
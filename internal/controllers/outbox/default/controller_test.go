@@ -0,0 +1,329 @@
+package defaultController_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
+	defaultController "github.com/rzmn/governi/internal/controllers/outbox/default"
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	outbox_mock "github.com/rzmn/governi/internal/repositories/outbox/mock"
+	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
+	spendings_mock "github.com/rzmn/governi/internal/repositories/spendings/mock"
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+	pushNotifications_mock "github.com/rzmn/governi/internal/services/pushNotifications/mock"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+	realtimeEvents_mock "github.com/rzmn/governi/internal/services/realtimeEvents/mock"
+
+	"github.com/google/uuid"
+)
+
+var now = time.Unix(1000, 0)
+
+// testLogger does not stop the test on LogError, dead lettering is reported as an error.
+type testLogger struct {
+	t *testing.T
+}
+
+func (c testLogger) LogInfo(format string, v ...any) {
+	c.t.Logf(format, v...)
+}
+
+func (c testLogger) LogError(format string, v ...any) {
+	c.t.Logf(format, v...)
+}
+
+func (c testLogger) LogFatal(format string, v ...any) {
+	c.t.Fatalf(format, v...)
+}
+
+type outcome struct {
+	removed        []outbox.MessageId
+	retriedAt      map[outbox.MessageId]int64
+	retriedTokens  map[outbox.MessageId][]string
+	deadLetteredAt map[outbox.MessageId]int64
+}
+
+func outboxRepositoryMock(messages []outbox.Message, result *outcome) *outbox_mock.RepositoryMock {
+	result.retriedAt = map[outbox.MessageId]int64{}
+	result.retriedTokens = map[outbox.MessageId][]string{}
+	result.deadLetteredAt = map[outbox.MessageId]int64{}
+	return &outbox_mock.RepositoryMock{
+		LeaseMessagesImpl: func(ctx context.Context, now int64, leaseUntil int64, limit int) ([]outbox.Message, error) {
+			return messages, nil
+		},
+		RemoveMessageImpl: func(ctx context.Context, id outbox.MessageId) error {
+			result.removed = append(result.removed, id)
+			return nil
+		},
+		RetryMessageImpl: func(ctx context.Context, id outbox.MessageId, nextAttemptAt int64, lastError string, tokens []string) error {
+			result.retriedAt[id] = nextAttemptAt
+			result.retriedTokens[id] = tokens
+			return nil
+		},
+		DeadLetterMessageImpl: func(ctx context.Context, id outbox.MessageId, deadLetteredAt int64, lastError string) error {
+			result.deadLetteredAt[id] = deadLetteredAt
+			return nil
+		},
+	}
+}
+
+func message(t *testing.T, kind outboxController.Kind, payload any, attempts int) outbox.Message {
+	message, err := outboxController.NewMessage(kind, payload)
+	if err != nil {
+		t.Fatalf("failed to create message err: %v", err)
+	}
+	message.Id = outbox.MessageId(uuid.New().String())
+	message.Attempts = attempts
+	return message
+}
+
+func createController(t *testing.T, repository *outbox_mock.RepositoryMock, spendings *spendings_mock.RepositoryMock, push *pushNotifications_mock.ServiceMock, realtime *realtimeEvents_mock.ServiceMock) outboxController.Controller {
+	return defaultController.New(repository, spendings, push, realtime, testLogger{t: t}, func() time.Time {
+		return now
+	})
+}
+
+func TestDispatchLeaseFailed(t *testing.T) {
+	repository := outbox_mock.RepositoryMock{
+		LeaseMessagesImpl: func(ctx context.Context, now int64, leaseUntil int64, limit int) ([]outbox.Message, error) {
+			return nil, errors.New("some error")
+		},
+	}
+	controller := createController(t, &repository, &spendings_mock.RepositoryMock{}, &pushNotifications_mock.ServiceMock{}, &realtimeEvents_mock.ServiceMock{})
+	_, err := controller.Dispatch(context.Background())
+	if err == nil {
+		t.Fatalf("`Dispatch` should be failed, found nil err")
+	}
+	if err.Code != outboxController.DispatchErrorInternal {
+		t.Fatalf("`Dispatch` should be failed with `internal`, found err %v", err)
+	}
+}
+
+func TestDispatchSendsAndRemovesMessages(t *testing.T) {
+	receiver := uuid.New().String()
+	author := uuid.New().String()
+	expense := spendingsRepository.IdentifiableExpense{
+		Id: spendingsRepository.ExpenseId(uuid.New().String()),
+	}
+	notification := outboxController.ExpenseNotification{
+		Receiver: outboxController.UserId(receiver),
+		Author:   outboxController.UserId(author),
+		Expense:  expense,
+	}
+	messages := []outbox.Message{
+		message(t, outboxController.KindNewExpenseReceived, notification, 1),
+		message(t, outboxController.KindExpenseAdded, notification, 1),
+		message(t, outboxController.KindBalanceChanged, outboxController.BalanceNotification{Receiver: outboxController.UserId(receiver)}, 1),
+	}
+	var result outcome
+	repository := outboxRepositoryMock(messages, &result)
+	var pushed, added, balanceChanged int
+	var balanceReadFromPrimary bool
+	spendings := spendings_mock.RepositoryMock{
+		GetBalanceImpl: func(ctx context.Context, counterparty spendingsRepository.CounterpartyId) ([]spendingsRepository.Balance, error) {
+			balanceReadFromPrimary = db.IsPrimaryForced(ctx)
+			return []spendingsRepository.Balance{{Counterparty: spendingsRepository.CounterpartyId(author)}}, nil
+		},
+	}
+	push := pushNotifications_mock.ServiceMock{
+		NewExpenseReceivedImpl: func(to pushNotifications.UserId, expense pushNotifications.Expense, by pushNotifications.UserId) error {
+			if string(to) != receiver || string(by) != author {
+				t.Fatalf("unexpected push receiver %s author %s", to, by)
+			}
+			pushed += 1
+			return nil
+		},
+	}
+	realtime := realtimeEvents_mock.ServiceMock{
+		ExpenseAddedImpl: func(uid realtimeEvents.UserId, event schema.ExpenseAddedEvent) error {
+			if string(uid) != receiver || string(event.Counterparty) != author || string(event.Expense.Id) != string(expense.Id) {
+				t.Fatalf("unexpected expense added event %v to %s", event, uid)
+			}
+			added += 1
+			return nil
+		},
+		BalanceChangedImpl: func(uid realtimeEvents.UserId, event schema.BalanceChangedEvent) error {
+			if string(uid) != receiver || len(event.Balance) != 1 || event.Balance[0].Counterparty != author {
+				t.Fatalf("unexpected balance changed event %v to %s", event, uid)
+			}
+			balanceChanged += 1
+			return nil
+		},
+	}
+	controller := createController(t, repository, &spendings, &push, &realtime)
+	processed, err := controller.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("`Dispatch` should not be failed, found err %v", err)
+	}
+	if processed != len(messages) {
+		t.Fatalf("all messages should be processed, found %d", processed)
+	}
+	if pushed != 1 || added != 1 || balanceChanged != 1 {
+		t.Fatalf("each notification should be sent once, found push %d added %d balance %d", pushed, added, balanceChanged)
+	}
+	if !balanceReadFromPrimary {
+		t.Fatalf("balance should be read from primary")
+	}
+	if len(result.removed) != len(messages) || len(result.retriedAt) != 0 || len(result.deadLetteredAt) != 0 {
+		t.Fatalf("sent messages should be removed, found %v", result)
+	}
+}
+
+func TestDispatchRetriesFailedMessages(t *testing.T) {
+	notification := outboxController.FriendRequestNotification{
+		Receiver: outboxController.UserId(uuid.New().String()),
+		Sender:   outboxController.UserId(uuid.New().String()),
+	}
+	first := message(t, outboxController.KindFriendRequestReceived, notification, 1)
+	third := message(t, outboxController.KindFriendRequestReceived, notification, 3)
+	var result outcome
+	repository := outboxRepositoryMock([]outbox.Message{first, third}, &result)
+	realtime := realtimeEvents_mock.ServiceMock{
+		FriendRequestReceivedImpl: func(uid realtimeEvents.UserId, event schema.FriendRequestReceivedEvent) error {
+			return errors.New("some error")
+		},
+	}
+	controller := createController(t, repository, &spendings_mock.RepositoryMock{}, &pushNotifications_mock.ServiceMock{}, &realtime)
+	if _, err := controller.Dispatch(context.Background()); err != nil {
+		t.Fatalf("`Dispatch` should not be failed, found err %v", err)
+	}
+	if len(result.removed) != 0 || len(result.deadLetteredAt) != 0 {
+		t.Fatalf("failed messages should not be removed or dead lettered, found %v", result)
+	}
+	if result.retriedAt[first.Id] != now.Add(2*time.Second).Unix() {
+		t.Fatalf("first attempt should be retried in 2s, found %d", result.retriedAt[first.Id])
+	}
+	if result.retriedAt[third.Id] != now.Add(8*time.Second).Unix() {
+		t.Fatalf("third attempt should be retried in 8s, found %d", result.retriedAt[third.Id])
+	}
+}
+
+func TestDispatchDeadLettersMessages(t *testing.T) {
+	notification := outboxController.FriendRequestNotification{
+		Receiver: outboxController.UserId(uuid.New().String()),
+		Sender:   outboxController.UserId(uuid.New().String()),
+	}
+	exhausted := message(t, outboxController.KindFriendRequestReceived, notification, 10)
+	unknown := message(t, outboxController.Kind("unknown"), notification, 1)
+	malformed := outbox.Message{
+		Id:       outbox.MessageId(uuid.New().String()),
+		Kind:     string(outboxController.KindFriendRequestReceived),
+		Payload:  []byte("{"),
+		Attempts: 1,
+	}
+	var result outcome
+	repository := outboxRepositoryMock([]outbox.Message{exhausted, unknown, malformed}, &result)
+	realtime := realtimeEvents_mock.ServiceMock{
+		FriendRequestReceivedImpl: func(uid realtimeEvents.UserId, event schema.FriendRequestReceivedEvent) error {
+			return errors.New("some error")
+		},
+	}
+	controller := createController(t, repository, &spendings_mock.RepositoryMock{}, &pushNotifications_mock.ServiceMock{}, &realtime)
+	if _, err := controller.Dispatch(context.Background()); err != nil {
+		t.Fatalf("`Dispatch` should not be failed, found err %v", err)
+	}
+	if len(result.removed) != 0 || len(result.retriedAt) != 0 {
+		t.Fatalf("messages should not be removed or retried, found %v", result)
+	}
+	for _, id := range []outbox.MessageId{exhausted.Id, unknown.Id, malformed.Id} {
+		if result.deadLetteredAt[id] != now.Unix() {
+			t.Fatalf("message %s should be dead lettered, found %v", id, result.deadLetteredAt)
+		}
+	}
+}
+
+func TestDispatchRetriesUndeliveredTokens(t *testing.T) {
+	notification := outboxController.ExpenseNotification{
+		Receiver: outboxController.UserId(uuid.New().String()),
+		Author:   outboxController.UserId(uuid.New().String()),
+	}
+	partiallyDelivered := message(t, outboxController.KindNewExpenseReceived, notification, 1)
+	retried := message(t, outboxController.KindNewExpenseReceived, notification, 2)
+	retried.Tokens = []string{"undelivered"}
+	var result outcome
+	repository := outboxRepositoryMock([]outbox.Message{partiallyDelivered, retried}, &result)
+	var restrictedTo []string
+	push := pushNotifications_mock.ServiceMock{
+		NewExpenseReceivedImpl: func(to pushNotifications.UserId, expense pushNotifications.Expense, by pushNotifications.UserId) error {
+			return &pushNotifications.UndeliveredError{
+				Tokens: []string{"failed"},
+				Err:    errors.New("some error"),
+			}
+		},
+		WithTokensImpl: func(tokens []string) pushNotifications.Service {
+			restrictedTo = tokens
+			return &pushNotifications_mock.ServiceMock{
+				NewExpenseReceivedImpl: func(to pushNotifications.UserId, expense pushNotifications.Expense, by pushNotifications.UserId) error {
+					return nil
+				},
+			}
+		},
+	}
+	controller := createController(t, repository, &spendings_mock.RepositoryMock{}, &push, &realtimeEvents_mock.ServiceMock{})
+	if _, err := controller.Dispatch(context.Background()); err != nil {
+		t.Fatalf("`Dispatch` should not be failed, found err %v", err)
+	}
+	if !reflect.DeepEqual(result.retriedTokens[partiallyDelivered.Id], []string{"failed"}) {
+		t.Fatalf("push should be retried only to undelivered tokens, found %v", result.retriedTokens)
+	}
+	if !reflect.DeepEqual(restrictedTo, retried.Tokens) {
+		t.Fatalf("retried push should be sent only to %v, found %v", retried.Tokens, restrictedTo)
+	}
+	if len(result.removed) != 1 || result.removed[0] != retried.Id {
+		t.Fatalf("retried push should be removed once delivered, found %v", result.removed)
+	}
+}
+
+func TestDispatchRenewsLeases(t *testing.T) {
+	notification := outboxController.FriendRequestNotification{
+		Receiver: outboxController.UserId(uuid.New().String()),
+		Sender:   outboxController.UserId(uuid.New().String()),
+	}
+	messages := []outbox.Message{
+		message(t, outboxController.KindFriendRequestReceived, notification, 1),
+		message(t, outboxController.KindFriendRequestReceived, notification, 1),
+		message(t, outboxController.KindFriendRequestReceived, notification, 1),
+		message(t, outboxController.KindFriendRequestReceived, notification, 1),
+	}
+	var result outcome
+	repository := outboxRepositoryMock(messages, &result)
+	var renewedIds []outbox.MessageId
+	var renewedLeasedUntil, renewedLeaseUntil int64
+	repository.RenewLeasesImpl = func(ctx context.Context, ids []outbox.MessageId, leasedUntil int64, leaseUntil int64) ([]outbox.MessageId, error) {
+		renewedIds = ids
+		renewedLeasedUntil = leasedUntil
+		renewedLeaseUntil = leaseUntil
+		// the last message was leased by another dispatcher
+		return ids[:len(ids)-1], nil
+	}
+	realtime := realtimeEvents_mock.ServiceMock{
+		FriendRequestReceivedImpl: func(uid realtimeEvents.UserId, event schema.FriendRequestReceivedEvent) error {
+			return nil
+		},
+	}
+	// every read of the clock takes 10 seconds
+	calls := 0
+	controller := defaultController.New(repository, &spendings_mock.RepositoryMock{}, &pushNotifications_mock.ServiceMock{}, &realtime, testLogger{t: t}, func() time.Time {
+		calls += 1
+		return now.Add(time.Duration(calls-1) * 10 * time.Second)
+	})
+	processed, err := controller.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("`Dispatch` should not be failed, found err %v", err)
+	}
+	if !reflect.DeepEqual(renewedIds, []outbox.MessageId{messages[2].Id, messages[3].Id}) {
+		t.Fatalf("leases of unsent messages should be renewed, found %v", renewedIds)
+	}
+	if renewedLeasedUntil != now.Add(time.Minute).Unix() || renewedLeaseUntil != now.Add(50*time.Second+time.Minute).Unix() {
+		t.Fatalf("lease until %d should be renewed for a minute, found %d -> %d", now.Add(time.Minute).Unix(), renewedLeasedUntil, renewedLeaseUntil)
+	}
+	if processed != 3 || !reflect.DeepEqual(result.removed, []outbox.MessageId{messages[0].Id, messages[1].Id, messages[2].Id}) {
+		t.Fatalf("message leased by another dispatcher should be skipped, found %d processed %v removed", processed, result.removed)
+	}
+}
@@ -0,0 +1,17 @@
+package outbox
+
+type DispatchErrorCode int
+
+const (
+	_ DispatchErrorCode = iota
+	DispatchErrorInternal
+)
+
+func (c DispatchErrorCode) Message() string {
+	switch c {
+	case DispatchErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"encoding/json"
+
+	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
+	outboxRepository "github.com/rzmn/governi/internal/repositories/outbox"
+	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
+)
+
+type UserId string
+type Kind string
+
+const (
+	KindNewExpenseReceived    Kind = "push.newExpenseReceived"
+	KindExpenseAdded          Kind = "realtime.expenseAdded"
+	KindExpenseRemoved        Kind = "realtime.expenseRemoved"
+	KindBalanceChanged        Kind = "realtime.balanceChanged"
+	KindFriendRequestReceived Kind = "realtime.friendRequestReceived"
+	KindFriendStatusChanged   Kind = "realtime.friendStatusChanged"
+	KindProfileChanged        Kind = "realtime.profileChanged"
+)
+
+type ExpenseNotification struct {
+	Receiver UserId                                  `json:"receiver"`
+	Author   UserId                                  `json:"author"`
+	Expense  spendingsRepository.IdentifiableExpense `json:"expense"`
+}
+
+// BalanceNotification carries no balance, it is read when the notification is sent
+// so that the receiver gets the latest one.
+type BalanceNotification struct {
+	Receiver UserId `json:"receiver"`
+}
+
+type FriendRequestNotification struct {
+	Receiver UserId `json:"receiver"`
+	Sender   UserId `json:"sender"`
+}
+
+type FriendStatusNotification struct {
+	Receiver UserId                         `json:"receiver"`
+	UserId   UserId                         `json:"userId"`
+	Status   friendsRepository.FriendStatus `json:"status"`
+}
+
+type ProfileNotification struct {
+	Receiver    UserId  `json:"receiver"`
+	DisplayName *string `json:"displayName,omitempty"`
+	AvatarId    *string `json:"avatarId,omitempty"`
+}
+
+func NewMessage(kind Kind, payload any) (outboxRepository.Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return outboxRepository.Message{}, err
+	}
+	return outboxRepository.Message{
+		Kind:    string(kind),
+		Payload: data,
+	}, nil
+}
+
+// ExpenseMessages returns messages notifying every counterparty of expense except author
+// that expense has been added or removed.
+func ExpenseMessages(kind Kind, expense spendingsRepository.IdentifiableExpense, author UserId) ([]outboxRepository.Message, error) {
+	messages := []outboxRepository.Message{}
+	for _, share := range expense.Shares {
+		receiver := UserId(share.Counterparty)
+		if receiver == author {
+			continue
+		}
+		notification := ExpenseNotification{
+			Receiver: receiver,
+			Author:   author,
+			Expense:  expense,
+		}
+		for _, item := range []struct {
+			kind    Kind
+			payload any
+		}{
+			{kind: KindNewExpenseReceived, payload: notification},
+			{kind: kind, payload: notification},
+			{kind: KindBalanceChanged, payload: BalanceNotification{Receiver: receiver}},
+		} {
+			message, err := NewMessage(item.kind, item.payload)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
@@ -1,11 +1,14 @@
 package profile
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 )
 
 type UserId string
 type AvatarId string
+type Language string
 
 type ProfileInfo struct {
 	Id            UserId
@@ -16,7 +19,9 @@ type ProfileInfo struct {
 }
 
 type Controller interface {
-	GetProfileInfo(id UserId) (ProfileInfo, *common.CodeBasedError[GetInfoErrorCode])
-	UpdateDisplayName(name string, id UserId) *common.CodeBasedError[UpdateDisplayNameErrorCode]
-	UpdateAvatar(base64 string, id UserId) (AvatarId, *common.CodeBasedError[UpdateAvatarErrorCode])
+	GetProfileInfo(ctx context.Context, id UserId) (ProfileInfo, *common.CodeBasedError[GetInfoErrorCode])
+	UpdateDisplayName(ctx context.Context, name string, id UserId) *common.CodeBasedError[UpdateDisplayNameErrorCode]
+	UpdateAvatar(ctx context.Context, base64 string, id UserId) (AvatarId, *common.CodeBasedError[UpdateAvatarErrorCode])
+	UpdateLanguage(ctx context.Context, language Language, id UserId) *common.CodeBasedError[UpdateLanguageErrorCode]
+	DeleteAccount(ctx context.Context, password string, id UserId) *common.CodeBasedError[DeleteAccountErrorCode]
 }
@@ -1,32 +1,67 @@
 package defaultController
 
 import (
+	"context"
 	"errors"
 
 	"github.com/rzmn/governi/internal/common"
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
 	"github.com/rzmn/governi/internal/controllers/profile"
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/auth"
+	"github.com/rzmn/governi/internal/repositories/dataExports"
+	"github.com/rzmn/governi/internal/repositories/friends"
+	"github.com/rzmn/governi/internal/repositories/identities"
+	"github.com/rzmn/governi/internal/repositories/images"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+	"github.com/rzmn/governi/internal/repositories/pushNotifications"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
 	"github.com/rzmn/governi/internal/repositories/users"
 	"github.com/rzmn/governi/internal/services/formatValidation"
+	"github.com/rzmn/governi/internal/services/localization"
 	"github.com/rzmn/governi/internal/services/logging"
 
 	authRepository "github.com/rzmn/governi/internal/repositories/auth"
+	dataExportsRepository "github.com/rzmn/governi/internal/repositories/dataExports"
 	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
+	identitiesRepository "github.com/rzmn/governi/internal/repositories/identities"
 	imagesRepository "github.com/rzmn/governi/internal/repositories/images"
+	loginAttemptsRepository "github.com/rzmn/governi/internal/repositories/loginAttempts"
+	outboxRepository "github.com/rzmn/governi/internal/repositories/outbox"
+	pushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
+	twoFactorRepository "github.com/rzmn/governi/internal/repositories/twoFactor"
 	usersRepository "github.com/rzmn/governi/internal/repositories/users"
+	verificationRepository "github.com/rzmn/governi/internal/repositories/verification"
 )
 
 type AuthRepository authRepository.Repository
 type ImagesRepository imagesRepository.Repository
 type UsersRepository usersRepository.Repository
 type FriendsRepository friendsRepository.Repository
+type PushNotificationsRepository pushNotificationsRepository.Repository
+type IdentitiesRepository identitiesRepository.Repository
+type TwoFactorRepository twoFactorRepository.Repository
+type VerificationRepository verificationRepository.Repository
+type DataExportsRepository dataExportsRepository.Repository
+type LoginAttemptsRepository loginAttemptsRepository.Repository
+type OutboxRepository outboxRepository.Repository
 
 func New(
 	auth AuthRepository,
 	images ImagesRepository,
 	users UsersRepository,
 	friends FriendsRepository,
+	pushTokens PushNotificationsRepository,
+	identities IdentitiesRepository,
+	twoFactor TwoFactorRepository,
+	verification VerificationRepository,
+	dataExports DataExportsRepository,
+	loginAttempts LoginAttemptsRepository,
+	outbox OutboxRepository,
+	unitOfWork db.UnitOfWork,
 	formatValidation formatValidation.Service,
+	localization localization.Service,
 	logger logging.Service,
 ) profile.Controller {
 	return &defaultController{
@@ -34,7 +69,16 @@ func New(
 		images:           images,
 		users:            users,
 		friends:          friends,
+		pushTokens:       pushTokens,
+		identities:       identities,
+		twoFactor:        twoFactor,
+		verification:     verification,
+		dataExports:      dataExports,
+		loginAttempts:    loginAttempts,
+		outbox:           outbox,
+		unitOfWork:       unitOfWork,
 		formatValidation: formatValidation,
+		localization:     localization,
 		logger:           logger,
 	}
 }
@@ -44,15 +88,24 @@ type defaultController struct {
 	images           ImagesRepository
 	users            UsersRepository
 	friends          FriendsRepository
+	pushTokens       PushNotificationsRepository
+	identities       IdentitiesRepository
+	twoFactor        TwoFactorRepository
+	verification     VerificationRepository
+	dataExports      DataExportsRepository
+	loginAttempts    LoginAttemptsRepository
+	outbox           OutboxRepository
+	unitOfWork       db.UnitOfWork
 	formatValidation formatValidation.Service
+	localization     localization.Service
 	logger           logging.Service
 }
 
-func (c *defaultController) GetProfileInfo(id profile.UserId) (profile.ProfileInfo, *common.CodeBasedError[profile.GetInfoErrorCode]) {
+func (c *defaultController) GetProfileInfo(ctx context.Context, id profile.UserId) (profile.ProfileInfo, *common.CodeBasedError[profile.GetInfoErrorCode]) {
 	const op = "profile.defaultController.GetProfileInfo"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 
-	users, err := c.users.GetUsers([]users.UserId{users.UserId(id)})
+	users, err := c.users.GetUsers(ctx, []users.UserId{users.UserId(id)})
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get user info err: %v", op, err)
 		return profile.ProfileInfo{}, common.NewErrorWithDescription(profile.GetInfoErrorInternal, err.Error())
@@ -62,7 +115,7 @@ func (c *defaultController) GetProfileInfo(id profile.UserId) (profile.ProfileIn
 		c.logger.LogInfo("%s: cannot get user info err: %v", op, err)
 		return profile.ProfileInfo{}, common.NewErrorWithDescription(profile.GetInfoErrorInternal, err.Error())
 	}
-	credentials, err := c.auth.GetUserInfo(auth.UserId(id))
+	credentials, err := c.auth.GetUserInfo(ctx, auth.UserId(id))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get user credentials err: %v", op, err)
 		return profile.ProfileInfo{}, common.NewErrorWithDescription(profile.GetInfoErrorInternal, err.Error())
@@ -78,15 +131,27 @@ func (c *defaultController) GetProfileInfo(id profile.UserId) (profile.ProfileIn
 	}, nil
 }
 
-func (c *defaultController) UpdateDisplayName(name string, id profile.UserId) *common.CodeBasedError[profile.UpdateDisplayNameErrorCode] {
+func (c *defaultController) UpdateDisplayName(ctx context.Context, name string, id profile.UserId) *common.CodeBasedError[profile.UpdateDisplayNameErrorCode] {
 	const op = "profile.defaultController.UpdateDisplayName"
 	c.logger.LogInfo("%s: start[id=%s name=%s]", op, id, name)
 	if err := c.formatValidation.ValidateDisplayNameFormat(name); err != nil {
 		c.logger.LogInfo("%s: invalid display name format err: %v", op, err)
 		return common.NewError(profile.UpdateDisplayNameErrorWrongFormat)
 	}
-	transaction := c.users.UpdateDisplayName(name, users.UserId(id))
-	if err := transaction.Perform(); err != nil {
+	message, err := outboxController.NewMessage(outboxController.KindProfileChanged, outboxController.ProfileNotification{
+		Receiver:    outboxController.UserId(id),
+		DisplayName: &name,
+	})
+	if err != nil {
+		c.logger.LogInfo("%s: cannot create profile changed message err: %v", op, err)
+		return common.NewError(profile.UpdateDisplayNameErrorInternal)
+	}
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		return repositories.PerformAll(
+			c.users.WithTx(tx).UpdateDisplayName(ctx, name, users.UserId(id)),
+			c.outbox.WithTx(tx).AddMessages(ctx, []outboxRepository.Message{message}),
+		)
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot write to db err: %v", op, err)
 		return common.NewError(profile.UpdateDisplayNameErrorInternal)
 	}
@@ -94,21 +159,103 @@ func (c *defaultController) UpdateDisplayName(name string, id profile.UserId) *c
 	return nil
 }
 
-func (c *defaultController) UpdateAvatar(base64 string, id profile.UserId) (profile.AvatarId, *common.CodeBasedError[profile.UpdateAvatarErrorCode]) {
+func (c *defaultController) UpdateAvatar(ctx context.Context, base64 string, id profile.UserId) (profile.AvatarId, *common.CodeBasedError[profile.UpdateAvatarErrorCode]) {
 	const op = "profile.defaultController.UpdateAvatar"
 	c.logger.LogInfo("%s: start[id=%s, base64 len=%d]", op, id, len(base64))
-	uploadImageTransaction := c.images.UploadImageBase64(base64)
-	aid, err := uploadImageTransaction.Perform()
-	if err != nil {
-		c.logger.LogInfo("%s: cannot write to db err: %v", op, err)
-		return profile.AvatarId(aid), common.NewError(profile.UpdateAvatarErrorInternal)
-	}
-	updateAvatarTransaction := c.users.UpdateAvatarId((*users.AvatarId)(&aid), users.UserId(id))
-	if err := updateAvatarTransaction.Perform(); err != nil {
-		uploadImageTransaction.Rollback()
+	var aid images.ImageId
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		var err error
+		aid, err = c.images.WithTx(tx).UploadImageBase64(ctx, base64).Perform()
+		if err != nil {
+			return err
+		}
+		avatarId := string(aid)
+		message, err := outboxController.NewMessage(outboxController.KindProfileChanged, outboxController.ProfileNotification{
+			Receiver: outboxController.UserId(id),
+			AvatarId: &avatarId,
+		})
+		if err != nil {
+			return err
+		}
+		return repositories.PerformAll(
+			c.users.WithTx(tx).UpdateAvatarId(ctx, (*users.AvatarId)(&aid), users.UserId(id)),
+			c.outbox.WithTx(tx).AddMessages(ctx, []outboxRepository.Message{message}),
+		)
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot write to db err: %v", op, err)
 		return profile.AvatarId(aid), common.NewError(profile.UpdateAvatarErrorInternal)
 	}
 	c.logger.LogInfo("%s: success[id=%s, base64 len=%d]", op, id, len(base64))
 	return profile.AvatarId(aid), nil
 }
+
+func (c *defaultController) UpdateLanguage(ctx context.Context, language profile.Language, id profile.UserId) *common.CodeBasedError[profile.UpdateLanguageErrorCode] {
+	const op = "profile.defaultController.UpdateLanguage"
+	c.logger.LogInfo("%s: start[id=%s language=%s]", op, id, language)
+	if !c.localization.Supports(localization.Language(language)) {
+		c.logger.LogInfo("%s: language %s is not supported", op, language)
+		return common.NewError(profile.UpdateLanguageErrorUnsupportedLanguage)
+	}
+	if err := c.users.UpdateLanguage(ctx, users.Language(language), users.UserId(id)).Perform(); err != nil {
+		c.logger.LogInfo("%s: cannot write to db err: %v", op, err)
+		return common.NewErrorWithDescription(profile.UpdateLanguageErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success[id=%s language=%s]", op, id, language)
+	return nil
+}
+
+func (c *defaultController) DeleteAccount(ctx context.Context, password string, id profile.UserId) *common.CodeBasedError[profile.DeleteAccountErrorCode] {
+	const op = "profile.defaultController.DeleteAccount"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	credentials, err := c.auth.GetUserInfo(ctx, auth.UserId(id))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get user credentials err: %v", op, err)
+		return common.NewErrorWithDescription(profile.DeleteAccountErrorInternal, err.Error())
+	}
+	passed, err := c.auth.CheckCredentials(ctx, credentials.Email, password)
+	if err != nil {
+		c.logger.LogInfo("%s: cannot check password err: %v", op, err)
+		return common.NewErrorWithDescription(profile.DeleteAccountErrorInternal, err.Error())
+	}
+	if !passed {
+		c.logger.LogInfo("%s: password is wrong", op)
+		return common.NewError(profile.DeleteAccountErrorWrongPassword)
+	}
+	usersFromDb, err := c.users.GetUsers(ctx, []users.UserId{users.UserId(id)})
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get user info err: %v", op, err)
+		return common.NewErrorWithDescription(profile.DeleteAccountErrorInternal, err.Error())
+	}
+	if len(usersFromDb) == 0 {
+		err := errors.New("no such user exists")
+		c.logger.LogInfo("%s: cannot get user info err: %v", op, err)
+		return common.NewErrorWithDescription(profile.DeleteAccountErrorInternal, err.Error())
+	}
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		twoFactorSettings := c.twoFactor.WithTx(tx)
+		transactions := []repositories.MutationWorkItem{
+			c.friends.WithTx(tx).RemoveAllFriendRequests(ctx, friends.UserId(id)),
+			c.pushTokens.WithTx(tx).RemovePushTokens(ctx, pushNotifications.UserId(id)),
+			c.identities.WithTx(tx).RemoveIdentities(ctx, identities.UserId(id)),
+			twoFactorSettings.RemoveSecret(ctx, twoFactor.UserId(id)),
+			twoFactorSettings.RemoveRecoveryCodes(ctx, twoFactor.UserId(id)),
+			c.verification.WithTx(tx).RemoveEmailVerificationCode(ctx, credentials.Email),
+			c.dataExports.WithTx(tx).RemoveExports(ctx, dataExports.UserId(id)),
+			c.loginAttempts.WithTx(tx).RemoveFailedLogins(ctx, loginAttempts.UserId(id), credentials.Email),
+		}
+		if usersFromDb[0].AvatarId != nil {
+			transactions = append(transactions, c.images.WithTx(tx).RemoveImage(ctx, images.ImageId(*usersFromDb[0].AvatarId)))
+		}
+		transactions = append(
+			transactions,
+			c.users.WithTx(tx).AnonymizeUser(ctx, users.UserId(id)),
+			c.auth.WithTx(tx).DeleteUser(ctx, auth.UserId(id)),
+		)
+		return repositories.PerformAll(transactions...)
+	}); err != nil {
+		c.logger.LogInfo("%s: cannot write to db err: %v", op, err)
+		return common.NewErrorWithDescription(profile.DeleteAccountErrorInternal, err.Error())
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
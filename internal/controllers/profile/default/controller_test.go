@@ -1,21 +1,61 @@
 package defaultController_test
 
 import (
+	"context"
 	"errors"
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	db_mock "github.com/rzmn/governi/internal/db/mock"
+	"reflect"
 	"testing"
+	"time"
+
+	defaultAuthController "github.com/rzmn/governi/internal/controllers/auth/default"
 
 	"github.com/rzmn/governi/internal/controllers/profile"
 	defaultController "github.com/rzmn/governi/internal/controllers/profile/default"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/auth"
+	authMemoryRepository "github.com/rzmn/governi/internal/repositories/auth/memory"
 	auth_mock "github.com/rzmn/governi/internal/repositories/auth/mock"
+	"github.com/rzmn/governi/internal/repositories/dataExports"
+	dataExportsMemoryRepository "github.com/rzmn/governi/internal/repositories/dataExports/memory"
+	dataExports_mock "github.com/rzmn/governi/internal/repositories/dataExports/mock"
+	"github.com/rzmn/governi/internal/repositories/friends"
+	friendsMemoryRepository "github.com/rzmn/governi/internal/repositories/friends/memory"
 	friends_mock "github.com/rzmn/governi/internal/repositories/friends/mock"
+	"github.com/rzmn/governi/internal/repositories/identities"
+	identitiesMemoryRepository "github.com/rzmn/governi/internal/repositories/identities/memory"
+	identities_mock "github.com/rzmn/governi/internal/repositories/identities/mock"
 	"github.com/rzmn/governi/internal/repositories/images"
+	imagesMemoryRepository "github.com/rzmn/governi/internal/repositories/images/memory"
 	images_mock "github.com/rzmn/governi/internal/repositories/images/mock"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+	loginAttemptsMemoryRepository "github.com/rzmn/governi/internal/repositories/loginAttempts/memory"
+	loginAttempts_mock "github.com/rzmn/governi/internal/repositories/loginAttempts/mock"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	outboxMemoryRepository "github.com/rzmn/governi/internal/repositories/outbox/memory"
+	outbox_mock "github.com/rzmn/governi/internal/repositories/outbox/mock"
+	"github.com/rzmn/governi/internal/repositories/pushNotifications"
+	pushNotificationsMemoryRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/memory"
+	pushNotifications_mock "github.com/rzmn/governi/internal/repositories/pushNotifications/mock"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
+	twoFactorMemoryRepository "github.com/rzmn/governi/internal/repositories/twoFactor/memory"
+	twoFactor_mock "github.com/rzmn/governi/internal/repositories/twoFactor/mock"
 	"github.com/rzmn/governi/internal/repositories/users"
+	usersMemoryRepository "github.com/rzmn/governi/internal/repositories/users/memory"
 	users_mock "github.com/rzmn/governi/internal/repositories/users/mock"
+	verificationMemoryRepository "github.com/rzmn/governi/internal/repositories/verification/memory"
+	verification_mock "github.com/rzmn/governi/internal/repositories/verification/mock"
 	formatValidation_mock "github.com/rzmn/governi/internal/services/formatValidation/mock"
+	"github.com/rzmn/governi/internal/services/jwt"
+	jwt_mock "github.com/rzmn/governi/internal/services/jwt/mock"
+	"github.com/rzmn/governi/internal/services/localization"
+	localization_mock "github.com/rzmn/governi/internal/services/localization/mock"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/oidc"
+	oidc_mock "github.com/rzmn/governi/internal/services/oidc/mock"
+	totp_mock "github.com/rzmn/governi/internal/services/totp/mock"
 
 	"github.com/google/uuid"
 )
@@ -24,15 +64,16 @@ func TestGetInfoGetUsersFailed(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []users.UserId) ([]users.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []users.UserId) ([]users.User, error) {
 			return []users.User{}, errors.New("some error")
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
 
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.GetProfileInfo(profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.GetProfileInfo(context.Background(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetProfileInfo` should be failed, found no err")
 	}
@@ -45,15 +86,16 @@ func TestGetInfoNoUsersFound(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []users.UserId) ([]users.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []users.UserId) ([]users.User, error) {
 			return []users.User{}, nil
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
 
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.GetProfileInfo(profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.GetProfileInfo(context.Background(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetProfileInfo` should be failed, found no err")
 	}
@@ -64,21 +106,22 @@ func TestGetInfoNoUsersFound(t *testing.T) {
 
 func TestGetInfoGetCredentialsFailed(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, errors.New("some error")
 		},
 	}
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []users.UserId) ([]users.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []users.UserId) ([]users.User, error) {
 			return []users.User{{}}, nil
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
 
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.GetProfileInfo(profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.GetProfileInfo(context.Background(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetProfileInfo` should be failed, found no err")
 	}
@@ -89,21 +132,22 @@ func TestGetInfoGetCredentialsFailed(t *testing.T) {
 
 func TestGetInfoOk(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []users.UserId) ([]users.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []users.UserId) ([]users.User, error) {
 			return []users.User{{}}, nil
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
 
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.GetProfileInfo(profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.GetProfileInfo(context.Background(), profile.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`GetProfileInfo` should not be failed, found err %v", err)
 	}
@@ -114,13 +158,14 @@ func TestUpdateDisplayNameWrongFormat(t *testing.T) {
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{
 		ValidateDisplayNameFormatImpl: func(name string) error {
 			return errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	err := controller.UpdateDisplayName(uuid.New().String(), profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	err := controller.UpdateDisplayName(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`UpdateDisplayName` should be failed, found no err")
 	}
@@ -133,7 +178,7 @@ func TestUpdateDisplayNameUpdateFailed(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{
-		UpdateDisplayNameImpl: func(name string, id users.UserId) repositories.MutationWorkItem {
+		UpdateDisplayNameImpl: func(ctx context.Context, name string, id users.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -142,13 +187,14 @@ func TestUpdateDisplayNameUpdateFailed(t *testing.T) {
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{
 		ValidateDisplayNameFormatImpl: func(name string) error {
 			return nil
 		},
 	}
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	err := controller.UpdateDisplayName(uuid.New().String(), profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	err := controller.UpdateDisplayName(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`UpdateDisplayName` should be failed, found no err")
 	}
@@ -162,7 +208,7 @@ func TestUpdateDisplayNameOk(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{}
 	usersRepository := users_mock.RepositoryMock{
-		UpdateDisplayNameImpl: func(name string, id users.UserId) repositories.MutationWorkItem {
+		UpdateDisplayNameImpl: func(ctx context.Context, name string, id users.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeCalls += 1
@@ -172,13 +218,14 @@ func TestUpdateDisplayNameOk(t *testing.T) {
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{
 		ValidateDisplayNameFormatImpl: func(name string) error {
 			return nil
 		},
 	}
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	err := controller.UpdateDisplayName(uuid.New().String(), profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	err := controller.UpdateDisplayName(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`UpdateDisplayName` should not be failed, found err %v", err)
 	}
@@ -190,7 +237,7 @@ func TestUpdateDisplayNameOk(t *testing.T) {
 func TestUpdateAvatarFailedToUploadData(t *testing.T) {
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{
-		UploadImageBase64Impl: func(base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
+		UploadImageBase64Impl: func(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
 			return repositories.MutationWorkItemWithReturnValue[images.ImageId]{
 				Perform: func() (images.ImageId, error) {
 					return images.ImageId(uuid.New().String()), errors.New("some error")
@@ -200,9 +247,10 @@ func TestUpdateAvatarFailedToUploadData(t *testing.T) {
 	}
 	usersRepository := users_mock.RepositoryMock{}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.UpdateAvatar(uuid.New().String(), profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.UpdateAvatar(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`UpdateAvatar` should be failed, found no err")
 	}
@@ -217,7 +265,7 @@ func TestUpdateAvatarFailedToStoreAvatar(t *testing.T) {
 	rollbackUploadCalls := 0
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{
-		UploadImageBase64Impl: func(base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
+		UploadImageBase64Impl: func(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
 			return repositories.MutationWorkItemWithReturnValue[images.ImageId]{
 				Perform: func() (images.ImageId, error) {
 					uploadCalls += 1
@@ -231,7 +279,7 @@ func TestUpdateAvatarFailedToStoreAvatar(t *testing.T) {
 		},
 	}
 	usersRepository := users_mock.RepositoryMock{
-		UpdateAvatarIdImpl: func(avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
+		UpdateAvatarIdImpl: func(ctx context.Context, avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -240,17 +288,31 @@ func TestUpdateAvatarFailedToStoreAvatar(t *testing.T) {
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.UpdateAvatar(uuid.New().String(), profile.UserId(uuid.New().String()))
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), &unitOfWork, &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.UpdateAvatar(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`UpdateAvatar` should be failed, found no err")
 	}
 	if err.Code != profile.UpdateAvatarErrorInternal {
 		t.Fatalf("`UpdateAvatar` should fail with `internal`, found %v", err)
 	}
-	if uploadCalls != 1 || rollbackUploadCalls != 1 {
-		t.Fatalf("update should be called once and then rolled back once, found %d %d", uploadCalls, rollbackUploadCalls)
+	if uploadCalls != 1 || rollbackUploadCalls != 0 {
+		t.Fatalf("upload should be called once without manual rollback, found %d %d", uploadCalls, rollbackUploadCalls)
+	}
+	if abortedUnitsOfWork != 1 {
+		t.Fatalf("unit of work should be aborted once, found %d", abortedUnitsOfWork)
 	}
 }
 
@@ -260,7 +322,7 @@ func TestUpdateAvatarOk(t *testing.T) {
 	updateCalls := 0
 	authRepository := auth_mock.RepositoryMock{}
 	imagesRepository := images_mock.RepositoryMock{
-		UploadImageBase64Impl: func(base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
+		UploadImageBase64Impl: func(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
 			return repositories.MutationWorkItemWithReturnValue[images.ImageId]{
 				Perform: func() (images.ImageId, error) {
 					uploadCalls += 1
@@ -270,7 +332,7 @@ func TestUpdateAvatarOk(t *testing.T) {
 		},
 	}
 	usersRepository := users_mock.RepositoryMock{
-		UpdateAvatarIdImpl: func(avatarIdToUpdate *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
+		UpdateAvatarIdImpl: func(ctx context.Context, avatarIdToUpdate *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					updateCalls += 1
@@ -283,9 +345,10 @@ func TestUpdateAvatarOk(t *testing.T) {
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{}
 	formatValidation := formatValidation_mock.ServiceMock{}
-	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &formatValidation, standartOutputLoggingService.New())
-	_, err := controller.UpdateAvatar(uuid.New().String(), profile.UserId(uuid.New().String()))
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	_, err := controller.UpdateAvatar(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`UpdateAvatar` should not be failed, found err %v", err)
 	}
@@ -293,3 +356,365 @@ func TestUpdateAvatarOk(t *testing.T) {
 		t.Fatalf("update should be called once and then run update user data, found %d %d", uploadCalls, updateCalls)
 	}
 }
+
+func deleteAccountMocks(passwordIsCorrect bool, failingStep string, performed *[]string, rolledBack *[]string) (
+	auth_mock.RepositoryMock,
+	images_mock.RepositoryMock,
+	users_mock.RepositoryMock,
+	friends_mock.RepositoryMock,
+	pushNotifications_mock.RepositoryMock,
+	identities_mock.RepositoryMock,
+	twoFactor_mock.RepositoryMock,
+	verification_mock.RepositoryMock,
+	dataExports_mock.RepositoryMock,
+	loginAttempts_mock.RepositoryMock,
+) {
+	transaction := func(step string) repositories.MutationWorkItem {
+		return repositories.MutationWorkItem{
+			Perform: func() error {
+				if step == failingStep {
+					return errors.New("some error")
+				}
+				*performed = append(*performed, step)
+				return nil
+			},
+			Rollback: func() error {
+				*rolledBack = append(*rolledBack, step)
+				return nil
+			},
+		}
+	}
+	avatarId := users.AvatarId(uuid.New().String())
+	authRepository := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
+			return auth.UserInfo{
+				UserId: uid,
+				Email:  "a@b.com",
+			}, nil
+		},
+		CheckCredentialsImpl: func(ctx context.Context, email string, password string) (bool, error) {
+			return passwordIsCorrect, nil
+		},
+		DeleteUserImpl: func(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
+			return transaction("credentials")
+		},
+	}
+	imagesRepository := images_mock.RepositoryMock{
+		RemoveImageImpl: func(ctx context.Context, id images.ImageId) repositories.MutationWorkItem {
+			if id != images.ImageId(avatarId) {
+				panic("should remove user avatar")
+			}
+			return transaction("avatar")
+		},
+	}
+	usersRepository := users_mock.RepositoryMock{
+		GetUsersImpl: func(ctx context.Context, ids []users.UserId) ([]users.User, error) {
+			return []users.User{
+				{
+					Id:          ids[0],
+					DisplayName: uuid.New().String(),
+					AvatarId:    &avatarId,
+				},
+			}, nil
+		},
+		AnonymizeUserImpl: func(ctx context.Context, id users.UserId) repositories.MutationWorkItem {
+			return transaction("user")
+		},
+	}
+	friendsRepository := friends_mock.RepositoryMock{
+		RemoveAllFriendRequestsImpl: func(ctx context.Context, userId friends.UserId) repositories.MutationWorkItem {
+			return transaction("friendRequests")
+		},
+	}
+	pushTokensRepository := pushNotifications_mock.RepositoryMock{
+		RemovePushTokensImpl: func(ctx context.Context, uid pushNotifications.UserId) repositories.MutationWorkItem {
+			return transaction("pushToken")
+		},
+	}
+	identitiesRepository := identities_mock.RepositoryMock{
+		RemoveIdentitiesImpl: func(ctx context.Context, uid identities.UserId) repositories.MutationWorkItem {
+			return transaction("identities")
+		},
+	}
+	twoFactorRepository := twoFactor_mock.RepositoryMock{
+		RemoveSecretImpl: func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+			return transaction("twoFactorSecret")
+		},
+		RemoveRecoveryCodesImpl: func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+			return transaction("recoveryCodes")
+		},
+	}
+	verificationRepository := verification_mock.RepositoryMock{
+		RemoveEmailVerificationCodeImpl: func(ctx context.Context, email string) repositories.MutationWorkItem {
+			if email != "a@b.com" {
+				panic("should remove verification code of the user email")
+			}
+			return transaction("verificationCode")
+		},
+	}
+	dataExportsRepository := dataExports_mock.RepositoryMock{
+		RemoveExportsImpl: func(ctx context.Context, uid dataExports.UserId) repositories.MutationWorkItem {
+			return transaction("dataExports")
+		},
+	}
+	loginAttemptsRepository := loginAttempts_mock.RepositoryMock{
+		RemoveFailedLoginsImpl: func(ctx context.Context, uid loginAttempts.UserId, email string) repositories.MutationWorkItem {
+			if email != "a@b.com" {
+				panic("should remove failed logins with the user email")
+			}
+			return transaction("failedLogins")
+		},
+	}
+	return authRepository, imagesRepository, usersRepository, friendsRepository, pushTokensRepository, identitiesRepository, twoFactorRepository, verificationRepository, dataExportsRepository, loginAttemptsRepository
+}
+
+func localizationMock(supported bool) *localization_mock.ServiceMock {
+	return &localization_mock.ServiceMock{
+		SupportsImpl: func(language localization.Language) bool {
+			return supported
+		},
+	}
+}
+
+func TestUpdateLanguageUnsupported(t *testing.T) {
+	usersRepository := users_mock.RepositoryMock{}
+	formatValidation := formatValidation_mock.ServiceMock{}
+	controller := defaultController.New(&auth_mock.RepositoryMock{}, &images_mock.RepositoryMock{}, &usersRepository, &friends_mock.RepositoryMock{}, &pushNotifications_mock.RepositoryMock{}, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, localizationMock(false), standartOutputLoggingService.New())
+	err := controller.UpdateLanguage(context.Background(), profile.Language("xx"), profile.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("`UpdateLanguage` should be failed, found nil err")
+	}
+	if err.Code != profile.UpdateLanguageErrorUnsupportedLanguage {
+		t.Fatalf("`UpdateLanguage` should be failed with `unsupported language`, found %v", err)
+	}
+}
+
+func TestUpdateLanguageUpdateFailed(t *testing.T) {
+	usersRepository := users_mock.RepositoryMock{
+		UpdateLanguageImpl: func(ctx context.Context, language users.Language, id users.UserId) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	formatValidation := formatValidation_mock.ServiceMock{}
+	controller := defaultController.New(&auth_mock.RepositoryMock{}, &images_mock.RepositoryMock{}, &usersRepository, &friends_mock.RepositoryMock{}, &pushNotifications_mock.RepositoryMock{}, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, localizationMock(true), standartOutputLoggingService.New())
+	err := controller.UpdateLanguage(context.Background(), profile.Language("ru"), profile.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("`UpdateLanguage` should be failed, found nil err")
+	}
+	if err.Code != profile.UpdateLanguageErrorInternal {
+		t.Fatalf("`UpdateLanguage` should be failed with `internal`, found %v", err)
+	}
+}
+
+func TestUpdateLanguageOk(t *testing.T) {
+	var stored users.Language
+	usersRepository := users_mock.RepositoryMock{
+		UpdateLanguageImpl: func(ctx context.Context, language users.Language, id users.UserId) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					stored = language
+					return nil
+				},
+			}
+		},
+	}
+	formatValidation := formatValidation_mock.ServiceMock{}
+	controller := defaultController.New(&auth_mock.RepositoryMock{}, &images_mock.RepositoryMock{}, &usersRepository, &friends_mock.RepositoryMock{}, &pushNotifications_mock.RepositoryMock{}, &identities_mock.RepositoryMock{}, &twoFactor_mock.RepositoryMock{}, &verification_mock.RepositoryMock{}, &dataExports_mock.RepositoryMock{}, &loginAttempts_mock.RepositoryMock{}, outboxMock(nil), unitOfWorkMock(), &formatValidation, localizationMock(true), standartOutputLoggingService.New())
+	if err := controller.UpdateLanguage(context.Background(), profile.Language("ru"), profile.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("`UpdateLanguage` should not be failed, found err %v", err)
+	}
+	if stored != "ru" {
+		t.Fatalf("language should be stored, found %s", stored)
+	}
+}
+
+func TestDeleteAccountWrongPassword(t *testing.T) {
+	performed := []string{}
+	rolledBack := []string{}
+	authRepository, imagesRepository, usersRepository, friendsRepository, pushTokensRepository, identitiesRepository, twoFactorRepository, verificationRepository, dataExportsRepository, loginAttemptsRepository := deleteAccountMocks(false, "", &performed, &rolledBack)
+	formatValidation := formatValidation_mock.ServiceMock{}
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identitiesRepository, &twoFactorRepository, &verificationRepository, &dataExportsRepository, &loginAttemptsRepository, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	err := controller.DeleteAccount(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("`DeleteAccount` should be failed, found no err")
+	}
+	if err.Code != profile.DeleteAccountErrorWrongPassword {
+		t.Fatalf("`DeleteAccount` should fail with `wrong password`, found %v", err)
+	}
+	if len(performed) != 0 {
+		t.Fatalf("nothing should be removed, found %v", performed)
+	}
+}
+
+func TestDeleteAccountAbortedOnFailure(t *testing.T) {
+	performed := []string{}
+	rolledBack := []string{}
+	authRepository, imagesRepository, usersRepository, friendsRepository, pushTokensRepository, identitiesRepository, twoFactorRepository, verificationRepository, dataExportsRepository, loginAttemptsRepository := deleteAccountMocks(true, "user", &performed, &rolledBack)
+	formatValidation := formatValidation_mock.ServiceMock{}
+	abortedUnitsOfWork := 0
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			if err != nil {
+				abortedUnitsOfWork += 1
+			}
+			return err
+		},
+	}
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identitiesRepository, &twoFactorRepository, &verificationRepository, &dataExportsRepository, &loginAttemptsRepository, outboxMock(nil), &unitOfWork, &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	err := controller.DeleteAccount(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("`DeleteAccount` should be failed, found no err")
+	}
+	if err.Code != profile.DeleteAccountErrorInternal {
+		t.Fatalf("`DeleteAccount` should fail with `internal`, found %v", err)
+	}
+	expectedPerformed := []string{"friendRequests", "pushToken", "identities", "twoFactorSecret", "recoveryCodes", "verificationCode", "dataExports", "failedLogins", "avatar"}
+	if !reflect.DeepEqual(performed, expectedPerformed) {
+		t.Fatalf("steps should be performed until failure %v, found %v", expectedPerformed, performed)
+	}
+	if len(rolledBack) != 0 || abortedUnitsOfWork != 1 {
+		t.Fatalf("should abort unit of work instead of manual rollback, found %v rollbacks %d aborts", rolledBack, abortedUnitsOfWork)
+	}
+}
+
+func TestDeleteAccountOk(t *testing.T) {
+	performed := []string{}
+	rolledBack := []string{}
+	authRepository, imagesRepository, usersRepository, friendsRepository, pushTokensRepository, identitiesRepository, twoFactorRepository, verificationRepository, dataExportsRepository, loginAttemptsRepository := deleteAccountMocks(true, "", &performed, &rolledBack)
+	formatValidation := formatValidation_mock.ServiceMock{}
+	controller := defaultController.New(&authRepository, &imagesRepository, &usersRepository, &friendsRepository, &pushTokensRepository, &identitiesRepository, &twoFactorRepository, &verificationRepository, &dataExportsRepository, &loginAttemptsRepository, outboxMock(nil), unitOfWorkMock(), &formatValidation, &localization_mock.ServiceMock{}, standartOutputLoggingService.New())
+	err := controller.DeleteAccount(context.Background(), uuid.New().String(), profile.UserId(uuid.New().String()))
+	if err != nil {
+		t.Fatalf("`DeleteAccount` should not be failed, found err %v", err)
+	}
+	expectedSteps := []string{"friendRequests", "pushToken", "identities", "twoFactorSecret", "recoveryCodes", "verificationCode", "dataExports", "failedLogins", "avatar", "user", "credentials"}
+	if !reflect.DeepEqual(performed, expectedSteps) {
+		t.Fatalf("should perform %v, found %v", expectedSteps, performed)
+	}
+	if len(rolledBack) != 0 {
+		t.Fatalf("nothing should be rolled back, found %v", rolledBack)
+	}
+}
+
+func TestDeleteAccountThenLoginWithIdentityProvider(t *testing.T) {
+	logger := standartOutputLoggingService.New()
+	authRepository := authMemoryRepository.New(logger)
+	usersRepository := usersMemoryRepository.New(logger)
+	pushTokensRepository := pushNotificationsMemoryRepository.New(logger)
+	identitiesRepository := identitiesMemoryRepository.New(logger)
+	twoFactorRepository := twoFactorMemoryRepository.New(logger)
+	loginAttemptsRepository := loginAttemptsMemoryRepository.New(logger)
+	unitOfWork := memoryDb.NewUnitOfWork()
+	formatValidation := formatValidation_mock.ServiceMock{
+		ValidateEmailFormatImpl: func(email string) error {
+			return nil
+		},
+		ValidatePasswordFormatImpl: func(password string) error {
+			return nil
+		},
+	}
+	email := uuid.New().String() + "@x.com"
+	jwtService := jwt_mock.ServiceMock{
+		IssueAccessTokenImpl: func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error) {
+			return jwt.AccessToken(uuid.New().String()), nil
+		},
+		IssueRefreshTokenImpl: func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
+			return jwt.RefreshToken(uuid.New().String()), nil
+		},
+	}
+	oidcService := oidc_mock.ServiceMock{
+		VerifyImpl: func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+			return oidc.Identity{
+				Issuer:        "https://accounts.google.com",
+				Subject:       "subject",
+				Email:         &email,
+				EmailVerified: true,
+			}, nil
+		},
+	}
+	authController := defaultAuthController.New(
+		authRepository,
+		pushTokensRepository,
+		usersRepository,
+		twoFactorRepository,
+		identitiesRepository,
+		loginAttemptsRepository,
+		unitOfWork,
+		&jwtService,
+		&totp_mock.ServiceMock{},
+		&oidcService,
+		&formatValidation,
+		logger,
+		time.Now,
+	)
+	controller := defaultController.New(
+		authRepository,
+		imagesMemoryRepository.New(logger),
+		usersRepository,
+		friendsMemoryRepository.New(logger),
+		pushTokensRepository,
+		identitiesRepository,
+		twoFactorRepository,
+		verificationMemoryRepository.New(logger),
+		dataExportsMemoryRepository.New(logger),
+		loginAttemptsRepository,
+		outboxMemoryRepository.New(logger),
+		unitOfWork,
+		&formatValidation,
+		&localization_mock.ServiceMock{},
+		logger,
+	)
+	password := uuid.New().String()
+	session, signupErr := authController.Signup(context.Background(), email, password)
+	if signupErr != nil {
+		t.Fatalf("`Signup` should not be failed, found err %v", signupErr)
+	}
+	if err := authRepository.MarkUserEmailValidated(context.Background(), auth.UserId(session.Id)).Perform(); err != nil {
+		t.Fatalf("`MarkUserEmailValidated` should not be failed, found err %v", err)
+	}
+	linked, loginErr := authController.LoginWithIdentityProvider(context.Background(), "google", uuid.New().String())
+	if loginErr != nil {
+		t.Fatalf("[linked] `LoginWithIdentityProvider` should not be failed, found err %v", loginErr)
+	}
+	if linked.Session == nil || linked.Session.Id != session.Id {
+		t.Fatalf("[linked] identity should be linked to %s, found %v", session.Id, linked.Session)
+	}
+	if err := controller.DeleteAccount(context.Background(), password, profile.UserId(session.Id)); err != nil {
+		t.Fatalf("`DeleteAccount` should not be failed, found err %v", err)
+	}
+	created, loginErr := authController.LoginWithIdentityProvider(context.Background(), "google", uuid.New().String())
+	if loginErr != nil {
+		t.Fatalf("[after deletion] `LoginWithIdentityProvider` should not be failed, found err %v", loginErr)
+	}
+	if created.Session == nil || created.Session.Id == session.Id {
+		t.Fatalf("[after deletion] should sign up a new user, found %v", created.Session)
+	}
+}
+
+func unitOfWorkMock() *db_mock.UnitOfWorkMock {
+	return &db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			return work(nil)
+		},
+	}
+}
+
+func outboxMock(stored *[]outbox.Message) *outbox_mock.RepositoryMock {
+	return &outbox_mock.RepositoryMock{
+		AddMessagesImpl: func(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					if stored != nil {
+						*stored = append(*stored, messages...)
+					}
+					return nil
+				},
+			}
+		},
+	}
+}
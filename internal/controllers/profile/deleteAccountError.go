@@ -0,0 +1,20 @@
+package profile
+
+type DeleteAccountErrorCode int
+
+const (
+	_ DeleteAccountErrorCode = iota
+	DeleteAccountErrorWrongPassword
+	DeleteAccountErrorInternal
+)
+
+func (c DeleteAccountErrorCode) Message() string {
+	switch c {
+	case DeleteAccountErrorWrongPassword:
+		return "wrong password"
+	case DeleteAccountErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -0,0 +1,20 @@
+package profile
+
+type UpdateLanguageErrorCode int
+
+const (
+	_ UpdateLanguageErrorCode = iota
+	UpdateLanguageErrorUnsupportedLanguage
+	UpdateLanguageErrorInternal
+)
+
+func (c UpdateLanguageErrorCode) Message() string {
+	switch c {
+	case UpdateLanguageErrorUnsupportedLanguage:
+		return "unsupported language"
+	case UpdateLanguageErrorInternal:
+		return "internal error"
+	default:
+		return "unknown error"
+	}
+}
@@ -6,6 +6,7 @@ const (
 	_ AddExpenseErrorCode = iota
 	AddExpenseErrorNoSuchUser
 	AddExpenseErrorNotYourExpense
+	AddExpenseErrorUnbalancedShares
 	AddExpenseErrorInternal
 )
 
@@ -15,6 +16,8 @@ func (c AddExpenseErrorCode) Message() string {
 		return "no such user"
 	case AddExpenseErrorNotYourExpense:
 		return "not your expense"
+	case AddExpenseErrorUnbalancedShares:
+		return "shares do not add up to zero"
 	case AddExpenseErrorInternal:
 		return "internal error"
 	default:
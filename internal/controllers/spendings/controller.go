@@ -1,6 +1,8 @@
 package spendings
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
 )
@@ -12,9 +14,9 @@ type IdentifiableExpense spendingsRepository.IdentifiableExpense
 type Balance spendingsRepository.Balance
 
 type Controller interface {
-	AddExpense(expense Expense, actor CounterpartyId) (IdentifiableExpense, *common.CodeBasedError[AddExpenseErrorCode])
-	RemoveExpense(expenseId ExpenseId, actor CounterpartyId) (IdentifiableExpense, *common.CodeBasedError[RemoveExpenseErrorCode])
-	GetExpense(expenseId ExpenseId, actor CounterpartyId) (IdentifiableExpense, *common.CodeBasedError[GetExpenseErrorCode])
-	GetExpensesWith(counterparty CounterpartyId, actor CounterpartyId) ([]IdentifiableExpense, *common.CodeBasedError[GetExpensesErrorCode])
-	GetBalance(actor CounterpartyId) ([]Balance, *common.CodeBasedError[GetBalanceErrorCode])
+	AddExpense(ctx context.Context, expense Expense, actor CounterpartyId) (IdentifiableExpense, *common.CodeBasedError[AddExpenseErrorCode])
+	RemoveExpense(ctx context.Context, expenseId ExpenseId, actor CounterpartyId) (IdentifiableExpense, *common.CodeBasedError[RemoveExpenseErrorCode])
+	GetExpense(ctx context.Context, expenseId ExpenseId, actor CounterpartyId) (IdentifiableExpense, *common.CodeBasedError[GetExpenseErrorCode])
+	GetExpensesWith(ctx context.Context, counterparty CounterpartyId, actor CounterpartyId) ([]IdentifiableExpense, *common.CodeBasedError[GetExpensesErrorCode])
+	GetBalance(ctx context.Context, actor CounterpartyId) ([]Balance, *common.CodeBasedError[GetBalanceErrorCode])
 }
@@ -1,27 +1,37 @@
 package defaultController
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
 	"github.com/rzmn/governi/internal/controllers/spendings"
+	"github.com/rzmn/governi/internal/db"
+	outboxRepository "github.com/rzmn/governi/internal/repositories/outbox"
 	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
 	"github.com/rzmn/governi/internal/services/logging"
 )
 
 type Repository spendingsRepository.Repository
+type OutboxRepository outboxRepository.Repository
 
-func New(repository Repository, logger logging.Service) spendings.Controller {
+func New(repository Repository, outbox OutboxRepository, unitOfWork db.UnitOfWork, logger logging.Service) spendings.Controller {
 	return &defaultController{
 		repository: repository,
+		outbox:     outbox,
+		unitOfWork: unitOfWork,
 		logger:     logger,
 	}
 }
 
 type defaultController struct {
 	repository Repository
+	outbox     OutboxRepository
+	unitOfWork db.UnitOfWork
 	logger     logging.Service
 }
 
-func (c *defaultController) AddExpense(expense spendings.Expense, actor spendings.CounterpartyId) (spendings.IdentifiableExpense, *common.CodeBasedError[spendings.AddExpenseErrorCode]) {
+func (c *defaultController) AddExpense(ctx context.Context, expense spendings.Expense, actor spendings.CounterpartyId) (spendings.IdentifiableExpense, *common.CodeBasedError[spendings.AddExpenseErrorCode]) {
 	const op = "spendings.defaultController.AddExpense"
 	c.logger.LogInfo("%s: start[actor=%s]", op, actor)
 	var isYourExpense bool
@@ -35,9 +45,26 @@ func (c *defaultController) AddExpense(expense spendings.Expense, actor spending
 		c.logger.LogInfo("%s: user %s is not found in expense %v shares", op, actor, expense)
 		return spendings.IdentifiableExpense{}, common.NewError(spendings.AddExpenseErrorNotYourExpense)
 	}
-	transaction := c.repository.AddExpense(spendingsRepository.Expense(expense))
-	expenseId, err := transaction.Perform()
-	if err != nil {
+	if _, err := spendingsRepository.Debts(expense.Shares); err != nil {
+		c.logger.LogInfo("%s: expense %v shares are not balanced", op, expense)
+		return spendings.IdentifiableExpense{}, common.NewErrorWithDescription(spendings.AddExpenseErrorUnbalancedShares, err.Error())
+	}
+	var expenseId spendingsRepository.ExpenseId
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		var err error
+		expenseId, err = c.repository.WithTx(tx).AddExpense(ctx, spendingsRepository.Expense(expense)).Perform()
+		if err != nil {
+			return err
+		}
+		messages, err := outboxController.ExpenseMessages(outboxController.KindExpenseAdded, spendingsRepository.IdentifiableExpense{
+			Id:      expenseId,
+			Expense: spendingsRepository.Expense(expense),
+		}, outboxController.UserId(actor))
+		if err != nil {
+			return err
+		}
+		return c.outbox.WithTx(tx).AddMessages(ctx, messages).Perform()
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot insert expense into db err: %v", op, err)
 		return spendings.IdentifiableExpense{}, common.NewErrorWithDescription(spendings.AddExpenseErrorInternal, err.Error())
 	}
@@ -48,10 +75,10 @@ func (c *defaultController) AddExpense(expense spendings.Expense, actor spending
 	}, nil
 }
 
-func (c *defaultController) RemoveExpense(expenseId spendings.ExpenseId, actor spendings.CounterpartyId) (spendings.IdentifiableExpense, *common.CodeBasedError[spendings.RemoveExpenseErrorCode]) {
+func (c *defaultController) RemoveExpense(ctx context.Context, expenseId spendings.ExpenseId, actor spendings.CounterpartyId) (spendings.IdentifiableExpense, *common.CodeBasedError[spendings.RemoveExpenseErrorCode]) {
 	const op = "spendings.defaultController.RemoveExpense"
 	c.logger.LogInfo("%s: start[expenseId=%s actor=%s]", op, expenseId, actor)
-	expense, err := c.repository.GetExpense(spendingsRepository.ExpenseId(expenseId))
+	expense, err := c.repository.GetExpense(ctx, spendingsRepository.ExpenseId(expenseId))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get expense from db err: %v", op, err)
 		return spendings.IdentifiableExpense{}, common.NewErrorWithDescription(spendings.RemoveExpenseErrorInternal, err.Error())
@@ -71,8 +98,16 @@ func (c *defaultController) RemoveExpense(expenseId spendings.ExpenseId, actor s
 		c.logger.LogInfo("%s: user %s is not found in expense %s shares", op, actor, expenseId)
 		return spendings.IdentifiableExpense{}, common.NewError(spendings.RemoveExpenseErrorNotYourExpense)
 	}
-	transaction := c.repository.RemoveExpense(spendingsRepository.ExpenseId(expenseId))
-	if err := transaction.Perform(); err != nil {
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		if err := c.repository.WithTx(tx).RemoveExpense(ctx, spendingsRepository.ExpenseId(expenseId)).Perform(); err != nil {
+			return err
+		}
+		messages, err := outboxController.ExpenseMessages(outboxController.KindExpenseRemoved, *expense, outboxController.UserId(actor))
+		if err != nil {
+			return err
+		}
+		return c.outbox.WithTx(tx).AddMessages(ctx, messages).Perform()
+	}); err != nil {
 		c.logger.LogInfo("%s: cannot remove expense from db err: %v", op, err)
 		return spendings.IdentifiableExpense{}, common.NewErrorWithDescription(spendings.RemoveExpenseErrorInternal, err.Error())
 	}
@@ -80,10 +115,10 @@ func (c *defaultController) RemoveExpense(expenseId spendings.ExpenseId, actor s
 	return spendings.IdentifiableExpense(*expense), nil
 }
 
-func (c *defaultController) GetExpense(expenseId spendings.ExpenseId, actor spendings.CounterpartyId) (spendings.IdentifiableExpense, *common.CodeBasedError[spendings.GetExpenseErrorCode]) {
+func (c *defaultController) GetExpense(ctx context.Context, expenseId spendings.ExpenseId, actor spendings.CounterpartyId) (spendings.IdentifiableExpense, *common.CodeBasedError[spendings.GetExpenseErrorCode]) {
 	const op = "spendings.defaultController.GetExpense"
 	c.logger.LogInfo("%s: start[expenseId=%s actor=%s]", op, expenseId, actor)
-	expense, err := c.repository.GetExpense(spendingsRepository.ExpenseId(expenseId))
+	expense, err := c.repository.GetExpense(ctx, spendingsRepository.ExpenseId(expenseId))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get expense from db err: %v", op, err)
 		return spendings.IdentifiableExpense{}, common.NewErrorWithDescription(spendings.GetExpenseErrorInternal, err.Error())
@@ -107,10 +142,10 @@ func (c *defaultController) GetExpense(expenseId spendings.ExpenseId, actor spen
 	return spendings.IdentifiableExpense(*expense), nil
 }
 
-func (c *defaultController) GetExpensesWith(counterparty spendings.CounterpartyId, actor spendings.CounterpartyId) ([]spendings.IdentifiableExpense, *common.CodeBasedError[spendings.GetExpensesErrorCode]) {
+func (c *defaultController) GetExpensesWith(ctx context.Context, counterparty spendings.CounterpartyId, actor spendings.CounterpartyId) ([]spendings.IdentifiableExpense, *common.CodeBasedError[spendings.GetExpensesErrorCode]) {
 	const op = "spendings.defaultController.GetExpensesWith"
 	c.logger.LogInfo("%s: start[counterparty=%s actor=%s]", op, counterparty, actor)
-	expenses, err := c.repository.GetExpensesBetween(spendingsRepository.CounterpartyId(counterparty), spendingsRepository.CounterpartyId(actor))
+	expenses, err := c.repository.GetExpensesBetween(ctx, spendingsRepository.CounterpartyId(counterparty), spendingsRepository.CounterpartyId(actor))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get expenses from db err: %v", op, err)
 		return []spendings.IdentifiableExpense{}, common.NewErrorWithDescription(spendings.GetExpensesErrorInternal, err.Error())
@@ -121,10 +156,10 @@ func (c *defaultController) GetExpensesWith(counterparty spendings.CounterpartyI
 	}), nil
 }
 
-func (c *defaultController) GetBalance(actor spendings.CounterpartyId) ([]spendings.Balance, *common.CodeBasedError[spendings.GetBalanceErrorCode]) {
+func (c *defaultController) GetBalance(ctx context.Context, actor spendings.CounterpartyId) ([]spendings.Balance, *common.CodeBasedError[spendings.GetBalanceErrorCode]) {
 	const op = "spendings.defaultController.GetBalance"
 	c.logger.LogInfo("%s: start[actor=%s]", op, actor)
-	balance, err := c.repository.GetBalance(spendingsRepository.CounterpartyId(actor))
+	balance, err := c.repository.GetBalance(ctx, spendingsRepository.CounterpartyId(actor))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get balance for %s from db err: %v", op, actor, err)
 		return []spendings.Balance{}, common.NewErrorWithDescription(spendings.GetBalanceErrorInternal, err.Error())
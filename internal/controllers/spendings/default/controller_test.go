@@ -1,12 +1,21 @@
 package defaultController_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 
+	"github.com/rzmn/governi/internal/common"
+	outboxController "github.com/rzmn/governi/internal/controllers/outbox"
 	"github.com/rzmn/governi/internal/controllers/spendings"
 	defaultController "github.com/rzmn/governi/internal/controllers/spendings/default"
+	"github.com/rzmn/governi/internal/db"
+	db_mock "github.com/rzmn/governi/internal/db/mock"
 	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	outbox_mock "github.com/rzmn/governi/internal/repositories/outbox/mock"
 	spendingsRepository "github.com/rzmn/governi/internal/repositories/spendings"
 	spendings_mock "github.com/rzmn/governi/internal/repositories/spendings/mock"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
@@ -17,7 +26,7 @@ import (
 func TestAddExpenseFailedNotYourExpense(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{}
 
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
 
 	expense := spendings.Expense{
 		Shares: []spendingsRepository.ShareOfExpense{
@@ -29,7 +38,7 @@ func TestAddExpenseFailedNotYourExpense(t *testing.T) {
 			},
 		},
 	}
-	_, err := controller.AddExpense(expense, spendings.CounterpartyId(uuid.New().String()))
+	_, err := controller.AddExpense(context.Background(), expense, spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`AddExpense` should be failed, found nil err")
 	}
@@ -40,7 +49,7 @@ func TestAddExpenseFailedNotYourExpense(t *testing.T) {
 
 func TestAddExpenseFailedToAddInRepository(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		AddExpenseImpl: func(id spendingsRepository.Expense) repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId] {
+		AddExpenseImpl: func(ctx context.Context, id spendingsRepository.Expense) repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId] {
 			return repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId]{
 				Perform: func() (spendingsRepository.ExpenseId, error) {
 					return spendingsRepository.ExpenseId(uuid.New().String()), errors.New("some error")
@@ -49,7 +58,7 @@ func TestAddExpenseFailedToAddInRepository(t *testing.T) {
 		},
 	}
 
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
 	actor := spendings.CounterpartyId(uuid.New().String())
 	counterparty := spendings.CounterpartyId(uuid.New().String())
 
@@ -63,7 +72,7 @@ func TestAddExpenseFailedToAddInRepository(t *testing.T) {
 			},
 		},
 	}
-	_, err := controller.AddExpense(expense, actor)
+	_, err := controller.AddExpense(context.Background(), expense, actor)
 	if err == nil {
 		t.Fatalf("`AddExpense` should be failed, found nil err")
 	}
@@ -72,9 +81,36 @@ func TestAddExpenseFailedToAddInRepository(t *testing.T) {
 	}
 }
 
+func TestAddExpenseUnbalancedShares(t *testing.T) {
+	repository := spendings_mock.RepositoryMock{}
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	actor := spendings.CounterpartyId(uuid.New().String())
+	counterparty := spendings.CounterpartyId(uuid.New().String())
+
+	expense := spendings.Expense{
+		Shares: []spendingsRepository.ShareOfExpense{
+			{
+				Counterparty: spendingsRepository.CounterpartyId(actor),
+				Cost:         100,
+			},
+			{
+				Counterparty: spendingsRepository.CounterpartyId(counterparty),
+				Cost:         -50,
+			},
+		},
+	}
+	_, err := controller.AddExpense(context.Background(), expense, actor)
+	if err == nil {
+		t.Fatalf("`AddExpense` should be failed, found nil err")
+	}
+	if err.Code != spendings.AddExpenseErrorUnbalancedShares {
+		t.Fatalf("`AddExpense` should be failed with `unbalanced shares`, found err %v", err)
+	}
+}
+
 func TestAddExpenseOk(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		AddExpenseImpl: func(id spendingsRepository.Expense) repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId] {
+		AddExpenseImpl: func(ctx context.Context, id spendingsRepository.Expense) repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId] {
 			return repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId]{
 				Perform: func() (spendingsRepository.ExpenseId, error) {
 					return spendingsRepository.ExpenseId(uuid.New().String()), nil
@@ -82,7 +118,7 @@ func TestAddExpenseOk(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
 	actor := spendings.CounterpartyId(uuid.New().String())
 	counterparty := spendings.CounterpartyId(uuid.New().String())
 
@@ -96,20 +132,110 @@ func TestAddExpenseOk(t *testing.T) {
 			},
 		},
 	}
-	_, err := controller.AddExpense(expense, actor)
+	_, err := controller.AddExpense(context.Background(), expense, actor)
 	if err != nil {
 		t.Fatalf("`AddExpense` should not be failed, found err %v", err)
 	}
 }
 
+func TestAddExpenseStoresNotifications(t *testing.T) {
+	expenseId := spendingsRepository.ExpenseId(uuid.New().String())
+	repository := spendings_mock.RepositoryMock{
+		AddExpenseImpl: func(ctx context.Context, id spendingsRepository.Expense) repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId] {
+			return repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId]{
+				Perform: func() (spendingsRepository.ExpenseId, error) {
+					return expenseId, nil
+				},
+			}
+		},
+	}
+	stored := []outbox.Message{}
+	controller := defaultController.New(&repository, outboxMock(&stored), unitOfWorkMock(), standartOutputLoggingService.New())
+	actor := spendings.CounterpartyId(uuid.New().String())
+	counterparty := spendings.CounterpartyId(uuid.New().String())
+
+	expense := spendings.Expense{
+		Shares: []spendingsRepository.ShareOfExpense{
+			{
+				Counterparty: spendingsRepository.CounterpartyId(actor),
+			},
+			{
+				Counterparty: spendingsRepository.CounterpartyId(counterparty),
+			},
+		},
+	}
+	if _, err := controller.AddExpense(context.Background(), expense, actor); err != nil {
+		t.Fatalf("`AddExpense` should not be failed, found err %v", err)
+	}
+	kinds := common.Map(stored, func(message outbox.Message) string {
+		return message.Kind
+	})
+	expected := []string{
+		string(outboxController.KindNewExpenseReceived),
+		string(outboxController.KindExpenseAdded),
+		string(outboxController.KindBalanceChanged),
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("counterparty should be notified with %v, found %v", expected, kinds)
+	}
+	var notification outboxController.ExpenseNotification
+	if err := json.Unmarshal(stored[1].Payload, &notification); err != nil {
+		t.Fatalf("failed to decode notification err: %v", err)
+	}
+	if notification.Receiver != outboxController.UserId(counterparty) || notification.Author != outboxController.UserId(actor) || notification.Expense.Id != expenseId {
+		t.Fatalf("unexpected notification %v", notification)
+	}
+}
+
+func TestAddExpenseFailedToStoreNotifications(t *testing.T) {
+	repository := spendings_mock.RepositoryMock{
+		AddExpenseImpl: func(ctx context.Context, id spendingsRepository.Expense) repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId] {
+			return repositories.MutationWorkItemWithReturnValue[spendingsRepository.ExpenseId]{
+				Perform: func() (spendingsRepository.ExpenseId, error) {
+					return spendingsRepository.ExpenseId(uuid.New().String()), nil
+				},
+			}
+		},
+	}
+	outboxRepository := outbox_mock.RepositoryMock{
+		AddMessagesImpl: func(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					return errors.New("some error")
+				},
+			}
+		},
+	}
+	controller := defaultController.New(&repository, &outboxRepository, unitOfWorkMock(), standartOutputLoggingService.New())
+	actor := spendings.CounterpartyId(uuid.New().String())
+
+	expense := spendings.Expense{
+		Shares: []spendingsRepository.ShareOfExpense{
+			{
+				Counterparty: spendingsRepository.CounterpartyId(actor),
+			},
+			{
+				Counterparty: spendingsRepository.CounterpartyId(uuid.New().String()),
+			},
+		},
+	}
+	_, err := controller.AddExpense(context.Background(), expense, actor)
+	if err == nil {
+		t.Fatalf("`AddExpense` should be failed, found nil err")
+	}
+	if err.Code != spendings.AddExpenseErrorInternal {
+		t.Fatalf("`AddExpense` should be failed with `internal`, found err %v", err)
+	}
+}
+
 func TestRemoveExpenseFailedToGetById(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return nil, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.RemoveExpense(spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.RemoveExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`RemoveExpense` should be failed, found nil err")
 	}
@@ -120,12 +246,12 @@ func TestRemoveExpenseFailedToGetById(t *testing.T) {
 
 func TestRemoveExpenseFailedNotFound(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return nil, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.RemoveExpense(spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.RemoveExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`RemoveExpense` should be failed, found nil err")
 	}
@@ -136,7 +262,7 @@ func TestRemoveExpenseFailedNotFound(t *testing.T) {
 
 func TestRemoveExpenseFailedNotYourExpense(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return &spendingsRepository.IdentifiableExpense{
 				Id: id,
 				Expense: spendingsRepository.Expense{
@@ -152,8 +278,8 @@ func TestRemoveExpenseFailedNotYourExpense(t *testing.T) {
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.RemoveExpense(spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.RemoveExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`RemoveExpense` should be failed, found nil err")
 	}
@@ -166,7 +292,7 @@ func TestRemoveExpenseRemoveFailed(t *testing.T) {
 	actor := spendings.CounterpartyId(uuid.New().String())
 	counterparty := spendings.CounterpartyId(uuid.New().String())
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return &spendingsRepository.IdentifiableExpense{
 				Id: id,
 				Expense: spendingsRepository.Expense{
@@ -181,7 +307,7 @@ func TestRemoveExpenseRemoveFailed(t *testing.T) {
 				},
 			}, nil
 		},
-		RemoveExpenseImpl: func(id spendingsRepository.ExpenseId) repositories.MutationWorkItem {
+		RemoveExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -189,8 +315,8 @@ func TestRemoveExpenseRemoveFailed(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.RemoveExpense(spendings.ExpenseId(uuid.New().String()), actor)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.RemoveExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), actor)
 	if err == nil {
 		t.Fatalf("`RemoveExpense` should be failed, found nil err")
 	}
@@ -204,7 +330,7 @@ func TestRemoveExpenseOk(t *testing.T) {
 	actor := spendings.CounterpartyId(uuid.New().String())
 	counterparty := spendings.CounterpartyId(uuid.New().String())
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return &spendingsRepository.IdentifiableExpense{
 				Id: id,
 				Expense: spendingsRepository.Expense{
@@ -219,7 +345,7 @@ func TestRemoveExpenseOk(t *testing.T) {
 				},
 			}, nil
 		},
-		RemoveExpenseImpl: func(id spendingsRepository.ExpenseId) repositories.MutationWorkItem {
+		RemoveExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					removeCalls += 1
@@ -228,8 +354,8 @@ func TestRemoveExpenseOk(t *testing.T) {
 			}
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.RemoveExpense(spendings.ExpenseId(uuid.New().String()), actor)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.RemoveExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), actor)
 	if err != nil {
 		t.Fatalf("`RemoveExpense` should not be failed, found err %v", err)
 	}
@@ -240,12 +366,12 @@ func TestRemoveExpenseOk(t *testing.T) {
 
 func TestGetExpenseFailedToGetFromRepository(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return nil, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetExpense(spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetExpense` should be failed, found nil err")
 	}
@@ -256,12 +382,12 @@ func TestGetExpenseFailedToGetFromRepository(t *testing.T) {
 
 func TestGetExpenseFailedNotFound(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return nil, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetExpense(spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetExpense` should be failed, found nil err")
 	}
@@ -272,7 +398,7 @@ func TestGetExpenseFailedNotFound(t *testing.T) {
 
 func TestGetExpenseNotYourExpense(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			return &spendingsRepository.IdentifiableExpense{
 				Id: id,
 				Expense: spendingsRepository.Expense{
@@ -288,8 +414,8 @@ func TestGetExpenseNotYourExpense(t *testing.T) {
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetExpense(spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetExpense` should be failed, found nil err")
 	}
@@ -303,7 +429,7 @@ func TestGetExpenseOk(t *testing.T) {
 	actor := spendings.CounterpartyId(uuid.New().String())
 	counterparty := spendings.CounterpartyId(uuid.New().String())
 	repository := spendings_mock.RepositoryMock{
-		GetExpenseImpl: func(id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
+		GetExpenseImpl: func(ctx context.Context, id spendingsRepository.ExpenseId) (*spendingsRepository.IdentifiableExpense, error) {
 			getCalls += 1
 			return &spendingsRepository.IdentifiableExpense{
 				Id: id,
@@ -320,8 +446,8 @@ func TestGetExpenseOk(t *testing.T) {
 			}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetExpense(spendings.ExpenseId(uuid.New().String()), actor)
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetExpense(context.Background(), spendings.ExpenseId(uuid.New().String()), actor)
 	if err != nil {
 		t.Fatalf("`GetExpense` should not be failed, found err %v", err)
 	}
@@ -332,12 +458,12 @@ func TestGetExpenseOk(t *testing.T) {
 
 func TestGetExpensesWithFailedToGetFromRepository(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetExpensesBetweenImpl: func(counterparty1, counterparty2 spendingsRepository.CounterpartyId) ([]spendingsRepository.IdentifiableExpense, error) {
+		GetExpensesBetweenImpl: func(ctx context.Context, counterparty1, counterparty2 spendingsRepository.CounterpartyId) ([]spendingsRepository.IdentifiableExpense, error) {
 			return []spendingsRepository.IdentifiableExpense{}, errors.New("some error")
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetExpensesWith(spendings.CounterpartyId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetExpensesWith(context.Background(), spendings.CounterpartyId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetExpensesWith` should be failed, found nil err")
 	}
@@ -349,13 +475,13 @@ func TestGetExpensesWithFailedToGetFromRepository(t *testing.T) {
 func TestGetExpensesOk(t *testing.T) {
 	getCalls := 0
 	repository := spendings_mock.RepositoryMock{
-		GetExpensesBetweenImpl: func(counterparty1, counterparty2 spendingsRepository.CounterpartyId) ([]spendingsRepository.IdentifiableExpense, error) {
+		GetExpensesBetweenImpl: func(ctx context.Context, counterparty1, counterparty2 spendingsRepository.CounterpartyId) ([]spendingsRepository.IdentifiableExpense, error) {
 			getCalls += 1
 			return []spendingsRepository.IdentifiableExpense{}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetExpensesWith(spendings.CounterpartyId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetExpensesWith(context.Background(), spendings.CounterpartyId(uuid.New().String()), spendings.CounterpartyId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`GetExpensesWith` should not be failed, found err %v", err)
 	}
@@ -366,13 +492,13 @@ func TestGetExpensesOk(t *testing.T) {
 
 func TestGetBalanceWithFailedToGetFromRepository(t *testing.T) {
 	repository := spendings_mock.RepositoryMock{
-		GetBalanceImpl: func(counterparty spendingsRepository.CounterpartyId) ([]spendingsRepository.Balance, error) {
+		GetBalanceImpl: func(ctx context.Context, counterparty spendingsRepository.CounterpartyId) ([]spendingsRepository.Balance, error) {
 			return []spendingsRepository.Balance{}, errors.New("some error")
 		},
 	}
 
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetBalance(spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetBalance(context.Background(), spendings.CounterpartyId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`GetBalance` should be failed, found nil err")
 	}
@@ -384,13 +510,13 @@ func TestGetBalanceWithFailedToGetFromRepository(t *testing.T) {
 func TestGetBalanceOk(t *testing.T) {
 	getCalls := 0
 	repository := spendings_mock.RepositoryMock{
-		GetBalanceImpl: func(counterparty spendingsRepository.CounterpartyId) ([]spendingsRepository.Balance, error) {
+		GetBalanceImpl: func(ctx context.Context, counterparty spendingsRepository.CounterpartyId) ([]spendingsRepository.Balance, error) {
 			getCalls += 1
 			return []spendingsRepository.Balance{}, nil
 		},
 	}
-	controller := defaultController.New(&repository, standartOutputLoggingService.New())
-	_, err := controller.GetBalance(spendings.CounterpartyId(uuid.New().String()))
+	controller := defaultController.New(&repository, outboxMock(nil), unitOfWorkMock(), standartOutputLoggingService.New())
+	_, err := controller.GetBalance(context.Background(), spendings.CounterpartyId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`GetBalance` should not be failed, found err %v", err)
 	}
@@ -398,3 +524,26 @@ func TestGetBalanceOk(t *testing.T) {
 		t.Fatalf("get should be called once, found %d", getCalls)
 	}
 }
+
+func outboxMock(stored *[]outbox.Message) *outbox_mock.RepositoryMock {
+	return &outbox_mock.RepositoryMock{
+		AddMessagesImpl: func(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					if stored != nil {
+						*stored = append(*stored, messages...)
+					}
+					return nil
+				},
+			}
+		},
+	}
+}
+
+func unitOfWorkMock() *db_mock.UnitOfWorkMock {
+	return &db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			return work(nil)
+		},
+	}
+}
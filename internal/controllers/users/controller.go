@@ -1,6 +1,8 @@
 package users
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 	friendsRepository "github.com/rzmn/governi/internal/repositories/friends"
 )
@@ -17,6 +19,6 @@ type User struct {
 }
 
 type Controller interface {
-	Get(ids []UserId, sender UserId) ([]User, *common.CodeBasedError[GetUsersErrorCode])
-	Search(query string, sender UserId) ([]User, *common.CodeBasedError[SearchUsersErrorCode])
+	Get(ctx context.Context, ids []UserId, sender UserId) ([]User, *common.CodeBasedError[GetUsersErrorCode])
+	Search(ctx context.Context, query string, sender UserId) ([]User, *common.CodeBasedError[SearchUsersErrorCode])
 }
@@ -1,6 +1,7 @@
 package defaultController
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/rzmn/governi/internal/common"
@@ -27,17 +28,17 @@ type defaultController struct {
 	logger  logging.Service
 }
 
-func (c *defaultController) Get(ids []users.UserId, sender users.UserId) ([]users.User, *common.CodeBasedError[users.GetUsersErrorCode]) {
+func (c *defaultController) Get(ctx context.Context, ids []users.UserId, sender users.UserId) ([]users.User, *common.CodeBasedError[users.GetUsersErrorCode]) {
 	const op = "users.defaultController.Get"
 	c.logger.LogInfo("%s: start[sender=%s, ids=%v]", op, sender, ids)
-	usersFromRepository, err := c.users.GetUsers(common.Map(ids, func(id users.UserId) usersRepository.UserId {
+	usersFromRepository, err := c.users.GetUsers(ctx, common.Map(ids, func(id users.UserId) usersRepository.UserId {
 		return usersRepository.UserId(id)
 	}))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get users from db err: %v", op, err)
 		return []users.User{}, common.NewErrorWithDescription(users.GetUsersErrorInternal, err.Error())
 	}
-	userStatuses, err := c.friends.GetStatuses(friendsRepository.UserId(sender), common.Map(ids, func(id users.UserId) friendsRepository.UserId {
+	userStatuses, err := c.friends.GetStatuses(ctx, friendsRepository.UserId(sender), common.Map(ids, func(id users.UserId) friendsRepository.UserId {
 		return friendsRepository.UserId(id)
 	}))
 	if err != nil {
@@ -62,15 +63,15 @@ func (c *defaultController) Get(ids []users.UserId, sender users.UserId) ([]user
 	return result, nil
 }
 
-func (c *defaultController) Search(query string, sender users.UserId) ([]users.User, *common.CodeBasedError[users.SearchUsersErrorCode]) {
+func (c *defaultController) Search(ctx context.Context, query string, sender users.UserId) ([]users.User, *common.CodeBasedError[users.SearchUsersErrorCode]) {
 	const op = "users.defaultController.Search"
 	c.logger.LogInfo("%s: start[sender=%s, query=%v]", op, sender, query)
-	usersFromRepository, err := c.users.SearchUsers(query)
+	usersFromRepository, err := c.users.SearchUsers(ctx, query)
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get users from db err: %v", op, err)
 		return []users.User{}, common.NewErrorWithDescription(users.SearchUsersErrorInternal, err.Error())
 	}
-	userStatuses, err := c.friends.GetStatuses(friendsRepository.UserId(sender), common.Map(usersFromRepository, func(user usersRepository.User) friendsRepository.UserId {
+	userStatuses, err := c.friends.GetStatuses(ctx, friendsRepository.UserId(sender), common.Map(usersFromRepository, func(user usersRepository.User) friendsRepository.UserId {
 		return friendsRepository.UserId(user.Id)
 	}))
 	if err != nil {
@@ -1,6 +1,7 @@
 package defaultController_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -17,17 +18,17 @@ import (
 
 func TestGetUsersFailed(t *testing.T) {
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []usersRepository.UserId) ([]usersRepository.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []usersRepository.UserId) ([]usersRepository.User, error) {
 			return []usersRepository.User{}, errors.New("some error")
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{}, nil
 		},
 	}
 	controller := defaultController.New(&usersRepository, &friendsRepository, standartOutputLoggingService.New())
-	_, err := controller.Get([]users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
+	_, err := controller.Get(context.Background(), []users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`Get` should be failed, found no err")
 	}
@@ -38,17 +39,17 @@ func TestGetUsersFailed(t *testing.T) {
 
 func TestGetFriendStatusesFailed(t *testing.T) {
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []usersRepository.UserId) ([]usersRepository.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []usersRepository.UserId) ([]usersRepository.User, error) {
 			return []usersRepository.User{}, nil
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{}, errors.New("some error")
 		},
 	}
 	controller := defaultController.New(&usersRepository, &friendsRepository, standartOutputLoggingService.New())
-	_, err := controller.Get([]users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
+	_, err := controller.Get(context.Background(), []users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`Get` should be failed, found no err")
 	}
@@ -65,19 +66,19 @@ func TestGetUsersMissingUserStatus(t *testing.T) {
 		Id: usersRepository.UserId(uuid.New().String()),
 	}
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []usersRepository.UserId) ([]usersRepository.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []usersRepository.UserId) ([]usersRepository.User, error) {
 			return []usersRepository.User{userA, userB}, nil
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(userA.Id): friendsRepository.FriendStatusSubscriber,
 			}, nil
 		},
 	}
 	controller := defaultController.New(&usersRepository, &friendsRepository, standartOutputLoggingService.New())
-	_, err := controller.Get([]users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
+	_, err := controller.Get(context.Background(), []users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`Get` should be failed, found no err")
 	}
@@ -94,12 +95,12 @@ func TestGetUsersOk(t *testing.T) {
 		Id: usersRepository.UserId(uuid.New().String()),
 	}
 	usersRepository := users_mock.RepositoryMock{
-		GetUsersImpl: func(ids []usersRepository.UserId) ([]usersRepository.User, error) {
+		GetUsersImpl: func(ctx context.Context, ids []usersRepository.UserId) ([]usersRepository.User, error) {
 			return []usersRepository.User{userA, userB}, nil
 		},
 	}
 	friendsRepository := friends_mock.RepositoryMock{
-		GetStatusesImpl: func(sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
+		GetStatusesImpl: func(ctx context.Context, sender friendsRepository.UserId, ids []friendsRepository.UserId) (map[friendsRepository.UserId]friendsRepository.FriendStatus, error) {
 			return map[friendsRepository.UserId]friendsRepository.FriendStatus{
 				friendsRepository.UserId(userA.Id): friendsRepository.FriendStatusSubscriber,
 				friendsRepository.UserId(userB.Id): friendsRepository.FriendStatusSubscription,
@@ -107,7 +108,7 @@ func TestGetUsersOk(t *testing.T) {
 		},
 	}
 	controller := defaultController.New(&usersRepository, &friendsRepository, standartOutputLoggingService.New())
-	_, err := controller.Get([]users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
+	_, err := controller.Get(context.Background(), []users.UserId{users.UserId(uuid.New().String())}, users.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`Get` should not be failed, found err: %v", err)
 	}
@@ -1,11 +1,13 @@
 package verification
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/common"
 )
 
 type UserId string
 type Controller interface {
-	SendConfirmationCode(uid UserId) *common.CodeBasedError[SendConfirmationCodeErrorCode]
-	ConfirmEmail(uid UserId, code string) *common.CodeBasedError[ConfirmEmailErrorCode]
+	SendConfirmationCode(ctx context.Context, uid UserId) *common.CodeBasedError[SendConfirmationCodeErrorCode]
+	ConfirmEmail(ctx context.Context, uid UserId, code string) *common.CodeBasedError[ConfirmEmailErrorCode]
 }
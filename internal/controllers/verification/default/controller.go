@@ -1,30 +1,41 @@
 package defaultController
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 
 	"github.com/rzmn/governi/internal/common"
 	"github.com/rzmn/governi/internal/controllers/verification"
+	"github.com/rzmn/governi/internal/db"
 	authRepository "github.com/rzmn/governi/internal/repositories/auth"
+	usersRepository "github.com/rzmn/governi/internal/repositories/users"
 	verificationRepository "github.com/rzmn/governi/internal/repositories/verification"
 	"github.com/rzmn/governi/internal/services/emailSender"
+	"github.com/rzmn/governi/internal/services/localization"
 	"github.com/rzmn/governi/internal/services/logging"
 )
 
 type VerificationRepository verificationRepository.Repository
 type AuthRepository authRepository.Repository
+type UsersRepository usersRepository.Repository
 
 func New(
 	verification VerificationRepository,
 	auth AuthRepository,
+	users UsersRepository,
+	unitOfWork db.UnitOfWork,
 	emailService emailSender.Service,
+	localization localization.Service,
 	logger logging.Service,
 ) verification.Controller {
 	return &defaultController{
 		verification: verification,
 		auth:         auth,
+		users:        users,
+		unitOfWork:   unitOfWork,
 		emailService: emailService,
+		localization: localization,
 		logger:       logger,
 	}
 }
@@ -32,49 +43,64 @@ func New(
 type defaultController struct {
 	verification VerificationRepository
 	auth         AuthRepository
+	users        UsersRepository
+	unitOfWork   db.UnitOfWork
 	emailService emailSender.Service
+	localization localization.Service
 	logger       logging.Service
 }
 
-func (c *defaultController) SendConfirmationCode(uid verification.UserId) *common.CodeBasedError[verification.SendConfirmationCodeErrorCode] {
+func (c *defaultController) SendConfirmationCode(ctx context.Context, uid verification.UserId) *common.CodeBasedError[verification.SendConfirmationCodeErrorCode] {
 	const op = "confirmation.EmailConfirmation.SendConfirmationCode"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	user, err := c.auth.GetUserInfo(authRepository.UserId(uid))
+	user, err := c.auth.GetUserInfo(ctx, authRepository.UserId(uid))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get user email by id err: %v", op, err)
 		return common.NewErrorWithDescription(verification.SendConfirmationCodeErrorInternal, err.Error())
 	}
 	email := user.Email
+	storedLanguage, err := c.users.GetLanguage(ctx, usersRepository.UserId(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get user language err: %v", op, err)
+		return common.NewErrorWithDescription(verification.SendConfirmationCodeErrorInternal, err.Error())
+	}
+	language := localization.DefaultLanguage
+	if storedLanguage != nil {
+		language = localization.Language(*storedLanguage)
+	}
 	code := fmt.Sprintf("%d", generate6DigitCode())
-	transaction := c.verification.StoreEmailVerificationCode(email, code)
-	if err := transaction.Perform(); err != nil {
-		c.logger.LogInfo("%s: store tokens failed %v", op, err)
+	if err := c.unitOfWork.Run(ctx, func(tx db.DB) error {
+		return c.verification.WithTx(tx).StoreEmailVerificationCode(ctx, email, code).Perform()
+	}); err != nil {
+		c.logger.LogInfo("%s: store code failed %v", op, err)
 		return common.NewErrorWithDescription(verification.SendConfirmationCodeErrorInternal, err.Error())
 	}
-	if err := c.emailService.Send(
-		"Subject: Confirm your Verni email\r\n"+
-			"\r\n"+
-			fmt.Sprintf("Email Verification code: %s.\r\n", code),
-		email,
-	); err != nil {
+	if err := c.emailService.Send(email, emailSender.Message{
+		Subject: c.localization.Localize(language, localization.KeyEmailVerificationSubject, nil),
+		Body: c.localization.Localize(language, localization.KeyEmailVerificationBody, map[string]string{
+			"code": code,
+		}),
+	}); err != nil {
 		c.logger.LogInfo("%s: send failed: %v", op, err)
-		transaction.Rollback()
+		if removeErr := c.verification.RemoveEmailVerificationCode(ctx, email).Perform(); removeErr != nil {
+			c.logger.LogError("%s: cannot remove undelivered code err: %v", op, removeErr)
+		}
 		return common.NewErrorWithDescription(verification.SendConfirmationCodeErrorNotDelivered, err.Error())
 	}
 	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
 	return nil
 }
 
-func (c *defaultController) ConfirmEmail(uid verification.UserId, code string) *common.CodeBasedError[verification.ConfirmEmailErrorCode] {
+func (c *defaultController) ConfirmEmail(ctx context.Context, uid verification.UserId, code string) *common.CodeBasedError[verification.ConfirmEmailErrorCode] {
 	const op = "confirmation.EmailConfirmation.ConfirmEmail"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	user, err := c.auth.GetUserInfo(authRepository.UserId(uid))
+	user, err := c.auth.GetUserInfo(ctx, authRepository.UserId(uid))
 	if err != nil {
 		c.logger.LogInfo("%s: cannot get user email by id err: %v", op, err)
 		return common.NewErrorWithDescription(verification.ConfirmEmailErrorInternal, err.Error())
 	}
 	email := user.Email
-	codeFromDb, err := c.verification.GetEmailVerificationCode(email)
+	codeFromDb, err := c.verification.GetEmailVerificationCode(ctx, email)
 	if err != nil {
 		c.logger.LogInfo("%s: extract token failed: %v", op, err)
 		return common.NewErrorWithDescription(verification.ConfirmEmailErrorInternal, err.Error())
@@ -87,7 +113,7 @@ func (c *defaultController) ConfirmEmail(uid verification.UserId, code string) *
 		c.logger.LogInfo("%s: verification code is wrong", op)
 		return common.NewError(verification.ConfirmEmailErrorWrongConfirmationCode)
 	}
-	transaction := c.auth.MarkUserEmailValidated(authRepository.UserId(uid))
+	transaction := c.auth.MarkUserEmailValidated(ctx, authRepository.UserId(uid))
 	if err := transaction.Perform(); err != nil {
 		c.logger.LogInfo("%s: failed to mark email as validated: %v", op, err)
 		return common.NewErrorWithDescription(verification.ConfirmEmailErrorInternal, err.Error())
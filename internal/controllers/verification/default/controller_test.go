@@ -1,7 +1,12 @@
 package defaultController_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/rzmn/governi/internal/db"
+	db_mock "github.com/rzmn/governi/internal/db/mock"
+	"reflect"
 	"testing"
 
 	"github.com/rzmn/governi/internal/controllers/verification"
@@ -9,60 +14,79 @@ import (
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/auth"
 	auth_mock "github.com/rzmn/governi/internal/repositories/auth/mock"
+	"github.com/rzmn/governi/internal/repositories/users"
+	users_mock "github.com/rzmn/governi/internal/repositories/users/mock"
 	verification_mock "github.com/rzmn/governi/internal/repositories/verification/mock"
+	"github.com/rzmn/governi/internal/services/emailSender"
 	emailSender_mock "github.com/rzmn/governi/internal/services/emailSender/mock"
+	"github.com/rzmn/governi/internal/services/localization"
+	localization_mock "github.com/rzmn/governi/internal/services/localization/mock"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 
 	"github.com/google/uuid"
 )
 
 func TestSendConfirmationCodeNotDelivered(t *testing.T) {
-	storeCalled := 0
-	storeRolledBack := 0
-	triedToSend := 0
+	events := []string{}
 	emailSenderMock := emailSender_mock.ServiceMock{
-		SendImpl: func(subject, email string) error {
-			triedToSend += 1
+		SendImpl: func(email string, message emailSender.Message) error {
+			events = append(events, "send")
 			return errors.New("some error")
 		},
 	}
 	verificationMock := verification_mock.RepositoryMock{
-		StoreEmailVerificationCodeImpl: func(email string, code string) repositories.MutationWorkItem {
+		StoreEmailVerificationCodeImpl: func(ctx context.Context, email string, code string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
-					storeCalled += 1
+					events = append(events, "store")
 					return nil
 				},
 				Rollback: func() error {
-					storeRolledBack += 1
+					events = append(events, "rollback")
+					return nil
+				},
+			}
+		},
+		RemoveEmailVerificationCodeImpl: func(ctx context.Context, email string) repositories.MutationWorkItem {
+			return repositories.MutationWorkItem{
+				Perform: func() error {
+					events = append(events, "remove")
 					return nil
 				},
 			}
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
+	unitOfWork := db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			err := work(nil)
+			events = append(events, "commit")
+			return err
+		},
+	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		&unitOfWork,
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.SendConfirmationCode(verification.UserId(uuid.New().String()))
+	err := controller.SendConfirmationCode(context.Background(), verification.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
 	if err.Code != verification.SendConfirmationCodeErrorNotDelivered {
 		t.Fatalf("unexpected error code, expected `not delivered`, found %v", err)
 	}
-	if storeCalled != 1 || storeRolledBack != 1 {
-		t.Fatalf("should try to store once then roll back")
-	}
-	if triedToSend != 1 {
-		t.Fatalf("should try to send mail, once")
+	expectedEvents := []string{"store", "commit", "send", "remove"}
+	if !reflect.DeepEqual(events, expectedEvents) {
+		t.Fatalf("should send after commit then remove undelivered code %v, found %v", expectedEvents, events)
 	}
 }
 
@@ -70,17 +94,20 @@ func TestSendConfirmationGetEmailFailed(t *testing.T) {
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, errors.New("some error")
 		},
 	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.SendConfirmationCode(verification.UserId(uuid.New().String()))
+	err := controller.SendConfirmationCode(context.Background(), verification.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -94,7 +121,7 @@ func TestSendConfirmationCodeStoreFailed(t *testing.T) {
 	storeRolledBack := 0
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{
-		StoreEmailVerificationCodeImpl: func(email string, code string) repositories.MutationWorkItem {
+		StoreEmailVerificationCodeImpl: func(ctx context.Context, email string, code string) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeCalled += 1
@@ -108,17 +135,20 @@ func TestSendConfirmationCodeStoreFailed(t *testing.T) {
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.SendConfirmationCode(verification.UserId(uuid.New().String()))
+	err := controller.SendConfirmationCode(context.Background(), verification.UserId(uuid.New().String()))
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -134,14 +164,18 @@ func TestSendConfirmationCodeDelivered(t *testing.T) {
 	storeCalled := 0
 	storeRolledBack := 0
 	triedToSend := 0
+	var storedCode string
+	var sentMessage emailSender.Message
 	emailSenderMock := emailSender_mock.ServiceMock{
-		SendImpl: func(subject, email string) error {
+		SendImpl: func(email string, message emailSender.Message) error {
 			triedToSend += 1
+			sentMessage = message
 			return nil
 		},
 	}
 	verificationMock := verification_mock.RepositoryMock{
-		StoreEmailVerificationCodeImpl: func(email string, code string) repositories.MutationWorkItem {
+		StoreEmailVerificationCodeImpl: func(ctx context.Context, email string, code string) repositories.MutationWorkItem {
+			storedCode = code
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					storeCalled += 1
@@ -155,17 +189,21 @@ func TestSendConfirmationCodeDelivered(t *testing.T) {
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
+	russian := users.Language("ru")
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(&russian),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.SendConfirmationCode(verification.UserId(uuid.New().String()))
+	err := controller.SendConfirmationCode(context.Background(), verification.UserId(uuid.New().String()))
 	if err != nil {
 		t.Fatalf("`err` should be nil, found %v", err)
 	}
@@ -175,23 +213,61 @@ func TestSendConfirmationCodeDelivered(t *testing.T) {
 	if triedToSend != 1 {
 		t.Fatalf("should send email, once")
 	}
+	if sentMessage.Subject != fmt.Sprintf("ru %s map[]", localization.KeyEmailVerificationSubject) ||
+		sentMessage.Body != fmt.Sprintf("ru %s map[code:%s]", localization.KeyEmailVerificationBody, storedCode) {
+		t.Fatalf("email should be localized to user language, found %v", sentMessage)
+	}
+}
+
+func TestSendConfirmationCodeGetLanguageFailed(t *testing.T) {
+	emailSenderMock := emailSender_mock.ServiceMock{}
+	verificationMock := verification_mock.RepositoryMock{}
+	authMock := auth_mock.RepositoryMock{
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
+			return auth.UserInfo{}, nil
+		},
+	}
+	usersMock := users_mock.RepositoryMock{
+		GetLanguageImpl: func(ctx context.Context, id users.UserId) (*users.Language, error) {
+			return nil, errors.New("some error")
+		},
+	}
+	controller := defaultController.New(
+		&verificationMock,
+		&authMock,
+		&usersMock,
+		unitOfWorkMock(),
+		&emailSenderMock,
+		localizationMock(),
+		standartOutputLoggingService.New(),
+	)
+	err := controller.SendConfirmationCode(context.Background(), verification.UserId(uuid.New().String()))
+	if err == nil {
+		t.Fatalf("`err` should not be nil")
+	}
+	if err.Code != verification.SendConfirmationCodeErrorInternal {
+		t.Fatalf("unexpected error code, expected `internal`, found %v", err)
+	}
 }
 
 func TestConfirmEmailGetEmailFailed(t *testing.T) {
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, errors.New("some error")
 		},
 	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.ConfirmEmail(verification.UserId(uuid.New().String()), "")
+	err := controller.ConfirmEmail(context.Background(), verification.UserId(uuid.New().String()), "")
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -203,22 +279,25 @@ func TestConfirmEmailGetEmailFailed(t *testing.T) {
 func TestConfirmEmailGetCodeFailed(t *testing.T) {
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{
-		GetEmailVerificationCodeImpl: func(email string) (*string, error) {
+		GetEmailVerificationCodeImpl: func(ctx context.Context, email string) (*string, error) {
 			return nil, errors.New("some error")
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.ConfirmEmail(verification.UserId(uuid.New().String()), "")
+	err := controller.ConfirmEmail(context.Background(), verification.UserId(uuid.New().String()), "")
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -230,22 +309,25 @@ func TestConfirmEmailGetCodeFailed(t *testing.T) {
 func TestConfirmEmailCodeHasNotBeenSent(t *testing.T) {
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{
-		GetEmailVerificationCodeImpl: func(email string) (*string, error) {
+		GetEmailVerificationCodeImpl: func(ctx context.Context, email string) (*string, error) {
 			return nil, nil
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.ConfirmEmail(verification.UserId(uuid.New().String()), "")
+	err := controller.ConfirmEmail(context.Background(), verification.UserId(uuid.New().String()), "")
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -258,22 +340,25 @@ func TestConfirmEmailCodeIsWrong(t *testing.T) {
 	codeFromRepository := uuid.New().String()
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{
-		GetEmailVerificationCodeImpl: func(email string) (*string, error) {
+		GetEmailVerificationCodeImpl: func(ctx context.Context, email string) (*string, error) {
 			return &codeFromRepository, nil
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
 	}
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.ConfirmEmail(verification.UserId(uuid.New().String()), "")
+	err := controller.ConfirmEmail(context.Background(), verification.UserId(uuid.New().String()), "")
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -286,15 +371,15 @@ func TestConfirmEmailMarkValidatedFailed(t *testing.T) {
 	codeFromRepository := uuid.New().String()
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{
-		GetEmailVerificationCodeImpl: func(email string) (*string, error) {
+		GetEmailVerificationCodeImpl: func(ctx context.Context, email string) (*string, error) {
 			return &codeFromRepository, nil
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
-		MarkUserEmailValidatedImpl: func(uid auth.UserId) repositories.MutationWorkItem {
+		MarkUserEmailValidatedImpl: func(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return errors.New("some error")
@@ -305,10 +390,13 @@ func TestConfirmEmailMarkValidatedFailed(t *testing.T) {
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.ConfirmEmail(verification.UserId(uuid.New().String()), codeFromRepository)
+	err := controller.ConfirmEmail(context.Background(), verification.UserId(uuid.New().String()), codeFromRepository)
 	if err == nil {
 		t.Fatalf("`err` should not be nil")
 	}
@@ -321,15 +409,15 @@ func TestConfirmEmailOk(t *testing.T) {
 	codeFromRepository := uuid.New().String()
 	emailSenderMock := emailSender_mock.ServiceMock{}
 	verificationMock := verification_mock.RepositoryMock{
-		GetEmailVerificationCodeImpl: func(email string) (*string, error) {
+		GetEmailVerificationCodeImpl: func(ctx context.Context, email string) (*string, error) {
 			return &codeFromRepository, nil
 		},
 	}
 	authMock := auth_mock.RepositoryMock{
-		GetUserInfoImpl: func(uid auth.UserId) (auth.UserInfo, error) {
+		GetUserInfoImpl: func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 			return auth.UserInfo{}, nil
 		},
-		MarkUserEmailValidatedImpl: func(uid auth.UserId) repositories.MutationWorkItem {
+		MarkUserEmailValidatedImpl: func(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
 			return repositories.MutationWorkItem{
 				Perform: func() error {
 					return nil
@@ -340,11 +428,38 @@ func TestConfirmEmailOk(t *testing.T) {
 	controller := defaultController.New(
 		&verificationMock,
 		&authMock,
+		usersMock(nil),
+		unitOfWorkMock(),
 		&emailSenderMock,
+		localizationMock(),
 		standartOutputLoggingService.New(),
 	)
-	err := controller.ConfirmEmail(verification.UserId(uuid.New().String()), codeFromRepository)
+	err := controller.ConfirmEmail(context.Background(), verification.UserId(uuid.New().String()), codeFromRepository)
 	if err != nil {
 		t.Fatalf("`err` should be nil, found %v", err)
 	}
 }
+
+func unitOfWorkMock() *db_mock.UnitOfWorkMock {
+	return &db_mock.UnitOfWorkMock{
+		RunImpl: func(ctx context.Context, work func(tx db.DB) error) error {
+			return work(nil)
+		},
+	}
+}
+
+func usersMock(language *users.Language) *users_mock.RepositoryMock {
+	return &users_mock.RepositoryMock{
+		GetLanguageImpl: func(ctx context.Context, id users.UserId) (*users.Language, error) {
+			return language, nil
+		},
+	}
+}
+
+func localizationMock() *localization_mock.ServiceMock {
+	return &localization_mock.ServiceMock{
+		LocalizeImpl: func(language localization.Language, key localization.Key, arguments map[string]string) string {
+			return fmt.Sprintf("%s %s %v", language, key, arguments)
+		},
+	}
+}
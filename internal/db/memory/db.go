@@ -0,0 +1,115 @@
+package memoryDb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+)
+
+var (
+	ErrNotSupported = errors.New("sql queries are not supported by in-memory storage")
+)
+
+// Transaction is passed to units of work running on in-memory storage. Repositories bound to it
+// via WithTx register compensating actions that are executed if the unit of work is aborted.
+type Transaction struct {
+	mutex     sync.Mutex
+	rollbacks []func() error
+}
+
+func (c *Transaction) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *Transaction) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return nil
+}
+
+func (c *Transaction) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *Transaction) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return nil, db.ErrNestedTransaction
+}
+
+func (c *Transaction) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *Transaction) Close() error {
+	return nil
+}
+
+func (c *Transaction) onRollback(rollback func() error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rollbacks = append(c.rollbacks, rollback)
+}
+
+func (c *Transaction) rollback() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	errs := []error{}
+	for i := len(c.rollbacks) - 1; i >= 0; i-- {
+		if err := c.rollbacks[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.rollbacks = nil
+	return errors.Join(errs...)
+}
+
+// Track wraps perform so that a successful mutation made within tx is undone by rollback
+// when the unit of work is aborted. Outside of a unit of work perform is returned as is.
+func Track(tx db.DB, perform func() error, rollback func() error) func() error {
+	transaction, ok := tx.(*Transaction)
+	if !ok {
+		return perform
+	}
+	return func() error {
+		if err := perform(); err != nil {
+			return err
+		}
+		transaction.onRollback(rollback)
+		return nil
+	}
+}
+
+func TrackWithReturnValue[T any](tx db.DB, perform func() (T, error), rollback func() error) func() (T, error) {
+	transaction, ok := tx.(*Transaction)
+	if !ok {
+		return perform
+	}
+	return func() (T, error) {
+		value, err := perform()
+		if err != nil {
+			return value, err
+		}
+		transaction.onRollback(rollback)
+		return value, nil
+	}
+}
+
+func NewUnitOfWork() db.UnitOfWork {
+	return &unitOfWork{}
+}
+
+type unitOfWork struct{}
+
+func (c *unitOfWork) Run(ctx context.Context, work func(tx db.DB) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx := &Transaction{}
+	if err := work(tx); err != nil {
+		if rollbackErr := tx.rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+const (
+	EmbeddedDirectory = "internal/db/migrations/sql"
+)
+
+type Version int64
+
+type Migration struct {
+	Version Version
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   Version
+	Name      string
+	Applied   bool
+	AppliedAt *int64
+	Known     bool
+}
+
+type Engine interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context, count int) ([]Migration, error)
+	Status(ctx context.Context) ([]MigrationStatus, error)
+	Pending(ctx context.Context) ([]Migration, error)
+}
+
+func Embedded() ([]Migration, error) {
+	source, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return Load(source)
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+func Load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+	migrations := map[Version]*Migration{}
+	scripts := map[Version]map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %s", entry.Name())
+		}
+		parsed, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad migration version in %s err: %v", entry.Name(), err)
+		}
+		version := Version(parsed)
+		data, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{
+				Version: version,
+				Name:    match[2],
+			}
+			migrations[version] = migration
+			scripts[version] = map[string]bool{}
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, migration.Name, match[2])
+		}
+		scripts[version][match[3]] = true
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+	result := []Migration{}
+	for _, migration := range migrations {
+		if !scripts[migration.Version]["up"] || !scripts[migration.Version]["down"] {
+			return nil, fmt.Errorf("migration %d_%s should have both up and down scripts", migration.Version, migration.Name)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+func Create(directory string, name string, existing []Migration) (string, string, error) {
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return "", "", fmt.Errorf("migration name %s should contain only lowercase letters, digits and underscores", name)
+	}
+	version := Version(1)
+	for _, migration := range existing {
+		if migration.Version >= version {
+			version = migration.Version + 1
+		}
+	}
+	base := filepath.Join(directory, fmt.Sprintf("%04d_%s", version, name))
+	up := base + ".up.sql"
+	down := base + ".down.sql"
+	for _, path := range []string{up, down} {
+		if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
+
+func New(db db.DB, migrations []Migration, logger logging.Service, currentTime func() time.Time) Engine {
+	return &postgresEngine{
+		db:          db,
+		migrations:  migrations,
+		logger:      logger,
+		currentTime: currentTime,
+	}
+}
+
+type postgresEngine struct {
+	db          db.DB
+	migrations  []Migration
+	logger      logging.Service
+	currentTime func() time.Time
+}
+
+func (c *postgresEngine) Up(ctx context.Context) ([]Migration, error) {
+	const op = "migrations.postgresEngine.Up"
+	c.logger.LogInfo("%s: start", op)
+	pending, err := c.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := []Migration{}
+	for _, migration := range pending {
+		performed, err := c.apply(ctx, migration, migration.Up, true)
+		if err != nil {
+			c.logger.LogInfo("%s: failed to apply %d_%s err: %v", op, migration.Version, migration.Name, err)
+			return applied, fmt.Errorf("apply %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if performed {
+			applied = append(applied, migration)
+		}
+	}
+	c.logger.LogInfo("%s: success[applied=%d]", op, len(applied))
+	return applied, nil
+}
+
+func (c *postgresEngine) Down(ctx context.Context, count int) ([]Migration, error) {
+	const op = "migrations.postgresEngine.Down"
+	c.logger.LogInfo("%s: start[count=%d]", op, count)
+	applied, err := c.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+	if count < len(versions) {
+		versions = versions[:count]
+	}
+	known := map[Version]Migration{}
+	for _, migration := range c.migrations {
+		known[migration.Version] = migration
+	}
+	reverted := []Migration{}
+	for _, version := range versions {
+		migration, ok := known[version]
+		if !ok {
+			return reverted, fmt.Errorf("applied migration %d is unknown to this binary", version)
+		}
+		performed, err := c.apply(ctx, migration, migration.Down, false)
+		if err != nil {
+			c.logger.LogInfo("%s: failed to revert %d_%s err: %v", op, migration.Version, migration.Name, err)
+			return reverted, fmt.Errorf("revert %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if performed {
+			reverted = append(reverted, migration)
+		}
+	}
+	c.logger.LogInfo("%s: success[reverted=%d]", op, len(reverted))
+	return reverted, nil
+}
+
+func (c *postgresEngine) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := c.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []MigrationStatus{}
+	for _, migration := range c.migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Known:   true,
+		}
+		if appliedMigration, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedMigration.AppliedAt
+			delete(applied, migration.Version)
+		}
+		result = append(result, status)
+	}
+	for version, appliedMigration := range applied {
+		result = append(result, MigrationStatus{
+			Version:   version,
+			Name:      appliedMigration.Name,
+			Applied:   true,
+			AppliedAt: &appliedMigration.AppliedAt,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+func (c *postgresEngine) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := c.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, migration := range c.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+type appliedMigration struct {
+	Name      string
+	AppliedAt int64
+}
+
+func (c *postgresEngine) applied(ctx context.Context) (map[Version]appliedMigration, error) {
+	const op = "migrations.postgresEngine.applied"
+	if err := c.createTable(ctx); err != nil {
+		c.logger.LogInfo("%s: failed to create schema_migrations err: %v", op, err)
+		return nil, err
+	}
+	rows, err := c.db.QueryContext(ctx, `SELECT version, name, appliedAt FROM schema_migrations;`)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	result := map[Version]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var migration appliedMigration
+		if err := rows.Scan(&version, &migration.Name, &migration.AppliedAt); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		result[Version(version)] = migration
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// lockKey identifies the advisory lock that serializes migrations run by concurrent instances.
+const lockKey = 7281946031
+
+// lock takes the migrations advisory lock until the end of the transaction.
+func lock(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1);`, int64(lockKey))
+	return err
+}
+
+func (c *postgresEngine) createTable(ctx context.Context) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := lock(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations(
+	version bigint NOT NULL PRIMARY KEY,
+	name text NOT NULL,
+	appliedAt bigint NOT NULL
+);
+`); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// apply runs the script under the migrations advisory lock. The migration is checked again once the
+// lock is taken, so a migration applied or reverted by another instance meanwhile is skipped and
+// false is returned.
+func (c *postgresEngine) apply(ctx context.Context, migration Migration, script string, up bool) (bool, error) {
+	const op = "migrations.postgresEngine.apply"
+	c.logger.LogInfo("%s: start[migration=%d_%s up=%t]", op, migration.Version, migration.Name, up)
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to create tx err: %v", op, err)
+		return false, err
+	}
+	if err := lock(ctx, tx); err != nil {
+		c.logger.LogInfo("%s: failed to take lock err: %v", op, err)
+		tx.Rollback()
+		return false, err
+	}
+	var applied bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1);`, int64(migration.Version)).Scan(&applied); err != nil {
+		c.logger.LogInfo("%s: failed to check migration err: %v", op, err)
+		tx.Rollback()
+		return false, err
+	}
+	if applied == up {
+		c.logger.LogInfo("%s: skipped, already done by another instance[migration=%d_%s up=%t]", op, migration.Version, migration.Name, up)
+		tx.Rollback()
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if up {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations(version, name, appliedAt) VALUES ($1, $2, $3);`,
+			int64(migration.Version),
+			migration.Name,
+			c.currentTime().Unix(),
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, int64(migration.Version))
+	}
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.LogInfo("%s: failed to commit tx err: %v", op, err)
+		return false, err
+	}
+	c.logger.LogInfo("%s: success[migration=%d_%s up=%t]", op, migration.Version, migration.Name, up)
+	return true, nil
+}
@@ -0,0 +1,102 @@
+package migrations_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rzmn/governi/internal/db/migrations"
+)
+
+func TestEmbedded(t *testing.T) {
+	embedded, err := migrations.Embedded()
+	if err != nil {
+		t.Fatalf("failed to load embedded migrations err: %v", err)
+	}
+	if len(embedded) == 0 {
+		t.Fatalf("embedded migrations should not be empty")
+	}
+	for i, migration := range embedded {
+		if migration.Version != migrations.Version(i+1) {
+			t.Fatalf("migration versions should be sequential, found %d at %d", migration.Version, i)
+		}
+	}
+}
+
+func TestLoadSorted(t *testing.T) {
+	loaded, err := migrations.Load(fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("up2")},
+		"0002_second.down.sql": {Data: []byte("down2")},
+		"0001_first.up.sql":    {Data: []byte("up1")},
+		"0001_first.down.sql":  {Data: []byte("down1")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("should be 2 migrations, found %v", loaded)
+	}
+	if loaded[0].Version != 1 || loaded[0].Name != "first" || loaded[0].Up != "up1" || loaded[0].Down != "down1" {
+		t.Fatalf("unexpected first migration %v", loaded[0])
+	}
+	if loaded[1].Version != 2 || loaded[1].Name != "second" || loaded[1].Up != "up2" || loaded[1].Down != "down2" {
+		t.Fatalf("unexpected second migration %v", loaded[1])
+	}
+}
+
+func TestLoadMissingDown(t *testing.T) {
+	_, err := migrations.Load(fstest.MapFS{
+		"0001_first.up.sql": {Data: []byte("up1")},
+	})
+	if err == nil {
+		t.Fatalf("should fail on migration without down script")
+	}
+}
+
+func TestLoadConflictingNames(t *testing.T) {
+	_, err := migrations.Load(fstest.MapFS{
+		"0001_first.up.sql":   {Data: []byte("up1")},
+		"0001_other.down.sql": {Data: []byte("down1")},
+	})
+	if err == nil {
+		t.Fatalf("should fail on conflicting migration names")
+	}
+}
+
+func TestLoadUnexpectedFile(t *testing.T) {
+	_, err := migrations.Load(fstest.MapFS{
+		"first.sql": {Data: []byte("up1")},
+	})
+	if err == nil {
+		t.Fatalf("should fail on unexpected file name")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	directory := t.TempDir()
+	up, down, err := migrations.Create(directory, "add_things", []migrations.Migration{
+		{Version: 1, Name: "first"},
+		{Version: 7, Name: "seventh"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if up != filepath.Join(directory, "0008_add_things.up.sql") {
+		t.Fatalf("unexpected up path %s", up)
+	}
+	if down != filepath.Join(directory, "0008_add_things.down.sql") {
+		t.Fatalf("unexpected down path %s", down)
+	}
+	for _, path := range []string{up, down} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("file %s should exist err: %v", path, err)
+		}
+	}
+}
+
+func TestCreateBadName(t *testing.T) {
+	if _, _, err := migrations.Create(t.TempDir(), "Bad Name", []migrations.Migration{}); err == nil {
+		t.Fatalf("should fail on bad migration name")
+	}
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS emailVerification;
+DROP TABLE IF EXISTS pushTokens;
+DROP TABLE IF EXISTS images;
+DROP TABLE IF EXISTS deals;
+DROP TABLE IF EXISTS spendings;
+DROP TABLE IF EXISTS friendRequests;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS credentials;
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS credentials(
+	id text NOT NULL PRIMARY KEY,
+	email text NOT NULL,
+	password text NOT NULL,
+	token text NOT NULL,
+	emailVerified bool NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users(
+	id text NOT NULL PRIMARY KEY,
+	displayName text NOT NULL,
+	avatarId text
+);
+
+CREATE TABLE IF NOT EXISTS friendRequests(
+	sender text NOT NULL,
+	target text NOT NULL,
+	PRIMARY KEY(sender, target)
+);
+
+CREATE TABLE IF NOT EXISTS spendings(
+	id text NOT NULL PRIMARY KEY,
+	dealId text NOT NULL,
+	cost int NOT NULL,
+	counterparty text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS deals(
+	id text NOT NULL PRIMARY KEY,
+	timestamp int NOT NULL,
+	details text NOT NULL,
+	cost int NOT NULL,
+	currency text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS images(
+	id text NOT NULL PRIMARY KEY,
+	base64 text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pushTokens(
+	id text NOT NULL PRIMARY KEY,
+	token text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS emailVerification(
+	email text NOT NULL PRIMARY KEY,
+	code text
+);
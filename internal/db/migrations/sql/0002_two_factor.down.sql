@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS twoFactorRecoveryCodes;
+DROP TABLE IF EXISTS twoFactorSecrets;
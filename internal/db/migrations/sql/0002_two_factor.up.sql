@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS twoFactorSecrets(
+	id text NOT NULL PRIMARY KEY,
+	secret text NOT NULL,
+	confirmed bool NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS twoFactorRecoveryCodes(
+	id text NOT NULL,
+	codeHash text NOT NULL,
+	PRIMARY KEY(id, codeHash)
+);
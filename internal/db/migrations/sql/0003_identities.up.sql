@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS identities(
+	issuer text NOT NULL,
+	subject text NOT NULL,
+	id text NOT NULL,
+	PRIMARY KEY(issuer, subject)
+);
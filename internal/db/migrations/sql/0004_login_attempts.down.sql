@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS failedLogins;
+DROP TABLE IF EXISTS loginFailures;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS loginFailures(
+	key text NOT NULL PRIMARY KEY,
+	count int NOT NULL,
+	lastFailure bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS failedLogins(
+	id text NOT NULL PRIMARY KEY,
+	uid text,
+	email text NOT NULL,
+	ip text NOT NULL,
+	timestamp bigint NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS dataExports(
+	id text NOT NULL PRIMARY KEY,
+	uid text NOT NULL,
+	status int NOT NULL,
+	createdAt bigint NOT NULL,
+	archive bytea
+);
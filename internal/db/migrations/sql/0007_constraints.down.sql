@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS deals_timestamp_idx;
+DROP INDEX IF EXISTS friendRequests_target_idx;
+DROP INDEX IF EXISTS spendings_counterparty_idx;
+DROP INDEX IF EXISTS spendings_dealId_idx;
+DROP INDEX IF EXISTS credentials_email_key;
+
+ALTER TABLE spendings DROP CONSTRAINT IF EXISTS spendings_dealId_fkey;
+
+ALTER TABLE spendings ALTER COLUMN cost TYPE int;
+ALTER TABLE deals ALTER COLUMN timestamp TYPE int USING extract(epoch FROM timestamp)::int;
+ALTER TABLE deals ALTER COLUMN cost TYPE int;
@@ -0,0 +1,33 @@
+DELETE FROM spendings WHERE dealId NOT IN (SELECT id FROM deals);
+
+-- credentials sharing an email can not be told apart safely, they have to be merged or
+-- removed by hand before the unique index is created.
+DO $$
+DECLARE
+	duplicates text;
+BEGIN
+	SELECT string_agg(format('%s (ids: %s)', email, ids), '; ')
+	INTO duplicates
+	FROM (
+		SELECT email, string_agg(id, ', ' ORDER BY id) AS ids
+		FROM credentials
+		GROUP BY email
+		HAVING count(*) > 1
+	) duplicated;
+	IF duplicates IS NOT NULL THEN
+		RAISE EXCEPTION 'credentials have duplicate emails, resolve them before migrating: %', duplicates;
+	END IF;
+END
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE deals ALTER COLUMN cost TYPE bigint;
+ALTER TABLE deals ALTER COLUMN timestamp TYPE timestamptz USING to_timestamp(timestamp);
+ALTER TABLE spendings ALTER COLUMN cost TYPE bigint;
+
+ALTER TABLE spendings ADD CONSTRAINT spendings_dealId_fkey FOREIGN KEY (dealId) REFERENCES deals(id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS credentials_email_key ON credentials(email);
+CREATE INDEX IF NOT EXISTS spendings_dealId_idx ON spendings(dealId);
+CREATE INDEX IF NOT EXISTS spendings_counterparty_idx ON spendings(counterparty);
+CREATE INDEX IF NOT EXISTS friendRequests_target_idx ON friendRequests(target);
+CREATE INDEX IF NOT EXISTS deals_timestamp_idx ON deals(timestamp);
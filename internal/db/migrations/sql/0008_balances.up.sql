@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS balances(
+	uid text NOT NULL,
+	counterparty text NOT NULL,
+	currency text NOT NULL,
+	amount bigint NOT NULL,
+	entries bigint NOT NULL,
+	PRIMARY KEY(uid, counterparty, currency)
+);
+
+INSERT INTO balances(uid, counterparty, currency, amount, entries)
+SELECT s1.counterparty, s2.counterparty, d.currency, SUM(s1.cost), COUNT(*)
+FROM
+	deals d
+	JOIN spendings s1 ON s1.dealId = d.id
+	JOIN spendings s2 ON s2.dealId = d.id
+WHERE s1.counterparty != s2.counterparty
+GROUP BY s1.counterparty, s2.counterparty, d.currency;
@@ -0,0 +1,21 @@
+DROP TABLE IF EXISTS journal;
+DROP FUNCTION IF EXISTS journal_balanced();
+DROP FUNCTION IF EXISTS journal_append_only();
+
+DELETE FROM spendings WHERE dealId IN (SELECT id FROM deals WHERE removedAt IS NOT NULL);
+DELETE FROM deals WHERE removedAt IS NOT NULL;
+ALTER TABLE deals DROP COLUMN IF EXISTS removedAt;
+ALTER TABLE spendings DROP CONSTRAINT IF EXISTS spendings_dealId_fkey;
+ALTER TABLE spendings ADD CONSTRAINT spendings_dealId_fkey FOREIGN KEY (dealId) REFERENCES deals(id) ON DELETE CASCADE;
+
+DELETE FROM balances;
+ALTER TABLE balances ADD COLUMN IF NOT EXISTS entries bigint NOT NULL DEFAULT 0;
+ALTER TABLE balances ALTER COLUMN entries DROP DEFAULT;
+INSERT INTO balances(uid, counterparty, currency, amount, entries)
+SELECT s1.counterparty, s2.counterparty, d.currency, SUM(s1.cost), COUNT(*)
+FROM
+	deals d
+	JOIN spendings s1 ON s1.dealId = d.id
+	JOIN spendings s2 ON s2.dealId = d.id
+WHERE s1.counterparty != s2.counterparty
+GROUP BY s1.counterparty, s2.counterparty, d.currency;
@@ -0,0 +1,90 @@
+CREATE TABLE IF NOT EXISTS journal(
+	sequence bigserial PRIMARY KEY,
+	transactionId text NOT NULL,
+	dealId text NOT NULL REFERENCES deals(id),
+	kind text NOT NULL,
+	account text NOT NULL,
+	counterparty text NOT NULL,
+	currency text NOT NULL,
+	amount bigint NOT NULL,
+	timestamp timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS journal_account_counterparty_idx ON journal(account, counterparty);
+CREATE INDEX IF NOT EXISTS journal_transactionId_idx ON journal(transactionId);
+CREATE INDEX IF NOT EXISTS journal_dealId_idx ON journal(dealId);
+
+CREATE OR REPLACE FUNCTION journal_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'journal is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER journal_append_only BEFORE UPDATE OR DELETE ON journal
+FOR EACH ROW EXECUTE FUNCTION journal_append_only();
+
+CREATE TRIGGER journal_no_truncate BEFORE TRUNCATE ON journal
+FOR EACH STATEMENT EXECUTE FUNCTION journal_append_only();
+
+CREATE OR REPLACE FUNCTION journal_balanced() RETURNS trigger AS $$
+BEGIN
+	IF (SELECT SUM(amount) FROM journal WHERE transactionId = NEW.transactionId) != 0 THEN
+		RAISE EXCEPTION 'journal transaction % is not balanced', NEW.transactionId;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_balanced AFTER INSERT ON journal
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION journal_balanced();
+
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS removedAt timestamptz;
+ALTER TABLE spendings DROP CONSTRAINT IF EXISTS spendings_dealId_fkey;
+ALTER TABLE spendings ADD CONSTRAINT spendings_dealId_fkey FOREIGN KEY (dealId) REFERENCES deals(id);
+
+-- existing expenses are posted the same way the repository does: positive and negative shares
+-- are matched in the order of counterparty ids, shares of unbalanced expenses are posted partially
+INSERT INTO journal(transactionId, dealId, kind, account, counterparty, currency, amount, timestamp)
+WITH
+	totals AS (
+		SELECT dealId, counterparty, SUM(cost) AS cost FROM spendings GROUP BY dealId, counterparty
+	),
+	creditors AS (
+		SELECT
+			dealId,
+			counterparty,
+			SUM(cost) OVER (PARTITION BY dealId ORDER BY counterparty) - cost AS rangeStart,
+			SUM(cost) OVER (PARTITION BY dealId ORDER BY counterparty) AS rangeEnd
+		FROM totals WHERE cost > 0
+	),
+	debtors AS (
+		SELECT
+			dealId,
+			counterparty,
+			SUM(-cost) OVER (PARTITION BY dealId ORDER BY counterparty) + cost AS rangeStart,
+			SUM(-cost) OVER (PARTITION BY dealId ORDER BY counterparty) AS rangeEnd
+		FROM totals WHERE cost < 0
+	),
+	debts AS (
+		SELECT
+			c.dealId,
+			c.counterparty AS creditor,
+			d.counterparty AS debtor,
+			LEAST(c.rangeEnd, d.rangeEnd) - GREATEST(c.rangeStart, d.rangeStart) AS amount
+		FROM creditors c JOIN debtors d ON c.dealId = d.dealId
+		WHERE LEAST(c.rangeEnd, d.rangeEnd) > GREATEST(c.rangeStart, d.rangeStart)
+	)
+SELECT debts.dealId, debts.dealId, 'expense', debts.creditor, debts.debtor, deals.currency, debts.amount, deals.timestamp
+FROM debts JOIN deals ON deals.id = debts.dealId
+UNION ALL
+SELECT debts.dealId, debts.dealId, 'expense', debts.debtor, debts.creditor, deals.currency, -debts.amount, deals.timestamp
+FROM debts JOIN deals ON deals.id = debts.dealId;
+
+DELETE FROM balances;
+ALTER TABLE balances DROP COLUMN IF EXISTS entries;
+INSERT INTO balances(uid, counterparty, currency, amount)
+SELECT account, counterparty, currency, SUM(amount)
+FROM journal
+GROUP BY account, counterparty, currency
+HAVING SUM(amount) != 0;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotencyKeys(
+	scope text NOT NULL,
+	key text NOT NULL,
+	requestHash text NOT NULL,
+	completed bool NOT NULL,
+	status int NOT NULL,
+	contentType text NOT NULL,
+	body bytea,
+	createdAt bigint NOT NULL,
+	PRIMARY KEY(scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotencyKeys_createdAt_idx ON idempotencyKeys(createdAt);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS realtimeEvents(
+	id bigserial PRIMARY KEY,
+	payload text NOT NULL,
+	createdAt bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS realtimeEvents_createdAt_idx ON realtimeEvents(createdAt);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox(
+	id text PRIMARY KEY,
+	sequence bigserial NOT NULL,
+	kind text NOT NULL,
+	payload bytea NOT NULL,
+	attempts int NOT NULL DEFAULT 0,
+	nextAttemptAt bigint NOT NULL DEFAULT 0,
+	lastError text,
+	deadLetteredAt bigint
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox(nextAttemptAt, sequence) WHERE deadLetteredAt IS NULL;
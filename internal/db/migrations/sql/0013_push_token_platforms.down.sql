@@ -0,0 +1,6 @@
+DELETE FROM pushTokens WHERE platform <> 'ios';
+
+ALTER TABLE pushTokens DROP CONSTRAINT IF EXISTS pushTokens_pkey;
+ALTER TABLE pushTokens ADD CONSTRAINT pushTokens_pkey PRIMARY KEY (id);
+
+ALTER TABLE pushTokens DROP COLUMN IF EXISTS platform;
@@ -0,0 +1,5 @@
+ALTER TABLE pushTokens ADD COLUMN IF NOT EXISTS platform text NOT NULL DEFAULT 'ios';
+ALTER TABLE pushTokens ALTER COLUMN platform DROP DEFAULT;
+
+ALTER TABLE pushTokens DROP CONSTRAINT IF EXISTS pushTokens_pkey;
+ALTER TABLE pushTokens ADD CONSTRAINT pushTokens_pkey PRIMARY KEY (id, platform);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS pushTokens_id_idx;
+
+DELETE FROM pushTokens a USING pushTokens b WHERE a.id = b.id AND a.platform = b.platform AND a.ctid < b.ctid;
+
+ALTER TABLE pushTokens DROP COLUMN IF EXISTS appVersion;
+ALTER TABLE pushTokens DROP CONSTRAINT IF EXISTS pushTokens_pkey;
+ALTER TABLE pushTokens ADD CONSTRAINT pushTokens_pkey PRIMARY KEY (id, platform);
@@ -0,0 +1,7 @@
+DELETE FROM pushTokens a USING pushTokens b WHERE a.token = b.token AND a.ctid < b.ctid;
+
+ALTER TABLE pushTokens DROP CONSTRAINT IF EXISTS pushTokens_pkey;
+ALTER TABLE pushTokens ADD CONSTRAINT pushTokens_pkey PRIMARY KEY (token);
+ALTER TABLE pushTokens ADD COLUMN IF NOT EXISTS appVersion text;
+
+CREATE INDEX IF NOT EXISTS pushTokens_id_idx ON pushTokens(id);
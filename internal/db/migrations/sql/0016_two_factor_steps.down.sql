@@ -0,0 +1 @@
+ALTER TABLE twoFactorSecrets DROP COLUMN IF EXISTS lastAcceptedStep;
@@ -0,0 +1 @@
+ALTER TABLE twoFactorSecrets ADD COLUMN IF NOT EXISTS lastAcceptedStep bigint NOT NULL DEFAULT 0;
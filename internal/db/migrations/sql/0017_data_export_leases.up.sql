@@ -0,0 +1,2 @@
+ALTER TABLE dataExports ADD COLUMN IF NOT EXISTS startedAt bigint NOT NULL DEFAULT 0;
+UPDATE dataExports SET startedAt = createdAt;
@@ -0,0 +1,2 @@
+ALTER TABLE idempotencyKeys ADD COLUMN IF NOT EXISTS reservedAt bigint NOT NULL DEFAULT 0;
+UPDATE idempotencyKeys SET reservedAt = createdAt;
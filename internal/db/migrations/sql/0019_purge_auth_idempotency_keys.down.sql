@@ -0,0 +1 @@
+-- purged records are not restored, clients retrying these routes run the request again.
@@ -0,0 +1,10 @@
+DELETE FROM idempotencyKeys
+WHERE scope LIKE '% /auth/signup'
+	OR scope LIKE '% /auth/login'
+	OR scope LIKE '% /auth/loginWithIdentityProvider'
+	OR scope LIKE '% /auth/verify2fa'
+	OR scope LIKE '% /auth/refresh'
+	OR scope LIKE '% /auth/updateEmail'
+	OR scope LIKE '% /auth/updatePassword'
+	OR scope LIKE '% /auth/enroll2fa'
+	OR scope LIKE '% /auth/confirm2fa';
@@ -0,0 +1 @@
+ALTER TABLE dataExports DROP COLUMN IF EXISTS expiresAt;
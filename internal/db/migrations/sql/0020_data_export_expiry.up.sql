@@ -0,0 +1,2 @@
+ALTER TABLE dataExports ADD COLUMN IF NOT EXISTS expiresAt bigint NOT NULL DEFAULT 0;
+UPDATE dataExports SET expiresAt = createdAt + 604800 WHERE status = 1;
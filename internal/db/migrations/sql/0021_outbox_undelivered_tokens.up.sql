@@ -0,0 +1 @@
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS tokens text[] NOT NULL DEFAULT '{}';
@@ -6,32 +6,32 @@ import (
 )
 
 type DbMock struct {
-	QueryImpl    func(query string, args ...any) (*sql.Rows, error)
-	QueryRowImpl func(query string, args ...any) *sql.Row
-	ExecImpl     func(query string, args ...any) (sql.Result, error)
-	BeginTxImpl  func(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
-	PrepareImpl  func(query string) (*sql.Stmt, error)
-	CloseImpl    func() error
+	QueryContextImpl    func(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContextImpl func(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContextImpl     func(ctx context.Context, query string, args ...any) (sql.Result, error)
+	BeginTxImpl         func(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	PrepareContextImpl  func(ctx context.Context, query string) (*sql.Stmt, error)
+	CloseImpl           func() error
 }
 
-func (c *DbMock) Query(query string, args ...any) (*sql.Rows, error) {
-	return c.QueryImpl(query, args...)
+func (c *DbMock) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.QueryContextImpl(ctx, query, args...)
 }
 
-func (c *DbMock) QueryRow(query string, args ...any) *sql.Row {
-	return c.QueryRowImpl(query, args...)
+func (c *DbMock) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.QueryRowContextImpl(ctx, query, args...)
 }
 
-func (c *DbMock) Exec(query string, args ...any) (sql.Result, error) {
-	return c.ExecImpl(query, args...)
+func (c *DbMock) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.ExecContextImpl(ctx, query, args...)
 }
 
 func (c *DbMock) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	return c.BeginTxImpl(ctx, opts)
 }
 
-func (c *DbMock) Prepare(query string) (*sql.Stmt, error) {
-	return c.PrepareImpl(query)
+func (c *DbMock) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.PrepareContextImpl(ctx, query)
 }
 
 func (c *DbMock) Close() error {
@@ -0,0 +1,15 @@
+package db_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+)
+
+type UnitOfWorkMock struct {
+	RunImpl func(ctx context.Context, work func(tx db.DB) error) error
+}
+
+func (c *UnitOfWorkMock) Run(ctx context.Context, work func(tx db.DB) error) error {
+	return c.RunImpl(ctx, work)
+}
@@ -1,11 +1,14 @@
 package postgresDb
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
-	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/metrics"
 
 	_ "github.com/lib/pq"
 )
@@ -16,22 +19,163 @@ type PostgresConfig struct {
 	User     string `json:"user"`
 	Password string `json:"password"`
 	DbName   string `json:"dbName"`
+
+	SslMode     string `json:"sslMode"`
+	SslRootCert string `json:"sslRootCert"`
+	SslCert     string `json:"sslCert"`
+	SslKey      string `json:"sslKey"`
+
+	MaxOpenConnections       int `json:"maxOpenConnections"`
+	MaxIdleConnections       int `json:"maxIdleConnections"`
+	ConnectionMaxLifetimeSec int `json:"connectionMaxLifetimeSec"`
+	ConnectionMaxIdleTimeSec int `json:"connectionMaxIdleTimeSec"`
+	ConnectTimeoutSec        int `json:"connectTimeoutSec"`
+	StatementTimeoutMs       int `json:"statementTimeoutMs"`
+
+	PingAttempts  int `json:"pingAttempts"`
+	PingBackoffMs int `json:"pingBackoffMs"`
+
+	Replicas []PostgresConfig `json:"replicas"`
 }
 
-func Postgres(config PostgresConfig, logger logging.Service) (db.DB, error) {
-	const op = "repositories.friends.PostgresRepository"
-	psqlConnection := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		config.Host,
-		config.Port,
-		config.User,
-		config.Password,
-		config.DbName,
-	)
-	db, err := sql.Open("postgres", psqlConnection)
+func Postgres(config PostgresConfig, logger logging.Service) (*sql.DB, error) {
+	const op = "db.postgres.Postgres"
+	db, err := sql.Open("postgres", ConnectionString(config))
 	if err != nil {
 		logger.LogInfo("%s: open db failed err: %v", op, err)
 		return nil, err
 	}
+	db.SetMaxOpenConns(config.MaxOpenConnections)
+	if config.MaxIdleConnections != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConnections)
+	}
+	db.SetConnMaxLifetime(time.Second * time.Duration(config.ConnectionMaxLifetimeSec))
+	db.SetConnMaxIdleTime(time.Second * time.Duration(config.ConnectionMaxIdleTimeSec))
+	if err := ping(db, config, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
+
+func ping(db *sql.DB, config PostgresConfig, logger logging.Service) error {
+	const op = "db.postgres.ping"
+	if config.PingAttempts <= 0 {
+		return nil
+	}
+	backoff := time.Millisecond * time.Duration(config.PingBackoffMs)
+	var err error
+	for attempt := 1; attempt <= config.PingAttempts; attempt++ {
+		if err = db.PingContext(context.Background()); err == nil {
+			logger.LogInfo("%s: success[attempt=%d]", op, attempt)
+			return nil
+		}
+		logger.LogInfo("%s: attempt %d/%d failed err: %v", op, attempt, config.PingAttempts, err)
+		if attempt < config.PingAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("postgres is unreachable after %d attempts: %w", config.PingAttempts, err)
+}
+
+func ConnectionString(config PostgresConfig) string {
+	sslMode := config.SslMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	parameters := []string{
+		parameter("host", config.Host),
+		parameter("port", fmt.Sprint(config.Port)),
+		parameter("user", config.User),
+		parameter("password", config.Password),
+		parameter("dbname", config.DbName),
+		parameter("sslmode", sslMode),
+	}
+	for _, optional := range []struct {
+		key   string
+		value string
+	}{
+		{"sslrootcert", config.SslRootCert},
+		{"sslcert", config.SslCert},
+		{"sslkey", config.SslKey},
+	} {
+		if optional.value != "" {
+			parameters = append(parameters, parameter(optional.key, optional.value))
+		}
+	}
+	if config.ConnectTimeoutSec > 0 {
+		parameters = append(parameters, parameter("connect_timeout", fmt.Sprint(config.ConnectTimeoutSec)))
+	}
+	if config.StatementTimeoutMs > 0 {
+		parameters = append(parameters, parameter("statement_timeout", fmt.Sprint(config.StatementTimeoutMs)))
+	}
+	return strings.Join(parameters, " ")
+}
+
+func parameter(key string, value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return fmt.Sprintf("%s='%s'", key, escaped)
+}
+
+func StatsCollector(db *sql.DB) metrics.Collector {
+	return func() []metrics.Metric {
+		stats := db.Stats()
+		return []metrics.Metric{
+			{
+				Name:  "db_max_open_connections",
+				Help:  "Maximum number of open connections to the database.",
+				Type:  metrics.TypeGauge,
+				Value: float64(stats.MaxOpenConnections),
+			},
+			{
+				Name:  "db_open_connections",
+				Help:  "The number of established connections both in use and idle.",
+				Type:  metrics.TypeGauge,
+				Value: float64(stats.OpenConnections),
+			},
+			{
+				Name:  "db_in_use_connections",
+				Help:  "The number of connections currently in use.",
+				Type:  metrics.TypeGauge,
+				Value: float64(stats.InUse),
+			},
+			{
+				Name:  "db_idle_connections",
+				Help:  "The number of idle connections.",
+				Type:  metrics.TypeGauge,
+				Value: float64(stats.Idle),
+			},
+			{
+				Name:  "db_wait_count_total",
+				Help:  "The total number of connections waited for.",
+				Type:  metrics.TypeCounter,
+				Value: float64(stats.WaitCount),
+			},
+			{
+				Name:  "db_wait_duration_seconds_total",
+				Help:  "The total time blocked waiting for a new connection.",
+				Type:  metrics.TypeCounter,
+				Value: stats.WaitDuration.Seconds(),
+			},
+			{
+				Name:  "db_max_idle_closed_total",
+				Help:  "The total number of connections closed due to SetMaxIdleConns.",
+				Type:  metrics.TypeCounter,
+				Value: float64(stats.MaxIdleClosed),
+			},
+			{
+				Name:  "db_max_idle_time_closed_total",
+				Help:  "The total number of connections closed due to SetConnMaxIdleTime.",
+				Type:  metrics.TypeCounter,
+				Value: float64(stats.MaxIdleTimeClosed),
+			},
+			{
+				Name:  "db_max_lifetime_closed_total",
+				Help:  "The total number of connections closed due to SetConnMaxLifetime.",
+				Type:  metrics.TypeCounter,
+				Value: float64(stats.MaxLifetimeClosed),
+			},
+		}
+	}
+}
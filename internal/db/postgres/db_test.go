@@ -0,0 +1,39 @@
+package postgresDb_test
+
+import (
+	"testing"
+
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+)
+
+func TestConnectionStringDefaults(t *testing.T) {
+	connection := postgresDb.ConnectionString(postgresDb.PostgresConfig{
+		Host:     "localhost",
+		Port:     5432,
+		User:     "root",
+		Password: "pwd",
+		DbName:   "verni",
+	})
+	expected := `host='localhost' port='5432' user='root' password='pwd' dbname='verni' sslmode='disable'`
+	if connection != expected {
+		t.Fatalf("connection string should be %s, found %s", expected, connection)
+	}
+}
+
+func TestConnectionStringOptions(t *testing.T) {
+	connection := postgresDb.ConnectionString(postgresDb.PostgresConfig{
+		Host:               "db.example.com",
+		Port:               5432,
+		User:               "root",
+		Password:           `it's a \secret`,
+		DbName:             "verni",
+		SslMode:            "verify-full",
+		SslRootCert:        "/certs/root.crt",
+		ConnectTimeoutSec:  5,
+		StatementTimeoutMs: 3000,
+	})
+	expected := `host='db.example.com' port='5432' user='root' password='it\'s a \\secret' dbname='verni' sslmode='verify-full' sslrootcert='/certs/root.crt' connect_timeout='5' statement_timeout='3000'`
+	if connection != expected {
+		t.Fatalf("connection string should be %s, found %s", expected, connection)
+	}
+}
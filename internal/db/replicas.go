@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+)
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary marks ctx so that reads made with it are served by the primary, e.g. to observe
+// a write made right before that replicas may not have caught up with yet.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+func IsPrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return forced
+}
+
+// Reader returns a database suitable for read-only queries: one of the replicas when database
+// was created by NewReplicated, otherwise database itself. Transactions always read from themselves.
+func Reader(ctx context.Context, database DB) DB {
+	replicated, ok := database.(*replicated)
+	if !ok || IsPrimaryForced(ctx) {
+		return database
+	}
+	return replicated.replica()
+}
+
+// NewReplicated returns a DB that sends every query to primary, while Reader picks one of
+// replicas in round-robin order.
+func NewReplicated(primary DB, replicas []DB) DB {
+	if len(replicas) == 0 {
+		return primary
+	}
+	return &replicated{
+		primary:  primary,
+		replicas: replicas,
+	}
+}
+
+type replicated struct {
+	primary  DB
+	replicas []DB
+	next     atomic.Uint64
+}
+
+func (c *replicated) replica() DB {
+	return c.replicas[(c.next.Add(1)-1)%uint64(len(c.replicas))]
+}
+
+func (c *replicated) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.primary.QueryContext(ctx, query, args...)
+}
+
+func (c *replicated) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.primary.QueryRowContext(ctx, query, args...)
+}
+
+func (c *replicated) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+func (c *replicated) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.primary.BeginTx(ctx, opts)
+}
+
+func (c *replicated) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.primary.PrepareContext(ctx, query)
+}
+
+func (c *replicated) Close() error {
+	errs := []error{c.primary.Close()}
+	for _, replica := range c.replicas {
+		errs = append(errs, replica.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,83 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/rzmn/governi/internal/db"
+	db_mock "github.com/rzmn/governi/internal/db/mock"
+)
+
+func queryMock(name string, queried *[]string) *db_mock.DbMock {
+	return &db_mock.DbMock{
+		QueryContextImpl: func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+			*queried = append(*queried, name)
+			return nil, nil
+		},
+	}
+}
+
+func TestReaderRoundRobin(t *testing.T) {
+	queried := []string{}
+	database := db.NewReplicated(queryMock("primary", &queried), []db.DB{
+		queryMock("replica1", &queried),
+		queryMock("replica2", &queried),
+	})
+	for i := 0; i < 3; i++ {
+		db.Reader(context.Background(), database).QueryContext(context.Background(), "")
+	}
+	database.QueryContext(context.Background(), "")
+	expected := []string{"replica1", "replica2", "replica1", "primary"}
+	if len(queried) != len(expected) {
+		t.Fatalf("queried should be %v, found %v", expected, queried)
+	}
+	for i := range expected {
+		if queried[i] != expected[i] {
+			t.Fatalf("queried should be %v, found %v", expected, queried)
+		}
+	}
+}
+
+func TestReaderForcePrimary(t *testing.T) {
+	queried := []string{}
+	database := db.NewReplicated(queryMock("primary", &queried), []db.DB{
+		queryMock("replica", &queried),
+	})
+	ctx := db.ForcePrimary(context.Background())
+	db.Reader(ctx, database).QueryContext(ctx, "")
+	if len(queried) != 1 || queried[0] != "primary" {
+		t.Fatalf("queried should be [primary], found %v", queried)
+	}
+}
+
+func TestReaderWithoutReplicas(t *testing.T) {
+	primary := &db_mock.DbMock{}
+	if db.NewReplicated(primary, nil) != db.DB(primary) {
+		t.Fatalf("database without replicas should be the primary itself")
+	}
+	if db.Reader(context.Background(), primary) != db.DB(primary) {
+		t.Fatalf("reader of a plain database should be the database itself")
+	}
+}
+
+func TestReplicatedClose(t *testing.T) {
+	closed := 0
+	closeMock := func(err error) *db_mock.DbMock {
+		return &db_mock.DbMock{
+			CloseImpl: func() error {
+				closed += 1
+				return err
+			},
+		}
+	}
+	replicaErr := errors.New("replica close failed")
+	database := db.NewReplicated(closeMock(nil), []db.DB{closeMock(replicaErr), closeMock(nil)})
+	if err := database.Close(); !errors.Is(err, replicaErr) {
+		t.Fatalf("close should report replica error, found %v", err)
+	}
+	if closed != 3 {
+		t.Fatalf("all connections should be closed, closed %d", closed)
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrNestedTransaction = errors.New("nested transactions are not supported")
+
+type UnitOfWork interface {
+	Run(ctx context.Context, work func(tx DB) error) error
+}
+
+func NewUnitOfWork(database DB) UnitOfWork {
+	return &unitOfWork{
+		database: database,
+	}
+}
+
+type unitOfWork struct {
+	database DB
+}
+
+func (c *unitOfWork) Run(ctx context.Context, work func(tx DB) error) error {
+	return Transact(ctx, c.database, work)
+}
+
+func Transact(ctx context.Context, database DB, work func(tx DB) error) error {
+	if tx, ok := database.(*transaction); ok {
+		return work(tx)
+	}
+	sqlTx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := work(&transaction{tx: sqlTx}); err != nil {
+		if rollbackErr := sqlTx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+type transaction struct {
+	tx *sql.Tx
+}
+
+func (c *transaction) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.tx.QueryContext(ctx, query, args...)
+}
+
+func (c *transaction) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (c *transaction) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.tx.ExecContext(ctx, query, args...)
+}
+
+func (c *transaction) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return nil, ErrNestedTransaction
+}
+
+func (c *transaction) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.tx.PrepareContext(ctx, query)
+}
+
+func (c *transaction) Close() error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package defaultRepository
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/rzmn/governi/internal/db"
@@ -23,6 +24,10 @@ type defaultRepository struct {
 	logger logging.Service
 }
 
+func (c *defaultRepository) WithTx(tx db.DB) auth.Repository {
+	return New(tx, c.logger)
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
 	return string(bytes), err
@@ -32,22 +37,22 @@ func checkPasswordHash(password, hash string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
 
-func (c *defaultRepository) CreateUser(uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem {
+func (c *defaultRepository) CreateUser(ctx context.Context, uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem {
 	return repositories.MutationWorkItem{
 		Perform: func() error {
-			return c.createUser(uid, email, password, refreshToken)
+			return c.createUser(ctx, uid, email, password, refreshToken)
 		},
 		Rollback: func() error {
-			return c.deleteUser(uid)
+			return c.deleteUser(ctx, uid)
 		},
 	}
 }
 
-func (c *defaultRepository) CheckCredentials(email string, password string) (bool, error) {
+func (c *defaultRepository) CheckCredentials(ctx context.Context, email string, password string) (bool, error) {
 	const op = "repositories.auth.postgresRepository.CheckCredentials"
 	c.logger.LogInfo("%s: start[email=%s]", op, email)
 	query := `SELECT password FROM credentials WHERE email = $1;`
-	row := c.db.QueryRow(query, email)
+	row := c.db.QueryRowContext(ctx, query, email)
 	var passwordHash string
 	if err := row.Scan(&passwordHash); err != nil {
 		if err == sql.ErrNoRows {
@@ -62,11 +67,11 @@ func (c *defaultRepository) CheckCredentials(email string, password string) (boo
 	return checkPasswordHash(password, passwordHash), nil
 }
 
-func (c *defaultRepository) GetUserIdByEmail(email string) (*auth.UserId, error) {
+func (c *defaultRepository) GetUserIdByEmail(ctx context.Context, email string) (*auth.UserId, error) {
 	const op = "repositories.auth.postgresRepository.GetUserIdByEmail"
 	c.logger.LogInfo("%s: start[email=%s]", op, email)
 	query := `SELECT id FROM credentials WHERE email = $1;`
-	rows, err := c.db.Query(query, email)
+	rows, err := c.db.QueryContext(ctx, query, email)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return nil, err
@@ -93,33 +98,33 @@ func (c *defaultRepository) GetUserIdByEmail(email string) (*auth.UserId, error)
 	return nil, nil
 }
 
-func (c *defaultRepository) UpdateRefreshToken(uid auth.UserId, token string) repositories.MutationWorkItem {
+func (c *defaultRepository) UpdateRefreshToken(ctx context.Context, uid auth.UserId, token string) repositories.MutationWorkItem {
 	const op = "repositories.auth.postgresRepository.UpdateRefreshToken"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	existed, err := c.GetUserInfo(uid)
+	existed, err := c.GetUserInfo(ctx, uid)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, err)
 				return err
 			}
-			return c.updateRefreshToken(uid, token)
+			return c.updateRefreshToken(ctx, uid, token)
 		},
 		Rollback: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, err)
 				return err
 			}
-			return c.updateRefreshToken(uid, existed.RefreshToken)
+			return c.updateRefreshToken(ctx, uid, existed.RefreshToken)
 		},
 	}
 }
 
-func (c *defaultRepository) updateRefreshToken(uid auth.UserId, token string) error {
+func (c *defaultRepository) updateRefreshToken(ctx context.Context, uid auth.UserId, token string) error {
 	const op = "repositories.auth.postgresRepository.updateRefreshToken"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `UPDATE credentials SET token = $2 WHERE id = $1;`
-	_, err := c.db.Exec(query, string(uid), token)
+	_, err := c.db.ExecContext(ctx, query, string(uid), token)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -128,10 +133,10 @@ func (c *defaultRepository) updateRefreshToken(uid auth.UserId, token string) er
 	return nil
 }
 
-func (c *defaultRepository) UpdatePassword(uid auth.UserId, password string) repositories.MutationWorkItem {
+func (c *defaultRepository) UpdatePassword(ctx context.Context, uid auth.UserId, password string) repositories.MutationWorkItem {
 	const op = "repositories.auth.postgresRepository.UpdatePassword"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	existed, getCredentialsErr := c.GetUserInfo(uid)
+	existed, getCredentialsErr := c.GetUserInfo(ctx, uid)
 	passwordHash, hashPasswordErr := hashPassword(password)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
@@ -143,23 +148,23 @@ func (c *defaultRepository) UpdatePassword(uid auth.UserId, password string) rep
 				c.logger.LogInfo("%s: cannot hash password %v", op, hashPasswordErr)
 				return hashPasswordErr
 			}
-			return c.updatePassword(uid, passwordHash)
+			return c.updatePassword(ctx, uid, passwordHash)
 		},
 		Rollback: func() error {
 			if getCredentialsErr != nil {
 				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, getCredentialsErr)
 				return getCredentialsErr
 			}
-			return c.updatePassword(uid, existed.PasswordHash)
+			return c.updatePassword(ctx, uid, existed.PasswordHash)
 		},
 	}
 }
 
-func (c *defaultRepository) updatePassword(uid auth.UserId, passwordHash string) error {
+func (c *defaultRepository) updatePassword(ctx context.Context, uid auth.UserId, passwordHash string) error {
 	const op = "repositories.auth.postgresRepository.updatePassword"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `UPDATE credentials SET password = $2 WHERE id = $1;`
-	_, err := c.db.Exec(query, string(uid), passwordHash)
+	_, err := c.db.ExecContext(ctx, query, string(uid), passwordHash)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -168,28 +173,28 @@ func (c *defaultRepository) updatePassword(uid auth.UserId, passwordHash string)
 	return nil
 }
 
-func (c *defaultRepository) UpdateEmail(uid auth.UserId, newEmail string) repositories.MutationWorkItem {
+func (c *defaultRepository) UpdateEmail(ctx context.Context, uid auth.UserId, newEmail string) repositories.MutationWorkItem {
 	const op = "repositories.auth.postgresRepository.UpdateEmail"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	existed, err := c.GetUserInfo(uid)
+	existed, err := c.GetUserInfo(ctx, uid)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, err)
 				return err
 			}
-			return c.updateEmail(uid, newEmail)
+			return c.updateEmail(ctx, uid, newEmail)
 		},
 		Rollback: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, err)
 				return err
 			}
-			if err := c.updateEmail(uid, existed.Email); err != nil {
+			if err := c.updateEmail(ctx, uid, existed.Email); err != nil {
 				return err
 			}
 			if existed.EmailVerified {
-				if err := c.markUserEmailValidated(uid, true); err != nil {
+				if err := c.markUserEmailValidated(ctx, uid, true); err != nil {
 					return err
 				}
 			}
@@ -198,11 +203,11 @@ func (c *defaultRepository) UpdateEmail(uid auth.UserId, newEmail string) reposi
 	}
 }
 
-func (c *defaultRepository) updateEmail(uid auth.UserId, newEmail string) error {
+func (c *defaultRepository) updateEmail(ctx context.Context, uid auth.UserId, newEmail string) error {
 	const op = "repositories.auth.postgresRepository.updateEmail"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `UPDATE credentials SET email = $2, emailVerified = False WHERE id = $1;`
-	_, err := c.db.Exec(query, string(uid), newEmail)
+	_, err := c.db.ExecContext(ctx, query, string(uid), newEmail)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -211,11 +216,11 @@ func (c *defaultRepository) updateEmail(uid auth.UserId, newEmail string) error
 	return nil
 }
 
-func (c *defaultRepository) GetRefreshToken(uid auth.UserId) (string, error) {
+func (c *defaultRepository) GetRefreshToken(ctx context.Context, uid auth.UserId) (string, error) {
 	const op = "repositories.auth.postgresRepository.GetRefreshToken"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `SELECT token FROM credentials WHERE id = $1;`
-	row := c.db.QueryRow(query, string(uid))
+	row := c.db.QueryRowContext(ctx, query, string(uid))
 	var token string
 	if err := row.Scan(&token); err != nil {
 		c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
@@ -225,9 +230,9 @@ func (c *defaultRepository) GetRefreshToken(uid auth.UserId) (string, error) {
 	return token, nil
 }
 
-func (c *defaultRepository) MarkUserEmailValidated(uid auth.UserId) repositories.MutationWorkItem {
+func (c *defaultRepository) MarkUserEmailValidated(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
 	const op = "repositories.auth.postgresRepository.MarkUserEmailValidated"
-	existed, err := c.GetUserInfo(uid)
+	existed, err := c.GetUserInfo(ctx, uid)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
@@ -237,7 +242,7 @@ func (c *defaultRepository) MarkUserEmailValidated(uid auth.UserId) repositories
 			if existed.EmailVerified {
 				return nil
 			} else {
-				return c.markUserEmailValidated(uid, true)
+				return c.markUserEmailValidated(ctx, uid, true)
 			}
 		},
 		Rollback: func() error {
@@ -248,17 +253,17 @@ func (c *defaultRepository) MarkUserEmailValidated(uid auth.UserId) repositories
 			if existed.EmailVerified {
 				return nil
 			} else {
-				return c.markUserEmailValidated(uid, false)
+				return c.markUserEmailValidated(ctx, uid, false)
 			}
 		},
 	}
 }
 
-func (c *defaultRepository) markUserEmailValidated(uid auth.UserId, validated bool) error {
+func (c *defaultRepository) markUserEmailValidated(ctx context.Context, uid auth.UserId, validated bool) error {
 	const op = "repositories.auth.postgresRepository.markUserEmailValidated"
 	c.logger.LogInfo("%s: start[uid=%s validated=%t]", op, uid, validated)
 	query := `UPDATE credentials SET emailVerified = $2 WHERE id = $1;`
-	_, err := c.db.Exec(query, string(uid), validated)
+	_, err := c.db.ExecContext(ctx, query, string(uid), validated)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -267,7 +272,7 @@ func (c *defaultRepository) markUserEmailValidated(uid auth.UserId, validated bo
 	return nil
 }
 
-func (c *defaultRepository) createUser(uid auth.UserId, email string, password string, refreshToken string) error {
+func (c *defaultRepository) createUser(ctx context.Context, uid auth.UserId, email string, password string, refreshToken string) error {
 	const op = "repositories.auth.postgresRepository.createUser"
 	c.logger.LogInfo("%s: start[uid=%s email=%s]", op, uid, email)
 	passwordHash, err := hashPassword(password)
@@ -276,7 +281,7 @@ func (c *defaultRepository) createUser(uid auth.UserId, email string, password s
 		return err
 	}
 	query := `INSERT INTO credentials(id, email, password, token, emailVerified) VALUES($1, $2, $3, $4, False);`
-	_, err = c.db.Exec(query, string(uid), string(email), passwordHash, refreshToken)
+	_, err = c.db.ExecContext(ctx, query, string(uid), string(email), passwordHash, refreshToken)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -285,11 +290,11 @@ func (c *defaultRepository) createUser(uid auth.UserId, email string, password s
 	return nil
 }
 
-func (c *defaultRepository) deleteUser(uid auth.UserId) error {
+func (c *defaultRepository) deleteUser(ctx context.Context, uid auth.UserId) error {
 	const op = "repositories.auth.postgresRepository.deleteUser"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `DELETE FROM credentials WHERE id = $1;`
-	_, err := c.db.Exec(query, string(uid))
+	_, err := c.db.ExecContext(ctx, query, string(uid))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -298,11 +303,46 @@ func (c *defaultRepository) deleteUser(uid auth.UserId) error {
 	return nil
 }
 
-func (c *defaultRepository) GetUserInfo(uid auth.UserId) (auth.UserInfo, error) {
+func (c *defaultRepository) DeleteUser(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
+	const op = "repositories.auth.postgresRepository.DeleteUser"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	existed, err := c.GetUserInfo(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, err)
+				return err
+			}
+			return c.deleteUser(ctx, uid)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current credentals err: %v", op, err)
+				return err
+			}
+			return c.restoreUser(ctx, existed)
+		},
+	}
+}
+
+func (c *defaultRepository) restoreUser(ctx context.Context, info auth.UserInfo) error {
+	const op = "repositories.auth.postgresRepository.restoreUser"
+	c.logger.LogInfo("%s: start[uid=%s]", op, info.UserId)
+	query := `INSERT INTO credentials(id, email, password, token, emailVerified) VALUES($1, $2, $3, $4, $5);`
+	_, err := c.db.ExecContext(ctx, query, string(info.UserId), info.Email, info.PasswordHash, info.RefreshToken, info.EmailVerified)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, info.UserId)
+	return nil
+}
+
+func (c *defaultRepository) GetUserInfo(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
 	const op = "repositories.auth.postgresRepository.GetCredentials"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `SELECT email, password, token, emailVerified FROM credentials WHERE id = $1;`
-	row := c.db.QueryRow(query, string(uid))
+	row := c.db.QueryRowContext(ctx, query, string(uid))
 	result := auth.UserInfo{
 		UserId: auth.UserId(uid),
 	}
@@ -314,11 +354,11 @@ func (c *defaultRepository) GetUserInfo(uid auth.UserId) (auth.UserInfo, error)
 	return result, nil
 }
 
-func (c *defaultRepository) IsUserExists(uid auth.UserId) (bool, error) {
+func (c *defaultRepository) IsUserExists(ctx context.Context, uid auth.UserId) (bool, error) {
 	const op = "repositories.auth.postgresRepository.IsUserExists"
 	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
 	query := `SELECT EXISTS(SELECT 1 FROM credentials WHERE id = $1);`
-	row := c.db.QueryRow(query, string(uid))
+	row := c.db.QueryRowContext(ctx, query, string(uid))
 	var exists bool
 	if err := row.Scan(&exists); err != nil {
 		c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
@@ -1,6 +1,7 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
 	"github.com/rzmn/governi/internal/repositories/auth"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/auth/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/auth/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -19,30 +21,41 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() auth.Repository
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() auth.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() auth.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+	}
 	code := m.Run()
 
 	os.Exit(code)
@@ -57,21 +70,21 @@ func randomEmail() string {
 }
 
 func TestGetUserInfo(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	userInfo, err := repository.GetUserInfo(userId)
+	userInfo, err := repository.GetUserInfo(context.Background(), userId)
 	if err == nil {
 		t.Fatalf("[initial] expected to get error from `GetUserInfo` info, found nil")
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
-	userInfo, err = repository.GetUserInfo(userId)
+	userInfo, err = repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to get `userInfo` err: %v", err)
 	}
@@ -86,31 +99,53 @@ func TestGetUserInfo(t *testing.T) {
 	if err := createUserTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `createUserTransaction` err: %v", err)
 	}
-	userInfo, err = repository.GetUserInfo(userId)
+	userInfo, err = repository.GetUserInfo(context.Background(), userId)
 	if err == nil {
 		t.Fatalf("[after rollback] expected to get error from `GetUserInfo` info, found nil")
 	}
 }
 
+func TestCreateUserWithTakenEmail(t *testing.T) {
+	repository := newRepository()
+	userEmail := randomEmail()
+
+	createUserTransaction := repository.CreateUser(context.Background(), randomUid(), userEmail, uuid.New().String(), uuid.New().String())
+	if err := createUserTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
+	}
+	defer createUserTransaction.Rollback()
+	anotherUserId := randomUid()
+	if err := repository.CreateUser(context.Background(), anotherUserId, userEmail, uuid.New().String(), uuid.New().String()).Perform(); err == nil {
+		t.Fatalf("expected to get error when creating user with taken email, found nil")
+	}
+	exists, err := repository.IsUserExists(context.Background(), anotherUserId)
+	if err != nil {
+		t.Fatalf("failed to check if user exists err: %v", err)
+	}
+	if exists {
+		t.Fatalf("user with taken email should not be created")
+	}
+}
+
 func TestMarkUserEmailValidated(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	if err := repository.MarkUserEmailValidated(userId).Perform(); err == nil {
+	if err := repository.MarkUserEmailValidated(context.Background(), userId).Perform(); err == nil {
 		t.Fatalf("[initial] expected to get error from `MarkUserEmailValidated` info, found nil")
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
-	markValidatedTransaction := repository.MarkUserEmailValidated(userId)
+	markValidatedTransaction := repository.MarkUserEmailValidated(context.Background(), userId)
 	if err := markValidatedTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `markValidatedTransaction` err: %v", err)
 	}
-	userInfo, err := repository.GetUserInfo(userId)
+	userInfo, err := repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to get `userInfo` err: %v", err)
 	}
@@ -120,7 +155,7 @@ func TestMarkUserEmailValidated(t *testing.T) {
 	if err := markValidatedTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `markValidatedTransaction` err: %v", err)
 	}
-	userInfo, err = repository.GetUserInfo(userId)
+	userInfo, err = repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `userInfo` err: %v", err)
 	}
@@ -130,24 +165,24 @@ func TestMarkUserEmailValidated(t *testing.T) {
 }
 
 func TestIsUserExists(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	userExists, err := repository.IsUserExists(userId)
+	userExists, err := repository.IsUserExists(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("[initial] failed to get `userExists` err: %v", err)
 	}
 	if userExists {
 		t.Fatalf("[initial] `userExists` should be false")
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
-	userExists, err = repository.IsUserExists(userId)
+	userExists, err = repository.IsUserExists(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to get `userExists` err: %v", err)
 	}
@@ -157,7 +192,7 @@ func TestIsUserExists(t *testing.T) {
 	if err := createUserTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `createUserTransaction` err: %v", err)
 	}
-	userExists, err = repository.IsUserExists(userId)
+	userExists, err = repository.IsUserExists(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `userExists` err: %v", err)
 	}
@@ -167,24 +202,24 @@ func TestIsUserExists(t *testing.T) {
 }
 
 func TestCheckCredentials(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	passed, err := repository.CheckCredentials(userEmail, userPassword)
+	passed, err := repository.CheckCredentials(context.Background(), userEmail, userPassword)
 	if err != nil {
 		t.Fatalf("[initial] failed to perform `CheckCredentials`, err: %v", err)
 	}
 	if passed {
 		t.Fatalf("`CheckCredentials` should return false, arg %v", userPassword)
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
-	passed, err = repository.CheckCredentials(userEmail, userPassword)
+	passed, err = repository.CheckCredentials(context.Background(), userEmail, userPassword)
 	if err != nil {
 		t.Fatalf("failed to run `CheckCredentials` err: %v", err)
 	}
@@ -192,7 +227,7 @@ func TestCheckCredentials(t *testing.T) {
 		t.Fatalf("`CheckCredentials` should be true, arg: %v", userPassword)
 	}
 	wrongPassword := uuid.New().String()
-	shouldNotPass, err := repository.CheckCredentials(userEmail, wrongPassword)
+	shouldNotPass, err := repository.CheckCredentials(context.Background(), userEmail, wrongPassword)
 	if err != nil {
 		t.Fatalf("failed to get `shouldNotPass` err: %v", err)
 	}
@@ -202,24 +237,24 @@ func TestCheckCredentials(t *testing.T) {
 }
 
 func TestGetUserIdByEmail(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	shouldBeNil, err := repository.GetUserIdByEmail(userEmail)
+	shouldBeNil, err := repository.GetUserIdByEmail(context.Background(), userEmail)
 	if err != nil {
 		t.Fatalf("[initial] failed to get `shouldBeNil` info, found nil")
 	}
 	if shouldBeNil != nil {
 		t.Fatalf("[initial] `shouldBeNil` should be nil, found %s", *shouldBeNil)
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
-	shouldBeEqualToUserId, err := repository.GetUserIdByEmail(userEmail)
+	shouldBeEqualToUserId, err := repository.GetUserIdByEmail(context.Background(), userEmail)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToUserId` err: %v", err)
 	}
@@ -232,7 +267,7 @@ func TestGetUserIdByEmail(t *testing.T) {
 	if err := createUserTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `createUserTransaction` err: %v", err)
 	}
-	shouldBeNil, err = repository.GetUserIdByEmail(userEmail)
+	shouldBeNil, err = repository.GetUserIdByEmail(context.Background(), userEmail)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `shouldBeNil` info, found nil")
 	}
@@ -242,25 +277,25 @@ func TestGetUserIdByEmail(t *testing.T) {
 }
 
 func TestUpdateRefreshToken(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	if err := repository.UpdateRefreshToken(userId, uuid.New().String()).Perform(); err == nil {
+	if err := repository.UpdateRefreshToken(context.Background(), userId, uuid.New().String()).Perform(); err == nil {
 		t.Fatalf("[initial] expected to get error from `UpdateRefreshToken` info, found nil")
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
 	newRefreshToken := uuid.New().String()
-	updateRefreshTokenTransaction := repository.UpdateRefreshToken(userId, newRefreshToken)
+	updateRefreshTokenTransaction := repository.UpdateRefreshToken(context.Background(), userId, newRefreshToken)
 	if err := updateRefreshTokenTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `updateRefreshTokenTransaction` err: %v", err)
 	}
-	userInfo, err := repository.GetUserInfo(userId)
+	userInfo, err := repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to get `userInfo` err: %v", err)
 	}
@@ -270,7 +305,7 @@ func TestUpdateRefreshToken(t *testing.T) {
 	if err := updateRefreshTokenTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `updateRefreshTokenTransaction` err: %v", err)
 	}
-	userInfo, err = repository.GetUserInfo(userId)
+	userInfo, err = repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `userInfo` err: %v", err)
 	}
@@ -280,25 +315,25 @@ func TestUpdateRefreshToken(t *testing.T) {
 }
 
 func TestUpdatePassword(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	if err := repository.UpdatePassword(userId, uuid.New().String()).Perform(); err == nil {
+	if err := repository.UpdatePassword(context.Background(), userId, uuid.New().String()).Perform(); err == nil {
 		t.Fatalf("[initial] expected to get error from `UpdatePassword` info, found nil")
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
 	newPassword := uuid.New().String()
-	updatePasswordTransaction := repository.UpdatePassword(userId, newPassword)
+	updatePasswordTransaction := repository.UpdatePassword(context.Background(), userId, newPassword)
 	if err := updatePasswordTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `updatePasswordTransaction` err: %v", err)
 	}
-	shouldBeTrue, err := repository.CheckCredentials(userEmail, newPassword)
+	shouldBeTrue, err := repository.CheckCredentials(context.Background(), userEmail, newPassword)
 	if err != nil {
 		t.Fatalf("failed to perform `CheckCredentials` err: %v", err)
 	}
@@ -308,7 +343,7 @@ func TestUpdatePassword(t *testing.T) {
 	if err := updatePasswordTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `updateRefreshTokenTransaction` err: %v", err)
 	}
-	shouldBeTrue, err = repository.CheckCredentials(userEmail, userPassword)
+	shouldBeTrue, err = repository.CheckCredentials(context.Background(), userEmail, userPassword)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to perform `CheckCredentials` err: %v", err)
 	}
@@ -318,28 +353,28 @@ func TestUpdatePassword(t *testing.T) {
 }
 
 func TestUpdateEmail(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	userId := randomUid()
 	userEmail := randomEmail()
 	userToken := uuid.New().String()
 	userPassword := uuid.New().String()
 
-	if err := repository.UpdateEmail(userId, uuid.New().String()).Perform(); err == nil {
+	if err := repository.UpdateEmail(context.Background(), userId, uuid.New().String()).Perform(); err == nil {
 		t.Fatalf("[initial] expected to get error from `UpdateEmail` info, found nil")
 	}
-	createUserTransaction := repository.CreateUser(userId, userEmail, userPassword, userToken)
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, userToken)
 	if err := createUserTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
 	}
-	if err := repository.MarkUserEmailValidated(userId).Perform(); err != nil {
+	if err := repository.MarkUserEmailValidated(context.Background(), userId).Perform(); err != nil {
 		t.Fatalf("[initial] `MarkUserEmailValidated` failed err: %v", err)
 	}
 	newEmail := randomEmail()
-	updateEmailTransaction := repository.UpdateEmail(userId, newEmail)
+	updateEmailTransaction := repository.UpdateEmail(context.Background(), userId, newEmail)
 	if err := updateEmailTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `updateEmailTransaction` err: %v", err)
 	}
-	userInfo, err := repository.GetUserInfo(userId)
+	userInfo, err := repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to get `userInfo` err: %v", err)
 	}
@@ -349,7 +384,7 @@ func TestUpdateEmail(t *testing.T) {
 	if err := updateEmailTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `updateEmailTransaction` err: %v", err)
 	}
-	userInfo, err = repository.GetUserInfo(userId)
+	userInfo, err = repository.GetUserInfo(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `userInfo` err: %v", err)
 	}
@@ -357,3 +392,36 @@ func TestUpdateEmail(t *testing.T) {
 		t.Fatalf("[after rollback] userInfo should have old and verified email, found verified=%t email=%s", userInfo.EmailVerified, userInfo.Email)
 	}
 }
+
+func TestDeleteUser(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	userEmail := randomEmail()
+	userPassword := uuid.New().String()
+
+	createUserTransaction := repository.CreateUser(context.Background(), userId, userEmail, userPassword, uuid.New().String())
+	if err := createUserTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `createUserTransaction` err: %v", err)
+	}
+	deleteUserTransaction := repository.DeleteUser(context.Background(), userId)
+	if err := deleteUserTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `deleteUserTransaction` err: %v", err)
+	}
+	exists, err := repository.IsUserExists(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to check `exists` err: %v", err)
+	}
+	if exists {
+		t.Fatalf("user should not exist after deletion")
+	}
+	if err := deleteUserTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `deleteUserTransaction` err: %v", err)
+	}
+	passed, err := repository.CheckCredentials(context.Background(), userEmail, userPassword)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to check credentials err: %v", err)
+	}
+	if !passed {
+		t.Fatalf("[after rollback] credentials should be restored")
+	}
+}
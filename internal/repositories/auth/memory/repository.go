@@ -0,0 +1,278 @@
+package memoryRepository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/auth"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func New(logger logging.Service) auth.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			credentials: map[auth.UserId]auth.UserInfo{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex       sync.RWMutex
+	credentials map[auth.UserId]auth.UserInfo
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) auth.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func hashPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	return string(bytes), err
+}
+
+func checkPasswordHash(password, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (c *memoryRepository) CreateUser(ctx context.Context, uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem {
+	const op = "repositories.auth.memoryRepository.CreateUser"
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.logger.LogInfo("%s: start[uid=%s email=%s]", op, uid, email)
+			passwordHash, err := hashPassword(password)
+			if err != nil {
+				c.logger.LogInfo("%s: cannot hash password %v", op, err)
+				return err
+			}
+			if err := c.insert(auth.UserInfo{
+				UserId:       uid,
+				Email:        email,
+				PasswordHash: passwordHash,
+				RefreshToken: refreshToken,
+			}); err != nil {
+				c.logger.LogInfo("%s: failed to insert err: %v", op, err)
+				return err
+			}
+			c.logger.LogInfo("%s: success[uid=%s email=%s]", op, uid, email)
+			return nil
+		},
+		Rollback: func() error {
+			c.delete(uid)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) MarkUserEmailValidated(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
+	var existed auth.UserInfo
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.update(uid, func(info *auth.UserInfo) {
+				existed = *info
+				info.EmailVerified = true
+			})
+		},
+		Rollback: func() error {
+			return c.update(uid, func(info *auth.UserInfo) {
+				info.EmailVerified = existed.EmailVerified
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) IsUserExists(ctx context.Context, uid auth.UserId) (bool, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	_, ok := c.storage.credentials[uid]
+	return ok, nil
+}
+
+func (c *memoryRepository) CheckCredentials(ctx context.Context, email string, password string) (bool, error) {
+	info, ok := c.findByEmail(email)
+	if !ok {
+		return false, nil
+	}
+	return checkPasswordHash(password, info.PasswordHash), nil
+}
+
+func (c *memoryRepository) GetUserIdByEmail(ctx context.Context, email string) (*auth.UserId, error) {
+	info, ok := c.findByEmail(email)
+	if !ok {
+		return nil, nil
+	}
+	return &info.UserId, nil
+}
+
+func (c *memoryRepository) UpdateRefreshToken(ctx context.Context, uid auth.UserId, token string) repositories.MutationWorkItem {
+	var existed auth.UserInfo
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.update(uid, func(info *auth.UserInfo) {
+				existed = *info
+				info.RefreshToken = token
+			})
+		},
+		Rollback: func() error {
+			return c.update(uid, func(info *auth.UserInfo) {
+				info.RefreshToken = existed.RefreshToken
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) UpdatePassword(ctx context.Context, uid auth.UserId, newPassword string) repositories.MutationWorkItem {
+	const op = "repositories.auth.memoryRepository.UpdatePassword"
+	existed, getCredentialsErr := c.GetUserInfo(ctx, uid)
+	passwordHash, hashPasswordErr := hashPassword(newPassword)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if getCredentialsErr != nil {
+				return getCredentialsErr
+			}
+			if hashPasswordErr != nil {
+				c.logger.LogInfo("%s: cannot hash password %v", op, hashPasswordErr)
+				return hashPasswordErr
+			}
+			return c.update(uid, func(info *auth.UserInfo) {
+				info.PasswordHash = passwordHash
+			})
+		},
+		Rollback: func() error {
+			if getCredentialsErr != nil {
+				return getCredentialsErr
+			}
+			return c.update(uid, func(info *auth.UserInfo) {
+				info.PasswordHash = existed.PasswordHash
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) UpdateEmail(ctx context.Context, uid auth.UserId, newEmail string) repositories.MutationWorkItem {
+	existed, err := c.GetUserInfo(ctx, uid)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(uid, func(info *auth.UserInfo) {
+				info.Email = newEmail
+				info.EmailVerified = false
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(uid, func(info *auth.UserInfo) {
+				info.Email = existed.Email
+				info.EmailVerified = existed.EmailVerified
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) GetUserInfo(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	info, ok := c.storage.credentials[uid]
+	if !ok {
+		return auth.UserInfo{}, fmt.Errorf("no credentials for user %s", uid)
+	}
+	return info, nil
+}
+
+func (c *memoryRepository) DeleteUser(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
+	existed, err := c.GetUserInfo(ctx, uid)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			c.delete(uid)
+			return nil
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.insert(existed)
+		},
+	})
+}
+
+func (c *memoryRepository) insert(info auth.UserInfo) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if _, ok := c.storage.credentials[info.UserId]; ok {
+		return fmt.Errorf("credentials for user %s already exist", info.UserId)
+	}
+	if err := c.checkEmailIsFree(info); err != nil {
+		return err
+	}
+	c.storage.credentials[info.UserId] = info
+	return nil
+}
+
+func (c *memoryRepository) update(uid auth.UserId, modify func(info *auth.UserInfo)) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	info, ok := c.storage.credentials[uid]
+	if !ok {
+		return fmt.Errorf("no credentials for user %s", uid)
+	}
+	modify(&info)
+	if err := c.checkEmailIsFree(info); err != nil {
+		return err
+	}
+	c.storage.credentials[uid] = info
+	return nil
+}
+
+func (c *memoryRepository) checkEmailIsFree(info auth.UserInfo) error {
+	for uid, existing := range c.storage.credentials {
+		if uid != info.UserId && existing.Email == info.Email {
+			return fmt.Errorf("email %s is already taken", info.Email)
+		}
+	}
+	return nil
+}
+
+func (c *memoryRepository) delete(uid auth.UserId) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	delete(c.storage.credentials, uid)
+}
+
+func (c *memoryRepository) findByEmail(email string) (auth.UserInfo, bool) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	for _, info := range c.storage.credentials {
+		if info.Email == email {
+			return info, true
+		}
+	}
+	return auth.UserInfo{}, false
+}
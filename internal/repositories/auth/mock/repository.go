@@ -1,47 +1,59 @@
 package auth_mock
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 
 	"github.com/rzmn/governi/internal/repositories/auth"
 )
 
 type RepositoryMock struct {
-	CreateUserImpl             func(uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem
-	MarkUserEmailValidatedImpl func(uid auth.UserId) repositories.MutationWorkItem
-	IsUserExistsImpl           func(uid auth.UserId) (bool, error)
-	CheckCredentialsImpl       func(email string, password string) (bool, error)
-	GetUserIdByEmailImpl       func(email string) (*auth.UserId, error)
-	UpdateRefreshTokenImpl     func(uid auth.UserId, token string) repositories.MutationWorkItem
-	UpdatePasswordImpl         func(uid auth.UserId, newPassword string) repositories.MutationWorkItem
-	UpdateEmailImpl            func(uid auth.UserId, newEmail string) repositories.MutationWorkItem
-	GetUserInfoImpl            func(uid auth.UserId) (auth.UserInfo, error)
+	CreateUserImpl             func(ctx context.Context, uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem
+	MarkUserEmailValidatedImpl func(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem
+	IsUserExistsImpl           func(ctx context.Context, uid auth.UserId) (bool, error)
+	CheckCredentialsImpl       func(ctx context.Context, email string, password string) (bool, error)
+	GetUserIdByEmailImpl       func(ctx context.Context, email string) (*auth.UserId, error)
+	UpdateRefreshTokenImpl     func(ctx context.Context, uid auth.UserId, token string) repositories.MutationWorkItem
+	UpdatePasswordImpl         func(ctx context.Context, uid auth.UserId, newPassword string) repositories.MutationWorkItem
+	UpdateEmailImpl            func(ctx context.Context, uid auth.UserId, newEmail string) repositories.MutationWorkItem
+	GetUserInfoImpl            func(ctx context.Context, uid auth.UserId) (auth.UserInfo, error)
+	DeleteUserImpl             func(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem
 }
 
-func (c *RepositoryMock) CreateUser(uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem {
-	return c.CreateUserImpl(uid, email, password, refreshToken)
+func (c *RepositoryMock) CreateUser(ctx context.Context, uid auth.UserId, email string, password string, refreshToken string) repositories.MutationWorkItem {
+	return c.CreateUserImpl(ctx, uid, email, password, refreshToken)
+}
+func (c *RepositoryMock) MarkUserEmailValidated(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
+	return c.MarkUserEmailValidatedImpl(ctx, uid)
 }
-func (c *RepositoryMock) MarkUserEmailValidated(uid auth.UserId) repositories.MutationWorkItem {
-	return c.MarkUserEmailValidatedImpl(uid)
+func (c *RepositoryMock) IsUserExists(ctx context.Context, uid auth.UserId) (bool, error) {
+	return c.IsUserExistsImpl(ctx, uid)
 }
-func (c *RepositoryMock) IsUserExists(uid auth.UserId) (bool, error) {
-	return c.IsUserExistsImpl(uid)
+func (c *RepositoryMock) CheckCredentials(ctx context.Context, email string, password string) (bool, error) {
+	return c.CheckCredentialsImpl(ctx, email, password)
 }
-func (c *RepositoryMock) CheckCredentials(email string, password string) (bool, error) {
-	return c.CheckCredentialsImpl(email, password)
+func (c *RepositoryMock) GetUserIdByEmail(ctx context.Context, email string) (*auth.UserId, error) {
+	return c.GetUserIdByEmailImpl(ctx, email)
 }
-func (c *RepositoryMock) GetUserIdByEmail(email string) (*auth.UserId, error) {
-	return c.GetUserIdByEmailImpl(email)
+func (c *RepositoryMock) UpdateRefreshToken(ctx context.Context, uid auth.UserId, token string) repositories.MutationWorkItem {
+	return c.UpdateRefreshTokenImpl(ctx, uid, token)
 }
-func (c *RepositoryMock) UpdateRefreshToken(uid auth.UserId, token string) repositories.MutationWorkItem {
-	return c.UpdateRefreshTokenImpl(uid, token)
+func (c *RepositoryMock) UpdatePassword(ctx context.Context, uid auth.UserId, newPassword string) repositories.MutationWorkItem {
+	return c.UpdatePasswordImpl(ctx, uid, newPassword)
 }
-func (c *RepositoryMock) UpdatePassword(uid auth.UserId, newPassword string) repositories.MutationWorkItem {
-	return c.UpdatePasswordImpl(uid, newPassword)
+func (c *RepositoryMock) UpdateEmail(ctx context.Context, uid auth.UserId, newEmail string) repositories.MutationWorkItem {
+	return c.UpdateEmailImpl(ctx, uid, newEmail)
 }
-func (c *RepositoryMock) UpdateEmail(uid auth.UserId, newEmail string) repositories.MutationWorkItem {
-	return c.UpdateEmailImpl(uid, newEmail)
+func (c *RepositoryMock) GetUserInfo(ctx context.Context, uid auth.UserId) (auth.UserInfo, error) {
+	return c.GetUserInfoImpl(ctx, uid)
 }
-func (c *RepositoryMock) GetUserInfo(uid auth.UserId) (auth.UserInfo, error) {
-	return c.GetUserInfoImpl(uid)
+
+func (c *RepositoryMock) DeleteUser(ctx context.Context, uid auth.UserId) repositories.MutationWorkItem {
+	return c.DeleteUserImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) auth.Repository {
+	return c
 }
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
@@ -13,16 +16,19 @@ type UserInfo struct {
 	EmailVerified bool
 }
 type Repository interface {
-	CreateUser(uid UserId, email string, password string, refreshToken string) repositories.MutationWorkItem
-	MarkUserEmailValidated(uid UserId) repositories.MutationWorkItem
-	IsUserExists(uid UserId) (bool, error)
+	CreateUser(ctx context.Context, uid UserId, email string, password string, refreshToken string) repositories.MutationWorkItem
+	MarkUserEmailValidated(ctx context.Context, uid UserId) repositories.MutationWorkItem
+	IsUserExists(ctx context.Context, uid UserId) (bool, error)
+
+	CheckCredentials(ctx context.Context, email string, password string) (bool, error)
+	GetUserIdByEmail(ctx context.Context, email string) (*UserId, error)
 
-	CheckCredentials(email string, password string) (bool, error)
-	GetUserIdByEmail(email string) (*UserId, error)
+	UpdateRefreshToken(ctx context.Context, uid UserId, token string) repositories.MutationWorkItem
+	UpdatePassword(ctx context.Context, uid UserId, newPassword string) repositories.MutationWorkItem
+	UpdateEmail(ctx context.Context, uid UserId, newEmail string) repositories.MutationWorkItem
 
-	UpdateRefreshToken(uid UserId, token string) repositories.MutationWorkItem
-	UpdatePassword(uid UserId, newPassword string) repositories.MutationWorkItem
-	UpdateEmail(uid UserId, newEmail string) repositories.MutationWorkItem
+	GetUserInfo(ctx context.Context, uid UserId) (UserInfo, error)
+	DeleteUser(ctx context.Context, uid UserId) repositories.MutationWorkItem
 
-	GetUserInfo(uid UserId) (UserInfo, error)
+	WithTx(tx db.DB) Repository
 }
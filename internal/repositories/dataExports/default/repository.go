@@ -0,0 +1,313 @@
+package defaultRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/dataExports"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(db db.DB, logger logging.Service) dataExports.Repository {
+	return &defaultRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type defaultRepository struct {
+	db     db.DB
+	logger logging.Service
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) dataExports.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) StoreExport(ctx context.Context, export dataExports.Export) repositories.MutationWorkItem {
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.storeExport(ctx, export)
+		},
+		Rollback: func() error {
+			return c.removeExport(ctx, export.Id)
+		},
+	}
+}
+
+func (c *defaultRepository) storeExport(ctx context.Context, export dataExports.Export) error {
+	const op = "repositories.dataExports.postgresRepository.storeExport"
+	c.logger.LogInfo("%s: start[id=%s uid=%s]", op, export.Id, export.UserId)
+	query := `INSERT INTO dataExports(id, uid, status, createdAt, startedAt, expiresAt, archive) VALUES ($1, $2, $3, $4, $5, $6, NULL);`
+	_, err := c.db.ExecContext(ctx, query, string(export.Id), string(export.UserId), int(export.Status), export.CreatedAt, export.StartedAt, export.ExpiresAt)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s uid=%s]", op, export.Id, export.UserId)
+	return nil
+}
+
+func (c *defaultRepository) removeExport(ctx context.Context, id dataExports.ExportId) error {
+	const op = "repositories.dataExports.postgresRepository.removeExport"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `DELETE FROM dataExports WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(id))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
+
+func (c *defaultRepository) CompleteExport(ctx context.Context, id dataExports.ExportId, archive []byte, expiresAt int64) repositories.MutationWorkItem {
+	return c.finishExport(ctx, id, dataExports.StatusReady, archive, expiresAt)
+}
+
+func (c *defaultRepository) FailExport(ctx context.Context, id dataExports.ExportId) repositories.MutationWorkItem {
+	return c.finishExport(ctx, id, dataExports.StatusFailed, nil, 0)
+}
+
+func (c *defaultRepository) finishExport(ctx context.Context, id dataExports.ExportId, status dataExports.Status, archive []byte, expiresAt int64) repositories.MutationWorkItem {
+	const op = "repositories.dataExports.postgresRepository.finishExport"
+	existed, err := c.GetExport(ctx, id)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current export err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				err := errors.New("no such export exists")
+				c.logger.LogInfo("%s: failed to get current export err: %v", op, err)
+				return err
+			}
+			return c.updateExport(ctx, id, status, archive, expiresAt)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current export err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				err := errors.New("no such export exists")
+				c.logger.LogInfo("%s: failed to get current export err: %v", op, err)
+				return err
+			}
+			return c.updateExport(ctx, id, existed.Status, nil, existed.ExpiresAt)
+		},
+	}
+}
+
+func (c *defaultRepository) FailStaleExport(ctx context.Context, id dataExports.ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	var failed bool
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: func() (bool, error) {
+			var err error
+			failed, err = c.failStaleExport(ctx, id, startedBefore)
+			return failed, err
+		},
+		Rollback: func() error {
+			if !failed {
+				return nil
+			}
+			return c.updateExport(ctx, id, dataExports.StatusPending, nil, 0)
+		},
+	}
+}
+
+func (c *defaultRepository) failStaleExport(ctx context.Context, id dataExports.ExportId, startedBefore int64) (bool, error) {
+	const op = "repositories.dataExports.postgresRepository.failStaleExport"
+	c.logger.LogInfo("%s: start[id=%s startedBefore=%d]", op, id, startedBefore)
+	query := `UPDATE dataExports SET status = $2 WHERE id = $1 AND status = $3 AND startedAt < $4;`
+	result, err := c.db.ExecContext(ctx, query, string(id), int(dataExports.StatusFailed), int(dataExports.StatusPending), startedBefore)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get affected rows err: %v", op, err)
+		return false, err
+	}
+	c.logger.LogInfo("%s: success[id=%s failed=%t]", op, id, updated > 0)
+	return updated > 0, nil
+}
+
+type exportRecord struct {
+	export  dataExports.Export
+	archive []byte
+}
+
+func (c *defaultRepository) RemoveExports(ctx context.Context, uid dataExports.UserId) repositories.MutationWorkItem {
+	const op = "repositories.dataExports.postgresRepository.RemoveExports"
+	existed, err := c.getExportRecords(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current exports err: %v", op, err)
+				return err
+			}
+			return c.removeExports(ctx, uid)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current exports err: %v", op, err)
+				return err
+			}
+			for _, record := range existed {
+				if err := c.storeExport(ctx, record.export); err != nil {
+					return err
+				}
+				if err := c.updateExport(ctx, record.export.Id, record.export.Status, record.archive, record.export.ExpiresAt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (c *defaultRepository) removeExports(ctx context.Context, uid dataExports.UserId) error {
+	const op = "repositories.dataExports.postgresRepository.removeExports"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `DELETE FROM dataExports WHERE uid = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
+
+func (c *defaultRepository) getExportRecords(ctx context.Context, uid dataExports.UserId) ([]exportRecord, error) {
+	const op = "repositories.dataExports.postgresRepository.getExportRecords"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT id, status, createdAt, startedAt, expiresAt, archive FROM dataExports WHERE uid = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	records := []exportRecord{}
+	for rows.Next() {
+		record := exportRecord{
+			export: dataExports.Export{
+				UserId: uid,
+			},
+		}
+		var id string
+		var status int
+		if err := rows.Scan(&id, &status, &record.export.CreatedAt, &record.export.StartedAt, &record.export.ExpiresAt, &record.archive); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		record.export.Id = dataExports.ExportId(id)
+		record.export.Status = dataExports.Status(status)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return records, nil
+}
+
+func (c *defaultRepository) updateExport(ctx context.Context, id dataExports.ExportId, status dataExports.Status, archive []byte, expiresAt int64) error {
+	const op = "repositories.dataExports.postgresRepository.updateExport"
+	c.logger.LogInfo("%s: start[id=%s status=%d archive len=%d]", op, id, status, len(archive))
+	query := `UPDATE dataExports SET status = $2, archive = $3, expiresAt = $4 WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(id), int(status), archive, expiresAt)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s status=%d archive len=%d]", op, id, status, len(archive))
+	return nil
+}
+
+func (c *defaultRepository) GetExport(ctx context.Context, id dataExports.ExportId) (*dataExports.Export, error) {
+	const op = "repositories.dataExports.postgresRepository.GetExport"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `SELECT uid, status, createdAt, startedAt, expiresAt FROM dataExports WHERE id = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(id))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		export := dataExports.Export{
+			Id: id,
+		}
+		var uid string
+		var status int
+		if err := rows.Scan(&uid, &status, &export.CreatedAt, &export.StartedAt, &export.ExpiresAt); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		if err := rows.Err(); err != nil {
+			c.logger.LogInfo("%s: found rows err: %v", op, err)
+			return nil, err
+		}
+		export.UserId = dataExports.UserId(uid)
+		export.Status = dataExports.Status(status)
+		c.logger.LogInfo("%s: success[id=%s]", op, id)
+		return &export, nil
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil, nil
+}
+
+func (c *defaultRepository) GetArchive(ctx context.Context, id dataExports.ExportId) ([]byte, error) {
+	const op = "repositories.dataExports.postgresRepository.GetArchive"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `SELECT archive FROM dataExports WHERE id = $1 AND archive IS NOT NULL;`
+	rows, err := c.db.QueryContext(ctx, query, string(id))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	var archive []byte
+	if rows.Next() {
+		if err := rows.Scan(&archive); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return archive, nil
+}
+
+func (c *defaultRepository) RemoveExpiredExports(ctx context.Context, expiredBefore int64) error {
+	const op = "repositories.dataExports.postgresRepository.RemoveExpiredExports"
+	c.logger.LogInfo("%s: start[expiredBefore=%d]", op, expiredBefore)
+	query := `DELETE FROM dataExports WHERE status = $1 AND expiresAt < $2;`
+	result, err := c.db.ExecContext(ctx, query, int(dataExports.StatusReady), expiredBefore)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get affected rows err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[removed=%d]", op, removed)
+	return nil
+}
@@ -0,0 +1,319 @@
+package defaultRepository_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/dataExports"
+	defaultRepository "github.com/rzmn/governi/internal/repositories/dataExports/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/dataExports/memory"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+
+	"github.com/google/uuid"
+)
+
+var (
+	newRepository func() dataExports.Repository
+)
+
+func TestMain(m *testing.M) {
+	logger := standartOutputLoggingService.New()
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() dataExports.Repository {
+			return defaultRepository.New(database, logger)
+		}
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() dataExports.Repository {
+			return repository
+		}
+	}
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func randomExport() dataExports.Export {
+	return dataExports.Export{
+		Id:        dataExports.ExportId(uuid.New().String()),
+		UserId:    dataExports.UserId(uuid.New().String()),
+		Status:    dataExports.StatusPending,
+		CreatedAt: 123,
+		StartedAt: 123,
+	}
+}
+
+func TestStoreExport(t *testing.T) {
+	repository := newRepository()
+	export := randomExport()
+
+	shouldBeNil, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeNil` err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("`shouldBeNil` should be nil, found %v", *shouldBeNil)
+	}
+	storeTransaction := repository.StoreExport(context.Background(), export)
+	if err := storeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
+	}
+	shouldBeEqualToExport, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEqualToExport` err: %v", err)
+	}
+	if shouldBeEqualToExport == nil || *shouldBeEqualToExport != export {
+		t.Fatalf("`shouldBeEqualToExport` should be equal to %v, found %v", export, shouldBeEqualToExport)
+	}
+	if err := storeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `storeTransaction` err: %v", err)
+	}
+	shouldBeNil, err = repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `shouldBeNil` err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("[after rollback] `shouldBeNil` should be nil, found %v", *shouldBeNil)
+	}
+}
+
+func TestCompleteExport(t *testing.T) {
+	repository := newRepository()
+	export := randomExport()
+	archive := []byte(uuid.New().String())
+
+	if err := repository.StoreExport(context.Background(), export).Perform(); err != nil {
+		t.Fatalf("failed to store export err: %v", err)
+	}
+	shouldBeEmpty, err := repository.GetArchive(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
+	}
+	if shouldBeEmpty != nil {
+		t.Fatalf("`shouldBeEmpty` should be nil, found %v", shouldBeEmpty)
+	}
+	completeTransaction := repository.CompleteExport(context.Background(), export.Id, archive, 456)
+	if err := completeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `completeTransaction` err: %v", err)
+	}
+	completed, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `completed` err: %v", err)
+	}
+	if completed == nil || completed.Status != dataExports.StatusReady || completed.ExpiresAt != 456 {
+		t.Fatalf("`completed` should be ready until 456, found %v", completed)
+	}
+	shouldBeEqualToArchive, err := repository.GetArchive(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEqualToArchive` err: %v", err)
+	}
+	if string(shouldBeEqualToArchive) != string(archive) {
+		t.Fatalf("`shouldBeEqualToArchive` should be equal to %s, found %s", archive, shouldBeEqualToArchive)
+	}
+	if err := completeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `completeTransaction` err: %v", err)
+	}
+	pending, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `pending` err: %v", err)
+	}
+	if pending == nil || pending.Status != dataExports.StatusPending || pending.ExpiresAt != 0 {
+		t.Fatalf("[after rollback] `pending` should be pending, found %v", pending)
+	}
+}
+
+func TestFailExport(t *testing.T) {
+	repository := newRepository()
+	export := randomExport()
+
+	if err := repository.StoreExport(context.Background(), export).Perform(); err != nil {
+		t.Fatalf("failed to store export err: %v", err)
+	}
+	if err := repository.FailExport(context.Background(), export.Id).Perform(); err != nil {
+		t.Fatalf("failed to fail export err: %v", err)
+	}
+	failed, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `failed` err: %v", err)
+	}
+	if failed == nil || failed.Status != dataExports.StatusFailed {
+		t.Fatalf("`failed` should be failed, found %v", failed)
+	}
+}
+
+func TestFailStaleExport(t *testing.T) {
+	repository := newRepository()
+	export := randomExport()
+
+	if err := repository.StoreExport(context.Background(), export).Perform(); err != nil {
+		t.Fatalf("failed to store export err: %v", err)
+	}
+	failed, err := repository.FailStaleExport(context.Background(), export.Id, export.StartedAt).Perform()
+	if err != nil {
+		t.Fatalf("[fresh] failed to fail stale export err: %v", err)
+	}
+	if failed {
+		t.Fatalf("[fresh] export with a live lease should not be failed")
+	}
+	failTransaction := repository.FailStaleExport(context.Background(), export.Id, export.StartedAt+1)
+	failed, err = failTransaction.Perform()
+	if err != nil {
+		t.Fatalf("failed to perform `failTransaction` err: %v", err)
+	}
+	if !failed {
+		t.Fatalf("export with an expired lease should be failed")
+	}
+	stale, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("failed to get `stale` err: %v", err)
+	}
+	if stale == nil || stale.Status != dataExports.StatusFailed {
+		t.Fatalf("`stale` should be failed, found %v", stale)
+	}
+	if err := failTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `failTransaction` err: %v", err)
+	}
+	pending, err := repository.GetExport(context.Background(), export.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `pending` err: %v", err)
+	}
+	if pending == nil || pending.Status != dataExports.StatusPending || pending.ExpiresAt != 0 {
+		t.Fatalf("[after rollback] `pending` should be pending, found %v", pending)
+	}
+	if err := repository.CompleteExport(context.Background(), export.Id, []byte(uuid.New().String()), 456).Perform(); err != nil {
+		t.Fatalf("failed to complete export err: %v", err)
+	}
+	failed, err = repository.FailStaleExport(context.Background(), export.Id, export.StartedAt+1).Perform()
+	if err != nil {
+		t.Fatalf("[completed] failed to fail stale export err: %v", err)
+	}
+	if failed {
+		t.Fatalf("[completed] ready export should not be failed")
+	}
+}
+
+func TestRemoveExports(t *testing.T) {
+	repository := newRepository()
+	ready := randomExport()
+	pending := randomExport()
+	pending.UserId = ready.UserId
+	other := randomExport()
+	archive := []byte(uuid.New().String())
+
+	for _, export := range []dataExports.Export{ready, pending, other} {
+		if err := repository.StoreExport(context.Background(), export).Perform(); err != nil {
+			t.Fatalf("failed to store export err: %v", err)
+		}
+	}
+	if err := repository.CompleteExport(context.Background(), ready.Id, archive, 456).Perform(); err != nil {
+		t.Fatalf("failed to complete export err: %v", err)
+	}
+	removeTransaction := repository.RemoveExports(context.Background(), ready.UserId)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	for _, id := range []dataExports.ExportId{ready.Id, pending.Id} {
+		shouldBeNil, err := repository.GetExport(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get `shouldBeNil` err: %v", err)
+		}
+		if shouldBeNil != nil {
+			t.Fatalf("`shouldBeNil` should be nil, found %v", *shouldBeNil)
+		}
+	}
+	shouldBeEqualToOther, err := repository.GetExport(context.Background(), other.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEqualToOther` err: %v", err)
+	}
+	if shouldBeEqualToOther == nil || *shouldBeEqualToOther != other {
+		t.Fatalf("exports of other users should be kept, found %v", shouldBeEqualToOther)
+	}
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	restored, err := repository.GetExport(context.Background(), ready.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `restored` err: %v", err)
+	}
+	if restored == nil || restored.Status != dataExports.StatusReady || restored.ExpiresAt != 456 {
+		t.Fatalf("[after rollback] `restored` should be ready until 456, found %v", restored)
+	}
+	restoredArchive, err := repository.GetArchive(context.Background(), ready.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `restoredArchive` err: %v", err)
+	}
+	if string(restoredArchive) != string(archive) {
+		t.Fatalf("[after rollback] `restoredArchive` should be equal to %s, found %s", archive, restoredArchive)
+	}
+	restored, err = repository.GetExport(context.Background(), pending.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `restored` err: %v", err)
+	}
+	if restored == nil || *restored != pending {
+		t.Fatalf("[after rollback] `restored` should be equal to %v, found %v", pending, restored)
+	}
+}
+
+func TestRemoveExpiredExports(t *testing.T) {
+	repository := newRepository()
+	expired := randomExport()
+	ready := randomExport()
+	pending := randomExport()
+
+	for _, export := range []dataExports.Export{expired, ready, pending} {
+		if err := repository.StoreExport(context.Background(), export).Perform(); err != nil {
+			t.Fatalf("failed to store export err: %v", err)
+		}
+	}
+	if err := repository.CompleteExport(context.Background(), expired.Id, []byte(uuid.New().String()), 456).Perform(); err != nil {
+		t.Fatalf("failed to complete `expired` err: %v", err)
+	}
+	if err := repository.CompleteExport(context.Background(), ready.Id, []byte(uuid.New().String()), 1000).Perform(); err != nil {
+		t.Fatalf("failed to complete `ready` err: %v", err)
+	}
+	if err := repository.RemoveExpiredExports(context.Background(), 500); err != nil {
+		t.Fatalf("failed to remove expired exports err: %v", err)
+	}
+	shouldBeNil, err := repository.GetExport(context.Background(), expired.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeNil` err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("`shouldBeNil` should be nil, found %v", *shouldBeNil)
+	}
+	for _, id := range []dataExports.ExportId{ready.Id, pending.Id} {
+		shouldBeKept, err := repository.GetExport(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get `shouldBeKept` err: %v", err)
+		}
+		if shouldBeKept == nil {
+			t.Fatalf("export %s should be kept", id)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/dataExports"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) dataExports.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			exports: map[dataExports.ExportId]exportRecord{},
+		},
+		logger: logger,
+	}
+}
+
+type exportRecord struct {
+	export  dataExports.Export
+	archive []byte
+}
+
+type storage struct {
+	mutex   sync.RWMutex
+	exports map[dataExports.ExportId]exportRecord
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) dataExports.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) StoreExport(ctx context.Context, export dataExports.Export) repositories.MutationWorkItem {
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			if _, ok := c.storage.exports[export.Id]; ok {
+				return fmt.Errorf("export %s already exists", export.Id)
+			}
+			c.storage.exports[export.Id] = exportRecord{
+				export: export,
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			delete(c.storage.exports, export.Id)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) CompleteExport(ctx context.Context, id dataExports.ExportId, archive []byte, expiresAt int64) repositories.MutationWorkItem {
+	return c.finishExport(ctx, id, dataExports.StatusReady, archive, expiresAt)
+}
+
+func (c *memoryRepository) FailExport(ctx context.Context, id dataExports.ExportId) repositories.MutationWorkItem {
+	return c.finishExport(ctx, id, dataExports.StatusFailed, nil, 0)
+}
+
+func (c *memoryRepository) finishExport(ctx context.Context, id dataExports.ExportId, status dataExports.Status, archive []byte, expiresAt int64) repositories.MutationWorkItem {
+	existed, err := c.GetExport(ctx, id)
+	if err == nil && existed == nil {
+		err = errors.New("no such export exists")
+	}
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			return c.updateExport(id, status, archive, expiresAt)
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.updateExport(id, existed.Status, nil, existed.ExpiresAt)
+		},
+	})
+}
+
+func (c *memoryRepository) FailStaleExport(ctx context.Context, id dataExports.ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	var failed bool
+	rollback := func() error {
+		if !failed {
+			return nil
+		}
+		return c.updateExport(id, dataExports.StatusPending, nil, 0)
+	}
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (bool, error) {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			record, ok := c.storage.exports[id]
+			if !ok || record.export.Status != dataExports.StatusPending || record.export.StartedAt >= startedBefore {
+				failed = false
+				return false, nil
+			}
+			record.export.Status = dataExports.StatusFailed
+			c.storage.exports[id] = record
+			failed = true
+			return true, nil
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) RemoveExports(ctx context.Context, uid dataExports.UserId) repositories.MutationWorkItem {
+	var removed []exportRecord
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			removed = []exportRecord{}
+			for id, record := range c.storage.exports {
+				if record.export.UserId == uid {
+					removed = append(removed, record)
+					delete(c.storage.exports, id)
+				}
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			for _, record := range removed {
+				c.storage.exports[record.export.Id] = record
+			}
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) updateExport(id dataExports.ExportId, status dataExports.Status, archive []byte, expiresAt int64) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	record, ok := c.storage.exports[id]
+	if !ok {
+		return fmt.Errorf("export %s not found", id)
+	}
+	record.export.Status = status
+	record.archive = archive
+	record.export.ExpiresAt = expiresAt
+	c.storage.exports[id] = record
+	return nil
+}
+
+func (c *memoryRepository) GetExport(ctx context.Context, id dataExports.ExportId) (*dataExports.Export, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	record, ok := c.storage.exports[id]
+	if !ok {
+		return nil, nil
+	}
+	export := record.export
+	return &export, nil
+}
+
+func (c *memoryRepository) GetArchive(ctx context.Context, id dataExports.ExportId) ([]byte, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	return c.storage.exports[id].archive, nil
+}
+
+func (c *memoryRepository) RemoveExpiredExports(ctx context.Context, expiredBefore int64) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	for id, record := range c.storage.exports {
+		if record.export.Status == dataExports.StatusReady && record.export.ExpiresAt < expiredBefore {
+			delete(c.storage.exports, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package dataExports_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/dataExports"
+)
+
+type RepositoryMock struct {
+	StoreExportImpl          func(ctx context.Context, export dataExports.Export) repositories.MutationWorkItem
+	CompleteExportImpl       func(ctx context.Context, id dataExports.ExportId, archive []byte, expiresAt int64) repositories.MutationWorkItem
+	FailExportImpl           func(ctx context.Context, id dataExports.ExportId) repositories.MutationWorkItem
+	FailStaleExportImpl      func(ctx context.Context, id dataExports.ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool]
+	RemoveExportsImpl        func(ctx context.Context, uid dataExports.UserId) repositories.MutationWorkItem
+	GetExportImpl            func(ctx context.Context, id dataExports.ExportId) (*dataExports.Export, error)
+	GetArchiveImpl           func(ctx context.Context, id dataExports.ExportId) ([]byte, error)
+	RemoveExpiredExportsImpl func(ctx context.Context, expiredBefore int64) error
+}
+
+func (c *RepositoryMock) StoreExport(ctx context.Context, export dataExports.Export) repositories.MutationWorkItem {
+	return c.StoreExportImpl(ctx, export)
+}
+
+func (c *RepositoryMock) CompleteExport(ctx context.Context, id dataExports.ExportId, archive []byte, expiresAt int64) repositories.MutationWorkItem {
+	return c.CompleteExportImpl(ctx, id, archive, expiresAt)
+}
+
+func (c *RepositoryMock) FailExport(ctx context.Context, id dataExports.ExportId) repositories.MutationWorkItem {
+	return c.FailExportImpl(ctx, id)
+}
+
+func (c *RepositoryMock) FailStaleExport(ctx context.Context, id dataExports.ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	return c.FailStaleExportImpl(ctx, id, startedBefore)
+}
+
+func (c *RepositoryMock) RemoveExports(ctx context.Context, uid dataExports.UserId) repositories.MutationWorkItem {
+	return c.RemoveExportsImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) GetExport(ctx context.Context, id dataExports.ExportId) (*dataExports.Export, error) {
+	return c.GetExportImpl(ctx, id)
+}
+
+func (c *RepositoryMock) GetArchive(ctx context.Context, id dataExports.ExportId) ([]byte, error) {
+	return c.GetArchiveImpl(ctx, id)
+}
+
+func (c *RepositoryMock) RemoveExpiredExports(ctx context.Context, expiredBefore int64) error {
+	return c.RemoveExpiredExportsImpl(ctx, expiredBefore)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) dataExports.Repository {
+	return c
+}
@@ -0,0 +1,53 @@
+package dataExports
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+)
+
+type ExportId string
+type UserId string
+
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusReady
+	StatusFailed
+)
+
+type Export struct {
+	Id        ExportId
+	UserId    UserId
+	Status    Status
+	CreatedAt int64
+	// StartedAt is when the build of a pending export took its lease. A pending export whose
+	// lease is too old was abandoned, e.g. the process building it died.
+	StartedAt int64
+	// ExpiresAt is when the archive of a ready export stops being available, zero for exports
+	// that are not ready.
+	ExpiresAt int64
+}
+
+type Repository interface {
+	StoreExport(ctx context.Context, export Export) repositories.MutationWorkItem
+	CompleteExport(ctx context.Context, id ExportId, archive []byte, expiresAt int64) repositories.MutationWorkItem
+	FailExport(ctx context.Context, id ExportId) repositories.MutationWorkItem
+
+	// FailStaleExport marks the export as failed if it is still pending and its lease started
+	// before startedBefore. It returns whether the export was marked as failed.
+	FailStaleExport(ctx context.Context, id ExportId, startedBefore int64) repositories.MutationWorkItemWithReturnValue[bool]
+
+	// RemoveExports removes every export of uid along with its archive.
+	RemoveExports(ctx context.Context, uid UserId) repositories.MutationWorkItem
+
+	GetExport(ctx context.Context, id ExportId) (*Export, error)
+	GetArchive(ctx context.Context, id ExportId) ([]byte, error)
+
+	// RemoveExpiredExports removes ready exports that expired before expiredBefore.
+	RemoveExpiredExports(ctx context.Context, expiredBefore int64) error
+
+	WithTx(tx db.DB) Repository
+}
@@ -1,6 +1,7 @@
 package defaultRepository
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -23,7 +24,11 @@ type defaultRepository struct {
 	logger logging.Service
 }
 
-func (c *defaultRepository) GetFriends(userId friends.UserId) ([]friends.UserId, error) {
+func (c *defaultRepository) WithTx(tx db.DB) friends.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) GetFriends(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
 	const op = "repositories.friends.postgresRepository.GetFriends"
 	c.logger.LogInfo("%s: start[userId=%s]", op, userId)
 	query := `
@@ -31,7 +36,7 @@ SELECT r1.sender FROM friendRequests r1 WHERE r1.target = $1 AND EXISTS (
 	SELECT * FROM friendRequests r2 WHERE r2.sender = $1 AND r2.target = r1.sender
 );
 `
-	rows, err := c.db.Query(query, string(userId))
+	rows, err := c.db.QueryContext(ctx, query, string(userId))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return []friends.UserId{}, err
@@ -54,7 +59,7 @@ SELECT r1.sender FROM friendRequests r1 WHERE r1.target = $1 AND EXISTS (
 	return subscriptions, nil
 }
 
-func (c *defaultRepository) GetSubscribers(userId friends.UserId) ([]friends.UserId, error) {
+func (c *defaultRepository) GetSubscribers(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
 	const op = "repositories.friends.postgresRepository.GetSubscribers"
 	c.logger.LogInfo("%s: start[userId=%s]", op, userId)
 	query := `
@@ -62,7 +67,7 @@ SELECT r1.sender FROM friendRequests r1 WHERE r1.target = $1 AND NOT EXISTS (
 	SELECT * FROM friendRequests r2 WHERE r2.sender = $1 AND r2.target = r1.sender
 );
 `
-	rows, err := c.db.Query(query, string(userId))
+	rows, err := c.db.QueryContext(ctx, query, string(userId))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return []friends.UserId{}, err
@@ -85,7 +90,7 @@ SELECT r1.sender FROM friendRequests r1 WHERE r1.target = $1 AND NOT EXISTS (
 	return subscriptions, nil
 }
 
-func (c *defaultRepository) GetSubscriptions(userId friends.UserId) ([]friends.UserId, error) {
+func (c *defaultRepository) GetSubscriptions(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
 	const op = "repositories.friends.postgresRepository.GetSubscriptions"
 	c.logger.LogInfo("%s: start[userId=%s]", op, userId)
 	query := `
@@ -93,7 +98,7 @@ SELECT r1.target FROM friendRequests r1 WHERE r1.sender = $1 AND NOT EXISTS (
 	SELECT * FROM friendRequests r2 WHERE r2.target = $1 AND r2.sender = r1.target
 );
 `
-	rows, err := c.db.Query(query, string(userId))
+	rows, err := c.db.QueryContext(ctx, query, string(userId))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return []friends.UserId{}, err
@@ -116,7 +121,7 @@ SELECT r1.target FROM friendRequests r1 WHERE r1.sender = $1 AND NOT EXISTS (
 	return subscriptions, nil
 }
 
-func (c *defaultRepository) GetStatuses(sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error) {
+func (c *defaultRepository) GetStatuses(ctx context.Context, sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error) {
 	const op = "repositories.friends.postgresRepository.GetStatuses"
 	c.logger.LogInfo("%s: start[sender=%s]", op, sender)
 
@@ -152,7 +157,7 @@ SELECT
 FROM 
     unnest( ARRAY[(%s)] ) AS id;
 `, argsList)
-	rows, err := c.db.Query(query, string(sender))
+	rows, err := c.db.QueryContext(ctx, query, string(sender))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return map[friends.UserId]friends.FriendStatus{}, err
@@ -188,9 +193,9 @@ FROM
 	return statuses, nil
 }
 
-func (c *defaultRepository) HasFriendRequest(sender friends.UserId, target friends.UserId) (bool, error) {
+func (c *defaultRepository) HasFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) (bool, error) {
 	const op = "repositories.friends.postgresRepository.HasFriendRequest"
-	hasRequest, err := c.hasFriendRequest(sender, target)
+	hasRequest, err := c.hasFriendRequest(ctx, sender, target)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to call hasFriendRequest from sender to target err: %v", op, err)
 		return false, err
@@ -198,7 +203,7 @@ func (c *defaultRepository) HasFriendRequest(sender friends.UserId, target frien
 	if !hasRequest {
 		return false, nil
 	}
-	hasRequestFromTarget, err := c.hasFriendRequest(target, sender)
+	hasRequestFromTarget, err := c.hasFriendRequest(ctx, target, sender)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to call hasFriendRequest from target to sender err: %v", op, err)
 		return false, err
@@ -206,11 +211,11 @@ func (c *defaultRepository) HasFriendRequest(sender friends.UserId, target frien
 	return !hasRequestFromTarget, nil
 }
 
-func (c *defaultRepository) hasFriendRequest(sender friends.UserId, target friends.UserId) (bool, error) {
+func (c *defaultRepository) hasFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) (bool, error) {
 	const op = "repositories.friends.postgresRepository.hasFriendRequest"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
 	query := `SELECT EXISTS(SELECT 1 FROM friendRequests WHERE sender = $1 AND target = $2);`
-	row := c.db.QueryRow(query, string(sender), string(target))
+	row := c.db.QueryRowContext(ctx, query, string(sender), string(target))
 	var has bool
 	if err := row.Scan(&has); err != nil {
 		c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
@@ -220,22 +225,22 @@ func (c *defaultRepository) hasFriendRequest(sender friends.UserId, target frien
 	return has, nil
 }
 
-func (c *defaultRepository) StoreFriendRequest(sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
+func (c *defaultRepository) StoreFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
 	return repositories.MutationWorkItem{
 		Perform: func() error {
-			return c.storeFriendRequest(sender, target)
+			return c.storeFriendRequest(ctx, sender, target)
 		},
 		Rollback: func() error {
-			return c.removeFriendRequest(sender, target)
+			return c.removeFriendRequest(ctx, sender, target)
 		},
 	}
 }
 
-func (c *defaultRepository) storeFriendRequest(sender friends.UserId, target friends.UserId) error {
+func (c *defaultRepository) storeFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) error {
 	const op = "repositories.friends.postgresRepository.storeFriendRequest"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
 	query := `INSERT INTO friendRequests(sender, target) VALUES($1, $2);`
-	_, err := c.db.Exec(query, string(sender), string(target))
+	_, err := c.db.ExecContext(ctx, query, string(sender), string(target))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -244,9 +249,9 @@ func (c *defaultRepository) storeFriendRequest(sender friends.UserId, target fri
 	return nil
 }
 
-func (c *defaultRepository) RemoveFriendRequest(sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
+func (c *defaultRepository) RemoveFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
 	const op = "repositories.friends.postgresRepository.RemoveFriendRequest"
-	has, err := c.HasFriendRequest(sender, target)
+	has, err := c.HasFriendRequest(ctx, sender, target)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
@@ -254,7 +259,7 @@ func (c *defaultRepository) RemoveFriendRequest(sender friends.UserId, target fr
 				return err
 			}
 			if has {
-				return c.removeFriendRequest(sender, target)
+				return c.removeFriendRequest(ctx, sender, target)
 			} else {
 				return nil
 			}
@@ -265,7 +270,7 @@ func (c *defaultRepository) RemoveFriendRequest(sender friends.UserId, target fr
 				return err
 			}
 			if has {
-				return c.storeFriendRequest(sender, target)
+				return c.storeFriendRequest(ctx, sender, target)
 			} else {
 				return nil
 			}
@@ -273,11 +278,11 @@ func (c *defaultRepository) RemoveFriendRequest(sender friends.UserId, target fr
 	}
 }
 
-func (c *defaultRepository) removeFriendRequest(sender friends.UserId, target friends.UserId) error {
+func (c *defaultRepository) removeFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) error {
 	const op = "repositories.friends.postgresRepository.removeFriendRequest"
 	c.logger.LogInfo("%s: start[sender=%s target=%s]", op, sender, target)
 	query := `DELETE FROM friendRequests WHERE sender = $1 and target = $2;`
-	_, err := c.db.Exec(query, string(sender), string(target))
+	_, err := c.db.ExecContext(ctx, query, string(sender), string(target))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -285,3 +290,78 @@ func (c *defaultRepository) removeFriendRequest(sender friends.UserId, target fr
 	c.logger.LogInfo("%s: success[sender=%s target=%s]", op, sender, target)
 	return nil
 }
+
+type friendRequest struct {
+	sender friends.UserId
+	target friends.UserId
+}
+
+func (c *defaultRepository) RemoveAllFriendRequests(ctx context.Context, userId friends.UserId) repositories.MutationWorkItem {
+	const op = "repositories.friends.postgresRepository.RemoveAllFriendRequests"
+	existed, err := c.getFriendRequests(ctx, userId)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current friend requests err: %v", op, err)
+				return err
+			}
+			return c.removeAllFriendRequests(ctx, userId)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current friend requests err: %v", op, err)
+				return err
+			}
+			for _, request := range existed {
+				if err := c.storeFriendRequest(ctx, request.sender, request.target); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (c *defaultRepository) getFriendRequests(ctx context.Context, userId friends.UserId) ([]friendRequest, error) {
+	const op = "repositories.friends.postgresRepository.getFriendRequests"
+	c.logger.LogInfo("%s: start[userId=%s]", op, userId)
+	query := `SELECT sender, target FROM friendRequests WHERE sender = $1 OR target = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(userId))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return []friendRequest{}, err
+	}
+	defer rows.Close()
+	requests := []friendRequest{}
+	for rows.Next() {
+		var sender string
+		var target string
+		if err := rows.Scan(&sender, &target); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return []friendRequest{}, err
+		}
+		requests = append(requests, friendRequest{
+			sender: friends.UserId(sender),
+			target: friends.UserId(target),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return []friendRequest{}, err
+	}
+	c.logger.LogInfo("%s: success[userId=%s]", op, userId)
+	return requests, nil
+}
+
+func (c *defaultRepository) removeAllFriendRequests(ctx context.Context, userId friends.UserId) error {
+	const op = "repositories.friends.postgresRepository.removeAllFriendRequests"
+	c.logger.LogInfo("%s: start[userId=%s]", op, userId)
+	query := `DELETE FROM friendRequests WHERE sender = $1 OR target = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(userId))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[userId=%s]", op, userId)
+	return nil
+}
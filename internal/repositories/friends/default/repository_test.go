@@ -1,6 +1,7 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/friends"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/friends/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/friends/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -20,30 +22,41 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() friends.Repository
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() friends.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() friends.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+	}
 	code := m.Run()
 
 	os.Exit(code)
@@ -54,11 +67,11 @@ func randomUid() friends.UserId {
 }
 
 func TestSubscribtion(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	subscriber := randomUid()
 	subscription := randomUid()
 
-	subsctiptionTransaction := repository.StoreFriendRequest(subscriber, subscription)
+	subsctiptionTransaction := repository.StoreFriendRequest(context.Background(), subscriber, subscription)
 	if err := subsctiptionTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `subsctiptionTransaction` err: %v", err)
 	}
@@ -84,11 +97,11 @@ func TestSubscribtion(t *testing.T) {
 }
 
 func TestStoreAndRemoveFriendRequest(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	subscriber := randomUid()
 	subscription := randomUid()
 
-	subsctiptionTransaction := repository.StoreFriendRequest(subscriber, subscription)
+	subsctiptionTransaction := repository.StoreFriendRequest(context.Background(), subscriber, subscription)
 	if err := subsctiptionTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `subsctiptionTransaction` err: %v", err)
 	}
@@ -100,7 +113,7 @@ func TestStoreAndRemoveFriendRequest(t *testing.T) {
 	ensureSubscribersIgnoringOrder(repository, t, subscription, []friends.UserId{subscriber})
 	ensureFriendsIgnoringOrder(repository, t, subscriber, []friends.UserId{})
 	ensureFriendsIgnoringOrder(repository, t, subscription, []friends.UserId{})
-	removeTransaction := repository.RemoveFriendRequest(subscriber, subscription)
+	removeTransaction := repository.RemoveFriendRequest(context.Background(), subscriber, subscription)
 	if err := removeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
 	}
@@ -115,13 +128,13 @@ func TestStoreAndRemoveFriendRequest(t *testing.T) {
 }
 
 func TestFriendship(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	subscriber := randomUid()
 	subscription := randomUid()
 
 	friendshipTransaction := []repositories.MutationWorkItem{
-		repository.StoreFriendRequest(subscriber, subscription),
-		repository.StoreFriendRequest(subscription, subscriber),
+		repository.StoreFriendRequest(context.Background(), subscriber, subscription),
+		repository.StoreFriendRequest(context.Background(), subscription, subscriber),
 	}
 	for i := 0; i < len(friendshipTransaction); i++ {
 		if err := friendshipTransaction[i].Perform(); err != nil {
@@ -152,26 +165,26 @@ func TestFriendship(t *testing.T) {
 }
 
 func TestHasFriendRequestEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	subscriber := randomUid()
 	subscription := randomUid()
 	ensureFriendRequest(repository, t, subscriber, subscription, false)
 }
 
 func TestGetSubscribersEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	uid := randomUid()
 	ensureSubscribersIgnoringOrder(repository, t, uid, []friends.UserId{})
 }
 
 func TestGetSubsriptionsEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	uid := randomUid()
 	ensureSubscriptionsIgnoringOrder(repository, t, uid, []friends.UserId{})
 }
 
 func TestGetFriendsEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	uid := randomUid()
 	ensureFriendsIgnoringOrder(repository, t, uid, []friends.UserId{})
 }
@@ -179,7 +192,7 @@ func TestGetFriendsEmpty(t *testing.T) {
 //
 
 func ensureFriendRequest(repository friends.Repository, t *testing.T, from friends.UserId, to friends.UserId, expectation bool) {
-	has, err := repository.HasFriendRequest(from, to)
+	has, err := repository.HasFriendRequest(context.Background(), from, to)
 	if err != nil {
 		t.Fatalf("failed to perform `HasFriendRequest` err: %v", err)
 	}
@@ -189,7 +202,7 @@ func ensureFriendRequest(repository friends.Repository, t *testing.T, from frien
 }
 
 func ensureSubscriptionsIgnoringOrder(repository friends.Repository, t *testing.T, userId friends.UserId, expectation []friends.UserId) {
-	subscriptions, err := repository.GetSubscriptions(userId)
+	subscriptions, err := repository.GetSubscriptions(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to perform `GetSubscriptions` err: %v", err)
 	}
@@ -197,7 +210,7 @@ func ensureSubscriptionsIgnoringOrder(repository friends.Repository, t *testing.
 }
 
 func ensureSubscribersIgnoringOrder(repository friends.Repository, t *testing.T, userId friends.UserId, expectation []friends.UserId) {
-	subscribers, err := repository.GetSubscribers(userId)
+	subscribers, err := repository.GetSubscribers(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to perform `GetSubscribers` err: %v", err)
 	}
@@ -205,7 +218,7 @@ func ensureSubscribersIgnoringOrder(repository friends.Repository, t *testing.T,
 }
 
 func ensureFriendsIgnoringOrder(repository friends.Repository, t *testing.T, userId friends.UserId, expectation []friends.UserId) {
-	subscribers, err := repository.GetFriends(userId)
+	subscribers, err := repository.GetFriends(context.Background(), userId)
 	if err != nil {
 		t.Fatalf("failed to get `GetFriends` err: %v", err)
 	}
@@ -224,3 +237,31 @@ func ensureEqualIgnoringOrder(t *testing.T, lhs []friends.UserId, rhs []friends.
 		t.Fatalf("%s: result should be %v, found %v", label, lhs, rhs)
 	}
 }
+
+func TestRemoveAllFriendRequests(t *testing.T) {
+	repository := newRepository()
+	user := randomUid()
+	friend := randomUid()
+	subscriber := randomUid()
+	subscription := randomUid()
+
+	for _, request := range [][2]friends.UserId{{user, friend}, {friend, user}, {subscriber, user}, {user, subscription}} {
+		if err := repository.StoreFriendRequest(context.Background(), request[0], request[1]).Perform(); err != nil {
+			t.Fatalf("failed to store friend request err: %v", err)
+		}
+	}
+	removeTransaction := repository.RemoveAllFriendRequests(context.Background(), user)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	ensureFriendsIgnoringOrder(repository, t, user, []friends.UserId{})
+	ensureSubscribersIgnoringOrder(repository, t, user, []friends.UserId{})
+	ensureSubscriptionsIgnoringOrder(repository, t, user, []friends.UserId{})
+	ensureFriendsIgnoringOrder(repository, t, friend, []friends.UserId{})
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	ensureFriendsIgnoringOrder(repository, t, user, []friends.UserId{friend})
+	ensureSubscribersIgnoringOrder(repository, t, user, []friends.UserId{subscriber})
+	ensureSubscriptionsIgnoringOrder(repository, t, user, []friends.UserId{subscription})
+}
@@ -0,0 +1,212 @@
+package memoryRepository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/friends"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) friends.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			requests: map[friendRequest]struct{}{},
+		},
+		logger: logger,
+	}
+}
+
+type friendRequest struct {
+	sender friends.UserId
+	target friends.UserId
+}
+
+type storage struct {
+	mutex    sync.RWMutex
+	requests map[friendRequest]struct{}
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) friends.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) GetFriends(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []friends.UserId{}
+	for request := range c.storage.requests {
+		if request.target == userId && c.has(userId, request.sender) {
+			result = append(result, request.sender)
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) GetSubscribers(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []friends.UserId{}
+	for request := range c.storage.requests {
+		if request.target == userId && !c.has(userId, request.sender) {
+			result = append(result, request.sender)
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) GetSubscriptions(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []friends.UserId{}
+	for request := range c.storage.requests {
+		if request.sender == userId && !c.has(request.target, userId) {
+			result = append(result, request.target)
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) GetStatuses(ctx context.Context, sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	statuses := map[friends.UserId]friends.FriendStatus{}
+	for _, id := range ids {
+		isSubscriber := c.has(sender, id)
+		isSubscription := c.has(id, sender)
+		status := friends.FriendStatusNo
+		if isSubscriber && isSubscription {
+			status = friends.FriendStatusFriend
+		} else if isSubscriber {
+			status = friends.FriendStatusSubscriber
+		} else if isSubscription {
+			status = friends.FriendStatusSubscription
+		} else if id == sender {
+			status = friends.FriendStatusMe
+		}
+		statuses[id] = friends.FriendStatus(status)
+	}
+	return statuses, nil
+}
+
+func (c *memoryRepository) HasFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) (bool, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	return c.has(sender, target) && !c.has(target, sender), nil
+}
+
+func (c *memoryRepository) StoreFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.store(sender, target)
+		},
+		Rollback: func() error {
+			c.remove(sender, target)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) RemoveFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
+	has, err := c.HasFriendRequest(ctx, sender, target)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			if has {
+				c.remove(sender, target)
+			}
+			return nil
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			if has {
+				return c.store(sender, target)
+			}
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) RemoveAllFriendRequests(ctx context.Context, userId friends.UserId) repositories.MutationWorkItem {
+	c.storage.mutex.RLock()
+	existed := []friendRequest{}
+	for request := range c.storage.requests {
+		if request.sender == userId || request.target == userId {
+			existed = append(existed, request)
+		}
+	}
+	c.storage.mutex.RUnlock()
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			for request := range c.storage.requests {
+				if request.sender == userId || request.target == userId {
+					delete(c.storage.requests, request)
+				}
+			}
+			return nil
+		},
+		Rollback: func() error {
+			for _, request := range existed {
+				if err := c.store(request.sender, request.target); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) has(sender friends.UserId, target friends.UserId) bool {
+	_, ok := c.storage.requests[friendRequest{
+		sender: sender,
+		target: target,
+	}]
+	return ok
+}
+
+func (c *memoryRepository) store(sender friends.UserId, target friends.UserId) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if c.has(sender, target) {
+		return fmt.Errorf("friend request from %s to %s already exists", sender, target)
+	}
+	c.storage.requests[friendRequest{
+		sender: sender,
+		target: target,
+	}] = struct{}{}
+	return nil
+}
+
+func (c *memoryRepository) remove(sender friends.UserId, target friends.UserId) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	delete(c.storage.requests, friendRequest{
+		sender: sender,
+		target: target,
+	})
+}
@@ -1,44 +1,56 @@
 package friends_mock
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/friends"
 )
 
 type RepositoryMock struct {
-	GetFriendsImpl          func(userId friends.UserId) ([]friends.UserId, error)
-	GetSubscribersImpl      func(userId friends.UserId) ([]friends.UserId, error)
-	GetSubscriptionsImpl    func(userId friends.UserId) ([]friends.UserId, error)
-	GetStatusesImpl         func(sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error)
-	HasFriendRequestImpl    func(sender friends.UserId, target friends.UserId) (bool, error)
-	StoreFriendRequestImpl  func(sender friends.UserId, target friends.UserId) repositories.MutationWorkItem
-	RemoveFriendRequestImpl func(sender friends.UserId, target friends.UserId) repositories.MutationWorkItem
+	GetFriendsImpl              func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error)
+	GetSubscribersImpl          func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error)
+	GetSubscriptionsImpl        func(ctx context.Context, userId friends.UserId) ([]friends.UserId, error)
+	GetStatusesImpl             func(ctx context.Context, sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error)
+	HasFriendRequestImpl        func(ctx context.Context, sender friends.UserId, target friends.UserId) (bool, error)
+	StoreFriendRequestImpl      func(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem
+	RemoveFriendRequestImpl     func(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem
+	RemoveAllFriendRequestsImpl func(ctx context.Context, userId friends.UserId) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) GetFriends(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+	return c.GetFriendsImpl(ctx, userId)
+}
+
+func (c *RepositoryMock) GetSubscribers(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+	return c.GetSubscribersImpl(ctx, userId)
 }
 
-func (c *RepositoryMock) GetFriends(userId friends.UserId) ([]friends.UserId, error) {
-	return c.GetFriendsImpl(userId)
+func (c *RepositoryMock) GetSubscriptions(ctx context.Context, userId friends.UserId) ([]friends.UserId, error) {
+	return c.GetSubscriptionsImpl(ctx, userId)
 }
 
-func (c *RepositoryMock) GetSubscribers(userId friends.UserId) ([]friends.UserId, error) {
-	return c.GetSubscribersImpl(userId)
+func (c *RepositoryMock) GetStatuses(ctx context.Context, sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error) {
+	return c.GetStatusesImpl(ctx, sender, ids)
 }
 
-func (c *RepositoryMock) GetSubscriptions(userId friends.UserId) ([]friends.UserId, error) {
-	return c.GetSubscriptionsImpl(userId)
+func (c *RepositoryMock) HasFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) (bool, error) {
+	return c.HasFriendRequestImpl(ctx, sender, target)
 }
 
-func (c *RepositoryMock) GetStatuses(sender friends.UserId, ids []friends.UserId) (map[friends.UserId]friends.FriendStatus, error) {
-	return c.GetStatusesImpl(sender, ids)
+func (c *RepositoryMock) StoreFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
+	return c.StoreFriendRequestImpl(ctx, sender, target)
 }
 
-func (c *RepositoryMock) HasFriendRequest(sender friends.UserId, target friends.UserId) (bool, error) {
-	return c.HasFriendRequestImpl(sender, target)
+func (c *RepositoryMock) RemoveFriendRequest(ctx context.Context, sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
+	return c.RemoveFriendRequestImpl(ctx, sender, target)
 }
 
-func (c *RepositoryMock) StoreFriendRequest(sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
-	return c.StoreFriendRequestImpl(sender, target)
+func (c *RepositoryMock) RemoveAllFriendRequests(ctx context.Context, userId friends.UserId) repositories.MutationWorkItem {
+	return c.RemoveAllFriendRequestsImpl(ctx, userId)
 }
 
-func (c *RepositoryMock) RemoveFriendRequest(sender friends.UserId, target friends.UserId) repositories.MutationWorkItem {
-	return c.RemoveFriendRequestImpl(sender, target)
+func (c *RepositoryMock) WithTx(tx db.DB) friends.Repository {
+	return c
 }
@@ -1,6 +1,9 @@
 package friends
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
@@ -17,12 +20,15 @@ const (
 )
 
 type Repository interface {
-	GetFriends(userId UserId) ([]UserId, error)
-	GetSubscribers(userId UserId) ([]UserId, error)
-	GetSubscriptions(userId UserId) ([]UserId, error)
-	GetStatuses(sender UserId, ids []UserId) (map[UserId]FriendStatus, error)
-	HasFriendRequest(sender UserId, target UserId) (bool, error)
-
-	StoreFriendRequest(sender UserId, target UserId) repositories.MutationWorkItem
-	RemoveFriendRequest(sender UserId, target UserId) repositories.MutationWorkItem
+	GetFriends(ctx context.Context, userId UserId) ([]UserId, error)
+	GetSubscribers(ctx context.Context, userId UserId) ([]UserId, error)
+	GetSubscriptions(ctx context.Context, userId UserId) ([]UserId, error)
+	GetStatuses(ctx context.Context, sender UserId, ids []UserId) (map[UserId]FriendStatus, error)
+	HasFriendRequest(ctx context.Context, sender UserId, target UserId) (bool, error)
+
+	StoreFriendRequest(ctx context.Context, sender UserId, target UserId) repositories.MutationWorkItem
+	RemoveFriendRequest(ctx context.Context, sender UserId, target UserId) repositories.MutationWorkItem
+	RemoveAllFriendRequests(ctx context.Context, userId UserId) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
 }
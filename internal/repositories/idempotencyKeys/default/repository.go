@@ -0,0 +1,256 @@
+package defaultRepository
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/idempotencyKeys"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(db db.DB, logger logging.Service) idempotencyKeys.Repository {
+	return &defaultRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type defaultRepository struct {
+	db     db.DB
+	logger logging.Service
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) idempotencyKeys.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) GetRecord(ctx context.Context, key idempotencyKeys.Key) (*idempotencyKeys.Record, error) {
+	const op = "repositories.idempotencyKeys.postgresRepository.GetRecord"
+	c.logger.LogInfo("%s: start[scope=%s key=%s]", op, key.Scope, key.Value)
+	query := `
+SELECT requestHash, completed, status, contentType, body, createdAt, reservedAt
+FROM idempotencyKeys
+WHERE scope = $1 AND key = $2;
+`
+	rows, err := c.db.QueryContext(ctx, query, key.Scope, key.Value)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			c.logger.LogInfo("%s: found rows err: %v", op, err)
+			return nil, err
+		}
+		c.logger.LogInfo("%s: success[scope=%s key=%s]", op, key.Scope, key.Value)
+		return nil, nil
+	}
+	record := idempotencyKeys.Record{
+		Key: key,
+	}
+	if err := rows.Scan(
+		&record.RequestHash,
+		&record.Completed,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.Body,
+		&record.CreatedAt,
+		&record.ReservedAt,
+	); err != nil {
+		c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s]", op, key.Scope, key.Value)
+	return &record, nil
+}
+
+func (c *defaultRepository) ReserveKey(ctx context.Context, record idempotencyKeys.Record) repositories.MutationWorkItemWithReturnValue[bool] {
+	var reserved bool
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: func() (bool, error) {
+			var err error
+			reserved, err = c.reserveKey(ctx, record)
+			return reserved, err
+		},
+		Rollback: func() error {
+			if !reserved {
+				return nil
+			}
+			return c.removeRecord(ctx, record.Key)
+		},
+	}
+}
+
+func (c *defaultRepository) reserveKey(ctx context.Context, record idempotencyKeys.Record) (bool, error) {
+	const op = "repositories.idempotencyKeys.postgresRepository.reserveKey"
+	c.logger.LogInfo("%s: start[scope=%s key=%s]", op, record.Key.Scope, record.Key.Value)
+	query := `
+INSERT INTO idempotencyKeys(scope, key, requestHash, completed, status, contentType, body, createdAt, reservedAt)
+VALUES ($1, $2, $3, False, 0, '', NULL, $4, $5)
+ON CONFLICT (scope, key) DO NOTHING;
+`
+	result, err := c.db.ExecContext(ctx, query, record.Key.Scope, record.Key.Value, record.RequestHash, record.CreatedAt, record.ReservedAt)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get affected rows err: %v", op, err)
+		return false, err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s reserved=%t]", op, record.Key.Scope, record.Key.Value, inserted > 0)
+	return inserted > 0, nil
+}
+
+func (c *defaultRepository) ReclaimKey(ctx context.Context, record idempotencyKeys.Record, reservedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	const op = "repositories.idempotencyKeys.postgresRepository.ReclaimKey"
+	existed, err := c.GetRecord(ctx, record.Key)
+	var reclaimed bool
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: func() (bool, error) {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current record err: %v", op, err)
+				return false, err
+			}
+			var reclaimErr error
+			reclaimed, reclaimErr = c.reclaimKey(ctx, record, reservedBefore)
+			return reclaimed, reclaimErr
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current record err: %v", op, err)
+				return err
+			}
+			if !reclaimed || existed == nil {
+				return nil
+			}
+			return c.restoreReservation(ctx, record.Key, record.ReservedAt, existed.ReservedAt)
+		},
+	}
+}
+
+func (c *defaultRepository) reclaimKey(ctx context.Context, record idempotencyKeys.Record, reservedBefore int64) (bool, error) {
+	const op = "repositories.idempotencyKeys.postgresRepository.reclaimKey"
+	c.logger.LogInfo("%s: start[scope=%s key=%s]", op, record.Key.Scope, record.Key.Value)
+	query := `
+UPDATE idempotencyKeys SET reservedAt = $4
+WHERE scope = $1 AND key = $2 AND requestHash = $3 AND NOT completed AND reservedAt < $5;
+`
+	result, err := c.db.ExecContext(ctx, query, record.Key.Scope, record.Key.Value, record.RequestHash, record.ReservedAt, reservedBefore)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get affected rows err: %v", op, err)
+		return false, err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s reclaimed=%t]", op, record.Key.Scope, record.Key.Value, updated > 0)
+	return updated > 0, nil
+}
+
+func (c *defaultRepository) restoreReservation(ctx context.Context, key idempotencyKeys.Key, reservedAt int64, previous int64) error {
+	const op = "repositories.idempotencyKeys.postgresRepository.restoreReservation"
+	c.logger.LogInfo("%s: start[scope=%s key=%s]", op, key.Scope, key.Value)
+	query := `
+UPDATE idempotencyKeys SET reservedAt = $4
+WHERE scope = $1 AND key = $2 AND NOT completed AND reservedAt = $3;
+`
+	if _, err := c.db.ExecContext(ctx, query, key.Scope, key.Value, reservedAt, previous); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s]", op, key.Scope, key.Value)
+	return nil
+}
+
+func (c *defaultRepository) CompleteRecord(ctx context.Context, key idempotencyKeys.Key, statusCode int, contentType string, body []byte) repositories.MutationWorkItem {
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.completeRecord(ctx, key, true, statusCode, contentType, body)
+		},
+		Rollback: func() error {
+			return c.completeRecord(ctx, key, false, 0, "", nil)
+		},
+	}
+}
+
+func (c *defaultRepository) completeRecord(ctx context.Context, key idempotencyKeys.Key, completed bool, statusCode int, contentType string, body []byte) error {
+	const op = "repositories.idempotencyKeys.postgresRepository.completeRecord"
+	c.logger.LogInfo("%s: start[scope=%s key=%s completed=%t status=%d]", op, key.Scope, key.Value, completed, statusCode)
+	query := `
+UPDATE idempotencyKeys SET completed = $3, status = $4, contentType = $5, body = $6
+WHERE scope = $1 AND key = $2;
+`
+	if _, err := c.db.ExecContext(ctx, query, key.Scope, key.Value, completed, statusCode, contentType, body); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s completed=%t status=%d]", op, key.Scope, key.Value, completed, statusCode)
+	return nil
+}
+
+func (c *defaultRepository) RemoveRecord(ctx context.Context, key idempotencyKeys.Key) repositories.MutationWorkItem {
+	const op = "repositories.idempotencyKeys.postgresRepository.RemoveRecord"
+	existed, err := c.GetRecord(ctx, key)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current record err: %v", op, err)
+				return err
+			}
+			return c.removeRecord(ctx, key)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current record err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				return nil
+			}
+			return c.storeRecord(ctx, *existed)
+		},
+	}
+}
+
+func (c *defaultRepository) removeRecord(ctx context.Context, key idempotencyKeys.Key) error {
+	const op = "repositories.idempotencyKeys.postgresRepository.removeRecord"
+	c.logger.LogInfo("%s: start[scope=%s key=%s]", op, key.Scope, key.Value)
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM idempotencyKeys WHERE scope = $1 AND key = $2;`, key.Scope, key.Value); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s]", op, key.Scope, key.Value)
+	return nil
+}
+
+func (c *defaultRepository) storeRecord(ctx context.Context, record idempotencyKeys.Record) error {
+	const op = "repositories.idempotencyKeys.postgresRepository.storeRecord"
+	c.logger.LogInfo("%s: start[scope=%s key=%s]", op, record.Key.Scope, record.Key.Value)
+	query := `
+INSERT INTO idempotencyKeys(scope, key, requestHash, completed, status, contentType, body, createdAt, reservedAt)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+`
+	if _, err := c.db.ExecContext(ctx, query, record.Key.Scope, record.Key.Value, record.RequestHash, record.Completed, record.StatusCode, record.ContentType, record.Body, record.CreatedAt, record.ReservedAt); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[scope=%s key=%s]", op, record.Key.Scope, record.Key.Value)
+	return nil
+}
+
+func (c *defaultRepository) RemoveExpiredRecords(ctx context.Context, createdBefore int64) error {
+	const op = "repositories.idempotencyKeys.postgresRepository.RemoveExpiredRecords"
+	c.logger.LogInfo("%s: start[createdBefore=%d]", op, createdBefore)
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM idempotencyKeys WHERE createdAt < $1;`, createdBefore); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[createdBefore=%d]", op, createdBefore)
+	return nil
+}
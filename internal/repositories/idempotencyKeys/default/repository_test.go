@@ -0,0 +1,285 @@
+package defaultRepository_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/idempotencyKeys"
+	defaultRepository "github.com/rzmn/governi/internal/repositories/idempotencyKeys/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/idempotencyKeys/memory"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+
+	"github.com/google/uuid"
+)
+
+var (
+	newRepository func() idempotencyKeys.Repository
+)
+
+func TestMain(m *testing.M) {
+	logger := standartOutputLoggingService.New()
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() idempotencyKeys.Repository {
+			return defaultRepository.New(database, logger)
+		}
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() idempotencyKeys.Repository {
+			return repository
+		}
+	}
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func randomKey() idempotencyKeys.Key {
+	return idempotencyKeys.Key{
+		Scope: uuid.New().String(),
+		Value: uuid.New().String(),
+	}
+}
+
+func TestReserveAndCompleteKey(t *testing.T) {
+	repository := newRepository()
+	key := randomKey()
+	record := idempotencyKeys.Record{
+		Key:         key,
+		RequestHash: uuid.New().String(),
+		CreatedAt:   123,
+		ReservedAt:  123,
+	}
+
+	shouldBeNil, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[initial] failed to get record err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("[initial] record should be nil, found %v", *shouldBeNil)
+	}
+	reserveTransaction := repository.ReserveKey(context.Background(), record)
+	reserved, err := reserveTransaction.Perform()
+	if err != nil {
+		t.Fatalf("failed to perform `reserveTransaction` err: %v", err)
+	}
+	if !reserved {
+		t.Fatalf("key should be reserved")
+	}
+	reservedAgain, err := repository.ReserveKey(context.Background(), record).Perform()
+	if err != nil {
+		t.Fatalf("failed to reserve key again err: %v", err)
+	}
+	if reservedAgain {
+		t.Fatalf("key should not be reserved twice")
+	}
+	reservedRecord, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[reserved] failed to get record err: %v", err)
+	}
+	if reservedRecord == nil || reservedRecord.Completed || reservedRecord.RequestHash != record.RequestHash || reservedRecord.CreatedAt != record.CreatedAt {
+		t.Fatalf("[reserved] record should be reserved and not completed, found %v", reservedRecord)
+	}
+	body := []byte(uuid.New().String())
+	completeTransaction := repository.CompleteRecord(context.Background(), key, 200, "application/json", body)
+	if err := completeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `completeTransaction` err: %v", err)
+	}
+	completedRecord, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[completed] failed to get record err: %v", err)
+	}
+	expected := idempotencyKeys.Record{
+		Key:         key,
+		RequestHash: record.RequestHash,
+		Completed:   true,
+		StatusCode:  200,
+		ContentType: "application/json",
+		Body:        body,
+		CreatedAt:   record.CreatedAt,
+		ReservedAt:  record.ReservedAt,
+	}
+	if completedRecord == nil || !reflect.DeepEqual(*completedRecord, expected) {
+		t.Fatalf("[completed] record should be %v, found %v", expected, completedRecord)
+	}
+	if err := completeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `completeTransaction` err: %v", err)
+	}
+	rolledBackRecord, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[completion rolled back] failed to get record err: %v", err)
+	}
+	if rolledBackRecord == nil || rolledBackRecord.Completed {
+		t.Fatalf("[completion rolled back] record should not be completed, found %v", rolledBackRecord)
+	}
+	if err := reserveTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `reserveTransaction` err: %v", err)
+	}
+	shouldBeNil, err = repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[reservation rolled back] failed to get record err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("[reservation rolled back] record should be nil, found %v", *shouldBeNil)
+	}
+}
+
+func TestRemoveRecord(t *testing.T) {
+	repository := newRepository()
+	key := randomKey()
+	if _, err := repository.ReserveKey(context.Background(), idempotencyKeys.Record{
+		Key:         key,
+		RequestHash: uuid.New().String(),
+		CreatedAt:   123,
+	}).Perform(); err != nil {
+		t.Fatalf("failed to reserve key err: %v", err)
+	}
+	removeTransaction := repository.RemoveRecord(context.Background(), key)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	shouldBeNil, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[removed] failed to get record err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("[removed] record should be nil, found %v", *shouldBeNil)
+	}
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	restored, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[restored] failed to get record err: %v", err)
+	}
+	if restored == nil {
+		t.Fatalf("[restored] record should be restored")
+	}
+}
+
+func TestRemoveExpiredRecords(t *testing.T) {
+	repository := newRepository()
+	expiredKey := randomKey()
+	freshKey := randomKey()
+	for key, createdAt := range map[idempotencyKeys.Key]int64{expiredKey: 100, freshKey: 1 << 40} {
+		if _, err := repository.ReserveKey(context.Background(), idempotencyKeys.Record{
+			Key:         key,
+			RequestHash: uuid.New().String(),
+			CreatedAt:   createdAt,
+		}).Perform(); err != nil {
+			t.Fatalf("failed to reserve key err: %v", err)
+		}
+	}
+	if err := repository.RemoveExpiredRecords(context.Background(), 1<<39); err != nil {
+		t.Fatalf("failed to remove expired records err: %v", err)
+	}
+	expired, err := repository.GetRecord(context.Background(), expiredKey)
+	if err != nil {
+		t.Fatalf("failed to get expired record err: %v", err)
+	}
+	if expired != nil {
+		t.Fatalf("expired record should be removed, found %v", *expired)
+	}
+	fresh, err := repository.GetRecord(context.Background(), freshKey)
+	if err != nil {
+		t.Fatalf("failed to get fresh record err: %v", err)
+	}
+	if fresh == nil {
+		t.Fatalf("fresh record should not be removed")
+	}
+	repository.RemoveRecord(context.Background(), freshKey).Perform()
+}
+
+func TestReclaimKey(t *testing.T) {
+	repository := newRepository()
+	key := randomKey()
+	record := idempotencyKeys.Record{
+		Key:         key,
+		RequestHash: uuid.New().String(),
+		CreatedAt:   123,
+		ReservedAt:  123,
+	}
+
+	if _, err := repository.ReserveKey(context.Background(), record).Perform(); err != nil {
+		t.Fatalf("failed to reserve key err: %v", err)
+	}
+	retry := record
+	retry.ReservedAt = 456
+	reclaimed, err := repository.ReclaimKey(context.Background(), retry, record.ReservedAt).Perform()
+	if err != nil {
+		t.Fatalf("[active lease] failed to reclaim key err: %v", err)
+	}
+	if reclaimed {
+		t.Fatalf("[active lease] key should not be reclaimed")
+	}
+	otherRequest := retry
+	otherRequest.RequestHash = uuid.New().String()
+	reclaimed, err = repository.ReclaimKey(context.Background(), otherRequest, record.ReservedAt+1).Perform()
+	if err != nil {
+		t.Fatalf("[other request] failed to reclaim key err: %v", err)
+	}
+	if reclaimed {
+		t.Fatalf("[other request] key should not be reclaimed for a different request")
+	}
+	reclaimTransaction := repository.ReclaimKey(context.Background(), retry, record.ReservedAt+1)
+	reclaimed, err = reclaimTransaction.Perform()
+	if err != nil {
+		t.Fatalf("failed to perform `reclaimTransaction` err: %v", err)
+	}
+	if !reclaimed {
+		t.Fatalf("key with an expired lease should be reclaimed")
+	}
+	reclaimedRecord, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[reclaimed] failed to get record err: %v", err)
+	}
+	if reclaimedRecord == nil || reclaimedRecord.ReservedAt != retry.ReservedAt || reclaimedRecord.CreatedAt != record.CreatedAt {
+		t.Fatalf("[reclaimed] record should be reserved at %d, found %v", retry.ReservedAt, reclaimedRecord)
+	}
+	if err := reclaimTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `reclaimTransaction` err: %v", err)
+	}
+	rolledBackRecord, err := repository.GetRecord(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[rolled back] failed to get record err: %v", err)
+	}
+	if rolledBackRecord == nil || rolledBackRecord.ReservedAt != record.ReservedAt {
+		t.Fatalf("[rolled back] record should be reserved at %d, found %v", record.ReservedAt, rolledBackRecord)
+	}
+	if err := repository.CompleteRecord(context.Background(), key, 200, "application/json", nil).Perform(); err != nil {
+		t.Fatalf("failed to complete record err: %v", err)
+	}
+	reclaimed, err = repository.ReclaimKey(context.Background(), retry, record.ReservedAt+1).Perform()
+	if err != nil {
+		t.Fatalf("[completed] failed to reclaim key err: %v", err)
+	}
+	if reclaimed {
+		t.Fatalf("[completed] completed key should not be reclaimed")
+	}
+}
@@ -0,0 +1,189 @@
+package memoryRepository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/idempotencyKeys"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) idempotencyKeys.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			records: map[idempotencyKeys.Key]idempotencyKeys.Record{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex   sync.RWMutex
+	records map[idempotencyKeys.Key]idempotencyKeys.Record
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) idempotencyKeys.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) GetRecord(ctx context.Context, key idempotencyKeys.Key) (*idempotencyKeys.Record, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	record, ok := c.storage.records[key]
+	if !ok {
+		return nil, nil
+	}
+	record.Body = append([]byte(nil), record.Body...)
+	return &record, nil
+}
+
+func (c *memoryRepository) ReserveKey(ctx context.Context, record idempotencyKeys.Record) repositories.MutationWorkItemWithReturnValue[bool] {
+	var reserved bool
+	rollback := func() error {
+		if !reserved {
+			return nil
+		}
+		c.remove(record.Key)
+		return nil
+	}
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (bool, error) {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			if _, ok := c.storage.records[record.Key]; ok {
+				reserved = false
+				return false, nil
+			}
+			c.storage.records[record.Key] = idempotencyKeys.Record{
+				Key:         record.Key,
+				RequestHash: record.RequestHash,
+				CreatedAt:   record.CreatedAt,
+				ReservedAt:  record.ReservedAt,
+			}
+			reserved = true
+			return true, nil
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) ReclaimKey(ctx context.Context, record idempotencyKeys.Record, reservedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	var reclaimed bool
+	var previous int64
+	rollback := func() error {
+		if !reclaimed {
+			return nil
+		}
+		c.storage.mutex.Lock()
+		defer c.storage.mutex.Unlock()
+		if existing, ok := c.storage.records[record.Key]; ok && !existing.Completed && existing.ReservedAt == record.ReservedAt {
+			existing.ReservedAt = previous
+			c.storage.records[record.Key] = existing
+		}
+		return nil
+	}
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (bool, error) {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			existing, ok := c.storage.records[record.Key]
+			if !ok || existing.Completed || existing.RequestHash != record.RequestHash || existing.ReservedAt >= reservedBefore {
+				reclaimed = false
+				return false, nil
+			}
+			previous = existing.ReservedAt
+			existing.ReservedAt = record.ReservedAt
+			c.storage.records[record.Key] = existing
+			reclaimed = true
+			return true, nil
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) CompleteRecord(ctx context.Context, key idempotencyKeys.Key, statusCode int, contentType string, body []byte) repositories.MutationWorkItem {
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.complete(key, true, statusCode, contentType, body)
+		},
+		Rollback: func() error {
+			return c.complete(key, false, 0, "", nil)
+		},
+	})
+}
+
+func (c *memoryRepository) RemoveRecord(ctx context.Context, key idempotencyKeys.Key) repositories.MutationWorkItem {
+	existed, err := c.GetRecord(ctx, key)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			c.remove(key)
+			return nil
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			if existed == nil {
+				return nil
+			}
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			c.storage.records[key] = *existed
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) RemoveExpiredRecords(ctx context.Context, createdBefore int64) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	for key, record := range c.storage.records {
+		if record.CreatedAt < createdBefore {
+			delete(c.storage.records, key)
+		}
+	}
+	return nil
+}
+
+func (c *memoryRepository) complete(key idempotencyKeys.Key, completed bool, statusCode int, contentType string, body []byte) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	record, ok := c.storage.records[key]
+	if !ok {
+		return fmt.Errorf("idempotency key %s is not reserved", key.Value)
+	}
+	record.Completed = completed
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = append([]byte(nil), body...)
+	c.storage.records[key] = record
+	return nil
+}
+
+func (c *memoryRepository) remove(key idempotencyKeys.Key) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	delete(c.storage.records, key)
+}
@@ -0,0 +1,46 @@
+package idempotencyKeys_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/idempotencyKeys"
+)
+
+type RepositoryMock struct {
+	GetRecordImpl            func(ctx context.Context, key idempotencyKeys.Key) (*idempotencyKeys.Record, error)
+	ReserveKeyImpl           func(ctx context.Context, record idempotencyKeys.Record) repositories.MutationWorkItemWithReturnValue[bool]
+	ReclaimKeyImpl           func(ctx context.Context, record idempotencyKeys.Record, reservedBefore int64) repositories.MutationWorkItemWithReturnValue[bool]
+	CompleteRecordImpl       func(ctx context.Context, key idempotencyKeys.Key, statusCode int, contentType string, body []byte) repositories.MutationWorkItem
+	RemoveRecordImpl         func(ctx context.Context, key idempotencyKeys.Key) repositories.MutationWorkItem
+	RemoveExpiredRecordsImpl func(ctx context.Context, createdBefore int64) error
+}
+
+func (c *RepositoryMock) GetRecord(ctx context.Context, key idempotencyKeys.Key) (*idempotencyKeys.Record, error) {
+	return c.GetRecordImpl(ctx, key)
+}
+
+func (c *RepositoryMock) ReserveKey(ctx context.Context, record idempotencyKeys.Record) repositories.MutationWorkItemWithReturnValue[bool] {
+	return c.ReserveKeyImpl(ctx, record)
+}
+
+func (c *RepositoryMock) ReclaimKey(ctx context.Context, record idempotencyKeys.Record, reservedBefore int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	return c.ReclaimKeyImpl(ctx, record, reservedBefore)
+}
+
+func (c *RepositoryMock) CompleteRecord(ctx context.Context, key idempotencyKeys.Key, statusCode int, contentType string, body []byte) repositories.MutationWorkItem {
+	return c.CompleteRecordImpl(ctx, key, statusCode, contentType, body)
+}
+
+func (c *RepositoryMock) RemoveRecord(ctx context.Context, key idempotencyKeys.Key) repositories.MutationWorkItem {
+	return c.RemoveRecordImpl(ctx, key)
+}
+
+func (c *RepositoryMock) RemoveExpiredRecords(ctx context.Context, createdBefore int64) error {
+	return c.RemoveExpiredRecordsImpl(ctx, createdBefore)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) idempotencyKeys.Repository {
+	return c
+}
@@ -0,0 +1,39 @@
+package idempotencyKeys
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+)
+
+type Key struct {
+	Scope string
+	Value string
+}
+
+type Record struct {
+	Key         Key
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   int64
+	// ReservedAt is when the request currently processing the key took its lease. An incomplete
+	// record whose lease is too old was abandoned, e.g. the process handling it died.
+	ReservedAt int64
+}
+
+type Repository interface {
+	GetRecord(ctx context.Context, key Key) (*Record, error)
+	ReserveKey(ctx context.Context, record Record) repositories.MutationWorkItemWithReturnValue[bool]
+	// ReclaimKey takes over the reservation of an incomplete record made for the same request if
+	// it was reserved before reservedBefore. It returns whether the reservation was taken over.
+	ReclaimKey(ctx context.Context, record Record, reservedBefore int64) repositories.MutationWorkItemWithReturnValue[bool]
+	CompleteRecord(ctx context.Context, key Key, statusCode int, contentType string, body []byte) repositories.MutationWorkItem
+	RemoveRecord(ctx context.Context, key Key) repositories.MutationWorkItem
+	RemoveExpiredRecords(ctx context.Context, createdBefore int64) error
+
+	WithTx(tx db.DB) Repository
+}
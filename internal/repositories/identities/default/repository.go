@@ -0,0 +1,160 @@
+package defaultRepository
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/identities"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(db db.DB, logger logging.Service) identities.Repository {
+	return &defaultRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type defaultRepository struct {
+	db     db.DB
+	logger logging.Service
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) identities.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) LinkIdentity(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.linkIdentity(ctx, uid, identity)
+		},
+		Rollback: func() error {
+			return c.unlinkIdentity(ctx, identity)
+		},
+	}
+}
+
+func (c *defaultRepository) linkIdentity(ctx context.Context, uid identities.UserId, identity identities.Identity) error {
+	const op = "repositories.identities.postgresRepository.linkIdentity"
+	c.logger.LogInfo("%s: start[uid=%s issuer=%s]", op, uid, identity.Issuer)
+	query := `INSERT INTO identities(issuer, subject, id) VALUES ($1, $2, $3);`
+	_, err := c.db.ExecContext(ctx, query, identity.Issuer, identity.Subject, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s issuer=%s]", op, uid, identity.Issuer)
+	return nil
+}
+
+func (c *defaultRepository) unlinkIdentity(ctx context.Context, identity identities.Identity) error {
+	const op = "repositories.identities.postgresRepository.unlinkIdentity"
+	c.logger.LogInfo("%s: start[issuer=%s]", op, identity.Issuer)
+	query := `DELETE FROM identities WHERE issuer = $1 AND subject = $2;`
+	_, err := c.db.ExecContext(ctx, query, identity.Issuer, identity.Subject)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[issuer=%s]", op, identity.Issuer)
+	return nil
+}
+
+func (c *defaultRepository) GetUserIdByIdentity(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+	const op = "repositories.identities.postgresRepository.GetUserIdByIdentity"
+	c.logger.LogInfo("%s: start[issuer=%s]", op, identity.Issuer)
+	query := `SELECT id FROM identities WHERE issuer = $1 AND subject = $2;`
+	rows, err := c.db.QueryContext(ctx, query, identity.Issuer, identity.Subject)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		if err := rows.Err(); err != nil {
+			c.logger.LogInfo("%s: found rows err: %v", op, err)
+			return nil, err
+		}
+		c.logger.LogInfo("%s: success[issuer=%s]", op, identity.Issuer)
+		return (*identities.UserId)(&uid), nil
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[issuer=%s]", op, identity.Issuer)
+	return nil, nil
+}
+
+func (c *defaultRepository) RemoveIdentities(ctx context.Context, uid identities.UserId) repositories.MutationWorkItem {
+	const op = "repositories.identities.postgresRepository.RemoveIdentities"
+	existed, err := c.getIdentities(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current identities err: %v", op, err)
+				return err
+			}
+			return c.removeIdentities(ctx, uid)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current identities err: %v", op, err)
+				return err
+			}
+			for _, identity := range existed {
+				if err := c.linkIdentity(ctx, uid, identity); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (c *defaultRepository) removeIdentities(ctx context.Context, uid identities.UserId) error {
+	const op = "repositories.identities.postgresRepository.removeIdentities"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `DELETE FROM identities WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
+
+func (c *defaultRepository) getIdentities(ctx context.Context, uid identities.UserId) ([]identities.Identity, error) {
+	const op = "repositories.identities.postgresRepository.getIdentities"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT issuer, subject FROM identities WHERE id = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return []identities.Identity{}, err
+	}
+	defer rows.Close()
+	result := []identities.Identity{}
+	for rows.Next() {
+		var identity identities.Identity
+		if err := rows.Scan(&identity.Issuer, &identity.Subject); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return []identities.Identity{}, err
+		}
+		result = append(result, identity)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return []identities.Identity{}, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return result, nil
+}
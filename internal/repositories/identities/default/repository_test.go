@@ -0,0 +1,172 @@
+package defaultRepository_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/identities"
+	defaultRepository "github.com/rzmn/governi/internal/repositories/identities/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/identities/memory"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+
+	"github.com/google/uuid"
+)
+
+var (
+	newRepository func() identities.Repository
+)
+
+func TestMain(m *testing.M) {
+	logger := standartOutputLoggingService.New()
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() identities.Repository {
+			return defaultRepository.New(database, logger)
+		}
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() identities.Repository {
+			return repository
+		}
+	}
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func TestLinkIdentity(t *testing.T) {
+	repository := newRepository()
+	userId := identities.UserId(uuid.New().String())
+	identity := identities.Identity{
+		Issuer:  uuid.New().String(),
+		Subject: uuid.New().String(),
+	}
+
+	uidFromDb, err := repository.GetUserIdByIdentity(context.Background(), identity)
+	if err != nil {
+		t.Fatalf("[initial] failed to get uid err: %v", err)
+	}
+	if uidFromDb != nil {
+		t.Fatalf("[initial] uid should be nil, found %s", *uidFromDb)
+	}
+	linkTransaction := repository.LinkIdentity(context.Background(), userId, identity)
+	if err := linkTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `linkTransaction` err: %v", err)
+	}
+	uidFromDb, err = repository.GetUserIdByIdentity(context.Background(), identity)
+	if err != nil {
+		t.Fatalf("failed to get uid err: %v", err)
+	}
+	if uidFromDb == nil || *uidFromDb != userId {
+		t.Fatalf("uid should be %s, found %v", userId, uidFromDb)
+	}
+	otherSubject := identities.Identity{
+		Issuer:  identity.Issuer,
+		Subject: uuid.New().String(),
+	}
+	uidFromDb, err = repository.GetUserIdByIdentity(context.Background(), otherSubject)
+	if err != nil {
+		t.Fatalf("[other subject] failed to get uid err: %v", err)
+	}
+	if uidFromDb != nil {
+		t.Fatalf("[other subject] uid should be nil, found %s", *uidFromDb)
+	}
+	if err := repository.LinkIdentity(context.Background(), identities.UserId(uuid.New().String()), identity).Perform(); err == nil {
+		t.Fatalf("identity should not be linked twice")
+	}
+	if err := linkTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `linkTransaction` err: %v", err)
+	}
+	uidFromDb, err = repository.GetUserIdByIdentity(context.Background(), identity)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get uid err: %v", err)
+	}
+	if uidFromDb != nil {
+		t.Fatalf("[after rollback] uid should be nil, found %s", *uidFromDb)
+	}
+}
+
+func TestRemoveIdentities(t *testing.T) {
+	repository := newRepository()
+	userId := identities.UserId(uuid.New().String())
+	linked := []identities.Identity{
+		{
+			Issuer:  uuid.New().String(),
+			Subject: uuid.New().String(),
+		},
+		{
+			Issuer:  uuid.New().String(),
+			Subject: uuid.New().String(),
+		},
+	}
+	other := identities.Identity{
+		Issuer:  uuid.New().String(),
+		Subject: uuid.New().String(),
+	}
+	otherUserId := identities.UserId(uuid.New().String())
+	for _, identity := range linked {
+		if err := repository.LinkIdentity(context.Background(), userId, identity).Perform(); err != nil {
+			t.Fatalf("failed to link identity err: %v", err)
+		}
+	}
+	if err := repository.LinkIdentity(context.Background(), otherUserId, other).Perform(); err != nil {
+		t.Fatalf("failed to link other identity err: %v", err)
+	}
+	removeTransaction := repository.RemoveIdentities(context.Background(), userId)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	for _, identity := range linked {
+		uidFromDb, err := repository.GetUserIdByIdentity(context.Background(), identity)
+		if err != nil {
+			t.Fatalf("failed to get uid err: %v", err)
+		}
+		if uidFromDb != nil {
+			t.Fatalf("removed identity should not be linked, found %s", *uidFromDb)
+		}
+	}
+	uidFromDb, err := repository.GetUserIdByIdentity(context.Background(), other)
+	if err != nil {
+		t.Fatalf("[other user] failed to get uid err: %v", err)
+	}
+	if uidFromDb == nil || *uidFromDb != otherUserId {
+		t.Fatalf("[other user] uid should be %s, found %v", otherUserId, uidFromDb)
+	}
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	for _, identity := range linked {
+		uidFromDb, err := repository.GetUserIdByIdentity(context.Background(), identity)
+		if err != nil {
+			t.Fatalf("[after rollback] failed to get uid err: %v", err)
+		}
+		if uidFromDb == nil || *uidFromDb != userId {
+			t.Fatalf("[after rollback] uid should be %s, found %v", userId, uidFromDb)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package memoryRepository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/identities"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) identities.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			identities: map[identities.Identity]identities.UserId{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex      sync.RWMutex
+	identities map[identities.Identity]identities.UserId
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) identities.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) LinkIdentity(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			if _, ok := c.storage.identities[identity]; ok {
+				return fmt.Errorf("identity of issuer %s is already linked", identity.Issuer)
+			}
+			c.storage.identities[identity] = uid
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			delete(c.storage.identities, identity)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) GetUserIdByIdentity(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	uid, ok := c.storage.identities[identity]
+	if !ok {
+		return nil, nil
+	}
+	return &uid, nil
+}
+
+func (c *memoryRepository) RemoveIdentities(ctx context.Context, uid identities.UserId) repositories.MutationWorkItem {
+	c.storage.mutex.RLock()
+	existed := []identities.Identity{}
+	for identity, linkedUid := range c.storage.identities {
+		if linkedUid == uid {
+			existed = append(existed, identity)
+		}
+	}
+	c.storage.mutex.RUnlock()
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			for _, identity := range existed {
+				delete(c.storage.identities, identity)
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			for _, identity := range existed {
+				c.storage.identities[identity] = uid
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package identities_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/identities"
+)
+
+type RepositoryMock struct {
+	LinkIdentityImpl        func(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem
+	GetUserIdByIdentityImpl func(ctx context.Context, identity identities.Identity) (*identities.UserId, error)
+	RemoveIdentitiesImpl    func(ctx context.Context, uid identities.UserId) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) LinkIdentity(ctx context.Context, uid identities.UserId, identity identities.Identity) repositories.MutationWorkItem {
+	return c.LinkIdentityImpl(ctx, uid, identity)
+}
+
+func (c *RepositoryMock) GetUserIdByIdentity(ctx context.Context, identity identities.Identity) (*identities.UserId, error) {
+	return c.GetUserIdByIdentityImpl(ctx, identity)
+}
+
+func (c *RepositoryMock) RemoveIdentities(ctx context.Context, uid identities.UserId) repositories.MutationWorkItem {
+	return c.RemoveIdentitiesImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) identities.Repository {
+	return c
+}
@@ -0,0 +1,22 @@
+package identities
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+)
+
+type UserId string
+type Identity struct {
+	Issuer  string
+	Subject string
+}
+
+type Repository interface {
+	LinkIdentity(ctx context.Context, uid UserId, identity Identity) repositories.MutationWorkItem
+	GetUserIdByIdentity(ctx context.Context, identity Identity) (*UserId, error)
+	RemoveIdentities(ctx context.Context, uid UserId) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
+}
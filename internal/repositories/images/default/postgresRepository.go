@@ -1,6 +1,7 @@
 package defaultRepository
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -25,23 +26,53 @@ type defaultRepository struct {
 	logger logging.Service
 }
 
-func (c *defaultRepository) UploadImageBase64(base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
+func (c *defaultRepository) WithTx(tx db.DB) images.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) UploadImageBase64(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
 	id := images.ImageId(uuid.New().String())
 	return repositories.MutationWorkItemWithReturnValue[images.ImageId]{
 		Perform: func() (images.ImageId, error) {
-			return id, c.uploadImageBase64(id, base64)
+			return id, c.uploadImageBase64(ctx, id, base64)
+		},
+		Rollback: func() error {
+			return c.removeImage(ctx, id)
+		},
+	}
+}
+
+func (c *defaultRepository) RemoveImage(ctx context.Context, id images.ImageId) repositories.MutationWorkItem {
+	const op = "repositories.images.postgresRepository.RemoveImage"
+	existed, err := c.GetImagesBase64(db.ForcePrimary(ctx), []images.ImageId{id})
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current image err: %v", op, err)
+				return err
+			}
+			return c.removeImage(ctx, id)
 		},
 		Rollback: func() error {
-			return c.removeImage(id)
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current image err: %v", op, err)
+				return err
+			}
+			for _, image := range existed {
+				if err := c.uploadImageBase64(ctx, image.Id, image.Base64); err != nil {
+					return err
+				}
+			}
+			return nil
 		},
 	}
 }
 
-func (c *defaultRepository) removeImage(id images.ImageId) error {
+func (c *defaultRepository) removeImage(ctx context.Context, id images.ImageId) error {
 	const op = "repositories.images.postgresRepository.removeImage"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 	query := `DELETE FROM images WHERE id = $1;`
-	_, err := c.db.Exec(query, string(id))
+	_, err := c.db.ExecContext(ctx, query, string(id))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -50,11 +81,11 @@ func (c *defaultRepository) removeImage(id images.ImageId) error {
 	return nil
 }
 
-func (c *defaultRepository) uploadImageBase64(id images.ImageId, base64 string) error {
+func (c *defaultRepository) uploadImageBase64(ctx context.Context, id images.ImageId, base64 string) error {
 	const op = "repositories.images.postgresRepository.uploadImageBase64"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 	query := `INSERT INTO images(id, base64) VALUES ($1, $2);`
-	_, err := c.db.Exec(query, string(id), base64)
+	_, err := c.db.ExecContext(ctx, query, string(id), base64)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -63,7 +94,7 @@ func (c *defaultRepository) uploadImageBase64(id images.ImageId, base64 string)
 	return nil
 }
 
-func (c *defaultRepository) GetImagesBase64(ids []images.ImageId) ([]images.Image, error) {
+func (c *defaultRepository) GetImagesBase64(ctx context.Context, ids []images.ImageId) ([]images.Image, error) {
 	const op = "repositories.images.postgresRepository.GetImagesBase64"
 	c.logger.LogInfo("%s: start", op)
 	if len(ids) == 0 {
@@ -74,7 +105,7 @@ func (c *defaultRepository) GetImagesBase64(ids []images.ImageId) ([]images.Imag
 		return fmt.Sprintf("'%s'", id)
 	}), ",")
 	query := fmt.Sprintf(`SELECT id, base64 FROM images WHERE id IN (%s);`, argsList)
-	rows, err := c.db.Query(query)
+	rows, err := db.Reader(ctx, c.db).QueryContext(ctx, query)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return []images.Image{}, err
@@ -1,6 +1,7 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
@@ -10,6 +11,7 @@ import (
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
 	"github.com/rzmn/governi/internal/repositories/images"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/images/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/images/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -17,45 +19,56 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() images.Repository
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() images.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() images.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+	}
 	code := m.Run()
 
 	os.Exit(code)
 }
 
 func TestUpload(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	base64 := uuid.New().String()
 
-	transaction := repository.UploadImageBase64(base64)
+	transaction := repository.UploadImageBase64(context.Background(), base64)
 	uploadedId, err := transaction.Perform()
 	if err != nil {
 		t.Fatalf("failed to perform transaction err: %v", err)
 	}
-	shouldContainUploadedId, err := repository.GetImagesBase64([]images.ImageId{uploadedId})
+	shouldContainUploadedId, err := repository.GetImagesBase64(context.Background(), []images.ImageId{uploadedId})
 	if err != nil {
 		t.Fatalf("failed to get `shouldContainUploadedId` err: %v", err)
 	}
@@ -65,7 +78,7 @@ func TestUpload(t *testing.T) {
 	if err := transaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback transaction err: %v", err)
 	}
-	shouldBeEmpty, err := repository.GetImagesBase64([]images.ImageId{uploadedId})
+	shouldBeEmpty, err := repository.GetImagesBase64(context.Background(), []images.ImageId{uploadedId})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -75,10 +88,10 @@ func TestUpload(t *testing.T) {
 }
 
 func TestGetEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	id := images.ImageId(uuid.New().String())
 
-	shouldBeEmpty, err := repository.GetImagesBase64([]images.ImageId{id})
+	shouldBeEmpty, err := repository.GetImagesBase64(context.Background(), []images.ImageId{id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -86,3 +99,34 @@ func TestGetEmpty(t *testing.T) {
 		t.Fatalf("`shouldBeEmpty` should be empty, found %v", shouldBeEmpty)
 	}
 }
+
+func TestRemove(t *testing.T) {
+	repository := newRepository()
+	base64 := uuid.New().String()
+
+	uploadedId, err := repository.UploadImageBase64(context.Background(), base64).Perform()
+	if err != nil {
+		t.Fatalf("failed to perform upload err: %v", err)
+	}
+	removeTransaction := repository.RemoveImage(context.Background(), uploadedId)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	shouldBeEmpty, err := repository.GetImagesBase64(context.Background(), []images.ImageId{uploadedId})
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
+	}
+	if len(shouldBeEmpty) != 0 {
+		t.Fatalf("`shouldBeEmpty` should be empty, found %v", shouldBeEmpty)
+	}
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	shouldContainUploadedId, err := repository.GetImagesBase64(context.Background(), []images.ImageId{uploadedId})
+	if err != nil {
+		t.Fatalf("failed to get `shouldContainUploadedId` err: %v", err)
+	}
+	if len(shouldContainUploadedId) != 1 || shouldContainUploadedId[0].Base64 != base64 {
+		t.Fatalf("`shouldContainUploadedId` is %v, expected to contain %s id %s data", shouldContainUploadedId, uploadedId, base64)
+	}
+}
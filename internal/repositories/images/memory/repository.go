@@ -0,0 +1,117 @@
+package memoryRepository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/images"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/google/uuid"
+)
+
+func New(logger logging.Service) images.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			images: map[images.ImageId]string{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex  sync.RWMutex
+	images map[images.ImageId]string
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) images.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) UploadImageBase64(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
+	id := images.ImageId(uuid.New().String())
+	rollback := func() error {
+		c.remove(id)
+		return nil
+	}
+	return repositories.MutationWorkItemWithReturnValue[images.ImageId]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (images.ImageId, error) {
+			return id, c.store(id, base64)
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) GetImagesBase64(ctx context.Context, ids []images.ImageId) ([]images.Image, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []images.Image{}
+	for _, id := range ids {
+		if base64, ok := c.storage.images[id]; ok {
+			result = append(result, images.Image{
+				Id:     id,
+				Base64: base64,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) RemoveImage(ctx context.Context, id images.ImageId) repositories.MutationWorkItem {
+	existed, err := c.GetImagesBase64(ctx, []images.ImageId{id})
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			c.remove(id)
+			return nil
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			for _, image := range existed {
+				if err := c.store(image.Id, image.Base64); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) store(id images.ImageId, base64 string) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if _, ok := c.storage.images[id]; ok {
+		return fmt.Errorf("image %s already exists", id)
+	}
+	c.storage.images[id] = base64
+	return nil
+}
+
+func (c *memoryRepository) remove(id images.ImageId) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	delete(c.storage.images, id)
+}
@@ -1,19 +1,31 @@
 package images_mock
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/images"
 )
 
 type RepositoryMock struct {
-	UploadImageBase64Impl func(base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId]
-	GetImagesBase64Impl   func(ids []images.ImageId) ([]images.Image, error)
+	UploadImageBase64Impl func(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId]
+	GetImagesBase64Impl   func(ctx context.Context, ids []images.ImageId) ([]images.Image, error)
+	RemoveImageImpl       func(ctx context.Context, id images.ImageId) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) UploadImageBase64(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
+	return c.UploadImageBase64Impl(ctx, base64)
+}
+
+func (c *RepositoryMock) GetImagesBase64(ctx context.Context, ids []images.ImageId) ([]images.Image, error) {
+	return c.GetImagesBase64Impl(ctx, ids)
 }
 
-func (c *RepositoryMock) UploadImageBase64(base64 string) repositories.MutationWorkItemWithReturnValue[images.ImageId] {
-	return c.UploadImageBase64Impl(base64)
+func (c *RepositoryMock) RemoveImage(ctx context.Context, id images.ImageId) repositories.MutationWorkItem {
+	return c.RemoveImageImpl(ctx, id)
 }
 
-func (c *RepositoryMock) GetImagesBase64(ids []images.ImageId) ([]images.Image, error) {
-	return c.GetImagesBase64Impl(ids)
+func (c *RepositoryMock) WithTx(tx db.DB) images.Repository {
+	return c
 }
@@ -1,6 +1,9 @@
 package images
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
@@ -11,6 +14,9 @@ type Image struct {
 }
 
 type Repository interface {
-	UploadImageBase64(base64 string) repositories.MutationWorkItemWithReturnValue[ImageId]
-	GetImagesBase64(ids []ImageId) ([]Image, error)
+	UploadImageBase64(ctx context.Context, base64 string) repositories.MutationWorkItemWithReturnValue[ImageId]
+	GetImagesBase64(ctx context.Context, ids []ImageId) ([]Image, error)
+	RemoveImage(ctx context.Context, id ImageId) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
 }
@@ -0,0 +1,291 @@
+package defaultRepository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/google/uuid"
+)
+
+func New(db db.DB, logger logging.Service) loginAttempts.Repository {
+	return &defaultRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type defaultRepository struct {
+	db     db.DB
+	logger logging.Service
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) loginAttempts.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) GetFailures(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+	const op = "repositories.loginAttempts.postgresRepository.GetFailures"
+	c.logger.LogInfo("%s: start[key=%s]", op, key)
+	query := `SELECT count, lastFailure FROM loginFailures WHERE key = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(key))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return loginAttempts.Failures{}, err
+	}
+	defer rows.Close()
+	failures := loginAttempts.Failures{}
+	if rows.Next() {
+		if err := rows.Scan(&failures.Count, &failures.LastFailureTimestamp); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return loginAttempts.Failures{}, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return loginAttempts.Failures{}, err
+	}
+	c.logger.LogInfo("%s: success[key=%s]", op, key)
+	return failures, nil
+}
+
+func (c *defaultRepository) AddFailure(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+	var added bool
+	return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+		Perform: func() (loginAttempts.Failures, error) {
+			failures, err := c.addFailure(ctx, key, timestamp, forgetBefore)
+			added = err == nil
+			return failures, err
+		},
+		Rollback: func() error {
+			if !added {
+				return nil
+			}
+			return c.removeFailure(ctx, key)
+		},
+	}
+}
+
+func (c *defaultRepository) addFailure(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) (loginAttempts.Failures, error) {
+	const op = "repositories.loginAttempts.postgresRepository.addFailure"
+	c.logger.LogInfo("%s: start[key=%s]", op, key)
+	query := `
+INSERT INTO loginFailures(key, count, lastFailure) VALUES ($1, 1, $2)
+ON CONFLICT (key) DO UPDATE SET
+	count = CASE WHEN loginFailures.lastFailure < $3 THEN 1 ELSE loginFailures.count + 1 END,
+	lastFailure = $2
+RETURNING count, lastFailure;
+`
+	failures := loginAttempts.Failures{}
+	if err := c.db.QueryRowContext(ctx, query, string(key), timestamp, forgetBefore).Scan(&failures.Count, &failures.LastFailureTimestamp); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return loginAttempts.Failures{}, err
+	}
+	c.logger.LogInfo("%s: success[key=%s count=%d]", op, key, failures.Count)
+	return failures, nil
+}
+
+func (c *defaultRepository) removeFailure(ctx context.Context, key loginAttempts.Key) error {
+	const op = "repositories.loginAttempts.postgresRepository.removeFailure"
+	c.logger.LogInfo("%s: start[key=%s]", op, key)
+	if _, err := c.db.ExecContext(ctx, `UPDATE loginFailures SET count = count - 1 WHERE key = $1;`, string(key)); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM loginFailures WHERE key = $1 AND count <= 0;`, string(key)); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[key=%s]", op, key)
+	return nil
+}
+
+func (c *defaultRepository) ResetFailures(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+	const op = "repositories.loginAttempts.postgresRepository.ResetFailures"
+	existed, err := c.GetFailures(ctx, key)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current failures err: %v", op, err)
+				return err
+			}
+			return c.storeFailures(ctx, key, loginAttempts.Failures{})
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current failures err: %v", op, err)
+				return err
+			}
+			return c.storeFailures(ctx, key, existed)
+		},
+	}
+}
+
+func (c *defaultRepository) storeFailures(ctx context.Context, key loginAttempts.Key, failures loginAttempts.Failures) error {
+	const op = "repositories.loginAttempts.postgresRepository.storeFailures"
+	c.logger.LogInfo("%s: start[key=%s count=%d]", op, key, failures.Count)
+	var err error
+	if failures.Count == 0 {
+		_, err = c.db.ExecContext(ctx, `DELETE FROM loginFailures WHERE key = $1;`, string(key))
+	} else {
+		query := `
+INSERT INTO loginFailures(key, count, lastFailure) VALUES ($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET count = $2, lastFailure = $3;
+`
+		_, err = c.db.ExecContext(ctx, query, string(key), failures.Count, failures.LastFailureTimestamp)
+	}
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[key=%s count=%d]", op, key, failures.Count)
+	return nil
+}
+
+func (c *defaultRepository) RecordFailedLogin(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+	id := uuid.New().String()
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.recordFailedLogin(ctx, id, login)
+		},
+		Rollback: func() error {
+			return c.removeFailedLogin(ctx, id)
+		},
+	}
+}
+
+func (c *defaultRepository) recordFailedLogin(ctx context.Context, id string, login loginAttempts.FailedLogin) error {
+	const op = "repositories.loginAttempts.postgresRepository.recordFailedLogin"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `INSERT INTO failedLogins(id, uid, email, ip, timestamp) VALUES ($1, $2, $3, $4, $5);`
+	var uid sql.NullString
+	if login.UserId != nil {
+		uid = sql.NullString{
+			String: string(*login.UserId),
+			Valid:  true,
+		}
+	}
+	_, err := c.db.ExecContext(ctx, query, id, uid, login.Email, login.Ip, login.Timestamp)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
+
+func (c *defaultRepository) removeFailedLogin(ctx context.Context, id string) error {
+	const op = "repositories.loginAttempts.postgresRepository.removeFailedLogin"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	_, err := c.db.ExecContext(ctx, `DELETE FROM failedLogins WHERE id = $1;`, id)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
+
+func (c *defaultRepository) GetFailedLogins(ctx context.Context, uid loginAttempts.UserId) ([]loginAttempts.FailedLogin, error) {
+	const op = "repositories.loginAttempts.postgresRepository.GetFailedLogins"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT email, ip, timestamp FROM failedLogins WHERE uid = $1 ORDER BY timestamp DESC;`
+	rows, err := c.db.QueryContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return []loginAttempts.FailedLogin{}, err
+	}
+	defer rows.Close()
+	logins := []loginAttempts.FailedLogin{}
+	for rows.Next() {
+		login := loginAttempts.FailedLogin{
+			UserId: &uid,
+		}
+		if err := rows.Scan(&login.Email, &login.Ip, &login.Timestamp); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return []loginAttempts.FailedLogin{}, err
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return []loginAttempts.FailedLogin{}, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return logins, nil
+}
+
+func (c *defaultRepository) RemoveFailedLogins(ctx context.Context, uid loginAttempts.UserId, email string) repositories.MutationWorkItem {
+	const op = "repositories.loginAttempts.postgresRepository.RemoveFailedLogins"
+	existed, err := c.getFailedLoginRecords(ctx, uid, email)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current failed logins err: %v", op, err)
+				return err
+			}
+			return c.removeFailedLogins(ctx, uid, email)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current failed logins err: %v", op, err)
+				return err
+			}
+			for id, login := range existed {
+				if err := c.recordFailedLogin(ctx, id, login); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (c *defaultRepository) removeFailedLogins(ctx context.Context, uid loginAttempts.UserId, email string) error {
+	const op = "repositories.loginAttempts.postgresRepository.removeFailedLogins"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `DELETE FROM failedLogins WHERE uid = $1 OR lower(email) = lower($2);`
+	_, err := c.db.ExecContext(ctx, query, string(uid), email)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
+
+func (c *defaultRepository) getFailedLoginRecords(ctx context.Context, uid loginAttempts.UserId, email string) (map[string]loginAttempts.FailedLogin, error) {
+	const op = "repositories.loginAttempts.postgresRepository.getFailedLoginRecords"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT id, uid, email, ip, timestamp FROM failedLogins WHERE uid = $1 OR lower(email) = lower($2);`
+	rows, err := c.db.QueryContext(ctx, query, string(uid), email)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	logins := map[string]loginAttempts.FailedLogin{}
+	for rows.Next() {
+		var id string
+		var loginUid sql.NullString
+		var login loginAttempts.FailedLogin
+		if err := rows.Scan(&id, &loginUid, &login.Email, &login.Ip, &login.Timestamp); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		if loginUid.Valid {
+			login.UserId = (*loginAttempts.UserId)(&loginUid.String)
+		}
+		logins[id] = login
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return logins, nil
+}
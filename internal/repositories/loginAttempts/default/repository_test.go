@@ -0,0 +1,270 @@
+package defaultRepository_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+	defaultRepository "github.com/rzmn/governi/internal/repositories/loginAttempts/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/loginAttempts/memory"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+
+	"github.com/google/uuid"
+)
+
+var (
+	newRepository func() loginAttempts.Repository
+)
+
+func TestMain(m *testing.M) {
+	logger := standartOutputLoggingService.New()
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() loginAttempts.Repository {
+			return defaultRepository.New(database, logger)
+		}
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() loginAttempts.Repository {
+			return repository
+		}
+	}
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func TestAddFailure(t *testing.T) {
+	repository := newRepository()
+	key := loginAttempts.Key(uuid.New().String())
+	timestamp := time.Now().Unix()
+
+	failuresFromDb, err := repository.GetFailures(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[initial] failed to get failures err: %v", err)
+	}
+	if failuresFromDb.Count != 0 {
+		t.Fatalf("[initial] failures count should be 0, found %d", failuresFromDb.Count)
+	}
+	for i := 1; i <= 3; i++ {
+		failures, err := repository.AddFailure(context.Background(), key, timestamp, timestamp-60).Perform()
+		if err != nil {
+			t.Fatalf("[%d] failed to perform `addTransaction` err: %v", i, err)
+		}
+		expected := loginAttempts.Failures{
+			Count:                i,
+			LastFailureTimestamp: timestamp,
+		}
+		if failures != expected {
+			t.Fatalf("[%d] added failures should be %v, found %v", i, expected, failures)
+		}
+	}
+	addTransaction := repository.AddFailure(context.Background(), key, timestamp+1, timestamp-60)
+	failures, err := addTransaction.Perform()
+	if err != nil {
+		t.Fatalf("failed to perform `addTransaction` err: %v", err)
+	}
+	if failures.Count != 4 || failures.LastFailureTimestamp != timestamp+1 {
+		t.Fatalf("added failures should be 4 at %d, found %v", timestamp+1, failures)
+	}
+	if err := addTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `addTransaction` err: %v", err)
+	}
+	failuresFromDb, err = repository.GetFailures(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[after add rollback] failed to get failures err: %v", err)
+	}
+	if failuresFromDb.Count != 3 {
+		t.Fatalf("[after add rollback] failures count should be 3, found %d", failuresFromDb.Count)
+	}
+	resetTransaction := repository.ResetFailures(context.Background(), key)
+	if err := resetTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `resetTransaction` err: %v", err)
+	}
+	failuresFromDb, err = repository.GetFailures(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[after reset] failed to get failures err: %v", err)
+	}
+	if failuresFromDb.Count != 0 {
+		t.Fatalf("[after reset] failures count should be 0, found %d", failuresFromDb.Count)
+	}
+	if err := resetTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `resetTransaction` err: %v", err)
+	}
+	failuresFromDb, err = repository.GetFailures(context.Background(), key)
+	if err != nil {
+		t.Fatalf("[after reset rollback] failed to get failures err: %v", err)
+	}
+	if failuresFromDb.Count != 3 {
+		t.Fatalf("[after reset rollback] failures count should be 3, found %d", failuresFromDb.Count)
+	}
+}
+
+func TestAddFailureForgetsStaleFailures(t *testing.T) {
+	repository := newRepository()
+	key := loginAttempts.Key(uuid.New().String())
+	timestamp := time.Now().Unix()
+
+	for i := 0; i < 3; i++ {
+		if _, err := repository.AddFailure(context.Background(), key, timestamp-3600, timestamp-7200).Perform(); err != nil {
+			t.Fatalf("[stale %d] failed to perform `addTransaction` err: %v", i, err)
+		}
+	}
+	failures, err := repository.AddFailure(context.Background(), key, timestamp, timestamp-60).Perform()
+	if err != nil {
+		t.Fatalf("failed to perform `addTransaction` err: %v", err)
+	}
+	if failures.Count != 1 || failures.LastFailureTimestamp != timestamp {
+		t.Fatalf("stale failures should be forgotten, found %v", failures)
+	}
+}
+
+func TestAddFailureConcurrently(t *testing.T) {
+	const attempts = 20
+	repository := newRepository()
+	key := loginAttempts.Key(uuid.New().String())
+	timestamp := time.Now().Unix()
+
+	var wg sync.WaitGroup
+	counts := make(chan int, attempts)
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			failures, err := repository.AddFailure(context.Background(), key, timestamp, timestamp-60).Perform()
+			if err != nil {
+				errs <- err
+				return
+			}
+			counts <- failures.Count
+		}()
+	}
+	wg.Wait()
+	close(counts)
+	close(errs)
+	for err := range errs {
+		t.Fatalf("failed to perform `addTransaction` err: %v", err)
+	}
+	seen := map[int]bool{}
+	for count := range counts {
+		if seen[count] {
+			t.Fatalf("count %d returned to more than one concurrent attempt", count)
+		}
+		seen[count] = true
+	}
+	failuresFromDb, err := repository.GetFailures(context.Background(), key)
+	if err != nil {
+		t.Fatalf("failed to get failures err: %v", err)
+	}
+	if failuresFromDb.Count != attempts {
+		t.Fatalf("failures count should be %d, found %d", attempts, failuresFromDb.Count)
+	}
+}
+
+func TestRecordFailedLogin(t *testing.T) {
+	repository := newRepository()
+	userId := loginAttempts.UserId(uuid.New().String())
+	login := loginAttempts.FailedLogin{
+		UserId:    &userId,
+		Email:     uuid.New().String(),
+		Ip:        "127.0.0.1",
+		Timestamp: time.Now().Unix(),
+	}
+
+	recordTransaction := repository.RecordFailedLogin(context.Background(), login)
+	if err := recordTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `recordTransaction` err: %v", err)
+	}
+	logins, err := repository.GetFailedLogins(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get failed logins err: %v", err)
+	}
+	if len(logins) != 1 || logins[0].Email != login.Email || logins[0].Ip != login.Ip || logins[0].Timestamp != login.Timestamp {
+		t.Fatalf("failed logins should be [%v], found %v", login, logins)
+	}
+	if err := recordTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `recordTransaction` err: %v", err)
+	}
+	logins, err = repository.GetFailedLogins(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get failed logins err: %v", err)
+	}
+	if len(logins) != 0 {
+		t.Fatalf("[after rollback] failed logins should be empty, found %v", logins)
+	}
+}
+
+func TestRemoveFailedLogins(t *testing.T) {
+	repository := newRepository()
+	userId := loginAttempts.UserId(uuid.New().String())
+	email := uuid.New().String() + "@x.com"
+	otherUserId := loginAttempts.UserId(uuid.New().String())
+	timestamp := time.Now().Unix()
+	logins := []loginAttempts.FailedLogin{
+		{UserId: &userId, Email: email, Ip: "127.0.0.1", Timestamp: timestamp},
+		{UserId: nil, Email: strings.ToUpper(email), Ip: "127.0.0.2", Timestamp: timestamp},
+		{UserId: &otherUserId, Email: uuid.New().String(), Ip: "127.0.0.3", Timestamp: timestamp},
+	}
+	for _, login := range logins {
+		if err := repository.RecordFailedLogin(context.Background(), login).Perform(); err != nil {
+			t.Fatalf("failed to record failed login err: %v", err)
+		}
+	}
+	removeTransaction := repository.RemoveFailedLogins(context.Background(), userId, email)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	removed, err := repository.GetFailedLogins(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get failed logins err: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("failed logins should be empty, found %v", removed)
+	}
+	kept, err := repository.GetFailedLogins(context.Background(), otherUserId)
+	if err != nil {
+		t.Fatalf("failed to get failed logins of other user err: %v", err)
+	}
+	if len(kept) != 1 {
+		t.Fatalf("failed logins of other user should be kept, found %v", kept)
+	}
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	restored, err := repository.GetFailedLogins(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get failed logins err: %v", err)
+	}
+	if len(restored) != 1 || restored[0].Email != email {
+		t.Fatalf("[after rollback] failed logins should be [%v], found %v", logins[0], restored)
+	}
+}
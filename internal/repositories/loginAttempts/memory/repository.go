@@ -0,0 +1,176 @@
+package memoryRepository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/google/uuid"
+)
+
+func New(logger logging.Service) loginAttempts.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			failures:     map[loginAttempts.Key]loginAttempts.Failures{},
+			failedLogins: map[string]loginAttempts.FailedLogin{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex        sync.RWMutex
+	failures     map[loginAttempts.Key]loginAttempts.Failures
+	failedLogins map[string]loginAttempts.FailedLogin
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) loginAttempts.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) GetFailures(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	return c.storage.failures[key], nil
+}
+
+func (c *memoryRepository) AddFailure(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+	var added bool
+	rollback := func() error {
+		if !added {
+			return nil
+		}
+		c.storage.mutex.Lock()
+		defer c.storage.mutex.Unlock()
+		failures := c.storage.failures[key]
+		failures.Count -= 1
+		if failures.Count <= 0 {
+			delete(c.storage.failures, key)
+		} else {
+			c.storage.failures[key] = failures
+		}
+		return nil
+	}
+	return repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (loginAttempts.Failures, error) {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			failures := c.storage.failures[key]
+			if failures.LastFailureTimestamp < forgetBefore {
+				failures.Count = 0
+			}
+			failures.Count += 1
+			failures.LastFailureTimestamp = timestamp
+			c.storage.failures[key] = failures
+			added = true
+			return failures, nil
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) ResetFailures(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+	existed, _ := c.GetFailures(ctx, key)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storeFailures(key, loginAttempts.Failures{})
+			return nil
+		},
+		Rollback: func() error {
+			c.storeFailures(key, existed)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) storeFailures(key loginAttempts.Key, failures loginAttempts.Failures) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if failures.Count == 0 {
+		delete(c.storage.failures, key)
+	} else {
+		c.storage.failures[key] = failures
+	}
+}
+
+func (c *memoryRepository) RecordFailedLogin(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+	id := uuid.New().String()
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			c.storage.failedLogins[id] = login
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			delete(c.storage.failedLogins, id)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) RemoveFailedLogins(ctx context.Context, uid loginAttempts.UserId, email string) repositories.MutationWorkItem {
+	var removed map[string]loginAttempts.FailedLogin
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			removed = map[string]loginAttempts.FailedLogin{}
+			for id, login := range c.storage.failedLogins {
+				if (login.UserId != nil && *login.UserId == uid) || strings.EqualFold(login.Email, email) {
+					removed[id] = login
+					delete(c.storage.failedLogins, id)
+				}
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			for id, login := range removed {
+				c.storage.failedLogins[id] = login
+			}
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) GetFailedLogins(ctx context.Context, uid loginAttempts.UserId) ([]loginAttempts.FailedLogin, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	logins := []loginAttempts.FailedLogin{}
+	for _, login := range c.storage.failedLogins {
+		if login.UserId != nil && *login.UserId == uid {
+			login.UserId = &uid
+			logins = append(logins, login)
+		}
+	}
+	sort.SliceStable(logins, func(i, j int) bool {
+		return logins[i].Timestamp > logins[j].Timestamp
+	})
+	return logins, nil
+}
@@ -0,0 +1,46 @@
+package loginAttempts_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/loginAttempts"
+)
+
+type RepositoryMock struct {
+	GetFailuresImpl        func(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error)
+	AddFailureImpl         func(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures]
+	ResetFailuresImpl      func(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem
+	RecordFailedLoginImpl  func(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem
+	GetFailedLoginsImpl    func(ctx context.Context, uid loginAttempts.UserId) ([]loginAttempts.FailedLogin, error)
+	RemoveFailedLoginsImpl func(ctx context.Context, uid loginAttempts.UserId, email string) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) GetFailures(ctx context.Context, key loginAttempts.Key) (loginAttempts.Failures, error) {
+	return c.GetFailuresImpl(ctx, key)
+}
+
+func (c *RepositoryMock) AddFailure(ctx context.Context, key loginAttempts.Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[loginAttempts.Failures] {
+	return c.AddFailureImpl(ctx, key, timestamp, forgetBefore)
+}
+
+func (c *RepositoryMock) ResetFailures(ctx context.Context, key loginAttempts.Key) repositories.MutationWorkItem {
+	return c.ResetFailuresImpl(ctx, key)
+}
+
+func (c *RepositoryMock) RecordFailedLogin(ctx context.Context, login loginAttempts.FailedLogin) repositories.MutationWorkItem {
+	return c.RecordFailedLoginImpl(ctx, login)
+}
+
+func (c *RepositoryMock) GetFailedLogins(ctx context.Context, uid loginAttempts.UserId) ([]loginAttempts.FailedLogin, error) {
+	return c.GetFailedLoginsImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) RemoveFailedLogins(ctx context.Context, uid loginAttempts.UserId, email string) repositories.MutationWorkItem {
+	return c.RemoveFailedLoginsImpl(ctx, uid, email)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) loginAttempts.Repository {
+	return c
+}
@@ -0,0 +1,38 @@
+package loginAttempts
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+)
+
+type UserId string
+type Key string
+
+type Failures struct {
+	Count                int
+	LastFailureTimestamp int64
+}
+
+type FailedLogin struct {
+	UserId    *UserId
+	Email     string
+	Ip        string
+	Timestamp int64
+}
+
+type Repository interface {
+	GetFailures(ctx context.Context, key Key) (Failures, error)
+	// AddFailure atomically increments the failures counter of key and returns the updated value.
+	// A counter whose last failure happened before forgetBefore starts over from one.
+	AddFailure(ctx context.Context, key Key, timestamp int64, forgetBefore int64) repositories.MutationWorkItemWithReturnValue[Failures]
+	ResetFailures(ctx context.Context, key Key) repositories.MutationWorkItem
+
+	RecordFailedLogin(ctx context.Context, login FailedLogin) repositories.MutationWorkItem
+	GetFailedLogins(ctx context.Context, uid UserId) ([]FailedLogin, error)
+	// RemoveFailedLogins removes failed logins of uid along with those attempted with its email.
+	RemoveFailedLogins(ctx context.Context, uid UserId, email string) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
+}
@@ -9,3 +9,12 @@ type MutationWorkItemWithReturnValue[T any] struct {
 	Perform  func() (T, error)
 	Rollback func() error
 }
+
+func PerformAll(items ...MutationWorkItem) error {
+	for _, item := range items {
+		if err := item.Perform(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package defaultRepository
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func New(db db.DB, logger logging.Service) outbox.Repository {
+	return &defaultRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type defaultRepository struct {
+	db     db.DB
+	logger logging.Service
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) outbox.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) AddMessages(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+	ids := make([]string, len(messages))
+	for i := range messages {
+		ids[i] = uuid.New().String()
+	}
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			return c.addMessages(ctx, messages, ids)
+		},
+		Rollback: func() error {
+			return c.removeMessages(ctx, ids)
+		},
+	}
+}
+
+func (c *defaultRepository) addMessages(ctx context.Context, messages []outbox.Message, ids []string) error {
+	const op = "repositories.outbox.postgresRepository.addMessages"
+	c.logger.LogInfo("%s: start[count=%d]", op, len(messages))
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		for i, message := range messages {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO outbox(id, kind, payload) VALUES ($1, $2, $3);`, ids[i], message.Kind, message.Payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[count=%d]", op, len(messages))
+	return nil
+}
+
+func (c *defaultRepository) removeMessages(ctx context.Context, ids []string) error {
+	const op = "repositories.outbox.postgresRepository.removeMessages"
+	c.logger.LogInfo("%s: start[count=%d]", op, len(ids))
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM outbox WHERE id = ANY($1);`, pq.Array(ids)); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[count=%d]", op, len(ids))
+	return nil
+}
+
+func (c *defaultRepository) LeaseMessages(ctx context.Context, now int64, leaseUntil int64, limit int) ([]outbox.Message, error) {
+	const op = "repositories.outbox.postgresRepository.LeaseMessages"
+	c.logger.LogInfo("%s: start[now=%d limit=%d]", op, now, limit)
+	query := `
+WITH leased AS (
+	UPDATE outbox SET attempts = attempts + 1, nextAttemptAt = $2
+	WHERE id IN (
+		SELECT id FROM outbox
+		WHERE deadLetteredAt IS NULL AND nextAttemptAt <= $1
+		ORDER BY sequence
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, sequence, kind, payload, attempts, tokens
+)
+SELECT id, kind, payload, attempts, tokens FROM leased ORDER BY sequence;
+`
+	rows, err := c.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []outbox.Message{}
+	for rows.Next() {
+		var message outbox.Message
+		if err := rows.Scan(&message.Id, &message.Kind, &message.Payload, &message.Attempts, pq.Array(&message.Tokens)); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[now=%d limit=%d count=%d]", op, now, limit, len(messages))
+	return messages, nil
+}
+
+func (c *defaultRepository) RenewLeases(ctx context.Context, ids []outbox.MessageId, leasedUntil int64, leaseUntil int64) ([]outbox.MessageId, error) {
+	const op = "repositories.outbox.postgresRepository.RenewLeases"
+	c.logger.LogInfo("%s: start[count=%d leaseUntil=%d]", op, len(ids), leaseUntil)
+	query := `UPDATE outbox SET nextAttemptAt = $3 WHERE id = ANY($1) AND nextAttemptAt = $2 AND deadLetteredAt IS NULL RETURNING id;`
+	rawIds := make([]string, len(ids))
+	for i, id := range ids {
+		rawIds[i] = string(id)
+	}
+	rows, err := c.db.QueryContext(ctx, query, pq.Array(rawIds), leasedUntil, leaseUntil)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	renewed := []outbox.MessageId{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		renewed = append(renewed, outbox.MessageId(id))
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[count=%d renewed=%d]", op, len(ids), len(renewed))
+	return renewed, nil
+}
+
+func (c *defaultRepository) RemoveMessage(ctx context.Context, id outbox.MessageId) error {
+	const op = "repositories.outbox.postgresRepository.RemoveMessage"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM outbox WHERE id = $1;`, string(id)); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
+
+func (c *defaultRepository) RetryMessage(ctx context.Context, id outbox.MessageId, nextAttemptAt int64, lastError string, tokens []string) error {
+	const op = "repositories.outbox.postgresRepository.RetryMessage"
+	c.logger.LogInfo("%s: start[id=%s nextAttemptAt=%d tokens=%d]", op, id, nextAttemptAt, len(tokens))
+	if tokens == nil {
+		tokens = []string{}
+	}
+	query := `UPDATE outbox SET nextAttemptAt = $2, lastError = $3, tokens = $4 WHERE id = $1;`
+	if _, err := c.db.ExecContext(ctx, query, string(id), nextAttemptAt, lastError, pq.Array(tokens)); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s nextAttemptAt=%d]", op, id, nextAttemptAt)
+	return nil
+}
+
+func (c *defaultRepository) DeadLetterMessage(ctx context.Context, id outbox.MessageId, deadLetteredAt int64, lastError string) error {
+	const op = "repositories.outbox.postgresRepository.DeadLetterMessage"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `UPDATE outbox SET deadLetteredAt = $2, lastError = $3 WHERE id = $1;`
+	if _, err := c.db.ExecContext(ctx, query, string(id), deadLetteredAt, lastError); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
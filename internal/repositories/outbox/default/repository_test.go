@@ -0,0 +1,184 @@
+package defaultRepository_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	defaultRepository "github.com/rzmn/governi/internal/repositories/outbox/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/outbox/memory"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+
+	"github.com/google/uuid"
+)
+
+var (
+	newRepository func() outbox.Repository
+)
+
+func TestMain(m *testing.M) {
+	logger := standartOutputLoggingService.New()
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() outbox.Repository {
+			return defaultRepository.New(database, logger)
+		}
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() outbox.Repository {
+			return repository
+		}
+	}
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+// leaseMessagesOfKind leases every due message and returns the ones of kind, so messages
+// left by other tests do not interfere.
+func leaseMessagesOfKind(t *testing.T, repository outbox.Repository, kind string, now int64, leaseUntil int64) []outbox.Message {
+	leased, err := repository.LeaseMessages(context.Background(), now, leaseUntil, 1000)
+	if err != nil {
+		t.Fatalf("failed to lease messages err: %v", err)
+	}
+	messages := []outbox.Message{}
+	for _, message := range leased {
+		if message.Kind == kind {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func TestAddAndLeaseMessages(t *testing.T) {
+	repository := newRepository()
+	kind := uuid.New().String()
+	transaction := repository.AddMessages(context.Background(), []outbox.Message{
+		{Kind: kind, Payload: []byte("first")},
+		{Kind: kind, Payload: []byte("second")},
+	})
+	if err := transaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `transaction` err: %v", err)
+	}
+	leased := leaseMessagesOfKind(t, repository, kind, 100, 200)
+	if len(leased) != 2 || string(leased[0].Payload) != "first" || string(leased[1].Payload) != "second" {
+		t.Fatalf("should lease added messages in order, found %v", leased)
+	}
+	for _, message := range leased {
+		if message.Attempts != 1 || message.Id == "" {
+			t.Fatalf("leased message should have id and one attempt, found %v", message)
+		}
+	}
+	if leasedAgain := leaseMessagesOfKind(t, repository, kind, 150, 300); len(leasedAgain) != 0 {
+		t.Fatalf("leased messages should be hidden until lease expires, found %v", leasedAgain)
+	}
+	expired := leaseMessagesOfKind(t, repository, kind, 200, 300)
+	if len(expired) != 2 || expired[0].Attempts != 2 {
+		t.Fatalf("messages should be leased again after lease expires, found %v", expired)
+	}
+	if err := repository.RemoveMessage(context.Background(), expired[0].Id); err != nil {
+		t.Fatalf("failed to remove message err: %v", err)
+	}
+	if err := transaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `transaction` err: %v", err)
+	}
+	if rolledBack := leaseMessagesOfKind(t, repository, kind, 1000, 2000); len(rolledBack) != 0 {
+		t.Fatalf("messages should be removed after rollback, found %v", rolledBack)
+	}
+}
+
+func TestRetryAndDeadLetterMessages(t *testing.T) {
+	repository := newRepository()
+	kind := uuid.New().String()
+	if err := repository.AddMessages(context.Background(), []outbox.Message{
+		{Kind: kind, Payload: []byte("retried")},
+		{Kind: kind, Payload: []byte("deadLettered")},
+	}).Perform(); err != nil {
+		t.Fatalf("failed to add messages err: %v", err)
+	}
+	leased := leaseMessagesOfKind(t, repository, kind, 100, 200)
+	if len(leased) != 2 {
+		t.Fatalf("should lease added messages, found %v", leased)
+	}
+	if err := repository.RetryMessage(context.Background(), leased[0].Id, 150, "failed", []string{"a", "b"}); err != nil {
+		t.Fatalf("failed to retry message err: %v", err)
+	}
+	if err := repository.DeadLetterMessage(context.Background(), leased[1].Id, 100, "failed"); err != nil {
+		t.Fatalf("failed to dead letter message err: %v", err)
+	}
+	retried := leaseMessagesOfKind(t, repository, kind, 150, 300)
+	if len(retried) != 1 || retried[0].Id != leased[0].Id || retried[0].Attempts != 2 {
+		t.Fatalf("only retried message should be leased, found %v", retried)
+	}
+	if !reflect.DeepEqual(retried[0].Tokens, []string{"a", "b"}) {
+		t.Fatalf("retried message should keep undelivered tokens, found %v", retried[0].Tokens)
+	}
+	if err := repository.RemoveMessage(context.Background(), retried[0].Id); err != nil {
+		t.Fatalf("failed to remove message err: %v", err)
+	}
+	if remaining := leaseMessagesOfKind(t, repository, kind, 1000, 2000); len(remaining) != 0 {
+		t.Fatalf("dead lettered message should not be leased, found %v", remaining)
+	}
+}
+
+func TestRenewLeases(t *testing.T) {
+	repository := newRepository()
+	kind := uuid.New().String()
+	if err := repository.AddMessages(context.Background(), []outbox.Message{
+		{Kind: kind, Payload: []byte("renewed")},
+		{Kind: kind, Payload: []byte("expired")},
+	}).Perform(); err != nil {
+		t.Fatalf("failed to add messages err: %v", err)
+	}
+	leased := leaseMessagesOfKind(t, repository, kind, 100, 200)
+	if len(leased) != 2 {
+		t.Fatalf("should lease added messages, found %v", leased)
+	}
+	if released := leaseMessagesOfKind(t, repository, kind, 200, 300); len(released) != 2 {
+		t.Fatalf("messages should be leased again once the lease runs out, found %v", released)
+	}
+	// the first lease ran out, so neither of the messages is renewed with it
+	renewed, err := repository.RenewLeases(context.Background(), []outbox.MessageId{leased[0].Id, leased[1].Id}, 200, 400)
+	if err != nil {
+		t.Fatalf("failed to renew leases err: %v", err)
+	}
+	if len(renewed) != 0 {
+		t.Fatalf("messages leased by another caller should not be renewed, found %v", renewed)
+	}
+	renewed, err = repository.RenewLeases(context.Background(), []outbox.MessageId{leased[0].Id}, 300, 400)
+	if err != nil {
+		t.Fatalf("failed to renew leases err: %v", err)
+	}
+	if !reflect.DeepEqual(renewed, []outbox.MessageId{leased[0].Id}) {
+		t.Fatalf("current lease should be renewed, found %v", renewed)
+	}
+	if due := leaseMessagesOfKind(t, repository, kind, 300, 500); len(due) != 1 || due[0].Id != leased[1].Id {
+		t.Fatalf("only the message with not renewed lease should be due, found %v", due)
+	}
+}
@@ -0,0 +1,158 @@
+package memoryRepository
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/google/uuid"
+)
+
+func New(logger logging.Service) outbox.Repository {
+	return &memoryRepository{
+		storage: &storage{},
+		logger:  logger,
+	}
+}
+
+type record struct {
+	message        outbox.Message
+	nextAttemptAt  int64
+	lastError      string
+	deadLetteredAt *int64
+}
+
+type storage struct {
+	mutex   sync.RWMutex
+	records []record
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) outbox.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) AddMessages(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+	ids := make([]outbox.MessageId, len(messages))
+	for i := range messages {
+		ids[i] = outbox.MessageId(uuid.New().String())
+	}
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			for i, message := range messages {
+				c.storage.records = append(c.storage.records, record{
+					message: outbox.Message{
+						Id:      ids[i],
+						Kind:    message.Kind,
+						Payload: append([]byte(nil), message.Payload...),
+					},
+				})
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			c.storage.records = slices.DeleteFunc(c.storage.records, func(record record) bool {
+				return slices.Contains(ids, record.message.Id)
+			})
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) LeaseMessages(ctx context.Context, now int64, leaseUntil int64, limit int) ([]outbox.Message, error) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	messages := []outbox.Message{}
+	for i := range c.storage.records {
+		if len(messages) == limit {
+			break
+		}
+		record := &c.storage.records[i]
+		if record.deadLetteredAt != nil || record.nextAttemptAt > now {
+			continue
+		}
+		record.message.Attempts += 1
+		record.nextAttemptAt = leaseUntil
+		message := record.message
+		message.Payload = append([]byte(nil), message.Payload...)
+		message.Tokens = slices.Clone(message.Tokens)
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (c *memoryRepository) RenewLeases(ctx context.Context, ids []outbox.MessageId, leasedUntil int64, leaseUntil int64) ([]outbox.MessageId, error) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	renewed := []outbox.MessageId{}
+	for i := range c.storage.records {
+		record := &c.storage.records[i]
+		if !slices.Contains(ids, record.message.Id) || record.deadLetteredAt != nil || record.nextAttemptAt != leasedUntil {
+			continue
+		}
+		record.nextAttemptAt = leaseUntil
+		renewed = append(renewed, record.message.Id)
+	}
+	return renewed, nil
+}
+
+func (c *memoryRepository) RemoveMessage(ctx context.Context, id outbox.MessageId) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	c.storage.records = slices.DeleteFunc(c.storage.records, func(record record) bool {
+		return record.message.Id == id
+	})
+	return nil
+}
+
+func (c *memoryRepository) RetryMessage(ctx context.Context, id outbox.MessageId, nextAttemptAt int64, lastError string, tokens []string) error {
+	c.update(id, func(record *record) {
+		record.nextAttemptAt = nextAttemptAt
+		record.lastError = lastError
+		record.message.Tokens = slices.Clone(tokens)
+	})
+	return nil
+}
+
+func (c *memoryRepository) DeadLetterMessage(ctx context.Context, id outbox.MessageId, deadLetteredAt int64, lastError string) error {
+	c.update(id, func(record *record) {
+		record.deadLetteredAt = &deadLetteredAt
+		record.lastError = lastError
+	})
+	return nil
+}
+
+func (c *memoryRepository) update(id outbox.MessageId, mutate func(record *record)) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	for i := range c.storage.records {
+		if c.storage.records[i].message.Id == id {
+			mutate(&c.storage.records[i])
+			return
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package outbox_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/outbox"
+)
+
+type RepositoryMock struct {
+	AddMessagesImpl       func(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem
+	LeaseMessagesImpl     func(ctx context.Context, now int64, leaseUntil int64, limit int) ([]outbox.Message, error)
+	RenewLeasesImpl       func(ctx context.Context, ids []outbox.MessageId, leasedUntil int64, leaseUntil int64) ([]outbox.MessageId, error)
+	RemoveMessageImpl     func(ctx context.Context, id outbox.MessageId) error
+	RetryMessageImpl      func(ctx context.Context, id outbox.MessageId, nextAttemptAt int64, lastError string, tokens []string) error
+	DeadLetterMessageImpl func(ctx context.Context, id outbox.MessageId, deadLetteredAt int64, lastError string) error
+}
+
+func (c *RepositoryMock) AddMessages(ctx context.Context, messages []outbox.Message) repositories.MutationWorkItem {
+	return c.AddMessagesImpl(ctx, messages)
+}
+
+func (c *RepositoryMock) LeaseMessages(ctx context.Context, now int64, leaseUntil int64, limit int) ([]outbox.Message, error) {
+	return c.LeaseMessagesImpl(ctx, now, leaseUntil, limit)
+}
+
+func (c *RepositoryMock) RenewLeases(ctx context.Context, ids []outbox.MessageId, leasedUntil int64, leaseUntil int64) ([]outbox.MessageId, error) {
+	return c.RenewLeasesImpl(ctx, ids, leasedUntil, leaseUntil)
+}
+
+func (c *RepositoryMock) RemoveMessage(ctx context.Context, id outbox.MessageId) error {
+	return c.RemoveMessageImpl(ctx, id)
+}
+
+func (c *RepositoryMock) RetryMessage(ctx context.Context, id outbox.MessageId, nextAttemptAt int64, lastError string, tokens []string) error {
+	return c.RetryMessageImpl(ctx, id, nextAttemptAt, lastError, tokens)
+}
+
+func (c *RepositoryMock) DeadLetterMessage(ctx context.Context, id outbox.MessageId, deadLetteredAt int64, lastError string) error {
+	return c.DeadLetterMessageImpl(ctx, id, deadLetteredAt, lastError)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) outbox.Repository {
+	return c
+}
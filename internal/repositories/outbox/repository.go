@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+)
+
+type MessageId string
+
+type Message struct {
+	Id       MessageId
+	Kind     string
+	Payload  []byte
+	Attempts int
+	// Tokens are the push tokens of the receiver a push is still to be delivered to, empty
+	// when the push goes to every token of the receiver.
+	Tokens []string
+}
+
+type Repository interface {
+	AddMessages(ctx context.Context, messages []Message) repositories.MutationWorkItem
+
+	// LeaseMessages returns up to limit messages that are due at now, in the order they were added,
+	// and hides them from other callers until leaseUntil. Attempts of returned messages are incremented.
+	LeaseMessages(ctx context.Context, now int64, leaseUntil int64, limit int) ([]Message, error)
+	// RenewLeases extends the lease of messages that are still leased until leasedUntil and returns
+	// ids of the renewed ones, a message whose lease ran out may have been leased by another caller.
+	RenewLeases(ctx context.Context, ids []MessageId, leasedUntil int64, leaseUntil int64) ([]MessageId, error)
+	RemoveMessage(ctx context.Context, id MessageId) error
+	// RetryMessage schedules the next attempt, which delivers a push only to tokens when they are not empty.
+	RetryMessage(ctx context.Context, id MessageId, nextAttemptAt int64, lastError string, tokens []string) error
+	DeadLetterMessage(ctx context.Context, id MessageId, deadLetteredAt int64, lastError string) error
+
+	WithTx(tx db.DB) Repository
+}
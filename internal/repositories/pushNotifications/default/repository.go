@@ -1,6 +1,8 @@
 package defaultRepository
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/pushNotifications"
@@ -19,87 +21,156 @@ type defaultRepository struct {
 	logger logging.Service
 }
 
-func (c *defaultRepository) StorePushToken(uid pushNotifications.UserId, token string) repositories.MutationWorkItem {
+type ownedPushToken struct {
+	uid   pushNotifications.UserId
+	token pushNotifications.PushToken
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) pushNotifications.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) StorePushToken(ctx context.Context, uid pushNotifications.UserId, token pushNotifications.PushToken) repositories.MutationWorkItem {
 	const op = "repositories.pushNotifications.postgresRepository.StorePushToken"
-	currentToken, err := c.GetPushToken(uid)
+	current, err := c.getOwnedPushTokens(ctx, `SELECT id, platform, token, appVersion FROM pushTokens WHERE token = $1;`, token.Token)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current token info err: %v", op, err)
 				return err
 			}
-			return c.storePushToken(uid, token)
+			return c.storePushToken(ctx, ownedPushToken{
+				uid:   uid,
+				token: token,
+			})
 		},
 		Rollback: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current token info err: %v", op, err)
 				return err
 			}
-			if currentToken == nil {
-				return c.removePushToken(uid)
-			} else {
-				return c.storePushToken(uid, *currentToken)
+			if len(current) == 0 {
+				return c.removePushTokens(ctx, `DELETE FROM pushTokens WHERE token = $1;`, token.Token)
 			}
+			return c.storePushToken(ctx, current[0])
 		},
 	}
 }
 
-func (c *defaultRepository) storePushToken(uid pushNotifications.UserId, token string) error {
+func (c *defaultRepository) storePushToken(ctx context.Context, token ownedPushToken) error {
 	const op = "repositories.pushNotifications.postgresRepository.storePushToken"
-	c.logger.LogInfo("%s: start[uid=%v]", op, uid)
+	c.logger.LogInfo("%s: start[uid=%v platform=%s]", op, token.uid, token.token.Platform)
 	query := `
-INSERT INTO pushTokens(id, token) VALUES ($1, $2) 
-ON CONFLICT (id) DO UPDATE SET token = $2;
+INSERT INTO pushTokens(id, platform, token, appVersion) VALUES ($1, $2, $3, $4) 
+ON CONFLICT (token) DO UPDATE SET id = $1, platform = $2, appVersion = $4;
 `
-	_, err := c.db.Exec(query, string(uid), token)
+	_, err := c.db.ExecContext(ctx, query, string(token.uid), string(token.token.Platform), token.token.Token, token.token.AppVersion)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
 	}
-	c.logger.LogInfo("%s: success[uid=%v]", op, uid)
+	c.logger.LogInfo("%s: success[uid=%v platform=%s]", op, token.uid, token.token.Platform)
 	return nil
 }
 
-func (c *defaultRepository) removePushToken(uid pushNotifications.UserId) error {
-	const op = "repositories.pushNotifications.postgresRepository.removePushToken"
-	c.logger.LogInfo("%s: start[uid=%v]", op, uid)
-	query := `DELETE FROM pushTokens WHERE id = $1;`
-	_, err := c.db.Exec(query, string(uid))
-	if err != nil {
+func (c *defaultRepository) RemovePushToken(ctx context.Context, token string) repositories.MutationWorkItem {
+	const op = "repositories.pushNotifications.postgresRepository.RemovePushToken"
+	current, err := c.getOwnedPushTokens(ctx, `SELECT id, platform, token, appVersion FROM pushTokens WHERE token = $1;`, token)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current token info err: %v", op, err)
+				return err
+			}
+			return c.removePushTokens(ctx, `DELETE FROM pushTokens WHERE token = $1;`, token)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current token info err: %v", op, err)
+				return err
+			}
+			return c.restorePushTokens(ctx, current)
+		},
+	}
+}
+
+func (c *defaultRepository) RemovePushTokens(ctx context.Context, uid pushNotifications.UserId) repositories.MutationWorkItem {
+	const op = "repositories.pushNotifications.postgresRepository.RemovePushTokens"
+	current, err := c.getOwnedPushTokens(ctx, `SELECT id, platform, token, appVersion FROM pushTokens WHERE id = $1;`, string(uid))
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current token info err: %v", op, err)
+				return err
+			}
+			return c.removePushTokens(ctx, `DELETE FROM pushTokens WHERE id = $1;`, string(uid))
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current token info err: %v", op, err)
+				return err
+			}
+			return c.restorePushTokens(ctx, current)
+		},
+	}
+}
+
+func (c *defaultRepository) restorePushTokens(ctx context.Context, tokens []ownedPushToken) error {
+	for _, token := range tokens {
+		if err := c.storePushToken(ctx, token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *defaultRepository) removePushTokens(ctx context.Context, query string, arg string) error {
+	const op = "repositories.pushNotifications.postgresRepository.removePushTokens"
+	c.logger.LogInfo("%s: start", op)
+	if _, err := c.db.ExecContext(ctx, query, arg); err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
 	}
-	c.logger.LogInfo("%s: success[uid=%v]", op, uid)
+	c.logger.LogInfo("%s: success", op)
 	return nil
 }
 
-func (c *defaultRepository) GetPushToken(uid pushNotifications.UserId) (*string, error) {
-	const op = "repositories.pushNotifications.postgresRepository.GetPushToken"
+func (c *defaultRepository) GetPushTokens(ctx context.Context, uid pushNotifications.UserId) ([]pushNotifications.PushToken, error) {
+	const op = "repositories.pushNotifications.postgresRepository.GetPushTokens"
 	c.logger.LogInfo("%s: start[uid=%v]", op, uid)
-	query := `SELECT token FROM pushTokens WHERE id = $1;`
-	rows, err := c.db.Query(query, string(uid))
+	owned, err := c.getOwnedPushTokens(ctx, `SELECT id, platform, token, appVersion FROM pushTokens WHERE id = $1 ORDER BY platform, token;`, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get tokens err: %v", op, err)
+		return nil, err
+	}
+	tokens := make([]pushNotifications.PushToken, 0, len(owned))
+	for _, token := range owned {
+		tokens = append(tokens, token.token)
+	}
+	c.logger.LogInfo("%s: success[uid=%v count=%d]", op, uid, len(tokens))
+	return tokens, nil
+}
+
+func (c *defaultRepository) getOwnedPushTokens(ctx context.Context, query string, arg string) ([]ownedPushToken, error) {
+	const op = "repositories.pushNotifications.postgresRepository.getOwnedPushTokens"
+	rows, err := c.db.QueryContext(ctx, query, arg)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return nil, err
 	}
 	defer rows.Close()
-	if rows.Next() {
-		var token string
-		if err := rows.Scan(&token); err != nil {
+	tokens := []ownedPushToken{}
+	for rows.Next() {
+		var token ownedPushToken
+		if err := rows.Scan(&token.uid, &token.token.Platform, &token.token.Token, &token.token.AppVersion); err != nil {
 			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
 			return nil, err
 		}
-		if err := rows.Err(); err != nil {
-			c.logger.LogInfo("%s: found rows err: %v", op, err)
-			return nil, err
-		}
-		c.logger.LogInfo("%s: success[uid=%v]", op, uid)
-		return &token, nil
+		tokens = append(tokens, token)
 	}
 	if err := rows.Err(); err != nil {
 		c.logger.LogInfo("%s: found rows err: %v", op, err)
 		return nil, err
 	}
-	c.logger.LogInfo("%s: success[uid=%v]", op, uid)
-	return nil, nil
+	return tokens, nil
 }
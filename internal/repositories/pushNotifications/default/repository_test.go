@@ -1,15 +1,19 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
+	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/rzmn/governi/internal/db"
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
 	"github.com/rzmn/governi/internal/repositories/pushNotifications"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -17,30 +21,41 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() pushNotifications.Repository
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() pushNotifications.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() pushNotifications.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+	}
 	code := m.Run()
 
 	os.Exit(code)
@@ -50,82 +65,148 @@ func randomUid() pushNotifications.UserId {
 	return pushNotifications.UserId(uuid.New().String())
 }
 
-func TestStorePushToken(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
-
-	// initially token should be nil
+func randomToken(platform pushNotifications.Platform) pushNotifications.PushToken {
+	appVersion := "1.0.0"
+	return pushNotifications.PushToken{
+		Platform:   platform,
+		Token:      uuid.New().String(),
+		AppVersion: &appVersion,
+	}
+}
 
-	uid := randomUid()
-	shouldBeEmpty, err := repository.GetPushToken(uid)
+func assertTokens(t *testing.T, repository pushNotifications.Repository, uid pushNotifications.UserId, expected ...pushNotifications.PushToken) {
+	tokens, err := repository.GetPushTokens(context.Background(), uid)
 	if err != nil {
-		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
+		t.Fatalf("failed to get tokens err: %v", err)
 	}
-	if shouldBeEmpty != nil {
-		t.Fatalf("`shouldBeEmpty` unexpected value: %s", *shouldBeEmpty)
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, found %v", len(expected), tokens)
 	}
+	for _, token := range expected {
+		if !slices.ContainsFunc(tokens, func(found pushNotifications.PushToken) bool {
+			return found.Token == token.Token && found.Platform == token.Platform && reflect.DeepEqual(found.AppVersion, token.AppVersion)
+		}) {
+			t.Fatalf("expected token %v, found %v", token, tokens)
+		}
+	}
+}
 
-	// test store token when there is no token set previously
+func TestStorePushToken(t *testing.T) {
+	repository := newRepository()
 
-	token := uuid.New().String()
-	storeTransaction := repository.StorePushToken(uid, token)
-	if err := storeTransaction.Perform(); err != nil {
-		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
-	}
-	shouldBeEqualToToken, err := repository.GetPushToken(uid)
-	if err != nil {
-		t.Fatalf("failed to get `shouldBeEqualToToken` err: %v", err)
+	// initially there are no tokens
+
+	uid := randomUid()
+	assertTokens(t, repository, uid)
+
+	// test store tokens of several devices
+
+	iosToken := randomToken(pushNotifications.PlatformIos)
+	storeIosTransaction := repository.StorePushToken(context.Background(), uid, iosToken)
+	if err := storeIosTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `storeIosTransaction` err: %v", err)
 	}
-	if shouldBeEqualToToken == nil {
-		t.Fatalf("`shouldBeEqualToToken` is nil")
+	anotherIosToken := randomToken(pushNotifications.PlatformIos)
+	anotherIosToken.AppVersion = nil
+	if err := repository.StorePushToken(context.Background(), uid, anotherIosToken).Perform(); err != nil {
+		t.Fatalf("failed to store `anotherIosToken` err: %v", err)
 	}
-	if *shouldBeEqualToToken != token {
-		t.Fatalf("`shouldBeEqualToToken` should be equal to %s, found %s", token, *shouldBeEqualToToken)
+	androidToken := randomToken(pushNotifications.PlatformAndroid)
+	storeAndroidTransaction := repository.StorePushToken(context.Background(), uid, androidToken)
+	if err := storeAndroidTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `storeAndroidTransaction` err: %v", err)
 	}
+	assertTokens(t, repository, uid, iosToken, anotherIosToken, androidToken)
 
-	// test store token when there is some token set previously
+	// test store same token again updates its metadata
 
-	newToken := uuid.New().String()
-	updateTransaction := repository.StorePushToken(uid, newToken)
+	updatedVersion := "1.1.0"
+	updatedIosToken := iosToken
+	updatedIosToken.AppVersion = &updatedVersion
+	updateTransaction := repository.StorePushToken(context.Background(), uid, updatedIosToken)
 	if err := updateTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `updateTransaction` err: %v", err)
 	}
-	shouldBeEqualToNewToken, err := repository.GetPushToken(uid)
-	if err != nil {
-		t.Fatalf("failed to get `shouldBeEqualToNewToken` err: %v", err)
+	assertTokens(t, repository, uid, updatedIosToken, anotherIosToken, androidToken)
+
+	// test rollbacks
+
+	if err := updateTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `updateTransaction` err: %v", err)
 	}
-	if shouldBeEqualToNewToken == nil {
-		t.Fatalf("`shouldBeEqualToNewToken` is nil")
+	assertTokens(t, repository, uid, iosToken, anotherIosToken, androidToken)
+	if err := storeAndroidTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `storeAndroidTransaction` err: %v", err)
 	}
-	if *shouldBeEqualToNewToken != newToken {
-		t.Fatalf("`shouldBeEqualToNewToken` should be equal to %s, found %s", token, *shouldBeEqualToNewToken)
+	assertTokens(t, repository, uid, iosToken, anotherIosToken)
+}
+
+func TestStorePushTokenOfAnotherUser(t *testing.T) {
+	repository := newRepository()
+	uid := randomUid()
+	anotherUid := randomUid()
+	token := randomToken(pushNotifications.PlatformIos)
+
+	if err := repository.StorePushToken(context.Background(), uid, token).Perform(); err != nil {
+		t.Fatalf("failed to store token err: %v", err)
 	}
 
-	// test rollback store token when there is some token set previously
+	// device token belongs to the user who registered it last
 
-	if err := updateTransaction.Rollback(); err != nil {
-		t.Fatalf("failed to rollback `updateTransaction` err: %v", err)
+	moveTransaction := repository.StorePushToken(context.Background(), anotherUid, token)
+	if err := moveTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `moveTransaction` err: %v", err)
 	}
-	shouldBeEqualToToken, err = repository.GetPushToken(uid)
-	if err != nil {
-		t.Fatalf("[after rollback] failed to get `shouldBeEqualToToken` err: %v", err)
+	assertTokens(t, repository, uid)
+	assertTokens(t, repository, anotherUid, token)
+
+	if err := moveTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `moveTransaction` err: %v", err)
 	}
-	if shouldBeEqualToToken == nil {
-		t.Fatalf("[after rollback] `shouldBeEqualToToken` is nil")
+	assertTokens(t, repository, uid, token)
+	assertTokens(t, repository, anotherUid)
+}
+
+func TestRemovePushToken(t *testing.T) {
+	repository := newRepository()
+	uid := randomUid()
+	deadToken := randomToken(pushNotifications.PlatformIos)
+	aliveToken := randomToken(pushNotifications.PlatformIos)
+
+	for _, token := range []pushNotifications.PushToken{deadToken, aliveToken} {
+		if err := repository.StorePushToken(context.Background(), uid, token).Perform(); err != nil {
+			t.Fatalf("failed to store token err: %v", err)
+		}
 	}
-	if *shouldBeEqualToToken != token {
-		t.Fatalf("[after rollback] `shouldBeEqualToToken` should be equal to %s, found %s", token, *shouldBeEqualToToken)
+	removeTransaction := repository.RemovePushToken(context.Background(), deadToken.Token)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
 	}
+	assertTokens(t, repository, uid, aliveToken)
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	assertTokens(t, repository, uid, deadToken, aliveToken)
+}
 
-	// test rollback store token when there is no token set previously
+func TestRemovePushTokens(t *testing.T) {
+	repository := newRepository()
+	uid := randomUid()
+	iosToken := randomToken(pushNotifications.PlatformIos)
+	androidToken := randomToken(pushNotifications.PlatformAndroid)
 
-	if err := storeTransaction.Rollback(); err != nil {
-		t.Fatalf("failed to rollback `storeTransaction` err: %v", err)
+	for _, token := range []pushNotifications.PushToken{iosToken, androidToken} {
+		if err := repository.StorePushToken(context.Background(), uid, token).Perform(); err != nil {
+			t.Fatalf("failed to store token err: %v", err)
+		}
 	}
-	shouldBeEmpty, err = repository.GetPushToken(uid)
-	if err != nil {
-		t.Fatalf("[after rollback] failed to get `shouldBeEmpty` err: %v", err)
+	removeTransaction := repository.RemovePushTokens(context.Background(), uid)
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
 	}
-	if shouldBeEmpty != nil {
-		t.Fatalf("[after rollback] `shouldBeEmpty` unexpected value: %s", *shouldBeEmpty)
+	assertTokens(t, repository, uid)
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
 	}
+	assertTokens(t, repository, uid, iosToken, androidToken)
 }
@@ -0,0 +1,148 @@
+package memoryRepository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/pushNotifications"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) pushNotifications.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			tokens: map[string]record{},
+		},
+		logger: logger,
+	}
+}
+
+type record struct {
+	uid   pushNotifications.UserId
+	token pushNotifications.PushToken
+}
+
+type storage struct {
+	mutex  sync.RWMutex
+	tokens map[string]record
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) pushNotifications.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) StorePushToken(ctx context.Context, uid pushNotifications.UserId, token pushNotifications.PushToken) repositories.MutationWorkItem {
+	current := c.records(func(record record) bool {
+		return record.token.Token == token.Token
+	})
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.store(token.Token, &record{
+				uid:   uid,
+				token: token,
+			})
+			return nil
+		},
+		Rollback: func() error {
+			c.store(token.Token, nil)
+			c.restore(current)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) GetPushTokens(ctx context.Context, uid pushNotifications.UserId) ([]pushNotifications.PushToken, error) {
+	records := c.records(func(record record) bool {
+		return record.uid == uid
+	})
+	tokens := make([]pushNotifications.PushToken, 0, len(records))
+	for _, record := range records {
+		tokens = append(tokens, record.token)
+	}
+	slices.SortFunc(tokens, func(lhs, rhs pushNotifications.PushToken) int {
+		return cmp.Or(cmp.Compare(lhs.Platform, rhs.Platform), cmp.Compare(lhs.Token, rhs.Token))
+	})
+	return tokens, nil
+}
+
+func (c *memoryRepository) RemovePushToken(ctx context.Context, token string) repositories.MutationWorkItem {
+	current := c.records(func(record record) bool {
+		return record.token.Token == token
+	})
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.store(token, nil)
+			return nil
+		},
+		Rollback: func() error {
+			c.restore(current)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) RemovePushTokens(ctx context.Context, uid pushNotifications.UserId) repositories.MutationWorkItem {
+	current := c.records(func(record record) bool {
+		return record.uid == uid
+	})
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			for _, record := range current {
+				c.store(record.token.Token, nil)
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.restore(current)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) records(filter func(record record) bool) []record {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	records := []record{}
+	for _, record := range c.storage.tokens {
+		if filter(record) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (c *memoryRepository) restore(records []record) {
+	for _, record := range records {
+		c.store(record.token.Token, &record)
+	}
+}
+
+func (c *memoryRepository) store(token string, record *record) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if record == nil {
+		delete(c.storage.tokens, token)
+	} else {
+		c.storage.tokens[token] = *record
+	}
+}
@@ -1,19 +1,36 @@
 package pushNotifications_mock
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/pushNotifications"
 )
 
 type RepositoryMock struct {
-	StorePushTokenImpl func(uid pushNotifications.UserId, token string) repositories.MutationWorkItem
-	GetPushTokenImpl   func(uid pushNotifications.UserId) (*string, error)
+	StorePushTokenImpl   func(ctx context.Context, uid pushNotifications.UserId, token pushNotifications.PushToken) repositories.MutationWorkItem
+	GetPushTokensImpl    func(ctx context.Context, uid pushNotifications.UserId) ([]pushNotifications.PushToken, error)
+	RemovePushTokenImpl  func(ctx context.Context, token string) repositories.MutationWorkItem
+	RemovePushTokensImpl func(ctx context.Context, uid pushNotifications.UserId) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) StorePushToken(ctx context.Context, uid pushNotifications.UserId, token pushNotifications.PushToken) repositories.MutationWorkItem {
+	return c.StorePushTokenImpl(ctx, uid, token)
+}
+
+func (c *RepositoryMock) GetPushTokens(ctx context.Context, uid pushNotifications.UserId) ([]pushNotifications.PushToken, error) {
+	return c.GetPushTokensImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) RemovePushToken(ctx context.Context, token string) repositories.MutationWorkItem {
+	return c.RemovePushTokenImpl(ctx, token)
 }
 
-func (c *RepositoryMock) StorePushToken(uid pushNotifications.UserId, token string) repositories.MutationWorkItem {
-	return c.StorePushTokenImpl(uid, token)
+func (c *RepositoryMock) RemovePushTokens(ctx context.Context, uid pushNotifications.UserId) repositories.MutationWorkItem {
+	return c.RemovePushTokensImpl(ctx, uid)
 }
 
-func (c *RepositoryMock) GetPushToken(uid pushNotifications.UserId) (*string, error) {
-	return c.GetPushTokenImpl(uid)
+func (c *RepositoryMock) WithTx(tx db.DB) pushNotifications.Repository {
+	return c
 }
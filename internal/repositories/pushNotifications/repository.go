@@ -1,12 +1,32 @@
 package pushNotifications
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
 type UserId string
+type Platform string
+
+const (
+	PlatformIos     Platform = "ios"
+	PlatformAndroid Platform = "android"
+)
+
+type PushToken struct {
+	Platform   Platform
+	Token      string
+	AppVersion *string
+}
 
 type Repository interface {
-	StorePushToken(uid UserId, token string) repositories.MutationWorkItem
-	GetPushToken(uid UserId) (*string, error)
+	// StorePushToken adds a device token of the user, a token registered by another user before is moved to this one
+	StorePushToken(ctx context.Context, uid UserId, token PushToken) repositories.MutationWorkItem
+	GetPushTokens(ctx context.Context, uid UserId) ([]PushToken, error)
+	RemovePushToken(ctx context.Context, token string) repositories.MutationWorkItem
+	RemovePushTokens(ctx context.Context, uid UserId) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
 }
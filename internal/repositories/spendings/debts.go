@@ -0,0 +1,61 @@
+package spendings
+
+import (
+	"sort"
+)
+
+type Debt struct {
+	Debtor   CounterpartyId
+	Creditor CounterpartyId
+	Amount   Cost
+}
+
+// Debts splits shares of an expense into debts between its participants. Shares with a positive
+// cost are owed to, shares with a negative one owe; both sides are matched in the order of their
+// ids, so the same shares always produce the same debts.
+func Debts(shares []ShareOfExpense) ([]Debt, error) {
+	totals := map[CounterpartyId]Cost{}
+	var sum Cost
+	for _, share := range shares {
+		totals[share.Counterparty] += share.Cost
+		sum += share.Cost
+	}
+	if sum != 0 {
+		return nil, ErrUnbalancedExpense
+	}
+	creditors := []CounterpartyId{}
+	debtors := []CounterpartyId{}
+	for counterparty, total := range totals {
+		if total > 0 {
+			creditors = append(creditors, counterparty)
+		} else if total < 0 {
+			debtors = append(debtors, counterparty)
+			totals[counterparty] = -total
+		}
+	}
+	sort.Slice(creditors, func(i, j int) bool {
+		return creditors[i] < creditors[j]
+	})
+	sort.Slice(debtors, func(i, j int) bool {
+		return debtors[i] < debtors[j]
+	})
+	debts := []Debt{}
+	for i, j := 0, 0; i < len(creditors) && j < len(debtors); {
+		creditor, debtor := creditors[i], debtors[j]
+		amount := min(totals[creditor], totals[debtor])
+		debts = append(debts, Debt{
+			Debtor:   debtor,
+			Creditor: creditor,
+			Amount:   amount,
+		})
+		totals[creditor] -= amount
+		totals[debtor] -= amount
+		if totals[creditor] == 0 {
+			i++
+		}
+		if totals[debtor] == 0 {
+			j++
+		}
+	}
+	return debts, nil
+}
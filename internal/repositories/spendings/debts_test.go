@@ -0,0 +1,38 @@
+package spendings_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rzmn/governi/internal/repositories/spendings"
+)
+
+func TestDebts(t *testing.T) {
+	debts, err := spendings.Debts([]spendings.ShareOfExpense{
+		{Counterparty: "c", Cost: -40},
+		{Counterparty: "a", Cost: 70},
+		{Counterparty: "d", Cost: -50},
+		{Counterparty: "b", Cost: 20},
+	})
+	if err != nil {
+		t.Fatalf("failed to get debts err: %v", err)
+	}
+	expected := []spendings.Debt{
+		{Debtor: "c", Creditor: "a", Amount: 40},
+		{Debtor: "d", Creditor: "a", Amount: 30},
+		{Debtor: "d", Creditor: "b", Amount: 20},
+	}
+	if !reflect.DeepEqual(debts, expected) {
+		t.Fatalf("debts should be %v, found %v", expected, debts)
+	}
+}
+
+func TestDebtsUnbalanced(t *testing.T) {
+	if _, err := spendings.Debts([]spendings.ShareOfExpense{
+		{Counterparty: "a", Cost: 10},
+		{Counterparty: "b", Cost: -5},
+	}); !errors.Is(err, spendings.ErrUnbalancedExpense) {
+		t.Fatalf("debts of unbalanced shares should fail with `ErrUnbalancedExpense`, found %v", err)
+	}
+}
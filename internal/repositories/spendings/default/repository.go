@@ -24,65 +24,64 @@ type defaultRepository struct {
 	logger logging.Service
 }
 
-func (c *defaultRepository) AddExpense(expense spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId] {
+func (c *defaultRepository) WithTx(tx db.DB) spendings.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) AddExpense(ctx context.Context, expense spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId] {
 	const op = "repositories.spendings.postgresRepository.AddExpense"
 	expenseId := spendings.ExpenseId(uuid.New().String())
 	return repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId]{
 		Perform: func() (spendings.ExpenseId, error) {
-			if err := c.addExpense(expense, expenseId); err != nil {
+			if err := c.addExpense(ctx, expense, expenseId); err != nil {
 				c.logger.LogInfo("%s: failed to insert err: %v", op, err)
 				return expenseId, err
 			}
 			return expenseId, nil
 		},
 		Rollback: func() error {
-			return c.removeExpense(expenseId)
+			return c.removeExpense(ctx, expenseId)
 		},
 	}
 }
 
-func (c *defaultRepository) addExpense(expense spendings.Expense, id spendings.ExpenseId) error {
+func (c *defaultRepository) addExpense(ctx context.Context, expense spendings.Expense, id spendings.ExpenseId) error {
 	const op = "repositories.spendings.postgresRepository.addExpense"
 	c.logger.LogInfo("%s: start[expense=%v id=%s]", op, expense, id)
-	tx, err := c.db.BeginTx(context.Background(), nil)
+	debts, err := spendings.Debts(expense.Shares)
 	if err != nil {
-		c.logger.LogInfo("%s: failed to create tx err: %v", op, err)
+		c.logger.LogInfo("%s: bad shares err: %v", op, err)
 		return err
 	}
-	_, err = c.db.Exec(`
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, `
 INSERT INTO 
 	deals(id, timestamp, details, cost, currency) 
-VALUES($1, $2, $3, $4, $5);
-`, string(id), expense.Timestamp, expense.Details, int64(expense.Total), string(expense.Currency))
-	if err != nil {
-		c.logger.LogInfo("%s: failed to insert expense err: %v", op, err)
-		tx.Rollback()
-		return err
-	}
-	for i := 0; i < len(expense.Shares); i++ {
-		share := expense.Shares[i]
-		_, err = c.db.Exec(`
-INSERT INTO 
-	spendings(id, dealId, cost, counterparty) 
-VALUES($1, $2, $3, $4);
-		`, uuid.New().String(), string(id), int64(share.Cost), string(share.Counterparty))
-		if err != nil {
-			c.logger.LogInfo("%s: failed to insert share %d err: %v", op, i, err)
-			tx.Rollback()
+VALUES($1, to_timestamp($2), $3, $4, $5);
+`, string(id), expense.Timestamp, expense.Details, int64(expense.Total), string(expense.Currency)); err != nil {
+			c.logger.LogInfo("%s: failed to insert expense err: %v", op, err)
 			return err
 		}
-	}
-	if err := tx.Commit(); err != nil {
-		c.logger.LogInfo("%s: failed to commit tx err: %v", op, err)
+		if err := c.insertShares(ctx, tx, id, expense.Shares); err != nil {
+			c.logger.LogInfo("%s: failed to insert shares err: %v", op, err)
+			return err
+		}
+		if err := c.post(ctx, tx, id, expense.Currency, debts); err != nil {
+			c.logger.LogInfo("%s: failed to post expense err: %v", op, err)
+			return err
+		}
+		return nil
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform tx err: %v", op, err)
 		return err
 	}
 	c.logger.LogInfo("%s: success[expense=%v id=%s]", op, expense, id)
 	return nil
 }
 
-func (c *defaultRepository) RemoveExpense(expenseId spendings.ExpenseId) repositories.MutationWorkItem {
+func (c *defaultRepository) RemoveExpense(ctx context.Context, expenseId spendings.ExpenseId) repositories.MutationWorkItem {
 	const op = "repositories.spendings.postgresRepository.RemoveExpense"
-	expense, err := c.GetExpense(expenseId)
+	expense, err := c.GetExpense(ctx, expenseId)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
@@ -93,7 +92,7 @@ func (c *defaultRepository) RemoveExpense(expenseId spendings.ExpenseId) reposit
 				c.logger.LogInfo("%s: expense to remove not found", op)
 				return errors.New("expense to remove not found")
 			}
-			return c.removeExpense(expenseId)
+			return c.removeExpense(ctx, expenseId)
 		},
 		Rollback: func() error {
 			if err != nil {
@@ -104,46 +103,225 @@ func (c *defaultRepository) RemoveExpense(expenseId spendings.ExpenseId) reposit
 				c.logger.LogInfo("%s: expense to remove not found", op)
 				return errors.New("expense to remove not found")
 			}
-			return c.addExpense(spendings.Expense((*expense).Expense), spendings.ExpenseId((*expense).Id))
+			return c.restoreExpense(ctx, *expense)
 		},
 	}
 }
 
-func (c *defaultRepository) removeExpense(id spendings.ExpenseId) error {
+func (c *defaultRepository) removeExpense(ctx context.Context, id spendings.ExpenseId) error {
 	const op = "repositories.spendings.postgresRepository.removeExpense"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
-	tx, err := c.db.BeginTx(context.Background(), nil)
-	if err != nil {
-		c.logger.LogInfo("%s: failed to create tx err: %v", op, err)
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		result, err := tx.ExecContext(ctx, `UPDATE deals SET removedAt = now() WHERE id = $1 AND removedAt IS NULL;`, string(id))
+		if err != nil {
+			c.logger.LogInfo("%s: failed to mark expense removed err: %v", op, err)
+			return err
+		}
+		if removed, err := result.RowsAffected(); err != nil || removed == 0 {
+			c.logger.LogInfo("%s: expense is not found err: %v", op, err)
+			return errors.Join(errors.New("expense to remove not found"), err)
+		}
+		transactionId := uuid.New().String()
+		if err := c.reverse(ctx, tx, transactionId, id, spendings.JournalEntryKindReversal); err != nil {
+			c.logger.LogInfo("%s: failed to post reversal err: %v", op, err)
+			return err
+		}
+		return c.updateBalances(ctx, tx, transactionId)
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform tx err: %v", op, err)
 		return err
 	}
-	_, err = c.db.Exec(`DELETE FROM deals WHERE id = $1;`, string(id))
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
+
+func (c *defaultRepository) restoreExpense(ctx context.Context, expense spendings.IdentifiableExpense) error {
+	const op = "repositories.spendings.postgresRepository.restoreExpense"
+	c.logger.LogInfo("%s: start[id=%s]", op, expense.Id)
+	debts, err := spendings.Debts(expense.Shares)
 	if err != nil {
-		c.logger.LogInfo("%s: failed to remove expense err: %v", op, err)
-		tx.Rollback()
+		c.logger.LogInfo("%s: bad shares err: %v", op, err)
+		return err
+	}
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE deals SET removedAt = NULL WHERE id = $1;`, string(expense.Id)); err != nil {
+			c.logger.LogInfo("%s: failed to unmark expense removed err: %v", op, err)
+			return err
+		}
+		return c.post(ctx, tx, expense.Id, expense.Currency, debts)
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform tx err: %v", op, err)
 		return err
 	}
-	_, err = c.db.Exec(`DELETE FROM spendings WHERE dealId = $1;`, string(id))
+	c.logger.LogInfo("%s: success[id=%s]", op, expense.Id)
+	return nil
+}
+
+func (c *defaultRepository) EditExpense(ctx context.Context, id spendings.ExpenseId, expense spendings.Expense) repositories.MutationWorkItem {
+	const op = "repositories.spendings.postgresRepository.EditExpense"
+	existed, err := c.GetExpense(ctx, id)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get expense to edit err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				c.logger.LogInfo("%s: expense to edit not found", op)
+				return errors.New("expense to edit not found")
+			}
+			return c.editExpense(ctx, spendings.IdentifiableExpense{
+				Expense: expense,
+				Id:      id,
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get expense to edit err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				c.logger.LogInfo("%s: expense to edit not found", op)
+				return errors.New("expense to edit not found")
+			}
+			return c.editExpense(ctx, *existed)
+		},
+	}
+}
+
+// editExpense replaces the expense and posts a single journal transaction that reverses its
+// outstanding lines and re-posts its new debts.
+func (c *defaultRepository) editExpense(ctx context.Context, expense spendings.IdentifiableExpense) error {
+	const op = "repositories.spendings.postgresRepository.editExpense"
+	c.logger.LogInfo("%s: start[id=%s]", op, expense.Id)
+	debts, err := spendings.Debts(expense.Shares)
 	if err != nil {
-		c.logger.LogInfo("%s: failed to remove shares err: %v", op, err)
-		tx.Rollback()
+		c.logger.LogInfo("%s: bad shares err: %v", op, err)
 		return err
 	}
-	if err := tx.Commit(); err != nil {
-		c.logger.LogInfo("%s: failed to commit tx err: %v", op, err)
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		result, err := tx.ExecContext(ctx, `
+UPDATE deals SET
+	timestamp = to_timestamp($2),
+	details = $3,
+	cost = $4,
+	currency = $5
+WHERE id = $1 AND removedAt IS NULL;
+`, string(expense.Id), expense.Timestamp, expense.Details, int64(expense.Total), string(expense.Currency))
+		if err != nil {
+			c.logger.LogInfo("%s: failed to update expense err: %v", op, err)
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+			c.logger.LogInfo("%s: expense is not found err: %v", op, err)
+			return errors.Join(errors.New("expense to edit not found"), err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM spendings WHERE dealId = $1;`, string(expense.Id)); err != nil {
+			c.logger.LogInfo("%s: failed to remove shares err: %v", op, err)
+			return err
+		}
+		if err := c.insertShares(ctx, tx, expense.Id, expense.Shares); err != nil {
+			c.logger.LogInfo("%s: failed to insert shares err: %v", op, err)
+			return err
+		}
+		transactionId := uuid.New().String()
+		if err := c.reverse(ctx, tx, transactionId, expense.Id, spendings.JournalEntryKindEdit); err != nil {
+			c.logger.LogInfo("%s: failed to reverse expense err: %v", op, err)
+			return err
+		}
+		if err := c.postLines(ctx, tx, transactionId, expense.Id, spendings.JournalEntryKindEdit, expense.Currency, debts); err != nil {
+			c.logger.LogInfo("%s: failed to re-post expense err: %v", op, err)
+			return err
+		}
+		return c.updateBalances(ctx, tx, transactionId)
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform tx err: %v", op, err)
 		return err
 	}
-	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	c.logger.LogInfo("%s: success[id=%s]", op, expense.Id)
+	return nil
+}
+
+func (c *defaultRepository) insertShares(ctx context.Context, tx db.DB, id spendings.ExpenseId, shares []spendings.ShareOfExpense) error {
+	for _, share := range shares {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO 
+	spendings(id, dealId, cost, counterparty) 
+VALUES($1, $2, $3, $4);
+`, uuid.New().String(), string(id), int64(share.Cost), string(share.Counterparty)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post appends a balanced pair of journal lines for every debt of the expense.
+func (c *defaultRepository) post(ctx context.Context, tx db.DB, id spendings.ExpenseId, currency spendings.Currency, debts []spendings.Debt) error {
+	transactionId := uuid.New().String()
+	if err := c.postLines(ctx, tx, transactionId, id, spendings.JournalEntryKindExpense, currency, debts); err != nil {
+		return err
+	}
+	return c.updateBalances(ctx, tx, transactionId)
+}
+
+func (c *defaultRepository) postLines(ctx context.Context, tx db.DB, transactionId string, id spendings.ExpenseId, kind spendings.JournalEntryKind, currency spendings.Currency, debts []spendings.Debt) error {
+	for _, debt := range debts {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO
+	journal(transactionId, dealId, kind, account, counterparty, currency, amount)
+VALUES ($1, $2, $3, $4, $5, $6, $7), ($1, $2, $3, $5, $4, $6, -$7::bigint);
+`, transactionId, string(id), string(kind), string(debt.Creditor), string(debt.Debtor), string(currency), int64(debt.Amount)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (c *defaultRepository) GetExpense(id spendings.ExpenseId) (*spendings.IdentifiableExpense, error) {
+// reverse appends journal lines of the transaction that cancel everything posted for the expense.
+func (c *defaultRepository) reverse(ctx context.Context, tx db.DB, transactionId string, id spendings.ExpenseId, kind spendings.JournalEntryKind) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO
+	journal(transactionId, dealId, kind, account, counterparty, currency, amount)
+SELECT $2, dealId, $3, account, counterparty, currency, -SUM(amount)
+FROM journal
+WHERE dealId = $1
+GROUP BY dealId, account, counterparty, currency
+HAVING SUM(amount) != 0;
+`, string(id), transactionId, string(kind))
+	return err
+}
+
+// updateBalances applies journal lines of the transaction to the balances table. Settled balances
+// are removed so they match ones computed from the journal.
+func (c *defaultRepository) updateBalances(ctx context.Context, tx db.DB, transactionId string) error {
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO
+	balances(uid, counterparty, currency, amount)
+SELECT account, counterparty, currency, SUM(amount)::bigint
+FROM journal
+WHERE transactionId = $1
+GROUP BY account, counterparty, currency
+ON CONFLICT (uid, counterparty, currency) DO UPDATE SET
+	amount = balances.amount + EXCLUDED.amount;
+`, transactionId); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+DELETE FROM balances
+WHERE
+	amount = 0 AND
+	(uid, counterparty, currency) IN (SELECT account, counterparty, currency FROM journal WHERE transactionId = $1);
+`, transactionId)
+	return err
+}
+
+func (c *defaultRepository) GetExpense(ctx context.Context, id spendings.ExpenseId) (*spendings.IdentifiableExpense, error) {
 	const op = "repositories.spendings.postgresRepository.GetExpense"
 	c.logger.LogInfo("%s: start[id=%s]", op, id)
 	query := `
 SELECT 
 	d.id, 
-	d.timestamp,
+	extract(epoch FROM d.timestamp)::bigint,
 	d.details,
 	d.cost,
 	d.currency,
@@ -153,9 +331,9 @@ FROM
 	deals d
 	JOIN spendings s ON s.dealId = d.id
 WHERE 
-	d.id = $1;
+	d.id = $1 AND d.removedAt IS NULL;
 `
-	rows, err := c.db.Query(query, string(id))
+	rows, err := c.db.QueryContext(ctx, query, string(id))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return nil, err
@@ -199,7 +377,7 @@ WHERE
 	return expense, nil
 }
 
-func (c *defaultRepository) GetExpensesBetween(counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+func (c *defaultRepository) GetExpensesBetween(ctx context.Context, counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
 	const op = "repositories.spendings.postgresRepository.GetExpensesBetween"
 	c.logger.LogInfo("%s: start[c1=%s c2=%s]", op, counterparty1, counterparty2)
 	query := `
@@ -209,7 +387,7 @@ SELECT
   s1.cost,
   s2.counterparty,
   s2.cost,
-  d.timestamp,
+  extract(epoch FROM d.timestamp)::bigint,
   d.details,
   d.cost,
   d.currency
@@ -218,10 +396,10 @@ FROM
   JOIN spendings s2 ON s1.dealId = s2.dealId
   JOIN deals d ON s1.dealId = d.id
 WHERE
-  s1.counterparty = $1 AND s2.counterparty = $2
+  s1.counterparty = $1 AND s2.counterparty = $2 AND d.removedAt IS NULL
 ORDER BY d.timestamp;
 `
-	rows, err := c.db.Query(query, string(counterparty1), string(counterparty2))
+	rows, err := db.Reader(ctx, c.db).QueryContext(ctx, query, string(counterparty1), string(counterparty2))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return nil, err
@@ -261,38 +439,86 @@ ORDER BY d.timestamp;
 	return expenses, nil
 }
 
-func (c *defaultRepository) GetBalance(counterparty spendings.CounterpartyId) ([]spendings.Balance, error) {
-	const op = "repositories.spendings.postgresRepository.GetBalance"
+func (c *defaultRepository) GetExpensesOf(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+	const op = "repositories.spendings.postgresRepository.GetExpensesOf"
 	c.logger.LogInfo("%s: start[counterparty=%s]", op, counterparty)
 	query := `
-SELECT
-  d.currency,
-  s2.counterparty,
-  s1.cost
-FROM
-  deals d
-  JOIN spendings s1 ON s1.dealId = d.id
-  JOIN spendings s2 ON s2.dealId = d.id
+SELECT 
+	d.id, 
+	extract(epoch FROM d.timestamp)::bigint,
+	d.details,
+	d.cost,
+	d.currency,
+	s.cost,
+	s.counterparty
+FROM 
+	deals d
+	JOIN spendings s ON s.dealId = d.id
 WHERE 
-  s1.counterparty = $1 AND s2.counterparty != $1;
+	d.id IN (SELECT dealId FROM spendings WHERE counterparty = $1) AND d.removedAt IS NULL
+ORDER BY d.timestamp, d.id;
 `
-	rows, err := c.db.Query(query, string(counterparty))
+	rows, err := c.db.QueryContext(ctx, query, string(counterparty))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return nil, err
 	}
 	defer rows.Close()
-	balancesMap := map[spendings.CounterpartyId]spendings.Balance{}
+	expenses := []spendings.IdentifiableExpense{}
 	for rows.Next() {
-		var currency string
-		var user string
+		var expense spendings.IdentifiableExpense
+		var expenseIdString string
 		var cost int64
+		var shareCounterparty string
 		err = rows.Scan(
-			&currency,
-			&user,
+			&expenseIdString,
+			&expense.Timestamp,
+			&expense.Details,
+			&expense.Total,
+			&expense.Currency,
 			&cost,
-		)
+			&shareCounterparty)
 		if err != nil {
+			c.logger.LogInfo("%s: scan failed err: %v", op, err)
+			return nil, err
+		}
+		share := spendings.ShareOfExpense{
+			Counterparty: spendings.CounterpartyId(shareCounterparty),
+			Cost:         spendings.Cost(cost),
+		}
+		last := len(expenses) - 1
+		if last >= 0 && expenses[last].Id == spendings.ExpenseId(expenseIdString) {
+			expenses[last].Shares = append(expenses[last].Shares, share)
+			continue
+		}
+		expense.Id = spendings.ExpenseId(expenseIdString)
+		expense.Shares = []spendings.ShareOfExpense{share}
+		expenses = append(expenses, expense)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[counterparty=%s]", op, counterparty)
+	return expenses, nil
+}
+
+func (c *defaultRepository) GetBalance(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.Balance, error) {
+	const op = "repositories.spendings.postgresRepository.GetBalance"
+	c.logger.LogInfo("%s: start[counterparty=%s]", op, counterparty)
+	query := `SELECT counterparty, currency, amount FROM balances WHERE uid = $1;`
+	rows, err := db.Reader(ctx, c.db).QueryContext(ctx, query, string(counterparty))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	balancesMap := map[spendings.CounterpartyId]spendings.Balance{}
+	for rows.Next() {
+		var user string
+		var currency string
+		var amount int64
+		if err := rows.Scan(&user, &currency, &amount); err != nil {
 			c.logger.LogInfo("%s: scan failed err: %v", op, err)
 			return []spendings.Balance{}, err
 		}
@@ -303,20 +529,145 @@ WHERE
 				Currencies:   map[spendings.Currency]spendings.Cost{},
 			}
 		}
-		balancesMap[spendings.CounterpartyId(user)].Currencies[spendings.Currency(currency)] += spendings.Cost(cost)
+		balancesMap[spendings.CounterpartyId(user)].Currencies[spendings.Currency(currency)] = spendings.Cost(amount)
 	}
 	if err := rows.Err(); err != nil {
 		c.logger.LogInfo("%s: found rows err: %v", op, err)
 		return nil, err
 	}
 	balance := make([]spendings.Balance, 0, len(balancesMap))
-	if err != nil {
-		c.logger.LogInfo("%s: unexpected err %v", op, err)
-		return balance, err
-	}
 	for _, value := range balancesMap {
 		balance = append(balance, value)
 	}
-	c.logger.LogInfo("%s: start[success=%s]", op, counterparty)
+	c.logger.LogInfo("%s: success[counterparty=%s]", op, counterparty)
 	return balance, nil
 }
+
+func (c *defaultRepository) GetJournal(ctx context.Context, account spendings.CounterpartyId, counterparty spendings.CounterpartyId) ([]spendings.JournalEntry, error) {
+	const op = "repositories.spendings.postgresRepository.GetJournal"
+	c.logger.LogInfo("%s: start[account=%s counterparty=%s]", op, account, counterparty)
+	query := `
+SELECT
+	sequence,
+	transactionId,
+	dealId,
+	kind,
+	account,
+	counterparty,
+	currency,
+	amount,
+	extract(epoch FROM timestamp)::bigint
+FROM journal
+WHERE account = $1 AND counterparty = $2
+ORDER BY sequence;
+`
+	rows, err := db.Reader(ctx, c.db).QueryContext(ctx, query, string(account), string(counterparty))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []spendings.JournalEntry{}
+	for rows.Next() {
+		var entry spendings.JournalEntry
+		if err := rows.Scan(
+			&entry.Sequence,
+			&entry.TransactionId,
+			&entry.ExpenseId,
+			&entry.Kind,
+			&entry.Account,
+			&entry.Counterparty,
+			&entry.Currency,
+			&entry.Amount,
+			&entry.Timestamp,
+		); err != nil {
+			c.logger.LogInfo("%s: scan failed err: %v", op, err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[account=%s counterparty=%s]", op, account, counterparty)
+	return entries, nil
+}
+
+const ledgerBalancesQuery = `
+SELECT account AS uid, counterparty, currency, SUM(amount)::bigint AS amount
+FROM journal
+GROUP BY account, counterparty, currency
+HAVING SUM(amount) != 0
+`
+
+func (c *defaultRepository) RebuildBalances(ctx context.Context) error {
+	const op = "repositories.spendings.postgresRepository.RebuildBalances"
+	c.logger.LogInfo("%s: start", op)
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE journal, balances IN EXCLUSIVE MODE;`); err != nil {
+			c.logger.LogInfo("%s: failed to lock tables err: %v", op, err)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM balances;`); err != nil {
+			c.logger.LogInfo("%s: failed to clear balances err: %v", op, err)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO balances(uid, counterparty, currency, amount) `+ledgerBalancesQuery+`;`); err != nil {
+			c.logger.LogInfo("%s: failed to fill balances err: %v", op, err)
+			return err
+		}
+		return nil
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform tx err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success", op)
+	return nil
+}
+
+func (c *defaultRepository) VerifyBalances(ctx context.Context) ([]spendings.BalanceMismatch, error) {
+	const op = "repositories.spendings.postgresRepository.VerifyBalances"
+	c.logger.LogInfo("%s: start", op)
+	query := `
+SELECT
+	COALESCE(b.uid, l.uid),
+	COALESCE(b.counterparty, l.counterparty),
+	COALESCE(b.currency, l.currency),
+	COALESCE(b.amount, 0),
+	COALESCE(l.amount, 0)
+FROM
+	balances b
+	FULL OUTER JOIN (` + ledgerBalancesQuery + `) l
+	ON b.uid = l.uid AND b.counterparty = l.counterparty AND b.currency = l.currency
+WHERE
+	b.uid IS NULL OR l.uid IS NULL OR b.amount != l.amount;
+`
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	mismatches := []spendings.BalanceMismatch{}
+	for rows.Next() {
+		var mismatch spendings.BalanceMismatch
+		if err := rows.Scan(
+			&mismatch.Owner,
+			&mismatch.Counterparty,
+			&mismatch.Currency,
+			&mismatch.Materialized,
+			&mismatch.Ledger,
+		); err != nil {
+			c.logger.LogInfo("%s: scan failed err: %v", op, err)
+			return nil, err
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[mismatches=%d]", op, len(mismatches))
+	return mismatches, nil
+}
@@ -1,7 +1,9 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"reflect"
@@ -9,9 +11,11 @@ import (
 	"testing"
 
 	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
 	"github.com/rzmn/governi/internal/repositories/spendings"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/spendings/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/spendings/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -19,30 +23,44 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() spendings.Repository
+	unitOfWork    db.UnitOfWork
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() spendings.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+		unitOfWork = db.NewUnitOfWork(database)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() spendings.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+		unitOfWork = memoryDb.NewUnitOfWork()
+	}
 	code := m.Run()
 
 	os.Exit(code)
@@ -67,10 +85,10 @@ func expensesAreEqual(lhs spendings.Expense, rhs spendings.Expense) bool {
 }
 
 func TestGetExpensesEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	expenseId := randomEid()
 
-	shouldBeEmpty, err := repository.GetExpense(expenseId)
+	shouldBeEmpty, err := repository.GetExpense(context.Background(), expenseId)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -80,10 +98,10 @@ func TestGetExpensesEmpty(t *testing.T) {
 }
 
 func TestGetBalanceEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	counterparty := randomUid()
 
-	shouldBeEmpty, err := repository.GetBalance(counterparty)
+	shouldBeEmpty, err := repository.GetBalance(context.Background(), counterparty)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -93,7 +111,7 @@ func TestGetBalanceEmpty(t *testing.T) {
 }
 
 func TestExpensesAndCounterparties(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	firstCounterparty := randomUid()
 	secondCounterparty := randomUid()
 	cost1 := spendings.Cost(456)
@@ -120,12 +138,12 @@ func TestExpensesAndCounterparties(t *testing.T) {
 			},
 		},
 	}
-	insertFirstExpenseTransaction := repository.AddExpense(firstExpense)
+	insertFirstExpenseTransaction := repository.AddExpense(context.Background(), firstExpense)
 	firstExpenseId, err := insertFirstExpenseTransaction.Perform()
 	if err != nil {
 		t.Fatalf("failed to perform `insertFirstExpenseTransaction` err: %v", err)
 	}
-	shouldBeEqualToFirstExpense, err := repository.GetExpense(firstExpenseId)
+	shouldBeEqualToFirstExpense, err := repository.GetExpense(context.Background(), firstExpenseId)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToFirstExpense` err: %v", err)
 	}
@@ -156,12 +174,12 @@ func TestExpensesAndCounterparties(t *testing.T) {
 			},
 		},
 	}
-	insertSecondExpenseTransaction := repository.AddExpense(secondExpense)
+	insertSecondExpenseTransaction := repository.AddExpense(context.Background(), secondExpense)
 	secondExpenseId, err := insertSecondExpenseTransaction.Perform()
 	if err != nil {
 		t.Fatalf("failed to perform `insertSecondExpenseTransaction` err: %v", err)
 	}
-	shouldBeEqualToSecondExpense, err := repository.GetExpense(secondExpenseId)
+	shouldBeEqualToSecondExpense, err := repository.GetExpense(context.Background(), secondExpenseId)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToSecondExpense` err: %v", err)
 	}
@@ -174,7 +192,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 
 	// check that both expenses are available by GetExpensesBetween
 
-	expensesBetween, err := repository.GetExpensesBetween(firstCounterparty, secondCounterparty)
+	expensesBetween, err := repository.GetExpensesBetween(context.Background(), firstCounterparty, secondCounterparty)
 	if err != nil {
 		t.Fatalf("failed to get `expensesBetween` err: %v", err)
 	}
@@ -190,7 +208,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 
 	// check that first counterparty's balance mentions second counterparty
 
-	firstCounterpartyBalance, err := repository.GetBalance(firstCounterparty)
+	firstCounterpartyBalance, err := repository.GetBalance(context.Background(), firstCounterparty)
 	if err != nil {
 		t.Fatalf("failed to get `firstCounterpartyBalance` err: %v", err)
 	}
@@ -205,7 +223,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 
 	// check that second counterparty's balance mentions second counterparty
 
-	secondCounterpartyBalance, err := repository.GetBalance(secondCounterparty)
+	secondCounterpartyBalance, err := repository.GetBalance(context.Background(), secondCounterparty)
 	if err != nil {
 		t.Fatalf("failed to get `secondCounterpartyBalance` err: %v", err)
 	}
@@ -223,7 +241,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 	if err := insertSecondExpenseTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `insertSecondExpenseTransaction` err: %v", err)
 	}
-	expensesBetween, err = repository.GetExpensesBetween(firstCounterparty, secondCounterparty)
+	expensesBetween, err = repository.GetExpensesBetween(context.Background(), firstCounterparty, secondCounterparty)
 	if err != nil {
 		t.Fatalf("[after first rollback] failed to get `expensesBetween` err: %v", err)
 	}
@@ -239,7 +257,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 	if err := insertFirstExpenseTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `insertFirstExpenseTransaction` err: %v", err)
 	}
-	expensesBetween, err = repository.GetExpensesBetween(firstCounterparty, secondCounterparty)
+	expensesBetween, err = repository.GetExpensesBetween(context.Background(), firstCounterparty, secondCounterparty)
 	if err != nil {
 		t.Fatalf("[after second rollback] failed to get `expensesBetween` err: %v", err)
 	}
@@ -249,7 +267,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 
 	// check balances are empty after rollbacks
 
-	firstCounterpartyBalance, err = repository.GetBalance(firstCounterparty)
+	firstCounterpartyBalance, err = repository.GetBalance(context.Background(), firstCounterparty)
 	if err != nil {
 		t.Fatalf("[after second rollback] failed to get `firstCounterpartyBalance` err: %v", err)
 	}
@@ -257,7 +275,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 		t.Fatalf("[after second rollback] `firstCounterpartyBalance` should be equal, found %v", firstCounterpartyBalance)
 	}
 
-	secondCounterpartyBalance, err = repository.GetBalance(secondCounterparty)
+	secondCounterpartyBalance, err = repository.GetBalance(context.Background(), secondCounterparty)
 	if err != nil {
 		t.Fatalf("[after second rollback] failed to get `secondCounterpartyBalance` err: %v", err)
 	}
@@ -267,7 +285,7 @@ func TestExpensesAndCounterparties(t *testing.T) {
 }
 
 func TestAddAndRemoveExpense(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	counterparty1 := randomUid()
 	counterparty2 := randomUid()
 	cost := spendings.Cost(456)
@@ -289,12 +307,12 @@ func TestAddAndRemoveExpense(t *testing.T) {
 			},
 		},
 	}
-	insertTransaction := repository.AddExpense(expense)
+	insertTransaction := repository.AddExpense(context.Background(), expense)
 	expenseId, err := insertTransaction.Perform()
 	if err != nil {
 		t.Fatalf("failed to perform `insertTransaction` err: %v", err)
 	}
-	expenses, err := repository.GetExpensesBetween(counterparty1, counterparty2)
+	expenses, err := repository.GetExpensesBetween(context.Background(), counterparty1, counterparty2)
 	if err != nil {
 		t.Fatalf("failed to get `expenses` err: %v", err)
 	}
@@ -304,7 +322,7 @@ func TestAddAndRemoveExpense(t *testing.T) {
 	if expenses[0].Id != expenseId {
 		t.Fatalf("`expenseId` should be equal to %s, found: %s", expenseId, expenses[0].Id)
 	}
-	shouldBeEqualToExpense, err := repository.GetExpense(expenseId)
+	shouldBeEqualToExpense, err := repository.GetExpense(context.Background(), expenseId)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToExpense` err: %v", err)
 	}
@@ -314,11 +332,11 @@ func TestAddAndRemoveExpense(t *testing.T) {
 	if !expensesAreEqual(shouldBeEqualToExpense.Expense, expense) {
 		t.Fatalf("`shouldBeEqualToExpense` should be equal to %v, found %v", expense, shouldBeEqualToExpense.Expense)
 	}
-	deleteTransaction := repository.RemoveExpense(expenseId)
+	deleteTransaction := repository.RemoveExpense(context.Background(), expenseId)
 	if err := deleteTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `deleteTransaction` err: %v", err)
 	}
-	shouldBeEmpty, err := repository.GetExpense(expenseId)
+	shouldBeEmpty, err := repository.GetExpense(context.Background(), expenseId)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -328,7 +346,7 @@ func TestAddAndRemoveExpense(t *testing.T) {
 	if err := deleteTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `deleteTransaction` err: %v", err)
 	}
-	shouldBeEqualToExpense, err = repository.GetExpense(expenseId)
+	shouldBeEqualToExpense, err = repository.GetExpense(context.Background(), expenseId)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `shouldBeEqualToExpense` err: %v", err)
 	}
@@ -341,7 +359,7 @@ func TestAddAndRemoveExpense(t *testing.T) {
 	if err := insertTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `insertTransaction` err: %v", err)
 	}
-	shouldBeEmpty, err = repository.GetExpense(expenseId)
+	shouldBeEmpty, err = repository.GetExpense(context.Background(), expenseId)
 	if err != nil {
 		t.Fatalf("[after second rollback] failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -349,3 +367,351 @@ func TestAddAndRemoveExpense(t *testing.T) {
 		t.Fatalf("[after second rollback] `shouldBeEmpty` should be empty, found %v", *shouldBeEmpty)
 	}
 }
+
+func TestGetExpensesOf(t *testing.T) {
+	repository := newRepository()
+	counterparty := randomUid()
+	currency := spendings.Currency(uuid.New().String())
+
+	shouldBeEmpty, err := repository.GetExpensesOf(context.Background(), counterparty)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
+	}
+	if len(shouldBeEmpty) != 0 {
+		t.Fatalf("`shouldBeEmpty` should be empty, found %v", shouldBeEmpty)
+	}
+	expenses := []spendings.Expense{}
+	for i, other := range []spendings.CounterpartyId{randomUid(), randomUid()} {
+		cost := spendings.Cost(100 * (i + 1))
+		expense := spendings.Expense{
+			Timestamp: int64(i),
+			Details:   uuid.New().String(),
+			Total:     cost,
+			Currency:  currency,
+			Shares: []spendings.ShareOfExpense{
+				{
+					Counterparty: counterparty,
+					Cost:         cost,
+				},
+				{
+					Counterparty: other,
+					Cost:         -cost,
+				},
+			},
+		}
+		if _, err := repository.AddExpense(context.Background(), expense).Perform(); err != nil {
+			t.Fatalf("failed to add expense err: %v", err)
+		}
+		expenses = append(expenses, expense)
+	}
+	shouldContainExpenses, err := repository.GetExpensesOf(context.Background(), counterparty)
+	if err != nil {
+		t.Fatalf("failed to get `shouldContainExpenses` err: %v", err)
+	}
+	if len(shouldContainExpenses) != len(expenses) {
+		t.Fatalf("`shouldContainExpenses` should contain %v, found %v", expenses, shouldContainExpenses)
+	}
+	for i := range expenses {
+		if !expensesAreEqual(shouldContainExpenses[i].Expense, expenses[i]) {
+			t.Fatalf("`shouldContainExpenses[%d]` should be equal to %v, found %v", i, expenses[i], shouldContainExpenses[i].Expense)
+		}
+	}
+}
+
+func TestAddExpenseWithTxAborted(t *testing.T) {
+	repository := newRepository()
+	counterparty := randomUid()
+	expense := spendings.Expense{
+		Timestamp: 123,
+		Details:   uuid.New().String(),
+		Total:     100,
+		Currency:  spendings.Currency(uuid.New().String()),
+		Shares: []spendings.ShareOfExpense{
+			{
+				Counterparty: counterparty,
+				Cost:         100,
+			},
+			{
+				Counterparty: randomUid(),
+				Cost:         -100,
+			},
+		},
+	}
+	var expenseId spendings.ExpenseId
+	if err := unitOfWork.Run(context.Background(), func(tx db.DB) error {
+		var err error
+		expenseId, err = repository.WithTx(tx).AddExpense(context.Background(), expense).Perform()
+		if err != nil {
+			t.Fatalf("failed to add expense in tx err: %v", err)
+		}
+		return errors.New("abort")
+	}); err == nil {
+		t.Fatalf("unit of work should be aborted")
+	}
+	shouldBeEmpty, err := repository.GetExpense(context.Background(), expenseId)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
+	}
+	if shouldBeEmpty != nil {
+		t.Fatalf("`shouldBeEmpty` should be nil after aborted unit of work, found %v", *shouldBeEmpty)
+	}
+	balance, err := repository.GetBalance(context.Background(), counterparty)
+	if err != nil {
+		t.Fatalf("failed to get balance err: %v", err)
+	}
+	if len(balance) != 0 {
+		t.Fatalf("balance should be empty after aborted unit of work, found %v", balance)
+	}
+}
+
+func TestRebuildAndVerifyBalances(t *testing.T) {
+	repository := newRepository()
+	counterparty1 := randomUid()
+	counterparty2 := randomUid()
+	currency := spendings.Currency(uuid.New().String())
+	expense := func(cost spendings.Cost) spendings.Expense {
+		return spendings.Expense{
+			Timestamp: 123,
+			Details:   uuid.New().String(),
+			Total:     cost,
+			Currency:  currency,
+			Shares: []spendings.ShareOfExpense{
+				{
+					Counterparty: counterparty1,
+					Cost:         cost,
+				},
+				{
+					Counterparty: counterparty2,
+					Cost:         -cost,
+				},
+			},
+		}
+	}
+	firstInsertTransaction := repository.AddExpense(context.Background(), expense(100))
+	if _, err := firstInsertTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `firstInsertTransaction` err: %v", err)
+	}
+	defer firstInsertTransaction.Rollback()
+	secondInsertTransaction := repository.AddExpense(context.Background(), expense(20))
+	if _, err := secondInsertTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `secondInsertTransaction` err: %v", err)
+	}
+	if err := secondInsertTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `secondInsertTransaction` err: %v", err)
+	}
+	mismatches, err := repository.VerifyBalances(context.Background())
+	if err != nil {
+		t.Fatalf("failed to verify balances err: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("`mismatches` should be empty, found %v", mismatches)
+	}
+	if err := repository.RebuildBalances(context.Background()); err != nil {
+		t.Fatalf("failed to rebuild balances err: %v", err)
+	}
+	balance, err := repository.GetBalance(context.Background(), counterparty1)
+	if err != nil {
+		t.Fatalf("failed to get `balance` err: %v", err)
+	}
+	expected := []spendings.Balance{
+		{
+			Counterparty: counterparty2,
+			Currencies: map[spendings.Currency]spendings.Cost{
+				currency: 100,
+			},
+		},
+	}
+	if !reflect.DeepEqual(balance, expected) {
+		t.Fatalf("`balance` should be equal to %v, found %v", expected, balance)
+	}
+}
+
+func TestAddUnbalancedExpense(t *testing.T) {
+	repository := newRepository()
+	counterparty := randomUid()
+	expense := spendings.Expense{
+		Timestamp: 123,
+		Details:   uuid.New().String(),
+		Total:     100,
+		Currency:  spendings.Currency(uuid.New().String()),
+		Shares: []spendings.ShareOfExpense{
+			{
+				Counterparty: counterparty,
+				Cost:         100,
+			},
+			{
+				Counterparty: randomUid(),
+				Cost:         -99,
+			},
+		},
+	}
+	if _, err := repository.AddExpense(context.Background(), expense).Perform(); !errors.Is(err, spendings.ErrUnbalancedExpense) {
+		t.Fatalf("adding unbalanced expense should fail with `ErrUnbalancedExpense`, found %v", err)
+	}
+	expenses, err := repository.GetExpensesOf(context.Background(), counterparty)
+	if err != nil {
+		t.Fatalf("failed to get `expenses` err: %v", err)
+	}
+	if len(expenses) != 0 {
+		t.Fatalf("`expenses` should be empty, found %v", expenses)
+	}
+}
+
+func TestGetJournal(t *testing.T) {
+	repository := newRepository()
+	payer := randomUid()
+	debtor := randomUid()
+	currency := spendings.Currency(uuid.New().String())
+	expense := spendings.Expense{
+		Timestamp: 123,
+		Details:   uuid.New().String(),
+		Total:     90,
+		Currency:  currency,
+		Shares: []spendings.ShareOfExpense{
+			{
+				Counterparty: payer,
+				Cost:         60,
+			},
+			{
+				Counterparty: debtor,
+				Cost:         -30,
+			},
+			{
+				Counterparty: randomUid(),
+				Cost:         -30,
+			},
+		},
+	}
+	expenseId, err := repository.AddExpense(context.Background(), expense).Perform()
+	if err != nil {
+		t.Fatalf("failed to add expense err: %v", err)
+	}
+	if err := repository.RemoveExpense(context.Background(), expenseId).Perform(); err != nil {
+		t.Fatalf("failed to remove expense err: %v", err)
+	}
+	journal, err := repository.GetJournal(context.Background(), payer, debtor)
+	if err != nil {
+		t.Fatalf("failed to get `journal` err: %v", err)
+	}
+	if len(journal) != 2 {
+		t.Fatalf("`journal` should contain expense and its reversal, found %v", journal)
+	}
+	if journal[0].Kind != spendings.JournalEntryKindExpense || journal[0].Amount != 30 || journal[0].ExpenseId != expenseId || journal[0].Currency != currency {
+		t.Fatalf("first entry of `journal` should be the expense, found %v", journal[0])
+	}
+	if journal[1].Kind != spendings.JournalEntryKindReversal || journal[1].Amount != -30 || journal[1].ExpenseId != expenseId {
+		t.Fatalf("second entry of `journal` should be the reversal, found %v", journal[1])
+	}
+	if journal[0].Sequence >= journal[1].Sequence {
+		t.Fatalf("`journal` should be ordered by sequence, found %v", journal)
+	}
+	mirrored, err := repository.GetJournal(context.Background(), debtor, payer)
+	if err != nil {
+		t.Fatalf("failed to get `mirrored` err: %v", err)
+	}
+	if len(mirrored) != 2 || mirrored[0].Amount != -30 || mirrored[1].Amount != 30 {
+		t.Fatalf("`mirrored` should contain opposite entries, found %v", mirrored)
+	}
+	balance, err := repository.GetBalance(context.Background(), payer)
+	if err != nil {
+		t.Fatalf("failed to get `balance` err: %v", err)
+	}
+	if len(balance) != 0 {
+		t.Fatalf("`balance` should be empty after reversal, found %v", balance)
+	}
+}
+
+func TestEditExpense(t *testing.T) {
+	repository := newRepository()
+	payer := randomUid()
+	debtor := randomUid()
+	currency := spendings.Currency(uuid.New().String())
+	expense := func(cost spendings.Cost) spendings.Expense {
+		return spendings.Expense{
+			Timestamp: 123,
+			Details:   uuid.New().String(),
+			Total:     cost,
+			Currency:  currency,
+			Shares: []spendings.ShareOfExpense{
+				{
+					Counterparty: payer,
+					Cost:         cost,
+				},
+				{
+					Counterparty: debtor,
+					Cost:         -cost,
+				},
+			},
+		}
+	}
+	initial := expense(30)
+	expenseId, err := repository.AddExpense(context.Background(), initial).Perform()
+	if err != nil {
+		t.Fatalf("failed to add expense err: %v", err)
+	}
+	edited := expense(50)
+	editTransaction := repository.EditExpense(context.Background(), expenseId, edited)
+	if err := editTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `editTransaction` err: %v", err)
+	}
+	shouldBeEqualToEdited, err := repository.GetExpense(context.Background(), expenseId)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEqualToEdited` err: %v", err)
+	}
+	if shouldBeEqualToEdited == nil || !expensesAreEqual(shouldBeEqualToEdited.Expense, edited) {
+		t.Fatalf("`shouldBeEqualToEdited` should be equal to %v, found %v", edited, shouldBeEqualToEdited)
+	}
+	journal, err := repository.GetJournal(context.Background(), payer, debtor)
+	if err != nil {
+		t.Fatalf("failed to get `journal` err: %v", err)
+	}
+	if len(journal) != 3 {
+		t.Fatalf("`journal` should contain expense, its reversal and re-post, found %v", journal)
+	}
+	if journal[1].Kind != spendings.JournalEntryKindEdit || journal[1].Amount != -30 {
+		t.Fatalf("second entry of `journal` should reverse the expense, found %v", journal[1])
+	}
+	if journal[2].Kind != spendings.JournalEntryKindEdit || journal[2].Amount != 50 {
+		t.Fatalf("third entry of `journal` should re-post the expense, found %v", journal[2])
+	}
+	if journal[1].TransactionId != journal[2].TransactionId || journal[0].TransactionId == journal[1].TransactionId {
+		t.Fatalf("reversal and re-post should share a transaction, found %v", journal)
+	}
+	balance, err := repository.GetBalance(context.Background(), payer)
+	if err != nil {
+		t.Fatalf("failed to get `balance` err: %v", err)
+	}
+	if len(balance) != 1 || balance[0].Currencies[currency] != 50 {
+		t.Fatalf("`balance` should be 50, found %v", balance)
+	}
+	if err := editTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `editTransaction` err: %v", err)
+	}
+	shouldBeEqualToInitial, err := repository.GetExpense(context.Background(), expenseId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `shouldBeEqualToInitial` err: %v", err)
+	}
+	if shouldBeEqualToInitial == nil || !expensesAreEqual(shouldBeEqualToInitial.Expense, initial) {
+		t.Fatalf("[after rollback] `shouldBeEqualToInitial` should be equal to %v, found %v", initial, shouldBeEqualToInitial)
+	}
+	balance, err = repository.GetBalance(context.Background(), payer)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `balance` err: %v", err)
+	}
+	if len(balance) != 1 || balance[0].Currencies[currency] != 30 {
+		t.Fatalf("[after rollback] `balance` should be 30, found %v", balance)
+	}
+	mismatches, err := repository.VerifyBalances(context.Background())
+	if err != nil {
+		t.Fatalf("failed to verify balances err: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("balances should match the journal, found %v", mismatches)
+	}
+	if err := repository.RemoveExpense(context.Background(), expenseId).Perform(); err != nil {
+		t.Fatalf("failed to remove expense err: %v", err)
+	}
+	if err := repository.EditExpense(context.Background(), expenseId, edited).Perform(); err == nil {
+		t.Fatalf("editing removed expense should fail")
+	}
+}
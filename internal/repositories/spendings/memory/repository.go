@@ -0,0 +1,377 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/spendings"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/google/uuid"
+)
+
+func New(logger logging.Service) spendings.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			expenses: map[spendings.ExpenseId]spendings.IdentifiableExpense{},
+			removed:  map[spendings.ExpenseId]struct{}{},
+			balances: map[balanceKey]spendings.Cost{},
+		},
+		logger: logger,
+	}
+}
+
+type balanceKey struct {
+	owner        spendings.CounterpartyId
+	counterparty spendings.CounterpartyId
+	currency     spendings.Currency
+}
+
+type storage struct {
+	mutex    sync.RWMutex
+	expenses map[spendings.ExpenseId]spendings.IdentifiableExpense
+	removed  map[spendings.ExpenseId]struct{}
+	journal  []spendings.JournalEntry
+	balances map[balanceKey]spendings.Cost
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) spendings.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) AddExpense(ctx context.Context, expense spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId] {
+	expenseId := spendings.ExpenseId(uuid.New().String())
+	rollback := func() error {
+		return c.remove(expenseId)
+	}
+	return repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (spendings.ExpenseId, error) {
+			return expenseId, c.store(spendings.IdentifiableExpense{
+				Expense: expense,
+				Id:      expenseId,
+			})
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) RemoveExpense(ctx context.Context, id spendings.ExpenseId) repositories.MutationWorkItem {
+	expense, err := c.GetExpense(ctx, id)
+	if err == nil && expense == nil {
+		err = errors.New("expense to remove not found")
+	}
+	rollback := func() error {
+		if err != nil {
+			return err
+		}
+		return c.restore(*expense)
+	}
+	return repositories.MutationWorkItem{
+		Perform: memoryDb.Track(c.tx, func() error {
+			if err != nil {
+				return err
+			}
+			return c.remove(id)
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) EditExpense(ctx context.Context, id spendings.ExpenseId, expense spendings.Expense) repositories.MutationWorkItem {
+	existed, err := c.GetExpense(ctx, id)
+	if err == nil && existed == nil {
+		err = errors.New("expense to edit not found")
+	}
+	rollback := func() error {
+		if err != nil {
+			return err
+		}
+		return c.edit(*existed)
+	}
+	return repositories.MutationWorkItem{
+		Perform: memoryDb.Track(c.tx, func() error {
+			if err != nil {
+				return err
+			}
+			return c.edit(spendings.IdentifiableExpense{
+				Expense: expense,
+				Id:      id,
+			})
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) GetExpense(ctx context.Context, id spendings.ExpenseId) (*spendings.IdentifiableExpense, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	expense, ok := c.storage.expenses[id]
+	if _, removed := c.storage.removed[id]; !ok || removed || len(expense.Shares) == 0 {
+		return nil, nil
+	}
+	expense = copyExpense(expense)
+	return &expense, nil
+}
+
+func (c *memoryRepository) GetExpensesBetween(ctx context.Context, counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []spendings.IdentifiableExpense{}
+	for _, expense := range c.sorted() {
+		for _, share1 := range expense.Shares {
+			if share1.Counterparty != counterparty1 {
+				continue
+			}
+			for _, share2 := range expense.Shares {
+				if share2.Counterparty != counterparty2 {
+					continue
+				}
+				between := expense
+				between.Shares = []spendings.ShareOfExpense{share1, share2}
+				result = append(result, between)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) GetExpensesOf(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []spendings.IdentifiableExpense{}
+	for _, expense := range c.sorted() {
+		for _, share := range expense.Shares {
+			if share.Counterparty == counterparty {
+				result = append(result, copyExpense(expense))
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) GetBalance(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.Balance, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	balancesMap := map[spendings.CounterpartyId]spendings.Balance{}
+	for key, amount := range c.storage.balances {
+		if key.owner != counterparty {
+			continue
+		}
+		if _, ok := balancesMap[key.counterparty]; !ok {
+			balancesMap[key.counterparty] = spendings.Balance{
+				Counterparty: key.counterparty,
+				Currencies:   map[spendings.Currency]spendings.Cost{},
+			}
+		}
+		balancesMap[key.counterparty].Currencies[key.currency] = amount
+	}
+	balance := make([]spendings.Balance, 0, len(balancesMap))
+	for _, value := range balancesMap {
+		balance = append(balance, value)
+	}
+	return balance, nil
+}
+
+func (c *memoryRepository) GetJournal(ctx context.Context, account spendings.CounterpartyId, counterparty spendings.CounterpartyId) ([]spendings.JournalEntry, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []spendings.JournalEntry{}
+	for _, entry := range c.storage.journal {
+		if entry.Account == account && entry.Counterparty == counterparty {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) RebuildBalances(ctx context.Context) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	c.storage.balances = c.ledgerBalances()
+	return nil
+}
+
+func (c *memoryRepository) VerifyBalances(ctx context.Context) ([]spendings.BalanceMismatch, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	ledger := c.ledgerBalances()
+	mismatches := []spendings.BalanceMismatch{}
+	mismatch := func(key balanceKey) {
+		mismatches = append(mismatches, spendings.BalanceMismatch{
+			Owner:        key.owner,
+			Counterparty: key.counterparty,
+			Currency:     key.currency,
+			Materialized: c.storage.balances[key],
+			Ledger:       ledger[key],
+		})
+	}
+	for key, amount := range c.storage.balances {
+		if ledgerAmount, ok := ledger[key]; !ok || ledgerAmount != amount {
+			mismatch(key)
+		}
+	}
+	for key := range ledger {
+		if _, ok := c.storage.balances[key]; !ok {
+			mismatch(key)
+		}
+	}
+	return mismatches, nil
+}
+
+func (c *memoryRepository) ledgerBalances() map[balanceKey]spendings.Cost {
+	balances := map[balanceKey]spendings.Cost{}
+	applyEntries(balances, c.storage.journal)
+	return balances
+}
+
+func applyEntries(balances map[balanceKey]spendings.Cost, entries []spendings.JournalEntry) {
+	for _, entry := range entries {
+		key := balanceKey{
+			owner:        entry.Account,
+			counterparty: entry.Counterparty,
+			currency:     entry.Currency,
+		}
+		balances[key] += entry.Amount
+		if balances[key] == 0 {
+			delete(balances, key)
+		}
+	}
+}
+
+func (c *memoryRepository) sorted() []spendings.IdentifiableExpense {
+	expenses := make([]spendings.IdentifiableExpense, 0, len(c.storage.expenses))
+	for id, expense := range c.storage.expenses {
+		if _, removed := c.storage.removed[id]; removed {
+			continue
+		}
+		expenses = append(expenses, expense)
+	}
+	sort.Slice(expenses, func(i, j int) bool {
+		if expenses[i].Timestamp != expenses[j].Timestamp {
+			return expenses[i].Timestamp < expenses[j].Timestamp
+		}
+		return expenses[i].Id < expenses[j].Id
+	})
+	return expenses
+}
+
+func (c *memoryRepository) store(expense spendings.IdentifiableExpense) error {
+	debts, err := spendings.Debts(expense.Shares)
+	if err != nil {
+		return err
+	}
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if _, ok := c.storage.expenses[expense.Id]; ok {
+		return fmt.Errorf("expense %s already exists", expense.Id)
+	}
+	c.storage.expenses[expense.Id] = copyExpense(expense)
+	c.post(expense.Id, expense.Currency, debts)
+	return nil
+}
+
+func (c *memoryRepository) restore(expense spendings.IdentifiableExpense) error {
+	debts, err := spendings.Debts(expense.Shares)
+	if err != nil {
+		return err
+	}
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	delete(c.storage.removed, expense.Id)
+	c.post(expense.Id, expense.Currency, debts)
+	return nil
+}
+
+func (c *memoryRepository) remove(id spendings.ExpenseId) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	_, removed := c.storage.removed[id]
+	if _, ok := c.storage.expenses[id]; !ok || removed {
+		return fmt.Errorf("expense %s not found", id)
+	}
+	c.storage.removed[id] = struct{}{}
+	c.reverse(uuid.New().String(), id, spendings.JournalEntryKindReversal)
+	return nil
+}
+
+func (c *memoryRepository) edit(expense spendings.IdentifiableExpense) error {
+	debts, err := spendings.Debts(expense.Shares)
+	if err != nil {
+		return err
+	}
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	_, removed := c.storage.removed[expense.Id]
+	if _, ok := c.storage.expenses[expense.Id]; !ok || removed {
+		return fmt.Errorf("expense %s not found", expense.Id)
+	}
+	c.storage.expenses[expense.Id] = copyExpense(expense)
+	transactionId := uuid.New().String()
+	c.reverse(transactionId, expense.Id, spendings.JournalEntryKindEdit)
+	c.postLines(transactionId, expense.Id, spendings.JournalEntryKindEdit, expense.Currency, debts)
+	return nil
+}
+
+func (c *memoryRepository) reverse(transactionId string, id spendings.ExpenseId, kind spendings.JournalEntryKind) {
+	outstanding := map[balanceKey]spendings.Cost{}
+	for _, entry := range c.storage.journal {
+		if entry.ExpenseId == id {
+			applyEntries(outstanding, []spendings.JournalEntry{entry})
+		}
+	}
+	for key, amount := range outstanding {
+		if amount != 0 {
+			c.append(transactionId, id, kind, key.owner, key.counterparty, key.currency, -amount)
+		}
+	}
+}
+
+func (c *memoryRepository) post(id spendings.ExpenseId, currency spendings.Currency, debts []spendings.Debt) {
+	c.postLines(uuid.New().String(), id, spendings.JournalEntryKindExpense, currency, debts)
+}
+
+func (c *memoryRepository) postLines(transactionId string, id spendings.ExpenseId, kind spendings.JournalEntryKind, currency spendings.Currency, debts []spendings.Debt) {
+	for _, debt := range debts {
+		c.append(transactionId, id, kind, debt.Creditor, debt.Debtor, currency, debt.Amount)
+		c.append(transactionId, id, kind, debt.Debtor, debt.Creditor, currency, -debt.Amount)
+	}
+}
+
+func (c *memoryRepository) append(transactionId string, id spendings.ExpenseId, kind spendings.JournalEntryKind, account spendings.CounterpartyId, counterparty spendings.CounterpartyId, currency spendings.Currency, amount spendings.Cost) {
+	entry := spendings.JournalEntry{
+		Sequence:      int64(len(c.storage.journal) + 1),
+		TransactionId: transactionId,
+		ExpenseId:     id,
+		Kind:          kind,
+		Account:       account,
+		Counterparty:  counterparty,
+		Currency:      currency,
+		Amount:        amount,
+		Timestamp:     time.Now().Unix(),
+	}
+	c.storage.journal = append(c.storage.journal, entry)
+	applyEntries(c.storage.balances, []spendings.JournalEntry{entry})
+}
+
+func copyExpense(expense spendings.IdentifiableExpense) spendings.IdentifiableExpense {
+	expense.Shares = append([]spendings.ShareOfExpense{}, expense.Shares...)
+	return expense
+}
@@ -1,34 +1,66 @@
 package spendings_mock
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/spendings"
 )
 
 type RepositoryMock struct {
-	AddExpenseImpl         func(id spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId]
-	RemoveExpenseImpl      func(id spendings.ExpenseId) repositories.MutationWorkItem
-	GetExpenseImpl         func(id spendings.ExpenseId) (*spendings.IdentifiableExpense, error)
-	GetExpensesBetweenImpl func(counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error)
-	GetBalanceImpl         func(counterparty spendings.CounterpartyId) ([]spendings.Balance, error)
+	AddExpenseImpl         func(ctx context.Context, id spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId]
+	RemoveExpenseImpl      func(ctx context.Context, id spendings.ExpenseId) repositories.MutationWorkItem
+	EditExpenseImpl        func(ctx context.Context, id spendings.ExpenseId, expense spendings.Expense) repositories.MutationWorkItem
+	GetExpenseImpl         func(ctx context.Context, id spendings.ExpenseId) (*spendings.IdentifiableExpense, error)
+	GetExpensesBetweenImpl func(ctx context.Context, counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error)
+	GetExpensesOfImpl      func(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error)
+	GetBalanceImpl         func(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.Balance, error)
+	GetJournalImpl         func(ctx context.Context, account spendings.CounterpartyId, counterparty spendings.CounterpartyId) ([]spendings.JournalEntry, error)
+	RebuildBalancesImpl    func(ctx context.Context) error
+	VerifyBalancesImpl     func(ctx context.Context) ([]spendings.BalanceMismatch, error)
+}
+
+func (c *RepositoryMock) AddExpense(ctx context.Context, id spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId] {
+	return c.AddExpenseImpl(ctx, id)
+}
+
+func (c *RepositoryMock) RemoveExpense(ctx context.Context, id spendings.ExpenseId) repositories.MutationWorkItem {
+	return c.RemoveExpenseImpl(ctx, id)
+}
+
+func (c *RepositoryMock) EditExpense(ctx context.Context, id spendings.ExpenseId, expense spendings.Expense) repositories.MutationWorkItem {
+	return c.EditExpenseImpl(ctx, id, expense)
+}
+
+func (c *RepositoryMock) GetExpense(ctx context.Context, id spendings.ExpenseId) (*spendings.IdentifiableExpense, error) {
+	return c.GetExpenseImpl(ctx, id)
+}
+
+func (c *RepositoryMock) GetExpensesBetween(ctx context.Context, counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+	return c.GetExpensesBetweenImpl(ctx, counterparty1, counterparty2)
+}
+
+func (c *RepositoryMock) GetBalance(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.Balance, error) {
+	return c.GetBalanceImpl(ctx, counterparty)
 }
 
-func (c *RepositoryMock) AddExpense(id spendings.Expense) repositories.MutationWorkItemWithReturnValue[spendings.ExpenseId] {
-	return c.AddExpenseImpl(id)
+func (c *RepositoryMock) GetExpensesOf(ctx context.Context, counterparty spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
+	return c.GetExpensesOfImpl(ctx, counterparty)
 }
 
-func (c *RepositoryMock) RemoveExpense(id spendings.ExpenseId) repositories.MutationWorkItem {
-	return c.RemoveExpenseImpl(id)
+func (c *RepositoryMock) WithTx(tx db.DB) spendings.Repository {
+	return c
 }
 
-func (c *RepositoryMock) GetExpense(id spendings.ExpenseId) (*spendings.IdentifiableExpense, error) {
-	return c.GetExpenseImpl(id)
+func (c *RepositoryMock) GetJournal(ctx context.Context, account spendings.CounterpartyId, counterparty spendings.CounterpartyId) ([]spendings.JournalEntry, error) {
+	return c.GetJournalImpl(ctx, account, counterparty)
 }
 
-func (c *RepositoryMock) GetExpensesBetween(counterparty1 spendings.CounterpartyId, counterparty2 spendings.CounterpartyId) ([]spendings.IdentifiableExpense, error) {
-	return c.GetExpensesBetweenImpl(counterparty1, counterparty2)
+func (c *RepositoryMock) RebuildBalances(ctx context.Context) error {
+	return c.RebuildBalancesImpl(ctx)
 }
 
-func (c *RepositoryMock) GetBalance(counterparty spendings.CounterpartyId) ([]spendings.Balance, error) {
-	return c.GetBalanceImpl(counterparty)
+func (c *RepositoryMock) VerifyBalances(ctx context.Context) ([]spendings.BalanceMismatch, error) {
+	return c.VerifyBalancesImpl(ctx)
 }
@@ -1,6 +1,10 @@
 package spendings
 
 import (
+	"context"
+	"errors"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
@@ -32,12 +36,57 @@ type Balance struct {
 	Currencies   map[Currency]Cost
 }
 
+var ErrUnbalancedExpense = errors.New("expense shares do not add up to zero")
+
+type JournalEntryKind string
+
+const (
+	JournalEntryKindExpense  JournalEntryKind = "expense"
+	JournalEntryKindReversal JournalEntryKind = "reversal"
+	// JournalEntryKindEdit marks a single transaction that reverses an edited expense and re-posts
+	// its new debts.
+	JournalEntryKindEdit JournalEntryKind = "edit"
+)
+
+// JournalEntry is a line of the append-only spendings journal. Every posting consists of pairs of
+// lines: Amount is owed to Account by Counterparty, and the mirrored line with the opposite Amount.
+// A balance of Account with Counterparty is a sum of Amount over all their lines.
+type JournalEntry struct {
+	Sequence      int64
+	TransactionId string
+	ExpenseId     ExpenseId
+	Kind          JournalEntryKind
+	Account       CounterpartyId
+	Counterparty  CounterpartyId
+	Currency      Currency
+	Amount        Cost
+	Timestamp     int64
+}
+
+// BalanceMismatch describes a materialized balance of Owner with Counterparty that differs from one
+// computed from the journal.
+type BalanceMismatch struct {
+	Owner        CounterpartyId
+	Counterparty CounterpartyId
+	Currency     Currency
+	Materialized Cost
+	Ledger       Cost
+}
+
 type Repository interface {
-	AddExpense(id Expense) repositories.MutationWorkItemWithReturnValue[ExpenseId]
-	RemoveExpense(id ExpenseId) repositories.MutationWorkItem
+	AddExpense(ctx context.Context, id Expense) repositories.MutationWorkItemWithReturnValue[ExpenseId]
+	RemoveExpense(ctx context.Context, id ExpenseId) repositories.MutationWorkItem
+	EditExpense(ctx context.Context, id ExpenseId, expense Expense) repositories.MutationWorkItem
+
+	GetExpense(ctx context.Context, id ExpenseId) (*IdentifiableExpense, error)
+
+	GetExpensesBetween(ctx context.Context, counterparty1 CounterpartyId, counterparty2 CounterpartyId) ([]IdentifiableExpense, error)
+	GetExpensesOf(ctx context.Context, counterparty CounterpartyId) ([]IdentifiableExpense, error)
+	GetBalance(ctx context.Context, counterparty CounterpartyId) ([]Balance, error)
+	GetJournal(ctx context.Context, account CounterpartyId, counterparty CounterpartyId) ([]JournalEntry, error)
 
-	GetExpense(id ExpenseId) (*IdentifiableExpense, error)
+	RebuildBalances(ctx context.Context) error
+	VerifyBalances(ctx context.Context) ([]BalanceMismatch, error)
 
-	GetExpensesBetween(counterparty1 CounterpartyId, counterparty2 CounterpartyId) ([]IdentifiableExpense, error)
-	GetBalance(counterparty CounterpartyId) ([]Balance, error)
+	WithTx(tx db.DB) Repository
 }
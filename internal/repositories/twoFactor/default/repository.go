@@ -0,0 +1,405 @@
+package defaultRepository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(db db.DB, logger logging.Service) twoFactor.Repository {
+	return &defaultRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type defaultRepository struct {
+	db     db.DB
+	logger logging.Service
+}
+
+func (c *defaultRepository) WithTx(tx db.DB) twoFactor.Repository {
+	return New(tx, c.logger)
+}
+
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c *defaultRepository) StoreSecret(ctx context.Context, uid twoFactor.UserId, secret string) repositories.MutationWorkItem {
+	const op = "repositories.twoFactor.postgresRepository.StoreSecret"
+	existed, err := c.GetSecret(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			return c.storeSecret(ctx, uid, twoFactor.Secret{
+				Value:     secret,
+				Confirmed: false,
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				return c.removeSecret(ctx, uid)
+			} else {
+				return c.storeSecret(ctx, uid, *existed)
+			}
+		},
+	}
+}
+
+func (c *defaultRepository) storeSecret(ctx context.Context, uid twoFactor.UserId, secret twoFactor.Secret) error {
+	const op = "repositories.twoFactor.postgresRepository.storeSecret"
+	c.logger.LogInfo("%s: start[uid=%s confirmed=%t]", op, uid, secret.Confirmed)
+	query := `
+INSERT INTO twoFactorSecrets(id, secret, confirmed, lastAcceptedStep) VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE SET secret = $2, confirmed = $3, lastAcceptedStep = $4;
+`
+	_, err := c.db.ExecContext(ctx, query, string(uid), secret.Value, secret.Confirmed, secret.LastAcceptedStep)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s confirmed=%t]", op, uid, secret.Confirmed)
+	return nil
+}
+
+func (c *defaultRepository) removeSecret(ctx context.Context, uid twoFactor.UserId) error {
+	const op = "repositories.twoFactor.postgresRepository.removeSecret"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `DELETE FROM twoFactorSecrets WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
+
+func (c *defaultRepository) ConfirmSecret(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	const op = "repositories.twoFactor.postgresRepository.ConfirmSecret"
+	existed, err := c.GetSecret(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				c.logger.LogInfo("%s: secret to confirm not found", op)
+				return errors.New("secret to confirm not found")
+			}
+			if existed.Confirmed {
+				return nil
+			}
+			return c.storeSecret(ctx, uid, twoFactor.Secret{
+				Value:            existed.Value,
+				Confirmed:        true,
+				LastAcceptedStep: existed.LastAcceptedStep,
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				c.logger.LogInfo("%s: secret to confirm not found", op)
+				return errors.New("secret to confirm not found")
+			}
+			return c.storeSecret(ctx, uid, *existed)
+		},
+	}
+}
+
+func (c *defaultRepository) GetSecret(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+	const op = "repositories.twoFactor.postgresRepository.GetSecret"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT secret, confirmed, lastAcceptedStep FROM twoFactorSecrets WHERE id = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var secret twoFactor.Secret
+		if err := rows.Scan(&secret.Value, &secret.Confirmed, &secret.LastAcceptedStep); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		if err := rows.Err(); err != nil {
+			c.logger.LogInfo("%s: found rows err: %v", op, err)
+			return nil, err
+		}
+		c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+		return &secret, nil
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil, nil
+}
+
+func (c *defaultRepository) RemoveSecret(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	const op = "repositories.twoFactor.postgresRepository.RemoveSecret"
+	existed, err := c.GetSecret(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			return c.removeSecret(ctx, uid)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			if existed == nil {
+				return nil
+			}
+			return c.storeSecret(ctx, uid, *existed)
+		},
+	}
+}
+
+func (c *defaultRepository) AcceptStep(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	const op = "repositories.twoFactor.postgresRepository.AcceptStep"
+	existed, err := c.GetSecret(ctx, uid)
+	var accepted bool
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: func() (bool, error) {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return false, err
+			}
+			var acceptErr error
+			accepted, acceptErr = c.acceptStep(ctx, uid, step)
+			return accepted, acceptErr
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current secret err: %v", op, err)
+				return err
+			}
+			if !accepted || existed == nil {
+				return nil
+			}
+			return c.restoreStep(ctx, uid, step, existed.LastAcceptedStep)
+		},
+	}
+}
+
+func (c *defaultRepository) acceptStep(ctx context.Context, uid twoFactor.UserId, step int64) (bool, error) {
+	const op = "repositories.twoFactor.postgresRepository.acceptStep"
+	c.logger.LogInfo("%s: start[uid=%s step=%d]", op, uid, step)
+	query := `UPDATE twoFactorSecrets SET lastAcceptedStep = $2 WHERE id = $1 AND lastAcceptedStep < $2;`
+	result, err := c.db.ExecContext(ctx, query, string(uid), step)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get affected rows err: %v", op, err)
+		return false, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s step=%d accepted=%t]", op, uid, step, updated > 0)
+	return updated > 0, nil
+}
+
+func (c *defaultRepository) restoreStep(ctx context.Context, uid twoFactor.UserId, step int64, previous int64) error {
+	const op = "repositories.twoFactor.postgresRepository.restoreStep"
+	c.logger.LogInfo("%s: start[uid=%s step=%d]", op, uid, previous)
+	query := `UPDATE twoFactorSecrets SET lastAcceptedStep = $3 WHERE id = $1 AND lastAcceptedStep = $2;`
+	if _, err := c.db.ExecContext(ctx, query, string(uid), step, previous); err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s step=%d]", op, uid, previous)
+	return nil
+}
+
+func (c *defaultRepository) StoreRecoveryCodes(ctx context.Context, uid twoFactor.UserId, codes []string) repositories.MutationWorkItem {
+	const op = "repositories.twoFactor.postgresRepository.StoreRecoveryCodes"
+	existed, err := c.getRecoveryCodeHashes(ctx, uid)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current recovery codes err: %v", op, err)
+				return err
+			}
+			return c.replaceRecoveryCodeHashes(ctx, uid, hashes)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current recovery codes err: %v", op, err)
+				return err
+			}
+			return c.replaceRecoveryCodeHashes(ctx, uid, existed)
+		},
+	}
+}
+
+func (c *defaultRepository) replaceRecoveryCodeHashes(ctx context.Context, uid twoFactor.UserId, hashes []string) error {
+	const op = "repositories.twoFactor.postgresRepository.replaceRecoveryCodeHashes"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	if err := db.Transact(ctx, c.db, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM twoFactorRecoveryCodes WHERE id = $1;`, string(uid)); err != nil {
+			c.logger.LogInfo("%s: failed to remove recovery codes err: %v", op, err)
+			return err
+		}
+		for i := range hashes {
+			query := `INSERT INTO twoFactorRecoveryCodes(id, codeHash) VALUES ($1, $2);`
+			if _, err := tx.ExecContext(ctx, query, string(uid), hashes[i]); err != nil {
+				c.logger.LogInfo("%s: failed to insert recovery code %d err: %v", op, i, err)
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		c.logger.LogInfo("%s: failed to perform tx err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
+
+func (c *defaultRepository) getRecoveryCodeHashes(ctx context.Context, uid twoFactor.UserId) ([]string, error) {
+	const op = "repositories.twoFactor.postgresRepository.getRecoveryCodeHashes"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT codeHash FROM twoFactorRecoveryCodes WHERE id = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(uid))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return []string{}, err
+	}
+	defer rows.Close()
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return []string{}, err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return []string{}, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return hashes, nil
+}
+
+func (c *defaultRepository) HasRecoveryCode(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+	const op = "repositories.twoFactor.postgresRepository.HasRecoveryCode"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `SELECT EXISTS(SELECT 1 FROM twoFactorRecoveryCodes WHERE id = $1 AND codeHash = $2);`
+	row := c.db.QueryRowContext(ctx, query, string(uid), hashRecoveryCode(code))
+	var exists bool
+	if err := row.Scan(&exists); err != nil {
+		c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+		return false, err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return exists, nil
+}
+
+func (c *defaultRepository) RemoveRecoveryCode(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem {
+	hash := hashRecoveryCode(code)
+	var removed bool
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err := c.removeRecoveryCodeHash(ctx, uid, hash); err != nil {
+				return err
+			}
+			removed = true
+			return nil
+		},
+		Rollback: func() error {
+			if !removed {
+				return nil
+			}
+			return c.storeRecoveryCodeHash(ctx, uid, hash)
+		},
+	}
+}
+
+func (c *defaultRepository) RemoveRecoveryCodes(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	const op = "repositories.twoFactor.postgresRepository.RemoveRecoveryCodes"
+	existed, err := c.getRecoveryCodeHashes(ctx, uid)
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current recovery codes err: %v", op, err)
+				return err
+			}
+			return c.replaceRecoveryCodeHashes(ctx, uid, []string{})
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: failed to get current recovery codes err: %v", op, err)
+				return err
+			}
+			return c.replaceRecoveryCodeHashes(ctx, uid, existed)
+		},
+	}
+}
+
+func (c *defaultRepository) storeRecoveryCodeHash(ctx context.Context, uid twoFactor.UserId, hash string) error {
+	const op = "repositories.twoFactor.postgresRepository.storeRecoveryCodeHash"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `INSERT INTO twoFactorRecoveryCodes(id, codeHash) VALUES ($1, $2);`
+	_, err := c.db.ExecContext(ctx, query, string(uid), hash)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
+
+func (c *defaultRepository) removeRecoveryCodeHash(ctx context.Context, uid twoFactor.UserId, hash string) error {
+	const op = "repositories.twoFactor.postgresRepository.removeRecoveryCodeHash"
+	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
+	query := `DELETE FROM twoFactorRecoveryCodes WHERE id = $1 AND codeHash = $2;`
+	result, err := c.db.ExecContext(ctx, query, string(uid), hash)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		c.logger.LogInfo("%s: failed to get affected rows err: %v", op, err)
+		return err
+	}
+	if removed == 0 {
+		c.logger.LogInfo("%s: recovery code not found", op)
+		return twoFactor.ErrRecoveryCodeNotFound
+	}
+	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+	return nil
+}
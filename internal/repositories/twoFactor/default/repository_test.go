@@ -0,0 +1,328 @@
+package defaultRepository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
+	defaultRepository "github.com/rzmn/governi/internal/repositories/twoFactor/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/twoFactor/memory"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+
+	"github.com/google/uuid"
+)
+
+var (
+	newRepository func() twoFactor.Repository
+)
+
+func TestMain(m *testing.M) {
+	logger := standartOutputLoggingService.New()
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() twoFactor.Repository {
+			return defaultRepository.New(database, logger)
+		}
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() twoFactor.Repository {
+			return repository
+		}
+	}
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func randomUid() twoFactor.UserId {
+	return twoFactor.UserId(uuid.New().String())
+}
+
+func TestStoreSecret(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	secret := uuid.New().String()
+
+	secretFromDb, err := repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[initial] failed to get secret err: %v", err)
+	}
+	if secretFromDb != nil {
+		t.Fatalf("[initial] secret should be nil, found %v", *secretFromDb)
+	}
+	storeTransaction := repository.StoreSecret(context.Background(), userId, secret)
+	if err := storeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
+	}
+	secretFromDb, err = repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get secret err: %v", err)
+	}
+	if secretFromDb == nil || secretFromDb.Value != secret || secretFromDb.Confirmed {
+		t.Fatalf("secret should be unconfirmed %s, found %v", secret, secretFromDb)
+	}
+	if err := storeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `storeTransaction` err: %v", err)
+	}
+	secretFromDb, err = repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get secret err: %v", err)
+	}
+	if secretFromDb != nil {
+		t.Fatalf("[after rollback] secret should be nil, found %v", *secretFromDb)
+	}
+}
+
+func TestConfirmSecret(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	secret := uuid.New().String()
+
+	if err := repository.ConfirmSecret(context.Background(), userId).Perform(); err == nil {
+		t.Fatalf("[initial] expected to get error from `ConfirmSecret`, found nil")
+	}
+	if err := repository.StoreSecret(context.Background(), userId, secret).Perform(); err != nil {
+		t.Fatalf("failed to store secret err: %v", err)
+	}
+	confirmTransaction := repository.ConfirmSecret(context.Background(), userId)
+	if err := confirmTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `confirmTransaction` err: %v", err)
+	}
+	secretFromDb, err := repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get secret err: %v", err)
+	}
+	if secretFromDb == nil || secretFromDb.Value != secret || !secretFromDb.Confirmed {
+		t.Fatalf("secret should be confirmed %s, found %v", secret, secretFromDb)
+	}
+	if err := confirmTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `confirmTransaction` err: %v", err)
+	}
+	secretFromDb, err = repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get secret err: %v", err)
+	}
+	if secretFromDb == nil || secretFromDb.Confirmed {
+		t.Fatalf("[after rollback] secret should be unconfirmed, found %v", secretFromDb)
+	}
+}
+
+func TestAcceptStep(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	step := time.Now().Unix() / 30
+
+	accepted, err := repository.AcceptStep(context.Background(), userId, step).Perform()
+	if err != nil {
+		t.Fatalf("[initial] failed to accept step err: %v", err)
+	}
+	if accepted {
+		t.Fatalf("[initial] step should not be accepted without a secret")
+	}
+	if err := repository.StoreSecret(context.Background(), userId, uuid.New().String()).Perform(); err != nil {
+		t.Fatalf("failed to store secret err: %v", err)
+	}
+	acceptTransaction := repository.AcceptStep(context.Background(), userId, step)
+	accepted, err = acceptTransaction.Perform()
+	if err != nil {
+		t.Fatalf("failed to perform `acceptTransaction` err: %v", err)
+	}
+	if !accepted {
+		t.Fatalf("step should be accepted")
+	}
+	for _, replayed := range []int64{step, step - 1} {
+		accepted, err = repository.AcceptStep(context.Background(), userId, replayed).Perform()
+		if err != nil {
+			t.Fatalf("[replay %d] failed to accept step err: %v", replayed, err)
+		}
+		if accepted {
+			t.Fatalf("[replay %d] step should not be accepted twice", replayed)
+		}
+	}
+	secretFromDb, err := repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get secret err: %v", err)
+	}
+	if secretFromDb == nil || secretFromDb.LastAcceptedStep != step {
+		t.Fatalf("last accepted step should be %d, found %v", step, secretFromDb)
+	}
+	if err := acceptTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `acceptTransaction` err: %v", err)
+	}
+	secretFromDb, err = repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get secret err: %v", err)
+	}
+	if secretFromDb == nil || secretFromDb.LastAcceptedStep != 0 {
+		t.Fatalf("[after rollback] last accepted step should be 0, found %v", secretFromDb)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	codes := []string{uuid.New().String(), uuid.New().String()}
+
+	storeTransaction := repository.StoreRecoveryCodes(context.Background(), userId, codes)
+	if err := storeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
+	}
+	for _, code := range codes {
+		has, err := repository.HasRecoveryCode(context.Background(), userId, code)
+		if err != nil {
+			t.Fatalf("failed to check recovery code err: %v", err)
+		}
+		if !has {
+			t.Fatalf("recovery code %s should exist", code)
+		}
+	}
+	removeTransaction := repository.RemoveRecoveryCode(context.Background(), userId, codes[0])
+	if err := removeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
+	}
+	has, err := repository.HasRecoveryCode(context.Background(), userId, codes[0])
+	if err != nil {
+		t.Fatalf("failed to check recovery code err: %v", err)
+	}
+	if has {
+		t.Fatalf("removed recovery code should not exist")
+	}
+	if err := removeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
+	}
+	has, err = repository.HasRecoveryCode(context.Background(), userId, codes[0])
+	if err != nil {
+		t.Fatalf("[after rollback] failed to check recovery code err: %v", err)
+	}
+	if !has {
+		t.Fatalf("[after rollback] recovery code should exist")
+	}
+	if err := storeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `storeTransaction` err: %v", err)
+	}
+	for _, code := range codes {
+		has, err := repository.HasRecoveryCode(context.Background(), userId, code)
+		if err != nil {
+			t.Fatalf("[after rollback] failed to check recovery code err: %v", err)
+		}
+		if has {
+			t.Fatalf("[after rollback] recovery code %s should not exist", code)
+		}
+	}
+}
+
+func TestRemoveRecoveryCodeTwice(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	code := uuid.New().String()
+
+	if err := repository.StoreRecoveryCodes(context.Background(), userId, []string{code}).Perform(); err != nil {
+		t.Fatalf("failed to store recovery codes err: %v", err)
+	}
+	// both are prepared before either is performed, as with two concurrent requests
+	first := repository.RemoveRecoveryCode(context.Background(), userId, code)
+	second := repository.RemoveRecoveryCode(context.Background(), userId, code)
+	if err := first.Perform(); err != nil {
+		t.Fatalf("failed to perform `first` err: %v", err)
+	}
+	if err := second.Perform(); !errors.Is(err, twoFactor.ErrRecoveryCodeNotFound) {
+		t.Fatalf("recovery code should not be removed twice, found err %v", err)
+	}
+	if err := second.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `second` err: %v", err)
+	}
+	has, err := repository.HasRecoveryCode(context.Background(), userId, code)
+	if err != nil {
+		t.Fatalf("failed to check recovery code err: %v", err)
+	}
+	if has {
+		t.Fatalf("failed removal should not restore the code on rollback")
+	}
+}
+
+func TestRemoveSecretAndRecoveryCodes(t *testing.T) {
+	repository := newRepository()
+	userId := randomUid()
+	secret := uuid.New().String()
+	codes := []string{uuid.New().String(), uuid.New().String()}
+
+	if err := repository.StoreSecret(context.Background(), userId, secret).Perform(); err != nil {
+		t.Fatalf("failed to store secret err: %v", err)
+	}
+	if err := repository.StoreRecoveryCodes(context.Background(), userId, codes).Perform(); err != nil {
+		t.Fatalf("failed to store recovery codes err: %v", err)
+	}
+	removeSecretTransaction := repository.RemoveSecret(context.Background(), userId)
+	if err := removeSecretTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeSecretTransaction` err: %v", err)
+	}
+	removeCodesTransaction := repository.RemoveRecoveryCodes(context.Background(), userId)
+	if err := removeCodesTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `removeCodesTransaction` err: %v", err)
+	}
+	secretFromDb, err := repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("failed to get secret err: %v", err)
+	}
+	if secretFromDb != nil {
+		t.Fatalf("secret should be removed, found %v", *secretFromDb)
+	}
+	for _, code := range codes {
+		has, err := repository.HasRecoveryCode(context.Background(), userId, code)
+		if err != nil {
+			t.Fatalf("failed to check recovery code err: %v", err)
+		}
+		if has {
+			t.Fatalf("recovery code %s should be removed", code)
+		}
+	}
+	if err := removeCodesTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeCodesTransaction` err: %v", err)
+	}
+	if err := removeSecretTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `removeSecretTransaction` err: %v", err)
+	}
+	secretFromDb, err = repository.GetSecret(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get secret err: %v", err)
+	}
+	if secretFromDb == nil || secretFromDb.Value != secret {
+		t.Fatalf("[after rollback] secret should be %s, found %v", secret, secretFromDb)
+	}
+	for _, code := range codes {
+		has, err := repository.HasRecoveryCode(context.Background(), userId, code)
+		if err != nil {
+			t.Fatalf("[after rollback] failed to check recovery code err: %v", err)
+		}
+		if !has {
+			t.Fatalf("[after rollback] recovery code %s should exist", code)
+		}
+	}
+}
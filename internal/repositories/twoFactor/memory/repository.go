@@ -0,0 +1,252 @@
+package memoryRepository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) twoFactor.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			secrets:       map[twoFactor.UserId]twoFactor.Secret{},
+			recoveryCodes: map[twoFactor.UserId]map[string]struct{}{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex         sync.RWMutex
+	secrets       map[twoFactor.UserId]twoFactor.Secret
+	recoveryCodes map[twoFactor.UserId]map[string]struct{}
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) twoFactor.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c *memoryRepository) StoreSecret(ctx context.Context, uid twoFactor.UserId, secret string) repositories.MutationWorkItem {
+	existed, _ := c.GetSecret(ctx, uid)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storeSecret(uid, &twoFactor.Secret{
+				Value:     secret,
+				Confirmed: false,
+			})
+			return nil
+		},
+		Rollback: func() error {
+			c.storeSecret(uid, existed)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) ConfirmSecret(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	existed, _ := c.GetSecret(ctx, uid)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if existed == nil {
+				return errors.New("secret to confirm not found")
+			}
+			c.storeSecret(uid, &twoFactor.Secret{
+				Value:            existed.Value,
+				Confirmed:        true,
+				LastAcceptedStep: existed.LastAcceptedStep,
+			})
+			return nil
+		},
+		Rollback: func() error {
+			if existed == nil {
+				return errors.New("secret to confirm not found")
+			}
+			c.storeSecret(uid, existed)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) GetSecret(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	secret, ok := c.storage.secrets[uid]
+	if !ok {
+		return nil, nil
+	}
+	return &secret, nil
+}
+
+func (c *memoryRepository) RemoveSecret(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	existed, _ := c.GetSecret(ctx, uid)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storeSecret(uid, nil)
+			return nil
+		},
+		Rollback: func() error {
+			c.storeSecret(uid, existed)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) AcceptStep(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	var accepted bool
+	var previous int64
+	rollback := func() error {
+		if !accepted {
+			return nil
+		}
+		c.storage.mutex.Lock()
+		defer c.storage.mutex.Unlock()
+		if secret, ok := c.storage.secrets[uid]; ok && secret.LastAcceptedStep == step {
+			secret.LastAcceptedStep = previous
+			c.storage.secrets[uid] = secret
+		}
+		return nil
+	}
+	return repositories.MutationWorkItemWithReturnValue[bool]{
+		Perform: memoryDb.TrackWithReturnValue(c.tx, func() (bool, error) {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			secret, ok := c.storage.secrets[uid]
+			if !ok || secret.LastAcceptedStep >= step {
+				accepted = false
+				return false, nil
+			}
+			previous = secret.LastAcceptedStep
+			secret.LastAcceptedStep = step
+			c.storage.secrets[uid] = secret
+			accepted = true
+			return true, nil
+		}, rollback),
+		Rollback: rollback,
+	}
+}
+
+func (c *memoryRepository) storeSecret(uid twoFactor.UserId, secret *twoFactor.Secret) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if secret == nil {
+		delete(c.storage.secrets, uid)
+	} else {
+		c.storage.secrets[uid] = *secret
+	}
+}
+
+func (c *memoryRepository) StoreRecoveryCodes(ctx context.Context, uid twoFactor.UserId, codes []string) repositories.MutationWorkItem {
+	c.storage.mutex.RLock()
+	existed := []string{}
+	for hash := range c.storage.recoveryCodes[uid] {
+		existed = append(existed, hash)
+	}
+	c.storage.mutex.RUnlock()
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.replaceRecoveryCodeHashes(uid, hashes)
+			return nil
+		},
+		Rollback: func() error {
+			c.replaceRecoveryCodeHashes(uid, existed)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) HasRecoveryCode(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	_, ok := c.storage.recoveryCodes[uid][hashRecoveryCode(code)]
+	return ok, nil
+}
+
+func (c *memoryRepository) RemoveRecoveryCode(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem {
+	hash := hashRecoveryCode(code)
+	var removed bool
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			if _, ok := c.storage.recoveryCodes[uid][hash]; !ok {
+				return twoFactor.ErrRecoveryCodeNotFound
+			}
+			delete(c.storage.recoveryCodes[uid], hash)
+			removed = true
+			return nil
+		},
+		Rollback: func() error {
+			if !removed {
+				return nil
+			}
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			if _, ok := c.storage.recoveryCodes[uid]; !ok {
+				c.storage.recoveryCodes[uid] = map[string]struct{}{}
+			}
+			c.storage.recoveryCodes[uid][hash] = struct{}{}
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) RemoveRecoveryCodes(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	c.storage.mutex.RLock()
+	existed := []string{}
+	for hash := range c.storage.recoveryCodes[uid] {
+		existed = append(existed, hash)
+	}
+	c.storage.mutex.RUnlock()
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.replaceRecoveryCodeHashes(uid, []string{})
+			return nil
+		},
+		Rollback: func() error {
+			c.replaceRecoveryCodeHashes(uid, existed)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) replaceRecoveryCodeHashes(uid twoFactor.UserId, hashes []string) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	codes := map[string]struct{}{}
+	for _, hash := range hashes {
+		codes[hash] = struct{}{}
+	}
+	c.storage.recoveryCodes[uid] = codes
+}
@@ -0,0 +1,61 @@
+package twoFactor_mock
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/twoFactor"
+)
+
+type RepositoryMock struct {
+	StoreSecretImpl         func(ctx context.Context, uid twoFactor.UserId, secret string) repositories.MutationWorkItem
+	ConfirmSecretImpl       func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem
+	GetSecretImpl           func(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error)
+	RemoveSecretImpl        func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem
+	AcceptStepImpl          func(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool]
+	StoreRecoveryCodesImpl  func(ctx context.Context, uid twoFactor.UserId, codes []string) repositories.MutationWorkItem
+	HasRecoveryCodeImpl     func(ctx context.Context, uid twoFactor.UserId, code string) (bool, error)
+	RemoveRecoveryCodeImpl  func(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem
+	RemoveRecoveryCodesImpl func(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) StoreSecret(ctx context.Context, uid twoFactor.UserId, secret string) repositories.MutationWorkItem {
+	return c.StoreSecretImpl(ctx, uid, secret)
+}
+
+func (c *RepositoryMock) ConfirmSecret(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	return c.ConfirmSecretImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) GetSecret(ctx context.Context, uid twoFactor.UserId) (*twoFactor.Secret, error) {
+	return c.GetSecretImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) RemoveSecret(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	return c.RemoveSecretImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) AcceptStep(ctx context.Context, uid twoFactor.UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool] {
+	return c.AcceptStepImpl(ctx, uid, step)
+}
+
+func (c *RepositoryMock) StoreRecoveryCodes(ctx context.Context, uid twoFactor.UserId, codes []string) repositories.MutationWorkItem {
+	return c.StoreRecoveryCodesImpl(ctx, uid, codes)
+}
+
+func (c *RepositoryMock) HasRecoveryCode(ctx context.Context, uid twoFactor.UserId, code string) (bool, error) {
+	return c.HasRecoveryCodeImpl(ctx, uid, code)
+}
+
+func (c *RepositoryMock) RemoveRecoveryCode(ctx context.Context, uid twoFactor.UserId, code string) repositories.MutationWorkItem {
+	return c.RemoveRecoveryCodeImpl(ctx, uid, code)
+}
+
+func (c *RepositoryMock) RemoveRecoveryCodes(ctx context.Context, uid twoFactor.UserId) repositories.MutationWorkItem {
+	return c.RemoveRecoveryCodesImpl(ctx, uid)
+}
+
+func (c *RepositoryMock) WithTx(tx db.DB) twoFactor.Repository {
+	return c
+}
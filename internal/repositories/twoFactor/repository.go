@@ -0,0 +1,39 @@
+package twoFactor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+)
+
+type UserId string
+
+var ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+
+type Secret struct {
+	Value            string
+	Confirmed        bool
+	LastAcceptedStep int64
+}
+
+type Repository interface {
+	StoreSecret(ctx context.Context, uid UserId, secret string) repositories.MutationWorkItem
+	ConfirmSecret(ctx context.Context, uid UserId) repositories.MutationWorkItem
+	GetSecret(ctx context.Context, uid UserId) (*Secret, error)
+	RemoveSecret(ctx context.Context, uid UserId) repositories.MutationWorkItem
+
+	// AcceptStep records step as the latest time step accepted for the secret of uid. It returns
+	// false without changes if the same or a later step has already been accepted.
+	AcceptStep(ctx context.Context, uid UserId, step int64) repositories.MutationWorkItemWithReturnValue[bool]
+
+	StoreRecoveryCodes(ctx context.Context, uid UserId, codes []string) repositories.MutationWorkItem
+	HasRecoveryCode(ctx context.Context, uid UserId, code string) (bool, error)
+	// RemoveRecoveryCode fails with ErrRecoveryCodeNotFound if the code does not exist by the time
+	// it is removed, so concurrent requests cannot redeem the same code twice.
+	RemoveRecoveryCode(ctx context.Context, uid UserId, code string) repositories.MutationWorkItem
+	RemoveRecoveryCodes(ctx context.Context, uid UserId) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
+}
@@ -1,6 +1,7 @@
 package defaultRepository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -25,31 +26,35 @@ type defaultRepository struct {
 	logger logging.Service
 }
 
-func (c *defaultRepository) StoreUser(user users.User) repositories.MutationWorkItem {
+func (c *defaultRepository) WithTx(tx db.DB) users.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *defaultRepository) StoreUser(ctx context.Context, user users.User) repositories.MutationWorkItem {
 	return repositories.MutationWorkItem{
 		Perform: func() error {
-			return c.storeUser(user)
+			return c.storeUser(ctx, user)
 		},
 		Rollback: func() error {
-			return c.removeUser(user.Id)
+			return c.removeUser(ctx, user.Id)
 		},
 	}
 }
 
-func (c *defaultRepository) storeUser(user users.User) error {
+func (c *defaultRepository) storeUser(ctx context.Context, user users.User) error {
 	const op = "repositories.users.postgresRepository.storeUser"
 	c.logger.LogInfo("%s: start[id=%s]", op, user.Id)
 
 	if user.AvatarId == nil {
 		query := `INSERT INTO users(id, displayName, avatarId) VALUES ($1, $2, NULL);`
-		_, err := c.db.Exec(query, user.Id, user.DisplayName)
+		_, err := c.db.ExecContext(ctx, query, user.Id, user.DisplayName)
 		if err != nil {
 			c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 			return err
 		}
 	} else {
 		query := `INSERT INTO users(id, displayName, avatarId) VALUES ($1, $2, $3);`
-		_, err := c.db.Exec(query, user.Id, user.DisplayName, *user.AvatarId)
+		_, err := c.db.ExecContext(ctx, query, user.Id, user.DisplayName, *user.AvatarId)
 		if err != nil {
 			c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 			return err
@@ -59,11 +64,11 @@ func (c *defaultRepository) storeUser(user users.User) error {
 	return nil
 }
 
-func (c *defaultRepository) removeUser(userId users.UserId) error {
+func (c *defaultRepository) removeUser(ctx context.Context, userId users.UserId) error {
 	const op = "repositories.users.postgresRepository.removeUser"
 	c.logger.LogInfo("%s: start[id=%s]", op, userId)
 	query := `DELETE FROM users WHERE id = $1;`
-	_, err := c.db.Exec(query, string(userId))
+	_, err := c.db.ExecContext(ctx, query, string(userId))
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -72,7 +77,7 @@ func (c *defaultRepository) removeUser(userId users.UserId) error {
 	return nil
 }
 
-func (c *defaultRepository) GetUsers(ids []users.UserId) ([]users.User, error) {
+func (c *defaultRepository) GetUsers(ctx context.Context, ids []users.UserId) ([]users.User, error) {
 	const op = "repositories.users.postgresRepository.GetUsers"
 	c.logger.LogInfo("%s: start", op)
 	if len(ids) == 0 {
@@ -85,7 +90,7 @@ func (c *defaultRepository) GetUsers(ids []users.UserId) ([]users.User, error) {
 			return fmt.Sprintf("'%s'", id)
 		}), ","),
 	)
-	rows, err := c.db.Query(query)
+	rows, err := c.db.QueryContext(ctx, query)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return []users.User{}, err
@@ -120,10 +125,10 @@ func (c *defaultRepository) GetUsers(ids []users.UserId) ([]users.User, error) {
 	return result, nil
 }
 
-func (c *defaultRepository) UpdateDisplayName(name string, id users.UserId) repositories.MutationWorkItem {
+func (c *defaultRepository) UpdateDisplayName(ctx context.Context, name string, id users.UserId) repositories.MutationWorkItem {
 	const op = "repositories.users.postgresRepository.UpdateDisplayName"
 	c.logger.LogInfo("%s: start[name=%s id=%s]", op, name, id)
-	usersFromDb, err := c.GetUsers([]users.UserId{id})
+	usersFromDb, err := c.GetUsers(ctx, []users.UserId{id})
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
@@ -135,7 +140,7 @@ func (c *defaultRepository) UpdateDisplayName(name string, id users.UserId) repo
 				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
 				return err
 			}
-			return c.updateDisplayName(name, id)
+			return c.updateDisplayName(ctx, name, id)
 		},
 		Rollback: func() error {
 			if err != nil {
@@ -147,16 +152,16 @@ func (c *defaultRepository) UpdateDisplayName(name string, id users.UserId) repo
 				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
 				return err
 			}
-			return c.updateDisplayName(usersFromDb[0].DisplayName, id)
+			return c.updateDisplayName(ctx, usersFromDb[0].DisplayName, id)
 		},
 	}
 }
 
-func (c *defaultRepository) updateDisplayName(name string, id users.UserId) error {
+func (c *defaultRepository) updateDisplayName(ctx context.Context, name string, id users.UserId) error {
 	const op = "repositories.users.postgresRepository.updateDisplayName"
 	c.logger.LogInfo("%s: start[name=%s id=%s]", op, name, id)
 	query := `UPDATE users SET displayName = $2 WHERE id = $1;`
-	_, err := c.db.Exec(query, string(id), name)
+	_, err := c.db.ExecContext(ctx, query, string(id), name)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -165,10 +170,10 @@ func (c *defaultRepository) updateDisplayName(name string, id users.UserId) erro
 	return nil
 }
 
-func (c *defaultRepository) UpdateAvatarId(avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
+func (c *defaultRepository) UpdateAvatarId(ctx context.Context, avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
 	const op = "repositories.users.postgresRepository.UpdateAvatarId"
 	c.logger.LogInfo("%s: start[avatarId=%v id=%s]", op, avatarId, id)
-	usersFromDb, err := c.GetUsers([]users.UserId{id})
+	usersFromDb, err := c.GetUsers(ctx, []users.UserId{id})
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
@@ -180,7 +185,7 @@ func (c *defaultRepository) UpdateAvatarId(avatarId *users.AvatarId, id users.Us
 				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
 				return err
 			}
-			return c.updateAvatarId(avatarId, id)
+			return c.updateAvatarId(ctx, avatarId, id)
 		},
 		Rollback: func() error {
 			if err != nil {
@@ -192,24 +197,24 @@ func (c *defaultRepository) UpdateAvatarId(avatarId *users.AvatarId, id users.Us
 				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
 				return err
 			}
-			return c.updateAvatarId((*users.AvatarId)(usersFromDb[0].AvatarId), id)
+			return c.updateAvatarId(ctx, (*users.AvatarId)(usersFromDb[0].AvatarId), id)
 		},
 	}
 }
 
-func (c *defaultRepository) updateAvatarId(avatarId *users.AvatarId, id users.UserId) error {
+func (c *defaultRepository) updateAvatarId(ctx context.Context, avatarId *users.AvatarId, id users.UserId) error {
 	const op = "repositories.users.postgresRepository.updateAvatarId"
 	c.logger.LogInfo("%s: start[avatarId=%v id=%s]", op, avatarId, id)
 	if avatarId == nil {
 		query := `UPDATE users SET avatarId = NULL WHERE id = $1;`
-		_, err := c.db.Exec(query, string(id))
+		_, err := c.db.ExecContext(ctx, query, string(id))
 		if err != nil {
 			c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 			return err
 		}
 	} else {
 		query := `UPDATE users SET avatarId = $2 WHERE id = $1;`
-		_, err := c.db.Exec(query, string(id), string(*avatarId))
+		_, err := c.db.ExecContext(ctx, query, string(id), string(*avatarId))
 		if err != nil {
 			c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 			return err
@@ -219,7 +224,143 @@ func (c *defaultRepository) updateAvatarId(avatarId *users.AvatarId, id users.Us
 	return nil
 }
 
-func (c *defaultRepository) SearchUsers(searchQuery string) ([]users.User, error) {
+func (c *defaultRepository) AnonymizeUser(ctx context.Context, id users.UserId) repositories.MutationWorkItem {
+	const op = "repositories.users.postgresRepository.AnonymizeUser"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	usersFromDb, err := c.GetUsers(ctx, []users.UserId{id})
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			if len(usersFromDb) == 0 {
+				err := errors.New("no such user exists")
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			return c.anonymizeUser(ctx, id)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			if len(usersFromDb) == 0 {
+				err := errors.New("no such user exists")
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			return c.restoreUser(ctx, usersFromDb[0])
+		},
+	}
+}
+
+func (c *defaultRepository) anonymizeUser(ctx context.Context, id users.UserId) error {
+	const op = "repositories.users.postgresRepository.anonymizeUser"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `UPDATE users SET displayName = $2, avatarId = NULL, deleted = True WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(id), users.DeletedUserDisplayName)
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return nil
+}
+
+func (c *defaultRepository) restoreUser(ctx context.Context, user users.User) error {
+	const op = "repositories.users.postgresRepository.restoreUser"
+	c.logger.LogInfo("%s: start[id=%s]", op, user.Id)
+	query := `UPDATE users SET displayName = $2, avatarId = $3, deleted = False WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(user.Id), user.DisplayName, (*string)(user.AvatarId))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, user.Id)
+	return nil
+}
+
+func (c *defaultRepository) GetLanguage(ctx context.Context, id users.UserId) (*users.Language, error) {
+	const op = "repositories.users.postgresRepository.GetLanguage"
+	c.logger.LogInfo("%s: start[id=%s]", op, id)
+	query := `SELECT language FROM users WHERE id = $1;`
+	rows, err := c.db.QueryContext(ctx, query, string(id))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return nil, err
+	}
+	defer rows.Close()
+	var language *users.Language
+	if rows.Next() {
+		var sqlLanguage sql.NullString
+		if err := rows.Scan(&sqlLanguage); err != nil {
+			c.logger.LogInfo("%s: failed to perform scan err: %v", op, err)
+			return nil, err
+		}
+		if sqlLanguage.Valid {
+			language = (*users.Language)(&sqlLanguage.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.LogInfo("%s: found rows err: %v", op, err)
+		return nil, err
+	}
+	c.logger.LogInfo("%s: success[id=%s]", op, id)
+	return language, nil
+}
+
+func (c *defaultRepository) UpdateLanguage(ctx context.Context, language users.Language, id users.UserId) repositories.MutationWorkItem {
+	const op = "repositories.users.postgresRepository.UpdateLanguage"
+	c.logger.LogInfo("%s: start[language=%s id=%s]", op, language, id)
+	usersFromDb, err := c.GetUsers(ctx, []users.UserId{id})
+	var existed *users.Language
+	if err == nil {
+		existed, err = c.GetLanguage(ctx, id)
+	}
+	return repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			if len(usersFromDb) == 0 {
+				err := errors.New("no such user exists")
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			return c.updateLanguage(ctx, &language, id)
+		},
+		Rollback: func() error {
+			if err != nil {
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			if len(usersFromDb) == 0 {
+				err := errors.New("no such user exists")
+				c.logger.LogInfo("%s: cannot get user info: %v", op, err)
+				return err
+			}
+			return c.updateLanguage(ctx, existed, id)
+		},
+	}
+}
+
+func (c *defaultRepository) updateLanguage(ctx context.Context, language *users.Language, id users.UserId) error {
+	const op = "repositories.users.postgresRepository.updateLanguage"
+	c.logger.LogInfo("%s: start[language=%v id=%s]", op, language, id)
+	query := `UPDATE users SET language = $2 WHERE id = $1;`
+	_, err := c.db.ExecContext(ctx, query, string(id), (*string)(language))
+	if err != nil {
+		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[language=%v id=%s]", op, language, id)
+	return nil
+}
+
+func (c *defaultRepository) SearchUsers(ctx context.Context, searchQuery string) ([]users.User, error) {
 	const op = "repositories.users.postgresRepository.SearchUsers"
 	c.logger.LogInfo("%s: start", op)
 	if len(searchQuery) == 0 {
@@ -227,10 +368,10 @@ func (c *defaultRepository) SearchUsers(searchQuery string) ([]users.User, error
 		return []users.User{}, nil
 	}
 	query := fmt.Sprintf(
-		`SELECT id, displayName, avatarId FROM users WHERE displayName LIKE '%%%s%%';`,
+		`SELECT id, displayName, avatarId FROM users WHERE displayName LIKE '%%%s%%' AND NOT deleted;`,
 		searchQuery,
 	)
-	rows, err := c.db.Query(query)
+	rows, err := db.Reader(ctx, c.db).QueryContext(ctx, query)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return []users.User{}, err
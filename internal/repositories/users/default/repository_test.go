@@ -1,6 +1,7 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
@@ -11,6 +12,7 @@ import (
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
 	"github.com/rzmn/governi/internal/repositories/users"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/users/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/users/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -18,30 +20,41 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() users.Repository
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() users.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() users.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+	}
 	code := m.Run()
 
 	os.Exit(code)
@@ -68,11 +81,11 @@ func TestStore(t *testing.T) {
 }
 
 func storeUser(user users.User, t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 
 	// if no user with this id, should return []
 
-	shouldBeEmpty, err := repository.GetUsers([]users.UserId{user.Id})
+	shouldBeEmpty, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -82,11 +95,11 @@ func storeUser(user users.User, t *testing.T) {
 
 	// check if store works
 
-	storeTransaction := repository.StoreUser(user)
+	storeTransaction := repository.StoreUser(context.Background(), user)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
-	shouldBeSingleUser, err := repository.GetUsers([]users.UserId{user.Id})
+	shouldBeSingleUser, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeSingleUser` err: %v", err)
 	}
@@ -98,7 +111,7 @@ func storeUser(user users.User, t *testing.T) {
 
 	userWithSameId := randomUserWithAvatar(true)
 	userWithSameId.Id = user.Id
-	storeUserWithSameIdAgain := repository.StoreUser(userWithSameId)
+	storeUserWithSameIdAgain := repository.StoreUser(context.Background(), userWithSameId)
 	if err := storeUserWithSameIdAgain.Perform(); err == nil {
 		t.Fatalf("`storeUserWithSameIdAgain` succeeded, expected to fail")
 	}
@@ -108,7 +121,7 @@ func storeUser(user users.User, t *testing.T) {
 	if err := storeTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `storeTransaction` err: %v", err)
 	}
-	shouldBeEmpty, err = repository.GetUsers([]users.UserId{user.Id})
+	shouldBeEmpty, err = repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -118,19 +131,19 @@ func storeUser(user users.User, t *testing.T) {
 }
 
 func TestUpdateDisplayName(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	user := randomUserWithAvatar(true)
-	storeTransaction := repository.StoreUser(user)
+	storeTransaction := repository.StoreUser(context.Background(), user)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
 	userWithNewDisplayName := user
 	userWithNewDisplayName.DisplayName = uuid.New().String()
-	updateDisplayNameTransaction := repository.UpdateDisplayName(userWithNewDisplayName.DisplayName, user.Id)
+	updateDisplayNameTransaction := repository.UpdateDisplayName(context.Background(), userWithNewDisplayName.DisplayName, user.Id)
 	if err := updateDisplayNameTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `updateDisplayNameTransaction` err: %v", err)
 	}
-	shouldBeUserWithNewDisplayName, err := repository.GetUsers([]users.UserId{user.Id})
+	shouldBeUserWithNewDisplayName, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeUserWithNewDisplayName` err: %v", err)
 	}
@@ -140,7 +153,7 @@ func TestUpdateDisplayName(t *testing.T) {
 	if err := updateDisplayNameTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `updateDisplayNameTransaction` err: %v", err)
 	}
-	shouldBeUserWithOldDisplayName, err := repository.GetUsers([]users.UserId{user.Id})
+	shouldBeUserWithOldDisplayName, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeUserWithOldDisplayName` err: %v", err)
 	}
@@ -160,18 +173,18 @@ func TestUpdateAvatar(t *testing.T) {
 }
 
 func testUpdateAvatar(user users.User, newAvatar *users.AvatarId, t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
-	storeTransaction := repository.StoreUser(user)
+	repository := newRepository()
+	storeTransaction := repository.StoreUser(context.Background(), user)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
 	userWithNewAvatar := user
 	userWithNewAvatar.AvatarId = newAvatar
-	updateAvatarTransaction := repository.UpdateAvatarId(newAvatar, user.Id)
+	updateAvatarTransaction := repository.UpdateAvatarId(context.Background(), newAvatar, user.Id)
 	if err := updateAvatarTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `updateAvatarTransaction` err: %v", err)
 	}
-	shouldBeUserWithNewAvatar, err := repository.GetUsers([]users.UserId{user.Id})
+	shouldBeUserWithNewAvatar, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeUserWithNewAvatar` err: %v", err)
 	}
@@ -181,7 +194,7 @@ func testUpdateAvatar(user users.User, newAvatar *users.AvatarId, t *testing.T)
 	if err := updateAvatarTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `updateAvatarTransaction` err: %v", err)
 	}
-	shouldBeUserWithOldAvatar, err := repository.GetUsers([]users.UserId{user.Id})
+	shouldBeUserWithOldAvatar, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeUserWithOldAvatar` err: %v", err)
 	}
@@ -191,13 +204,13 @@ func testUpdateAvatar(user users.User, newAvatar *users.AvatarId, t *testing.T)
 }
 
 func TestSearchNameContainsSubstring(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	user := randomUserWithAvatar(true)
-	storeTransaction := repository.StoreUser(user)
+	storeTransaction := repository.StoreUser(context.Background(), user)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
-	shouldContainUser, err := repository.SearchUsers(user.DisplayName[1:4])
+	shouldContainUser, err := repository.SearchUsers(context.Background(), user.DisplayName[1:4])
 	if err != nil {
 		t.Fatalf("failed to get `shouldContainUser` err: %v", err)
 	}
@@ -207,13 +220,13 @@ func TestSearchNameContainsSubstring(t *testing.T) {
 }
 
 func TestSearchNameContainsWholeString(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	user := randomUserWithAvatar(true)
-	storeTransaction := repository.StoreUser(user)
+	storeTransaction := repository.StoreUser(context.Background(), user)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
-	shouldContainUser, err := repository.SearchUsers(user.DisplayName)
+	shouldContainUser, err := repository.SearchUsers(context.Background(), user.DisplayName)
 	if err != nil {
 		t.Fatalf("failed to get `shouldContainUser` err: %v", err)
 	}
@@ -223,13 +236,13 @@ func TestSearchNameContainsWholeString(t *testing.T) {
 }
 
 func TestSearchNameDidNotMatch(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	user := randomUserWithAvatar(true)
-	storeTransaction := repository.StoreUser(user)
+	storeTransaction := repository.StoreUser(context.Background(), user)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
-	shouldNotContainUser, err := repository.SearchUsers("#$#$!@#$%)")
+	shouldNotContainUser, err := repository.SearchUsers(context.Background(), "#$#$!@#$%)")
 	if err != nil {
 		t.Fatalf("failed to get `shouldNotContainUser` err: %v", err)
 	}
@@ -237,3 +250,84 @@ func TestSearchNameDidNotMatch(t *testing.T) {
 		t.Fatalf("`shouldNotContainUser` is %v, expected empty", shouldNotContainUser)
 	}
 }
+
+func TestAnonymizeUser(t *testing.T) {
+	repository := newRepository()
+	user := randomUserWithAvatar(true)
+	if err := repository.StoreUser(context.Background(), user).Perform(); err != nil {
+		t.Fatalf("failed to store user err: %v", err)
+	}
+	anonymizeTransaction := repository.AnonymizeUser(context.Background(), user.Id)
+	if err := anonymizeTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `anonymizeTransaction` err: %v", err)
+	}
+	anonymized, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
+	if err != nil {
+		t.Fatalf("failed to get `anonymized` err: %v", err)
+	}
+	if len(anonymized) != 1 || anonymized[0].DisplayName != users.DeletedUserDisplayName || anonymized[0].AvatarId != nil {
+		t.Fatalf("`anonymized` should contain deleted user stub, found %v", anonymized)
+	}
+	shouldBeEmpty, err := repository.SearchUsers(context.Background(), users.DeletedUserDisplayName)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
+	}
+	for _, found := range shouldBeEmpty {
+		if found.Id == user.Id {
+			t.Fatalf("deleted user should not be searchable")
+		}
+	}
+	if err := anonymizeTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `anonymizeTransaction` err: %v", err)
+	}
+	restored, err := repository.GetUsers(context.Background(), []users.UserId{user.Id})
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `restored` err: %v", err)
+	}
+	if len(restored) != 1 || restored[0].DisplayName != user.DisplayName || restored[0].AvatarId == nil || *restored[0].AvatarId != *user.AvatarId {
+		t.Fatalf("[after rollback] `restored` should be equal to %v, found %v", user, restored)
+	}
+}
+
+func TestUpdateLanguage(t *testing.T) {
+	repository := newRepository()
+	user := randomUserWithAvatar(false)
+	if err := repository.StoreUser(context.Background(), user).Perform(); err != nil {
+		t.Fatalf("failed to store user err: %v", err)
+	}
+	shouldBeNil, err := repository.GetLanguage(context.Background(), user.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeNil` err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("`shouldBeNil` should be nil, found %s", *shouldBeNil)
+	}
+	updateLanguageTransaction := repository.UpdateLanguage(context.Background(), users.Language("ru"), user.Id)
+	if err := updateLanguageTransaction.Perform(); err != nil {
+		t.Fatalf("failed to perform `updateLanguageTransaction` err: %v", err)
+	}
+	shouldBeRussian, err := repository.GetLanguage(context.Background(), user.Id)
+	if err != nil {
+		t.Fatalf("failed to get `shouldBeRussian` err: %v", err)
+	}
+	if shouldBeRussian == nil || *shouldBeRussian != "ru" {
+		t.Fatalf("`shouldBeRussian` should be ru, found %v", shouldBeRussian)
+	}
+	if err := updateLanguageTransaction.Rollback(); err != nil {
+		t.Fatalf("failed to rollback `updateLanguageTransaction` err: %v", err)
+	}
+	shouldBeNil, err = repository.GetLanguage(context.Background(), user.Id)
+	if err != nil {
+		t.Fatalf("[after rollback] failed to get `shouldBeNil` err: %v", err)
+	}
+	if shouldBeNil != nil {
+		t.Fatalf("[after rollback] `shouldBeNil` should be nil, found %s", *shouldBeNil)
+	}
+}
+
+func TestUpdateLanguageOfUnknownUser(t *testing.T) {
+	repository := newRepository()
+	if err := repository.UpdateLanguage(context.Background(), users.Language("ru"), users.UserId(uuid.New().String())).Perform(); err == nil {
+		t.Fatalf("updating language of unknown user should be failed")
+	}
+}
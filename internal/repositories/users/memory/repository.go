@@ -0,0 +1,252 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/users"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) users.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			users: map[users.UserId]userRecord{},
+		},
+		logger: logger,
+	}
+}
+
+type userRecord struct {
+	user     users.User
+	language *users.Language
+	deleted  bool
+}
+
+type storage struct {
+	mutex sync.RWMutex
+	users map[users.UserId]userRecord
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) users.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) StoreUser(ctx context.Context, user users.User) repositories.MutationWorkItem {
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			if _, ok := c.storage.users[user.Id]; ok {
+				return fmt.Errorf("user %s already exists", user.Id)
+			}
+			c.storage.users[user.Id] = userRecord{
+				user: copyUser(user),
+			}
+			return nil
+		},
+		Rollback: func() error {
+			c.storage.mutex.Lock()
+			defer c.storage.mutex.Unlock()
+			delete(c.storage.users, user.Id)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) GetUsers(ctx context.Context, ids []users.UserId) ([]users.User, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []users.User{}
+	for _, id := range ids {
+		if record, ok := c.storage.users[id]; ok {
+			result = append(result, copyUser(record.user))
+		}
+	}
+	return result, nil
+}
+
+func (c *memoryRepository) SearchUsers(ctx context.Context, query string) ([]users.User, error) {
+	if len(query) == 0 {
+		return []users.User{}, nil
+	}
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	result := []users.User{}
+	for _, record := range c.storage.users {
+		if !record.deleted && strings.Contains(record.user.DisplayName, query) {
+			result = append(result, copyUser(record.user))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id < result[j].Id
+	})
+	return result, nil
+}
+
+func (c *memoryRepository) UpdateDisplayName(ctx context.Context, name string, id users.UserId) repositories.MutationWorkItem {
+	existed, err := c.getRecord(id)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.user.DisplayName = name
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.user.DisplayName = existed.user.DisplayName
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) UpdateAvatarId(ctx context.Context, avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
+	existed, err := c.getRecord(id)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.user.AvatarId = copyAvatarId(avatarId)
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.user.AvatarId = copyAvatarId(existed.user.AvatarId)
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) AnonymizeUser(ctx context.Context, id users.UserId) repositories.MutationWorkItem {
+	existed, err := c.getRecord(id)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.user.DisplayName = users.DeletedUserDisplayName
+				record.user.AvatarId = nil
+				record.deleted = true
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				*record = existed
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) GetLanguage(ctx context.Context, id users.UserId) (*users.Language, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	record, ok := c.storage.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyLanguage(record.language), nil
+}
+
+func (c *memoryRepository) UpdateLanguage(ctx context.Context, language users.Language, id users.UserId) repositories.MutationWorkItem {
+	existed, err := c.getRecord(id)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.language = &language
+			})
+		},
+		Rollback: func() error {
+			if err != nil {
+				return err
+			}
+			return c.update(id, func(record *userRecord) {
+				record.language = copyLanguage(existed.language)
+			})
+		},
+	})
+}
+
+func (c *memoryRepository) getRecord(id users.UserId) (userRecord, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	record, ok := c.storage.users[id]
+	if !ok {
+		return userRecord{}, errors.New("no such user exists")
+	}
+	record.user = copyUser(record.user)
+	record.language = copyLanguage(record.language)
+	return record, nil
+}
+
+func (c *memoryRepository) update(id users.UserId, modify func(record *userRecord)) error {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	record, ok := c.storage.users[id]
+	if !ok {
+		return errors.New("no such user exists")
+	}
+	modify(&record)
+	c.storage.users[id] = record
+	return nil
+}
+
+func copyAvatarId(avatarId *users.AvatarId) *users.AvatarId {
+	if avatarId == nil {
+		return nil
+	}
+	value := *avatarId
+	return &value
+}
+
+func copyLanguage(language *users.Language) *users.Language {
+	if language == nil {
+		return nil
+	}
+	value := *language
+	return &value
+}
+
+func copyUser(user users.User) users.User {
+	user.AvatarId = copyAvatarId(user.AvatarId)
+	return user
+}
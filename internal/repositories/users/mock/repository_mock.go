@@ -1,34 +1,56 @@
 package users_mock
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/users"
 )
 
 type RepositoryMock struct {
-	StoreUserImpl         func(user users.User) repositories.MutationWorkItem
-	GetUsersImpl          func(ids []users.UserId) ([]users.User, error)
-	SearchUsersImpl       func(query string) ([]users.User, error)
-	UpdateDisplayNameImpl func(name string, id users.UserId) repositories.MutationWorkItem
-	UpdateAvatarIdImpl    func(avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem
+	StoreUserImpl         func(ctx context.Context, user users.User) repositories.MutationWorkItem
+	GetUsersImpl          func(ctx context.Context, ids []users.UserId) ([]users.User, error)
+	SearchUsersImpl       func(ctx context.Context, query string) ([]users.User, error)
+	UpdateDisplayNameImpl func(ctx context.Context, name string, id users.UserId) repositories.MutationWorkItem
+	UpdateAvatarIdImpl    func(ctx context.Context, avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem
+	AnonymizeUserImpl     func(ctx context.Context, id users.UserId) repositories.MutationWorkItem
+	GetLanguageImpl       func(ctx context.Context, id users.UserId) (*users.Language, error)
+	UpdateLanguageImpl    func(ctx context.Context, language users.Language, id users.UserId) repositories.MutationWorkItem
+}
+
+func (c *RepositoryMock) StoreUser(ctx context.Context, user users.User) repositories.MutationWorkItem {
+	return c.StoreUserImpl(ctx, user)
+}
+
+func (c *RepositoryMock) GetUsers(ctx context.Context, ids []users.UserId) ([]users.User, error) {
+	return c.GetUsersImpl(ctx, ids)
+}
+
+func (c *RepositoryMock) SearchUsers(ctx context.Context, query string) ([]users.User, error) {
+	return c.SearchUsersImpl(ctx, query)
+}
+
+func (c *RepositoryMock) UpdateDisplayName(ctx context.Context, name string, id users.UserId) repositories.MutationWorkItem {
+	return c.UpdateDisplayNameImpl(ctx, name, id)
 }
 
-func (c *RepositoryMock) StoreUser(user users.User) repositories.MutationWorkItem {
-	return c.StoreUserImpl(user)
+func (c *RepositoryMock) UpdateAvatarId(ctx context.Context, avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
+	return c.UpdateAvatarIdImpl(ctx, avatarId, id)
 }
 
-func (c *RepositoryMock) GetUsers(ids []users.UserId) ([]users.User, error) {
-	return c.GetUsersImpl(ids)
+func (c *RepositoryMock) AnonymizeUser(ctx context.Context, id users.UserId) repositories.MutationWorkItem {
+	return c.AnonymizeUserImpl(ctx, id)
 }
 
-func (c *RepositoryMock) SearchUsers(query string) ([]users.User, error) {
-	return c.SearchUsersImpl(query)
+func (c *RepositoryMock) GetLanguage(ctx context.Context, id users.UserId) (*users.Language, error) {
+	return c.GetLanguageImpl(ctx, id)
 }
 
-func (c *RepositoryMock) UpdateDisplayName(name string, id users.UserId) repositories.MutationWorkItem {
-	return c.UpdateDisplayNameImpl(name, id)
+func (c *RepositoryMock) UpdateLanguage(ctx context.Context, language users.Language, id users.UserId) repositories.MutationWorkItem {
+	return c.UpdateLanguageImpl(ctx, language, id)
 }
 
-func (c *RepositoryMock) UpdateAvatarId(avatarId *users.AvatarId, id users.UserId) repositories.MutationWorkItem {
-	return c.UpdateAvatarIdImpl(avatarId, id)
+func (c *RepositoryMock) WithTx(tx db.DB) users.Repository {
+	return c
 }
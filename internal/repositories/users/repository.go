@@ -1,11 +1,17 @@
 package users
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
+const DeletedUserDisplayName = "Deleted user"
+
 type UserId string
 type AvatarId string
+type Language string
 type User struct {
 	Id          UserId
 	DisplayName string
@@ -13,9 +19,14 @@ type User struct {
 }
 
 type Repository interface {
-	StoreUser(user User) repositories.MutationWorkItem
-	GetUsers(ids []UserId) ([]User, error)
-	SearchUsers(query string) ([]User, error)
-	UpdateDisplayName(name string, id UserId) repositories.MutationWorkItem
-	UpdateAvatarId(avatarId *AvatarId, id UserId) repositories.MutationWorkItem
+	StoreUser(ctx context.Context, user User) repositories.MutationWorkItem
+	GetUsers(ctx context.Context, ids []UserId) ([]User, error)
+	SearchUsers(ctx context.Context, query string) ([]User, error)
+	UpdateDisplayName(ctx context.Context, name string, id UserId) repositories.MutationWorkItem
+	UpdateAvatarId(ctx context.Context, avatarId *AvatarId, id UserId) repositories.MutationWorkItem
+	AnonymizeUser(ctx context.Context, id UserId) repositories.MutationWorkItem
+	GetLanguage(ctx context.Context, id UserId) (*Language, error)
+	UpdateLanguage(ctx context.Context, language Language, id UserId) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
 }
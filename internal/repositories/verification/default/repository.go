@@ -1,6 +1,8 @@
 package defaultRepository
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 	"github.com/rzmn/governi/internal/repositories/verification"
@@ -19,16 +21,20 @@ type postgresRepository struct {
 	logger logging.Service
 }
 
-func (c *postgresRepository) StoreEmailVerificationCode(email string, code string) repositories.MutationWorkItem {
+func (c *postgresRepository) WithTx(tx db.DB) verification.Repository {
+	return New(tx, c.logger)
+}
+
+func (c *postgresRepository) StoreEmailVerificationCode(ctx context.Context, email string, code string) repositories.MutationWorkItem {
 	const op = "repositories.verification.postgresRepository.StoreEmailVerificationCode"
-	currentCode, err := c.GetEmailVerificationCode(email)
+	currentCode, err := c.GetEmailVerificationCode(ctx, email)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
 				c.logger.LogInfo("%s: failed to get current code err: %v", op, err)
 				return err
 			}
-			return c.storeEmailVerificationCode(email, code)
+			return c.storeEmailVerificationCode(ctx, email, code)
 		},
 		Rollback: func() error {
 			if err != nil {
@@ -36,22 +42,22 @@ func (c *postgresRepository) StoreEmailVerificationCode(email string, code strin
 				return err
 			}
 			if currentCode == nil {
-				return c.removeEmailVerificationCode(email)
+				return c.removeEmailVerificationCode(ctx, email)
 			} else {
-				return c.storeEmailVerificationCode(email, *currentCode)
+				return c.storeEmailVerificationCode(ctx, email, *currentCode)
 			}
 		},
 	}
 }
 
-func (c *postgresRepository) storeEmailVerificationCode(email string, code string) error {
+func (c *postgresRepository) storeEmailVerificationCode(ctx context.Context, email string, code string) error {
 	const op = "repositories.verification.postgresRepository.storeEmailVerificationCode"
 	c.logger.LogInfo("%s: start[email=%s]", op, email)
 	query := `
 INSERT INTO emailVerification(email, code) VALUES ($1, $2) 
 ON CONFLICT (email) DO UPDATE SET code = $2;
 `
-	_, err := c.db.Exec(query, email, code)
+	_, err := c.db.ExecContext(ctx, query, email, code)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
@@ -60,11 +66,11 @@ ON CONFLICT (email) DO UPDATE SET code = $2;
 	return nil
 }
 
-func (c *postgresRepository) GetEmailVerificationCode(email string) (*string, error) {
+func (c *postgresRepository) GetEmailVerificationCode(ctx context.Context, email string) (*string, error) {
 	const op = "repositories.verification.postgresRepository.GetEmailVerificationCode"
 	c.logger.LogInfo("%s: start[email=%s]", op, email)
 	query := `SELECT code FROM emailVerification WHERE email = $1;`
-	rows, err := c.db.Query(query, email)
+	rows, err := c.db.QueryContext(ctx, query, email)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return nil, err
@@ -91,9 +97,9 @@ func (c *postgresRepository) GetEmailVerificationCode(email string) (*string, er
 	return nil, nil
 }
 
-func (c *postgresRepository) RemoveEmailVerificationCode(email string) repositories.MutationWorkItem {
+func (c *postgresRepository) RemoveEmailVerificationCode(ctx context.Context, email string) repositories.MutationWorkItem {
 	const op = "repositories.verification.postgresRepository.RemoveEmailVerificationCode"
-	code, err := c.GetEmailVerificationCode(email)
+	code, err := c.GetEmailVerificationCode(ctx, email)
 	return repositories.MutationWorkItem{
 		Perform: func() error {
 			if err != nil {
@@ -103,7 +109,7 @@ func (c *postgresRepository) RemoveEmailVerificationCode(email string) repositor
 			if code == nil {
 				return nil
 			} else {
-				return c.removeEmailVerificationCode(email)
+				return c.removeEmailVerificationCode(ctx, email)
 			}
 		},
 		Rollback: func() error {
@@ -114,17 +120,17 @@ func (c *postgresRepository) RemoveEmailVerificationCode(email string) repositor
 			if code == nil {
 				return nil
 			} else {
-				return c.storeEmailVerificationCode(email, *code)
+				return c.storeEmailVerificationCode(ctx, email, *code)
 			}
 		},
 	}
 }
 
-func (c *postgresRepository) removeEmailVerificationCode(email string) error {
+func (c *postgresRepository) removeEmailVerificationCode(ctx context.Context, email string) error {
 	const op = "repositories.verification.postgresRepository.removeEmailVerificationCode"
 	c.logger.LogInfo("%s: start[email=%s]", op, email)
 	query := `DELETE FROM emailVerification WHERE email = $1;`
-	_, err := c.db.Exec(query, email)
+	_, err := c.db.ExecContext(ctx, query, email)
 	if err != nil {
 		c.logger.LogInfo("%s: failed to perform query err: %v", op, err)
 		return err
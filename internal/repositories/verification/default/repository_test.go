@@ -1,6 +1,7 @@
 package defaultRepository_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,7 +11,9 @@ import (
 
 	"github.com/rzmn/governi/internal/db"
 	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/repositories/verification"
 	defaultRepository "github.com/rzmn/governi/internal/repositories/verification/default"
+	memoryRepository "github.com/rzmn/governi/internal/repositories/verification/memory"
 	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
 	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
 
@@ -18,30 +21,41 @@ import (
 )
 
 var (
-	database db.DB
+	newRepository func() verification.Repository
 )
 
 func TestMain(m *testing.M) {
 	logger := standartOutputLoggingService.New()
-	pathProvider := envBasedPathProvider.New(logger)
-	database = func() db.DB {
-		configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
-		if err != nil {
-			logger.LogFatal("failed to open config file: %s", err)
+	switch os.Getenv("VERNI_TEST_STORAGE") {
+	case "postgres":
+		pathProvider := envBasedPathProvider.New(logger)
+		database := func() db.DB {
+			configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+			if err != nil {
+				logger.LogFatal("failed to open config file: %s", err)
+			}
+			defer configFile.Close()
+			configData, err := io.ReadAll(configFile)
+			if err != nil {
+				logger.LogFatal("failed to read config file: %s", err)
+			}
+			var config postgresDb.PostgresConfig
+			json.Unmarshal([]byte(configData), &config)
+			db, err := postgresDb.Postgres(config, logger)
+			if err != nil {
+				logger.LogFatal("failed to init db err: %v", err)
+			}
+			return db
+		}()
+		newRepository = func() verification.Repository {
+			return defaultRepository.New(database, logger)
 		}
-		defer configFile.Close()
-		configData, err := io.ReadAll(configFile)
-		if err != nil {
-			logger.LogFatal("failed to read config file: %s", err)
+	default:
+		repository := memoryRepository.New(logger)
+		newRepository = func() verification.Repository {
+			return repository
 		}
-		var config postgresDb.PostgresConfig
-		json.Unmarshal([]byte(configData), &config)
-		db, err := postgresDb.Postgres(config, logger)
-		if err != nil {
-			logger.LogFatal("failed to init db err: %v", err)
-		}
-		return db
-	}()
+	}
 	code := m.Run()
 
 	os.Exit(code)
@@ -56,12 +70,12 @@ func randomCode() string {
 }
 
 func TestStore(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	email := randomEmail()
 
 	// if no code was stored, should return nil
 
-	shouldBeEmpty, err := repository.GetEmailVerificationCode(email)
+	shouldBeEmpty, err := repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -72,11 +86,11 @@ func TestStore(t *testing.T) {
 	// check if store works when there is no token stored previously
 
 	codeToStoreForTheFirstTime := randomCode()
-	storeForTheFirstTimeTransaction := repository.StoreEmailVerificationCode(email, codeToStoreForTheFirstTime)
+	storeForTheFirstTimeTransaction := repository.StoreEmailVerificationCode(context.Background(), email, codeToStoreForTheFirstTime)
 	if err := storeForTheFirstTimeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeForTheFirstTimeTransaction` err: %v", err)
 	}
-	shouldBeEqualToCodeStoredAtFirstTime, err := repository.GetEmailVerificationCode(email)
+	shouldBeEqualToCodeStoredAtFirstTime, err := repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToCodeStoredAtFirstTime` err: %v", err)
 	}
@@ -90,11 +104,11 @@ func TestStore(t *testing.T) {
 	// check if store works when there is some token stored previously
 
 	codeToStoreForTheSecondTime := randomCode()
-	storeForTheSecondTimeTransaction := repository.StoreEmailVerificationCode(email, codeToStoreForTheSecondTime)
+	storeForTheSecondTimeTransaction := repository.StoreEmailVerificationCode(context.Background(), email, codeToStoreForTheSecondTime)
 	if err := storeForTheSecondTimeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeForTheSecondTimeTransaction` err: %v", err)
 	}
-	shouldBeEqualToCodeStoredAtSecondTime, err := repository.GetEmailVerificationCode(email)
+	shouldBeEqualToCodeStoredAtSecondTime, err := repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToCodeStoredAtSecondTime` err: %v", err)
 	}
@@ -110,7 +124,7 @@ func TestStore(t *testing.T) {
 	if err := storeForTheSecondTimeTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `storeForTheSecondTimeTransaction` err: %v", err)
 	}
-	shouldBeEqualToCodeStoredAtFirstTime, err = repository.GetEmailVerificationCode(email)
+	shouldBeEqualToCodeStoredAtFirstTime, err = repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `shouldBeEqualToCodeStoredAtFirstTime` err: %v", err)
 	}
@@ -126,7 +140,7 @@ func TestStore(t *testing.T) {
 	if err := storeForTheFirstTimeTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `storeForTheFirstTimeTransaction` err: %v", err)
 	}
-	shouldBeEmpty, err = repository.GetEmailVerificationCode(email)
+	shouldBeEmpty, err = repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -136,16 +150,16 @@ func TestStore(t *testing.T) {
 }
 
 func TestRemoveEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	email := randomEmail()
 
 	// check if remove works when there is no token stored previously
 
-	removeForTheFirstTime := repository.RemoveEmailVerificationCode(email)
+	removeForTheFirstTime := repository.RemoveEmailVerificationCode(context.Background(), email)
 	if err := removeForTheFirstTime.Perform(); err != nil {
 		t.Fatalf("failed to perform `removeForTheFirstTime` err: %v", err)
 	}
-	shouldBeEmpty, err := repository.GetEmailVerificationCode(email)
+	shouldBeEmpty, err := repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -158,7 +172,7 @@ func TestRemoveEmpty(t *testing.T) {
 	if err := removeForTheFirstTime.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `removeForTheFirstTime` err: %v", err)
 	}
-	shouldBeEmpty, err = repository.GetEmailVerificationCode(email)
+	shouldBeEmpty, err = repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("[after rollback] failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -168,21 +182,21 @@ func TestRemoveEmpty(t *testing.T) {
 }
 
 func TestRemoveNonEmpty(t *testing.T) {
-	repository := defaultRepository.New(database, standartOutputLoggingService.New())
+	repository := newRepository()
 	email := randomEmail()
 	codeToStore := randomCode()
-	storeTransaction := repository.StoreEmailVerificationCode(email, codeToStore)
+	storeTransaction := repository.StoreEmailVerificationCode(context.Background(), email, codeToStore)
 	if err := storeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `storeTransaction` err: %v", err)
 	}
 
 	// check if remove works when there is some token stored previously
 
-	removeTransaction := repository.RemoveEmailVerificationCode(email)
+	removeTransaction := repository.RemoveEmailVerificationCode(context.Background(), email)
 	if err := removeTransaction.Perform(); err != nil {
 		t.Fatalf("failed to perform `removeTransaction` err: %v", err)
 	}
-	shouldBeEmpty, err := repository.GetEmailVerificationCode(email)
+	shouldBeEmpty, err := repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEmpty` err: %v", err)
 	}
@@ -195,7 +209,7 @@ func TestRemoveNonEmpty(t *testing.T) {
 	if err := removeTransaction.Rollback(); err != nil {
 		t.Fatalf("failed to rollback `removeTransaction` err: %v", err)
 	}
-	shouldBeEqualToStoredCode, err := repository.GetEmailVerificationCode(email)
+	shouldBeEqualToStoredCode, err := repository.GetEmailVerificationCode(context.Background(), email)
 	if err != nil {
 		t.Fatalf("failed to get `shouldBeEqualToStoredCode` err: %v", err)
 	}
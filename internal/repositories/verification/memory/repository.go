@@ -0,0 +1,93 @@
+package memoryRepository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rzmn/governi/internal/db"
+	memoryDb "github.com/rzmn/governi/internal/db/memory"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/verification"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(logger logging.Service) verification.Repository {
+	return &memoryRepository{
+		storage: &storage{
+			codes: map[string]string{},
+		},
+		logger: logger,
+	}
+}
+
+type storage struct {
+	mutex sync.RWMutex
+	codes map[string]string
+}
+
+type memoryRepository struct {
+	storage *storage
+	tx      db.DB
+	logger  logging.Service
+}
+
+func (c *memoryRepository) WithTx(tx db.DB) verification.Repository {
+	return &memoryRepository{
+		storage: c.storage,
+		tx:      tx,
+		logger:  c.logger,
+	}
+}
+
+func (c *memoryRepository) track(item repositories.MutationWorkItem) repositories.MutationWorkItem {
+	item.Perform = memoryDb.Track(c.tx, item.Perform, item.Rollback)
+	return item
+}
+
+func (c *memoryRepository) StoreEmailVerificationCode(ctx context.Context, email string, code string) repositories.MutationWorkItem {
+	currentCode, _ := c.GetEmailVerificationCode(ctx, email)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.store(email, &code)
+			return nil
+		},
+		Rollback: func() error {
+			c.store(email, currentCode)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) GetEmailVerificationCode(ctx context.Context, email string) (*string, error) {
+	c.storage.mutex.RLock()
+	defer c.storage.mutex.RUnlock()
+	code, ok := c.storage.codes[email]
+	if !ok {
+		return nil, nil
+	}
+	return &code, nil
+}
+
+func (c *memoryRepository) RemoveEmailVerificationCode(ctx context.Context, email string) repositories.MutationWorkItem {
+	currentCode, _ := c.GetEmailVerificationCode(ctx, email)
+	return c.track(repositories.MutationWorkItem{
+		Perform: func() error {
+			c.store(email, nil)
+			return nil
+		},
+		Rollback: func() error {
+			c.store(email, currentCode)
+			return nil
+		},
+	})
+}
+
+func (c *memoryRepository) store(email string, code *string) {
+	c.storage.mutex.Lock()
+	defer c.storage.mutex.Unlock()
+	if code == nil {
+		delete(c.storage.codes, email)
+	} else {
+		c.storage.codes[email] = *code
+	}
+}
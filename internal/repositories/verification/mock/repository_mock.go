@@ -1,19 +1,29 @@
 package verification_mock
 
-import "github.com/rzmn/governi/internal/repositories"
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/repositories"
+	"github.com/rzmn/governi/internal/repositories/verification"
+)
 
 type RepositoryMock struct {
-	StoreEmailVerificationCodeImpl  func(email string, code string) repositories.MutationWorkItem
-	GetEmailVerificationCodeImpl    func(email string) (*string, error)
-	RemoveEmailVerificationCodeImpl func(email string) repositories.MutationWorkItem
+	StoreEmailVerificationCodeImpl  func(ctx context.Context, email string, code string) repositories.MutationWorkItem
+	GetEmailVerificationCodeImpl    func(ctx context.Context, email string) (*string, error)
+	RemoveEmailVerificationCodeImpl func(ctx context.Context, email string) repositories.MutationWorkItem
 }
 
-func (c *RepositoryMock) StoreEmailVerificationCode(email string, code string) repositories.MutationWorkItem {
-	return c.StoreEmailVerificationCodeImpl(email, code)
+func (c *RepositoryMock) StoreEmailVerificationCode(ctx context.Context, email string, code string) repositories.MutationWorkItem {
+	return c.StoreEmailVerificationCodeImpl(ctx, email, code)
+}
+func (c *RepositoryMock) GetEmailVerificationCode(ctx context.Context, email string) (*string, error) {
+	return c.GetEmailVerificationCodeImpl(ctx, email)
 }
-func (c *RepositoryMock) GetEmailVerificationCode(email string) (*string, error) {
-	return c.GetEmailVerificationCodeImpl(email)
+func (c *RepositoryMock) RemoveEmailVerificationCode(ctx context.Context, email string) repositories.MutationWorkItem {
+	return c.RemoveEmailVerificationCodeImpl(ctx, email)
 }
-func (c *RepositoryMock) RemoveEmailVerificationCode(email string) repositories.MutationWorkItem {
-	return c.RemoveEmailVerificationCodeImpl(email)
+
+func (c *RepositoryMock) WithTx(tx db.DB) verification.Repository {
+	return c
 }
@@ -1,11 +1,16 @@
 package verification
 
 import (
+	"context"
+
+	"github.com/rzmn/governi/internal/db"
 	"github.com/rzmn/governi/internal/repositories"
 )
 
 type Repository interface {
-	StoreEmailVerificationCode(email string, code string) repositories.MutationWorkItem
-	GetEmailVerificationCode(email string) (*string, error)
-	RemoveEmailVerificationCode(email string) repositories.MutationWorkItem
+	StoreEmailVerificationCode(ctx context.Context, email string, code string) repositories.MutationWorkItem
+	GetEmailVerificationCode(ctx context.Context, email string) (*string, error)
+	RemoveEmailVerificationCode(ctx context.Context, email string) repositories.MutationWorkItem
+
+	WithTx(tx db.DB) Repository
 }
@@ -1,6 +1,7 @@
 package defaultAccessTokenHandler
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -31,6 +32,7 @@ type defaultRequestsHandler struct {
 }
 
 func (c *defaultRequestsHandler) CheckToken(
+	ctx context.Context,
 	authorizationHeaderValue string,
 	success func(schema.StatusCode, schema.Response[schema.UserId]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
@@ -66,7 +68,7 @@ func (c *defaultRequestsHandler) CheckToken(
 		failure(http.StatusInternalServerError, schema.Failure(getSubjectError, schema.CodeInternal))
 		return
 	}
-	exists, err := c.repository.IsUserExists(authRepository.UserId(subject))
+	exists, err := c.repository.IsUserExists(ctx, authRepository.UserId(subject))
 	if err != nil {
 		c.logger.LogError("%s: valid token with invalid subject - %v", op, err)
 		failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
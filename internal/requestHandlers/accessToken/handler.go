@@ -1,11 +1,14 @@
 package accessToken
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestHandler interface {
 	CheckToken(
+		ctx context.Context,
 		authorizationHeaderValue string,
 		success func(schema.StatusCode, schema.Response[schema.UserId]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
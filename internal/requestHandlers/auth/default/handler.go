@@ -1,6 +1,7 @@
 package defaultAuthHandler
 
 import (
+	"context"
 	"net/http"
 
 	authController "github.com/rzmn/governi/internal/controllers/auth"
@@ -25,11 +26,12 @@ type defaultRequestsHandler struct {
 }
 
 func (c *defaultRequestsHandler) Signup(
+	ctx context.Context,
 	request schema.SignupRequest,
 	success func(schema.StatusCode, schema.Response[schema.Session]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	session, err := c.controller.Signup(request.Credentials.Email, request.Credentials.Password)
+	session, err := c.controller.Signup(ctx, request.Credentials.Email, request.Credentials.Password)
 	if err != nil {
 		switch err.Code {
 		case authController.SignupErrorAlreadyTaken:
@@ -46,30 +48,65 @@ func (c *defaultRequestsHandler) Signup(
 }
 
 func (c *defaultRequestsHandler) Login(
+	ctx context.Context,
+	clientIp string,
 	request schema.LoginRequest,
-	success func(schema.StatusCode, schema.Response[schema.Session]),
+	success func(schema.StatusCode, schema.Response[schema.LoginResult]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	session, err := c.controller.Login(request.Credentials.Email, request.Credentials.Password)
+	result, err := c.controller.Login(ctx, request.Credentials.Email, request.Credentials.Password, clientIp)
 	if err != nil {
 		switch err.Code {
 		case authController.LoginErrorWrongCredentials:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeIncorrectCredentials))
+		case authController.LoginErrorTooManyAttempts:
+			response := schema.Failure(err, schema.CodeTooManyAttempts)
+			response.Response.RetryAfterSec = &result.RetryAfterSec
+			failure(http.StatusTooManyRequests, response)
 		default:
 			c.logger.LogError("login request %v failed with unknown err: %v", request, err)
 			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
 		}
 		return
 	}
-	success(http.StatusOK, schema.Success(mapSession(session)))
+	success(http.StatusOK, schema.Success(mapLoginResult(result)))
+}
+
+func (c *defaultRequestsHandler) LoginWithIdentityProvider(
+	ctx context.Context,
+	request schema.LoginWithIdentityProviderRequest,
+	success func(schema.StatusCode, schema.Response[schema.LoginResult]),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	result, err := c.controller.LoginWithIdentityProvider(ctx, request.Provider, request.IdToken)
+	if err != nil {
+		switch err.Code {
+		case authController.LoginWithIdentityProviderErrorUnknownProvider:
+			failure(http.StatusUnprocessableEntity, schema.Failure(err, schema.CodeUnknownIdentityProvider))
+		case authController.LoginWithIdentityProviderErrorTokenExpired:
+			failure(http.StatusUnauthorized, schema.Failure(err, schema.CodeTokenExpired))
+		case authController.LoginWithIdentityProviderErrorTokenIsWrong:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeWrongAccessToken))
+		case authController.LoginWithIdentityProviderErrorEmailMissing:
+			failure(http.StatusUnprocessableEntity, schema.Failure(err, schema.CodeIdentityProviderEmailMissing))
+		case authController.LoginWithIdentityProviderErrorEmailAlreadyTaken:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeAlreadyTaken))
+		default:
+			c.logger.LogError("loginWithIdentityProvider request failed with unknown err: %v", err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.Success(mapLoginResult(result)))
 }
 
 func (c *defaultRequestsHandler) Refresh(
+	ctx context.Context,
 	request schema.RefreshRequest,
 	success func(schema.StatusCode, schema.Response[schema.Session]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	session, err := c.controller.Refresh(request.RefreshToken)
+	session, err := c.controller.Refresh(ctx, request.RefreshToken)
 	if err != nil {
 		switch err.Code {
 		case authController.RefreshErrorTokenExpired:
@@ -86,12 +123,13 @@ func (c *defaultRequestsHandler) Refresh(
 }
 
 func (c *defaultRequestsHandler) UpdateEmail(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.UpdateEmailRequest,
 	success func(schema.StatusCode, schema.Response[schema.Session]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	session, err := c.controller.UpdateEmail(request.Email, authController.UserId(subject))
+	session, err := c.controller.UpdateEmail(ctx, request.Email, authController.UserId(subject))
 	if err != nil {
 		switch err.Code {
 		case authController.UpdateEmailErrorAlreadyTaken:
@@ -108,12 +146,13 @@ func (c *defaultRequestsHandler) UpdateEmail(
 }
 
 func (c *defaultRequestsHandler) UpdatePassword(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.UpdatePasswordRequest,
 	success func(schema.StatusCode, schema.Response[schema.Session]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	session, err := c.controller.UpdatePassword(request.OldPassword, request.NewPassword, authController.UserId(subject))
+	session, err := c.controller.UpdatePassword(ctx, request.OldPassword, request.NewPassword, authController.UserId(subject))
 	if err != nil {
 		switch err.Code {
 		case authController.UpdatePasswordErrorOldPasswordIsWrong:
@@ -128,14 +167,26 @@ func (c *defaultRequestsHandler) UpdatePassword(
 }
 
 func (c *defaultRequestsHandler) RegisterForPushNotifications(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.RegisterForPushNotificationsRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	err := c.controller.RegisterForPushNotifications(request.Token, authController.UserId(subject))
+	// clients predating android support do not send the platform
+	platform := authController.PushPlatformIos
+	if request.Platform != nil {
+		platform = authController.PushPlatform(*request.Platform)
+	}
+	err := c.controller.RegisterForPushNotifications(ctx, authController.PushToken{
+		Token:      request.Token,
+		Platform:   platform,
+		AppVersion: request.AppVersion,
+	}, authController.UserId(subject))
 	if err != nil {
 		switch err.Code {
+		case authController.RegisterForPushNotificationsErrorUnsupportedPlatform:
+			failure(http.StatusUnprocessableEntity, schema.Failure(err, schema.CodeWrongFormat))
 		default:
 			c.logger.LogError("registerForPushNotifications request %v failed with unknown err: %v", request, err)
 			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
@@ -146,11 +197,12 @@ func (c *defaultRequestsHandler) RegisterForPushNotifications(
 }
 
 func (c *defaultRequestsHandler) Logout(
+	ctx context.Context,
 	subject schema.UserId,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	err := c.controller.Logout(authController.UserId(subject))
+	err := c.controller.Logout(ctx, authController.UserId(subject))
 	if err != nil {
 		switch err.Code {
 		default:
@@ -162,6 +214,100 @@ func (c *defaultRequestsHandler) Logout(
 	success(http.StatusOK, schema.OK())
 }
 
+func (c *defaultRequestsHandler) EnrollTwoFactor(
+	ctx context.Context,
+	subject schema.UserId,
+	success func(schema.StatusCode, schema.Response[schema.TwoFactorEnrollment]),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	enrollment, err := c.controller.EnrollTwoFactor(ctx, authController.UserId(subject))
+	if err != nil {
+		switch err.Code {
+		case authController.EnrollTwoFactorErrorAlreadyEnrolled:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeTwoFactorAlreadyEnabled))
+		default:
+			c.logger.LogError("enrollTwoFactor request failed with unknown err: %v", err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.Success(schema.TwoFactorEnrollment{
+		Secret: enrollment.Secret,
+		Uri:    enrollment.Uri,
+	}))
+}
+
+func (c *defaultRequestsHandler) ConfirmTwoFactor(
+	ctx context.Context,
+	subject schema.UserId,
+	request schema.ConfirmTwoFactorRequest,
+	success func(schema.StatusCode, schema.Response[schema.TwoFactorRecoveryCodes]),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	result, err := c.controller.ConfirmTwoFactor(ctx, request.Code, authController.UserId(subject))
+	if err != nil {
+		switch err.Code {
+		case authController.ConfirmTwoFactorErrorNotEnrolled:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeTwoFactorNotEnrolled))
+		case authController.ConfirmTwoFactorErrorAlreadyConfirmed:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeTwoFactorAlreadyEnabled))
+		case authController.ConfirmTwoFactorErrorWrongCode:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeWrongTwoFactorCode))
+		case authController.ConfirmTwoFactorErrorTooManyAttempts:
+			response := schema.Failure(err, schema.CodeTooManyAttempts)
+			response.Response.RetryAfterSec = &result.RetryAfterSec
+			failure(http.StatusTooManyRequests, response)
+		default:
+			c.logger.LogError("confirmTwoFactor request failed with unknown err: %v", err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.Success(schema.TwoFactorRecoveryCodes{
+		RecoveryCodes: result.RecoveryCodes,
+	}))
+}
+
+func (c *defaultRequestsHandler) VerifyTwoFactor(
+	ctx context.Context,
+	request schema.VerifyTwoFactorRequest,
+	success func(schema.StatusCode, schema.Response[schema.Session]),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	result, err := c.controller.VerifyTwoFactor(ctx, request.TwoFactorToken, request.Code)
+	if err != nil {
+		switch err.Code {
+		case authController.VerifyTwoFactorErrorTokenExpired:
+			failure(http.StatusUnauthorized, schema.Failure(err, schema.CodeTokenExpired))
+		case authController.VerifyTwoFactorErrorTokenIsWrong:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeWrongAccessToken))
+		case authController.VerifyTwoFactorErrorWrongCode:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeWrongTwoFactorCode))
+		case authController.VerifyTwoFactorErrorTooManyAttempts:
+			response := schema.Failure(err, schema.CodeTooManyAttempts)
+			response.Response.RetryAfterSec = &result.RetryAfterSec
+			failure(http.StatusTooManyRequests, response)
+		default:
+			c.logger.LogError("verifyTwoFactor request failed with unknown err: %v", err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.Success(mapSession(result.Session)))
+}
+
+func mapLoginResult(result authController.LoginResult) schema.LoginResult {
+	if result.Session == nil {
+		return schema.LoginResult{
+			TwoFactorToken: result.TwoFactorToken,
+		}
+	}
+	session := mapSession(*result.Session)
+	return schema.LoginResult{
+		Session: &session,
+	}
+}
+
 func mapSession(session authController.Session) schema.Session {
 	return schema.Session{
 		Id:           schema.UserId(session.Id),
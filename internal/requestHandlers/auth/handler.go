@@ -1,46 +1,81 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	Signup(
+		ctx context.Context,
 		request schema.SignupRequest,
 		success func(schema.StatusCode, schema.Response[schema.Session]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	Login(
+		ctx context.Context,
+		clientIp string,
 		request schema.LoginRequest,
-		success func(schema.StatusCode, schema.Response[schema.Session]),
+		success func(schema.StatusCode, schema.Response[schema.LoginResult]),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+	LoginWithIdentityProvider(
+		ctx context.Context,
+		request schema.LoginWithIdentityProviderRequest,
+		success func(schema.StatusCode, schema.Response[schema.LoginResult]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	Refresh(
+		ctx context.Context,
 		request schema.RefreshRequest,
 		success func(schema.StatusCode, schema.Response[schema.Session]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	UpdateEmail(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.UpdateEmailRequest,
 		success func(schema.StatusCode, schema.Response[schema.Session]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	UpdatePassword(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.UpdatePasswordRequest,
 		success func(schema.StatusCode, schema.Response[schema.Session]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	RegisterForPushNotifications(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.RegisterForPushNotificationsRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	Logout(
+		ctx context.Context,
 		subject schema.UserId,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
+	EnrollTwoFactor(
+		ctx context.Context,
+		subject schema.UserId,
+		success func(schema.StatusCode, schema.Response[schema.TwoFactorEnrollment]),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+	ConfirmTwoFactor(
+		ctx context.Context,
+		subject schema.UserId,
+		request schema.ConfirmTwoFactorRequest,
+		success func(schema.StatusCode, schema.Response[schema.TwoFactorRecoveryCodes]),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+	VerifyTwoFactor(
+		ctx context.Context,
+		request schema.VerifyTwoFactorRequest,
+		success func(schema.StatusCode, schema.Response[schema.Session]),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
 }
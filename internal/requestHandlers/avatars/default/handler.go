@@ -1,6 +1,7 @@
 package defaultAvatarsHandler
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/rzmn/governi/internal/common"
@@ -26,11 +27,12 @@ type defaultRequestsHandler struct {
 }
 
 func (c *defaultRequestsHandler) GetAvatars(
+	ctx context.Context,
 	request schema.GetAvatarsRequest,
 	success func(schema.StatusCode, schema.Response[map[schema.ImageId]schema.Image]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	info, err := c.controller.GetAvatars(common.Map(request.Ids, func(id schema.ImageId) avatarsController.AvatarId {
+	info, err := c.controller.GetAvatars(ctx, common.Map(request.Ids, func(id schema.ImageId) avatarsController.AvatarId {
 		return avatarsController.AvatarId(id)
 	}))
 	if err != nil {
@@ -1,11 +1,14 @@
 package avatars
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	GetAvatars(
+		ctx context.Context,
 		request schema.GetAvatarsRequest,
 		success func(schema.StatusCode, schema.Response[map[schema.ImageId]schema.Image]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
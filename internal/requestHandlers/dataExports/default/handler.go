@@ -0,0 +1,115 @@
+package defaultDataExportsHandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	dataExportsController "github.com/rzmn/governi/internal/controllers/dataExports"
+	"github.com/rzmn/governi/internal/requestHandlers/dataExports"
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+func New(
+	controller dataExportsController.Controller,
+	logger logging.Service,
+) dataExports.RequestsHandler {
+	return &defaultRequestsHandler{
+		controller: controller,
+		logger:     logger,
+	}
+}
+
+type defaultRequestsHandler struct {
+	controller dataExportsController.Controller
+	logger     logging.Service
+}
+
+func (c *defaultRequestsHandler) RequestExport(
+	ctx context.Context,
+	subject schema.UserId,
+	success func(schema.StatusCode, schema.Response[schema.DataExport]),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	export, err := c.controller.RequestExport(ctx, dataExportsController.UserId(subject))
+	if err != nil {
+		switch err.Code {
+		default:
+			c.logger.LogError("requestExport request failed with unknown err: %v", err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.Success(mapExport(export)))
+}
+
+func (c *defaultRequestsHandler) GetExport(
+	ctx context.Context,
+	subject schema.UserId,
+	request schema.GetDataExportRequest,
+	success func(schema.StatusCode, schema.Response[schema.DataExport]),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	export, err := c.controller.GetExport(ctx, dataExportsController.ExportId(request.Id), dataExportsController.UserId(subject))
+	if err != nil {
+		switch err.Code {
+		case dataExportsController.GetExportErrorNotFound:
+			failure(http.StatusNotFound, schema.Failure(err, schema.CodeDataExportNotFound))
+		default:
+			c.logger.LogError("getExport request %v failed with unknown err: %v", request, err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.Success(mapExport(export)))
+}
+
+func (c *defaultRequestsHandler) DownloadExport(
+	ctx context.Context,
+	subject schema.UserId,
+	request schema.GetDataExportRequest,
+	success func(schema.StatusCode, schema.DataExportArchive),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	archive, err := c.controller.DownloadExport(ctx, dataExportsController.ExportId(request.Id), dataExportsController.UserId(subject))
+	if err != nil {
+		switch err.Code {
+		case dataExportsController.DownloadExportErrorNotFound:
+			failure(http.StatusNotFound, schema.Failure(err, schema.CodeDataExportNotFound))
+		case dataExportsController.DownloadExportErrorNotReady:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeDataExportNotReady))
+		case dataExportsController.DownloadExportErrorExpired:
+			failure(http.StatusGone, schema.Failure(err, schema.CodeDataExportExpired))
+		default:
+			c.logger.LogError("downloadExport request %v failed with unknown err: %v", request, err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.DataExportArchive{
+		FileName: fmt.Sprintf("export-%s.zip", request.Id),
+		Data:     archive,
+	})
+}
+
+func mapExport(export dataExportsController.Export) schema.DataExport {
+	result := schema.DataExport{
+		Id:        schema.DataExportId(export.Id),
+		Status:    schema.DataExportStatus(export.Status),
+		CreatedAt: export.CreatedAt,
+	}
+	if export.Status == dataExportsController.StatusReady || export.Status == dataExportsController.StatusExpired {
+		result.ExpiresAt = &export.ExpiresAt
+	}
+	if export.Status == dataExportsController.StatusReady {
+		data, _ := json.Marshal(schema.GetDataExportRequest{
+			Id: result.Id,
+		})
+		downloadUrl := fmt.Sprintf("/dataExports/download?data=%s", url.QueryEscape(string(data)))
+		result.DownloadUrl = &downloadUrl
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package dataExports
+
+import (
+	"context"
+
+	"github.com/rzmn/governi/internal/schema"
+)
+
+type RequestsHandler interface {
+	RequestExport(
+		ctx context.Context,
+		subject schema.UserId,
+		success func(schema.StatusCode, schema.Response[schema.DataExport]),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+	GetExport(
+		ctx context.Context,
+		subject schema.UserId,
+		request schema.GetDataExportRequest,
+		success func(schema.StatusCode, schema.Response[schema.DataExport]),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+	DownloadExport(
+		ctx context.Context,
+		subject schema.UserId,
+		request schema.GetDataExportRequest,
+		success func(schema.StatusCode, schema.DataExportArchive),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+}
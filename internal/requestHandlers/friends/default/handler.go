@@ -1,6 +1,7 @@
 package defaultFriendsHandler
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/rzmn/governi/internal/common"
@@ -8,38 +9,31 @@ import (
 	"github.com/rzmn/governi/internal/requestHandlers/friends"
 	"github.com/rzmn/governi/internal/schema"
 	"github.com/rzmn/governi/internal/services/logging"
-	"github.com/rzmn/governi/internal/services/pushNotifications"
-	"github.com/rzmn/governi/internal/services/realtimeEvents"
 )
 
 func New(
 	controller friendsController.Controller,
-	pushService pushNotifications.Service,
-	realtimeEvents realtimeEvents.Service,
 	logger logging.Service,
 ) friends.RequestsHandler {
 	return &defaultRequestsHandler{
-		controller:     controller,
-		pushService:    pushService,
-		realtimeEvents: realtimeEvents,
-		logger:         logger,
+		controller: controller,
+		logger:     logger,
 	}
 }
 
 type defaultRequestsHandler struct {
-	controller     friendsController.Controller
-	pushService    pushNotifications.Service
-	realtimeEvents realtimeEvents.Service
-	logger         logging.Service
+	controller friendsController.Controller
+	logger     logging.Service
 }
 
 func (c *defaultRequestsHandler) AcceptRequest(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.AcceptFriendRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.AcceptFriendRequest(friendsController.UserId(request.Sender), friendsController.UserId(subject)); err != nil {
+	if err := c.controller.AcceptFriendRequest(ctx, friendsController.UserId(request.Sender), friendsController.UserId(subject)); err != nil {
 		switch err.Code {
 		case friendsController.AcceptFriendRequestErrorNoSuchRequest:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeNoSuchRequest))
@@ -53,12 +47,13 @@ func (c *defaultRequestsHandler) AcceptRequest(
 }
 
 func (c *defaultRequestsHandler) GetFriends(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.GetFriendsRequest,
 	success func(schema.StatusCode, schema.Response[map[schema.FriendStatus][]schema.UserId]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	friends, err := c.controller.GetFriends(common.Map(request.Statuses, func(status schema.FriendStatus) friendsController.FriendStatus {
+	friends, err := c.controller.GetFriends(ctx, common.Map(request.Statuses, func(status schema.FriendStatus) friendsController.FriendStatus {
 		return friendsController.FriendStatus(status)
 	}), friendsController.UserId(subject))
 	if err != nil {
@@ -79,12 +74,13 @@ func (c *defaultRequestsHandler) GetFriends(
 }
 
 func (c *defaultRequestsHandler) RejectRequest(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.RejectFriendRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.RollbackFriendRequest(friendsController.UserId(request.Sender), friendsController.UserId(subject)); err != nil {
+	if err := c.controller.RollbackFriendRequest(ctx, friendsController.UserId(request.Sender), friendsController.UserId(subject)); err != nil {
 		switch err.Code {
 		case friendsController.RollbackFriendRequestErrorNoSuchRequest:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeNoSuchRequest))
@@ -98,12 +94,13 @@ func (c *defaultRequestsHandler) RejectRequest(
 }
 
 func (c *defaultRequestsHandler) RollbackRequest(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.RollbackFriendRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.RollbackFriendRequest(friendsController.UserId(subject), friendsController.UserId(request.Target)); err != nil {
+	if err := c.controller.RollbackFriendRequest(ctx, friendsController.UserId(subject), friendsController.UserId(request.Target)); err != nil {
 		switch err.Code {
 		case friendsController.RollbackFriendRequestErrorNoSuchRequest:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeNoSuchRequest))
@@ -117,12 +114,13 @@ func (c *defaultRequestsHandler) RollbackRequest(
 }
 
 func (c *defaultRequestsHandler) SendRequest(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.SendFriendRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.SendFriendRequest(friendsController.UserId(subject), friendsController.UserId(request.Target)); err != nil {
+	if err := c.controller.SendFriendRequest(ctx, friendsController.UserId(subject), friendsController.UserId(request.Target)); err != nil {
 		switch err.Code {
 		case friendsController.SendFriendRequestErrorAlreadySent:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeAlreadySend))
@@ -140,12 +138,13 @@ func (c *defaultRequestsHandler) SendRequest(
 }
 
 func (c *defaultRequestsHandler) Unfriend(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.UnfriendRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.Unfriend(friendsController.UserId(subject), friendsController.UserId(request.Target)); err != nil {
+	if err := c.controller.Unfriend(ctx, friendsController.UserId(subject), friendsController.UserId(request.Target)); err != nil {
 		switch err.Code {
 		case friendsController.UnfriendErrorNotAFriend:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeNotAFriend))
@@ -1,41 +1,49 @@
 package friends
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	AcceptRequest(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.AcceptFriendRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	GetFriends(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.GetFriendsRequest,
 		success func(schema.StatusCode, schema.Response[map[schema.FriendStatus][]schema.UserId]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	RejectRequest(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.RejectFriendRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	RollbackRequest(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.RollbackFriendRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	SendRequest(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.SendFriendRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	Unfriend(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.UnfriendRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
@@ -1,6 +1,7 @@
 package defaultProfileHandler
 
 import (
+	"context"
 	"net/http"
 
 	profileController "github.com/rzmn/governi/internal/controllers/profile"
@@ -25,11 +26,12 @@ type defaultRequestsHandler struct {
 }
 
 func (c *defaultRequestsHandler) GetInfo(
+	ctx context.Context,
 	subject schema.UserId,
 	success func(schema.StatusCode, schema.Response[schema.Profile]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	info, err := c.controller.GetProfileInfo(profileController.UserId(subject))
+	info, err := c.controller.GetProfileInfo(ctx, profileController.UserId(subject))
 	if err != nil {
 		switch err.Code {
 		case profileController.GetInfoErrorNotFound:
@@ -44,12 +46,13 @@ func (c *defaultRequestsHandler) GetInfo(
 }
 
 func (c *defaultRequestsHandler) SetAvatar(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.SetAvatarRequest,
 	success func(schema.StatusCode, schema.Response[schema.ImageId]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	aid, err := c.controller.UpdateAvatar(request.DataBase64, profileController.UserId(subject))
+	aid, err := c.controller.UpdateAvatar(ctx, request.DataBase64, profileController.UserId(subject))
 	if err != nil {
 		switch err.Code {
 		default:
@@ -62,12 +65,13 @@ func (c *defaultRequestsHandler) SetAvatar(
 }
 
 func (c *defaultRequestsHandler) SetDisplayName(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.SetDisplayNameRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.UpdateDisplayName(request.DisplayName, profileController.UserId(subject)); err != nil {
+	if err := c.controller.UpdateDisplayName(ctx, request.DisplayName, profileController.UserId(subject)); err != nil {
 		switch err.Code {
 		case profileController.UpdateDisplayNameErrorNotFound:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeNoSuchUser))
@@ -82,6 +86,46 @@ func (c *defaultRequestsHandler) SetDisplayName(
 	success(http.StatusOK, schema.OK())
 }
 
+func (c *defaultRequestsHandler) SetLanguage(
+	ctx context.Context,
+	subject schema.UserId,
+	request schema.SetLanguageRequest,
+	success func(schema.StatusCode, schema.VoidResponse),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	if err := c.controller.UpdateLanguage(ctx, profileController.Language(request.Language), profileController.UserId(subject)); err != nil {
+		switch err.Code {
+		case profileController.UpdateLanguageErrorUnsupportedLanguage:
+			failure(http.StatusUnprocessableEntity, schema.Failure(err, schema.CodeWrongFormat))
+		default:
+			c.logger.LogError("setLanguage request %v failed with unknown err: %v", request, err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.OK())
+}
+
+func (c *defaultRequestsHandler) DeleteAccount(
+	ctx context.Context,
+	subject schema.UserId,
+	request schema.DeleteAccountRequest,
+	success func(schema.StatusCode, schema.VoidResponse),
+	failure func(schema.StatusCode, schema.Response[schema.Error]),
+) {
+	if err := c.controller.DeleteAccount(ctx, request.Password, profileController.UserId(subject)); err != nil {
+		switch err.Code {
+		case profileController.DeleteAccountErrorWrongPassword:
+			failure(http.StatusConflict, schema.Failure(err, schema.CodeIncorrectCredentials))
+		default:
+			c.logger.LogError("deleteAccount request failed with unknown err: %v", err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+		}
+		return
+	}
+	success(http.StatusOK, schema.OK())
+}
+
 func mapProfile(profile profileController.ProfileInfo) schema.Profile {
 	return schema.Profile{
 		User: schema.User{
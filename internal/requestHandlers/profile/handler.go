@@ -1,25 +1,44 @@
 package profile
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	GetInfo(
+		ctx context.Context,
 		subject schema.UserId,
 		success func(schema.StatusCode, schema.Response[schema.Profile]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	SetAvatar(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.SetAvatarRequest,
 		success func(schema.StatusCode, schema.Response[schema.ImageId]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	SetDisplayName(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.SetDisplayNameRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
+	SetLanguage(
+		ctx context.Context,
+		subject schema.UserId,
+		request schema.SetLanguageRequest,
+		success func(schema.StatusCode, schema.VoidResponse),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
+	DeleteAccount(
+		ctx context.Context,
+		subject schema.UserId,
+		request schema.DeleteAccountRequest,
+		success func(schema.StatusCode, schema.VoidResponse),
+		failure func(schema.StatusCode, schema.Response[schema.Error]),
+	)
 }
@@ -1,6 +1,7 @@
 package defaultSpendingsHandler
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/rzmn/governi/internal/common"
@@ -9,72 +10,56 @@ import (
 	"github.com/rzmn/governi/internal/requestHandlers/spendings"
 	"github.com/rzmn/governi/internal/schema"
 	"github.com/rzmn/governi/internal/services/logging"
-	"github.com/rzmn/governi/internal/services/pushNotifications"
-	"github.com/rzmn/governi/internal/services/realtimeEvents"
 )
 
 func New(
 	controller spendingsController.Controller,
-	pushService pushNotifications.Service,
-	realtimeEvents realtimeEvents.Service,
 	logger logging.Service,
 ) spendings.RequestsHandler {
 	return &defaultRequestsHandler{
-		controller:     controller,
-		pushService:    pushService,
-		realtimeEvents: realtimeEvents,
-		logger:         logger,
+		controller: controller,
+		logger:     logger,
 	}
 }
 
 type defaultRequestsHandler struct {
-	controller     spendingsController.Controller
-	pushService    pushNotifications.Service
-	realtimeEvents realtimeEvents.Service
-	logger         logging.Service
+	controller spendingsController.Controller
+	logger     logging.Service
 }
 
 func (c *defaultRequestsHandler) AddExpense(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.AddExpenseRequest,
 	success func(schema.StatusCode, schema.Response[schema.IdentifiableExpense]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	expense, err := c.controller.AddExpense(mapHttpServerExpense(request.Expense), spendingsController.CounterpartyId(subject))
+	expense, err := c.controller.AddExpense(ctx, mapHttpServerExpense(request.Expense), spendingsController.CounterpartyId(subject))
 	if err != nil {
 		switch err.Code {
 		case spendingsController.AddExpenseErrorNoSuchUser:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeNoSuchUser))
 		case spendingsController.AddExpenseErrorNotYourExpense:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeIsNotYourExpense))
+		case spendingsController.AddExpenseErrorUnbalancedShares:
+			failure(http.StatusBadRequest, schema.Failure(err, schema.CodeBadRequest))
 		default:
 			c.logger.LogError("addExpense request %v failed with unknown err: %v", request, err)
 			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
 		}
 		return
 	}
-	for _, share := range expense.Shares {
-		if share.Counterparty == spendingsRepository.CounterpartyId(subject) {
-			continue
-		}
-		c.pushService.NewExpenseReceived(
-			pushNotifications.UserId(share.Counterparty),
-			pushNotifications.Expense(mapIdentifiableExpense(expense)),
-			pushNotifications.UserId(subject),
-		)
-		c.realtimeEvents.ExpensesUpdated(realtimeEvents.UserId(share.Counterparty), realtimeEvents.UserId(subject))
-		c.realtimeEvents.CounterpartiesUpdated(realtimeEvents.UserId(share.Counterparty))
-	}
 	success(http.StatusOK, schema.Success(mapIdentifiableExpense(expense)))
 }
 
 func (c *defaultRequestsHandler) RemoveExpense(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.RemoveExpenseRequest,
 	success func(schema.StatusCode, schema.Response[schema.IdentifiableExpense]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	expense, err := c.controller.RemoveExpense(spendingsController.ExpenseId(request.ExpenseId), spendingsController.CounterpartyId(subject))
+	expense, err := c.controller.RemoveExpense(ctx, spendingsController.ExpenseId(request.ExpenseId), spendingsController.CounterpartyId(subject))
 	if err != nil {
 		switch err.Code {
 		case spendingsController.RemoveExpenseErrorExpenseNotFound:
@@ -89,27 +74,16 @@ func (c *defaultRequestsHandler) RemoveExpense(
 		}
 		return
 	}
-	for _, share := range expense.Shares {
-		if share.Counterparty == spendingsRepository.CounterpartyId(subject) {
-			continue
-		}
-		c.pushService.NewExpenseReceived(
-			pushNotifications.UserId(share.Counterparty),
-			pushNotifications.Expense(mapIdentifiableExpense(expense)),
-			pushNotifications.UserId(subject),
-		)
-		c.realtimeEvents.ExpensesUpdated(realtimeEvents.UserId(share.Counterparty), realtimeEvents.UserId(subject))
-		c.realtimeEvents.CounterpartiesUpdated(realtimeEvents.UserId(share.Counterparty))
-	}
 	success(http.StatusOK, schema.Success(mapIdentifiableExpense(expense)))
 }
 
 func (c *defaultRequestsHandler) GetBalance(
+	ctx context.Context,
 	subject schema.UserId,
 	success func(schema.StatusCode, schema.Response[[]schema.Balance]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	balance, err := c.controller.GetBalance(spendingsController.CounterpartyId(subject))
+	balance, err := c.controller.GetBalance(ctx, spendingsController.CounterpartyId(subject))
 	if err != nil {
 		switch err.Code {
 		default:
@@ -122,12 +96,13 @@ func (c *defaultRequestsHandler) GetBalance(
 }
 
 func (c *defaultRequestsHandler) GetExpenses(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.GetExpensesRequest,
 	success func(schema.StatusCode, schema.Response[[]schema.IdentifiableExpense]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	expenses, err := c.controller.GetExpensesWith(spendingsController.CounterpartyId(request.Counterparty), spendingsController.CounterpartyId(subject))
+	expenses, err := c.controller.GetExpensesWith(ctx, spendingsController.CounterpartyId(request.Counterparty), spendingsController.CounterpartyId(subject))
 	if err != nil {
 		switch err.Code {
 		default:
@@ -140,12 +115,13 @@ func (c *defaultRequestsHandler) GetExpenses(
 }
 
 func (c *defaultRequestsHandler) GetExpense(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.GetExpenseRequest,
 	success func(schema.StatusCode, schema.Response[schema.IdentifiableExpense]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	expense, err := c.controller.GetExpense(spendingsController.ExpenseId(request.Id), spendingsController.CounterpartyId(subject))
+	expense, err := c.controller.GetExpense(ctx, spendingsController.ExpenseId(request.Id), spendingsController.CounterpartyId(subject))
 	if err != nil {
 		switch err.Code {
 		case spendingsController.GetExpenseErrorExpenseNotFound:
@@ -1,34 +1,41 @@
 package spendings
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	AddExpense(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.AddExpenseRequest,
 		success func(schema.StatusCode, schema.Response[schema.IdentifiableExpense]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	RemoveExpense(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.RemoveExpenseRequest,
 		success func(schema.StatusCode, schema.Response[schema.IdentifiableExpense]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	GetBalance(
+		ctx context.Context,
 		subject schema.UserId,
 		success func(schema.StatusCode, schema.Response[[]schema.Balance]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	GetExpenses(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.GetExpensesRequest,
 		success func(schema.StatusCode, schema.Response[[]schema.IdentifiableExpense]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	GetExpense(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.GetExpenseRequest,
 		success func(schema.StatusCode, schema.Response[schema.IdentifiableExpense]),
@@ -1,6 +1,7 @@
 package defaultUsersHandler
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/rzmn/governi/internal/common"
@@ -26,12 +27,13 @@ type defaultRequestsHandler struct {
 }
 
 func (c *defaultRequestsHandler) GetUsers(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.GetUsersRequest,
 	success func(schema.StatusCode, schema.Response[[]schema.User]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	users, err := c.controller.Get(common.Map(request.Ids, func(id schema.UserId) usersController.UserId {
+	users, err := c.controller.Get(ctx, common.Map(request.Ids, func(id schema.UserId) usersController.UserId {
 		return usersController.UserId(id)
 	}), usersController.UserId(subject))
 	if err != nil {
@@ -46,12 +48,13 @@ func (c *defaultRequestsHandler) GetUsers(
 }
 
 func (c *defaultRequestsHandler) SearchUsers(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.SearchUsersRequest,
 	success func(schema.StatusCode, schema.Response[[]schema.User]),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	users, err := c.controller.Search(request.Query, usersController.UserId(subject))
+	users, err := c.controller.Search(ctx, request.Query, usersController.UserId(subject))
 	if err != nil {
 		switch err.Code {
 		default:
@@ -1,17 +1,21 @@
 package users
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	GetUsers(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.GetUsersRequest,
 		success func(schema.StatusCode, schema.Response[[]schema.User]),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	SearchUsers(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.SearchUsersRequest,
 		success func(schema.StatusCode, schema.Response[[]schema.User]),
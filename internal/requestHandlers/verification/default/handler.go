@@ -1,6 +1,7 @@
 package defaultVerificationHandler
 
 import (
+	"context"
 	"net/http"
 
 	verificationController "github.com/rzmn/governi/internal/controllers/verification"
@@ -25,12 +26,13 @@ type defaultRequestsHandler struct {
 }
 
 func (c *defaultRequestsHandler) ConfirmEmail(
+	ctx context.Context,
 	subject schema.UserId,
 	request schema.ConfirmEmailRequest,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.ConfirmEmail(verificationController.UserId(subject), request.Code); err != nil {
+	if err := c.controller.ConfirmEmail(ctx, verificationController.UserId(subject), request.Code); err != nil {
 		switch err.Code {
 		case verificationController.ConfirmEmailErrorWrongConfirmationCode:
 			failure(http.StatusConflict, schema.Failure(err, schema.CodeIncorrectCredentials))
@@ -43,11 +45,12 @@ func (c *defaultRequestsHandler) ConfirmEmail(
 }
 
 func (c *defaultRequestsHandler) SendEmailConfirmationCode(
+	ctx context.Context,
 	subject schema.UserId,
 	success func(schema.StatusCode, schema.VoidResponse),
 	failure func(schema.StatusCode, schema.Response[schema.Error]),
 ) {
-	if err := c.controller.SendConfirmationCode(verificationController.UserId(subject)); err != nil {
+	if err := c.controller.SendConfirmationCode(ctx, verificationController.UserId(subject)); err != nil {
 		switch err.Code {
 		default:
 			c.logger.LogError("sendEmailConfirmationCode request failed with unknown err: %v", err)
@@ -1,17 +1,21 @@
 package verification
 
 import (
+	"context"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
 type RequestsHandler interface {
 	ConfirmEmail(
+		ctx context.Context,
 		subject schema.UserId,
 		request schema.ConfirmEmailRequest,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
 	)
 	SendEmailConfirmationCode(
+		ctx context.Context,
 		subject schema.UserId,
 		success func(schema.StatusCode, schema.VoidResponse),
 		failure func(schema.StatusCode, schema.Response[schema.Error]),
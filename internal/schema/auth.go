@@ -8,6 +8,11 @@ type LoginRequest struct {
 	Credentials Credentials `json:"credentials"`
 }
 
+type LoginWithIdentityProviderRequest struct {
+	Provider string `json:"provider"`
+	IdToken  string `json:"idToken"`
+}
+
 type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken"`
 }
@@ -21,6 +26,24 @@ type UpdatePasswordRequest struct {
 	NewPassword string `json:"new"`
 }
 
+type PushPlatform string
+
+const (
+	PushPlatformIos     PushPlatform = "ios"
+	PushPlatformAndroid PushPlatform = "android"
+)
+
 type RegisterForPushNotificationsRequest struct {
-	Token string `json:"token"`
+	Token      string        `json:"token"`
+	Platform   *PushPlatform `json:"platform,omitempty"`
+	AppVersion *string       `json:"appVersion,omitempty"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+type VerifyTwoFactorRequest struct {
+	TwoFactorToken string `json:"twoFactorToken"`
+	Code           string `json:"code"`
 }
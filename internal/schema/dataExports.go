@@ -0,0 +1,28 @@
+package schema
+
+type DataExportId string
+type DataExportStatus int
+
+const (
+	DataExportStatusPending = iota
+	DataExportStatusReady
+	DataExportStatusFailed
+	DataExportStatusExpired
+)
+
+type DataExport struct {
+	Id          DataExportId     `json:"id"`
+	Status      DataExportStatus `json:"status"`
+	CreatedAt   int64            `json:"createdAt"`
+	ExpiresAt   *int64           `json:"expiresAt,omitempty"`
+	DownloadUrl *string          `json:"downloadUrl,omitempty"`
+}
+
+type DataExportArchive struct {
+	FileName string
+	Data     []byte
+}
+
+type GetDataExportRequest struct {
+	Id DataExportId `json:"id"`
+}
@@ -49,6 +49,20 @@ type Session struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+type LoginResult struct {
+	*Session
+	TwoFactorToken *string `json:"twoFactorToken,omitempty"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
 type Expense struct {
 	Timestamp   int64               `json:"timestamp"`
 	Details     string              `json:"details"`
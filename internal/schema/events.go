@@ -0,0 +1,62 @@
+package schema
+
+// EventVersion is increased whenever a payload of an existing event type changes incompatibly.
+const EventVersion = 1
+
+type EventType string
+
+const (
+	EventTypeExpenseAdded          EventType = "expenseAdded"
+	EventTypeExpenseRemoved        EventType = "expenseRemoved"
+	EventTypeBalanceChanged        EventType = "balanceChanged"
+	EventTypeFriendRequestReceived EventType = "friendRequestReceived"
+	EventTypeFriendStatusChanged   EventType = "friendStatusChanged"
+	EventTypeProfileChanged        EventType = "profileChanged"
+	EventTypeReset                 EventType = "reset"
+)
+
+type Event[T any] struct {
+	Type    EventType `json:"type"`
+	Version int       `json:"version"`
+	Payload T         `json:"payload"`
+}
+
+func NewEvent[T any](eventType EventType, payload T) Event[T] {
+	return Event[T]{
+		Type:    eventType,
+		Version: EventVersion,
+		Payload: payload,
+	}
+}
+
+type ExpenseAddedEvent struct {
+	Counterparty UserId              `json:"counterparty"`
+	Expense      IdentifiableExpense `json:"expense"`
+}
+
+type ExpenseRemovedEvent struct {
+	Counterparty UserId              `json:"counterparty"`
+	Expense      IdentifiableExpense `json:"expense"`
+}
+
+type BalanceChangedEvent struct {
+	Balance []Balance `json:"balance"`
+}
+
+type FriendRequestReceivedEvent struct {
+	Sender UserId `json:"sender"`
+}
+
+type FriendStatusChangedEvent struct {
+	UserId UserId       `json:"userId"`
+	Status FriendStatus `json:"status"`
+}
+
+// ResetEvent tells the client that some events might have been lost, it should refetch its state.
+type ResetEvent struct{}
+
+// ProfileChangedEvent contains only the fields that have been changed.
+type ProfileChangedEvent struct {
+	DisplayName *string  `json:"displayName,omitempty"`
+	AvatarId    *ImageId `json:"avatarId,omitempty"`
+}
@@ -7,3 +7,11 @@ type SetAvatarRequest struct {
 type SetDisplayNameRequest struct {
 	DisplayName string `json:"displayName"`
 }
+
+type SetLanguageRequest struct {
+	Language string `json:"language"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
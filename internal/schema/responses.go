@@ -6,8 +6,9 @@ import (
 
 type Code int
 type Error struct {
-	Code        Code    `json:"code"`
-	Description *string `json:"description,omitempty"`
+	Code          Code    `json:"code"`
+	Description   *string `json:"description,omitempty"`
+	RetryAfterSec *int    `json:"retryAfterSec,omitempty"`
 }
 
 func (e *Error) Error() string {
@@ -81,6 +82,18 @@ const (
 	CodeNotDelivered
 	CodeAlreadyConfirmed
 	CodeLongpollNoEvents
+	CodeTwoFactorAlreadyEnabled
+	CodeTwoFactorNotEnrolled
+	CodeWrongTwoFactorCode
+	CodeUnknownIdentityProvider
+	CodeIdentityProviderEmailMissing
+	CodeTooManyAttempts
+	CodeDataExportNotFound
+	CodeDataExportNotReady
+	CodeIdempotencyKeyReused
+	CodeIdempotencyKeyInProgress
+	CodeSubscriptionForbidden
+	CodeDataExportExpired
 )
 
 func (c Code) Message() string {
@@ -115,6 +128,30 @@ func (c Code) Message() string {
 		return "should be friends"
 	case CodeIsNotYourExpense:
 		return "not your expense"
+	case CodeTwoFactorAlreadyEnabled:
+		return "two factor authentication is already enabled"
+	case CodeTwoFactorNotEnrolled:
+		return "two factor authentication enrollment has not been started"
+	case CodeWrongTwoFactorCode:
+		return "wrong two factor code"
+	case CodeUnknownIdentityProvider:
+		return "unknown identity provider"
+	case CodeIdentityProviderEmailMissing:
+		return "identity provider did not share an email"
+	case CodeTooManyAttempts:
+		return "too many attempts"
+	case CodeDataExportNotFound:
+		return "data export not found"
+	case CodeDataExportNotReady:
+		return "data export is not ready yet"
+	case CodeIdempotencyKeyReused:
+		return "idempotency key was used for another request"
+	case CodeIdempotencyKeyInProgress:
+		return "request with this idempotency key is in progress"
+	case CodeSubscriptionForbidden:
+		return "subscription to this category is not allowed"
+	case CodeDataExportExpired:
+		return "data export has expired"
 	default:
 		return "unknown error"
 	}
@@ -0,0 +1,167 @@
+package ginServer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rzmn/governi/internal/repositories/idempotencyKeys"
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	defaultIdempotencyKeyTtl = 24 * time.Hour
+	// defaultIdempotencyKeyLease is how long a key may stay in progress before a retry is allowed to
+	// take it over, e.g. when the process handling the original request died.
+	defaultIdempotencyKeyLease = time.Minute
+)
+
+type ginResponseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (c *ginResponseRecorder) Write(data []byte) (int, error) {
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
+
+func (c *ginResponseRecorder) WriteString(data string) (int, error) {
+	c.body.WriteString(data)
+	return c.ResponseWriter.WriteString(data)
+}
+
+// ginIdempotency makes mutating requests carrying the Idempotency-Key header safe to retry: the first
+// response is stored for ttl and replayed to the subsequent requests with the same key and body.
+// Responses with 5xx and 429 statuses are not stored so the request can be retried. A key left in progress
+// for longer than lease is taken over by the next retry of the same request.
+func ginIdempotency(
+	repository idempotencyKeys.Repository,
+	ttl time.Duration,
+	lease time.Duration,
+	subject func(c *gin.Context) schema.UserId,
+	logger logging.Service,
+) gin.HandlerFunc {
+	const op = "ginServer.ginIdempotency"
+	return func(c *gin.Context) {
+		value := c.GetHeader(idempotencyKeyHeader)
+		if value == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		failure := ginFailureResponse(c)
+		if len(value) > maxIdempotencyKeyLength {
+			failure(http.StatusBadRequest, schema.Failure(fmt.Errorf("%s should not be longer than %d", idempotencyKeyHeader, maxIdempotencyKeyLength), schema.CodeBadRequest))
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			failure(http.StatusBadRequest, schema.Failure(err, schema.CodeBadRequest))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s\n%s\n%s\n", c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery)
+		hash.Write(body)
+		now := time.Now()
+		record := idempotencyKeys.Record{
+			Key: idempotencyKeys.Key{
+				Scope: fmt.Sprintf("%s %s %s", subject(c), c.Request.Method, c.Request.URL.Path),
+				Value: value,
+			},
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			CreatedAt:   now.Unix(),
+			ReservedAt:  now.Unix(),
+		}
+		ctx := c.Request.Context()
+		reserved, err := repository.ReserveKey(ctx, record).Perform()
+		if err != nil {
+			logger.LogError("%s: failed to reserve key err: %v", op, err)
+			failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+			return
+		}
+		if !reserved {
+			existing, err := repository.GetRecord(ctx, record.Key)
+			if err != nil {
+				logger.LogError("%s: failed to get record err: %v", op, err)
+				failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+				return
+			}
+			if existing != nil && existing.CreatedAt < now.Add(-ttl).Unix() {
+				if err := repository.RemoveRecord(ctx, record.Key).Perform(); err != nil {
+					logger.LogError("%s: failed to remove expired record err: %v", op, err)
+					failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+					return
+				}
+				existing = nil
+			}
+			if existing == nil {
+				reserved, err = repository.ReserveKey(ctx, record).Perform()
+				if err != nil {
+					logger.LogError("%s: failed to reserve key err: %v", op, err)
+					failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+					return
+				}
+			} else if !existing.Completed && existing.RequestHash == record.RequestHash && existing.ReservedAt < now.Add(-lease).Unix() {
+				reserved, err = repository.ReclaimKey(ctx, record, now.Add(-lease).Unix()).Perform()
+				if err != nil {
+					logger.LogError("%s: failed to reclaim key err: %v", op, err)
+					failure(http.StatusInternalServerError, schema.Failure(err, schema.CodeInternal))
+					return
+				}
+				if reserved {
+					logger.LogInfo("%s: reclaimed key abandoned since %d", op, existing.ReservedAt)
+				}
+			}
+			if !reserved {
+				switch {
+				case existing != nil && existing.RequestHash != record.RequestHash:
+					failure(http.StatusUnprocessableEntity, schema.Failure(errors.New("request differs from the one the key was used with"), schema.CodeIdempotencyKeyReused))
+				case existing == nil || !existing.Completed:
+					failure(http.StatusConflict, schema.Failure(nil, schema.CodeIdempotencyKeyInProgress))
+				default:
+					c.Header(idempotentReplayedHeader, "true")
+					c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+					c.Abort()
+				}
+				return
+			}
+		}
+		recorder := &ginResponseRecorder{
+			ResponseWriter: c.Writer,
+		}
+		c.Writer = recorder
+		ctx = context.WithoutCancel(ctx)
+		release := func() {
+			if err := repository.RemoveRecord(ctx, record.Key).Perform(); err != nil {
+				logger.LogError("%s: failed to release key err: %v", op, err)
+			}
+		}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				release()
+				panic(recovered)
+			}
+		}()
+		c.Next()
+		if recorder.Status() >= http.StatusInternalServerError || recorder.Status() == http.StatusTooManyRequests {
+			release()
+			return
+		}
+		if err := repository.CompleteRecord(ctx, record.Key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()).Perform(); err != nil {
+			logger.LogError("%s: failed to store response err: %v", op, err)
+		}
+	}
+}
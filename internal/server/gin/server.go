@@ -1,14 +1,20 @@
 package ginServer
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/rzmn/governi/internal/repositories/idempotencyKeys"
 	"github.com/rzmn/governi/internal/requestHandlers/accessToken"
 	"github.com/rzmn/governi/internal/requestHandlers/auth"
 	"github.com/rzmn/governi/internal/requestHandlers/avatars"
+	"github.com/rzmn/governi/internal/requestHandlers/dataExports"
 	"github.com/rzmn/governi/internal/requestHandlers/friends"
 	"github.com/rzmn/governi/internal/requestHandlers/profile"
 	"github.com/rzmn/governi/internal/requestHandlers/spendings"
@@ -17,8 +23,11 @@ import (
 	"github.com/rzmn/governi/internal/schema"
 	"github.com/rzmn/governi/internal/server"
 	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/metrics"
 	"github.com/rzmn/governi/internal/services/realtimeEvents"
 	ginLongpollRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/longpoll"
+	ginSseRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/sse"
+	ginWebsocketRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/websocket"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,13 +40,18 @@ type RequestHandlers struct {
 	Verification verification.RequestsHandler
 	Users        users.RequestsHandler
 	Avatars      avatars.RequestsHandler
+	DataExports  dataExports.RequestsHandler
 }
 
 type GinConfig struct {
-	TimeoutSec     int    `json:"timeoutSec"`
-	IdleTimeoutSec int    `json:"idleTimeoutSec"`
-	RunMode        string `json:"runMode"`
-	Port           string `json:"port"`
+	TimeoutSec     int      `json:"timeoutSec"`
+	IdleTimeoutSec int      `json:"idleTimeoutSec"`
+	RunMode        string   `json:"runMode"`
+	Port           string   `json:"port"`
+	TrustedProxies []string `json:"trustedProxies"`
+
+	IdempotencyKeyTtlSec   int `json:"idempotencyKeyTtlSec"`
+	IdempotencyKeyLeaseSec int `json:"idempotencyKeyLeaseSec"`
 }
 
 type ginAccessTokenChecker struct {
@@ -52,40 +66,66 @@ const (
 func New(
 	config GinConfig,
 	accessTokenChecker accessToken.RequestHandler,
-	requestHandlersBuilder func(realtimeEvents realtimeEvents.Service) RequestHandlers,
+	handlers RequestHandlers,
+	realtimeBroker realtimeEvents.Broker,
+	idempotencyKeys idempotencyKeys.Repository,
+	metrics metrics.Service,
 	logger logging.Service,
 ) server.Server {
 	server := createGinServer(
 		config,
 		accessTokenChecker,
-		requestHandlersBuilder,
+		handlers,
+		realtimeBroker,
+		idempotencyKeys,
+		metrics,
 		logger,
 	)
 	return &server
 }
 
 type ginServer struct {
-	server http.Server
-	logger logging.Service
+	server            http.Server
+	idempotencyKeys   idempotencyKeys.Repository
+	idempotencyKeyTtl time.Duration
+	logger            logging.Service
 }
 
 func (c *ginServer) ListenAndServe() {
 	c.logger.LogInfo("[info] start http server listening %s", c.server.Addr)
+	go c.removeExpiredIdempotencyKeys()
 	c.server.ListenAndServe()
 }
 
+func (c *ginServer) removeExpiredIdempotencyKeys() {
+	ticker := time.NewTicker(c.idempotencyKeyTtl)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := c.idempotencyKeys.RemoveExpiredRecords(context.Background(), time.Now().Add(-c.idempotencyKeyTtl).Unix()); err != nil {
+			c.logger.LogError("failed to remove expired idempotency keys err: %v", err)
+		}
+	}
+}
+
 func createGinServer(
 	config GinConfig,
 	accessTokenChecker accessToken.RequestHandler,
-	requestHandlersBuilder func(realtimeEvents realtimeEvents.Service) RequestHandlers,
+	handlers RequestHandlers,
+	realtimeBroker realtimeEvents.Broker,
+	idempotencyKeys idempotencyKeys.Repository,
+	metrics metrics.Service,
 	logger logging.Service,
 ) ginServer {
 	logger.LogInfo("creating gin server with config %v", config)
 	gin.SetMode(config.RunMode)
 	router := gin.New()
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		logger.LogFatal("failed to set trusted proxies err: %v", err)
+	}
 	tokenChecker := ginAccessTokenChecker{
 		handler: func(c *gin.Context) {
 			accessTokenChecker.CheckToken(
+				c.Request.Context(),
 				c.Request.Header.Get("Authorization"),
 				func(code schema.StatusCode, response schema.Response[schema.UserId]) {
 					c.Request.Header.Set(accessTokenSubjectKey, string(response.Response))
@@ -100,128 +140,180 @@ func createGinServer(
 			return schema.UserId(c.Request.Header.Get(accessTokenSubjectKey))
 		},
 	}
-	longpollService := ginLongpollRealtimeEvents.New(router, logger, tokenChecker.handler)
-	handlers := requestHandlersBuilder(longpollService)
+	realtimeSubject := func(c *gin.Context) realtimeEvents.UserId {
+		return realtimeEvents.UserId(tokenChecker.accessToken(c))
+	}
+	realtimeBroker.Subscribe(ginLongpollRealtimeEvents.New(router, logger, tokenChecker.handler, realtimeSubject))
+	realtimeBroker.Subscribe(ginWebsocketRealtimeEvents.New(router, logger, tokenChecker.handler, realtimeSubject))
+	realtimeBroker.Subscribe(ginSseRealtimeEvents.New(router, logger, tokenChecker.handler, realtimeSubject))
+	requestTimeout := ginRequestTimeout(config.TimeoutSec)
+	idempotencyKeyTtl := defaultIdempotencyKeyTtl
+	if config.IdempotencyKeyTtlSec > 0 {
+		idempotencyKeyTtl = time.Second * time.Duration(config.IdempotencyKeyTtlSec)
+	}
+	idempotencyKeyLease := defaultIdempotencyKeyLease
+	if config.IdempotencyKeyLeaseSec > 0 {
+		idempotencyKeyLease = time.Second * time.Duration(config.IdempotencyKeyLeaseSec)
+	}
+	idempotency := ginIdempotency(idempotencyKeys, idempotencyKeyTtl, idempotencyKeyLease, tokenChecker.accessToken, logger)
+	router.GET("/metrics", ginMetricsHandler(metrics))
 	{
-		auth := router.Group("/auth")
+		auth := router.Group("/auth", requestTimeout)
 		{
 			auth.PUT("/signup", ginRequestHandler(func(c *gin.Context, request schema.SignupRequest) {
-				handlers.Auth.Signup(request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
+				handlers.Auth.Signup(c.Request.Context(), request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
 			}))
 			auth.PUT("/login", ginRequestHandler(func(c *gin.Context, request schema.LoginRequest) {
-				handlers.Auth.Login(request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
+				handlers.Auth.Login(c.Request.Context(), c.ClientIP(), request, ginSuccessResponse[schema.Response[schema.LoginResult]](c), ginFailureResponse(c))
+			}))
+			auth.PUT("/loginWithIdentityProvider", ginRequestHandler(func(c *gin.Context, request schema.LoginWithIdentityProviderRequest) {
+				handlers.Auth.LoginWithIdentityProvider(c.Request.Context(), request, ginSuccessResponse[schema.Response[schema.LoginResult]](c), ginFailureResponse(c))
+			}))
+			auth.PUT("/verify2fa", ginRequestHandler(func(c *gin.Context, request schema.VerifyTwoFactorRequest) {
+				handlers.Auth.VerifyTwoFactor(c.Request.Context(), request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
 			}))
 			auth.PUT("/refresh", ginRequestHandler(func(c *gin.Context, request schema.RefreshRequest) {
-				handlers.Auth.Refresh(request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
+				handlers.Auth.Refresh(c.Request.Context(), request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
 			}))
 			auth.PUT("/updateEmail", tokenChecker.handler, ginRequestHandler(func(c *gin.Context, request schema.UpdateEmailRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Auth.UpdateEmail(subject, request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
+				handlers.Auth.UpdateEmail(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
 			}))
 			auth.PUT("/updatePassword", tokenChecker.handler, ginRequestHandler(func(c *gin.Context, request schema.UpdatePasswordRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Auth.UpdatePassword(subject, request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
+				handlers.Auth.UpdatePassword(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.Session]](c), ginFailureResponse(c))
 			}))
-			auth.DELETE("/logout", tokenChecker.handler, func(c *gin.Context) {
+			auth.DELETE("/logout", tokenChecker.handler, idempotency, func(c *gin.Context) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Auth.Logout(subject, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Auth.Logout(c.Request.Context(), subject, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			})
-			auth.PUT("/registerForPushNotifications", tokenChecker.handler, ginRequestHandler(func(c *gin.Context, request schema.RegisterForPushNotificationsRequest) {
+			auth.PUT("/registerForPushNotifications", tokenChecker.handler, idempotency, ginRequestHandler(func(c *gin.Context, request schema.RegisterForPushNotificationsRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Auth.RegisterForPushNotifications(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Auth.RegisterForPushNotifications(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+			}))
+			auth.PUT("/enroll2fa", tokenChecker.handler, func(c *gin.Context) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.Auth.EnrollTwoFactor(c.Request.Context(), subject, ginSuccessResponse[schema.Response[schema.TwoFactorEnrollment]](c), ginFailureResponse(c))
+			})
+			auth.PUT("/confirm2fa", tokenChecker.handler, ginRequestHandler(func(c *gin.Context, request schema.ConfirmTwoFactorRequest) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.Auth.ConfirmTwoFactor(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.TwoFactorRecoveryCodes]](c), ginFailureResponse(c))
 			}))
 		}
-		spendings := router.Group("/spendings", tokenChecker.handler)
+		spendings := router.Group("/spendings", requestTimeout, tokenChecker.handler, idempotency)
 		{
 			spendings.POST("/addExpense", ginRequestHandler(func(c *gin.Context, request schema.AddExpenseRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Spendings.AddExpense(subject, request, ginSuccessResponse[schema.Response[schema.IdentifiableExpense]](c), ginFailureResponse(c))
+				handlers.Spendings.AddExpense(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.IdentifiableExpense]](c), ginFailureResponse(c))
 			}))
 			spendings.POST("/removeExpense", ginRequestHandler(func(c *gin.Context, request schema.RemoveExpenseRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Spendings.RemoveExpense(subject, request, ginSuccessResponse[schema.Response[schema.IdentifiableExpense]](c), ginFailureResponse(c))
+				handlers.Spendings.RemoveExpense(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.IdentifiableExpense]](c), ginFailureResponse(c))
 			}))
 			spendings.GET("/getBalance", func(c *gin.Context) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Spendings.GetBalance(subject, ginSuccessResponse[schema.Response[[]schema.Balance]](c), ginFailureResponse(c))
+				handlers.Spendings.GetBalance(c.Request.Context(), subject, ginSuccessResponse[schema.Response[[]schema.Balance]](c), ginFailureResponse(c))
 			})
 			spendings.GET("/getExpenses", ginGetRequestHandler(func(c *gin.Context, request schema.GetExpensesRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Spendings.GetExpenses(subject, request, ginSuccessResponse[schema.Response[[]schema.IdentifiableExpense]](c), ginFailureResponse(c))
+				handlers.Spendings.GetExpenses(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[[]schema.IdentifiableExpense]](c), ginFailureResponse(c))
 			}))
 			spendings.GET("/getExpense", ginGetRequestHandler(func(c *gin.Context, request schema.GetExpenseRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Spendings.GetExpense(subject, request, ginSuccessResponse[schema.Response[schema.IdentifiableExpense]](c), ginFailureResponse(c))
+				handlers.Spendings.GetExpense(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.IdentifiableExpense]](c), ginFailureResponse(c))
 			}))
 		}
-		friends := router.Group("/friends", tokenChecker.handler)
+		friends := router.Group("/friends", requestTimeout, tokenChecker.handler, idempotency)
 		{
 			friends.POST("/acceptRequest", ginRequestHandler(func(c *gin.Context, request schema.AcceptFriendRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Friends.AcceptRequest(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Friends.AcceptRequest(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 			friends.GET("/get", ginGetRequestHandler(func(c *gin.Context, request schema.GetFriendsRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Friends.GetFriends(subject, request, ginSuccessResponse[schema.Response[map[schema.FriendStatus][]schema.UserId]](c), ginFailureResponse(c))
+				handlers.Friends.GetFriends(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[map[schema.FriendStatus][]schema.UserId]](c), ginFailureResponse(c))
 			}))
 			friends.POST("/rejectRequest", ginRequestHandler(func(c *gin.Context, request schema.RejectFriendRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Friends.RejectRequest(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Friends.RejectRequest(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 			friends.POST("/rollbackRequest", ginRequestHandler(func(c *gin.Context, request schema.RollbackFriendRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Friends.RollbackRequest(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Friends.RollbackRequest(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 			friends.POST("/sendRequest", ginRequestHandler(func(c *gin.Context, request schema.SendFriendRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Friends.SendRequest(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Friends.SendRequest(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 			friends.POST("/unfriend", ginRequestHandler(func(c *gin.Context, request schema.UnfriendRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Friends.Unfriend(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Friends.Unfriend(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 		}
-		profile := router.Group("/profile", tokenChecker.handler)
+		profile := router.Group("/profile", requestTimeout, tokenChecker.handler, idempotency)
 		{
 			profile.GET("/getInfo", func(c *gin.Context) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Profile.GetInfo(subject, ginSuccessResponse[schema.Response[schema.Profile]](c), ginFailureResponse(c))
+				handlers.Profile.GetInfo(c.Request.Context(), subject, ginSuccessResponse[schema.Response[schema.Profile]](c), ginFailureResponse(c))
 			})
 			profile.PUT("/setAvatar", ginRequestHandler(func(c *gin.Context, request schema.SetAvatarRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Profile.SetAvatar(subject, request, ginSuccessResponse[schema.Response[schema.ImageId]](c), ginFailureResponse(c))
+				handlers.Profile.SetAvatar(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.ImageId]](c), ginFailureResponse(c))
 			}))
 			profile.PUT("/setDisplayName", ginRequestHandler(func(c *gin.Context, request schema.SetDisplayNameRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Profile.SetDisplayName(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Profile.SetDisplayName(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+			}))
+			profile.PUT("/setLanguage", ginRequestHandler(func(c *gin.Context, request schema.SetLanguageRequest) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.Profile.SetLanguage(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+			}))
+			profile.DELETE("/account", ginRequestHandler(func(c *gin.Context, request schema.DeleteAccountRequest) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.Profile.DeleteAccount(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 		}
-		verification := router.Group("/verification", tokenChecker.handler)
+		verification := router.Group("/verification", requestTimeout, tokenChecker.handler, idempotency)
 		{
 			verification.PUT("/confirmEmail", ginRequestHandler(func(c *gin.Context, request schema.ConfirmEmailRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Verification.ConfirmEmail(subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Verification.ConfirmEmail(c.Request.Context(), subject, request, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			}))
 			verification.PUT("/sendEmailConfirmationCode", func(c *gin.Context) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Verification.SendEmailConfirmationCode(subject, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
+				handlers.Verification.SendEmailConfirmationCode(c.Request.Context(), subject, ginSuccessResponse[schema.VoidResponse](c), ginFailureResponse(c))
 			})
 		}
-		users := router.Group("/users", tokenChecker.handler)
+		users := router.Group("/users", requestTimeout, tokenChecker.handler)
 		{
 			users.GET("/get", ginGetRequestHandler(func(c *gin.Context, request schema.GetUsersRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Users.GetUsers(subject, request, ginSuccessResponse[schema.Response[[]schema.User]](c), ginFailureResponse(c))
+				handlers.Users.GetUsers(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[[]schema.User]](c), ginFailureResponse(c))
 			}))
 			users.GET("/search", ginGetRequestHandler(func(c *gin.Context, request schema.SearchUsersRequest) {
 				subject := schema.UserId(tokenChecker.accessToken(c))
-				handlers.Users.SearchUsers(subject, request, ginSuccessResponse[schema.Response[[]schema.User]](c), ginFailureResponse(c))
+				handlers.Users.SearchUsers(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[[]schema.User]](c), ginFailureResponse(c))
 			}))
 		}
-		avatars := router.Group("/avatars")
+		avatars := router.Group("/avatars", requestTimeout)
 		{
 			avatars.GET("/get", ginGetRequestHandler(func(c *gin.Context, request schema.GetAvatarsRequest) {
-				handlers.Avatars.GetAvatars(request, ginSuccessResponse[schema.Response[map[schema.ImageId]schema.Image]](c), ginFailureResponse(c))
+				handlers.Avatars.GetAvatars(c.Request.Context(), request, ginSuccessResponse[schema.Response[map[schema.ImageId]schema.Image]](c), ginFailureResponse(c))
+			}))
+		}
+		dataExports := router.Group("/dataExports", requestTimeout, tokenChecker.handler, idempotency)
+		{
+			dataExports.PUT("/request", func(c *gin.Context) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.DataExports.RequestExport(c.Request.Context(), subject, ginSuccessResponse[schema.Response[schema.DataExport]](c), ginFailureResponse(c))
+			})
+			dataExports.GET("/status", ginGetRequestHandler(func(c *gin.Context, request schema.GetDataExportRequest) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.DataExports.GetExport(c.Request.Context(), subject, request, ginSuccessResponse[schema.Response[schema.DataExport]](c), ginFailureResponse(c))
+			}))
+			dataExports.GET("/download", ginGetRequestHandler(func(c *gin.Context, request schema.GetDataExportRequest) {
+				subject := schema.UserId(tokenChecker.accessToken(c))
+				handlers.DataExports.DownloadExport(c.Request.Context(), subject, request, ginArchiveResponse(c), ginFailureResponse(c))
 			}))
 		}
 	}
@@ -232,7 +324,34 @@ func createGinServer(
 			ReadTimeout:  time.Second * time.Duration(config.IdleTimeoutSec),
 			WriteTimeout: time.Second * time.Duration(config.IdleTimeoutSec),
 		},
-		logger: logger,
+		idempotencyKeys:   idempotencyKeys,
+		idempotencyKeyTtl: idempotencyKeyTtl,
+		logger:            logger,
+	}
+}
+
+func ginRequestTimeout(timeoutSec int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeoutSec <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Second*time.Duration(timeoutSec))
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func ginMetricsHandler(metrics metrics.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var builder strings.Builder
+		for _, metric := range metrics.Collect() {
+			fmt.Fprintf(&builder, "# HELP %s %s\n", metric.Name, metric.Help)
+			fmt.Fprintf(&builder, "# TYPE %s %s\n", metric.Name, metric.Type)
+			fmt.Fprintf(&builder, "%s %s\n", metric.Name, strconv.FormatFloat(metric.Value, 'g', -1, 64))
+		}
+		c.Data(http.StatusOK, "text/plain; version=0.0.4", []byte(builder.String()))
 	}
 }
 
@@ -272,8 +391,18 @@ func ginSuccessResponse[R any](c *gin.Context) func(status schema.StatusCode, re
 	}
 }
 
+func ginArchiveResponse(c *gin.Context) func(status schema.StatusCode, archive schema.DataExportArchive) {
+	return func(status schema.StatusCode, archive schema.DataExportArchive) {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", archive.FileName))
+		c.Data(int(status), "application/zip", archive.Data)
+	}
+}
+
 func ginFailureResponse(c *gin.Context) func(status schema.StatusCode, response schema.Response[schema.Error]) {
 	return func(status schema.StatusCode, response schema.Response[schema.Error]) {
+		if response.Response.RetryAfterSec != nil {
+			c.Header("Retry-After", strconv.Itoa(*response.Response.RetryAfterSec))
+		}
 		c.AbortWithStatusJSON(int(status), response)
 	}
 }
@@ -1,9 +1,13 @@
 package emailSender_mock
 
+import (
+	"github.com/rzmn/governi/internal/services/emailSender"
+)
+
 type ServiceMock struct {
-	SendImpl func(subject string, email string) error
+	SendImpl func(email string, message emailSender.Message) error
 }
 
-func (c *ServiceMock) Send(subject string, email string) error {
-	return c.SendImpl(subject, email)
+func (c *ServiceMock) Send(email string, message emailSender.Message) error {
+	return c.SendImpl(email, message)
 }
@@ -1,5 +1,10 @@
 package emailSender
 
+type Message struct {
+	Subject string
+	Body    string
+}
+
 type Service interface {
-	Send(subject string, email string) error
+	Send(email string, message Message) error
 }
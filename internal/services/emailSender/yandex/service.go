@@ -1,7 +1,10 @@
 package yandexEmailSender
 
 import (
+	"bytes"
 	"fmt"
+	"mime"
+	"mime/quotedprintable"
 	"net/smtp"
 
 	"github.com/rzmn/governi/internal/services/emailSender"
@@ -36,23 +39,43 @@ type yandexService struct {
 	logger   logging.Service
 }
 
-func (c *yandexService) Send(subject string, email string) error {
+func (c *yandexService) Send(email string, message emailSender.Message) error {
 	const op = "emailSender.yandexService.Send"
 	c.logger.LogInfo("%s: start", op)
 	to := []string{
 		email,
 	}
 	auth := smtp.PlainAuth("", c.sender, c.password, c.host)
-
-	message := []byte(
-		fmt.Sprintf("From: Verni <%s>\r\n", c.sender) +
-			fmt.Sprintf("To: %s\r\n", email) + subject,
-	)
-	err := smtp.SendMail(c.host+":"+c.port, auth, c.sender, to, []byte(message))
+	data, err := encode(c.sender, email, message)
 	if err != nil {
+		c.logger.LogInfo("%s: encoding failed: %v", op, err)
+		return err
+	}
+	if err := smtp.SendMail(c.host+":"+c.port, auth, c.sender, to, data); err != nil {
 		c.logger.LogInfo("%s: send failed: %v", op, err)
 		return err
 	}
 	c.logger.LogInfo("%s: success", op)
 	return nil
 }
+
+// encode builds an utf-8 message, localized subjects and bodies are not limited to ascii
+func encode(sender string, email string, message emailSender.Message) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("From: Verni <%s>\r\n", sender))
+	buffer.WriteString(fmt.Sprintf("To: %s\r\n", email))
+	buffer.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject)))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buffer.WriteString("\r\n")
+	writer := quotedprintable.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(message.Body)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	buffer.WriteString("\r\n")
+	return buffer.Bytes(), nil
+}
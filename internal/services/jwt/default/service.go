@@ -11,10 +11,12 @@ import (
 )
 
 type DefaultConfig struct {
-	AccessTokenLifetimeHours  int    `json:"accessTokenLifetimeHours"`
-	RefreshTokenLifetimeHours int    `json:"refreshTokenLifetimeHours"`
-	RefreshTokenSecret        string `json:"refreshTokenSecret"`
-	AccessTokenSecret         string `json:"accessTokenSecret"`
+	AccessTokenLifetimeHours      int    `json:"accessTokenLifetimeHours"`
+	RefreshTokenLifetimeHours     int    `json:"refreshTokenLifetimeHours"`
+	TwoFactorTokenLifetimeMinutes int    `json:"twoFactorTokenLifetimeMinutes"`
+	RefreshTokenSecret            string `json:"refreshTokenSecret"`
+	AccessTokenSecret             string `json:"accessTokenSecret"`
+	TwoFactorTokenSecret          string `json:"twoFactorTokenSecret"`
 }
 
 func New(
@@ -23,27 +25,32 @@ func New(
 	currentTime func() time.Time,
 ) jwtService.Service {
 	return &defaultService{
-		refreshTokenLifetime: time.Hour * time.Duration(config.RefreshTokenLifetimeHours),
-		accessTokenLifetime:  time.Hour * time.Duration(config.AccessTokenLifetimeHours),
-		refreshTokenSecret:   config.RefreshTokenSecret,
-		accessTokenSecret:    config.AccessTokenSecret,
-		currentTime:          currentTime,
-		logger:               logger,
+		refreshTokenLifetime:   time.Hour * time.Duration(config.RefreshTokenLifetimeHours),
+		accessTokenLifetime:    time.Hour * time.Duration(config.AccessTokenLifetimeHours),
+		twoFactorTokenLifetime: time.Minute * time.Duration(config.TwoFactorTokenLifetimeMinutes),
+		refreshTokenSecret:     config.RefreshTokenSecret,
+		accessTokenSecret:      config.AccessTokenSecret,
+		twoFactorTokenSecret:   config.TwoFactorTokenSecret,
+		currentTime:            currentTime,
+		logger:                 logger,
 	}
 }
 
 const (
-	tokenTypeRefresh = "refresh"
-	tokenTypeAccess  = "access"
+	tokenTypeRefresh   = "refresh"
+	tokenTypeAccess    = "access"
+	tokenTypeTwoFactor = "2fa"
 )
 
 type defaultService struct {
-	refreshTokenLifetime time.Duration
-	accessTokenLifetime  time.Duration
-	refreshTokenSecret   string
-	accessTokenSecret    string
-	currentTime          func() time.Time
-	logger               logging.Service
+	refreshTokenLifetime   time.Duration
+	accessTokenLifetime    time.Duration
+	twoFactorTokenLifetime time.Duration
+	refreshTokenSecret     string
+	accessTokenSecret      string
+	twoFactorTokenSecret   string
+	currentTime            func() time.Time
+	logger                 logging.Service
 }
 
 func (c *defaultService) IssueRefreshToken(subject jwtService.Subject) (jwtService.RefreshToken, *jwtService.Error) {
@@ -86,6 +93,26 @@ func (c *defaultService) IssueAccessToken(subject jwtService.Subject) (jwtServic
 	return jwtService.AccessToken(rawToken), nil
 }
 
+func (c *defaultService) IssueTwoFactorToken(subject jwtService.Subject) (jwtService.TwoFactorToken, *jwtService.Error) {
+	const op = "jwt.defaultService.IssueTwoFactorToken"
+	currentTime := c.currentTime()
+	rawToken, err := generateToken(jwtClaims{
+		TokenType: tokenTypeTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   string(subject),
+			ExpiresAt: jwt.NewNumericDate(currentTime.Add(c.twoFactorTokenLifetime)),
+			IssuedAt:  jwt.NewNumericDate(currentTime),
+		},
+	}, []byte(c.twoFactorTokenSecret))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot generate token %v", op, err)
+		return jwtService.TwoFactorToken(rawToken), &jwtService.Error{
+			Code: jwtService.CodeInternal,
+		}
+	}
+	return jwtService.TwoFactorToken(rawToken), nil
+}
+
 func (c *defaultService) ValidateRefreshToken(token jwtService.RefreshToken) *jwtService.Error {
 	const op = "jwt.defaultService.ValidateRefreshToken"
 	rawToken, err := parseToken(string(token), []byte(c.refreshTokenSecret))
@@ -148,6 +175,37 @@ func (c *defaultService) ValidateAccessToken(token jwtService.AccessToken) *jwtS
 	return nil
 }
 
+func (c *defaultService) ValidateTwoFactorToken(token jwtService.TwoFactorToken) *jwtService.Error {
+	const op = "jwt.defaultService.ValidateTwoFactorToken"
+	rawToken, err := parseToken(string(token), []byte(c.twoFactorTokenSecret))
+	expired := errors.Is(err, jwt.ErrTokenExpired)
+	if rawToken == nil || (err != nil && !expired) {
+		c.logger.LogInfo("%s: bad jwt token %v", op, err)
+		return &jwtService.Error{
+			Code: jwtService.CodeTokenInvalid,
+		}
+	}
+	claims, ok := rawToken.Claims.(*jwtClaims)
+	if !ok {
+		c.logger.LogInfo("%s: bad jwt token claims", op)
+		return &jwtService.Error{
+			Code: jwtService.CodeTokenInvalid,
+		}
+	}
+	if claims.TokenType != tokenTypeTwoFactor || claims.ExpiresAt == nil {
+		c.logger.LogInfo("%s: bad token claims %s", op, claims)
+		return &jwtService.Error{
+			Code: jwtService.CodeTokenInvalid,
+		}
+	}
+	if expired {
+		return &jwtService.Error{
+			Code: jwtService.CodeTokenExpired,
+		}
+	}
+	return nil
+}
+
 func (c *defaultService) GetRefreshTokenSubject(token jwtService.RefreshToken) (jwtService.Subject, *jwtService.Error) {
 	const op = "jwt.defaultService.GetRefreshTokenSubject"
 	rawToken, err := parseToken(string(token), []byte(c.refreshTokenSecret))
@@ -200,6 +258,32 @@ func (c *defaultService) GetAccessTokenSubject(token jwtService.AccessToken) (jw
 	return jwtService.Subject(claims.Subject), nil
 }
 
+func (c *defaultService) GetTwoFactorTokenSubject(token jwtService.TwoFactorToken) (jwtService.Subject, *jwtService.Error) {
+	const op = "jwt.defaultService.GetTwoFactorTokenSubject"
+	rawToken, err := parseToken(string(token), []byte(c.twoFactorTokenSecret))
+	if rawToken == nil || err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			c.logger.LogInfo("%s: jwt token expired %v", op, err)
+			return "", &jwtService.Error{
+				Code: jwtService.CodeTokenExpired,
+			}
+		} else {
+			c.logger.LogInfo("%s: bad jwt token %v", op, err)
+			return "", &jwtService.Error{
+				Code: jwtService.CodeTokenInvalid,
+			}
+		}
+	}
+	claims, ok := rawToken.Claims.(*jwtClaims)
+	if !ok || claims.TokenType != tokenTypeTwoFactor {
+		c.logger.LogInfo("%s: bad jwt token claims", op)
+		return "", &jwtService.Error{
+			Code: jwtService.CodeTokenInvalid,
+		}
+	}
+	return jwtService.Subject(claims.Subject), nil
+}
+
 type jwtClaims struct {
 	TokenType string `json:"tokenType"`
 	jwt.RegisteredClaims
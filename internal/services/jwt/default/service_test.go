@@ -13,10 +13,12 @@ import (
 
 func createConfig() defaultJwtService.DefaultConfig {
 	return defaultJwtService.DefaultConfig{
-		RefreshTokenLifetimeHours: 24 * 30,
-		AccessTokenLifetimeHours:  1,
-		RefreshTokenSecret:        "RefreshTokenSecret",
-		AccessTokenSecret:         "AccessTokenSecret",
+		RefreshTokenLifetimeHours:     24 * 30,
+		AccessTokenLifetimeHours:      1,
+		TwoFactorTokenLifetimeMinutes: 5,
+		RefreshTokenSecret:            "RefreshTokenSecret",
+		AccessTokenSecret:             "AccessTokenSecret",
+		TwoFactorTokenSecret:          "TwoFactorTokenSecret",
 	}
 }
 
@@ -261,3 +263,91 @@ func TestAccessTokenValidOnTheLastMinute(t *testing.T) {
 		t.Fatalf("ValidateRefreshToken err: %v", err)
 	}
 }
+
+func TestIssuedTwoFactorTokenIsValid(t *testing.T) {
+	service := defaultJwtService.New(
+		createConfig(),
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return time.Now()
+		},
+	)
+	subject := jwt.Subject(uuid.New().String())
+	token, err := service.IssueTwoFactorToken(subject)
+	if err != nil {
+		t.Fatalf("IssueTwoFactorToken err: %v", err)
+	}
+	if err := service.ValidateTwoFactorToken(token); err != nil {
+		t.Fatalf("ValidateTwoFactorToken err: %v", err)
+	}
+	subjectFromToken, err := service.GetTwoFactorTokenSubject(token)
+	if err != nil {
+		t.Fatalf("GetTwoFactorTokenSubject err: %v", err)
+	}
+	if subject != subjectFromToken {
+		t.Fatalf("subjects did not match %s != %s", subject, subjectFromToken)
+	}
+}
+
+func TestIssuedTwoFactorTokenIsNotAnAccessToken(t *testing.T) {
+	service := defaultJwtService.New(
+		createConfig(),
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return time.Now()
+		},
+	)
+	subject := jwt.Subject(uuid.New().String())
+	token, err := service.IssueTwoFactorToken(subject)
+	if err != nil {
+		t.Fatalf("IssueTwoFactorToken err: %v", err)
+	}
+	validateAccessTokenError := service.ValidateAccessToken(jwt.AccessToken(token))
+	if validateAccessTokenError == nil {
+		t.Fatalf("two factor token is recognized as a valid access token")
+	} else if validateAccessTokenError.Code != jwt.CodeTokenInvalid {
+		t.Fatalf("ValidateAccessToken unexpected err: %v", validateAccessTokenError)
+	}
+}
+
+func TestIssuedAccessTokenIsNotATwoFactorToken(t *testing.T) {
+	service := defaultJwtService.New(
+		createConfig(),
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return time.Now()
+		},
+	)
+	subject := jwt.Subject(uuid.New().String())
+	token, err := service.IssueAccessToken(subject)
+	if err != nil {
+		t.Fatalf("IssueAccessToken err: %v", err)
+	}
+	validateTwoFactorTokenError := service.ValidateTwoFactorToken(jwt.TwoFactorToken(token))
+	if validateTwoFactorTokenError == nil {
+		t.Fatalf("access token is recognized as a valid two factor token")
+	} else if validateTwoFactorTokenError.Code != jwt.CodeTokenInvalid {
+		t.Fatalf("ValidateTwoFactorToken unexpected err: %v", validateTwoFactorTokenError)
+	}
+}
+
+func TestExpiredTwoFactorToken(t *testing.T) {
+	service := defaultJwtService.New(
+		createConfig(),
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return time.Now().Add(-(time.Minute*time.Duration(createConfig().TwoFactorTokenLifetimeMinutes) + time.Minute))
+		},
+	)
+	subject := jwt.Subject(uuid.New().String())
+	token, err := service.IssueTwoFactorToken(subject)
+	if err != nil {
+		t.Fatalf("IssueTwoFactorToken err: %v", err)
+	}
+	err = service.ValidateTwoFactorToken(token)
+	if err == nil {
+		t.Fatalf("outdated token should not be valid")
+	} else if err.Code != jwt.CodeTokenExpired {
+		t.Fatalf("outdated token unexpected validation err %v", err)
+	}
+}
@@ -5,12 +5,15 @@ import (
 )
 
 type ServiceMock struct {
-	IssueRefreshTokenImpl      func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error)
-	IssueAccessTokenImpl       func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error)
-	ValidateRefreshTokenImpl   func(token jwt.RefreshToken) *jwt.Error
-	ValidateAccessTokenImpl    func(token jwt.AccessToken) *jwt.Error
-	GetRefreshTokenSubjectImpl func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error)
-	GetAccessTokenSubjectImpl  func(token jwt.AccessToken) (jwt.Subject, *jwt.Error)
+	IssueRefreshTokenImpl        func(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error)
+	IssueAccessTokenImpl         func(subject jwt.Subject) (jwt.AccessToken, *jwt.Error)
+	ValidateRefreshTokenImpl     func(token jwt.RefreshToken) *jwt.Error
+	ValidateAccessTokenImpl      func(token jwt.AccessToken) *jwt.Error
+	GetRefreshTokenSubjectImpl   func(token jwt.RefreshToken) (jwt.Subject, *jwt.Error)
+	GetAccessTokenSubjectImpl    func(token jwt.AccessToken) (jwt.Subject, *jwt.Error)
+	IssueTwoFactorTokenImpl      func(subject jwt.Subject) (jwt.TwoFactorToken, *jwt.Error)
+	ValidateTwoFactorTokenImpl   func(token jwt.TwoFactorToken) *jwt.Error
+	GetTwoFactorTokenSubjectImpl func(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error)
 }
 
 func (c *ServiceMock) IssueRefreshToken(subject jwt.Subject) (jwt.RefreshToken, *jwt.Error) {
@@ -36,3 +39,15 @@ func (c *ServiceMock) GetRefreshTokenSubject(token jwt.RefreshToken) (jwt.Subjec
 func (c *ServiceMock) GetAccessTokenSubject(token jwt.AccessToken) (jwt.Subject, *jwt.Error) {
 	return c.GetAccessTokenSubjectImpl(token)
 }
+
+func (c *ServiceMock) IssueTwoFactorToken(subject jwt.Subject) (jwt.TwoFactorToken, *jwt.Error) {
+	return c.IssueTwoFactorTokenImpl(subject)
+}
+
+func (c *ServiceMock) ValidateTwoFactorToken(token jwt.TwoFactorToken) *jwt.Error {
+	return c.ValidateTwoFactorTokenImpl(token)
+}
+
+func (c *ServiceMock) GetTwoFactorTokenSubject(token jwt.TwoFactorToken) (jwt.Subject, *jwt.Error) {
+	return c.GetTwoFactorTokenSubjectImpl(token)
+}
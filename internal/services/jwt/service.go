@@ -3,14 +3,18 @@ package jwt
 type Subject string
 type AccessToken string
 type RefreshToken string
+type TwoFactorToken string
 
 type Service interface {
 	IssueRefreshToken(subject Subject) (RefreshToken, *Error)
 	IssueAccessToken(subject Subject) (AccessToken, *Error)
+	IssueTwoFactorToken(subject Subject) (TwoFactorToken, *Error)
 
 	ValidateRefreshToken(token RefreshToken) *Error
 	ValidateAccessToken(token AccessToken) *Error
+	ValidateTwoFactorToken(token TwoFactorToken) *Error
 
 	GetRefreshTokenSubject(token RefreshToken) (Subject, *Error)
 	GetAccessTokenSubject(token AccessToken) (Subject, *Error)
+	GetTwoFactorTokenSubject(token TwoFactorToken) (Subject, *Error)
 }
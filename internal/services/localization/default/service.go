@@ -0,0 +1,111 @@
+package defaultLocalization
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/rzmn/governi/internal/services/localization"
+	"github.com/rzmn/governi/internal/services/logging"
+)
+
+//go:embed catalogs/*.json
+var embedded embed.FS
+
+func New(logger logging.Service) (localization.Service, error) {
+	source, err := fs.Sub(embedded, "catalogs")
+	if err != nil {
+		return &defaultService{}, err
+	}
+	return Load(source, logger)
+}
+
+// Load reads `<language>.json` catalogs mapping keys to templates.
+func Load(source fs.FS, logger logging.Service) (localization.Service, error) {
+	const op = "localization.defaultService.Load"
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		logger.LogInfo("%s: failed to list catalogs: %v", op, err)
+		return &defaultService{}, err
+	}
+	catalogs := map[localization.Language]map[localization.Key]string{}
+	for _, entry := range entries {
+		language, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		data, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			logger.LogInfo("%s: failed to read catalog %s: %v", op, entry.Name(), err)
+			return &defaultService{}, err
+		}
+		var catalog map[localization.Key]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			logger.LogInfo("%s: failed to parse catalog %s: %v", op, entry.Name(), err)
+			return &defaultService{}, fmt.Errorf("parsing catalog %s: %w", entry.Name(), err)
+		}
+		catalogs[normalize(localization.Language(language))] = catalog
+	}
+	if _, ok := catalogs[localization.DefaultLanguage]; !ok {
+		return &defaultService{}, fmt.Errorf("catalog for default language %s is missing", localization.DefaultLanguage)
+	}
+	return &defaultService{
+		catalogs: catalogs,
+		logger:   logger,
+	}, nil
+}
+
+type defaultService struct {
+	catalogs map[localization.Language]map[localization.Key]string
+	logger   logging.Service
+}
+
+func (c *defaultService) Languages() []localization.Language {
+	result := []localization.Language{}
+	for language := range c.catalogs {
+		result = append(result, language)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+func (c *defaultService) Supports(language localization.Language) bool {
+	language = normalize(language)
+	if _, ok := c.catalogs[language]; ok {
+		return true
+	}
+	_, ok := c.catalogs[base(language)]
+	return ok
+}
+
+func (c *defaultService) Localize(language localization.Language, key localization.Key, arguments map[string]string) string {
+	const op = "localization.defaultService.Localize"
+	language = normalize(language)
+	for _, candidate := range []localization.Language{language, base(language), localization.DefaultLanguage} {
+		template, ok := c.catalogs[candidate][key]
+		if !ok {
+			continue
+		}
+		replacements := []string{}
+		for name, value := range arguments {
+			replacements = append(replacements, "{"+name+"}", value)
+		}
+		return strings.NewReplacer(replacements...).Replace(template)
+	}
+	c.logger.LogError("%s: no translation found for %s", op, key)
+	return string(key)
+}
+
+func normalize(language localization.Language) localization.Language {
+	return localization.Language(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(string(language))), "_", "-"))
+}
+
+func base(language localization.Language) localization.Language {
+	result, _, _ := strings.Cut(string(language), "-")
+	return localization.Language(result)
+}
@@ -0,0 +1,90 @@
+package defaultLocalization_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rzmn/governi/internal/services/localization"
+	defaultLocalization "github.com/rzmn/governi/internal/services/localization/default"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	service, err := defaultLocalization.New(standartOutputLoggingService.New())
+	if err != nil {
+		t.Fatalf("failed to load catalogs err: %v", err)
+	}
+	languages := service.Languages()
+	if len(languages) < 2 {
+		t.Fatalf("expected several languages, found %v", languages)
+	}
+	for _, language := range languages {
+		for _, key := range localization.Keys {
+			if translated := service.Localize(language, key, nil); translated == string(key) || strings.TrimSpace(translated) == "" {
+				t.Fatalf("catalog %s has no translation for %s", language, key)
+			}
+		}
+	}
+}
+
+func createService(t *testing.T) localization.Service {
+	service, err := defaultLocalization.Load(fstest.MapFS{
+		"en.json":    {Data: []byte(`{"greeting": "Hello, {name}!", "farewell": "Bye"}`)},
+		"pt.json":    {Data: []byte(`{"greeting": "Olá, {name}!"}`)},
+		"pt-BR.json": {Data: []byte(`{"farewell": "Tchau"}`)},
+	}, standartOutputLoggingService.New())
+	if err != nil {
+		t.Fatalf("failed to load catalogs err: %v", err)
+	}
+	return service
+}
+
+func TestLocalizeSubstitutesArguments(t *testing.T) {
+	service := createService(t)
+	if translated := service.Localize("en", "greeting", map[string]string{"name": "Alice"}); translated != "Hello, Alice!" {
+		t.Fatalf("unexpected translation %s", translated)
+	}
+}
+
+func TestLocalizeFallsBack(t *testing.T) {
+	service := createService(t)
+	for _, testCase := range []struct {
+		language localization.Language
+		key      localization.Key
+		expected string
+	}{
+		{language: "pt_BR", key: "farewell", expected: "Tchau"},
+		{language: "pt-BR", key: "greeting", expected: "Olá, {name}!"},
+		{language: "pt", key: "farewell", expected: "Bye"},
+		{language: "de", key: "greeting", expected: "Hello, {name}!"},
+		{language: "", key: "farewell", expected: "Bye"},
+	} {
+		if translated := service.Localize(testCase.language, testCase.key, nil); translated != testCase.expected {
+			t.Fatalf("%s for %s should be %s, found %s", testCase.key, testCase.language, testCase.expected, translated)
+		}
+	}
+}
+
+func TestSupports(t *testing.T) {
+	service := createService(t)
+	for language, expected := range map[localization.Language]bool{
+		"en":    true,
+		"EN-us": true,
+		"pt-BR": true,
+		"de":    false,
+		"":      false,
+	} {
+		if service.Supports(language) != expected {
+			t.Fatalf("`Supports(%s)` should be %t", language, expected)
+		}
+	}
+}
+
+func TestLoadWithoutDefaultLanguage(t *testing.T) {
+	if _, err := defaultLocalization.Load(fstest.MapFS{
+		"ru.json": {Data: []byte(`{}`)},
+	}, standartOutputLoggingService.New()); err == nil {
+		t.Fatalf("catalogs without default language should be failed")
+	}
+}
@@ -0,0 +1,23 @@
+package localization_mock
+
+import (
+	"github.com/rzmn/governi/internal/services/localization"
+)
+
+type ServiceMock struct {
+	LanguagesImpl func() []localization.Language
+	SupportsImpl  func(language localization.Language) bool
+	LocalizeImpl  func(language localization.Language, key localization.Key, arguments map[string]string) string
+}
+
+func (c *ServiceMock) Languages() []localization.Language {
+	return c.LanguagesImpl()
+}
+
+func (c *ServiceMock) Supports(language localization.Language) bool {
+	return c.SupportsImpl(language)
+}
+
+func (c *ServiceMock) Localize(language localization.Language, key localization.Key, arguments map[string]string) string {
+	return c.LocalizeImpl(language, key, arguments)
+}
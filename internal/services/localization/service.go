@@ -0,0 +1,37 @@
+package localization
+
+type Language string
+type Key string
+
+const DefaultLanguage Language = "en"
+
+const (
+	KeyFriendRequestHasBeenAcceptedTitle Key = "push.friendRequestHasBeenAccepted.title"
+	KeyFriendRequestHasBeenAcceptedBody  Key = "push.friendRequestHasBeenAccepted.body"
+	KeyGotFriendRequestTitle             Key = "push.gotFriendRequest.title"
+	KeyGotFriendRequestBody              Key = "push.gotFriendRequest.body"
+	KeyNewExpenseReceivedTitle           Key = "push.newExpenseReceived.title"
+	KeyNewExpenseReceivedBody            Key = "push.newExpenseReceived.body"
+	KeyEmailVerificationSubject          Key = "email.verification.subject"
+	KeyEmailVerificationBody             Key = "email.verification.body"
+)
+
+// Keys lists every key each catalog is expected to translate.
+var Keys = []Key{
+	KeyFriendRequestHasBeenAcceptedTitle,
+	KeyFriendRequestHasBeenAcceptedBody,
+	KeyGotFriendRequestTitle,
+	KeyGotFriendRequestBody,
+	KeyNewExpenseReceivedTitle,
+	KeyNewExpenseReceivedBody,
+	KeyEmailVerificationSubject,
+	KeyEmailVerificationBody,
+}
+
+type Service interface {
+	Languages() []Language
+	Supports(language Language) bool
+	// Localize substitutes `{name}` placeholders with arguments, falling back to the base language
+	// (`pt` for `pt-BR`), then to the default language and then to the key itself.
+	Localize(language Language, key Key, arguments map[string]string) string
+}
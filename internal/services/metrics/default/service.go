@@ -0,0 +1,37 @@
+package defaultMetricsService
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rzmn/governi/internal/services/metrics"
+)
+
+func New() metrics.Service {
+	return &defaultService{}
+}
+
+type defaultService struct {
+	mutex      sync.Mutex
+	collectors []metrics.Collector
+}
+
+func (c *defaultService) Register(collector metrics.Collector) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.collectors = append(c.collectors, collector)
+}
+
+func (c *defaultService) Collect() []metrics.Metric {
+	c.mutex.Lock()
+	collectors := append([]metrics.Collector{}, c.collectors...)
+	c.mutex.Unlock()
+	result := []metrics.Metric{}
+	for _, collector := range collectors {
+		result = append(result, collector()...)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,18 @@
+package metrics_mock
+
+import (
+	"github.com/rzmn/governi/internal/services/metrics"
+)
+
+type ServiceMock struct {
+	RegisterImpl func(collector metrics.Collector)
+	CollectImpl  func() []metrics.Metric
+}
+
+func (c *ServiceMock) Register(collector metrics.Collector) {
+	c.RegisterImpl(collector)
+}
+
+func (c *ServiceMock) Collect() []metrics.Metric {
+	return c.CollectImpl()
+}
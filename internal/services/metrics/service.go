@@ -0,0 +1,22 @@
+package metrics
+
+type Type string
+
+const (
+	TypeGauge   Type = "gauge"
+	TypeCounter Type = "counter"
+)
+
+type Metric struct {
+	Name  string
+	Help  string
+	Type  Type
+	Value float64
+}
+
+type Collector func() []Metric
+
+type Service interface {
+	Register(collector Collector)
+	Collect() []Metric
+}
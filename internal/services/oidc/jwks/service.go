@@ -0,0 +1,296 @@
+package jwksOidcService
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type ProviderConfig struct {
+	Issuer    string   `json:"issuer"`
+	JwksUri   string   `json:"jwksUri"`
+	Audiences []string `json:"audiences"`
+}
+
+type JwksConfig struct {
+	Providers                map[string]ProviderConfig `json:"providers"`
+	KeysCacheLifetimeMinutes int                       `json:"keysCacheLifetimeMinutes"`
+}
+
+func New(
+	config JwksConfig,
+	httpClient *http.Client,
+	logger logging.Service,
+	currentTime func() time.Time,
+) oidc.Service {
+	return &jwksService{
+		providers:          config.Providers,
+		keysCacheLifetime:  time.Minute * time.Duration(config.KeysCacheLifetimeMinutes),
+		httpClient:         httpClient,
+		currentTime:        currentTime,
+		logger:             logger,
+		keySets:            map[string]keySet{},
+		keySetFetchesMutex: sync.Mutex{},
+	}
+}
+
+const (
+	minKeysRefetchInterval = time.Minute
+)
+
+type keySet struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type jwksService struct {
+	providers          map[string]ProviderConfig
+	keysCacheLifetime  time.Duration
+	httpClient         *http.Client
+	currentTime        func() time.Time
+	logger             logging.Service
+	keySets            map[string]keySet
+	keySetFetchesMutex sync.Mutex
+}
+
+type idTokenClaims struct {
+	Email         *string      `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	jwt.RegisteredClaims
+}
+
+// some providers (e.g. Apple) encode boolean claims as strings
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = flexibleBool(value)
+		return nil
+	}
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return err
+	}
+	*b = flexibleBool(value)
+	return nil
+}
+
+func (c *jwksService) Verify(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+	const op = "oidc.jwksService.Verify"
+	config, ok := c.providers[string(provider)]
+	if !ok {
+		c.logger.LogInfo("%s: unknown provider %s", op, provider)
+		return oidc.Identity{}, &oidc.Error{
+			Code: oidc.CodeUnknownProvider,
+		}
+	}
+	var internalErr error
+	parsed, err := jwt.ParseWithClaims(
+		string(token),
+		&idTokenClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			kid, ok := token.Header["kid"].(string)
+			if !ok {
+				return nil, errors.New("token has no key id")
+			}
+			key, err := c.key(string(provider), config, kid)
+			if err != nil {
+				internalErr = err
+				return nil, err
+			}
+			if key == nil {
+				return nil, fmt.Errorf("unknown key id %s", kid)
+			}
+			return key, nil
+		},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(c.currentTime),
+	)
+	if internalErr != nil {
+		c.logger.LogInfo("%s: failed to get provider keys err: %v", op, internalErr)
+		description := internalErr.Error()
+		return oidc.Identity{}, &oidc.Error{
+			Code:        oidc.CodeInternal,
+			Description: &description,
+		}
+	}
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		c.logger.LogInfo("%s: token expired", op)
+		return oidc.Identity{}, &oidc.Error{
+			Code: oidc.CodeTokenExpired,
+		}
+	}
+	if err != nil || parsed == nil {
+		c.logger.LogInfo("%s: bad id token %v", op, err)
+		return oidc.Identity{}, &oidc.Error{
+			Code: oidc.CodeTokenInvalid,
+		}
+	}
+	claims, ok := parsed.Claims.(*idTokenClaims)
+	if !ok || claims.Subject == "" {
+		c.logger.LogInfo("%s: bad id token claims", op)
+		return oidc.Identity{}, &oidc.Error{
+			Code: oidc.CodeTokenInvalid,
+		}
+	}
+	if !hasAudience(claims.Audience, config.Audiences) {
+		c.logger.LogInfo("%s: unexpected audience %v", op, claims.Audience)
+		return oidc.Identity{}, &oidc.Error{
+			Code: oidc.CodeTokenInvalid,
+		}
+	}
+	return oidc.Identity{
+		Issuer:        config.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+	}, nil
+}
+
+func hasAudience(audience jwt.ClaimStrings, allowed []string) bool {
+	for i := range audience {
+		for j := range allowed {
+			if audience[i] == allowed[j] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *jwksService) key(provider string, config ProviderConfig, kid string) (crypto.PublicKey, error) {
+	c.keySetFetchesMutex.Lock()
+	defer c.keySetFetchesMutex.Unlock()
+	currentTime := c.currentTime()
+	cached, ok := c.keySets[provider]
+	if ok {
+		if key, found := cached.keys[kid]; found && currentTime.Sub(cached.fetchedAt) < c.keysCacheLifetime {
+			return key, nil
+		}
+		if currentTime.Sub(cached.fetchedAt) < minKeysRefetchInterval {
+			return cached.keys[kid], nil
+		}
+	}
+	keys, err := c.fetchKeys(config.JwksUri)
+	if err != nil {
+		return nil, err
+	}
+	c.keySets[provider] = keySet{
+		keys:      keys,
+		fetchedAt: currentTime,
+	}
+	return keys[kid], nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+func (c *jwksService) fetchKeys(uri string) (map[string]crypto.PublicKey, error) {
+	const op = "oidc.jwksService.fetchKeys"
+	c.logger.LogInfo("%s: start[uri=%s]", op, uri)
+	response, err := c.httpClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected jwks response status %d", response.StatusCode)
+	}
+	var set jsonWebKeySet
+	if err := json.NewDecoder(response.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseKey(jwk)
+		if err != nil {
+			c.logger.LogInfo("%s: skipping key %s err: %v", op, jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	c.logger.LogInfo("%s: success[uri=%s keys=%d]", op, uri, len(keys))
+	return keys, nil
+}
+
+func parseKey(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: n,
+			E: int(e.Int64()),
+		}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     x,
+			Y:     y,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
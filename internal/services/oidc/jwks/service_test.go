@@ -0,0 +1,199 @@
+package jwksOidcService_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/oidc"
+	jwksOidcService "github.com/rzmn/governi/internal/services/oidc/jwks"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	provider = oidc.Provider("apple")
+	issuer   = "https://appleid.apple.com"
+	audience = "app.verni"
+	keyId    = "test-key"
+)
+
+func createKeySetServer(t *testing.T, key *rsa.PrivateKey, fetches *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*fetches += 1
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": keyId,
+					"use": "sig",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func createService(server *httptest.Server, currentTime time.Time) oidc.Service {
+	return jwksOidcService.New(
+		jwksOidcService.JwksConfig{
+			Providers: map[string]jwksOidcService.ProviderConfig{
+				string(provider): {
+					Issuer:    issuer,
+					JwksUri:   server.URL,
+					Audiences: []string{audience},
+				},
+			},
+			KeysCacheLifetimeMinutes: 60,
+		},
+		server.Client(),
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
+	)
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) oidc.IdToken {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token err: %v", err)
+	}
+	return oidc.IdToken(signed)
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key err: %v", err)
+	}
+	return key
+}
+
+func validClaims(now time.Time, subject string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            issuer,
+		"aud":            audience,
+		"sub":            subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute * 10).Unix(),
+		"email":          "user@verni.app",
+		"email_verified": "true",
+	}
+}
+
+func TestVerifyValidToken(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+	subject := uuid.New().String()
+
+	identity, err := service.Verify(provider, signToken(t, key, keyId, validClaims(now, subject)))
+	if err != nil {
+		t.Fatalf("err should be nil, found %v", err)
+	}
+	if identity.Issuer != issuer || identity.Subject != subject {
+		t.Fatalf("unexpected identity %v", identity)
+	}
+	if identity.Email == nil || *identity.Email != "user@verni.app" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity email %v", identity)
+	}
+	if _, err := service.Verify(provider, signToken(t, key, keyId, validClaims(now, subject))); err != nil {
+		t.Fatalf("[second] err should be nil, found %v", err)
+	}
+	if fetches != 1 {
+		t.Fatalf("key set should be fetched once, fetched %d times", fetches)
+	}
+}
+
+func TestVerifyUnknownProvider(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+
+	_, err := service.Verify(oidc.Provider("google"), signToken(t, key, keyId, validClaims(now, uuid.New().String())))
+	if err == nil || err.Code != oidc.CodeUnknownProvider {
+		t.Fatalf("err code should be `unknown provider`, found %v", err)
+	}
+}
+
+func TestVerifyExpiredToken(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+	claims := validClaims(now.Add(-time.Hour), uuid.New().String())
+
+	_, err := service.Verify(provider, signToken(t, key, keyId, claims))
+	if err == nil || err.Code != oidc.CodeTokenExpired {
+		t.Fatalf("err code should be `token expired`, found %v", err)
+	}
+}
+
+func TestVerifyWrongIssuer(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+	claims := validClaims(now, uuid.New().String())
+	claims["iss"] = "https://accounts.google.com"
+
+	_, err := service.Verify(provider, signToken(t, key, keyId, claims))
+	if err == nil || err.Code != oidc.CodeTokenInvalid {
+		t.Fatalf("err code should be `token invalid`, found %v", err)
+	}
+}
+
+func TestVerifyWrongAudience(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+	claims := validClaims(now, uuid.New().String())
+	claims["aud"] = "app.other"
+
+	_, err := service.Verify(provider, signToken(t, key, keyId, claims))
+	if err == nil || err.Code != oidc.CodeTokenInvalid {
+		t.Fatalf("err code should be `token invalid`, found %v", err)
+	}
+}
+
+func TestVerifyForeignSignature(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+
+	_, err := service.Verify(provider, signToken(t, generateKey(t), keyId, validClaims(now, uuid.New().String())))
+	if err == nil || err.Code != oidc.CodeTokenInvalid {
+		t.Fatalf("err code should be `token invalid`, found %v", err)
+	}
+}
+
+func TestVerifyUnknownKeyId(t *testing.T) {
+	key := generateKey(t)
+	fetches := 0
+	now := time.Now()
+	service := createService(createKeySetServer(t, key, &fetches), now)
+
+	_, err := service.Verify(provider, signToken(t, key, uuid.New().String(), validClaims(now, uuid.New().String())))
+	if err == nil || err.Code != oidc.CodeTokenInvalid {
+		t.Fatalf("err code should be `token invalid`, found %v", err)
+	}
+}
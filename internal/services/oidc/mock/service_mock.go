@@ -0,0 +1,13 @@
+package oidc_mock
+
+import (
+	"github.com/rzmn/governi/internal/services/oidc"
+)
+
+type ServiceMock struct {
+	VerifyImpl func(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error)
+}
+
+func (c *ServiceMock) Verify(provider oidc.Provider, token oidc.IdToken) (oidc.Identity, *oidc.Error) {
+	return c.VerifyImpl(provider, token)
+}
@@ -0,0 +1,15 @@
+package oidc
+
+type Provider string
+type IdToken string
+
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         *string
+	EmailVerified bool
+}
+
+type Service interface {
+	Verify(provider Provider, token IdToken) (Identity, *Error)
+}
@@ -1,9 +1,12 @@
 package applePushNotifications
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 
 	pushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
 	"github.com/rzmn/governi/internal/services/logging"
@@ -12,58 +15,110 @@ import (
 
 	"github.com/sideshow/apns2"
 	"github.com/sideshow/apns2/certificate"
+	"github.com/sideshow/apns2/token"
 )
 
 type ApnsConfig struct {
+	Topic       string `json:"topic"`
+	Environment string `json:"environment"`
+
+	// token-based auth with a .p8 key, used when `keyPath` is set
+	KeyPath string `json:"keyPath"`
+	KeyId   string `json:"keyId"`
+	TeamId  string `json:"teamId"`
+
+	// certificate-based auth with a .p12 certificate
 	CertificatePath string `json:"certificatePath"`
 	CredentialsPath string `json:"credentialsPath"`
 }
 
+const (
+	EnvironmentSandbox    = "sandbox"
+	EnvironmentProduction = "production"
+)
+
+const defaultTopic = "com.rzmn.accountydev.app"
+
 type Repository pushNotificationsRepository.Repository
 
-func New(config ApnsConfig, logger logging.Service, pathProviderService pathProvider.Service, repository Repository) (pushNotifications.Service, error) {
+func New(config ApnsConfig, logger logging.Service, pathProviderService pathProvider.Service, repository Repository, content pushNotifications.ContentProvider) (pushNotifications.Service, error) {
 	const op = "apns.AppleService"
-	credentialsData, err := os.ReadFile(pathProviderService.AbsolutePath(config.CredentialsPath))
+	client, err := func() (*apns2.Client, error) {
+		if config.KeyPath != "" {
+			if config.KeyId == "" || config.TeamId == "" {
+				return nil, errors.New("keyId and teamId are required for token-based auth")
+			}
+			authKey, err := token.AuthKeyFromFile(pathProviderService.AbsolutePath(config.KeyPath))
+			if err != nil {
+				logger.LogInfo("%s: failed to open p8 key: %v", op, err)
+				return nil, err
+			}
+			return apns2.NewTokenClient(&token.Token{
+				AuthKey: authKey,
+				KeyID:   config.KeyId,
+				TeamID:  config.TeamId,
+			}), nil
+		}
+		credentialsData, err := os.ReadFile(pathProviderService.AbsolutePath(config.CredentialsPath))
+		if err != nil {
+			logger.LogInfo("%s: failed to open config: %v", op, err)
+			return nil, err
+		}
+		var credentials ApnsCredentials
+		json.Unmarshal(credentialsData, &credentials)
+		cert, err := certificate.FromP12File(pathProviderService.AbsolutePath(config.CertificatePath), credentials.Password)
+		if err != nil {
+			logger.LogInfo("%s: failed to open p12 creds: %v", op, err)
+			return nil, err
+		}
+		return apns2.NewClient(cert), nil
+	}()
 	if err != nil {
-		logger.LogInfo("%s: failed to open config: %v", op, err)
 		return &appleService{}, err
 	}
-	var credentials ApnsCredentials
-	json.Unmarshal(credentialsData, &credentials)
-	cert, err := certificate.FromP12File(pathProviderService.AbsolutePath(config.CertificatePath), credentials.Password)
-	if err != nil {
-		logger.LogInfo("%s: failed to open p12 creds %v: %v", op, err, credentials)
-		return &appleService{}, err
+	switch config.Environment {
+	case "", EnvironmentSandbox:
+		client = client.Development()
+	case EnvironmentProduction:
+		client = client.Production()
+	default:
+		return &appleService{}, fmt.Errorf("unknown apns environment %s", config.Environment)
+	}
+	topic := config.Topic
+	if topic == "" {
+		topic = defaultTopic
 	}
+	return NewWithClient(client, topic, logger, repository, content), nil
+}
+
+func NewWithClient(client *apns2.Client, topic string, logger logging.Service, repository Repository, content pushNotifications.ContentProvider) pushNotifications.Service {
 	return &appleService{
-		client:     apns2.NewClient(cert).Development(),
+		client:     client,
+		topic:      topic,
 		repository: repository,
+		content:    content,
 		logger:     logger,
-	}, nil
+	}
 }
 
 type appleService struct {
 	client     *apns2.Client
+	topic      string
 	repository Repository
+	content    pushNotifications.ContentProvider
 	logger     logging.Service
+	// tokens restricts pushes to these push tokens of the receiver when not empty
+	tokens []string
 }
 
-type PushDataType int
-
-const (
-	PushDataTypeFriendRequestHasBeenAccepted = iota
-	PushDataTypeGotFriendRequest
-	PushDataTypeNewExpenseReceived
-)
-
-type PushData[T any] struct {
-	Type    PushDataType `json:"t"`
-	Payload *T           `json:"p,omitempty"`
+type PushData struct {
+	Type    pushNotifications.DataType `json:"t"`
+	Payload any                        `json:"p,omitempty"`
 }
 
-type Push[T any] struct {
+type Push struct {
 	Aps  PushPayload `json:"aps"`
-	Data PushData[T] `json:"d"`
+	Data PushData    `json:"d"`
 }
 
 type PushPayload struct {
@@ -81,156 +136,113 @@ type ApnsCredentials struct {
 	Password string `json:"cert_pwd"`
 }
 
-func (c *appleService) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) {
+func (c *appleService) WithTokens(tokens []string) pushNotifications.Service {
+	restricted := *c
+	restricted.tokens = tokens
+	return &restricted
+}
+
+func (c *appleService) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) error {
 	const op = "apns.defaultService.FriendRequestHasBeenAccepted"
 	c.logger.LogInfo("%s: start[receiver=%s acceptedBy=%s]", op, receiver, acceptedBy)
-	receiverToken, err := c.repository.GetPushToken(pushNotificationsRepository.UserId(receiver))
+	content, err := c.content.FriendRequestHasBeenAccepted(receiver, acceptedBy)
 	if err != nil {
-		c.logger.LogError("%s: cannot get receiver token from db err: %v", op, err)
-		return
-	}
-	if receiverToken == nil {
-		c.logger.LogInfo("%s: receiver push token is nil", op)
-		return
-	}
-	type Payload struct {
-		Target pushNotifications.UserId `json:"t"`
-	}
-	body := fmt.Sprintf("By %s", acceptedBy)
-	payload := Payload{
-		Target: acceptedBy,
-	}
-	mutable := 1
-	payloadString, err := json.Marshal(Push[Payload]{
-		Aps: PushPayload{
-			MutableContent: &mutable,
-			Alert: PushPayloadAlert{
-				Title:    "Friend request has been accepted",
-				Subtitle: nil,
-				Body:     &body,
-			},
-		},
-		Data: PushData[Payload]{
-			Type:    PushDataTypeFriendRequestHasBeenAccepted,
-			Payload: &payload,
-		},
-	})
-	if err != nil {
-		c.logger.LogError("%s: failed to create payload string: %v", op, err)
-		return
+		c.logger.LogError("%s: failed to build push: %v", op, err)
+		return err
 	}
-	if err := c.send(*receiverToken, string(payloadString)); err != nil {
+	if err := c.push(receiver, content); err != nil {
 		c.logger.LogError("%s: failed to send push: %v", op, err)
-		return
+		return err
 	}
 	c.logger.LogInfo("%s: success[receiver=%s acceptedBy=%s]", op, receiver, acceptedBy)
+	return nil
 }
 
-func (c *appleService) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) {
+func (c *appleService) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) error {
 	const op = "apns.defaultService.FriendRequestHasBeenReceived"
 	c.logger.LogInfo("%s: start[receiver=%s sentBy=%s]", op, receiver, sentBy)
-	receiverToken, err := c.repository.GetPushToken(pushNotificationsRepository.UserId(receiver))
+	content, err := c.content.FriendRequestHasBeenReceived(receiver, sentBy)
 	if err != nil {
-		c.logger.LogError("%s: cannot get receiver token from db err: %v", op, err)
-		return
-	}
-	if receiverToken == nil {
-		c.logger.LogInfo("%s: receiver push token is nil", op)
-		return
-	}
-	type Payload struct {
-		Sender pushNotifications.UserId `json:"s"`
-	}
-	body := fmt.Sprintf("From: %s", sentBy)
-	payload := Payload{
-		Sender: sentBy,
-	}
-	mutable := 1
-	payloadString, err := json.Marshal(Push[Payload]{
-		Aps: PushPayload{
-			MutableContent: &mutable,
-			Alert: PushPayloadAlert{
-				Title:    "Got Friend Request",
-				Subtitle: nil,
-				Body:     &body,
-			},
-		},
-		Data: PushData[Payload]{
-			Type:    PushDataTypeGotFriendRequest,
-			Payload: &payload,
-		},
-	})
-	if err != nil {
-		c.logger.LogError("%s: failed to create payload string: %v", op, err)
-		return
+		c.logger.LogError("%s: failed to build push: %v", op, err)
+		return err
 	}
-	if err := c.send(*receiverToken, string(payloadString)); err != nil {
+	if err := c.push(receiver, content); err != nil {
 		c.logger.LogError("%s: failed to send push: %v", op, err)
-		return
+		return err
 	}
 	c.logger.LogInfo("%s: success[receiver=%s sentBy=%s]", op, receiver, sentBy)
+	return nil
 }
 
-func (c *appleService) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) {
+func (c *appleService) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) error {
 	const op = "apns.defaultService.NewExpenseReceived"
 	c.logger.LogInfo("%s: start[receiver=%s id=%s author=%s]", op, receiver, expense.Id, author)
-	receiverToken, err := c.repository.GetPushToken(pushNotificationsRepository.UserId(receiver))
+	content, err := c.content.NewExpenseReceived(receiver, expense, author)
 	if err != nil {
-		c.logger.LogError("%s: cannot get receiver token from db err: %v", op, err)
-		return
-	}
-	if receiverToken == nil {
-		c.logger.LogInfo("%s: receiver push token is nil", op)
-		return
-	}
-	type Payload struct {
-		DealId   pushNotifications.ExpenseId `json:"d"`
-		AuthorId pushNotifications.UserId    `json:"u"`
-		Cost     pushNotifications.Cost      `json:"c"`
-	}
-	body := fmt.Sprintf("%s: %d", expense.Details, expense.Total)
-	cost := expense.Total
-	for i := 0; i < len(expense.Shares); i++ {
-		if pushNotifications.UserId(expense.Shares[i].UserId) == receiver {
-			cost = expense.Shares[i].Cost
-		}
+		c.logger.LogError("%s: failed to build push: %v", op, err)
+		return err
+	}
+	if err := c.push(receiver, content); err != nil {
+		c.logger.LogError("%s: failed to send push: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[receiver=%s id=%s author=%s]", op, receiver, expense.Id, author)
+	return nil
+}
+
+func (c *appleService) push(receiver pushNotifications.UserId, content pushNotifications.Content) error {
+	const op = "apns.defaultService.push"
+	tokens, err := c.repository.GetPushTokens(context.Background(), pushNotificationsRepository.UserId(receiver))
+	if err != nil {
+		return fmt.Errorf("getting receiver tokens from db: %w", err)
 	}
-	payload := Payload{
-		DealId:   pushNotifications.ExpenseId(expense.Id),
-		AuthorId: author,
-		Cost:     pushNotifications.Cost(cost),
+	tokens = slices.DeleteFunc(tokens, func(token pushNotificationsRepository.PushToken) bool {
+		return token.Platform != pushNotificationsRepository.PlatformIos || (len(c.tokens) > 0 && !slices.Contains(c.tokens, token.Token))
+	})
+	if len(tokens) == 0 {
+		c.logger.LogInfo("%s: receiver has no ios push tokens", op)
+		return nil
 	}
 	mutable := 1
-	payloadString, err := json.Marshal(Push[Payload]{
+	payloadString, err := json.Marshal(Push{
 		Aps: PushPayload{
 			MutableContent: &mutable,
 			Alert: PushPayloadAlert{
-				Title:    "New Expense Received",
+				Title:    content.Title,
 				Subtitle: nil,
-				Body:     &body,
+				Body:     content.Body,
 			},
 		},
-		Data: PushData[Payload]{
-			Type:    PushDataTypeNewExpenseReceived,
-			Payload: &payload,
+		Data: PushData{
+			Type:    content.Type,
+			Payload: content.Payload,
 		},
 	})
 	if err != nil {
-		c.logger.LogError("%s: failed create payload string: %v", op, err)
-		return
+		return fmt.Errorf("creating payload string: %w", err)
 	}
-	if err := c.send(*receiverToken, string(payloadString)); err != nil {
-		c.logger.LogError("%s: failed to send push: %v", op, err)
-		return
+	errs := []error{}
+	undelivered := []string{}
+	for _, token := range tokens {
+		if err := c.send(token.Token, string(payloadString)); err != nil {
+			errs = append(errs, err)
+			undelivered = append(undelivered, token.Token)
+		}
 	}
-	c.logger.LogInfo("%s: success[receiver=%s id=%s author=%s]", op, receiver, expense.Id, author)
+	if len(errs) > 0 {
+		return &pushNotifications.UndeliveredError{
+			Tokens: undelivered,
+			Err:    errors.Join(errs...),
+		}
+	}
+	return nil
 }
 
 func (c *appleService) send(token string, payloadString string) error {
 	const op = "apns.defaultService.send"
 	notification := &apns2.Notification{}
 	notification.DeviceToken = token
-	notification.Topic = "com.rzmn.accountydev.app"
+	notification.Topic = c.topic
 
 	c.logger.LogInfo("%s: sending push: %s", op, payloadString)
 	notification.Payload = payloadString
@@ -241,6 +253,16 @@ func (c *appleService) send(token string, payloadString string) error {
 		c.logger.LogInfo("%s: failed to send notification: %v", op, err)
 		return err
 	}
-	fmt.Printf("%s: sent %v %v %v\n", op, res.StatusCode, res.ApnsID, res.Reason)
-	return nil
+	if res.Sent() {
+		c.logger.LogInfo("%s: sent %v", op, res.ApnsID)
+		return nil
+	}
+	if res.Reason == apns2.ReasonBadDeviceToken || res.Reason == apns2.ReasonUnregistered {
+		c.logger.LogInfo("%s: removing dead token, apns responded with %d %s", op, res.StatusCode, res.Reason)
+		if err := c.repository.RemovePushToken(context.Background(), token).Perform(); err != nil {
+			return fmt.Errorf("removing dead token: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("apns responded with %d %s", res.StatusCode, res.Reason)
 }
@@ -0,0 +1,255 @@
+package applePushNotifications_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	pushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
+	memoryPushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/memory"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+	applePushNotifications "github.com/rzmn/governi/internal/services/pushNotifications/apns"
+	pushNotifications_mock "github.com/rzmn/governi/internal/services/pushNotifications/mock"
+
+	"github.com/google/uuid"
+	"github.com/sideshow/apns2"
+)
+
+const topic = "app.verni.test"
+
+// testLogger does not stop the test on LogError, failed pushes are reported as errors.
+type testLogger struct {
+	t *testing.T
+}
+
+func (c testLogger) LogInfo(format string, v ...any) {
+	c.t.Logf(format, v...)
+}
+
+func (c testLogger) LogError(format string, v ...any) {
+	c.t.Logf(format, v...)
+}
+
+func (c testLogger) LogFatal(format string, v ...any) {
+	c.t.Fatalf(format, v...)
+}
+
+type apnsServer struct {
+	mutex     sync.Mutex
+	delivered []string
+	reasons   map[string]string
+}
+
+func createService(t *testing.T, server *apnsServer, repository pushNotificationsRepository.Repository) pushNotifications.Service {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		if r.Header.Get("apns-topic") != topic {
+			t.Errorf("unexpected topic %s", r.Header.Get("apns-topic"))
+		}
+		token := strings.TrimPrefix(r.URL.Path, "/3/device/")
+		switch reason := server.reasons[token]; reason {
+		case "":
+			server.delivered = append(server.delivered, token)
+			w.Header().Set("apns-id", uuid.New().String())
+		case apns2.ReasonUnregistered:
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"` + reason + `"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"` + reason + `"}`))
+		}
+	}))
+	t.Cleanup(httpServer.Close)
+	client := apns2.NewClient(tls.Certificate{})
+	client.Host = httpServer.URL
+	client.HTTPClient = httpServer.Client()
+	return applePushNotifications.NewWithClient(client, topic, testLogger{t: t}, repository, contentProvider())
+}
+
+func contentProvider() *pushNotifications_mock.ContentProviderMock {
+	content := func(dataType pushNotifications.DataType, payload any) pushNotifications.Content {
+		body := "body"
+		return pushNotifications.Content{
+			Title:   "title",
+			Body:    &body,
+			Type:    dataType,
+			Payload: payload,
+		}
+	}
+	return &pushNotifications_mock.ContentProviderMock{
+		FriendRequestHasBeenAcceptedImpl: func(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) (pushNotifications.Content, error) {
+			return content(pushNotifications.DataTypeFriendRequestHasBeenAccepted, map[string]pushNotifications.UserId{"t": acceptedBy}), nil
+		},
+		FriendRequestHasBeenReceivedImpl: func(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) (pushNotifications.Content, error) {
+			return content(pushNotifications.DataTypeGotFriendRequest, map[string]pushNotifications.UserId{"s": sentBy}), nil
+		},
+		NewExpenseReceivedImpl: func(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) (pushNotifications.Content, error) {
+			return content(pushNotifications.DataTypeNewExpenseReceived, map[string]any{"d": expense.Id, "u": author}), nil
+		},
+	}
+}
+
+func writeAuthKey(t *testing.T) string {
+	root := t.TempDir()
+	t.Setenv("VERNI_PROJECT_ROOT", root)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key err: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to serialize key err: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "AuthKey.p8"), pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}), 0600); err != nil {
+		t.Fatalf("failed to write key err: %v", err)
+	}
+	return "./AuthKey.p8"
+}
+
+func createConfiguredService(t *testing.T, config applePushNotifications.ApnsConfig) error {
+	logger := testLogger{t: t}
+	_, err := applePushNotifications.New(config, logger, envBasedPathProvider.New(logger), memoryPushNotificationsRepository.New(logger), contentProvider())
+	return err
+}
+
+func TestNewWithTokenAuth(t *testing.T) {
+	keyPath := writeAuthKey(t)
+	for _, environment := range []string{applePushNotifications.EnvironmentSandbox, applePushNotifications.EnvironmentProduction} {
+		if err := createConfiguredService(t, applePushNotifications.ApnsConfig{
+			Topic:       topic,
+			Environment: environment,
+			KeyPath:     keyPath,
+			KeyId:       "ABC123DEFG",
+			TeamId:      "DEF123GHIJ",
+		}); err != nil {
+			t.Fatalf("failed to create %s service err: %v", environment, err)
+		}
+	}
+}
+
+func TestNewWithTokenAuthWithoutTeamId(t *testing.T) {
+	if err := createConfiguredService(t, applePushNotifications.ApnsConfig{
+		Topic:   topic,
+		KeyPath: writeAuthKey(t),
+		KeyId:   "ABC123DEFG",
+	}); err == nil {
+		t.Fatalf("token-based auth without team id should be failed")
+	}
+}
+
+func TestNewWithUnknownEnvironment(t *testing.T) {
+	if err := createConfiguredService(t, applePushNotifications.ApnsConfig{
+		Topic:       topic,
+		Environment: "staging",
+		KeyPath:     writeAuthKey(t),
+		KeyId:       "ABC123DEFG",
+		TeamId:      "DEF123GHIJ",
+	}); err == nil {
+		t.Fatalf("unknown environment should be failed")
+	}
+}
+
+func storeToken(t *testing.T, repository pushNotificationsRepository.Repository, uid pushNotifications.UserId, platform pushNotificationsRepository.Platform) string {
+	token := uuid.New().String()
+	if err := repository.StorePushToken(context.Background(), pushNotificationsRepository.UserId(uid), pushNotificationsRepository.PushToken{
+		Platform: platform,
+		Token:    token,
+	}).Perform(); err != nil {
+		t.Fatalf("failed to store token err: %v", err)
+	}
+	return token
+}
+
+func getTokens(t *testing.T, repository pushNotificationsRepository.Repository, uid pushNotifications.UserId) []string {
+	tokens, err := repository.GetPushTokens(context.Background(), pushNotificationsRepository.UserId(uid))
+	if err != nil {
+		t.Fatalf("failed to get tokens err: %v", err)
+	}
+	result := []string{}
+	for _, token := range tokens {
+		result = append(result, token.Token)
+	}
+	return result
+}
+
+func TestPushIsSentToAllIosDevices(t *testing.T) {
+	server := apnsServer{}
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, &server, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	phoneToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	tabletToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	if err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("failed to send push err: %v", err)
+	}
+	slices.Sort(server.delivered)
+	expected := []string{phoneToken, tabletToken}
+	slices.Sort(expected)
+	if !slices.Equal(server.delivered, expected) {
+		t.Fatalf("push should be delivered to %v, found %v", expected, server.delivered)
+	}
+}
+
+func TestDeadTokensAreRemoved(t *testing.T) {
+	receiver := pushNotifications.UserId(uuid.New().String())
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	badToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	unregisteredToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	aliveToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	server := apnsServer{
+		reasons: map[string]string{
+			badToken:          apns2.ReasonBadDeviceToken,
+			unregisteredToken: apns2.ReasonUnregistered,
+		},
+	}
+	service := createService(t, &server, repository)
+	if err := service.FriendRequestHasBeenAccepted(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("push to dead tokens should not fail, found err: %v", err)
+	}
+	if !slices.Equal(server.delivered, []string{aliveToken}) {
+		t.Fatalf("push should be delivered to %s, found %v", aliveToken, server.delivered)
+	}
+	if tokens := getTokens(t, repository, receiver); !slices.Equal(tokens, []string{aliveToken}) {
+		t.Fatalf("dead tokens should be removed, found %v", tokens)
+	}
+}
+
+func TestPushFailed(t *testing.T) {
+	receiver := pushNotifications.UserId(uuid.New().String())
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	failingToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	aliveToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	server := apnsServer{
+		reasons: map[string]string{
+			failingToken: apns2.ReasonPayloadTooLarge,
+		},
+	}
+	service := createService(t, &server, repository)
+	if err := service.FriendRequestHasBeenAccepted(receiver, pushNotifications.UserId(uuid.New().String())); err == nil {
+		t.Fatalf("push should be failed when apns rejects it")
+	}
+	if !slices.Equal(server.delivered, []string{aliveToken}) {
+		t.Fatalf("push should still be delivered to %s, found %v", aliveToken, server.delivered)
+	}
+	if tokens := getTokens(t, repository, receiver); len(tokens) != 2 {
+		t.Fatalf("tokens should not be removed on other failures, found %v", tokens)
+	}
+}
@@ -0,0 +1,24 @@
+package pushNotifications
+
+type DataType int
+
+const (
+	DataTypeFriendRequestHasBeenAccepted DataType = iota
+	DataTypeGotFriendRequest
+	DataTypeNewExpenseReceived
+)
+
+// Content is a platform independent push, each provider maps it onto its own message format.
+type Content struct {
+	Title   string
+	Body    *string
+	Type    DataType
+	Payload any
+}
+
+// ContentProvider builds the push addressed to a receiver, texts are in the receiver's language.
+type ContentProvider interface {
+	FriendRequestHasBeenAccepted(receiver UserId, acceptedBy UserId) (Content, error)
+	FriendRequestHasBeenReceived(receiver UserId, sentBy UserId) (Content, error)
+	NewExpenseReceived(receiver UserId, expense Expense, author UserId) (Content, error)
+}
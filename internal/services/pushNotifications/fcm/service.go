@@ -0,0 +1,330 @@
+package firebasePushNotifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/pathProvider"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type FcmConfig struct {
+	CredentialsPath string `json:"credentialsPath"`
+	Endpoint        string `json:"endpoint"`
+}
+
+type Repository pushNotificationsRepository.Repository
+
+const (
+	defaultEndpoint       = "https://fcm.googleapis.com"
+	messagingScope        = "https://www.googleapis.com/auth/firebase.messaging"
+	assertionLifetime     = time.Hour
+	accessTokenExpirySkew = time.Minute
+	unregisteredErrorCode = "UNREGISTERED"
+)
+
+// serviceAccount is the subset of the service account key file downloaded from the firebase console
+type serviceAccount struct {
+	ProjectId   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenUri    string `json:"token_uri"`
+}
+
+func New(
+	config FcmConfig,
+	httpClient *http.Client,
+	logger logging.Service,
+	pathProviderService pathProvider.Service,
+	repository Repository,
+	content pushNotifications.ContentProvider,
+	currentTime func() time.Time,
+) (pushNotifications.Service, error) {
+	const op = "fcm.FirebaseService"
+	credentialsData, err := os.ReadFile(pathProviderService.AbsolutePath(config.CredentialsPath))
+	if err != nil {
+		logger.LogInfo("%s: failed to open credentials: %v", op, err)
+		return &firebaseService{}, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(credentialsData, &account); err != nil {
+		logger.LogInfo("%s: failed to parse credentials: %v", op, err)
+		return &firebaseService{}, err
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		logger.LogInfo("%s: failed to parse service account private key: %v", op, err)
+		return &firebaseService{}, err
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	return &firebaseService{
+		account:     account,
+		privateKey:  privateKey,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		httpClient:  httpClient,
+		repository:  repository,
+		content:     content,
+		logger:      logger,
+		currentTime: currentTime,
+		accessToken: &accessTokenCache{},
+	}, nil
+}
+
+type firebaseService struct {
+	account     serviceAccount
+	privateKey  any
+	endpoint    string
+	httpClient  *http.Client
+	repository  Repository
+	content     pushNotifications.ContentProvider
+	logger      logging.Service
+	currentTime func() time.Time
+	// tokens restricts pushes to these push tokens of the receiver when not empty
+	tokens []string
+
+	accessToken *accessTokenCache
+}
+
+// accessTokenCache is shared between the service and its copies returned by WithTokens
+type accessTokenCache struct {
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+type Message struct {
+	Token        string            `json:"token"`
+	Notification Notification      `json:"notification"`
+	Data         map[string]string `json:"data"`
+}
+
+type Notification struct {
+	Title string  `json:"title"`
+	Body  *string `json:"body,omitempty"`
+}
+
+type sendRequest struct {
+	Message Message `json:"message"`
+}
+
+type errorDetail struct {
+	ErrorCode string `json:"errorCode"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Details []errorDetail `json:"details"`
+	} `json:"error"`
+}
+
+func (c errorResponse) unregistered() bool {
+	return slices.ContainsFunc(c.Error.Details, func(detail errorDetail) bool {
+		return detail.ErrorCode == unregisteredErrorCode
+	})
+}
+
+type accessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (c *firebaseService) WithTokens(tokens []string) pushNotifications.Service {
+	restricted := *c
+	restricted.tokens = tokens
+	return &restricted
+}
+
+func (c *firebaseService) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) error {
+	const op = "fcm.defaultService.FriendRequestHasBeenAccepted"
+	c.logger.LogInfo("%s: start[receiver=%s acceptedBy=%s]", op, receiver, acceptedBy)
+	content, err := c.content.FriendRequestHasBeenAccepted(receiver, acceptedBy)
+	if err != nil {
+		c.logger.LogError("%s: failed to build push: %v", op, err)
+		return err
+	}
+	if err := c.push(receiver, content); err != nil {
+		c.logger.LogError("%s: failed to send push: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[receiver=%s acceptedBy=%s]", op, receiver, acceptedBy)
+	return nil
+}
+
+func (c *firebaseService) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) error {
+	const op = "fcm.defaultService.FriendRequestHasBeenReceived"
+	c.logger.LogInfo("%s: start[receiver=%s sentBy=%s]", op, receiver, sentBy)
+	content, err := c.content.FriendRequestHasBeenReceived(receiver, sentBy)
+	if err != nil {
+		c.logger.LogError("%s: failed to build push: %v", op, err)
+		return err
+	}
+	if err := c.push(receiver, content); err != nil {
+		c.logger.LogError("%s: failed to send push: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[receiver=%s sentBy=%s]", op, receiver, sentBy)
+	return nil
+}
+
+func (c *firebaseService) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) error {
+	const op = "fcm.defaultService.NewExpenseReceived"
+	c.logger.LogInfo("%s: start[receiver=%s id=%s author=%s]", op, receiver, expense.Id, author)
+	content, err := c.content.NewExpenseReceived(receiver, expense, author)
+	if err != nil {
+		c.logger.LogError("%s: failed to build push: %v", op, err)
+		return err
+	}
+	if err := c.push(receiver, content); err != nil {
+		c.logger.LogError("%s: failed to send push: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[receiver=%s id=%s author=%s]", op, receiver, expense.Id, author)
+	return nil
+}
+
+func (c *firebaseService) push(receiver pushNotifications.UserId, content pushNotifications.Content) error {
+	const op = "fcm.defaultService.push"
+	tokens, err := c.repository.GetPushTokens(context.Background(), pushNotificationsRepository.UserId(receiver))
+	if err != nil {
+		return fmt.Errorf("getting receiver tokens from db: %w", err)
+	}
+	tokens = slices.DeleteFunc(tokens, func(token pushNotificationsRepository.PushToken) bool {
+		return token.Platform != pushNotificationsRepository.PlatformAndroid || (len(c.tokens) > 0 && !slices.Contains(c.tokens, token.Token))
+	})
+	if len(tokens) == 0 {
+		c.logger.LogInfo("%s: receiver has no android push tokens", op)
+		return nil
+	}
+	// fcm data values must be strings, the payload is nested as json like the `d` field of apns pushes
+	payload, err := json.Marshal(content.Payload)
+	if err != nil {
+		return fmt.Errorf("creating payload string: %w", err)
+	}
+	errs := []error{}
+	undelivered := []string{}
+	for _, token := range tokens {
+		if err := c.send(Message{
+			Token: token.Token,
+			Notification: Notification{
+				Title: content.Title,
+				Body:  content.Body,
+			},
+			Data: map[string]string{
+				"t": strconv.Itoa(int(content.Type)),
+				"p": string(payload),
+			},
+		}); err != nil {
+			errs = append(errs, err)
+			undelivered = append(undelivered, token.Token)
+		}
+	}
+	if len(errs) > 0 {
+		return &pushNotifications.UndeliveredError{
+			Tokens: undelivered,
+			Err:    errors.Join(errs...),
+		}
+	}
+	return nil
+}
+
+func (c *firebaseService) send(message Message) error {
+	const op = "fcm.defaultService.send"
+	accessToken, err := c.getAccessToken()
+	if err != nil {
+		return fmt.Errorf("getting access token: %w", err)
+	}
+	body, err := json.Marshal(sendRequest{
+		Message: message,
+	})
+	if err != nil {
+		return fmt.Errorf("creating request body: %w", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/projects/%s/messages:send", c.endpoint, c.account.ProjectId), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Content-Type", "application/json")
+	c.logger.LogInfo("%s: sending push: %s", op, body)
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer response.Body.Close()
+	responseBody, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		var failure errorResponse
+		if json.Unmarshal(responseBody, &failure) == nil && failure.unregistered() {
+			c.logger.LogInfo("%s: removing dead token, fcm responded with %d %s", op, response.StatusCode, responseBody)
+			if err := c.repository.RemovePushToken(context.Background(), message.Token).Perform(); err != nil {
+				return fmt.Errorf("removing dead token: %w", err)
+			}
+			return nil
+		}
+		return fmt.Errorf("fcm responded with status %d: %s", response.StatusCode, responseBody)
+	}
+	c.logger.LogInfo("%s: sent %s", op, responseBody)
+	return nil
+}
+
+// getAccessToken exchanges a signed service account assertion for an oauth2 access token,
+// the token is reused until it is about to expire
+func (c *firebaseService) getAccessToken() (string, error) {
+	c.accessToken.mutex.Lock()
+	defer c.accessToken.mutex.Unlock()
+	now := c.currentTime()
+	if c.accessToken.token != "" && now.Before(c.accessToken.expiresAt) {
+		return c.accessToken.token, nil
+	}
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.account.ClientEmail,
+		"scope": messagingScope,
+		"aud":   c.account.TokenUri,
+		"iat":   now.Unix(),
+		"exp":   now.Add(assertionLifetime).Unix(),
+	}).SignedString(c.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("signing assertion: %w", err)
+	}
+	response, err := c.httpClient.PostForm(c.account.TokenUri, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", fmt.Errorf("requesting access token: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(response.Body)
+		return "", fmt.Errorf("token endpoint responded with status %d: %s", response.StatusCode, responseBody)
+	}
+	var token accessTokenResponse
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token endpoint returned empty access token")
+	}
+	c.accessToken.token = token.AccessToken
+	c.accessToken.expiresAt = now.Add(time.Duration(token.ExpiresIn)*time.Second - accessTokenExpirySkew)
+	return c.accessToken.token, nil
+}
@@ -0,0 +1,371 @@
+package firebasePushNotifications_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	pushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications"
+	memoryPushNotificationsRepository "github.com/rzmn/governi/internal/repositories/pushNotifications/memory"
+	"github.com/rzmn/governi/internal/schema"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+	firebasePushNotifications "github.com/rzmn/governi/internal/services/pushNotifications/fcm"
+	pushNotifications_mock "github.com/rzmn/governi/internal/services/pushNotifications/mock"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	projectId   = "test-project"
+	clientEmail = "push@test-project.iam.gserviceaccount.com"
+	accessToken = "test-access-token"
+)
+
+// testLogger does not stop the test on LogError, failed pushes are reported as errors.
+type testLogger struct {
+	t *testing.T
+}
+
+func (c testLogger) LogInfo(format string, v ...any) {
+	c.t.Logf(format, v...)
+}
+
+func (c testLogger) LogError(format string, v ...any) {
+	c.t.Logf(format, v...)
+}
+
+func (c testLogger) LogFatal(format string, v ...any) {
+	c.t.Fatalf(format, v...)
+}
+
+type fcmServer struct {
+	server       *httptest.Server
+	tokenFetches int
+	messages     []firebasePushNotifications.Message
+	sendStatus   int
+	unregistered map[string]bool
+	failing      map[string]bool
+}
+
+func createFcmServer(t *testing.T, key *rsa.PrivateKey) *fcmServer {
+	result := &fcmServer{
+		sendStatus:   http.StatusOK,
+		unregistered: map[string]bool{},
+		failing:      map[string]bool{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		result.tokenFetches += 1
+		if grantType := r.FormValue("grant_type"); grantType != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("unexpected grant type %s", grantType)
+		}
+		claims := jwt.MapClaims{}
+		if _, err := jwt.ParseWithClaims(r.FormValue("assertion"), claims, func(token *jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"RS256"})); err != nil {
+			t.Errorf("failed to verify assertion err: %v", err)
+		}
+		if claims["iss"] != clientEmail || claims["scope"] != "https://www.googleapis.com/auth/firebase.messaging" {
+			t.Errorf("unexpected assertion claims %v", claims)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": accessToken,
+			"expires_in":   3600,
+			"token_type":   "Bearer",
+		})
+	})
+	mux.HandleFunc("POST /v1/projects/"+projectId+"/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get("Authorization"); authorization != "Bearer "+accessToken {
+			t.Errorf("unexpected authorization header %s", authorization)
+		}
+		var request struct {
+			Message firebasePushNotifications.Message `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode message err: %v", err)
+		}
+		if result.unregistered[request.Message.Token] {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+		status := result.sendStatus
+		if result.failing[request.Message.Token] {
+			status = http.StatusInternalServerError
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"code":500,"status":"INTERNAL"}}`))
+			return
+		}
+		result.messages = append(result.messages, request.Message)
+		json.NewEncoder(w).Encode(map[string]string{
+			"name": "projects/" + projectId + "/messages/" + uuid.New().String(),
+		})
+	})
+	result.server = httptest.NewServer(mux)
+	t.Cleanup(result.server.Close)
+	return result
+}
+
+func createService(t *testing.T, server *fcmServer, key *rsa.PrivateKey, repository pushNotificationsRepository.Repository) pushNotifications.Service {
+	root := t.TempDir()
+	t.Setenv("VERNI_PROJECT_ROOT", root)
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   projectId,
+		"client_email": clientEmail,
+		"private_key": string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+		"token_uri": server.server.URL + "/token",
+	})
+	if err != nil {
+		t.Fatalf("failed to serialize credentials err: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "fcm.json"), credentials, 0600); err != nil {
+		t.Fatalf("failed to write credentials err: %v", err)
+	}
+	logger := testLogger{t: t}
+	service, err := firebasePushNotifications.New(
+		firebasePushNotifications.FcmConfig{
+			CredentialsPath: "./fcm.json",
+			Endpoint:        server.server.URL,
+		},
+		server.server.Client(),
+		logger,
+		envBasedPathProvider.New(logger),
+		repository,
+		contentProvider(),
+		time.Now,
+	)
+	if err != nil {
+		t.Fatalf("failed to create service err: %v", err)
+	}
+	return service
+}
+
+func contentProvider() *pushNotifications_mock.ContentProviderMock {
+	content := func(dataType pushNotifications.DataType, payload any) pushNotifications.Content {
+		body := "body"
+		return pushNotifications.Content{
+			Title:   "title",
+			Body:    &body,
+			Type:    dataType,
+			Payload: payload,
+		}
+	}
+	return &pushNotifications_mock.ContentProviderMock{
+		FriendRequestHasBeenAcceptedImpl: func(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) (pushNotifications.Content, error) {
+			return content(pushNotifications.DataTypeFriendRequestHasBeenAccepted, map[string]pushNotifications.UserId{"t": acceptedBy}), nil
+		},
+		FriendRequestHasBeenReceivedImpl: func(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) (pushNotifications.Content, error) {
+			return content(pushNotifications.DataTypeGotFriendRequest, map[string]pushNotifications.UserId{"s": sentBy}), nil
+		},
+		NewExpenseReceivedImpl: func(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) (pushNotifications.Content, error) {
+			return content(pushNotifications.DataTypeNewExpenseReceived, map[string]any{"d": expense.Id, "u": author}), nil
+		},
+	}
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key err: %v", err)
+	}
+	return key
+}
+
+func storeToken(t *testing.T, repository pushNotificationsRepository.Repository, uid pushNotifications.UserId, platform pushNotificationsRepository.Platform) string {
+	token := uuid.New().String()
+	if err := repository.StorePushToken(context.Background(), pushNotificationsRepository.UserId(uid), pushNotificationsRepository.PushToken{
+		Platform: platform,
+		Token:    token,
+	}).Perform(); err != nil {
+		t.Fatalf("failed to store token err: %v", err)
+	}
+	return token
+}
+
+func TestNewExpenseReceivedIsSentToAndroidTokens(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	author := pushNotifications.UserId(uuid.New().String())
+	storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	androidToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	expense := pushNotifications.Expense{
+		Id: "expense",
+		Expense: schema.Expense{
+			Details: "dinner",
+			Total:   300,
+		},
+	}
+	if err := service.NewExpenseReceived(receiver, expense, author); err != nil {
+		t.Fatalf("failed to send push err: %v", err)
+	}
+	if len(server.messages) != 1 {
+		t.Fatalf("push should be sent to android token only, found %v", server.messages)
+	}
+	message := server.messages[0]
+	if message.Token != androidToken {
+		t.Fatalf("push should be sent to %s, found %s", androidToken, message.Token)
+	}
+	if message.Notification.Title != "title" || message.Notification.Body == nil || *message.Notification.Body != "body" {
+		t.Fatalf("unexpected notification %v", message.Notification)
+	}
+	if message.Data["t"] != "2" {
+		t.Fatalf("data type should be `new expense received`, found %s", message.Data["t"])
+	}
+	var payload struct {
+		DealId   string `json:"d"`
+		AuthorId string `json:"u"`
+	}
+	if err := json.Unmarshal([]byte(message.Data["p"]), &payload); err != nil {
+		t.Fatalf("failed to decode payload err: %v", err)
+	}
+	if payload.DealId != "expense" || payload.AuthorId != string(author) {
+		t.Fatalf("unexpected payload %v", payload)
+	}
+}
+
+func TestAccessTokenIsReused(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	if err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("failed to send first push err: %v", err)
+	}
+	if err := service.FriendRequestHasBeenAccepted(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("failed to send second push err: %v", err)
+	}
+	if len(server.messages) != 2 {
+		t.Fatalf("both pushes should be sent, found %v", server.messages)
+	}
+	if server.tokenFetches != 1 {
+		t.Fatalf("access token should be fetched once, found %d", server.tokenFetches)
+	}
+}
+
+func TestNoAndroidTokens(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	storeToken(t, repository, receiver, pushNotificationsRepository.PlatformIos)
+	if err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("push without android tokens should not fail, found err: %v", err)
+	}
+	if len(server.messages) != 0 || server.tokenFetches != 0 {
+		t.Fatalf("fcm should not be called, found %d messages and %d token fetches", len(server.messages), server.tokenFetches)
+	}
+}
+
+func TestSendFailed(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	server.sendStatus = http.StatusInternalServerError
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	if err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err == nil {
+		t.Fatalf("push should be failed when fcm responds with an error")
+	}
+}
+
+func TestPushIsSentToAllDevices(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	phoneToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	tabletToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	if err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("failed to send push err: %v", err)
+	}
+	if len(server.messages) != 2 {
+		t.Fatalf("push should be sent to both devices, found %v", server.messages)
+	}
+	for _, token := range []string{phoneToken, tabletToken} {
+		if !slices.ContainsFunc(server.messages, func(message firebasePushNotifications.Message) bool {
+			return message.Token == token
+		}) {
+			t.Fatalf("push should be sent to %s, found %v", token, server.messages)
+		}
+	}
+}
+
+func TestUnregisteredTokenIsRemoved(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	deadToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	aliveToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	server.unregistered[deadToken] = true
+	if err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("push to unregistered token should not fail, found err: %v", err)
+	}
+	if len(server.messages) != 1 || server.messages[0].Token != aliveToken {
+		t.Fatalf("push should be delivered to %s, found %v", aliveToken, server.messages)
+	}
+	tokens, err := repository.GetPushTokens(context.Background(), pushNotificationsRepository.UserId(receiver))
+	if err != nil {
+		t.Fatalf("failed to get tokens err: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].Token != aliveToken {
+		t.Fatalf("unregistered token should be removed, found %v", tokens)
+	}
+}
+
+func TestPartiallyDeliveredPushIsRetriedToUndeliveredTokens(t *testing.T) {
+	key := generateKey(t)
+	server := createFcmServer(t, key)
+	repository := memoryPushNotificationsRepository.New(testLogger{t: t})
+	service := createService(t, server, key, repository)
+	receiver := pushNotifications.UserId(uuid.New().String())
+	deliveredToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	failingToken := storeToken(t, repository, receiver, pushNotificationsRepository.PlatformAndroid)
+	server.failing[failingToken] = true
+	err := service.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String()))
+	var undelivered *pushNotifications.UndeliveredError
+	if !errors.As(err, &undelivered) || !slices.Equal(undelivered.Tokens, []string{failingToken}) {
+		t.Fatalf("push should not be delivered to %s only, found err: %v", failingToken, err)
+	}
+	if len(server.messages) != 1 || server.messages[0].Token != deliveredToken {
+		t.Fatalf("push should be delivered to %s, found %v", deliveredToken, server.messages)
+	}
+	server.failing[failingToken] = false
+	if err := service.WithTokens(undelivered.Tokens).FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err != nil {
+		t.Fatalf("failed to send push err: %v", err)
+	}
+	if len(server.messages) != 2 || server.messages[1].Token != failingToken {
+		t.Fatalf("retried push should be delivered only to %s, found %v", failingToken, server.messages)
+	}
+	if server.tokenFetches != 1 {
+		t.Fatalf("access token should be shared with the restricted service, found %d fetches", server.tokenFetches)
+	}
+}
@@ -0,0 +1,148 @@
+package localizedPushContent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	usersRepository "github.com/rzmn/governi/internal/repositories/users"
+	"github.com/rzmn/governi/internal/services/localization"
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+)
+
+type UsersRepository usersRepository.Repository
+
+func New(
+	users UsersRepository,
+	localization localization.Service,
+	logger logging.Service,
+) pushNotifications.ContentProvider {
+	return &localizedProvider{
+		users:        users,
+		localization: localization,
+		logger:       logger,
+	}
+}
+
+type localizedProvider struct {
+	users        UsersRepository
+	localization localization.Service
+	logger       logging.Service
+}
+
+func (c *localizedProvider) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) (pushNotifications.Content, error) {
+	type Payload struct {
+		Target pushNotifications.UserId `json:"t"`
+	}
+	language, err := c.language(receiver)
+	if err != nil {
+		return pushNotifications.Content{}, err
+	}
+	name, err := c.displayName(acceptedBy)
+	if err != nil {
+		return pushNotifications.Content{}, err
+	}
+	body := c.localization.Localize(language, localization.KeyFriendRequestHasBeenAcceptedBody, map[string]string{
+		"name": name,
+	})
+	return pushNotifications.Content{
+		Title: c.localization.Localize(language, localization.KeyFriendRequestHasBeenAcceptedTitle, nil),
+		Body:  &body,
+		Type:  pushNotifications.DataTypeFriendRequestHasBeenAccepted,
+		Payload: Payload{
+			Target: acceptedBy,
+		},
+	}, nil
+}
+
+func (c *localizedProvider) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) (pushNotifications.Content, error) {
+	type Payload struct {
+		Sender pushNotifications.UserId `json:"s"`
+	}
+	language, err := c.language(receiver)
+	if err != nil {
+		return pushNotifications.Content{}, err
+	}
+	name, err := c.displayName(sentBy)
+	if err != nil {
+		return pushNotifications.Content{}, err
+	}
+	body := c.localization.Localize(language, localization.KeyGotFriendRequestBody, map[string]string{
+		"name": name,
+	})
+	return pushNotifications.Content{
+		Title: c.localization.Localize(language, localization.KeyGotFriendRequestTitle, nil),
+		Body:  &body,
+		Type:  pushNotifications.DataTypeGotFriendRequest,
+		Payload: Payload{
+			Sender: sentBy,
+		},
+	}, nil
+}
+
+func (c *localizedProvider) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) (pushNotifications.Content, error) {
+	type Payload struct {
+		DealId   pushNotifications.ExpenseId `json:"d"`
+		AuthorId pushNotifications.UserId    `json:"u"`
+		Cost     pushNotifications.Cost      `json:"c"`
+	}
+	language, err := c.language(receiver)
+	if err != nil {
+		return pushNotifications.Content{}, err
+	}
+	name, err := c.displayName(author)
+	if err != nil {
+		return pushNotifications.Content{}, err
+	}
+	cost := expense.Total
+	for i := 0; i < len(expense.Shares); i++ {
+		if pushNotifications.UserId(expense.Shares[i].UserId) == receiver {
+			cost = expense.Shares[i].Cost
+		}
+	}
+	body := c.localization.Localize(language, localization.KeyNewExpenseReceivedBody, map[string]string{
+		"details":  expense.Details,
+		"cost":     strconv.FormatInt(int64(expense.Total), 10),
+		"currency": string(expense.Currency),
+	})
+	return pushNotifications.Content{
+		Title: c.localization.Localize(language, localization.KeyNewExpenseReceivedTitle, map[string]string{
+			"name": name,
+		}),
+		Body: &body,
+		Type: pushNotifications.DataTypeNewExpenseReceived,
+		Payload: Payload{
+			DealId:   pushNotifications.ExpenseId(expense.Id),
+			AuthorId: author,
+			Cost:     pushNotifications.Cost(cost),
+		},
+	}, nil
+}
+
+func (c *localizedProvider) language(receiver pushNotifications.UserId) (localization.Language, error) {
+	const op = "localizedPushContent.localizedProvider.language"
+	language, err := c.users.GetLanguage(context.Background(), usersRepository.UserId(receiver))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get receiver language err: %v", op, err)
+		return "", fmt.Errorf("getting receiver language: %w", err)
+	}
+	if language == nil {
+		return localization.DefaultLanguage, nil
+	}
+	return localization.Language(*language), nil
+}
+
+func (c *localizedProvider) displayName(uid pushNotifications.UserId) (string, error) {
+	const op = "localizedPushContent.localizedProvider.displayName"
+	users, err := c.users.GetUsers(context.Background(), []usersRepository.UserId{usersRepository.UserId(uid)})
+	if err != nil {
+		c.logger.LogInfo("%s: cannot get user info err: %v", op, err)
+		return "", fmt.Errorf("getting display name: %w", err)
+	}
+	if len(users) == 0 {
+		return "", errors.New("no such user exists")
+	}
+	return users[0].DisplayName, nil
+}
@@ -0,0 +1,120 @@
+package localizedPushContent_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	usersRepository "github.com/rzmn/governi/internal/repositories/users"
+	memoryUsersRepository "github.com/rzmn/governi/internal/repositories/users/memory"
+	"github.com/rzmn/governi/internal/schema"
+	defaultLocalization "github.com/rzmn/governi/internal/services/localization/default"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+	localizedPushContent "github.com/rzmn/governi/internal/services/pushNotifications/localized"
+
+	"github.com/google/uuid"
+)
+
+func createProvider(t *testing.T) (pushNotifications.ContentProvider, usersRepository.Repository) {
+	logger := standartOutputLoggingService.New()
+	localization, err := defaultLocalization.New(logger)
+	if err != nil {
+		t.Fatalf("failed to load catalogs err: %v", err)
+	}
+	users := memoryUsersRepository.New(logger)
+	return localizedPushContent.New(users, localization, logger), users
+}
+
+func storeUser(t *testing.T, users usersRepository.Repository, name string, language *usersRepository.Language) pushNotifications.UserId {
+	id := usersRepository.UserId(uuid.New().String())
+	if err := users.StoreUser(context.Background(), usersRepository.User{
+		Id:          id,
+		DisplayName: name,
+	}).Perform(); err != nil {
+		t.Fatalf("failed to store user err: %v", err)
+	}
+	if language != nil {
+		if err := users.UpdateLanguage(context.Background(), *language, id).Perform(); err != nil {
+			t.Fatalf("failed to update language err: %v", err)
+		}
+	}
+	return pushNotifications.UserId(id)
+}
+
+func TestFriendRequestHasBeenReceivedUsesDefaultLanguage(t *testing.T) {
+	provider, users := createProvider(t)
+	receiver := storeUser(t, users, "receiver", nil)
+	sender := storeUser(t, users, "Alice", nil)
+	content, err := provider.FriendRequestHasBeenReceived(receiver, sender)
+	if err != nil {
+		t.Fatalf("failed to build content err: %v", err)
+	}
+	if content.Title != "Got Friend Request" || content.Body == nil || *content.Body != "From: Alice" {
+		t.Fatalf("unexpected content %v", content)
+	}
+	if content.Type != pushNotifications.DataTypeGotFriendRequest {
+		t.Fatalf("unexpected data type %d", content.Type)
+	}
+}
+
+func TestFriendRequestHasBeenAcceptedUsesReceiverLanguage(t *testing.T) {
+	provider, users := createProvider(t)
+	russian := usersRepository.Language("ru-RU")
+	receiver := storeUser(t, users, "receiver", &russian)
+	acceptedBy := storeUser(t, users, "Алиса", nil)
+	content, err := provider.FriendRequestHasBeenAccepted(receiver, acceptedBy)
+	if err != nil {
+		t.Fatalf("failed to build content err: %v", err)
+	}
+	if content.Title != "Заявка в друзья принята" || content.Body == nil || *content.Body != "Алиса принимает вашу заявку в друзья" {
+		t.Fatalf("unexpected content %v", content)
+	}
+}
+
+func TestNewExpenseReceived(t *testing.T) {
+	provider, users := createProvider(t)
+	receiver := storeUser(t, users, "receiver", nil)
+	author := storeUser(t, users, "Bob", nil)
+	content, err := provider.NewExpenseReceived(receiver, pushNotifications.Expense{
+		Id: "expense",
+		Expense: schema.Expense{
+			Details:  "dinner",
+			Total:    300,
+			Currency: "USD",
+			Shares: []schema.ShareOfExpense{
+				{UserId: schema.UserId(receiver), Cost: 100},
+				{UserId: schema.UserId(author), Cost: 200},
+			},
+		},
+	}, author)
+	if err != nil {
+		t.Fatalf("failed to build content err: %v", err)
+	}
+	if content.Title != "New expense from Bob" || content.Body == nil || *content.Body != "dinner: 300 USD" {
+		t.Fatalf("unexpected content %v", content)
+	}
+	data, err := json.Marshal(content.Payload)
+	if err != nil {
+		t.Fatalf("failed to encode payload err: %v", err)
+	}
+	var payload struct {
+		DealId   string `json:"d"`
+		AuthorId string `json:"u"`
+		Cost     int64  `json:"c"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("failed to decode payload err: %v", err)
+	}
+	if payload.DealId != "expense" || payload.AuthorId != string(author) || payload.Cost != 100 {
+		t.Fatalf("unexpected payload %v", payload)
+	}
+}
+
+func TestUnknownSenderFailed(t *testing.T) {
+	provider, users := createProvider(t)
+	receiver := storeUser(t, users, "receiver", nil)
+	if _, err := provider.FriendRequestHasBeenReceived(receiver, pushNotifications.UserId(uuid.New().String())); err == nil {
+		t.Fatalf("content for unknown sender should be failed")
+	}
+}
@@ -3,19 +3,42 @@ package pushNotifications_mock
 import "github.com/rzmn/governi/internal/services/pushNotifications"
 
 type ServiceMock struct {
-	FriendRequestHasBeenAcceptedImpl func(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId)
-	FriendRequestHasBeenReceivedImpl func(receiver pushNotifications.UserId, sentBy pushNotifications.UserId)
-	NewExpenseReceivedImpl           func(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId)
+	FriendRequestHasBeenAcceptedImpl func(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) error
+	FriendRequestHasBeenReceivedImpl func(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) error
+	NewExpenseReceivedImpl           func(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) error
+	WithTokensImpl                   func(tokens []string) pushNotifications.Service
 }
 
-func (c *ServiceMock) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) {
-	c.FriendRequestHasBeenAcceptedImpl(receiver, acceptedBy)
+func (c *ServiceMock) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) error {
+	return c.FriendRequestHasBeenAcceptedImpl(receiver, acceptedBy)
 }
 
-func (c *ServiceMock) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) {
-	c.FriendRequestHasBeenReceivedImpl(receiver, sentBy)
+func (c *ServiceMock) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) error {
+	return c.FriendRequestHasBeenReceivedImpl(receiver, sentBy)
 }
 
-func (c *ServiceMock) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) {
-	c.NewExpenseReceivedImpl(receiver, expense, author)
+func (c *ServiceMock) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) error {
+	return c.NewExpenseReceivedImpl(receiver, expense, author)
+}
+
+func (c *ServiceMock) WithTokens(tokens []string) pushNotifications.Service {
+	return c.WithTokensImpl(tokens)
+}
+
+type ContentProviderMock struct {
+	FriendRequestHasBeenAcceptedImpl func(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) (pushNotifications.Content, error)
+	FriendRequestHasBeenReceivedImpl func(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) (pushNotifications.Content, error)
+	NewExpenseReceivedImpl           func(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) (pushNotifications.Content, error)
+}
+
+func (c *ContentProviderMock) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) (pushNotifications.Content, error) {
+	return c.FriendRequestHasBeenAcceptedImpl(receiver, acceptedBy)
+}
+
+func (c *ContentProviderMock) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) (pushNotifications.Content, error) {
+	return c.FriendRequestHasBeenReceivedImpl(receiver, sentBy)
+}
+
+func (c *ContentProviderMock) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) (pushNotifications.Content, error) {
+	return c.NewExpenseReceivedImpl(receiver, expense, author)
 }
@@ -0,0 +1,74 @@
+package multiplatformPushNotifications
+
+import (
+	"errors"
+
+	"github.com/rzmn/governi/internal/services/pushNotifications"
+)
+
+// New sends every push through all of the services, each of them delivers to the tokens of its own platform.
+func New(services []pushNotifications.Service) pushNotifications.Service {
+	return &multiplatformService{
+		services: services,
+	}
+}
+
+type multiplatformService struct {
+	services []pushNotifications.Service
+}
+
+func (c *multiplatformService) FriendRequestHasBeenAccepted(receiver pushNotifications.UserId, acceptedBy pushNotifications.UserId) error {
+	return c.forEach(func(service pushNotifications.Service) error {
+		return service.FriendRequestHasBeenAccepted(receiver, acceptedBy)
+	})
+}
+
+func (c *multiplatformService) FriendRequestHasBeenReceived(receiver pushNotifications.UserId, sentBy pushNotifications.UserId) error {
+	return c.forEach(func(service pushNotifications.Service) error {
+		return service.FriendRequestHasBeenReceived(receiver, sentBy)
+	})
+}
+
+func (c *multiplatformService) NewExpenseReceived(receiver pushNotifications.UserId, expense pushNotifications.Expense, author pushNotifications.UserId) error {
+	return c.forEach(func(service pushNotifications.Service) error {
+		return service.NewExpenseReceived(receiver, expense, author)
+	})
+}
+
+func (c *multiplatformService) WithTokens(tokens []string) pushNotifications.Service {
+	services := make([]pushNotifications.Service, len(c.services))
+	for i, service := range c.services {
+		services[i] = service.WithTokens(tokens)
+	}
+	return New(services)
+}
+
+// forEach reports the undelivered tokens of all services together when each failure names them,
+// a failure that does not name them means that the push should be sent again to every token.
+func (c *multiplatformService) forEach(send func(service pushNotifications.Service) error) error {
+	errs := []error{}
+	undelivered := []string{}
+	for _, service := range c.services {
+		err := send(service)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		var undeliveredErr *pushNotifications.UndeliveredError
+		if undelivered != nil && errors.As(err, &undeliveredErr) {
+			undelivered = append(undelivered, undeliveredErr.Tokens...)
+		} else {
+			undelivered = nil
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if undelivered == nil {
+		return errors.Join(errs...)
+	}
+	return &pushNotifications.UndeliveredError{
+		Tokens: undelivered,
+		Err:    errors.Join(errs...),
+	}
+}
@@ -1,6 +1,8 @@
 package pushNotifications
 
 import (
+	"fmt"
+
 	"github.com/rzmn/governi/internal/schema"
 )
 
@@ -10,7 +12,25 @@ type ExpenseId schema.ExpenseId
 type Cost schema.Cost
 
 type Service interface {
-	FriendRequestHasBeenAccepted(receiver UserId, acceptedBy UserId)
-	FriendRequestHasBeenReceived(receiver UserId, sentBy UserId)
-	NewExpenseReceived(receiver UserId, expense Expense, author UserId)
+	FriendRequestHasBeenAccepted(receiver UserId, acceptedBy UserId) error
+	FriendRequestHasBeenReceived(receiver UserId, sentBy UserId) error
+	NewExpenseReceived(receiver UserId, expense Expense, author UserId) error
+
+	// WithTokens returns a service that delivers pushes only to the given push tokens of the receiver.
+	WithTokens(tokens []string) Service
+}
+
+// UndeliveredError is returned when a push was not delivered to some of the receiver's push tokens,
+// the push was delivered to the rest of them.
+type UndeliveredError struct {
+	Tokens []string
+	Err    error
+}
+
+func (e *UndeliveredError) Error() string {
+	return fmt.Sprintf("push was not delivered to %d tokens: %v", len(e.Tokens), e.Err)
+}
+
+func (e *UndeliveredError) Unwrap() error {
+	return e.Err
 }
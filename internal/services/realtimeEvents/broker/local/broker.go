@@ -0,0 +1,40 @@
+package localRealtimeBroker
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+)
+
+// New returns a broker for a single server instance.
+func New() realtimeEvents.Broker {
+	return &localBroker{}
+}
+
+type localBroker struct {
+	mutex       sync.RWMutex
+	subscribers []realtimeEvents.Subscriber
+}
+
+func (c *localBroker) Publish(uid realtimeEvents.UserId, category string, event any) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	errs := []error{}
+	for _, subscriber := range c.subscribers {
+		if err := subscriber.Publish(uid, category, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *localBroker) Subscribe(subscriber realtimeEvents.Subscriber) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.subscribers = append(c.subscribers, subscriber)
+}
+
+func (c *localBroker) Close() error {
+	return nil
+}
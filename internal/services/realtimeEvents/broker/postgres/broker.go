@@ -0,0 +1,185 @@
+package postgresRealtimeBroker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rzmn/governi/internal/db"
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+
+	"github.com/lib/pq"
+)
+
+const (
+	channel = "realtime_events"
+	// NOTIFY payloads are limited to 8000 bytes, larger events are passed through the realtimeEvents table.
+	maxNotificationBytes = 7900
+	storedEventTtl       = time.Minute
+	pingInterval         = 90 * time.Second
+)
+
+type notification struct {
+	Uid      realtimeEvents.UserId `json:"uid,omitempty"`
+	Category string                `json:"category,omitempty"`
+	Event    json.RawMessage       `json:"event,omitempty"`
+	StoredId *int64                `json:"storedId,omitempty"`
+}
+
+// New returns a broker that sends events to every instance connected to the same database
+// using LISTEN/NOTIFY, including the publishing one. Notifications sent while the listening
+// connection is down are lost, subscribers are reset once it is reestablished.
+func New(database db.DB, connectionString string, logger logging.Service) (realtimeEvents.Broker, error) {
+	const op = "realtimeEvents.postgresBroker.New"
+	logger.LogInfo("%s: start", op)
+	listener := pq.NewListener(connectionString, 100*time.Millisecond, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.LogError("%s: listener event %d err: %v", op, event, err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		logger.LogInfo("%s: failed to listen %s err: %v", op, channel, err)
+		return nil, fmt.Errorf("listening %s: %w", channel, err)
+	}
+	broker := &postgresBroker{
+		db:       database,
+		listener: listener,
+		logger:   logger,
+	}
+	go broker.receive()
+	logger.LogInfo("%s: success", op)
+	return broker, nil
+}
+
+type postgresBroker struct {
+	db          db.DB
+	listener    *pq.Listener
+	mutex       sync.RWMutex
+	subscribers []realtimeEvents.Subscriber
+	logger      logging.Service
+}
+
+func (c *postgresBroker) Publish(uid realtimeEvents.UserId, category string, event any) error {
+	const op = "realtimeEvents.postgresBroker.Publish"
+	c.logger.LogInfo("%s: start[uid=%s category=%s]", op, uid, category)
+	data, err := json.Marshal(event)
+	if err != nil {
+		c.logger.LogError("%s: failed to serialize event err: %v", op, err)
+		return err
+	}
+	payload, err := json.Marshal(notification{
+		Uid:      uid,
+		Category: category,
+		Event:    data,
+	})
+	if err != nil {
+		c.logger.LogError("%s: failed to serialize notification err: %v", op, err)
+		return err
+	}
+	if len(payload) > maxNotificationBytes {
+		payload, err = c.store(payload)
+		if err != nil {
+			c.logger.LogError("%s: failed to store event err: %v", op, err)
+			return err
+		}
+	}
+	if _, err := c.db.ExecContext(context.Background(), `SELECT pg_notify($1, $2);`, channel, string(payload)); err != nil {
+		c.logger.LogError("%s: failed to notify err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s category=%s]", op, uid, category)
+	return nil
+}
+
+func (c *postgresBroker) store(payload []byte) ([]byte, error) {
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM realtimeEvents WHERE createdAt < $1;`, now.Add(-storedEventTtl).Unix()); err != nil {
+		return nil, fmt.Errorf("removing expired events: %w", err)
+	}
+	var id int64
+	if err := c.db.QueryRowContext(
+		ctx,
+		`INSERT INTO realtimeEvents(payload, createdAt) VALUES ($1, $2) RETURNING id;`,
+		string(payload),
+		now.Unix(),
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("inserting event: %w", err)
+	}
+	return json.Marshal(notification{
+		StoredId: &id,
+	})
+}
+
+func (c *postgresBroker) Subscribe(subscriber realtimeEvents.Subscriber) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.subscribers = append(c.subscribers, subscriber)
+}
+
+func (c *postgresBroker) Close() error {
+	return c.listener.Close()
+}
+
+func (c *postgresBroker) receive() {
+	const op = "realtimeEvents.postgresBroker.receive"
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case received, ok := <-c.listener.Notify:
+			if !ok {
+				return
+			}
+			if received == nil {
+				c.logger.LogInfo("%s: reconnected, resetting subscribers as events published meanwhile are lost", op)
+				c.reset()
+				continue
+			}
+			c.deliver(received.Extra)
+		case <-ticker.C:
+			go c.listener.Ping()
+		}
+	}
+}
+
+func (c *postgresBroker) reset() {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, subscriber := range c.subscribers {
+		subscriber.Reset()
+	}
+}
+
+func (c *postgresBroker) deliver(payload string) {
+	const op = "realtimeEvents.postgresBroker.deliver"
+	var message notification
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		c.logger.LogError("%s: failed to parse notification err: %v", op, err)
+		return
+	}
+	if message.StoredId != nil {
+		if err := c.db.QueryRowContext(
+			context.Background(),
+			`SELECT payload FROM realtimeEvents WHERE id = $1;`,
+			*message.StoredId,
+		).Scan(&payload); err != nil {
+			c.logger.LogError("%s: failed to get stored event %d err: %v", op, *message.StoredId, err)
+			return
+		}
+		message = notification{}
+		if err := json.Unmarshal([]byte(payload), &message); err != nil {
+			c.logger.LogError("%s: failed to parse stored event err: %v", op, err)
+			return
+		}
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, subscriber := range c.subscribers {
+		subscriber.Publish(message.Uid, message.Category, message.Event)
+	}
+}
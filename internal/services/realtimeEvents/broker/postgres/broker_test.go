@@ -0,0 +1,180 @@
+package postgresRealtimeBroker_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rzmn/governi/internal/db"
+	postgresDb "github.com/rzmn/governi/internal/db/postgres"
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/logging"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	envBasedPathProvider "github.com/rzmn/governi/internal/services/pathProvider/env"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+	postgresRealtimeBroker "github.com/rzmn/governi/internal/services/realtimeEvents/broker/postgres"
+	defaultRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/default"
+	ginSseRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/sse"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type instance struct {
+	database db.DB
+	service  realtimeEvents.Service
+	server   *httptest.Server
+}
+
+func openDatabase(t *testing.T, logger logging.Service) (db.DB, string) {
+	if os.Getenv("VERNI_TEST_STORAGE") != "postgres" {
+		t.Skip("set VERNI_TEST_STORAGE=postgres to run against the database")
+	}
+	pathProvider := envBasedPathProvider.New(logger)
+	configFile, err := os.Open(pathProvider.AbsolutePath("./config/test/postgres_storage.json"))
+	if err != nil {
+		t.Fatalf("failed to open config file: %s", err)
+	}
+	defer configFile.Close()
+	configData, err := io.ReadAll(configFile)
+	if err != nil {
+		t.Fatalf("failed to read config file: %s", err)
+	}
+	var config postgresDb.PostgresConfig
+	json.Unmarshal([]byte(configData), &config)
+	database, err := postgresDb.Postgres(config, logger)
+	if err != nil {
+		t.Fatalf("failed to init db err: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database, postgresDb.ConnectionString(config)
+}
+
+func startInstance(t *testing.T, database db.DB, connectionString string, logger logging.Service) instance {
+	broker, err := postgresRealtimeBroker.New(database, connectionString, logger)
+	if err != nil {
+		t.Fatalf("failed to create broker err: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	broker.Subscribe(ginSseRealtimeEvents.New(
+		router,
+		logger,
+		func(c *gin.Context) {
+			c.Next()
+		},
+		func(c *gin.Context) realtimeEvents.UserId {
+			return realtimeEvents.UserId(c.Query("uid"))
+		},
+	))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return instance{
+		database: database,
+		service:  defaultRealtimeEvents.New([]realtimeEvents.Publisher{broker}, logger),
+		server:   server,
+	}
+}
+
+func startInstances(t *testing.T) (instance, instance) {
+	logger := standartOutputLoggingService.New()
+	database, connectionString := openDatabase(t, logger)
+	return startInstance(t, database, connectionString, logger), startInstance(t, database, connectionString, logger)
+}
+
+// nextEvent connects to the event stream of uid and returns the type and data of the first event received.
+func nextEvent(t *testing.T, server *httptest.Server, uid realtimeEvents.UserId, publish func()) (string, string) {
+	client := http.Client{Timeout: 10 * time.Second}
+	response, err := client.Get(server.URL + "/events/stream?uid=" + string(uid))
+	if err != nil {
+		t.Fatalf("failed to connect err: %v", err)
+	}
+	defer response.Body.Close()
+	reader := bufio.NewReader(response.Body)
+	reader.ReadString('\n')
+	reader.ReadString('\n')
+	publish()
+	var eventType string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event err: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if value, ok := strings.CutPrefix(line, "event: "); ok {
+			eventType = value
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			return eventType, data
+		}
+	}
+}
+
+// nextEventData connects to the event stream of uid and returns data of the first event received.
+func nextEventData(t *testing.T, server *httptest.Server, uid realtimeEvents.UserId, publish func()) string {
+	_, data := nextEvent(t, server, uid, publish)
+	return data
+}
+
+func TestEventReachesOtherInstance(t *testing.T) {
+	first, second := startInstances(t)
+	uid := realtimeEvents.UserId(uuid.New().String())
+	sender := schema.UserId(uuid.New().String())
+	data := nextEventData(t, second.server, uid, func() {
+		first.service.FriendRequestReceived(uid, schema.FriendRequestReceivedEvent{Sender: sender})
+	})
+	var event schema.Event[schema.FriendRequestReceivedEvent]
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("failed to decode event err: %v", err)
+	}
+	if event.Type != schema.EventTypeFriendRequestReceived || event.Payload.Sender != sender {
+		t.Fatalf("unexpected event: %s", data)
+	}
+}
+
+func TestLargeEventReachesOtherInstance(t *testing.T) {
+	first, second := startInstances(t)
+	uid := realtimeEvents.UserId(uuid.New().String())
+	details := strings.Repeat("x", 10000)
+	data := nextEventData(t, second.server, uid, func() {
+		first.service.ExpenseAdded(uid, schema.ExpenseAddedEvent{
+			Counterparty: schema.UserId(uuid.New().String()),
+			Expense: schema.IdentifiableExpense{
+				Expense: schema.Expense{
+					Details: details,
+				},
+			},
+		})
+	})
+	var event schema.Event[schema.ExpenseAddedEvent]
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("failed to decode event err: %v", err)
+	}
+	if event.Payload.Expense.Details != details {
+		t.Fatalf("large event should be delivered intact")
+	}
+}
+
+func TestReconnectResetsSubscribers(t *testing.T) {
+	first, _ := startInstances(t)
+	uid := realtimeEvents.UserId(uuid.New().String())
+	eventType, _ := nextEvent(t, first.server, uid, func() {
+		if _, err := first.database.ExecContext(
+			context.Background(),
+			`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query LIKE 'LISTEN %';`,
+		); err != nil {
+			t.Fatalf("failed to terminate listening connections err: %v", err)
+		}
+	})
+	if eventType != ginSseRealtimeEvents.ResetEvent {
+		t.Fatalf("subscribers should be reset after reconnect, found %s", eventType)
+	}
+}
@@ -0,0 +1,65 @@
+package defaultRealtimeEvents
+
+import (
+	"errors"
+
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+)
+
+func New(
+	publishers []realtimeEvents.Publisher,
+	logger logging.Service,
+) realtimeEvents.Service {
+	return &defaultService{
+		publishers: publishers,
+		logger:     logger,
+	}
+}
+
+type defaultService struct {
+	publishers []realtimeEvents.Publisher
+	logger     logging.Service
+}
+
+func (c *defaultService) ExpenseAdded(uid realtimeEvents.UserId, event schema.ExpenseAddedEvent) error {
+	return c.publish(uid, realtimeEvents.ExpensesCategory(uid, realtimeEvents.UserId(event.Counterparty)), schema.NewEvent(schema.EventTypeExpenseAdded, event))
+}
+
+func (c *defaultService) ExpenseRemoved(uid realtimeEvents.UserId, event schema.ExpenseRemovedEvent) error {
+	return c.publish(uid, realtimeEvents.ExpensesCategory(uid, realtimeEvents.UserId(event.Counterparty)), schema.NewEvent(schema.EventTypeExpenseRemoved, event))
+}
+
+func (c *defaultService) BalanceChanged(uid realtimeEvents.UserId, event schema.BalanceChangedEvent) error {
+	return c.publish(uid, realtimeEvents.CounterpartiesCategory(uid), schema.NewEvent(schema.EventTypeBalanceChanged, event))
+}
+
+func (c *defaultService) FriendRequestReceived(uid realtimeEvents.UserId, event schema.FriendRequestReceivedEvent) error {
+	return c.publish(uid, realtimeEvents.FriendsCategory(uid), schema.NewEvent(schema.EventTypeFriendRequestReceived, event))
+}
+
+func (c *defaultService) FriendStatusChanged(uid realtimeEvents.UserId, event schema.FriendStatusChangedEvent) error {
+	return c.publish(uid, realtimeEvents.FriendsCategory(uid), schema.NewEvent(schema.EventTypeFriendStatusChanged, event))
+}
+
+func (c *defaultService) ProfileChanged(uid realtimeEvents.UserId, event schema.ProfileChangedEvent) error {
+	return c.publish(uid, realtimeEvents.ProfileCategory(uid), schema.NewEvent(schema.EventTypeProfileChanged, event))
+}
+
+func (c *defaultService) publish(uid realtimeEvents.UserId, category string, event any) error {
+	const op = "realtimeEvents.defaultService.publish"
+	c.logger.LogInfo("%s: start[uid=%s category=%s]", op, uid, category)
+	errs := []error{}
+	for _, publisher := range c.publishers {
+		if err := publisher.Publish(uid, category, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		c.logger.LogInfo("%s: failed to publish err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s category=%s]", op, uid, category)
+	return nil
+}
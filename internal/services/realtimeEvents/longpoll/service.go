@@ -1,8 +1,11 @@
 package ginLongpollRealtimeEvents
 
 import (
-	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
+	"github.com/rzmn/governi/internal/schema"
 	"github.com/rzmn/governi/internal/services/logging"
 	"github.com/rzmn/governi/internal/services/realtimeEvents"
 
@@ -10,11 +13,15 @@ import (
 	"github.com/jcuga/golongpoll"
 )
 
+// categoryTtl is how long a category is remembered after its last poll to be reset.
+const categoryTtl = 10 * time.Minute
+
 func New(
 	e *gin.Engine,
 	logger logging.Service,
 	accessTokenMiddleware gin.HandlerFunc,
-) realtimeEvents.Service {
+	accessToken func(c *gin.Context) realtimeEvents.UserId,
+) realtimeEvents.Subscriber {
 	op := "realtimeEvents.GinService"
 	logger.LogInfo("%s: start", op)
 	longpoll, err := golongpoll.StartLongpoll(golongpoll.Options{})
@@ -23,46 +30,79 @@ func New(
 		return &ginService{}
 	}
 	logger.LogInfo("%s: success", op)
+	service := &ginService{
+		longPoll:   longpoll,
+		categories: map[string]time.Time{},
+		logger:     logger,
+	}
 	e.GET("/queue/subscribe", accessTokenMiddleware, func(c *gin.Context) {
+		category := c.Query("category")
+		owner, ok := realtimeEvents.CategoryOwner(category)
+		if !ok || owner != accessToken(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, schema.Failure(nil, schema.CodeSubscriptionForbidden))
+			return
+		}
+		service.polled(category)
 		longpoll.SubscriptionHandler(c.Writer, c.Request)
 	})
-	return &ginService{
-		longPoll: longpoll,
-		logger:   logger,
-	}
+	go service.removeStaleCategories()
+	return service
 }
 
 type ginService struct {
-	longPoll *golongpoll.LongpollManager
-	logger   logging.Service
+	longPoll   *golongpoll.LongpollManager
+	mutex      sync.Mutex
+	categories map[string]time.Time
+	logger     logging.Service
 }
 
-func (c *ginService) CounterpartiesUpdated(uid realtimeEvents.UserId) {
-	op := "longpoll.CounterpartiesUpdated"
-	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	type Payload struct{}
-	key := fmt.Sprintf("counterparties_%s", uid)
-	payload := Payload{}
-	c.longPoll.Publish(key, payload)
-	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+func (c *ginService) Publish(uid realtimeEvents.UserId, category string, event any) error {
+	op := "longpoll.Publish"
+	c.logger.LogInfo("%s: start[uid=%s, category=%s]", op, uid, category)
+	if err := c.longPoll.Publish(category, event); err != nil {
+		c.logger.LogError("%s: failed err: %v", op, err)
+		return err
+	}
+	c.logger.LogInfo("%s: success[uid=%s, category=%s]", op, uid, category)
+	return nil
 }
 
-func (c *ginService) ExpensesUpdated(uid realtimeEvents.UserId, counterparty realtimeEvents.UserId) {
-	op := "longpoll.ExpensesUpdated"
-	c.logger.LogInfo("%s: start[uid=%s, cid=%s]", op, uid, counterparty)
-	type Payload struct{}
-	key := fmt.Sprintf("spendings_%s_%s", uid, counterparty)
-	payload := Payload{}
-	c.longPoll.Publish(key, payload)
-	c.logger.LogInfo("%s: success[uid=%s, cid=%s]", op, uid, counterparty)
+// Reset publishes schema.ResetEvent to every recently polled category.
+func (c *ginService) Reset() {
+	op := "longpoll.Reset"
+	c.logger.LogInfo("%s: start", op)
+	c.mutex.Lock()
+	categories := make([]string, 0, len(c.categories))
+	for category := range c.categories {
+		categories = append(categories, category)
+	}
+	c.mutex.Unlock()
+	reset := schema.NewEvent(schema.EventTypeReset, schema.ResetEvent{})
+	for _, category := range categories {
+		if err := c.longPoll.Publish(category, reset); err != nil {
+			c.logger.LogError("%s: failed to reset %s err: %v", op, category, err)
+		}
+	}
+	c.logger.LogInfo("%s: success", op)
+}
+
+func (c *ginService) polled(category string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.categories[category] = time.Now()
 }
 
-func (c *ginService) FriendsUpdated(uid realtimeEvents.UserId) {
-	op := "longpoll.FriendsUpdated"
-	c.logger.LogInfo("%s: start[uid=%s]", op, uid)
-	type Payload struct{}
-	key := fmt.Sprintf("friends_%s", uid)
-	payload := Payload{}
-	c.longPoll.Publish(key, payload)
-	c.logger.LogInfo("%s: success[uid=%s]", op, uid)
+func (c *ginService) removeStaleCategories() {
+	ticker := time.NewTicker(categoryTtl)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.mutex.Lock()
+		expired := time.Now().Add(-categoryTtl)
+		for category, polledAt := range c.categories {
+			if polledAt.Before(expired) {
+				delete(c.categories, category)
+			}
+		}
+		c.mutex.Unlock()
+	}
 }
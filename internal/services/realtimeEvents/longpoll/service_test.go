@@ -0,0 +1,131 @@
+package ginLongpollRealtimeEvents_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rzmn/governi/internal/schema"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+	defaultRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/default"
+	ginLongpollRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/longpoll"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createSubscriber(t *testing.T) (realtimeEvents.Subscriber, *httptest.Server) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	subscriber := ginLongpollRealtimeEvents.New(
+		router,
+		standartOutputLoggingService.New(),
+		func(c *gin.Context) {
+			if c.Query("uid") == "" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.Next()
+		},
+		func(c *gin.Context) realtimeEvents.UserId {
+			return realtimeEvents.UserId(c.Query("uid"))
+		},
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return subscriber, server
+}
+
+func createServer(t *testing.T) (realtimeEvents.Service, *httptest.Server) {
+	subscriber, server := createSubscriber(t)
+	return defaultRealtimeEvents.New([]realtimeEvents.Publisher{subscriber}, standartOutputLoggingService.New()), server
+}
+
+func subscribe(t *testing.T, server *httptest.Server, uid string, category string) *http.Response {
+	query := url.Values{}
+	query.Set("uid", uid)
+	query.Set("category", category)
+	query.Set("timeout", "1")
+	query.Set("since_time", "1")
+	response, err := http.Get(server.URL + "/queue/subscribe?" + query.Encode())
+	if err != nil {
+		t.Fatalf("failed to subscribe, err: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func TestSubscribeToOwnCategory(t *testing.T) {
+	service, server := createServer(t)
+	service.FriendRequestReceived("alice", schema.FriendRequestReceivedEvent{Sender: "bob"})
+	response := subscribe(t, server, "alice", realtimeEvents.FriendsCategory("alice"))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("subscription to own category should be allowed, found %d", response.StatusCode)
+	}
+	var result struct {
+		Events []struct {
+			Category string                                          `json:"category"`
+			Data     schema.Event[schema.FriendRequestReceivedEvent] `json:"data"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response, err: %v", err)
+	}
+	if len(result.Events) != 1 || result.Events[0].Data.Payload.Sender != "bob" {
+		t.Fatalf("should receive published event, found %v", result.Events)
+	}
+}
+
+func TestSubscribeToOtherUserCategory(t *testing.T) {
+	service, server := createServer(t)
+	service.FriendRequestReceived("bob", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	for _, category := range []string{
+		realtimeEvents.FriendsCategory("bob"),
+		realtimeEvents.CounterpartiesCategory("bob"),
+		realtimeEvents.ExpensesCategory("bob", "alice"),
+		realtimeEvents.ProfileCategory("bob"),
+	} {
+		response := subscribe(t, server, "alice", category)
+		if response.StatusCode != http.StatusForbidden {
+			t.Fatalf("subscription to %s should be rejected, found %d", category, response.StatusCode)
+		}
+		var result schema.Response[schema.Error]
+		if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+			t.Fatalf("failed to decode response, err: %v", err)
+		}
+		if result.Response.Code != schema.CodeSubscriptionForbidden {
+			t.Fatalf("unexpected error code %v", result.Response.Code)
+		}
+	}
+}
+
+func TestSubscribeToUnknownCategory(t *testing.T) {
+	_, server := createServer(t)
+	for _, category := range []string{"", "alice", "spendings_alice", "unknown_alice"} {
+		response := subscribe(t, server, "alice", category)
+		if response.StatusCode != http.StatusForbidden {
+			t.Fatalf("subscription to %q should be rejected, found %d", category, response.StatusCode)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	subscriber, server := createSubscriber(t)
+	category := realtimeEvents.FriendsCategory("alice")
+	subscribe(t, server, "alice", category)
+	subscriber.Reset()
+	response := subscribe(t, server, "alice", category)
+	var result struct {
+		Events []struct {
+			Data schema.Event[schema.ResetEvent] `json:"data"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response, err: %v", err)
+	}
+	if len(result.Events) != 1 || result.Events[0].Data.Type != schema.EventTypeReset {
+		t.Fatalf("polled category should receive reset event, found %v", result.Events)
+	}
+}
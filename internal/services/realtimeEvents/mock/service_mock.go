@@ -0,0 +1,39 @@
+package realtimeEvents_mock
+
+import (
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+)
+
+type ServiceMock struct {
+	ExpenseAddedImpl          func(uid realtimeEvents.UserId, event schema.ExpenseAddedEvent) error
+	ExpenseRemovedImpl        func(uid realtimeEvents.UserId, event schema.ExpenseRemovedEvent) error
+	BalanceChangedImpl        func(uid realtimeEvents.UserId, event schema.BalanceChangedEvent) error
+	FriendRequestReceivedImpl func(uid realtimeEvents.UserId, event schema.FriendRequestReceivedEvent) error
+	FriendStatusChangedImpl   func(uid realtimeEvents.UserId, event schema.FriendStatusChangedEvent) error
+	ProfileChangedImpl        func(uid realtimeEvents.UserId, event schema.ProfileChangedEvent) error
+}
+
+func (c *ServiceMock) ExpenseAdded(uid realtimeEvents.UserId, event schema.ExpenseAddedEvent) error {
+	return c.ExpenseAddedImpl(uid, event)
+}
+
+func (c *ServiceMock) ExpenseRemoved(uid realtimeEvents.UserId, event schema.ExpenseRemovedEvent) error {
+	return c.ExpenseRemovedImpl(uid, event)
+}
+
+func (c *ServiceMock) BalanceChanged(uid realtimeEvents.UserId, event schema.BalanceChangedEvent) error {
+	return c.BalanceChangedImpl(uid, event)
+}
+
+func (c *ServiceMock) FriendRequestReceived(uid realtimeEvents.UserId, event schema.FriendRequestReceivedEvent) error {
+	return c.FriendRequestReceivedImpl(uid, event)
+}
+
+func (c *ServiceMock) FriendStatusChanged(uid realtimeEvents.UserId, event schema.FriendStatusChangedEvent) error {
+	return c.FriendStatusChangedImpl(uid, event)
+}
+
+func (c *ServiceMock) ProfileChanged(uid realtimeEvents.UserId, event schema.ProfileChangedEvent) error {
+	return c.ProfileChangedImpl(uid, event)
+}
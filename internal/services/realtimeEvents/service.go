@@ -1,9 +1,75 @@
 package realtimeEvents
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rzmn/governi/internal/schema"
+)
+
 type UserId string
 
 type Service interface {
-	CounterpartiesUpdated(uid UserId)
-	ExpensesUpdated(uid UserId, counterparty UserId)
-	FriendsUpdated(uid UserId)
+	ExpenseAdded(uid UserId, event schema.ExpenseAddedEvent) error
+	ExpenseRemoved(uid UserId, event schema.ExpenseRemovedEvent) error
+	BalanceChanged(uid UserId, event schema.BalanceChangedEvent) error
+	FriendRequestReceived(uid UserId, event schema.FriendRequestReceivedEvent) error
+	FriendStatusChanged(uid UserId, event schema.FriendStatusChangedEvent) error
+	ProfileChanged(uid UserId, event schema.ProfileChangedEvent) error
+}
+
+// Publisher delivers an event of uid to the clients subscribed to category over some transport.
+type Publisher interface {
+	Publish(uid UserId, category string, event any) error
+}
+
+// Subscriber is a transport receiving events from a Broker. Reset is called when events might
+// have been lost on the way, the transport tells its clients to refetch their state.
+type Subscriber interface {
+	Publisher
+	Reset()
+}
+
+// Broker distributes events published on any server instance to the subscribed
+// transports of every instance.
+type Broker interface {
+	Publisher
+	Subscribe(subscriber Subscriber)
+	Close() error
+}
+
+func CounterpartiesCategory(uid UserId) string {
+	return fmt.Sprintf("counterparties_%s", uid)
+}
+
+func ExpensesCategory(uid UserId, counterparty UserId) string {
+	return fmt.Sprintf("spendings_%s_%s", uid, counterparty)
+}
+
+func FriendsCategory(uid UserId) string {
+	return fmt.Sprintf("friends_%s", uid)
+}
+
+func ProfileCategory(uid UserId) string {
+	return fmt.Sprintf("profile_%s", uid)
+}
+
+// CategoryOwner returns the user whose events are published to category.
+func CategoryOwner(category string) (UserId, bool) {
+	kind, rest, found := strings.Cut(category, "_")
+	if !found || rest == "" {
+		return "", false
+	}
+	switch kind {
+	case "counterparties", "friends", "profile":
+		return UserId(rest), true
+	case "spendings":
+		uid, counterparty, found := strings.Cut(rest, "_")
+		if !found || uid == "" || counterparty == "" {
+			return "", false
+		}
+		return UserId(uid), true
+	default:
+		return "", false
+	}
 }
@@ -0,0 +1,269 @@
+package ginSseRealtimeEvents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	heartbeatInterval = 30 * time.Second
+	writeTimeout      = 10 * time.Second
+	retryMs           = 3000
+	bufferSize        = 100
+	bufferTtl         = 10 * time.Minute
+	sendBufferSize    = 64
+
+	// ResetEvent is sent when the events after Last-Event-ID are no longer buffered or might
+	// have been lost, the client should refetch its state instead of relying on the stream.
+	ResetEvent = "reset"
+)
+
+type event struct {
+	sequence  uint64
+	category  string
+	timestamp time.Time
+	data      any
+}
+
+type stream struct {
+	events []event
+	// evicted is the latest sequence a client can no longer resume after.
+	evicted   uint64
+	listeners map[chan event]struct{}
+}
+
+func New(
+	e *gin.Engine,
+	logger logging.Service,
+	accessTokenMiddleware gin.HandlerFunc,
+	accessToken func(c *gin.Context) realtimeEvents.UserId,
+) realtimeEvents.Subscriber {
+	service := &ginService{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		streams: map[realtimeEvents.UserId]*stream{},
+		logger:  logger,
+	}
+	e.GET("/events/stream", accessTokenMiddleware, func(c *gin.Context) {
+		service.serve(c, accessToken(c))
+	})
+	go service.removeStaleStreams()
+	return service
+}
+
+type ginService struct {
+	epoch    string
+	mutex    sync.Mutex
+	sequence uint64
+	streams  map[realtimeEvents.UserId]*stream
+	logger   logging.Service
+}
+
+func (c *ginService) Publish(uid realtimeEvents.UserId, category string, event any) error {
+	op := "sse.Publish"
+	c.logger.LogInfo("%s: start[uid=%s, category=%s]", op, uid, category)
+	c.publish(uid, category, event)
+	c.logger.LogInfo("%s: success[uid=%s, category=%s]", op, uid, category)
+	return nil
+}
+
+func (c *ginService) publish(uid realtimeEvents.UserId, category string, data any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	userStream := c.stream(uid)
+	c.sequence += 1
+	published := event{
+		sequence:  c.sequence,
+		category:  category,
+		timestamp: time.Now(),
+		data:      data,
+	}
+	userStream.events = append(userStream.events, published)
+	if len(userStream.events) > bufferSize {
+		userStream.evict(len(userStream.events) - bufferSize)
+	}
+	userStream.send(published)
+}
+
+// Reset sends ResetEvent to the connected clients and drops the buffered events, so clients
+// resuming after an earlier event are reset as well.
+func (c *ginService) Reset() {
+	op := "sse.Reset"
+	c.logger.LogInfo("%s: start", op)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sequence += 1
+	reset := event{
+		sequence:  c.sequence,
+		category:  ResetEvent,
+		timestamp: time.Now(),
+		data:      struct{}{},
+	}
+	for _, userStream := range c.streams {
+		userStream.events = nil
+		userStream.evicted = c.sequence
+		userStream.send(reset)
+	}
+	c.logger.LogInfo("%s: success", op)
+}
+
+// send expects c.mutex of the service to be held.
+func (c *stream) send(published event) {
+	for listener := range c.listeners {
+		select {
+		case listener <- published:
+		default:
+			// the client is too slow, it resumes from the buffer after reconnecting
+			delete(c.listeners, listener)
+			close(listener)
+		}
+	}
+}
+
+// subscribe registers a listener for uid and returns buffered events following lastEventId.
+// ok is false when some of these events are no longer buffered, in that case the stream
+// resumes after current.
+func (c *ginService) subscribe(uid realtimeEvents.UserId, lastEventId string) (listener chan event, missed []event, current uint64, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	userStream := c.stream(uid)
+	listener = make(chan event, sendBufferSize)
+	userStream.listeners[listener] = struct{}{}
+	if lastEventId == "" {
+		return listener, nil, c.sequence, true
+	}
+	epoch, sequenceString, found := strings.Cut(lastEventId, "-")
+	sequence, err := strconv.ParseUint(sequenceString, 10, 64)
+	if !found || err != nil || epoch != c.epoch || sequence > c.sequence || sequence < userStream.evicted {
+		return listener, nil, c.sequence, false
+	}
+	for _, buffered := range userStream.events {
+		if buffered.sequence > sequence {
+			missed = append(missed, buffered)
+		}
+	}
+	return listener, missed, c.sequence, true
+}
+
+// stream expects c.mutex to be held.
+func (c *ginService) stream(uid realtimeEvents.UserId) *stream {
+	userStream, ok := c.streams[uid]
+	if !ok {
+		// events published before the stream was created are unknown
+		userStream = &stream{
+			evicted:   c.sequence,
+			listeners: map[chan event]struct{}{},
+		}
+		c.streams[uid] = userStream
+	}
+	return userStream
+}
+
+func (c *stream) evict(count int) {
+	if count == 0 {
+		return
+	}
+	c.evicted = c.events[count-1].sequence
+	c.events = c.events[count:]
+}
+
+func (c *ginService) unsubscribe(uid realtimeEvents.UserId, listener chan event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	userStream, ok := c.streams[uid]
+	if !ok {
+		return
+	}
+	if _, ok := userStream.listeners[listener]; ok {
+		delete(userStream.listeners, listener)
+		close(listener)
+	}
+}
+
+func (c *ginService) removeStaleStreams() {
+	ticker := time.NewTicker(bufferTtl)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.mutex.Lock()
+		expired := time.Now().Add(-bufferTtl)
+		for uid, userStream := range c.streams {
+			count := 0
+			for count < len(userStream.events) && userStream.events[count].timestamp.Before(expired) {
+				count += 1
+			}
+			userStream.evict(count)
+			if len(userStream.events) == 0 && len(userStream.listeners) == 0 {
+				delete(c.streams, uid)
+			}
+		}
+		c.mutex.Unlock()
+	}
+}
+
+func (c *ginService) serve(ctx *gin.Context, uid realtimeEvents.UserId) {
+	op := "sse.serve"
+	c.logger.LogInfo("%s: connected[uid=%s]", op, uid)
+	defer c.logger.LogInfo("%s: disconnected[uid=%s]", op, uid)
+	listener, missed, current, ok := c.subscribe(uid, ctx.GetHeader("Last-Event-ID"))
+	defer c.unsubscribe(uid, listener)
+
+	controller := http.NewResponseController(ctx.Writer)
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	write := func(message string) bool {
+		controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := ctx.Writer.WriteString(message); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+	if !write(fmt.Sprintf("retry: %d\n\n", retryMs)) {
+		return
+	}
+	if !ok && !write(fmt.Sprintf("id: %s-%d\nevent: %s\ndata: {}\n\n", c.epoch, current, ResetEvent)) {
+		return
+	}
+	for _, buffered := range missed {
+		if !write(c.format(buffered)) {
+			return
+		}
+	}
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case published, open := <-listener:
+			if !open || !write(c.format(published)) {
+				return
+			}
+		case <-ticker.C:
+			if !write(": heartbeat\n\n") {
+				return
+			}
+		case <-ctx.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func (c *ginService) format(published event) string {
+	data, err := json.Marshal(published.data)
+	if err != nil {
+		data = []byte("{}")
+	}
+	return fmt.Sprintf("id: %s-%d\nevent: %s\ndata: %s\n\n", c.epoch, published.sequence, published.category, data)
+}
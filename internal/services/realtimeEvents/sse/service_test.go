@@ -0,0 +1,184 @@
+package ginSseRealtimeEvents_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rzmn/governi/internal/schema"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+	defaultRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/default"
+	ginSseRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/sse"
+
+	"github.com/gin-gonic/gin"
+)
+
+type streamEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+type eventStream struct {
+	response *http.Response
+	reader   *bufio.Reader
+}
+
+func createSubscriber(t *testing.T) (realtimeEvents.Subscriber, *httptest.Server) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	subscriber := ginSseRealtimeEvents.New(
+		router,
+		standartOutputLoggingService.New(),
+		func(c *gin.Context) {
+			if c.Query("uid") == "" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.Next()
+		},
+		func(c *gin.Context) realtimeEvents.UserId {
+			return realtimeEvents.UserId(c.Query("uid"))
+		},
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return subscriber, server
+}
+
+func createServer(t *testing.T) (realtimeEvents.Service, *httptest.Server) {
+	subscriber, server := createSubscriber(t)
+	return defaultRealtimeEvents.New([]realtimeEvents.Publisher{subscriber}, standartOutputLoggingService.New()), server
+}
+
+func connect(t *testing.T, server *httptest.Server, uid string, lastEventId string) *eventStream {
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/events/stream?uid="+uid, nil)
+	if err != nil {
+		t.Fatalf("failed to create request, err: %v", err)
+	}
+	if lastEventId != "" {
+		request.Header.Set("Last-Event-ID", lastEventId)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("failed to connect, err: %v", err)
+	}
+	stream := &eventStream{
+		response: response,
+		reader:   bufio.NewReader(response.Body),
+	}
+	t.Cleanup(stream.close)
+	if response.StatusCode != http.StatusOK {
+		return stream
+	}
+	if line, _ := stream.reader.ReadString('\n'); !strings.HasPrefix(line, "retry:") {
+		t.Fatalf("stream should start with retry interval, found %s", line)
+	}
+	stream.reader.ReadString('\n')
+	return stream
+}
+
+func (c *eventStream) close() {
+	c.response.Body.Close()
+}
+
+func (c *eventStream) next(t *testing.T) streamEvent {
+	var event streamEvent
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event, err: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			event.id = value
+		case "event":
+			event.event = value
+		case "data":
+			event.data = value
+		}
+	}
+}
+
+func TestReceiveOwnEvents(t *testing.T) {
+	service, server := createServer(t)
+	stream := connect(t, server, "alice", "")
+	service.FriendRequestReceived("bob", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	service.FriendRequestReceived("alice", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	event := stream.next(t)
+	if event.event != realtimeEvents.FriendsCategory("alice") || event.id == "" {
+		t.Fatalf("should receive only own events, found %v", event)
+	}
+	var payload schema.Event[schema.FriendRequestReceivedEvent]
+	if err := json.Unmarshal([]byte(event.data), &payload); err != nil {
+		t.Fatalf("failed to decode event data, err: %v", err)
+	}
+	if payload.Type != schema.EventTypeFriendRequestReceived || payload.Version != schema.EventVersion || payload.Payload.Sender != "carol" {
+		t.Fatalf("unexpected event data: %s", event.data)
+	}
+}
+
+func TestResumeFromLastEventId(t *testing.T) {
+	service, server := createServer(t)
+	stream := connect(t, server, "alice", "")
+	service.FriendRequestReceived("alice", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	last := stream.next(t)
+	stream.close()
+
+	service.BalanceChanged("alice", schema.BalanceChangedEvent{})
+	service.ExpenseAdded("alice", schema.ExpenseAddedEvent{Counterparty: "bob"})
+	stream = connect(t, server, "alice", last.id)
+	if event := stream.next(t); event.event != realtimeEvents.CounterpartiesCategory("alice") {
+		t.Fatalf("should receive first missed event, found %v", event)
+	}
+	if event := stream.next(t); event.event != realtimeEvents.ExpensesCategory("alice", "bob") {
+		t.Fatalf("should receive second missed event, found %v", event)
+	}
+}
+
+func TestResumeFromUnknownEventId(t *testing.T) {
+	service, server := createServer(t)
+	service.FriendRequestReceived("alice", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	stream := connect(t, server, "alice", "unknown-1")
+	if event := stream.next(t); event.event != ginSseRealtimeEvents.ResetEvent || event.id == "" {
+		t.Fatalf("should receive reset event, found %v", event)
+	}
+}
+
+func TestConnectWithoutAccessToken(t *testing.T) {
+	_, server := createServer(t)
+	response, err := http.Get(server.URL + "/events/stream")
+	if err != nil {
+		t.Fatalf("failed to connect, err: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("connection without access token should be rejected, found %d", response.StatusCode)
+	}
+}
+
+func TestReset(t *testing.T) {
+	subscriber, server := createSubscriber(t)
+	stream := connect(t, server, "alice", "")
+	subscriber.Publish("alice", realtimeEvents.FriendsCategory("alice"), struct{}{})
+	last := stream.next(t)
+	subscriber.Reset()
+	if event := stream.next(t); event.event != ginSseRealtimeEvents.ResetEvent || event.id == "" {
+		t.Fatalf("connected client should receive reset event, found %v", event)
+	}
+	stream.close()
+
+	stream = connect(t, server, "alice", last.id)
+	if event := stream.next(t); event.event != ginSseRealtimeEvents.ResetEvent {
+		t.Fatalf("client resuming from before reset should receive reset event, found %v", event)
+	}
+}
@@ -0,0 +1,242 @@
+package ginWebsocketRealtimeEvents
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rzmn/governi/internal/schema"
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	heartbeatInterval = 30 * time.Second
+	writeTimeout      = 10 * time.Second
+	sendBufferSize    = 64
+	maxMessageBytes   = 4 << 10
+)
+
+type MessageType string
+
+const (
+	MessageTypeSubscribe    MessageType = "subscribe"
+	MessageTypeUnsubscribe  MessageType = "unsubscribe"
+	MessageTypeSubscribed   MessageType = "subscribed"
+	MessageTypeUnsubscribed MessageType = "unsubscribed"
+	MessageTypeHeartbeat    MessageType = "heartbeat"
+	MessageTypeEvent        MessageType = "event"
+	MessageTypeError        MessageType = "error"
+	// MessageTypeReset is sent when events might have been lost, the client should refetch its state.
+	MessageTypeReset MessageType = "reset"
+)
+
+type Message struct {
+	Type      MessageType `json:"type"`
+	Category  string      `json:"category,omitempty"`
+	Timestamp int64       `json:"timestamp,omitempty"`
+	Data      any         `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+func New(
+	e *gin.Engine,
+	logger logging.Service,
+	accessTokenMiddleware gin.HandlerFunc,
+	accessToken func(c *gin.Context) realtimeEvents.UserId,
+) realtimeEvents.Subscriber {
+	service := &ginService{
+		subscribers: map[string]map[*client]struct{}{},
+		logger:      logger,
+	}
+	e.GET("/queue/websocket", accessTokenMiddleware, func(c *gin.Context) {
+		subject := accessToken(c)
+		server := websocket.Server{
+			Handler: func(socket *websocket.Conn) {
+				service.serve(subject, socket)
+			},
+		}
+		server.ServeHTTP(c.Writer, c.Request)
+	})
+	return service
+}
+
+type ginService struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[*client]struct{}
+	logger      logging.Service
+}
+
+type client struct {
+	subject       realtimeEvents.UserId
+	socket        *websocket.Conn
+	send          chan Message
+	done          chan struct{}
+	closeOnce     sync.Once
+	subscriptions map[string]struct{}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *ginService) Publish(uid realtimeEvents.UserId, category string, event any) error {
+	op := "websocket.Publish"
+	c.logger.LogInfo("%s: start[uid=%s, category=%s]", op, uid, category)
+	c.publish(category, event)
+	c.logger.LogInfo("%s: success[uid=%s, category=%s]", op, uid, category)
+	return nil
+}
+
+func (c *ginService) publish(category string, data any) {
+	c.mutex.Lock()
+	connections := make([]*client, 0, len(c.subscribers[category]))
+	for connection := range c.subscribers[category] {
+		connections = append(connections, connection)
+	}
+	c.mutex.Unlock()
+	message := Message{
+		Type:      MessageTypeEvent,
+		Category:  category,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	}
+	for _, connection := range connections {
+		c.enqueue(connection, message)
+	}
+}
+
+func (c *ginService) Reset() {
+	op := "websocket.Reset"
+	c.logger.LogInfo("%s: start", op)
+	c.mutex.Lock()
+	connections := map[*client]struct{}{}
+	for _, subscribers := range c.subscribers {
+		for connection := range subscribers {
+			connections[connection] = struct{}{}
+		}
+	}
+	c.mutex.Unlock()
+	for connection := range connections {
+		c.enqueue(connection, Message{
+			Type:      MessageTypeReset,
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}
+	c.logger.LogInfo("%s: success", op)
+}
+
+// enqueue never blocks the publisher: a client that does not keep up with its buffer
+// is disconnected and expected to reconnect and refetch the state it is interested in.
+func (c *ginService) enqueue(connection *client, message Message) {
+	select {
+	case connection.send <- message:
+	case <-connection.done:
+	default:
+		c.logger.LogInfo("websocket: disconnecting slow client[uid=%s]", connection.subject)
+		connection.close()
+	}
+}
+
+func (c *ginService) serve(subject realtimeEvents.UserId, socket *websocket.Conn) {
+	op := "websocket.serve"
+	c.logger.LogInfo("%s: connected[uid=%s]", op, subject)
+	socket.MaxPayloadBytes = maxMessageBytes
+	connection := &client{
+		subject:       subject,
+		socket:        socket,
+		send:          make(chan Message, sendBufferSize),
+		done:          make(chan struct{}),
+		subscriptions: map[string]struct{}{},
+	}
+	go c.write(connection)
+	c.read(connection)
+	connection.close()
+	c.mutex.Lock()
+	for category := range connection.subscriptions {
+		c.unsubscribe(connection, category)
+	}
+	c.mutex.Unlock()
+	c.logger.LogInfo("%s: disconnected[uid=%s]", op, subject)
+}
+
+func (c *ginService) read(connection *client) {
+	for {
+		if err := connection.socket.SetReadDeadline(time.Now().Add(2 * heartbeatInterval)); err != nil {
+			return
+		}
+		var message Message
+		if err := websocket.JSON.Receive(connection.socket, &message); err != nil {
+			return
+		}
+		switch message.Type {
+		case MessageTypeSubscribe:
+			owner, ok := realtimeEvents.CategoryOwner(message.Category)
+			if !ok || owner != connection.subject {
+				c.enqueue(connection, Message{
+					Type:     MessageTypeError,
+					Category: message.Category,
+					Error:    schema.CodeSubscriptionForbidden.Message(),
+				})
+				continue
+			}
+			c.mutex.Lock()
+			if c.subscribers[message.Category] == nil {
+				c.subscribers[message.Category] = map[*client]struct{}{}
+			}
+			c.subscribers[message.Category][connection] = struct{}{}
+			connection.subscriptions[message.Category] = struct{}{}
+			c.mutex.Unlock()
+			c.enqueue(connection, Message{Type: MessageTypeSubscribed, Category: message.Category})
+		case MessageTypeUnsubscribe:
+			c.mutex.Lock()
+			c.unsubscribe(connection, message.Category)
+			c.mutex.Unlock()
+			c.enqueue(connection, Message{Type: MessageTypeUnsubscribed, Category: message.Category})
+		case MessageTypeHeartbeat:
+		default:
+			c.enqueue(connection, Message{
+				Type:  MessageTypeError,
+				Error: "unknown message type",
+			})
+		}
+	}
+}
+
+// unsubscribe expects c.mutex to be held.
+func (c *ginService) unsubscribe(connection *client, category string) {
+	delete(connection.subscriptions, category)
+	delete(c.subscribers[category], connection)
+	if len(c.subscribers[category]) == 0 {
+		delete(c.subscribers, category)
+	}
+}
+
+func (c *ginService) write(connection *client) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer func() {
+		ticker.Stop()
+		connection.close()
+		connection.socket.Close()
+	}()
+	for {
+		var message Message
+		select {
+		case message = <-connection.send:
+		case <-ticker.C:
+			message = Message{Type: MessageTypeHeartbeat}
+		case <-connection.done:
+			return
+		}
+		if err := connection.socket.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return
+		}
+		if err := websocket.JSON.Send(connection.socket, message); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ginWebsocketRealtimeEvents_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rzmn/governi/internal/schema"
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/realtimeEvents"
+	defaultRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/default"
+	ginWebsocketRealtimeEvents "github.com/rzmn/governi/internal/services/realtimeEvents/websocket"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+func createSubscriber(t *testing.T) (realtimeEvents.Subscriber, *httptest.Server) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	subscriber := ginWebsocketRealtimeEvents.New(
+		router,
+		standartOutputLoggingService.New(),
+		func(c *gin.Context) {
+			if c.Query("uid") == "" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.Next()
+		},
+		func(c *gin.Context) realtimeEvents.UserId {
+			return realtimeEvents.UserId(c.Query("uid"))
+		},
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return subscriber, server
+}
+
+func createServer(t *testing.T) (realtimeEvents.Service, *httptest.Server) {
+	subscriber, server := createSubscriber(t)
+	return defaultRealtimeEvents.New([]realtimeEvents.Publisher{subscriber}, standartOutputLoggingService.New()), server
+}
+
+func connect(t *testing.T, server *httptest.Server, uid string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/queue/websocket?uid=" + uid
+	socket, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatalf("failed to connect, err: %v", err)
+	}
+	t.Cleanup(func() { socket.Close() })
+	return socket
+}
+
+func send(t *testing.T, socket *websocket.Conn, message ginWebsocketRealtimeEvents.Message) {
+	if err := websocket.JSON.Send(socket, message); err != nil {
+		t.Fatalf("failed to send message, err: %v", err)
+	}
+}
+
+func receive(t *testing.T, socket *websocket.Conn) ginWebsocketRealtimeEvents.Message {
+	socket.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message ginWebsocketRealtimeEvents.Message
+	if err := websocket.JSON.Receive(socket, &message); err != nil {
+		t.Fatalf("failed to receive message, err: %v", err)
+	}
+	return message
+}
+
+func TestSubscribeAndReceiveEvent(t *testing.T) {
+	service, server := createServer(t)
+	socket := connect(t, server, "alice")
+	category := realtimeEvents.ExpensesCategory("alice", "bob")
+	send(t, socket, ginWebsocketRealtimeEvents.Message{
+		Type:     ginWebsocketRealtimeEvents.MessageTypeSubscribe,
+		Category: category,
+	})
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeSubscribed || message.Category != category {
+		t.Fatalf("unexpected subscribe response: %v", message)
+	}
+	service.FriendRequestReceived("alice", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	service.ExpenseAdded("alice", schema.ExpenseAddedEvent{Counterparty: "bob"})
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeEvent || message.Category != category {
+		t.Fatalf("unexpected event: %v", message)
+	}
+	send(t, socket, ginWebsocketRealtimeEvents.Message{
+		Type:     ginWebsocketRealtimeEvents.MessageTypeUnsubscribe,
+		Category: category,
+	})
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeUnsubscribed {
+		t.Fatalf("unexpected unsubscribe response: %v", message)
+	}
+}
+
+func TestSubscribeToOtherUserCategory(t *testing.T) {
+	service, server := createServer(t)
+	socket := connect(t, server, "alice")
+	send(t, socket, ginWebsocketRealtimeEvents.Message{
+		Type:     ginWebsocketRealtimeEvents.MessageTypeSubscribe,
+		Category: realtimeEvents.FriendsCategory("bob"),
+	})
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeError {
+		t.Fatalf("subscription to other user category should be rejected, found %v", message)
+	}
+	send(t, socket, ginWebsocketRealtimeEvents.Message{
+		Type:     ginWebsocketRealtimeEvents.MessageTypeSubscribe,
+		Category: realtimeEvents.FriendsCategory("alice"),
+	})
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeSubscribed {
+		t.Fatalf("unexpected subscribe response: %v", message)
+	}
+	service.FriendRequestReceived("bob", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	service.FriendRequestReceived("alice", schema.FriendRequestReceivedEvent{Sender: "carol"})
+	if message := receive(t, socket); message.Category != realtimeEvents.FriendsCategory("alice") {
+		t.Fatalf("should receive only own events, found %v", message)
+	}
+}
+
+func TestConnectWithoutAccessToken(t *testing.T) {
+	_, server := createServer(t)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/queue/websocket"
+	if _, err := websocket.Dial(url, "", server.URL); err == nil {
+		t.Fatalf("connection without access token should be rejected")
+	}
+}
+
+func TestReset(t *testing.T) {
+	subscriber, server := createSubscriber(t)
+	socket := connect(t, server, "alice")
+	send(t, socket, ginWebsocketRealtimeEvents.Message{
+		Type:     ginWebsocketRealtimeEvents.MessageTypeSubscribe,
+		Category: realtimeEvents.FriendsCategory("alice"),
+	})
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeSubscribed {
+		t.Fatalf("unexpected subscribe response: %v", message)
+	}
+	subscriber.Reset()
+	if message := receive(t, socket); message.Type != ginWebsocketRealtimeEvents.MessageTypeReset {
+		t.Fatalf("subscribed client should receive reset message, found %v", message)
+	}
+}
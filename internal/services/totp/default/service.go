@@ -0,0 +1,104 @@
+package defaultTotpService
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rzmn/governi/internal/services/logging"
+	"github.com/rzmn/governi/internal/services/totp"
+)
+
+type DefaultConfig struct {
+	Issuer string `json:"issuer"`
+}
+
+func New(
+	config DefaultConfig,
+	logger logging.Service,
+	currentTime func() time.Time,
+) totp.Service {
+	return &defaultService{
+		issuer:      config.Issuer,
+		currentTime: currentTime,
+		logger:      logger,
+	}
+}
+
+const (
+	secretSizeBytes = 20
+	digits          = 6
+	periodSec       = 30
+	allowedSkew     = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type defaultService struct {
+	issuer      string
+	currentTime func() time.Time
+	logger      logging.Service
+}
+
+func (c *defaultService) GenerateSecret() (totp.Secret, error) {
+	const op = "totp.defaultService.GenerateSecret"
+	raw := make([]byte, secretSizeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		c.logger.LogInfo("%s: cannot read random bytes err: %v", op, err)
+		return "", err
+	}
+	return totp.Secret(encoding.EncodeToString(raw)), nil
+}
+
+func (c *defaultService) ProvisioningUri(secret totp.Secret, account string) string {
+	label := url.PathEscape(fmt.Sprintf("%s:%s", c.issuer, account))
+	query := url.Values{}
+	query.Set("secret", string(secret))
+	query.Set("issuer", c.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", digits))
+	query.Set("period", fmt.Sprintf("%d", periodSec))
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+func (c *defaultService) Validate(secret totp.Secret, code string) (totp.Step, bool) {
+	const op = "totp.defaultService.Validate"
+	key, err := encoding.DecodeString(strings.ToUpper(string(secret)))
+	if err != nil {
+		c.logger.LogInfo("%s: cannot decode secret err: %v", op, err)
+		return 0, false
+	}
+	if len(code) != digits {
+		return 0, false
+	}
+	step := c.currentTime().Unix() / periodSec
+	for skew := -allowedSkew; skew <= allowedSkew; skew++ {
+		expected := generateCode(key, uint64(step+int64(skew)))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return totp.Step(step + int64(skew)), true
+		}
+	}
+	return 0, false
+}
+
+func generateCode(key []byte, step uint64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], step)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
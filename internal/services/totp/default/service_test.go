@@ -0,0 +1,102 @@
+package defaultTotpService_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	standartOutputLoggingService "github.com/rzmn/governi/internal/services/logging/standartOutput"
+	"github.com/rzmn/governi/internal/services/totp"
+	defaultTotpService "github.com/rzmn/governi/internal/services/totp/default"
+)
+
+// base32 of the ASCII seed "12345678901234567890" from RFC 6238 Appendix B
+const rfcSecret = totp.Secret("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+func createService(currentTime time.Time) totp.Service {
+	return defaultTotpService.New(
+		defaultTotpService.DefaultConfig{
+			Issuer: "Verni",
+		},
+		standartOutputLoggingService.New(),
+		func() time.Time {
+			return currentTime
+		},
+	)
+}
+
+func TestRfcTestVectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, code := range vectors {
+		service := createService(time.Unix(unix, 0))
+		step, valid := service.Validate(rfcSecret, code)
+		if !valid {
+			t.Fatalf("code %s should be valid at %d", code, unix)
+		}
+		if step != totp.Step(unix/30) {
+			t.Fatalf("code %s should match step %d, found %d", code, unix/30, step)
+		}
+	}
+}
+
+func TestCodeFromAdjacentStepIsValid(t *testing.T) {
+	service := createService(time.Unix(59+30, 0))
+	step, valid := service.Validate(rfcSecret, "287082")
+	if !valid {
+		t.Fatalf("code from previous step should be valid")
+	}
+	if step != 1 {
+		t.Fatalf("code from previous step should match step 1, found %d", step)
+	}
+}
+
+func TestCodeFromDistantStepIsInvalid(t *testing.T) {
+	service := createService(time.Unix(59+90, 0))
+	if _, valid := service.Validate(rfcSecret, "287082"); valid {
+		t.Fatalf("code from distant step should not be valid")
+	}
+}
+
+func TestMalformedCodeIsInvalid(t *testing.T) {
+	service := createService(time.Unix(59, 0))
+	for _, code := range []string{"", "28708", "2870822", "abcdef"} {
+		if _, valid := service.Validate(rfcSecret, code); valid {
+			t.Fatalf("code %s should not be valid", code)
+		}
+	}
+}
+
+func TestGeneratedSecretsAreDifferent(t *testing.T) {
+	service := createService(time.Now())
+	first, err := service.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret err: %v", err)
+	}
+	second, err := service.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret err: %v", err)
+	}
+	if first == second {
+		t.Fatalf("generated secrets should be different")
+	}
+}
+
+func TestProvisioningUri(t *testing.T) {
+	service := createService(time.Now())
+	uri := service.ProvisioningUri(rfcSecret, "user@verni.app")
+	if !strings.HasPrefix(uri, "otpauth://totp/Verni:user@verni.app?") {
+		t.Fatalf("unexpected uri prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+string(rfcSecret)) {
+		t.Fatalf("uri should contain secret: %s", uri)
+	}
+	if !strings.Contains(uri, "issuer=Verni") {
+		t.Fatalf("uri should contain issuer: %s", uri)
+	}
+}
@@ -0,0 +1,23 @@
+package totp_mock
+
+import (
+	"github.com/rzmn/governi/internal/services/totp"
+)
+
+type ServiceMock struct {
+	GenerateSecretImpl  func() (totp.Secret, error)
+	ProvisioningUriImpl func(secret totp.Secret, account string) string
+	ValidateImpl        func(secret totp.Secret, code string) (totp.Step, bool)
+}
+
+func (c *ServiceMock) GenerateSecret() (totp.Secret, error) {
+	return c.GenerateSecretImpl()
+}
+
+func (c *ServiceMock) ProvisioningUri(secret totp.Secret, account string) string {
+	return c.ProvisioningUriImpl(secret, account)
+}
+
+func (c *ServiceMock) Validate(secret totp.Secret, code string) (totp.Step, bool) {
+	return c.ValidateImpl(secret, code)
+}
@@ -0,0 +1,12 @@
+package totp
+
+type Secret string
+
+// Step is the number of the time window a code was generated for.
+type Step int64
+
+type Service interface {
+	GenerateSecret() (Secret, error)
+	ProvisioningUri(secret Secret, account string) string
+	Validate(secret Secret, code string) (Step, bool)
+}